@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// frontend は、実行モードを指定せずに起動した場合に使用するUIです。
+// 通常のビルドではシステムトレイ、nogui タグ付きのビルドではヘッドレスの監視モードになります。
+// システムトレイ（fyne.io/systray）への依存はこのインターフェースの実装側に閉じ込めています。
+type frontend interface {
+	// Name は、ログに表示する実行モード名を返します。
+	Name() string
+	// Run は、ctx がキャンセルされるかユーザーが終了するまでUIを実行します。
+	Run(ctx context.Context, cfg *config.Config)
+}
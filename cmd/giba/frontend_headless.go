@@ -0,0 +1,25 @@
+//go:build nogui
+
+package main
+
+import (
+	"context"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// headlessFrontend は、GUIを持たない環境（Dockerコンテナなど）向けに、監視モードで全タスクを実行します。
+// 設定画面や検証結果はWeb UIではなく、CLIの各モードやヘルスチェックで確認します。
+type headlessFrontend struct{}
+
+func defaultFrontend() frontend {
+	return headlessFrontend{}
+}
+
+func (headlessFrontend) Name() string {
+	return "ヘッドレス監視モード"
+}
+
+func (headlessFrontend) Run(ctx context.Context, cfg *config.Config) {
+	runCliMode(ctx, cfg, true)
+}
@@ -0,0 +1,26 @@
+//go:build !nogui
+
+package main
+
+import (
+	"context"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/systray"
+)
+
+// systrayFrontend は、システムトレイに常駐するUIです。
+type systrayFrontend struct{}
+
+func defaultFrontend() frontend {
+	return systrayFrontend{}
+}
+
+func (systrayFrontend) Name() string {
+	return "システムトレイ"
+}
+
+func (systrayFrontend) Run(ctx context.Context, cfg *config.Config) {
+	hideConsole()
+	systray.RunSystrayApp(ctx, cfg, showConsole, hideConsole, toggleLogger)
+}
@@ -2,25 +2,37 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/archivecrypt"
 	"GoImageBoardArchiver/internal/config"
 	"GoImageBoardArchiver/internal/core"
-	"GoImageBoardArchiver/internal/systray"
+	"GoImageBoardArchiver/internal/network"
+	"GoImageBoardArchiver/internal/secrets"
+	"GoImageBoardArchiver/internal/service"
+	"GoImageBoardArchiver/internal/update"
+	"GoImageBoardArchiver/internal/version"
+	"GoImageBoardArchiver/internal/webui"
 )
 
 // グローバル変数
 var (
 	// ログファイル管理用
 	logFile *os.File
+	// logLocation は、日付ごとのログファイル名を決めるタイムゾーンです（設定の timezone）。
+	logLocation = time.Local
 
 	// コマンドラインフラグ
 	configFile *string
@@ -29,6 +41,18 @@ var (
 	verifyMode *bool
 	repairMode *bool
 	forceMode  *bool
+	verifyTask *string
+	mirrorPath *string
+	sinceDate  *string
+	untilDate  *string
+	debugMode  *bool
+	forceFull  *bool
+	healthAddr *string
+	traceHTTP  *string
+	traceDir   *string
+	workDir    *string
+	profile    *string
+	portable   *bool
 )
 
 func init() {
@@ -38,6 +62,18 @@ func init() {
 	verifyMode = flag.Bool("verify", false, "検証モードで実行")
 	repairMode = flag.Bool("repair", false, "検証モード時に修復を試みる")
 	forceMode = flag.Bool("force", false, "検証モード時に全スレッドを強制チェックする")
+	verifyTask = flag.String("verify-task", "", "検証モード時に対象とするタスク名。空の場合はすべてのタスク")
+	mirrorPath = flag.String("verify-mirror", "", "アーカイブを複製（ディレクトリ・s3://バケット/接頭辞・WebDAV の URL）とハッシュで照合し、差分を報告する（読み取りのみ）")
+	sinceDate = flag.String("since", "", "検証モード時に、この日付以降に更新されたスレッドのみ検証する (例: 2024-01-01)")
+	untilDate = flag.String("until", "", "検証モード時に、この日付までに更新されたスレッドのみ検証する (例: 2024-01-31。当日を含む)")
+	forceFull = flag.Bool("force-full", false, "CLI実行・再アーカイブ時に、レジューム情報と既存ファイルを無視してすべて再ダウンロードする")
+	workDir = flag.String("workdir", "", "作業ディレクトリ。サービスとして起動する場合など、相対パスの基準を固定するために使用する")
+	portable = flag.Bool("portable", false, "設定ファイル・ログ・履歴などを実行ファイルと同じディレクトリに置く（ポータブルモード）")
+	profile = flag.String("profile", "", "プロファイル名。作業ディレクトリの profiles/<名前>/ を基準に、設定ファイル・ログ・履歴・アーカイブを分けて管理する")
+	healthAddr = flag.String("health-addr", "", "CLIモードで /healthz を提供するアドレス (例: 127.0.0.1:8081)。空の場合は無効")
+	debugMode = flag.Bool("debug", false, "Web UIサーバーでpprofエンドポイント(/debug/pprof/)を有効にする")
+	traceHTTP = flag.String("trace-http", "", "指定したホストとのHTTP通信のリクエスト・レスポンスをログに記録する（カンマ区切り。例: may.2chan.net,2chan.net。* ですべて）")
+	traceDir = flag.String("trace-http-dir", "", "--trace-http で記録するレスポンスボディの保存先ディレクトリ。空の場合はメタデータのみ記録する")
 }
 
 // main関数はGIBAアプリケーションのエントリーポイントです。
@@ -46,6 +82,32 @@ func main() {
 	// (グローバル変数で定義済み)
 	flag.Parse()
 
+	// サブコマンド: giba version（設定ファイルなしで実行できるよう、読み込みより前に処理する）
+	if flag.Arg(0) == "version" {
+		fmt.Println(version.Get())
+		return
+	}
+	// サブコマンド: giba keygen（暗号化の鍵を生成する。シークレットストアに保存して encryption_key_secret で参照する）
+	if flag.Arg(0) == "keygen" {
+		key, err := archivecrypt.GenerateKey()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
+	enterBaseDir()
+	if *profile != "" {
+		enterProfile(*profile)
+	}
+
+	// サブコマンド: giba service install|uninstall|start|stop
+	if flag.Arg(0) == "service" {
+		runServiceCommand(flag.Args()[1:])
+		return
+	}
+
 	// --- ログファイルの設定 ---
 	// (setupLoggerで設定されるため、ここでは何もしないが、初期化前にエラーが出るのを防ぐため標準出力にしておく)
 	log.SetOutput(os.Stdout)
@@ -56,6 +118,35 @@ func main() {
 		log.Fatalf("設定ファイルの読み込みに失敗しました: %v", err)
 	}
 	setupLogger(cfg)
+	configureSecrets(cfg, *configFile)
+	if err := adapter.RegisterPlugins(cfg.AdapterPlugins); err != nil {
+		log.Fatalf("adapter_plugins の登録に失敗しました: %v", err)
+	}
+	log.Printf("%s を起動します。", version.Get())
+	webui.SetDebugMode(*debugMode)
+	webui.SetConfigPath(*configFile)
+	if *traceHTTP != "" {
+		domains := network.ParseTraceDomains(*traceHTTP)
+		if err := network.ConfigureTracing(network.TraceSettings{Domains: domains, BodyDir: *traceDir}); err != nil {
+			log.Fatalf("HTTPトレースの設定に失敗しました: %v", err)
+		}
+		log.Printf("HTTPトレースを有効にしました (対象: %s, ボディの保存先: %q)", strings.Join(domains, ", "), *traceDir)
+	} else if *traceDir != "" {
+		log.Println("WARNING: --trace-http-dir は --trace-http と組み合わせて指定してください。トレースは無効です。")
+	}
+
+	// Windowsサービスとして起動された場合は、サービスマネージャーの停止要求で終了する
+	if isService, err := service.RunAsService(service.DefaultName, func(ctx context.Context) {
+		runCliMode(ctx, cfg, true)
+	}); isService {
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Println("サービスを停止しました。")
+		return
+	} else if err != nil {
+		log.Printf("WARNING: %v", err)
+	}
 
 	// モード分岐
 	ctx, cancel := context.WithCancel(context.Background())
@@ -70,37 +161,534 @@ func main() {
 		cancel()
 	}()
 
-	if *verifyMode {
-		// runVerificationModeの引数を修正: (ctx, cfg, targetTaskName, repair, force)
-		// targetTaskNameは現状フラグがないので空文字
-		runVerificationMode(ctx, cfg, "", *repairMode, *forceMode)
+	// サブコマンド: giba rearchive <thread-id|url> / giba reprocess [thread-id|url ...] / giba site build [DIR] / giba self-update
+	// giba task export <name> [FILE] / giba task import <FILE> [name] / giba add-board [preset [name]]
+	// giba export bagit <DIR> [thread-id|url|task ...] / giba sync [-delete] [-bwlimit KB/s] <src> [dst]
+	// giba import <task> <DIR> / giba doctor --adapter [-json] [task ...]
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "rearchive":
+			runRearchiveMode(ctx, cfg, flag.Args()[1:])
+		case "reprocess":
+			runReprocessMode(ctx, cfg, flag.Args()[1:])
+		case "site":
+			runSiteMode(ctx, cfg, flag.Args()[1:])
+		case "self-update":
+			runSelfUpdateMode(ctx)
+		case "task":
+			runTaskMode(cfg, flag.Args()[1:])
+		case "add-board":
+			runAddBoardMode(cfg, flag.Args()[1:])
+		case "export":
+			runExportMode(ctx, cfg, flag.Args()[1:])
+		case "sync":
+			runSyncMode(ctx, flag.Args()[1:])
+		case "import":
+			runImportMode(ctx, cfg, flag.Args()[1:])
+		case "doctor":
+			runDoctorMode(ctx, cfg, flag.Args()[1:])
+		default:
+			log.Fatalf("不明なサブコマンドです: %s", flag.Arg(0))
+		}
+		log.Println("アプリケーションが正常にシャットダウンしました。")
+		return
+	}
+
+	// 共有モード: 設定画面とは別のアドレスで、アーカイブの閲覧のみを提供する
+	if cfg.Sharing != nil && !*verifyMode && *mirrorPath == "" {
+		if err := webui.StartShareServer(ctx, cfg); err != nil {
+			log.Printf("ERROR: 共有モードを開始できませんでした: %v", err)
+		}
+	}
+
+	// ブラウザのユーザースクリプトなどから、閲覧中のスレッドのアーカイブの依頼を受け付ける
+	if cfg.ArchiveHook != nil && !*verifyMode && *mirrorPath == "" {
+		if err := webui.StartArchiveHookServer(ctx, cfg); err != nil {
+			log.Printf("ERROR: アーカイブの依頼の受け付けを開始できませんでした: %v", err)
+		}
+	}
+
+	if *mirrorPath != "" {
+		if *repairMode {
+			log.Fatalf("--verify-mirror は読み取りのみのため、--repair と同時に指定できません")
+		}
+		filter, err := verificationFilterFromFlags(cfg)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		runMirrorVerificationMode(ctx, cfg, filter, *mirrorPath)
+	} else if *verifyMode {
+		filter, err := verificationFilterFromFlags(cfg)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		runVerificationMode(ctx, cfg, filter, *repairMode, *forceMode)
 	} else if *cliMode {
 		runCliMode(ctx, cfg, *watchMode)
 	} else {
-		log.Println("実行モード: システムトレイ (デフォルト)")
-		runSystrayMode(ctx)
+		fe := defaultFrontend()
+		log.Printf("実行モード: %s (デフォルト)", fe.Name())
+		fe.Run(ctx, cfg)
 	}
 
 	log.Println("アプリケーションが正常にシャットダウンしました。")
 }
 
-func runVerificationMode(ctx context.Context, cfg *config.Config, targetTaskName string, repair bool, force bool) {
+// enterBaseDir は、相対パスの基準にするディレクトリ（-workdir、--portable の場合は実行ファイルのディレクトリ、
+// 作業ディレクトリに設定ファイルがない場合はOSごとの設定ディレクトリ）に移動します。
+// システムトレイのショートカットなどから起動して作業ディレクトリが定まらない場合も、同じ設定・ログ・履歴を使用できます。
+func enterBaseDir() {
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("作業ディレクトリを取得できませんでした: %v", err)
+	}
+	exePath, err := os.Executable()
+	if err != nil && *portable {
+		log.Fatalf("実行ファイルのパスを取得できませんでした: %v", err)
+	}
+	dir, err := config.ResolveBaseDir(config.BaseDirOptions{
+		WorkDir:        *workDir,
+		Portable:       *portable,
+		ExecutablePath: exePath,
+		CurrentDir:     cwd,
+		ConfigPath:     *configFile,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if dir == cwd {
+		return
+	}
+	if *workDir == "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Fatalf("ディレクトリ %s を作成できません: %v", dir, err)
+		}
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatalf("作業ディレクトリ %s に移動できません: %v", dir, err)
+	}
+	if *workDir == "" {
+		log.Printf("設定ファイル・ログ・履歴の保存先: %s", dir)
+	}
+}
+
+// enterProfile は、プロファイルのディレクトリ（なければ作成）に移動します。
+// 以降の相対パス（-config の既定の config.json、ログファイル、検証履歴、既定の保存先など）はプロファイルごとに分かれます。
+func enterProfile(name string) {
+	dir, err := config.ProfileDir(".", name)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatalf("プロファイルのディレクトリ %s を作成できません: %v", dir, err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatalf("プロファイルのディレクトリ %s に移動できません: %v", dir, err)
+	}
+	log.Printf("プロファイル %q を使用します (ディレクトリ: %s)", name, dir)
+}
+
+// configureSecrets は、認証に使用するシークレットファイルを設定します。
+// secrets_file が未設定の場合は、設定ファイルと同じディレクトリの secrets.json を使用します。
+func configureSecrets(cfg *config.Config, configPath string) {
+	path := cfg.SecretsFile
+	if path == "" {
+		path = filepath.Join(filepath.Dir(configPath), secrets.DefaultFileName)
+	}
+	secrets.Configure(path)
+}
+
+// verificationFilterFromFlags は、--verify-task・--since・--until から検証の対象の条件を作成します。
+// 日付は設定の timezone で解釈し、--until はその日の終わりまでを含みます。
+func verificationFilterFromFlags(cfg *config.Config) (core.VerificationFilter, error) {
+	filter := core.VerificationFilter{TaskName: *verifyTask}
+	if filter.TaskName != "" {
+		found := false
+		for _, task := range cfg.Tasks {
+			found = found || task.TaskName == filter.TaskName
+		}
+		if !found {
+			return filter, fmt.Errorf("--verify-task のタスク '%s' は設定ファイルにありません", filter.TaskName)
+		}
+	}
+	loc := config.Location(cfg.Timezone)
+	if *sinceDate != "" {
+		since, err := time.ParseInLocation("2006-01-02", *sinceDate, loc)
+		if err != nil {
+			return filter, fmt.Errorf("--since の日付 '%s' を解釈できません（例: 2024-01-01）: %w", *sinceDate, err)
+		}
+		filter.Since = since
+	}
+	if *untilDate != "" {
+		until, err := time.ParseInLocation("2006-01-02", *untilDate, loc)
+		if err != nil {
+			return filter, fmt.Errorf("--until の日付 '%s' を解釈できません（例: 2024-01-31）: %w", *untilDate, err)
+		}
+		filter.Until = until.AddDate(0, 0, 1)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, fmt.Errorf("--since (%s) が --until (%s) より後です", *sinceDate, *untilDate)
+	}
+	return filter, nil
+}
+
+func runVerificationMode(ctx context.Context, cfg *config.Config, filter core.VerificationFilter, repair bool, force bool) {
 	log.Println("検証モードで起動します。")
-	if err := core.RunVerification(ctx, cfg, targetTaskName, repair, force); err != nil {
+	if err := core.RunVerification(ctx, cfg, filter, repair, force); err != nil {
 		log.Printf("検証中にエラーが発生しました: %v", err)
 		os.Exit(1)
 	}
 	log.Println("検証モードを終了します。")
 }
 
-func runSystrayMode(ctx context.Context) {
-	hideConsole()
-	systray.RunSystrayApp(ctx, showConsole, hideConsole, toggleLogger)
+// runMirrorVerificationMode は、アーカイブをミラーと照合します。
+func runMirrorVerificationMode(ctx context.Context, cfg *config.Config, filter core.VerificationFilter, target string) {
+	log.Println("ミラー照合モードで起動します。")
+	if err := core.RunMirrorVerification(ctx, cfg, filter, target); err != nil {
+		log.Printf("ミラーとの照合中にエラーが発生しました: %v", err)
+		os.Exit(1)
+	}
+	log.Println("ミラー照合モードを終了します。")
+}
+
+// runRearchiveMode は、指定されたスレッドを強制的に再アーカイブします。
+func runRearchiveMode(ctx context.Context, cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		log.Fatalln("使い方: giba rearchive <thread-id|url>")
+	}
+	result, err := core.RearchiveThread(ctx, cfg, args[0], *forceFull, log.Default())
+	if err != nil {
+		log.Printf("再アーカイブに失敗しました: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("再アーカイブが完了しました: %s (ファイル: %d)", result.SavePath, result.FilesDownloaded)
+}
+
+// runReprocessMode は、保存済みの raw.html.gz から、ネットワークにアクセスせずにスレッドのHTMLを再生成します。
+// 引数を省略した場合は、すべてのタスクのアーカイブ済みスレッドを対象とします。
+func runReprocessMode(ctx context.Context, cfg *config.Config, args []string) {
+	summary, err := core.ReprocessThreads(ctx, cfg, args, log.Default())
+	if err != nil {
+		log.Printf("再処理に失敗しました: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("再処理が完了しました (再処理: %d, raw.html.gz なし: %d, 失敗: %d, 見つからないメディア: %d)",
+		summary.Processed, summary.Skipped, summary.Failed, summary.Missing)
+	if summary.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runSiteMode は、アーカイブ全体を閲覧できる静的サイトを生成します（giba site build [DIR]、DIR の省略時は site）。
+func runSiteMode(ctx context.Context, cfg *config.Config, args []string) {
+	if len(args) == 0 || len(args) > 2 || args[0] != "build" {
+		log.Fatalln("使い方: giba site build [出力先ディレクトリ]")
+	}
+	outDir := core.DefaultSiteDir
+	if len(args) == 2 {
+		outDir = args[1]
+	}
+	summary, err := core.BuildSite(ctx, cfg, outDir, log.Default())
+	if err != nil {
+		log.Printf("静的サイトの生成に失敗しました: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("静的サイトを %s に生成しました (スレッド: %d, タスク: %d, タグ: %d, 掲載できなかったスレッド: %d)",
+		outDir, summary.Threads, summary.Tasks, summary.Tags, summary.Skipped)
+}
+
+// runExportMode は、アーカイブ済みのスレッドを長期保存用の形式で書き出します。
+// giba export bagit <出力先> [スレッドID|スレッドURL|タスク名 ...]（対象の省略時はすべてのスレッド）
+func runExportMode(ctx context.Context, cfg *config.Config, args []string) {
+	if len(args) < 2 || args[0] != "bagit" {
+		log.Fatalln("使い方: giba export bagit <出力先ディレクトリ> [スレッドID|スレッドURL|タスク名 ...]")
+	}
+	summary, err := core.ExportBagIt(ctx, cfg, args[1], args[2:], log.Default())
+	if err != nil {
+		log.Printf("エクスポートに失敗しました: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("BagIt 形式で %s に書き出しました (スレッド: %d, ファイル: %d, %d バイト)", args[1], summary.Threads, summary.Files, summary.Bytes)
+}
+
+// runSyncMode は、アーカイブのディレクトリを別の場所に一方向に複製します。
+// giba sync [-delete] [-bwlimit KB/s] <複製元> [複製先]（複製先の省略時は、複製元に記録されたすべての複製先）
+// giba sync -list <ディレクトリ>（記録された複製元・複製先の一覧を表示）
+func runSyncMode(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	deleteExtra := fs.Bool("delete", false, "複製元にないファイルを複製先から削除する")
+	bwLimit := fs.Int("bwlimit", 0, "コピーの帯域の上限（KB/秒）。0 の場合は制限しない")
+	list := fs.Bool("list", false, "ディレクトリに記録された複製元・複製先の一覧を表示する")
+	fs.Parse(args)
+	const usage = "使い方: giba sync [-delete] [-bwlimit KB/秒] <複製元ディレクトリ> [複製先ディレクトリ] / giba sync -list <ディレクトリ>"
+	if fs.NArg() < 1 || fs.NArg() > 2 || *bwLimit < 0 || (*list && fs.NArg() != 1) {
+		log.Fatalln(usage)
+	}
+	src := fs.Arg(0)
+	locations, err := core.LoadArchiveLocations(src)
+	if err != nil {
+		log.Fatalf("複製の記録を読み込めません: %v", err)
+	}
+	if *list {
+		if len(locations.Locations) == 0 {
+			log.Printf("%s には複製の記録がありません", src)
+			return
+		}
+		for _, location := range locations.Locations {
+			log.Printf("%s\t%s\t最終複製: %s", location.Role, location.Path, location.LastSyncedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		return
+	}
+
+	dsts := []string{fs.Arg(1)}
+	if fs.NArg() == 1 {
+		dsts = nil
+		for _, replica := range locations.Replicas() {
+			dsts = append(dsts, replica.Path)
+		}
+		if len(dsts) == 0 {
+			log.Fatalf("%s には複製先の記録がありません。複製先を指定してください\n%s", src, usage)
+		}
+	}
+
+	opts := core.SyncOptions{Delete: *deleteExtra, BytesPerSecond: *bwLimit * 1024}
+	failed := false
+	for _, dst := range dsts {
+		summary, err := core.SyncArchive(ctx, src, dst, opts, log.Default())
+		if err != nil {
+			log.Printf("%s への複製に失敗しました: %v", dst, err)
+			failed = true
+			continue
+		}
+		log.Printf("%s を %s に複製しました (コピー: %d, 変更なし: %d, 削除: %d, %d バイト)",
+			src, dst, summary.Copied, summary.Skipped, summary.Deleted, summary.Bytes)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runImportMode は、他のツール（ふたクロ・赤福など）で保存したスレッドをタスクの保存先に取り込みます。
+// giba import <タスク名> <取り込み元ディレクトリ>
+func runImportMode(ctx context.Context, cfg *config.Config, args []string) {
+	if len(args) != 2 {
+		log.Fatalln("使い方: giba import <タスク名> <取り込み元ディレクトリ>")
+	}
+	summary, err := core.ImportLocalArchive(ctx, cfg, args[0], args[1], log.Default())
+	if err != nil {
+		log.Printf("取り込みに失敗しました: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("%s から %d 件のスレッドを取り込みました (スキップ: %d, 失敗: %d, メディア: %d, 見つからないメディア: %d)",
+		args[1], summary.Imported, summary.Skipped, summary.Failed, summary.Media, summary.Missing)
+	if summary.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runDoctorMode は、タスクのサイトアダプタで実際のカタログとスレッドを取得・解析し、掲示板のマークアップの変更で解析できなくなっていないかを診断します。
+// giba doctor --adapter [-json] [タスク名 ...]（タスクの省略時は有効なすべてのタスク）。失敗した項目がある場合は終了コード 1 で終了します。
+func runDoctorMode(ctx context.Context, cfg *config.Config, args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	adapterCheck := fs.Bool("adapter", false, "サイトアダプタの解析を診断する")
+	jsonOutput := fs.Bool("json", false, "診断の結果を JSON で出力する")
+	fs.Parse(args)
+	if !*adapterCheck {
+		log.Fatalln("使い方: giba doctor --adapter [-json] [タスク名 ...]")
+	}
+	diagnoses, err := core.DiagnoseAdapters(ctx, cfg, fs.Args(), log.Default())
+	if err != nil {
+		log.Printf("診断に失敗しました: %v", err)
+		os.Exit(1)
+	}
+
+	healthy := true
+	for _, d := range diagnoses {
+		healthy = healthy && d.Healthy()
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(diagnoses, "", "  ")
+		if err != nil {
+			log.Fatalf("診断の結果の書き出しに失敗しました: %v", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, d := range diagnoses {
+			result := "正常"
+			if !d.Healthy() {
+				result = "異常"
+			}
+			fmt.Printf("[%s] タスク '%s' (%s)\n", result, d.TaskName, d.SiteAdapter)
+			for _, c := range d.Checks {
+				fmt.Printf("  %-8s %-16s %s\n", c.Status, c.Name, c.Detail)
+			}
+		}
+	}
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+// runTaskMode は、タスクの定義を共有用のプリセットファイルに書き出し、またはプリセットファイルから設定ファイルに追加します。
+// giba task export <タスク名> [出力先]（省略時は <タスク名>.preset.json）/ giba task import <プリセット> [タスク名]
+func runTaskMode(cfg *config.Config, args []string) {
+	const usage = "使い方: giba task export <タスク名> [出力ファイル] | giba task import <プリセットファイル> [タスク名]"
+	if len(args) < 2 || len(args) > 3 {
+		log.Fatalln(usage)
+	}
+	switch args[0] {
+	case "export":
+		preset, err := config.NewTaskPreset(cfg, args[1], version.Get().String())
+		if err != nil {
+			log.Fatalf("エクスポートに失敗しました: %v", err)
+		}
+		outPath := core.SanitizeFilename(args[1]) + ".preset.json"
+		if len(args) == 3 {
+			outPath = args[2]
+		}
+		data, err := json.MarshalIndent(preset, "", "  ")
+		if err != nil {
+			log.Fatalf("プリセットのシリアライズに失敗しました: %v", err)
+		}
+		if err := os.WriteFile(outPath, append(data, '\n'), 0644); err != nil {
+			log.Fatalf("プリセットの書き込みに失敗しました: %v", err)
+		}
+		log.Printf("タスク '%s' を %s にエクスポートしました。", args[1], outPath)
+		if len(preset.Secrets) > 0 {
+			log.Printf("認証情報は含まれていません。インポートした利用者は次のシークレットを設定する必要があります: %s", strings.Join(preset.Secrets, ", "))
+		}
+	case "import":
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			log.Fatalf("プリセットファイルを読み込めません: %v", err)
+		}
+		preset, err := config.ParseTaskPreset(data)
+		if err != nil {
+			log.Fatalf("インポートに失敗しました: %v", err)
+		}
+		task := preset.Task
+		if len(args) == 3 {
+			task.TaskName = args[2]
+		}
+		// 保存先はプリセットに含まれないため、設定ファイルの global_save_root_directory の下にタスク名で作成する
+		if task.SaveRootDirectory == "" {
+			task.SaveRootDirectory = defaultTaskSaveRoot(cfg, task.TaskName)
+		}
+		if err := config.AppendTaskToFile(*configFile, task); err != nil {
+			log.Fatalf("インポートに失敗しました: %v", err)
+		}
+		log.Printf("タスク '%s' を %s に追加しました (保存先: %s)。", task.TaskName, *configFile, task.SaveRootDirectory)
+		if len(preset.Secrets) > 0 {
+			log.Printf("次のシークレットを secrets.json または環境変数で設定してください: %s", strings.Join(preset.Secrets, ", "))
+		}
+	default:
+		log.Fatalln(usage)
+	}
+}
+
+// runAddBoardMode は、同梱の既知の掲示板の設定でタスクを設定ファイルに追加します。
+// 引数を省略した場合は、指定できる掲示板の一覧を表示します。タスク名の省略時は掲示板の表示名を使用します。
+func runAddBoardMode(cfg *config.Config, args []string) {
+	if len(args) > 2 {
+		log.Fatalln("使い方: giba add-board [掲示板のID [タスク名]]")
+	}
+	if len(args) == 0 {
+		presets, err := config.BoardPresets()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Println("追加できる掲示板（giba add-board <ID> [タスク名]）:")
+		for _, preset := range presets {
+			fmt.Printf("  %-18s %s - %s\n", preset.ID, preset.Name, preset.Description)
+		}
+		return
+	}
+
+	preset, err := config.FindBoardPreset(args[0])
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	taskName := preset.Name
+	if len(args) == 2 {
+		taskName = args[1]
+	}
+	task := preset.NewTask(taskName)
+	task.SaveRootDirectory = defaultTaskSaveRoot(cfg, taskName)
+	if err := config.AppendTaskToFile(*configFile, task); err != nil {
+		log.Fatalf("掲示板の追加に失敗しました: %v", err)
+	}
+	log.Printf("タスク '%s' (%s) を %s に追加しました (保存先: %s)。", taskName, task.TargetBoardURL, *configFile, task.SaveRootDirectory)
+}
+
+// defaultTaskSaveRoot は、追加するタスクの保存先を返します。設定ファイルの global_save_root_directory（未設定の場合は archives）の下のタスク名のディレクトリです。
+func defaultTaskSaveRoot(cfg *config.Config, taskName string) string {
+	root := cfg.GlobalSaveRootDirectory
+	if root == "" {
+		root = "archives"
+	}
+	return filepath.Join(root, core.SanitizeFilename(taskName))
+}
+
+// runServiceCommand は、GIBAをOSのサービスとして登録・削除・開始・停止します。
+// サービスは現在の作業ディレクトリ（-workdir 指定時はそのディレクトリ）と -config の設定ファイルで、監視モードとして実行されます。
+func runServiceCommand(args []string) {
+	if len(args) != 1 {
+		log.Fatalln("使い方: giba [-workdir DIR] [-config PATH] service install|uninstall|start|stop")
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		log.Fatalf("実行ファイルのパスを取得できませんでした: %v", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		log.Fatalf("作業ディレクトリを取得できませんでした: %v", err)
+	}
+	svcCfg, err := service.NewConfig(exePath, cwd, *configFile)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if args[0] == "install" {
+		if _, err := config.LoadAndResolve(svcCfg.ConfigPath); err != nil {
+			log.Fatalf("設定ファイル %s を読み込めません: %v", svcCfg.ConfigPath, err)
+		}
+	}
+
+	if err := service.Control(svcCfg, args[0]); err != nil {
+		log.Fatalf("サービスの%sに失敗しました: %v", args[0], err)
+	}
+	log.Printf("サービス %s の %s が完了しました (作業ディレクトリ: %s, 設定: %s)", svcCfg.Name, args[0], svcCfg.WorkDir, svcCfg.ConfigPath)
+}
+
+// runSelfUpdateMode は、最新のリリースを確認し、新しいバージョンがあれば実行ファイルを置き換えます。
+func runSelfUpdateMode(ctx context.Context) {
+	if !version.IsRelease() {
+		log.Fatalln("開発ビルドのため自己更新できません。リリース版を使用してください。")
+	}
+	status, release, err := update.Check(ctx)
+	if err != nil {
+		log.Fatalf("更新の確認に失敗しました: %v", err)
+	}
+	if !status.UpdateAvailable {
+		log.Printf("最新のバージョンを使用しています (%s)", status.Current)
+		return
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		log.Fatalf("実行ファイルのパスを取得できませんでした: %v", err)
+	}
+	log.Printf("%s から %s に更新します...", status.Current, status.Latest)
+	if err := update.Apply(ctx, release, exePath); err != nil {
+		log.Printf("自己更新に失敗しました: %v", err)
+		os.Exit(1)
+	}
+	log.Printf("%s に更新しました。GIBAを再起動してください。", status.Latest)
 }
 
 // setupLogger はログ出力先を設定します。
 // config.EnableLogFile が true の場合、ファイルにも出力します。
 func setupLogger(cfg *config.Config) {
+	logLocation = config.Location(cfg.Timezone)
 	err := toggleLogger(cfg.EnableLogFile, cfg.LogFilePath)
 	if err != nil {
 		return
@@ -123,7 +711,7 @@ func toggleLogger(enable bool, path string) error {
 	if enable {
 		if path == "" {
 			// デフォルトは日付形式
-			today := time.Now().Format("2006-01-02")
+			today := time.Now().In(logLocation).Format("2006-01-02")
 			path = fmt.Sprintf("giba_%s.log", today)
 		}
 		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -157,49 +745,60 @@ func runCliMode(ctx context.Context, cfg *config.Config, isWatch bool) {
 		return
 	}
 
-	// 並行実行数の制限 (グローバル設定)
-	maxConcurrent := cfg.GlobalMaxConcurrentTasks
-	if maxConcurrent <= 0 {
-		maxConcurrent = 1 // デフォルト
+	if *healthAddr != "" {
+		startHealthServer(ctx, *healthAddr)
 	}
-	taskSemaphore := make(chan struct{}, maxConcurrent)
-	var wg sync.WaitGroup
-
-	log.Printf("タスク数: %d, 最大並行数: %d", len(tasks), maxConcurrent)
 
-loop:
-	for _, task := range tasks {
+	// 実行枠とハートビートの設定はエンジンの開始時に適用される。
+	// 実行枠は巡回サイクルごとに確保するため、監視モードでも待機中のタスクが枠を占有しない。
+	log.Printf("タスク数: %d, 最大並行数: %d", len(tasks), core.EffectiveMaxConcurrentTasks(cfg.GlobalMaxConcurrentTasks))
+	for i, task := range tasks {
 		if task.Enabled == nil || !*task.Enabled {
 			log.Printf("タスク '%s' は無効化されているためスキップします。", task.TaskName)
-			continue
 		}
+		tasks[i].ForceFull = *forceFull
+	}
 
-		select {
-		case <-ctx.Done():
-			log.Println("コンテキストがキャンセルされたため、新規タスクの開始を中断します。")
-			break loop
-		default:
-			// 続行
+	engine := core.NewEngine(cfg)
+	if err := engine.Start(ctx); err != nil {
+		log.Printf("ERROR: コアエンジンの開始に失敗しました: %v", err)
+		return
+	}
+	if isWatch {
+		if err := engine.SetWatching(true); err != nil {
+			log.Printf("ERROR: 監視モードを開始できませんでした: %v", err)
 		}
-
-		wg.Add(1)
-		taskSemaphore <- struct{}{}
-
-		// task変数をgoroutineに渡すためにコピー
-		taskCopy := task
-
-		go func() {
-			defer func() { <-taskSemaphore }() // セマフォを解放
-			defer wg.Done()                    // WaitGroupカウンタを減らす
-
-			// コピーした変数 `taskCopy` を使う
-			core.ExecuteTask(ctx, taskCopy, cfg.Network, cfg.SafetyStopMinDiskGB, isWatch, nil)
-		}()
+		<-ctx.Done()
+	} else if err := engine.RunOnce(); err != nil {
+		log.Printf("ERROR: タスクを実行できませんでした: %v", err)
 	}
-	wg.Wait()
+	engine.Stop()
 	log.Println("全てのCLIタスクが完了しました。")
 }
 
+// startHealthServer は、CLIモード用に /healthz のみを提供するHTTPサーバーを起動します。
+// システムトレイモードでは Web UI サーバーが同じエンドポイントを提供します。
+func startHealthServer(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", webui.HandleHealthz)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		log.Printf("ヘルスチェックを http://%s/healthz で提供します。", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("ERROR: ヘルスチェックサーバーが異常終了しました: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("WARNING: ヘルスチェックサーバーの停止に失敗しました: %v", err)
+		}
+	}()
+}
+
 // setupLogFileは、日付ごとのログファイルを作成し、標準出力とファイルの両方に出力するように設定します。
 func _() *os.File {
 	// 現在の日付でログファイル名を生成
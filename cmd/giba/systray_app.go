@@ -56,6 +56,11 @@ func (a *FutabaAdapter) Prepare(client *network.Client, taskConfig config.Task)
 	return client.SetCookie(taskConfig.TargetBoardURL, cookie)
 }
 
+// Capabilities は、このアダプタが対応している機能を返します。HTMLのみを解析するため、JSONのAPIは使用しません。
+func (a *FutabaAdapter) Capabilities() adapter.Capabilities {
+	return adapter.Capabilities{Thumbnails: true, DeletionMarkers: true, ResNumbers: true}
+}
+
 // BuildCatalogURL は、ふたばちゃんねるのカタログURLを構築します。
 func (a *FutabaAdapter) BuildCatalogURL(baseURL string) (string, error) {
 	parsedURL, err := url.Parse(baseURL)
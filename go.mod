@@ -5,14 +5,15 @@ go 1.21
 require (
 	fyne.io/systray v1.10.0
 	github.com/PuerkitoBio/goquery v1.9.2
+	github.com/andybalholm/brotli v1.1.1
+	github.com/andybalholm/cascadia v1.3.2
+	golang.org/x/sys v0.19.0
 	golang.org/x/text v0.14.0
 	golang.org/x/time v0.5.0
 )
 
 require (
-	github.com/andybalholm/cascadia v1.3.2 // indirect
 	github.com/godbus/dbus/v5 v5.0.4 // indirect
 	github.com/tevino/abool v1.2.0 // indirect
 	golang.org/x/net v0.24.0 // indirect
-	golang.org/x/sys v0.19.0 // indirect
 )
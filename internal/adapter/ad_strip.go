@@ -0,0 +1,123 @@
+package adapter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commonAdPatterns は、多くのサイトに共通する広告枠・トラッキング用の要素の開始タグです。
+// 一致した要素は、対応する終了タグまで（入れ子を含めて）取り除かれます。
+var commonAdPatterns = []*regexp.Regexp{
+	// Google AdSense などの広告枠
+	regexp.MustCompile(`(?i)<ins\b[^>]*class=["']?[^"'>]*adsbygoogle[^>]*>`),
+	// 広告配信サーバーの iframe
+	regexp.MustCompile(`(?i)<iframe\b[^>]*src=["']?[^"'>]*(?:doubleclick\.net|googlesyndication\.com|adservice\.|amazon-adsystem\.com|/ads?/)[^>]*>`),
+	// id・class に広告やバナーを示す語を含むブロック（例: id="rightad", class="ad-banner"）
+	regexp.MustCompile(`(?i)<(?:div|aside|section|span|table)\b[^>]*\b(?:id|class)=["']?(?:[^"'>]*[\s_-])?(?:ads?|advert\w*|banner\w*|sponsor\w*|(?:top|bottom|left|right)ads?)(?:[\s_-][^"'>]*)?["'\s>/]`),
+	// 1x1 のトラッキングピクセル
+	regexp.MustCompile(`(?i)<img\b[^>]*\bwidth=["']?1["'\s][^>]*\bheight=["']?1["'\s/>][^>]*>`),
+	regexp.MustCompile(`(?i)<img\b[^>]*\bheight=["']?1["'\s][^>]*\bwidth=["']?1["'\s/>][^>]*>`),
+	// スクリプトを削除した後は不要な noscript（トラッキング用の画像などを含む）
+	regexp.MustCompile(`(?i)<noscript\b[^>]*>`),
+}
+
+// futabaAdPatterns は、ふたば☆ちゃんねるのスレッドから取り除く広告・トラッキング要素です。
+var futabaAdPatterns = commonAdPatterns
+
+// voidElements は、終了タグを持たない要素です。開始タグのみを取り除きます。
+var voidElements = map[string]bool{
+	"img": true, "input": true, "br": true, "hr": true, "source": true, "link": true, "meta": true, "embed": true,
+}
+
+var tagNamePattern = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9]*)`)
+
+// stripAdBlocks は、patterns のいずれかに開始タグが一致する要素をHTMLから取り除きます。
+// 終了タグは同じ名前のタグの入れ子を数えて対応するものを探し、見つからない場合は開始タグのみを取り除きます。
+func stripAdBlocks(htmlContent string, patterns []*regexp.Regexp) string {
+	for _, pattern := range patterns {
+		locs := pattern.FindAllStringIndex(htmlContent, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		lower := asciiLower(htmlContent)
+		var sb strings.Builder
+		last := 0
+		for _, loc := range locs {
+			if loc[0] < last {
+				// 直前に取り除いた要素の内側にある
+				continue
+			}
+			end := elementEnd(lower, loc[0], loc[1])
+			sb.WriteString(htmlContent[last:loc[0]])
+			last = end
+		}
+		sb.WriteString(htmlContent[last:])
+		htmlContent = sb.String()
+	}
+	return htmlContent
+}
+
+// elementEnd は、lower[start:openEnd] に一致した開始タグで始まる要素の終端の位置を返します。
+// lower は、ASCII の英字を小文字にしたHTMLです（元のHTMLとバイト位置が一致します）。
+func elementEnd(lower string, start, openEnd int) int {
+	// 属性の途中で一致した場合に備え、開始タグの終わり（'>'）まで進める
+	if i := strings.IndexByte(lower[openEnd-1:], '>'); i >= 0 {
+		openEnd += i
+	}
+
+	m := tagNamePattern.FindStringSubmatch(lower[start:openEnd])
+	if m == nil {
+		return openEnd
+	}
+	name := m[1]
+	if voidElements[name] || strings.HasSuffix(lower[start:openEnd], "/>") {
+		return openEnd
+	}
+
+	open, closeTag := "<"+name, "</"+name+">"
+	depth := 1
+	pos := openEnd
+	for depth > 0 {
+		nextClose := strings.Index(lower[pos:], closeTag)
+		if nextClose < 0 {
+			// 終了タグがない（省略されている）場合は開始タグのみを取り除く
+			return openEnd
+		}
+		nextOpen := indexOpenTag(lower[pos:], open)
+		if nextOpen >= 0 && nextOpen < nextClose {
+			depth++
+			pos += nextOpen + len(open)
+			continue
+		}
+		depth--
+		pos += nextClose + len(closeTag)
+	}
+	return pos
+}
+
+// indexOpenTag は、s の中で名前が完全に一致する開始タグ（"<div" に対して "<divider" は除く）の位置を返します。
+func indexOpenTag(s, open string) int {
+	offset := 0
+	for {
+		i := strings.Index(s[offset:], open)
+		if i < 0 {
+			return -1
+		}
+		next := offset + i + len(open)
+		if next >= len(s) || strings.IndexByte(" \t\r\n>/", s[next]) >= 0 {
+			return offset + i
+		}
+		offset = next
+	}
+}
+
+// asciiLower は、ASCII の英字のみを小文字にします。バイト数が変わらないため、元の文字列の位置をそのまま使用できます。
+func asciiLower(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
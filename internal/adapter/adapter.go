@@ -4,6 +4,8 @@
 package adapter
 
 import (
+	"time"
+
 	"GoImageBoardArchiver/internal/config"
 	"GoImageBoardArchiver/internal/model"
 	"GoImageBoardArchiver/internal/network"
@@ -22,4 +24,106 @@ type SiteAdapter interface {
 	ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error)
 	// ReconstructHTML は、HTMLコンテンツ内のリンクをローカルパスに書き換えます。
 	ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error)
+	// Capabilities は、アダプタが対応している機能を返します。Prepare の後に呼び出されます。
+	Capabilities() Capabilities
+}
+
+// Capabilities は、サイトアダプタが対応している機能です。
+// core は、ふたばの動作を前提にせず、対応していない処理（削除されたレスの検出など）を省略するために使用します。
+type Capabilities struct {
+	JSONAPI         bool `json:"json_api"`         // カタログ・スレッドをJSONのAPIから取得できる
+	Thumbnails      bool `json:"thumbnails"`       // メディアのサムネイルのURLを抽出できる（thumbnails_only に必要）
+	DeletionMarkers bool `json:"deletion_markers"` // 削除されたレスを表示（削除済みの印）から判別できる
+	ResNumbers      bool `json:"res_numbers"`      // スレッドのHTMLにレス番号が含まれる（削除されたレスの検出に必要）
+}
+
+// OPTextExtractor は、スレッド本文（OP）のテキストを抽出できるアダプタが任意で実装するインターフェースです。
+// スレッドタイトルが空や「無題」などの定型文の場合に、ディレクトリ名の代替として使用されます。
+type OPTextExtractor interface {
+	// ExtractOPText は、ParseThreadHTML で変換済みのHTMLからOP本文をプレーンテキストで返します。
+	// 本文が見つからない場合は空文字を返します。
+	ExtractOPText(htmlContent string) string
+}
+
+// ThreadDateExtractor は、スレッドの作成日時（OPの投稿日時）を抽出できるアダプタが任意で実装するインターフェースです。
+// カタログには投稿日時がないため、保存先の {year}/{month}/{day} やメタデータにはこの日時が使用されます。
+type ThreadDateExtractor interface {
+	// ExtractThreadDate は、ParseThreadHTML で変換済みのHTMLからOPの投稿日時を板のタイムゾーンで返します。
+	// 日時が見つからない場合は false を返します。
+	ExtractThreadDate(htmlContent string) (time.Time, bool)
+}
+
+// PostCounter は、スレッドのレス数を数えられるアダプタが任意で実装するインターフェースです。
+// 画像のないレスだけが増えた場合もスレッドの更新として検知し、スナップショットに記録するために使用されます。
+type PostCounter interface {
+	// CountPosts は、ParseThreadHTML で変換済みのHTMLからOPを含むレス数を返します。
+	CountPosts(htmlContent string) int
+}
+
+// FallbackMediaSource は、掲示板から取得できなくなったメディアを外部の保管サイト（ミラー）から取得できるアダプタが任意で実装するインターフェースです。
+// スレッドが落ちてメディアが 404 になった場合に、代わりのURLからの取得を試みるために使用されます。
+type FallbackMediaSource interface {
+	// FallbackMediaURLs は、thread のメディア（またはサムネイル）の mediaURL を掲示板から取得できなかった場合に試すURLを、試す順に返します。
+	FallbackMediaURLs(thread model.ThreadInfo, mediaURL string) []string
+}
+
+// SavedThreadImporter は、他のツール（ふたクロ・赤福など）やブラウザで保存したスレッドのHTMLを取り込めるアダプタが任意で実装するインターフェースです。
+// 既存のローカルのアーカイブを GIBA の保存形式に変換する import コマンドで使用されます。
+type SavedThreadImporter interface {
+	// ParseSavedThread は、保存されたスレッドのHTML（UTF-8 に変換済み）とそのファイル名から、スレッドの情報と、
+	// ローカルのファイルへのリンクを掲示板上のURLに戻したHTMLを返します。この板のスレッドのHTMLでない場合は ok が false です。
+	ParseSavedThread(htmlContent, fileName string) (thread model.ThreadInfo, restored string, ok bool)
+}
+
+// CatalogRetrier は、カタログの表示設定（Cookie など）が掲示板に無視された場合に、設定を変えてカタログを取得し直せるアダプタが任意で実装するインターフェースです。
+// タイトルが切り詰められてキーワードに一致するスレッドを見逃さないように、取得したカタログの解析結果を確認するために使用されます。
+type CatalogRetrier interface {
+	// RetryCatalog は、ParseCatalog の結果 threads から表示設定が無視されたと判断した場合に、次に試す設定を client に適用して true を返します。
+	// true を返した場合、呼び出し元はカタログを取得し直して ParseCatalog からやり直します。
+	RetryCatalog(client *network.Client, threads []model.ThreadInfo) bool
+}
+
+// CatalogPager は、カタログ（検索結果など）が複数のページに分かれているアダプタが任意で実装するインターフェースです。
+type CatalogPager interface {
+	// CatalogPageURLs は、BuildCatalogURL のURLの次に取得する、2ページ目以降のカタログのURLを取得する順に返します。
+	// 呼び出し元は、スレッドが1件もないページを取得した時点で以降のページの取得をやめます。
+	CatalogPageURLs(baseURL string) ([]string, error)
+}
+
+// MetadataSidecar は、スレッドのHTMLとは別に、スレッドのディレクトリに付随するメタデータのファイルを保存するアダプタが任意で実装するインターフェースです。
+// booru の投稿のタグなど、再構成したHTMLだけでは機械的に読み取りにくい情報を保存するために使用されます。
+type MetadataSidecar interface {
+	// MetadataSidecar は、ParseThreadHTML で変換済みのHTMLから、保存するファイルの名前と内容を返します。保存するものがない場合は ok が false です。
+	MetadataSidecar(htmlContent string) (name string, data []byte, ok bool)
+}
+
+// PostFeatureExtractor は、レスごとの掲示板固有の表示（ID・おみくじ・削除・お絵かき）を抽出できるアダプタが任意で実装するインターフェースです。
+// 再構成したHTMLには表示がそのまま残りますが、外部ツールから参照できるように thread.json にも記録するために使用されます。
+type PostFeatureExtractor interface {
+	// ExtractPostFeatures は、ParseThreadHTML で変換済みのHTMLから、いずれかの表示があるレスの情報をレス番号順に返します。
+	ExtractPostFeatures(htmlContent string) []model.PostFeatures
+}
+
+// PostParser は、スレッドのレスを構造化して返せるアダプタが任意で実装するインターフェースです。
+// 二次フィルタ（post_content_filters）で、本文や投稿者をHTML全体ではなくレスごとに照合するために使用されます。
+type PostParser interface {
+	// ParsePosts は、ParseThreadHTML で変換済みのHTMLからレスをレス番号順に返します。添付ファイルのURLは threadURL を基準に解決します。
+	ParsePosts(htmlContent, threadURL string) ([]model.Post, error)
+}
+
+// MaintenanceDetector は、掲示板がメンテナンス中・障害中に HTTP 200 で返すページを判別できるアダプタが任意で実装するインターフェースです。
+// メンテナンスの表示をカタログやスレッドとして処理して、空のアーカイブを作ったりスレッドが落ちたと判断したりしないために使用されます。
+type MaintenanceDetector interface {
+	// DetectMaintenance は、取得したカタログ・スレッドのレスポンスのボディがメンテナンス中の表示の場合に、検出した表示と true を返します。
+	DetectMaintenance(body []byte) (marker string, ok bool)
+}
+
+// BoardMetaSource は、板のルール・注意書きを含むページを知っているアダプタが任意で実装するインターフェースです。
+// タスクの board_meta が設定されている場合に、板の情報を保存先の _board_meta/ に保存するために使用されます。
+type BoardMetaSource interface {
+	// BoardMetaPages は、板のURL baseURL から、板のルール・注意書きを含むページのURLを返します。
+	BoardMetaPages(baseURL string) ([]string, error)
+	// ExtractBoardNotice は、BoardMetaPages のページ（ParseThreadHTML で変換済み）から板の注意書き・お知らせをプレーンテキストで返します。
+	// 見つからない場合は空文字を返します。
+	ExtractBoardNotice(htmlContent string) string
 }
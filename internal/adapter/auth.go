@@ -0,0 +1,79 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+	"GoImageBoardArchiver/internal/network"
+	"GoImageBoardArchiver/internal/secrets"
+)
+
+// authenticate は、タスクの認証設定（auth）に従って、パスワード付き・会員制の掲示板にアクセスできる状態にします。
+// 認証設定がない場合は何もしません。各アダプタの Prepare から呼び出されます。
+// パスワードなどの値はログやエラーメッセージに含めません。
+func authenticate(client *network.Client, task config.Task) error {
+	auth := task.Auth
+	if auth == nil {
+		return nil
+	}
+
+	// "form" の場合も、ログインフォームの表示に必要なCookie（同意画面など）を先に設定できる
+	for name, rawValue := range auth.Cookies {
+		value, err := secrets.Expand(rawValue)
+		if err != nil {
+			return fmt.Errorf("%w: Cookie '%s' の値を取得できません: %w", errs.ErrAuthFailed, name, err)
+		}
+		if err := client.SetCookie(task.TargetBoardURL, &http.Cookie{Name: name, Value: value, Path: "/"}); err != nil {
+			return fmt.Errorf("%w: Cookie '%s' を設定できません: %w", errs.ErrAuthFailed, name, err)
+		}
+	}
+	if auth.Type != config.AuthTypeForm {
+		log.Printf("INFO: 認証用のCookieを %d 件設定しました", len(auth.Cookies))
+		return nil
+	}
+
+	form := url.Values{}
+	for name, rawValue := range auth.Fields {
+		value, err := secrets.Expand(rawValue)
+		if err != nil {
+			return fmt.Errorf("%w: フォームの項目 '%s' の値を取得できません: %w", errs.ErrAuthFailed, name, err)
+		}
+		form.Set(name, value)
+	}
+
+	// Prepare はコンテキストを受け取らないため、クライアントのタイムアウトで打ち切る
+	body, err := client.PostForm(context.Background(), auth.LoginURL, form)
+	if err != nil {
+		return fmt.Errorf("%w: ログインフォームの送信に失敗しました (url=%s): %w", errs.ErrAuthFailed, auth.LoginURL, err)
+	}
+	if auth.FailureText != "" && strings.Contains(body, auth.FailureText) {
+		return fmt.Errorf("%w: ログインが拒否されました。パスワードなどを確認してください (url=%s)", errs.ErrAuthFailed, auth.LoginURL)
+	}
+	if auth.SuccessCookie != "" {
+		cookies, err := client.Cookies(task.TargetBoardURL)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errs.ErrAuthFailed, err)
+		}
+		if !hasCookie(cookies, auth.SuccessCookie) {
+			return fmt.Errorf("%w: ログイン後に Cookie '%s' が発行されませんでした (url=%s)", errs.ErrAuthFailed, auth.SuccessCookie, auth.LoginURL)
+		}
+	}
+	log.Printf("INFO: 掲示板にログインしました (url=%s)", auth.LoginURL)
+	return nil
+}
+
+// hasCookie は、cookies に name のCookieが含まれるかを判定します。
+func hasCookie(cookies []*http.Cookie, name string) bool {
+	for _, c := range cookies {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package adapter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// このテストは環境変数でシークレットを渡すため、並列実行しない。
+func TestAuthenticate(t *testing.T) {
+	t.Setenv("GIBA_SECRET_BOARD_PASS", "correct")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("pass") != "correct" {
+			w.Write([]byte("パスワードが違います"))
+			return
+		}
+		if c, err := r.Cookie("consent"); err != nil || c.Value != "yes" {
+			w.Write([]byte("同意が必要です"))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		w.Write([]byte("ようこそ"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		auth    *config.AuthSettings
+		wantErr bool
+	}{
+		{name: "認証なし", auth: nil},
+		{name: "Cookieのみ", auth: &config.AuthSettings{Type: config.AuthTypeCookie, Cookies: map[string]string{"session": "${secret:board-pass}"}}},
+		{name: "フォームでログイン", auth: &config.AuthSettings{
+			Type: config.AuthTypeForm, LoginURL: server.URL + "/login",
+			Fields:        map[string]string{"pass": "${secret:board-pass}"},
+			Cookies:       map[string]string{"consent": "yes"},
+			SuccessCookie: "session", FailureText: "パスワードが違います",
+		}},
+		{name: "パスワードの誤り", wantErr: true, auth: &config.AuthSettings{
+			Type: config.AuthTypeForm, LoginURL: server.URL + "/login",
+			Fields:      map[string]string{"pass": "wrong"},
+			FailureText: "パスワードが違います",
+		}},
+		{name: "セッションCookieが発行されない", wantErr: true, auth: &config.AuthSettings{
+			Type: config.AuthTypeForm, LoginURL: server.URL + "/login",
+			Fields:        map[string]string{"pass": "${secret:board-pass}"},
+			SuccessCookie: "session",
+		}},
+		{name: "シークレットが未設定", wantErr: true, auth: &config.AuthSettings{
+			Type: config.AuthTypeForm, LoginURL: server.URL + "/login",
+			Fields: map[string]string{"pass": "${secret:missing}"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := network.NewClient(config.NetworkSettings{PerDomainIntervalMillis: map[string]int{"127.0.0.1": 1}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			task := config.Task{TargetBoardURL: server.URL + "/b/", Auth: tt.auth}
+			err = authenticate(client, task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errs.ErrAuthFailed) {
+				t.Errorf("authenticate() error = %v, want ErrAuthFailed", err)
+			}
+			if err == nil && tt.auth != nil {
+				cookies, _ := client.Cookies(task.TargetBoardURL)
+				if !hasCookie(cookies, "session") {
+					t.Errorf("session Cookie が設定されていません: %v", cookies)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,475 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+	"GoImageBoardArchiver/internal/secrets"
+)
+
+// booruPostFileName は、booru の投稿のタグなどのメタデータを保存するファイル名です（スレッドのディレクトリ直下）。
+const booruPostFileName = "post.json"
+
+var (
+	// ParseThreadHTML が生成するHTML内の投稿の画像へのリンクと、そのサムネイル
+	booruFileLinkPattern = regexp.MustCompile(`<a class="booru-file" href="([^"]+)" data-res="(\d+)">(?:<img src="([^"]+)")?`)
+	// ParseThreadHTML がHTMLに埋め込んだ、投稿のメタデータ（booruPost）の JSON
+	booruPostDataPattern = regexp.MustCompile(`(?s)<script type="application/json" id="booru-post">(.*?)</script>`)
+)
+
+// BooruAdapter は、booru 系の画像掲示板（Danbooru・Gelbooru）のタグの検索結果をアーカイブするサイトアダプタです。
+// タスクの target_board_url にはサイトのURL（例: https://danbooru.donmai.us/）、booru_settings にAPIの種類と検索するタグを指定します。
+// 検索結果の各投稿を1つのスレッドとし、投稿の画像を1件のメディアとして保存します。タグなどのメタデータは post.json に保存します。
+type BooruAdapter struct {
+	// settings は、タスクの booru_settings です。
+	settings config.BooruSettings
+	// siteURL は、サイトのURL（target_board_url）です。投稿のページのURLの生成に使用します。
+	siteURL *url.URL
+	// extensions は、タスクの media_extensions から生成したアーカイブ対象の拡張子です（nil の場合はすべて）。
+	extensions map[string]bool
+}
+
+// NewBooruAdapter は、BooruAdapterの新しいインスタンスを返します。
+func NewBooruAdapter() SiteAdapter {
+	return &BooruAdapter{}
+}
+
+// booruPost は、Danbooru・Gelbooru の投稿を共通の形式にしたものです。post.json にはこの形式で保存します。
+type booruPost struct {
+	ID         int64     `json:"id"`
+	PostURL    string    `json:"post_url"`
+	CreatedAt  time.Time `json:"created_at"`
+	Tags       []string  `json:"tags"`
+	Rating     string    `json:"rating,omitempty"`
+	Score      int       `json:"score"`
+	Source     string    `json:"source,omitempty"`
+	MD5        string    `json:"md5,omitempty"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	FileURL    string    `json:"file_url"`
+	PreviewURL string    `json:"preview_url,omitempty"`
+}
+
+// danbooruPost は、Danbooru の API（/posts.json）の投稿のうち、アーカイブに使用する項目です。
+type danbooruPost struct {
+	ID             int64  `json:"id"`
+	CreatedAt      string `json:"created_at"`
+	TagString      string `json:"tag_string"`
+	Rating         string `json:"rating"`
+	Score          int    `json:"score"`
+	Source         string `json:"source"`
+	MD5            string `json:"md5"`
+	ImageWidth     int    `json:"image_width"`
+	ImageHeight    int    `json:"image_height"`
+	FileURL        string `json:"file_url"`
+	PreviewFileURL string `json:"preview_file_url"`
+}
+
+// gelbooruPost は、Gelbooru の API（index.php?page=dapi&s=post&q=index&json=1）の投稿のうち、アーカイブに使用する項目です。
+type gelbooruPost struct {
+	ID         int64  `json:"id"`
+	CreatedAt  string `json:"created_at"` // "Sun Jan 07 01:23:45 -0600 2024"
+	Tags       string `json:"tags"`
+	Rating     string `json:"rating"`
+	Score      int    `json:"score"`
+	Source     string `json:"source"`
+	MD5        string `json:"md5"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	FileURL    string `json:"file_url"`
+	PreviewURL string `json:"preview_url"`
+}
+
+// Prepare は、booru_settings と、アーカイブ対象の拡張子を設定します。
+// APIキーを指定した場合は、サイトへのAPIのリクエストにクエリパラメータとして追加します（メディアの配信元には送信しません）。
+// タスクに認証設定（auth）がある場合は、続けてログインします。
+func (a *BooruAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	if taskConfig.BooruSettings == nil {
+		return fmt.Errorf("booru アダプタには booru_settings の api と tags を指定してください")
+	}
+	a.settings = *taskConfig.BooruSettings
+	siteURL, err := url.Parse(taskConfig.TargetBoardURL)
+	if err != nil {
+		return fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	a.siteURL = siteURL
+
+	if len(taskConfig.MediaExtensions) > 0 {
+		a.extensions = make(map[string]bool, len(taskConfig.MediaExtensions))
+		for _, ext := range taskConfig.MediaExtensions {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if !extensionPattern.MatchString(ext) {
+				return fmt.Errorf("メディアの拡張子 %q が不正です（英数字のみ指定できます）", ext)
+			}
+			a.extensions[ext] = true
+		}
+	}
+
+	if a.settings.APIKey != "" {
+		login, err := secrets.Expand(a.settings.Login)
+		if err != nil {
+			return fmt.Errorf("booru_settings の login の値を取得できません: %w", err)
+		}
+		apiKey, err := secrets.Expand(a.settings.APIKey)
+		if err != nil {
+			return fmt.Errorf("booru_settings の api_key の値を取得できません: %w", err)
+		}
+		query := url.Values{"api_key": {apiKey}}
+		if a.settings.API == config.BooruAPIGelbooru {
+			query.Set("user_id", login)
+		} else {
+			query.Set("login", login)
+		}
+		if err := client.SetQueryParams(taskConfig.TargetBoardURL, query); err != nil {
+			return err
+		}
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、Booru系のアダプタが対応している機能を返します。検索結果はスレッドではないため、レス番号はありません。
+func (a *BooruAdapter) Capabilities() Capabilities {
+	return Capabilities{JSONAPI: true, Thumbnails: true}
+}
+
+// limit は、1ページあたりの投稿数を返します。
+func (a *BooruAdapter) limit() int {
+	if a.settings.Limit > 0 {
+		return a.settings.Limit
+	}
+	return config.MaxBooruPageLimit
+}
+
+// searchURL は、検索結果の page ページ目（1始まり）のURLを返します。
+func (a *BooruAdapter) searchURL(baseURL string, page int) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	q := url.Values{}
+	q.Set("tags", a.settings.Tags)
+	q.Set("limit", strconv.Itoa(a.limit()))
+	if a.settings.API == config.BooruAPIGelbooru {
+		u.Path = path.Join(u.Path, "index.php")
+		q.Set("page", "dapi")
+		q.Set("s", "post")
+		q.Set("q", "index")
+		q.Set("json", "1")
+		q.Set("pid", strconv.Itoa(page-1))
+	} else {
+		u.Path = path.Join(u.Path, "posts.json")
+		q.Set("page", strconv.Itoa(page))
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// BuildCatalogURL は、タグの検索結果の1ページ目のURLを構築します。
+func (a *BooruAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	return a.searchURL(baseURL, 1)
+}
+
+// CatalogPageURLs は、検索結果の2ページ目から booru_settings.max_pages ページ目までのURLを返します。
+func (a *BooruAdapter) CatalogPageURLs(baseURL string) ([]string, error) {
+	var urls []string
+	for page := 2; page <= a.settings.MaxPages; page++ {
+		u, err := a.searchURL(baseURL, page)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
+
+// threadURL は、投稿を1件だけ取得するAPIの、サイトのURLからの相対URLを返します。
+func (a *BooruAdapter) threadURL(id string) string {
+	if a.settings.API == config.BooruAPIGelbooru {
+		return "index.php?page=dapi&s=post&q=index&json=1&id=" + id
+	}
+	return "posts/" + id + ".json"
+}
+
+// postURL は、投稿のページ（閲覧用）の絶対URLを返します。
+func (a *BooruAdapter) postURL(id int64) string {
+	if a.siteURL == nil {
+		return ""
+	}
+	if a.settings.API == config.BooruAPIGelbooru {
+		return a.siteURL.JoinPath("index.php").String() + "?page=post&s=view&id=" + strconv.FormatInt(id, 10)
+	}
+	return a.siteURL.JoinPath("posts", strconv.FormatInt(id, 10)).String()
+}
+
+// decodePosts は、APIのレスポンスから投稿を取り出します。画像のURLがない投稿（閲覧に権限が必要なものなど）は含めません。
+// Danbooru は投稿の配列（1件の取得ではオブジェクト）、Gelbooru は "post" に投稿の配列を含むオブジェクト（古い版では配列）を返します。
+func (a *BooruAdapter) decodePosts(body []byte) ([]booruPost, error) {
+	body = bytes.TrimPrefix(bytes.TrimSpace(body), []byte("\xef\xbb\xbf"))
+	if len(body) == 0 {
+		return nil, nil
+	}
+	if a.settings.API == config.BooruAPIGelbooru {
+		if body[0] == '{' {
+			var response struct {
+				Post json.RawMessage `json:"post"`
+			}
+			if err := json.Unmarshal(body, &response); err != nil {
+				return nil, err
+			}
+			body = bytes.TrimSpace(response.Post)
+		}
+		var raw []gelbooruPost
+		if err := unmarshalOneOrMany(body, &raw); err != nil {
+			return nil, err
+		}
+		posts := make([]booruPost, 0, len(raw))
+		for _, p := range raw {
+			created, _ := time.Parse(time.RubyDate, p.CreatedAt)
+			posts = append(posts, booruPost{
+				ID: p.ID, CreatedAt: created, Tags: strings.Fields(html.UnescapeString(p.Tags)), Rating: p.Rating, Score: p.Score,
+				Source: p.Source, MD5: p.MD5, Width: p.Width, Height: p.Height, FileURL: p.FileURL, PreviewURL: p.PreviewURL,
+			})
+		}
+		return a.completePosts(posts), nil
+	}
+
+	var raw []danbooruPost
+	if err := unmarshalOneOrMany(body, &raw); err != nil {
+		return nil, err
+	}
+	posts := make([]booruPost, 0, len(raw))
+	for _, p := range raw {
+		created, _ := time.Parse(time.RFC3339, p.CreatedAt)
+		posts = append(posts, booruPost{
+			ID: p.ID, CreatedAt: created, Tags: strings.Fields(p.TagString), Rating: p.Rating, Score: p.Score,
+			Source: p.Source, MD5: p.MD5, Width: p.ImageWidth, Height: p.ImageHeight, FileURL: p.FileURL, PreviewURL: p.PreviewFileURL,
+		})
+	}
+	return a.completePosts(posts), nil
+}
+
+// completePosts は、画像のURLがない投稿を取り除き、投稿のページのURLを設定します。
+func (a *BooruAdapter) completePosts(posts []booruPost) []booruPost {
+	complete := posts[:0]
+	for _, p := range posts {
+		if p.ID == 0 || p.FileURL == "" {
+			continue
+		}
+		p.PostURL = a.postURL(p.ID)
+		complete = append(complete, p)
+	}
+	return complete
+}
+
+// unmarshalOneOrMany は、JSON の配列、または1件のオブジェクトを v（スライスへのポインタ）に読み込みます。
+func unmarshalOneOrMany[T any](data []byte, v *[]T) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if data[0] == '[' {
+		return json.Unmarshal(data, v)
+	}
+	var one T
+	if err := json.Unmarshal(data, &one); err != nil {
+		return err
+	}
+	*v = append(*v, one)
+	return nil
+}
+
+// ParseCatalog は、タグの検索結果を解析し、各投稿をスレッドとして返します。
+// タイトルは投稿のタグ（空白区切り）、スレッドのURLは投稿を1件だけ取得するAPIのURLです。
+func (a *BooruAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	posts, err := a.decodePosts(htmlBody)
+	if err != nil {
+		return nil, fmt.Errorf("検索結果の JSON の解析に失敗しました: %w", err)
+	}
+	threads := make([]model.ThreadInfo, 0, len(posts))
+	for _, post := range posts {
+		id := strconv.FormatInt(post.ID, 10)
+		title := strings.Join(post.Tags, " ")
+		if title == "" {
+			title = fmt.Sprintf("Post %s", id)
+		}
+		date := post.CreatedAt
+		if date.IsZero() {
+			date = time.Now()
+		}
+		threads = append(threads, model.ThreadInfo{
+			ID:       id,
+			Title:    title,
+			URL:      a.threadURL(id),
+			ResCount: 1,
+			Date:     date,
+		})
+	}
+	return threads, nil
+}
+
+// ParseThreadHTML は、投稿を1件取得したAPIのレスポンスを閲覧用のHTMLに変換します。
+// 投稿のメタデータは MetadataSidecar で post.json に保存するため、HTMLに JSON として埋め込みます。
+func (a *BooruAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	posts, err := a.decodePosts(htmlBody)
+	if err != nil {
+		return "", fmt.Errorf("投稿の JSON の解析に失敗しました: %w", err)
+	}
+	if len(posts) == 0 {
+		return "", fmt.Errorf("投稿の JSON に画像のある投稿がありません")
+	}
+	post := posts[0]
+	id := strconv.FormatInt(post.ID, 10)
+	data, err := json.Marshal(post)
+	if err != nil {
+		return "", fmt.Errorf("投稿のメタデータのシリアライズに失敗しました: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>Post #" + id + "</title>\n</head>\n<body>\n")
+	sb.WriteString(`<div class="post" id="p` + id + "\">\n")
+	fmt.Fprintf(&sb, `<div class="image"><a class="booru-file" href="%s" data-res="%s">`, html.EscapeString(post.FileURL), id)
+	if post.PreviewURL != "" {
+		fmt.Fprintf(&sb, `<img src="%s" alt="Post #%s">`, html.EscapeString(post.PreviewURL), id)
+	} else {
+		sb.WriteString(html.EscapeString(path.Base(post.FileURL)))
+	}
+	sb.WriteString("</a></div>\n")
+	sb.WriteString(`<ul class="tags">`)
+	for _, tag := range post.Tags {
+		sb.WriteString("<li>" + html.EscapeString(tag) + "</li>")
+	}
+	sb.WriteString("</ul>\n<dl class=\"info\">")
+	if post.PostURL != "" {
+		fmt.Fprintf(&sb, `<dt>Post</dt><dd><a href="%s">#%s</a></dd>`, html.EscapeString(post.PostURL), id)
+	}
+	if !post.CreatedAt.IsZero() {
+		fmt.Fprintf(&sb, `<dt>Posted</dt><dd data-utc="%d">%s</dd>`, post.CreatedAt.Unix(), post.CreatedAt.Format(time.RFC3339))
+	}
+	if post.Rating != "" {
+		sb.WriteString("<dt>Rating</dt><dd>" + html.EscapeString(post.Rating) + "</dd>")
+	}
+	fmt.Fprintf(&sb, "<dt>Score</dt><dd>%d</dd>", post.Score)
+	if post.Width > 0 && post.Height > 0 {
+		fmt.Fprintf(&sb, "<dt>Size</dt><dd>%dx%d</dd>", post.Width, post.Height)
+	}
+	if post.Source != "" {
+		sb.WriteString("<dt>Source</dt><dd>" + html.EscapeString(post.Source) + "</dd>")
+	}
+	sb.WriteString("</dl>\n")
+	// json.Marshal は < > & をエスケープするため、script 要素の中にそのまま埋め込める
+	sb.WriteString(`<script type="application/json" id="booru-post">` + string(data) + "</script>\n")
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+// embeddedPost は、ParseThreadHTML でHTMLに埋め込んだ投稿のメタデータを返します。
+func embeddedPost(htmlContent string) (booruPost, bool) {
+	m := booruPostDataPattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return booruPost{}, false
+	}
+	var post booruPost
+	if err := json.Unmarshal([]byte(m[1]), &post); err != nil {
+		return booruPost{}, false
+	}
+	return post, true
+}
+
+// ExtractMediaFiles は、ParseThreadHTML で生成したHTMLから投稿の画像とサムネイルを抽出します。
+func (a *BooruAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	var media []model.MediaInfo
+	for _, m := range booruFileLinkPattern.FindAllStringSubmatch(htmlContent, -1) {
+		fileURL := html.UnescapeString(m[1])
+		u, err := url.Parse(fileURL)
+		if err != nil {
+			log.Printf("WARNING: 投稿の画像のURLを解析できません (url=%s): %v", fileURL, err)
+			continue
+		}
+		name := path.Base(u.Path)
+		if a.extensions != nil && !a.extensions[strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))] {
+			continue
+		}
+		resNumber, _ := strconv.Atoi(m[2])
+		media = append(media, model.MediaInfo{
+			URL:              fileURL,
+			ThumbnailURL:     html.UnescapeString(m[3]),
+			OriginalFilename: name,
+			ResNumber:        resNumber,
+		})
+	}
+	return media, nil
+}
+
+// ReconstructHTML は、投稿の画像とサムネイルへのリンクを保存したローカルファイルへのリンクに書き換えます。
+func (a *BooruAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	for _, mf := range mediaFiles {
+		if mf.Blocked {
+			htmlContent = removeBlockedMedia(htmlContent, mf)
+			continue
+		}
+
+		localFilename := path.Base(mf.LocalPath)
+		if mf.LocalPath == "" {
+			localFilename = mf.OriginalFilename
+			log.Printf("WARNING: LocalPathが設定されていないため、元のファイル名を使用します: %s", localFilename)
+		}
+		targetPath := localLinkPath(mf.LocalPath, "img", localFilename)
+		htmlContent = strings.ReplaceAll(htmlContent, `href="`+html.EscapeString(mf.URL)+`"`, `href="`+targetPath+`"`)
+
+		if mf.ThumbnailURL == "" {
+			continue
+		}
+		thumbLocalFilename := path.Base(mf.ThumbnailURL)
+		if mf.LocalThumbPath != "" {
+			thumbLocalFilename = path.Base(mf.LocalThumbPath)
+		}
+		thumbLocal := localLinkPath(mf.LocalThumbPath, "thumb", thumbLocalFilename)
+		htmlContent = strings.ReplaceAll(htmlContent, `src="`+html.EscapeString(mf.ThumbnailURL)+`"`, `src="`+thumbLocal+`"`)
+		if mf.IsAnimated && thumbLocal != targetPath {
+			htmlContent = markAnimatedThumbnail(htmlContent, thumbLocal, targetPath)
+		}
+	}
+	return htmlContent, nil
+}
+
+// ExtractOPText は、投稿のタグを空白区切りで返します。
+func (a *BooruAdapter) ExtractOPText(htmlContent string) string {
+	post, ok := embeddedPost(htmlContent)
+	if !ok {
+		return ""
+	}
+	return strings.Join(post.Tags, " ")
+}
+
+// ExtractThreadDate は、投稿日時を返します。
+func (a *BooruAdapter) ExtractThreadDate(htmlContent string) (time.Time, bool) {
+	post, ok := embeddedPost(htmlContent)
+	if !ok || post.CreatedAt.IsZero() {
+		return time.Time{}, false
+	}
+	return post.CreatedAt, true
+}
+
+// MetadataSidecar は、投稿のタグ・レーティング・出典などを post.json として返します。
+func (a *BooruAdapter) MetadataSidecar(htmlContent string) (string, []byte, bool) {
+	post, ok := embeddedPost(htmlContent)
+	if !ok {
+		return "", nil, false
+	}
+	data, err := json.MarshalIndent(post, "", "  ")
+	if err != nil {
+		return "", nil, false
+	}
+	return booruPostFileName, data, true
+}
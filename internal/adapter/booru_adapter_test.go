@@ -0,0 +1,171 @@
+package adapter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+const danbooruTestPosts = `[
+  {"id": 101, "created_at": "2024-01-07T01:23:45.678-05:00", "tag_string": "1girl hakurei_reimu touhou", "rating": "g", "score": 12,
+   "source": "https://example.com/art", "md5": "abc", "image_width": 800, "image_height": 600,
+   "file_url": "https://cdn.donmai.us/original/ab/cd/abc.png", "preview_file_url": "https://cdn.donmai.us/180x180/ab/cd/abc.jpg"},
+  {"id": 102, "created_at": "2024-01-07T02:00:00.000-05:00", "tag_string": "touhou", "rating": "q"}
+]`
+
+const gelbooruTestPosts = `{"@attributes": {"limit": 100, "offset": 0, "count": 1}, "post": [
+  {"id": 201, "created_at": "Sun Jan 07 01:23:45 -0600 2024", "tags": "touhou kirisame_marisa &amp;_symbol", "rating": "general", "score": 3,
+   "md5": "def", "width": 1000, "height": 1400,
+   "file_url": "https://img3.gelbooru.com/images/de/f0/def.jpg", "preview_url": "https://img3.gelbooru.com/thumbnails/de/f0/thumbnail_def.jpg"}
+]}`
+
+func newTestBooruAdapter(t *testing.T, api string) *BooruAdapter {
+	t.Helper()
+	a := NewBooruAdapter().(*BooruAdapter)
+	task := config.Task{
+		TargetBoardURL: "https://booru.example.com/",
+		BooruSettings:  &config.BooruSettings{API: api, Tags: "touhou rating:general", MaxPages: 3},
+	}
+	if err := a.Prepare(nil, task); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	return a
+}
+
+func TestBooruAdapter_CatalogURLs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		api       string
+		wantFirst string
+		wantLast  string
+	}{
+		{
+			api:       config.BooruAPIDanbooru,
+			wantFirst: "https://booru.example.com/posts.json?limit=100&page=1&tags=touhou+rating%3Ageneral",
+			wantLast:  "https://booru.example.com/posts.json?limit=100&page=3&tags=touhou+rating%3Ageneral",
+		},
+		{
+			api:       config.BooruAPIGelbooru,
+			wantFirst: "https://booru.example.com/index.php?json=1&limit=100&page=dapi&pid=0&q=index&s=post&tags=touhou+rating%3Ageneral",
+			wantLast:  "https://booru.example.com/index.php?json=1&limit=100&page=dapi&pid=2&q=index&s=post&tags=touhou+rating%3Ageneral",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.api, func(t *testing.T) {
+			t.Parallel()
+			a := newTestBooruAdapter(t, tt.api)
+			first, err := a.BuildCatalogURL("https://booru.example.com/")
+			if err != nil || first != tt.wantFirst {
+				t.Errorf("BuildCatalogURL() = %q, %v, want %q", first, err, tt.wantFirst)
+			}
+			pages, err := a.CatalogPageURLs("https://booru.example.com/")
+			if err != nil || len(pages) != 2 || pages[1] != tt.wantLast {
+				t.Errorf("CatalogPageURLs() = %q, %v, want 2 pages ending with %q", pages, err, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestBooruAdapter_ParseCatalog(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		api  string
+		body string
+		want model.ThreadInfo
+	}{
+		{
+			api:  config.BooruAPIDanbooru,
+			body: danbooruTestPosts,
+			want: model.ThreadInfo{ID: "101", Title: "1girl hakurei_reimu touhou", URL: "posts/101.json", ResCount: 1,
+				Date: time.Date(2024, 1, 7, 6, 23, 45, 678000000, time.UTC)},
+		},
+		{
+			api:  config.BooruAPIGelbooru,
+			body: gelbooruTestPosts,
+			want: model.ThreadInfo{ID: "201", Title: "touhou kirisame_marisa &_symbol", URL: "index.php?page=dapi&s=post&q=index&json=1&id=201", ResCount: 1,
+				Date: time.Date(2024, 1, 7, 7, 23, 45, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.api, func(t *testing.T) {
+			t.Parallel()
+			a := newTestBooruAdapter(t, tt.api)
+			threads, err := a.ParseCatalog([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseCatalog() error = %v", err)
+			}
+			// 画像のURLがない投稿（閲覧に権限が必要なもの）は含めない
+			if len(threads) != 1 {
+				t.Fatalf("len(threads) = %d, want 1", len(threads))
+			}
+			got := threads[0]
+			if got.ID != tt.want.ID || got.Title != tt.want.Title || got.URL != tt.want.URL || got.ResCount != tt.want.ResCount || !got.Date.Equal(tt.want.Date) {
+				t.Errorf("threads[0] = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBooruAdapter_Thread(t *testing.T) {
+	t.Parallel()
+
+	a := newTestBooruAdapter(t, config.BooruAPIDanbooru)
+	// 1件の取得ではオブジェクトが返る
+	body := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(danbooruTestPosts), "["), "]")
+	body = body[:strings.Index(body, "},")+1]
+	htmlContent, err := a.ParseThreadHTML([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+
+	media, err := a.ExtractMediaFiles(htmlContent, "https://booru.example.com/posts/101.json")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	want := model.MediaInfo{
+		URL:              "https://cdn.donmai.us/original/ab/cd/abc.png",
+		ThumbnailURL:     "https://cdn.donmai.us/180x180/ab/cd/abc.jpg",
+		OriginalFilename: "abc.png",
+		ResNumber:        101,
+	}
+	if len(media) != 1 || media[0] != want {
+		t.Fatalf("ExtractMediaFiles() = %+v, want %+v", media, want)
+	}
+
+	if got, ok := a.ExtractThreadDate(htmlContent); !ok || !got.Equal(time.Date(2024, 1, 7, 6, 23, 45, 678000000, time.UTC)) {
+		t.Errorf("ExtractThreadDate() = %v, %v", got, ok)
+	}
+	if got := a.ExtractOPText(htmlContent); got != "1girl hakurei_reimu touhou" {
+		t.Errorf("ExtractOPText() = %q", got)
+	}
+
+	name, data, ok := a.MetadataSidecar(htmlContent)
+	if !ok || name != booruPostFileName {
+		t.Fatalf("MetadataSidecar() = %q, %v", name, ok)
+	}
+	var post booruPost
+	if err := json.Unmarshal(data, &post); err != nil {
+		t.Fatal(err)
+	}
+	if post.PostURL != "https://booru.example.com/posts/101" || post.Rating != "g" || len(post.Tags) != 3 || post.Source != "https://example.com/art" {
+		t.Errorf("sidecar = %+v", post)
+	}
+
+	media[0].LocalPath = "img/abc.png"
+	media[0].LocalThumbPath = "thumb/abc.jpg"
+	got, err := a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "101"}, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	if !strings.Contains(got, `href="img/abc.png"`) || !strings.Contains(got, `src="thumb/abc.jpg"`) {
+		t.Errorf("ReconstructHTML() = %s, want local links", got)
+	}
+}
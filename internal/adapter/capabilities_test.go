@@ -0,0 +1,37 @@
+package adapter
+
+import (
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		adapter SiteAdapter
+		want    Capabilities
+	}{
+		{name: "ふたば", adapter: &FutabaAdapter{}, want: Capabilities{JSONAPI: true, Thumbnails: true, DeletionMarkers: true, ResNumbers: true}},
+		{name: "ふたば（サムネイルのない板）", adapter: &FutabaAdapter{quirks: config.FutabaBoardQuirks{NoThumbnails: true}}, want: Capabilities{JSONAPI: true, DeletionMarkers: true, ResNumbers: true}},
+		{name: "4chan", adapter: &FourchanAdapter{}, want: Capabilities{JSONAPI: true, Thumbnails: true, ResNumbers: true}},
+		{name: "5ch", adapter: &FivechAdapter{}, want: Capabilities{ResNumbers: true}},
+		{name: "汎用（サムネイルのセレクタなし）", adapter: &GenericAdapter{}, want: Capabilities{}},
+		{name: "汎用（サムネイルのセレクタあり）", adapter: &GenericAdapter{settings: config.GenericSettings{ThumbnailSelector: "img"}}, want: Capabilities{Thumbnails: true}},
+		{name: "Booru", adapter: &BooruAdapter{}, want: Capabilities{JSONAPI: true, Thumbnails: true}},
+		{name: "外部プログラム（省略時）", adapter: NewPluginAdapter("p", config.AdapterPlugin{Command: "p"}), want: Capabilities{Thumbnails: true, ResNumbers: true}},
+		{name: "外部プログラム（指定あり）", adapter: NewPluginAdapter("p", config.AdapterPlugin{Command: "p", Capabilities: []string{"json_api", "deletion_markers"}}), want: Capabilities{JSONAPI: true, DeletionMarkers: true}},
+		{name: "外部プログラム（空の指定）", adapter: NewPluginAdapter("p", config.AdapterPlugin{Command: "p", Capabilities: []string{}}), want: Capabilities{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.adapter.Capabilities(); got != tt.want {
+				t.Errorf("Capabilities() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// metaCharsetScanBytes は、<meta> の文字コード宣言を探す範囲（先頭からのバイト数）です。
+const metaCharsetScanBytes = 4096
+
+var (
+	// metaCharsetPattern は、<meta charset="..."> と <meta http-equiv="Content-Type" content="...; charset=..."> の両方に一致します。
+	metaCharsetPattern = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_\-:.]+)`)
+	// iso2022JPEscapePattern は、ISO-2022-JP で文字集合を切り替えるエスケープシーケンスです。
+	iso2022JPEscapePattern = regexp.MustCompile("\x1b\\$[@B]|\x1b\\([BJ]")
+
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// decodeHTML は、HTMLの文字コードを判定して UTF-8 の文字列に変換します。
+// fallback は、内容から判定できない場合（ASCIIのみなど）に使用するアダプタの既定の文字コードです。
+func decodeHTML(body []byte, fallback encoding.Encoding) (string, error) {
+	enc := detectCharset(body, fallback)
+	if enc == unicode.UTF8 {
+		return string(bytes.TrimPrefix(body, utf8BOM)), nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", fmt.Errorf("文字コードの変換に失敗しました: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// detectCharset は、HTMLの文字コードを判定します。同じアダプタで文字コードの異なるミラーにも対応できるよう、次の順に判定します。
+//  1. UTF-8 の BOM
+//  2. UTF-8 として正しい非ASCII文字を含む（UTF-8 に変換したミラーが元の宣言を残している場合があるため、宣言より優先）
+//  3. ISO-2022-JP のエスケープシーケンス
+//  4. <meta> の文字コード宣言
+//  5. Shift_JIS と EUC-JP のうち、変換できない文字が少なく、かな文字が多いほう
+//
+// Content-Type ヘッダーは参照しません。アダプタに渡されるのはボディのみで、raw.html.gz からの再処理ではヘッダーが残っていないためです。
+func detectCharset(body []byte, fallback encoding.Encoding) encoding.Encoding {
+	if bytes.HasPrefix(body, utf8BOM) {
+		return unicode.UTF8
+	}
+	hasNonASCII := bytes.IndexFunc(body, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0
+	if hasNonASCII && utf8.Valid(body) {
+		return unicode.UTF8
+	}
+	if !hasNonASCII && iso2022JPEscapePattern.Match(body) {
+		return japanese.ISO2022JP
+	}
+	// UTF-8 と宣言されていても正しい UTF-8 でない場合は、宣言が誤っているとみなして内容から推定する
+	if enc := declaredCharset(body); enc != nil && enc != unicode.UTF8 {
+		return enc
+	}
+	if !hasNonASCII {
+		return fallback
+	}
+	return guessJapaneseCharset(body, fallback)
+}
+
+// declaredCharset は、<meta> で宣言された文字コードを返します。宣言がないか、不明な名前の場合は nil を返します。
+func declaredCharset(body []byte) encoding.Encoding {
+	head := body
+	if len(head) > metaCharsetScanBytes {
+		head = head[:metaCharsetScanBytes]
+	}
+	m := metaCharsetPattern.FindSubmatch(head)
+	if m == nil {
+		return nil
+	}
+	enc, err := htmlindex.Get(string(m[1]))
+	if err != nil {
+		return nil
+	}
+	return enc
+}
+
+// guessJapaneseCharset は、Shift_JIS と EUC-JP のそれぞれで変換した結果を比べて、より自然なほうを返します。
+// EUC-JP の文字は Shift_JIS としても変換できることが多いものの、その場合は半角カナや無関係な漢字になり、
+// ひらがな・全角カタカナがほとんど現れないことを利用します。
+func guessJapaneseCharset(body []byte, fallback encoding.Encoding) encoding.Encoding {
+	best, bestInvalid, bestKana := fallback, -1, -1
+	for _, enc := range []encoding.Encoding{japanese.ShiftJIS, japanese.EUCJP} {
+		decoded, err := enc.NewDecoder().Bytes(body)
+		if err != nil {
+			continue
+		}
+		invalid, kana := 0, 0
+		for _, r := range string(decoded) {
+			switch {
+			case r == utf8.RuneError:
+				invalid++
+			case r >= 0x3041 && r <= 0x30FF: // ひらがな・全角カタカナ
+				kana++
+			}
+		}
+		if bestInvalid < 0 || invalid < bestInvalid || (invalid == bestInvalid && kana > bestKana) {
+			best, bestInvalid, bestKana = enc, invalid, kana
+		}
+	}
+	return best
+}
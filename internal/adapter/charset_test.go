@@ -0,0 +1,100 @@
+package adapter
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodeForTest は、UTF-8 の文字列 s を enc で符号化します。
+func encodeForTest(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("符号化に失敗しました: %v", err)
+	}
+	return b
+}
+
+func TestDecodeHTML(t *testing.T) {
+	t.Parallel()
+
+	const text = "<blockquote>ひらがなとカタカナと漢字のスレッド本文です。</blockquote>"
+	withMeta := func(charset string) string {
+		return `<html><head><meta http-equiv="Content-Type" content="text/html; charset=` + charset + `"></head><body>` + text + `</body></html>`
+	}
+
+	tests := []struct {
+		name     string
+		body     func(t *testing.T) []byte
+		fallback encoding.Encoding
+		wantEnc  encoding.Encoding
+		want     string
+	}{
+		{
+			name:     "Shift_JISの宣言",
+			body:     func(t *testing.T) []byte { return encodeForTest(t, japanese.ShiftJIS, withMeta("Shift_JIS")) },
+			fallback: japanese.ShiftJIS, wantEnc: japanese.ShiftJIS, want: withMeta("Shift_JIS"),
+		},
+		{
+			name:     "EUC-JPの宣言",
+			body:     func(t *testing.T) []byte { return encodeForTest(t, japanese.EUCJP, `<meta charset="euc-jp">`+text) },
+			fallback: japanese.ShiftJIS, wantEnc: japanese.EUCJP, want: `<meta charset="euc-jp">` + text,
+		},
+		{
+			name:     "宣言のないEUC-JPを推定",
+			body:     func(t *testing.T) []byte { return encodeForTest(t, japanese.EUCJP, text) },
+			fallback: japanese.ShiftJIS, wantEnc: japanese.EUCJP, want: text,
+		},
+		{
+			name:     "宣言のないShift_JISを推定",
+			body:     func(t *testing.T) []byte { return encodeForTest(t, japanese.ShiftJIS, text) },
+			fallback: japanese.EUCJP, wantEnc: japanese.ShiftJIS, want: text,
+		},
+		{
+			name:     "UTF-8に変換されたミラー（宣言はShift_JISのまま）",
+			body:     func(t *testing.T) []byte { return []byte(withMeta("Shift_JIS")) },
+			fallback: japanese.ShiftJIS, wantEnc: unicode.UTF8, want: withMeta("Shift_JIS"),
+		},
+		{
+			name:     "UTF-8と宣言されたShift_JIS",
+			body:     func(t *testing.T) []byte { return encodeForTest(t, japanese.ShiftJIS, withMeta("UTF-8")) },
+			fallback: japanese.ShiftJIS, wantEnc: japanese.ShiftJIS, want: withMeta("UTF-8"),
+		},
+		{
+			name:     "BOM付きUTF-8",
+			body:     func(t *testing.T) []byte { return append([]byte{0xEF, 0xBB, 0xBF}, text...) },
+			fallback: japanese.ShiftJIS, wantEnc: unicode.UTF8, want: text,
+		},
+		{
+			name:     "ISO-2022-JP",
+			body:     func(t *testing.T) []byte { return encodeForTest(t, japanese.ISO2022JP, text) },
+			fallback: japanese.ShiftJIS, wantEnc: japanese.ISO2022JP, want: text,
+		},
+		{
+			name:     "ASCIIのみは既定の文字コード",
+			body:     func(t *testing.T) []byte { return []byte("<html>No.123</html>") },
+			fallback: japanese.ShiftJIS, wantEnc: japanese.ShiftJIS, want: "<html>No.123</html>",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body := tt.body(t)
+			if got := detectCharset(body, tt.fallback); got != tt.wantEnc {
+				t.Errorf("detectCharset() = %v, want %v", got, tt.wantEnc)
+			}
+			got, err := decodeHTML(body, tt.fallback)
+			if err != nil {
+				t.Fatalf("decodeHTML() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("decodeHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
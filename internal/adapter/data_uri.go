@@ -0,0 +1,70 @@
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+// dataURIPattern は、HTML内にBase64で埋め込まれた画像（data:image/png;base64,...）を検出します。
+var dataURIPattern = regexp.MustCompile(`(?i)data:image/(png|jpeg|jpg|gif|webp|bmp|svg\+xml);base64,[A-Za-z0-9+/]+={0,2}`)
+
+// dataURIExtensions は、data URI のMIMEサブタイプと保存時の拡張子の対応です。
+var dataURIExtensions = map[string]string{
+	"jpeg":    "jpg",
+	"svg+xml": "svg",
+}
+
+// IsDataURI は、URLがHTML内に埋め込まれた data URI かを判定します。
+// data URI のメディアはダウンロードせず、DecodeDataURI で内容を取り出して保存します。
+func IsDataURI(rawURL string) bool {
+	return len(rawURL) >= 5 && strings.EqualFold(rawURL[:5], "data:")
+}
+
+// DecodeDataURI は、Base64形式の data URI をデコードし、内容を返します。
+func DecodeDataURI(uri string) ([]byte, error) {
+	header, payload, ok := strings.Cut(uri, ",")
+	if !ok || !IsDataURI(header) || !strings.HasSuffix(strings.ToLower(header), ";base64") {
+		return nil, fmt.Errorf("Base64形式の data URI ではありません")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("data URI のデコードに失敗しました: %w", err)
+	}
+	return data, nil
+}
+
+// extractDataURIMedia は、HTML内の data URI 画像をメディアとして抽出します。
+// ファイル名は内容のハッシュから生成するため（inline_<sha256の先頭8桁>.<拡張子>）、同じ画像は同じファイルになります。
+func extractDataURIMedia(htmlContent string) []model.MediaInfo {
+	var media []model.MediaInfo
+	seen := make(map[string]bool)
+
+	for _, m := range dataURIPattern.FindAllStringSubmatch(htmlContent, -1) {
+		uri := m[0]
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+
+		data, err := DecodeDataURI(uri)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		ext := strings.ToLower(m[1])
+		if mapped, ok := dataURIExtensions[ext]; ok {
+			ext = mapped
+		}
+		sum := sha256.Sum256(data)
+		media = append(media, model.MediaInfo{
+			URL:              uri,
+			OriginalFilename: fmt.Sprintf("inline_%s.%s", hex.EncodeToString(sum[:4]), ext),
+		})
+	}
+	return media
+}
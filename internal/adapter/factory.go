@@ -2,19 +2,56 @@ package adapter
 
 import (
 	"fmt"
+	"sync"
+
+	"GoImageBoardArchiver/internal/config"
 )
 
 // adapterRegistry は、サイト名とSiteAdapter実装のマッピングを保持します。
 var adapterRegistry = map[string]func() SiteAdapter{
-	"futaba": NewFutabaAdapter,
+	"booru":             NewBooruAdapter,
+	"futaba":            NewFutabaAdapter,
+	"futaba_compatible": NewFutabaCompatibleAdapter,
+	"fourchan":          NewFourchanAdapter,
+	"fivech":            NewFivechAdapter,
+	"generic":           NewGenericAdapter,
+	"komica":            NewKomicaAdapter,
+	"vichan":            NewVichanAdapter,
 }
 
+// pluginRegistry は、設定ファイルの adapter_plugins で登録した外部プログラムのサイトアダプタを保持します。
+var (
+	pluginMu       sync.RWMutex
+	pluginRegistry = map[string]config.AdapterPlugin{}
+)
+
 // GetAdapter は、指定されたサイト名に対応するSiteAdapterの新しいインスタンスを返します。
 // ファクトリパターンを使用することで、新しいサイトアダプタの追加を容易にします。
 func GetAdapter(siteName string) (SiteAdapter, error) {
-	factory, ok := adapterRegistry[siteName]
-	if !ok {
-		return nil, fmt.Errorf("サイト名 '%s' に対応するアダプタが見つかりません", siteName)
+	if factory, ok := adapterRegistry[siteName]; ok {
+		return factory(), nil
+	}
+	pluginMu.RLock()
+	plugin, ok := pluginRegistry[siteName]
+	pluginMu.RUnlock()
+	if ok {
+		return NewPluginAdapter(siteName, plugin), nil
+	}
+	return nil, fmt.Errorf("サイト名 '%s' に対応するアダプタが見つかりません", siteName)
+}
+
+// RegisterPlugins は、外部プログラムのサイトアダプタを登録します。以前に登録したものはすべて置き換えます。
+// 組み込みのアダプタと同じ名前は使用できません。
+func RegisterPlugins(plugins map[string]config.AdapterPlugin) error {
+	registry := make(map[string]config.AdapterPlugin, len(plugins))
+	for name, plugin := range plugins {
+		if _, ok := adapterRegistry[name]; ok {
+			return fmt.Errorf("アダプタ名 '%s' は組み込みのアダプタと重複しています", name)
+		}
+		registry[name] = plugin
 	}
-	return factory(), nil
+	pluginMu.Lock()
+	pluginRegistry = registry
+	pluginMu.Unlock()
+	return nil
 }
@@ -0,0 +1,363 @@
+package adapter
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// DefaultFivechMediaExtensions は、5ch アダプタが既定でアーカイブする、本文中のURLのメディアの拡張子です。
+var DefaultFivechMediaExtensions = []string{"jpg", "jpeg", "png", "gif", "webp", "mp4", "webm"}
+
+var (
+	// subject.txt の1行（1234567890.dat<>スレッドタイトル (123)）
+	fivechSubjectPattern = regexp.MustCompile(`^(\d+)\.dat<>(.*?)\s*\((\d+)\)\s*$`)
+
+	// read.cgi のHTML（現行の 5ch の形式）のレスとスレッドタイトル
+	fivechReadCGIPostPattern  = regexp.MustCompile(`(?s)<div class="post" id="(\d+)"[^>]*>.*?<span class="name">(.*?)</span>.*?<span class="date">(.*?)</span>(?:<span class="uid">(.*?)</span>)?.*?<div class="message">(.*?)</div>`)
+	fivechReadCGITitlePattern = regexp.MustCompile(`(?s)<h1 class="title">(.*?)</h1>`)
+	htmlTitlePattern          = regexp.MustCompile(`(?is)<title>(.*?)</title>`)
+
+	// 本文中のリンク・レスアンカー（>>123）・URL（先頭の h を省略した ttp:// を含む）
+	fivechLinkTagPattern = regexp.MustCompile(`(?is)<a\s[^>]*>(.*?)</a>`)
+	fivechAnchorPattern  = regexp.MustCompile(`&gt;&gt;(\d+)`)
+	fivechURLPattern     = regexp.MustCompile(`h?ttps?://[^\s<>"']+`)
+
+	// ParseThreadHTML が生成するHTML内のメディアへのリンクとレス
+	fivechMediaLinkPattern = regexp.MustCompile(`<a class="media" href="([^"]+)" data-res="(\d+)"`)
+	fivechPostPattern      = regexp.MustCompile(`<div class="post" id="p(\d+)"`)
+	fivechMessagePattern   = regexp.MustCompile(`(?s)<div class="message">(.*?)</div>`)
+	// 投稿日時（2024/01/02(火) 12:34:56.78）
+	fivechPostDatePattern = regexp.MustCompile(`(\d{4})/(\d{2})/(\d{2})\([^)]{1,3}\) ?(\d{2}):(\d{2}):(\d{2})`)
+)
+
+// FivechAdapter は、5ch/2ch 互換の掲示板のサイトアダプタです。
+// タスクの target_board_url には板のURL（例: https://egg.5ch.net/software/）を指定します。
+// subject.txt からスレッドの一覧を取得し、スレッドは dat（または read.cgi のHTML）を閲覧用のHTMLに変換して保存します。
+type FivechAdapter struct {
+	// extensions は、アーカイブ対象の拡張子です（タスクの media_extensions。未設定の場合は既定値）。
+	extensions map[string]bool
+	// useReadCGI が true の場合、スレッドのURLを read.cgi にします（fivech_settings.use_read_cgi）。
+	useReadCGI bool
+	// board は、read.cgi のURLに使用する板の名前です。
+	board string
+	// location は、投稿日時を解釈するタイムゾーンです（nil の場合は日本時間）。
+	location *time.Location
+}
+
+// NewFivechAdapter は、FivechAdapterの新しいインスタンスを返します。
+func NewFivechAdapter() SiteAdapter {
+	return &FivechAdapter{}
+}
+
+// fivechPost は、dat または read.cgi から読み取った1件のレスです。各フィールドはHTMLの断片です。
+type fivechPost struct {
+	number  int
+	name    string
+	date    string
+	message string
+}
+
+// Prepare は、アーカイブ対象の拡張子とスレッドの取得方法を設定します。タスクに認証設定（auth）がある場合は、続けてログインします。
+func (a *FivechAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	extensions := taskConfig.MediaExtensions
+	if len(extensions) == 0 {
+		extensions = DefaultFivechMediaExtensions
+	}
+	a.extensions = make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if !extensionPattern.MatchString(ext) {
+			return fmt.Errorf("メディアの拡張子 %q が不正です（英数字のみ指定できます）", ext)
+		}
+		a.extensions[ext] = true
+	}
+	a.useReadCGI = taskConfig.FivechSettings != nil && taskConfig.FivechSettings.UseReadCGI
+	if u, err := url.Parse(taskConfig.TargetBoardURL); err == nil {
+		a.board = path.Base(strings.TrimSuffix(u.Path, "/"))
+	}
+	if taskConfig.BoardTimezone != "" {
+		loc, err := time.LoadLocation(taskConfig.BoardTimezone)
+		if err != nil {
+			return fmt.Errorf("board_timezone '%s' を読み込めません: %w", taskConfig.BoardTimezone, err)
+		}
+		a.location = loc
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、5ch系のアダプタが対応している機能を返します。5ch系の掲示板はサムネイルを生成しません。
+func (a *FivechAdapter) Capabilities() Capabilities {
+	return Capabilities{ResNumbers: true}
+}
+
+// BuildCatalogURL は、板の subject.txt のURLを構築します。
+func (a *FivechAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	u.Path = path.Join(u.Path, "subject.txt")
+	return u.String(), nil
+}
+
+// ParseCatalog は、subject.txt（Shift_JIS）を解析し、スレッド情報のスライスを返します。
+// スレッドのURLは板のURLからの相対パスで、dat（dat/<スレッドID>.dat）または read.cgi（../test/read.cgi/<板>/<スレッドID>/）です。
+func (a *FivechAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	body, err := decodeHTML(htmlBody, japanese.ShiftJIS)
+	if err != nil {
+		return nil, fmt.Errorf("文字コード変換に失敗しました: %w", err)
+	}
+
+	var threads []model.ThreadInfo
+	for _, line := range strings.Split(body, "\n") {
+		m := fivechSubjectPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		id := m[1]
+		resCount, _ := strconv.Atoi(m[3])
+		title := plainText(m[2])
+		if title == "" {
+			title = fmt.Sprintf("Thread %s", id)
+		}
+		threadURL := "dat/" + id + ".dat"
+		if a.useReadCGI {
+			threadURL = "../test/read.cgi/" + a.board + "/" + id + "/"
+		}
+		threads = append(threads, model.ThreadInfo{
+			ID:       id,
+			Title:    title,
+			URL:      threadURL,
+			ResCount: resCount,
+			Date:     threadIDTime(id),
+		})
+	}
+	return threads, nil
+}
+
+// threadIDTime は、スレッドID（スレッドが立てられた UNIX 時間）から作成日時を返します。
+// スレッドIDが UNIX 時間でない場合は現在時刻を返し、スレッドの取得後に ExtractThreadDate の値で置き換えます。
+func threadIDTime(id string) time.Time {
+	if sec, err := strconv.ParseInt(id, 10, 64); err == nil && sec > 0 {
+		return time.Unix(sec, 0)
+	}
+	return time.Now()
+}
+
+// ParseThreadHTML は、dat（名前<>メール<>日付とID<>本文<>スレッドタイトル の行）または read.cgi のHTMLを、
+// 閲覧用のHTMLに変換します。どちらの形式かは内容から判定します。
+// 本文中のレスアンカーはページ内リンク（#p123）に、メディアのURLは画像の表示とリンクに変換します。
+func (a *FivechAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	body, err := decodeHTML(htmlBody, japanese.ShiftJIS)
+	if err != nil {
+		return "", fmt.Errorf("文字コード変換に失敗しました: %w", err)
+	}
+
+	var title string
+	var posts []fivechPost
+	if isFivechDat(body) {
+		title, posts = parseFivechDat(body)
+	} else {
+		title, posts = parseFivechReadCGI(body)
+	}
+	if len(posts) == 0 {
+		return "", fmt.Errorf("スレッドにレスがありません")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>" + title + "</title>\n</head>\n<body>\n")
+	sb.WriteString(`<h1 class="title">` + title + "</h1>\n<div class=\"thread\">\n")
+	for _, post := range posts {
+		no := strconv.Itoa(post.number)
+		sb.WriteString(`<div class="post" id="p` + no + `" data-res="` + no + "\">\n")
+		sb.WriteString(`<div class="meta"><span class="number">` + no + `</span> <span class="name">` + post.name + `</span> <span class="date">` + post.date + "</span></div>\n")
+		sb.WriteString(`<div class="message">` + a.formatMessage(post.message, no) + "</div>\n</div>\n")
+	}
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+// isFivechDat は、内容が dat 形式（HTMLでなく、1行目が <> 区切り）かを判定します。
+func isFivechDat(body string) bool {
+	firstLine, _, _ := strings.Cut(body, "\n")
+	return !strings.Contains(strings.ToLower(firstLine), "<html") && strings.Count(firstLine, "<>") >= 3
+}
+
+// parseFivechDat は、dat の各行をレスとして読み取り、1行目の5番目の項目をスレッドタイトルとして返します。
+func parseFivechDat(body string) (string, []fivechPost) {
+	var title string
+	var posts []fivechPost
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "<>")
+		if len(fields) < 4 {
+			continue
+		}
+		if len(posts) == 0 && len(fields) >= 5 {
+			title = strings.TrimSpace(fields[4])
+		}
+		posts = append(posts, fivechPost{
+			number:  len(posts) + 1,
+			name:    fields[0],
+			date:    fields[2],
+			message: fields[3],
+		})
+	}
+	return title, posts
+}
+
+// parseFivechReadCGI は、read.cgi のHTMLからレスとスレッドタイトルを読み取ります。
+func parseFivechReadCGI(body string) (string, []fivechPost) {
+	title := ""
+	if m := fivechReadCGITitlePattern.FindStringSubmatch(body); m != nil {
+		title = strings.TrimSpace(m[1])
+	} else if m := htmlTitlePattern.FindStringSubmatch(body); m != nil {
+		title = strings.TrimSpace(m[1])
+	}
+
+	var posts []fivechPost
+	for _, m := range fivechReadCGIPostPattern.FindAllStringSubmatch(body, -1) {
+		number, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		date := m[3]
+		if m[4] != "" {
+			date += " " + m[4]
+		}
+		posts = append(posts, fivechPost{number: number, name: m[2], date: date, message: m[5]})
+	}
+	return title, posts
+}
+
+// formatMessage は、本文の既存のリンクを外したうえで、レスアンカーとURLをリンクに変換します。
+// アーカイブ対象の拡張子のURLは、ExtractMediaFiles が抽出できる形式（class="media"）で画像とリンクにします。
+func (a *FivechAdapter) formatMessage(message, resNumber string) string {
+	// read.cgi のリンクや外部リンクの中継（jump.5ch.net/?URL）は、テキストに戻してから変換し直す
+	message = fivechLinkTagPattern.ReplaceAllString(message, "$1")
+	message = fivechAnchorPattern.ReplaceAllString(message, `<a href="#p$1">&gt;&gt;$1</a>`)
+	return fivechURLPattern.ReplaceAllStringFunc(message, func(raw string) string {
+		link := raw
+		if strings.HasPrefix(link, "ttp") {
+			link = "h" + link
+		}
+		if !a.isMediaURL(link) {
+			return `<a href="` + link + `" rel="noreferrer">` + raw + `</a>`
+		}
+		if isVideoURL(link) {
+			return `<a class="media" href="` + link + `" data-res="` + resNumber + `">` + raw + `</a>`
+		}
+		return `<a class="media" href="` + link + `" data-res="` + resNumber + `"><img src="` + link + `" alt="" loading="lazy" style="max-width: 250px; max-height: 250px;"></a>`
+	})
+}
+
+// isMediaURL は、URLのパスの拡張子がアーカイブ対象かを判定します。
+func (a *FivechAdapter) isMediaURL(rawURL string) bool {
+	extensions := a.extensions
+	if extensions == nil {
+		extensions = make(map[string]bool, len(DefaultFivechMediaExtensions))
+		for _, ext := range DefaultFivechMediaExtensions {
+			extensions[ext] = true
+		}
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return extensions[strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))]
+}
+
+// isVideoURL は、URLが画像として表示できない動画かを判定します。
+func isVideoURL(rawURL string) bool {
+	switch strings.ToLower(path.Ext(rawURL)) {
+	case ".mp4", ".webm":
+		return true
+	}
+	return false
+}
+
+// ExtractMediaFiles は、ParseThreadHTML で変換したHTMLから本文中のメディアのURLを抽出します。
+// 5ch の画像は外部のサイトにあり、掲示板側のサムネイルはありません。
+func (a *FivechAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	var media []model.MediaInfo
+	seen := make(map[string]bool)
+	for _, m := range fivechMediaLinkPattern.FindAllStringSubmatch(htmlContent, -1) {
+		mediaURL := m[1]
+		if seen[mediaURL] {
+			continue
+		}
+		seen[mediaURL] = true
+		resNumber, _ := strconv.Atoi(m[2])
+		u, err := url.Parse(mediaURL)
+		if err != nil {
+			continue
+		}
+		media = append(media, model.MediaInfo{
+			URL:              mediaURL,
+			OriginalFilename: path.Base(u.Path),
+			ResNumber:        resNumber,
+		})
+	}
+	return media, nil
+}
+
+// ReconstructHTML は、本文中のメディアへのリンクと画像を保存したローカルファイルに書き換えます。
+func (a *FivechAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	for _, mf := range mediaFiles {
+		if mf.Blocked {
+			htmlContent = removeBlockedMedia(htmlContent, mf)
+			continue
+		}
+		if mf.LocalPath == "" {
+			log.Printf("WARNING: LocalPathが設定されていないため、元のURLのままにします: %s", mf.URL)
+			continue
+		}
+		localPath := localLinkPath(mf.LocalPath, "img", path.Base(mf.LocalPath))
+		htmlContent = strings.ReplaceAll(htmlContent, `href="`+mf.URL+`"`, `href="`+localPath+`"`)
+		htmlContent = strings.ReplaceAll(htmlContent, `src="`+mf.URL+`"`, `src="`+localPath+`"`)
+	}
+	return htmlContent, nil
+}
+
+// ExtractOPText は、1番目のレスの本文をプレーンテキストで返します。
+func (a *FivechAdapter) ExtractOPText(htmlContent string) string {
+	m := fivechMessagePattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return ""
+	}
+	return plainText(m[1])
+}
+
+// ExtractThreadDate は、1番目のレスの投稿日時を板のタイムゾーン（既定は日本時間）で返します。
+func (a *FivechAdapter) ExtractThreadDate(htmlContent string) (time.Time, bool) {
+	m := fivechPostDatePattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return time.Time{}, false
+	}
+	loc := a.location
+	if loc == nil {
+		loc = futabaLocation
+	}
+	// parseFutabaDate は年を下2桁で受け取る
+	year, err := strconv.Atoi(m[1])
+	if err != nil || year < 2000 || year > 2099 {
+		return time.Time{}, false
+	}
+	return parseFutabaDate(append([]string{strconv.Itoa(year - 2000)}, m[2:]...), loc)
+}
+
+// CountPosts は、スレッドのレス数を返します。
+func (a *FivechAdapter) CountPosts(htmlContent string) int {
+	return len(fivechPostPattern.FindAllStringIndex(htmlContent, -1))
+}
@@ -0,0 +1,137 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// toShiftJIS は、テスト用の文字列を Shift_JIS に変換します。
+func toShiftJIS(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFivechAdapter_ParseCatalog(t *testing.T) {
+	t.Parallel()
+
+	subject := "1700000000.dat<>ソフトウェア総合スレ Part2 (512)\n1700000100.dat<>質問スレ (3)\n不正な行\n"
+	tests := []struct {
+		name       string
+		useReadCGI bool
+		wantURL    string
+	}{
+		{name: "dat", wantURL: "dat/1700000000.dat"},
+		{name: "read.cgi", useReadCGI: true, wantURL: "../test/read.cgi/software/1700000000/"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client, err := network.NewClient(config.NetworkSettings{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewFivechAdapter()
+			task := config.Task{TargetBoardURL: "https://egg.5ch.net/software/", FivechSettings: &config.FivechSettings{UseReadCGI: tt.useReadCGI}}
+			if err := a.Prepare(client, task); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+			threads, err := a.ParseCatalog(toShiftJIS(t, subject))
+			if err != nil {
+				t.Fatalf("ParseCatalog() error = %v", err)
+			}
+			if len(threads) != 2 {
+				t.Fatalf("len(threads) = %d, want 2", len(threads))
+			}
+			got := threads[0]
+			if got.ID != "1700000000" || got.Title != "ソフトウェア総合スレ Part2" || got.ResCount != 512 || got.URL != tt.wantURL {
+				t.Errorf("threads[0] = %+v", got)
+			}
+			if !got.Date.Equal(time.Unix(1700000000, 0)) {
+				t.Errorf("Date = %v", got.Date)
+			}
+		})
+	}
+}
+
+func TestFivechAdapter_ParseThreadHTML(t *testing.T) {
+	t.Parallel()
+
+	dat := "名無しさん<>sage<>2023/11/15(水) 07:13:20.12 ID:abcd1234<> 壁紙貼ってく <br> http://i.imgur.com/abc123.jpg <br> ttp://example.com/page <>壁紙スレ\n" +
+		"名無しさん<><>2023/11/15(水) 07:15:00.00 ID:efgh5678<> <a href=\"../test/read.cgi/software/1700000000/1\" rel=\"noopener noreferrer\" target=\"_blank\">&gt;&gt;1</a> 動画も https://example.com/v/clip.mp4 <>\n"
+	readCGI := `<html><head><title>壁紙スレ</title></head><body><h1 class="title">壁紙スレ</h1>
+<div class="post" id="1" data-date="NG" data-userid="ID:abcd1234" data-id="1"><div class="meta"><span class="number">1</span><span class="name"><b>名無しさん</b></span><span class="date">2023/11/15(水) 07:13:20.12</span><span class="uid">ID:abcd1234</span></div><div class="message"><span class="escaped"> 壁紙貼ってく <br> <a href="http://jump.5ch.net/?http://i.imgur.com/abc123.jpg" rel="nofollow" target="_blank">http://i.imgur.com/abc123.jpg</a> </span></div></div>
+<div class="post" id="2" data-date="NG" data-userid="ID:efgh5678" data-id="2"><div class="meta"><span class="number">2</span><span class="name"><b>名無しさん</b></span><span class="date">2023/11/15(水) 07:15:00.00</span><span class="uid">ID:efgh5678</span></div><div class="message"><span class="escaped"> <a href="../test/read.cgi/software/1700000000/1" rel="noopener noreferrer" target="_blank">&gt;&gt;1</a> 動画も https://example.com/v/clip.mp4 </span></div></div>
+</body></html>`
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "dat", body: dat},
+		{name: "read.cgi", body: readCGI},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := NewFivechAdapter()
+			htmlContent, err := a.ParseThreadHTML(toShiftJIS(t, tt.body))
+			if err != nil {
+				t.Fatalf("ParseThreadHTML() error = %v", err)
+			}
+			for _, want := range []string{`<title>壁紙スレ</title>`, `id="p1"`, `<a href="#p1">&gt;&gt;1</a>`} {
+				if !strings.Contains(htmlContent, want) {
+					t.Errorf("変換したHTMLに %s がありません:\n%s", want, htmlContent)
+				}
+			}
+			if strings.Contains(htmlContent, "jump.5ch.net") || strings.Contains(htmlContent, "read.cgi") {
+				t.Errorf("掲示板へのリンクが残っています:\n%s", htmlContent)
+			}
+
+			media, err := a.ExtractMediaFiles(htmlContent, "https://egg.5ch.net/software/dat/1700000000.dat")
+			if err != nil {
+				t.Fatalf("ExtractMediaFiles() error = %v", err)
+			}
+			if len(media) != 2 || media[0].URL != "http://i.imgur.com/abc123.jpg" || media[0].ResNumber != 1 ||
+				media[1].URL != "https://example.com/v/clip.mp4" || media[1].ResNumber != 2 {
+				t.Fatalf("ExtractMediaFiles() = %+v", media)
+			}
+
+			if got := a.(PostCounter).CountPosts(htmlContent); got != 2 {
+				t.Errorf("CountPosts() = %d, want 2", got)
+			}
+			if got := a.(OPTextExtractor).ExtractOPText(htmlContent); !strings.HasPrefix(got, "壁紙貼ってく") {
+				t.Errorf("ExtractOPText() = %q", got)
+			}
+			want := time.Date(2023, 11, 15, 7, 13, 20, 0, futabaLocation)
+			if got, ok := a.(ThreadDateExtractor).ExtractThreadDate(htmlContent); !ok || !got.Equal(want) {
+				t.Errorf("ExtractThreadDate() = %v, %v, want %v", got, ok, want)
+			}
+
+			media[0].LocalPath = "/archive/1700000000/img/abc123.jpg"
+			media[1].Blocked = true
+			reconstructed, err := a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "1700000000"}, media)
+			if err != nil {
+				t.Fatalf("ReconstructHTML() error = %v", err)
+			}
+			if !strings.Contains(reconstructed, `href="img/abc123.jpg"`) || !strings.Contains(reconstructed, `src="img/abc123.jpg"`) {
+				t.Errorf("メディアのリンクが書き換えられていません:\n%s", reconstructed)
+			}
+			if strings.Contains(reconstructed, `href="https://example.com/v/clip.mp4"`) {
+				t.Errorf("ブロック対象のメディアが残っています:\n%s", reconstructed)
+			}
+		})
+	}
+}
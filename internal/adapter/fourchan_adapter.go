@@ -0,0 +1,289 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// fourchanMediaBaseURL は、4chan のメディア（フルサイズとサムネイル）の配信元です。
+const fourchanMediaBaseURL = "https://i.4cdn.org"
+
+var (
+	// ParseThreadHTML が生成するHTML内のメディアへのリンク（href はファイル名のみ）
+	fourchanFileLinkPattern = regexp.MustCompile(`<a class="fileThumb" href="([^"/]+)" data-res="(\d+)"`)
+	// 各投稿の日時（UNIX時間）
+	fourchanPostTimePattern = regexp.MustCompile(`data-utc="(\d+)"`)
+	// 各投稿の要素
+	fourchanPostPattern = regexp.MustCompile(`<div class="post (?:op|reply)" id="p(\d+)"`)
+)
+
+// FourchanAdapter は、4chan の公式 JSON API（a.4cdn.org）を使用するサイトアダプタです。
+// タスクの target_board_url には API の板のURL（例: https://a.4cdn.org/g/）を指定します。
+// スレッドの JSON は ParseThreadHTML で閲覧用のHTMLに変換し、以降はHTMLとして処理します。
+type FourchanAdapter struct {
+	// extensions は、タスクの media_extensions から生成したアーカイブ対象の拡張子です（nil の場合はすべて）。
+	extensions map[string]bool
+}
+
+// NewFourchanAdapter は、FourchanAdapterの新しいインスタンスを返します。
+func NewFourchanAdapter() SiteAdapter {
+	return &FourchanAdapter{}
+}
+
+// fourchanPost は、4chan API の投稿（カタログのスレッドを含む）のうち、アーカイブに使用する項目です。
+type fourchanPost struct {
+	No          int64  `json:"no"`
+	Now         string `json:"now"`
+	Time        int64  `json:"time"`
+	Name        string `json:"name"`
+	Subject     string `json:"sub"`
+	Comment     string `json:"com"`
+	Filename    string `json:"filename"`
+	Ext         string `json:"ext"`
+	Tim         int64  `json:"tim"`
+	ThumbWidth  int    `json:"tn_w"`
+	ThumbHeight int    `json:"tn_h"`
+	FileDeleted int    `json:"filedeleted"`
+	Replies     int    `json:"replies"`
+}
+
+// hasFile は、投稿に削除されていない添付ファイルがあるかを判定します。
+func (p fourchanPost) hasFile() bool {
+	return p.Tim != 0 && p.Ext != "" && p.FileDeleted == 0
+}
+
+// mediaName は、添付ファイルの配信元でのファイル名（例: 1700000000000.jpg）を返します。
+func (p fourchanPost) mediaName() string {
+	return strconv.FormatInt(p.Tim, 10) + p.Ext
+}
+
+// Prepare は、アーカイブ対象の拡張子を設定します。タスクに認証設定（auth）がある場合は、続けてログインします。
+func (a *FourchanAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	if len(taskConfig.MediaExtensions) > 0 {
+		a.extensions = make(map[string]bool, len(taskConfig.MediaExtensions))
+		for _, ext := range taskConfig.MediaExtensions {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if !extensionPattern.MatchString(ext) {
+				return fmt.Errorf("メディアの拡張子 %q が不正です（英数字のみ指定できます）", ext)
+			}
+			a.extensions[ext] = true
+		}
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、4chanのアダプタが対応している機能を返します。削除されたレスはAPIの応答から消えるため、削除済みの印はありません。
+func (a *FourchanAdapter) Capabilities() Capabilities {
+	return Capabilities{JSONAPI: true, Thumbnails: true, ResNumbers: true}
+}
+
+// BuildCatalogURL は、板の catalog.json のURLを構築します。
+// スレッドの取得にも target_board_url を使用するため、HTMLの板のURL（boards.4chan.org）はエラーとします。
+func (a *FourchanAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	if strings.HasPrefix(u.Host, "boards.") {
+		return "", fmt.Errorf("target_board_url には API のURL（例: https://a.4cdn.org%s）を指定してください (url=%s)", u.Path, baseURL)
+	}
+	u.Path = path.Join(u.Path, "catalog.json")
+	return u.String(), nil
+}
+
+// ParseCatalog は、catalog.json（ページごとのスレッドの配列）を解析し、スレッド情報のスライスを返します。
+// スレッドのURLは板のURLからの相対パス（thread/<スレッドID>.json）です。
+func (a *FourchanAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	var pages []struct {
+		Threads []fourchanPost `json:"threads"`
+	}
+	if err := json.Unmarshal(htmlBody, &pages); err != nil {
+		return nil, fmt.Errorf("catalog.json の解析に失敗しました: %w", err)
+	}
+
+	var threads []model.ThreadInfo
+	for _, page := range pages {
+		for _, th := range page.Threads {
+			id := strconv.FormatInt(th.No, 10)
+			title := plainText(th.Subject)
+			if title == "" {
+				title = plainText(th.Comment)
+			}
+			if title == "" {
+				title = fmt.Sprintf("Thread %s", id)
+			}
+			threads = append(threads, model.ThreadInfo{
+				ID:       id,
+				Title:    title,
+				URL:      "thread/" + id + ".json",
+				ResCount: th.Replies + 1, // OPを含む
+				Date:     time.Unix(th.Time, 0),
+			})
+		}
+	}
+	return threads, nil
+}
+
+// ParseThreadHTML は、スレッドの JSON を閲覧用のHTMLに変換します。
+// メディアへのリンクは配信元のファイル名のみとし、ExtractMediaFiles でスレッドのURLから板を判定して絶対URLにします。
+// 本文（com）・名前・題名は API がHTMLとして返す値をそのまま使用します。
+func (a *FourchanAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	var thread struct {
+		Posts []fourchanPost `json:"posts"`
+	}
+	if err := json.Unmarshal(htmlBody, &thread); err != nil {
+		return "", fmt.Errorf("スレッドの JSON の解析に失敗しました: %w", err)
+	}
+	if len(thread.Posts) == 0 {
+		return "", fmt.Errorf("スレッドの JSON に投稿がありません")
+	}
+
+	op := thread.Posts[0]
+	title := op.Subject
+	if title == "" {
+		title = "No." + strconv.FormatInt(op.No, 10)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>" + title + "</title>\n</head>\n<body>\n")
+	sb.WriteString(`<div class="thread" id="t` + strconv.FormatInt(op.No, 10) + "\">\n")
+	for i, post := range thread.Posts {
+		class := "reply"
+		if i == 0 {
+			class = "op"
+		}
+		no := strconv.FormatInt(post.No, 10)
+		sb.WriteString(`<div class="post ` + class + `" id="p` + no + "\">\n")
+		sb.WriteString(`<div class="postInfo">`)
+		if post.Subject != "" {
+			sb.WriteString(`<span class="subject">` + post.Subject + `</span> `)
+		}
+		sb.WriteString(`<span class="name">` + post.Name + `</span> `)
+		sb.WriteString(`<span class="dateTime" data-utc="` + strconv.FormatInt(post.Time, 10) + `">` + post.Now + `</span> `)
+		sb.WriteString(`<span class="postNum">No.` + no + "</span></div>\n")
+		if post.hasFile() {
+			name := post.mediaName()
+			thumb := strconv.FormatInt(post.Tim, 10) + "s.jpg"
+			fmt.Fprintf(&sb, `<div class="file"><a class="fileThumb" href="%s" data-res="%s" title="%s%s">`+
+				`<img src="%s" width="%d" height="%d" alt="%s"></a></div>`+"\n",
+				name, no, html.EscapeString(html.UnescapeString(post.Filename)), post.Ext, thumb, post.ThumbWidth, post.ThumbHeight, name)
+		}
+		sb.WriteString(`<blockquote class="postMessage">` + post.Comment + "</blockquote>\n</div>\n")
+	}
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+// ExtractMediaFiles は、ParseThreadHTML で生成したHTMLから添付ファイルを抽出します。
+// 板はスレッドのURL（https://a.4cdn.org/<板>/thread/<スレッドID>.json）から判定します。
+func (a *FourchanAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	u, err := url.Parse(threadURL)
+	if err != nil {
+		return nil, fmt.Errorf("スレッドURLの解析に失敗しました: %w", err)
+	}
+	board := strings.Split(strings.Trim(u.Path, "/"), "/")[0]
+	if board == "" {
+		return nil, fmt.Errorf("スレッドURLから板を判定できません (url=%s)", threadURL)
+	}
+
+	var media []model.MediaInfo
+	seen := make(map[string]bool)
+	for _, m := range fourchanFileLinkPattern.FindAllStringSubmatch(htmlContent, -1) {
+		name := m[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		ext := path.Ext(name)
+		if a.extensions != nil && !a.extensions[strings.ToLower(strings.TrimPrefix(ext, "."))] {
+			continue
+		}
+		resNumber, _ := strconv.Atoi(m[2])
+		media = append(media, model.MediaInfo{
+			URL:              fourchanMediaBaseURL + "/" + board + "/" + name,
+			ThumbnailURL:     fourchanMediaBaseURL + "/" + board + "/" + strings.TrimSuffix(name, ext) + "s.jpg",
+			OriginalFilename: name,
+			ResNumber:        resNumber,
+		})
+	}
+	return media, nil
+}
+
+// ReconstructHTML は、メディアへのリンクとサムネイルを保存したローカルファイルへのリンクに書き換えます。
+func (a *FourchanAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	for _, mf := range mediaFiles {
+		if mf.Blocked {
+			htmlContent = removeBlockedMedia(htmlContent, mf)
+			continue
+		}
+
+		name := path.Base(mf.URL)
+		thumbName := path.Base(mf.ThumbnailURL)
+		localFilename := path.Base(mf.LocalPath)
+		if mf.LocalPath == "" {
+			localFilename = name
+			log.Printf("WARNING: LocalPathが設定されていないため、元のファイル名を使用します: %s", name)
+		}
+		targetPath := localLinkPath(mf.LocalPath, "img", localFilename)
+		thumbLocalFilename := thumbName
+		if mf.LocalThumbPath != "" {
+			thumbLocalFilename = path.Base(mf.LocalThumbPath)
+		}
+		thumbLocal := localLinkPath(mf.LocalThumbPath, "thumb", thumbLocalFilename)
+
+		htmlContent = strings.ReplaceAll(htmlContent, `href="`+name+`"`, `href="`+targetPath+`"`)
+		htmlContent = strings.ReplaceAll(htmlContent, `src="`+thumbName+`"`, `src="`+thumbLocal+`"`)
+		if mf.IsAnimated && thumbLocal != targetPath {
+			htmlContent = markAnimatedThumbnail(htmlContent, thumbLocal, targetPath)
+		}
+	}
+	// 本文中のレスへのリンク（#p123）は ParseThreadHTML で付与した id="p123" をそのまま指す
+	return htmlContent, nil
+}
+
+// ExtractOPText は、最初の投稿の本文をプレーンテキストで返します。
+func (a *FourchanAdapter) ExtractOPText(htmlContent string) string {
+	m := opBlockquotePattern.FindStringSubmatch(htmlContent)
+	if len(m) < 2 {
+		return ""
+	}
+	return plainText(m[1])
+}
+
+// ExtractThreadDate は、最初の投稿の日時を返します。4chan API の日時は UNIX 時間のため、板のタイムゾーンに依存しません。
+func (a *FourchanAdapter) ExtractThreadDate(htmlContent string) (time.Time, bool) {
+	m := fourchanPostTimePattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// CountPosts は、スレッドの投稿数（OPを含む）を返します。
+func (a *FourchanAdapter) CountPosts(htmlContent string) int {
+	return len(fourchanPostPattern.FindAllStringIndex(htmlContent, -1))
+}
+
+// plainText は、HTMLの断片の改行タグを空白に置き換え、タグと実体参照を取り除いて連続する空白を1つにまとめます。
+func plainText(fragment string) string {
+	text := regexp.MustCompile(`(?i)<br\s*/?>`).ReplaceAllString(fragment, " ")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	return strings.Join(strings.Fields(text), " ")
+}
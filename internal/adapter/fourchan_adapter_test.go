@@ -0,0 +1,155 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+const fourchanTestCatalog = `[
+  {"page": 1, "threads": [
+    {"no": 100, "sub": "Desktop &amp; Battlestation", "com": "post your setups", "replies": 41, "time": 1700000000},
+    {"no": 200, "com": "no subject<br>second line", "replies": 0, "time": 1700000100}
+  ]},
+  {"page": 2, "threads": [{"no": 300, "replies": 3, "time": 1700000200}]}
+]`
+
+const fourchanTestThread = `{"posts": [
+  {"no": 100, "now": "11/14/23(Tue)22:13:20", "time": 1700000000, "name": "Anonymous", "sub": "Desktop &amp; Battlestation",
+   "com": "post your setups", "filename": "my \"desk\"", "ext": ".jpg", "tim": 1699999999001, "tn_w": 250, "tn_h": 140, "replies": 2},
+  {"no": 101, "now": "11/14/23(Tue)22:15:00", "time": 1700000100, "name": "Anonymous",
+   "com": "<a href=\"#p100\" class=\"quotelink\">&gt;&gt;100</a><br>nice", "filename": "clip", "ext": ".webm", "tim": 1699999999002, "tn_w": 125, "tn_h": 70},
+  {"no": 102, "now": "11/14/23(Tue)22:16:00", "time": 1700000200, "name": "Anonymous", "com": "deleted file", "filename": "x", "ext": ".png", "tim": 1699999999003, "filedeleted": 1}
+]}`
+
+func TestFourchanAdapter_ParseCatalog(t *testing.T) {
+	t.Parallel()
+
+	threads, err := NewFourchanAdapter().ParseCatalog([]byte(fourchanTestCatalog))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	if len(threads) != 3 {
+		t.Fatalf("len(threads) = %d, want 3", len(threads))
+	}
+	tests := []struct {
+		id, title, url string
+		resCount       int
+	}{
+		{id: "100", title: "Desktop & Battlestation", url: "thread/100.json", resCount: 42},
+		{id: "200", title: "no subject second line", url: "thread/200.json", resCount: 1},
+		{id: "300", title: "Thread 300", url: "thread/300.json", resCount: 4},
+	}
+	for i, tt := range tests {
+		got := threads[i]
+		if got.ID != tt.id || got.Title != tt.title || got.URL != tt.url || got.ResCount != tt.resCount {
+			t.Errorf("threads[%d] = %+v, want %+v", i, got, tt)
+		}
+	}
+	if !threads[0].Date.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Date = %v", threads[0].Date)
+	}
+
+	if _, err := NewFourchanAdapter().ParseCatalog([]byte(`<html>`)); err == nil {
+		t.Error("JSONでないカタログでエラーになりません")
+	}
+}
+
+func TestFourchanAdapter_BuildCatalogURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		baseURL string
+		want    string
+		wantErr bool
+	}{
+		{name: "APIのURL", baseURL: "https://a.4cdn.org/g/", want: "https://a.4cdn.org/g/catalog.json"},
+		{name: "末尾のスラッシュなし", baseURL: "https://a.4cdn.org/g", want: "https://a.4cdn.org/g/catalog.json"},
+		{name: "HTMLの板のURL", baseURL: "https://boards.4chan.org/g/", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := NewFourchanAdapter().BuildCatalogURL(tt.baseURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildCatalogURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BuildCatalogURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFourchanAdapter_Thread(t *testing.T) {
+	t.Parallel()
+
+	a := NewFourchanAdapter()
+	htmlContent, err := a.ParseThreadHTML([]byte(fourchanTestThread))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+	if strings.Contains(htmlContent, `my "desk"`) {
+		t.Error("ファイル名がエスケープされていません")
+	}
+
+	media, err := a.ExtractMediaFiles(htmlContent, "https://a.4cdn.org/g/thread/100.json")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	if len(media) != 2 {
+		t.Fatalf("len(media) = %d, want 2 (削除済みのファイルは対象外): %+v", len(media), media)
+	}
+	if media[0].URL != "https://i.4cdn.org/g/1699999999001.jpg" || media[0].ThumbnailURL != "https://i.4cdn.org/g/1699999999001s.jpg" || media[0].ResNumber != 100 {
+		t.Errorf("media[0] = %+v", media[0])
+	}
+	if media[1].URL != "https://i.4cdn.org/g/1699999999002.webm" || media[1].ResNumber != 101 {
+		t.Errorf("media[1] = %+v", media[1])
+	}
+
+	if got := a.(PostCounter).CountPosts(htmlContent); got != 3 {
+		t.Errorf("CountPosts() = %d, want 3", got)
+	}
+	if got := a.(OPTextExtractor).ExtractOPText(htmlContent); got != "post your setups" {
+		t.Errorf("ExtractOPText() = %q", got)
+	}
+	if got, ok := a.(ThreadDateExtractor).ExtractThreadDate(htmlContent); !ok || !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("ExtractThreadDate() = %v, %v", got, ok)
+	}
+
+	media[0].LocalPath = "/archive/100/img/1699999999001.jpg"
+	media[0].LocalThumbPath = "/archive/100/thumb/1699999999001s.jpg"
+	media[1].Blocked = true
+	reconstructed, err := a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "100"}, nil)
+	if err != nil || reconstructed != htmlContent {
+		t.Fatalf("メディアなしの ReconstructHTML() がHTMLを変更しました: %v", err)
+	}
+	reconstructed, err = a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "100"}, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	for _, want := range []string{`href="img/1699999999001.jpg"`, `src="thumb/1699999999001s.jpg"`, `href="#p100"`, `id="p101"`} {
+		if !strings.Contains(reconstructed, want) {
+			t.Errorf("再構成したHTMLに %s がありません", want)
+		}
+	}
+	if strings.Contains(reconstructed, "1699999999002") {
+		t.Error("ブロック対象のメディアが残っています")
+	}
+
+	if _, err := a.ParseThreadHTML([]byte(`{"posts": []}`)); err == nil {
+		t.Error("投稿のないスレッドでエラーになりません")
+	}
+}
+
+func TestGetAdapter_Fourchan(t *testing.T) {
+	t.Parallel()
+
+	if _, err := GetAdapter("fourchan"); err != nil {
+		t.Errorf("GetAdapter(\"fourchan\") error = %v", err)
+	}
+}
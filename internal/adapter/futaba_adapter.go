@@ -1,15 +1,14 @@
 package adapter
 
 import (
-	"bytes"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,29 +17,137 @@ import (
 	"GoImageBoardArchiver/internal/network"
 
 	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
 )
 
 var (
 	// ふたばちゃんねるの正規メディアファイル名を検出 (13桁以上の数字 + 任意の 's' + 拡張子)
-	futabaMediaPattern = regexp.MustCompile(`(\d{13,})(s?)\.(jpg|jpeg|png|webp|gif|webm|mp4|mp3|wav)`)
+	futabaMediaPattern = regexp.MustCompile(`(\d{13,})(s?)\.(` + strings.Join(DefaultFutabaMediaExtensions, "|") + `)`)
 	// スレッドID抽出用 (res/123456789.htm)
 
 	// カタログからのスレッド情報抽出用 (簡易的な正規表現)
 	// href属性内に res/<数字>.htm が含まれるものを抽出。シングル/ダブルクォート、前置きの ./ や パスも許容
 	catalogLinkPattern = regexp.MustCompile(`href=["']?([^"'>]*?res/(\d+)\.htm)["']?`)
+
+	// スレッドHTML内の最初の本文（OP）。ふたばではレス本文は <blockquote> で囲まれる
+	opBlockquotePattern = regexp.MustCompile(`(?is)<blockquote[^>]*>(.*?)</blockquote>`)
+	htmlTagPattern      = regexp.MustCompile(`<[^>]*>`)
+
+	// レス番号（No.123）と、スレッド内のレスを指すページ内リンク（href="#r123" など）
+	postNumberPattern = regexp.MustCompile(`No\.(\d+)\b`)
+	inPageLinkPattern = regexp.MustCompile(`href=(["']?)#(?:r|delcheck)(\d+)(["']?)`)
+	// JSON API から生成したHTMLの、レス番号付きの添付ファイルへのリンク
+	futabaResMediaLinkPattern = regexp.MustCompile(`href="([^"]+)" target="_blank" data-res="(\d+)"`)
+	// 付与済みのアンカー（id="p123"）
+	existingAnchorPattern = regexp.MustCompile(`id="p(\d+)"`)
+	// 投稿日時（25/11/18(火)12:34:56）。曜日は全角・半角のどちらも許容する
+	futabaPostDatePattern = regexp.MustCompile(`(\d{2})/(\d{2})/(\d{2})\([^)]{1,3}\)(\d{2}):(\d{2}):(\d{2})`)
 )
 
+// futabaLocation は、ふたばの投稿日時の既定のタイムゾーン（日本時間）です。
+// タイムゾーンデータベースがない環境（Windows など）では固定のオフセットを使用します。
+var futabaLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Tokyo"); err == nil {
+		return loc
+	}
+	return time.FixedZone("JST", 9*60*60)
+}()
+
 // FutabaAdapter は、ふたば☆ちゃんねる固有の解析ロジックを実装します。
-type FutabaAdapter struct{}
+type FutabaAdapter struct {
+	// mediaPattern は、タスクの media_extensions から生成したメディアファイル名のパターンです（nil の場合は既定値）。
+	mediaPattern *regexp.Regexp
+	// keepAds が true の場合、ReconstructHTML で広告・トラッキング要素を取り除きません（タスクの strip_ads: false）。
+	keepAds bool
+	// location は、投稿日時を解釈するタイムゾーンです（nil の場合は日本時間）。
+	location *time.Location
+	// useJSON が true の場合、カタログとスレッドを JSON API から取得します（タスクの futaba_settings.use_json_api）。
+	useJSON bool
+	// boardPath は、板のサイト内のパス（例: /b）です。JSON API の添付ファイルのパスを補完するために使用します。
+	boardPath string
+	// boardHost は、板のホスト名（例: may.2chan.net）です。
+	boardHost string
+	// fallbackSources は、掲示板から取得できなかったメディアを探す保管サイトのURLのテンプレートです（タスクの futaba_settings.fallback_sources）。
+	fallbackSources []string
+	// uploaderPattern は、タスクの media_extensions から生成したアップローダのファイル名のパターンです（nil の場合は既定値）。
+	uploaderPattern *regexp.Regexp
+	// skipUploader が true の場合、アップローダのファイル（fu1234567.jpg など）を取得しません（タスクの futaba_settings.skip_uploader_files）。
+	skipUploader bool
+	// compatible が true の場合、ふたば互換アダプタ（site_adapter: "futaba_compatible"）としてタスクの futaba_compatible_settings を使用します。
+	compatible bool
+	// cgiName・srcDir・thumbDir・cookieName は、ふたば互換の板のスクリプト名・添付ファイルとサムネイルのディレクトリ名・カタログの Cookie 名です。
+	// 空の場合はふたばの値（futaba.php・src・thumb・cxyl）を使用します。
+	cgiName, srcDir, thumbDir, cookieName string
+	// autoCatalog が true の場合、カタログの表示設定を futabaAutoCatalogShapes から自動で選択します（futaba_catalog_settings.auto）。
+	autoCatalog bool
+	// autoShape は、自動で選択したカタログの表示設定の futabaAutoCatalogShapes 内の位置です。
+	autoShape int
+	// catalogBoardURL は、カタログの表示設定の Cookie を設定する板のURLです。
+	catalogBoardURL string
+	// lastCatalogJSON は、直前の ParseCatalog で解析したカタログが JSON API のレスポンスだったかどうかです。
+	lastCatalogJSON bool
+	// quirks は、板ごとのパス・サムネイル・スクリプトのホストの違いです（タスクの futaba_board_quirks）。
+	quirks config.FutabaBoardQuirks
+}
 
 // NewFutabaAdapter は、FutabaAdapterの新しいインスタンスを返します。
 func NewFutabaAdapter() SiteAdapter {
 	return &FutabaAdapter{}
 }
 
-// Prepare は、ふたばちゃんねる用の準備として 'cxyl' Cookie を設定します。
+// Prepare は、ふたばちゃんねる用の準備として 'cxyl' Cookie を設定し、アーカイブ対象の拡張子と広告の削除を設定します。
+// ふたば互換アダプタでは futaba_compatible_settings のスクリプト名・ディレクトリ名を適用し、Cookie は板のホストに対して設定します。
+// タスクに認証設定（auth）がある場合は、続けてログインします。
 func (a *FutabaAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	if len(taskConfig.MediaExtensions) > 0 {
+		pattern, err := buildFutabaMediaPattern(taskConfig.MediaExtensions)
+		if err != nil {
+			return err
+		}
+		a.mediaPattern = pattern
+		if a.uploaderPattern, err = buildFutabaUploaderPattern(taskConfig.MediaExtensions); err != nil {
+			return err
+		}
+	}
+	a.keepAds = taskConfig.StripAds != nil && !*taskConfig.StripAds
+	if taskConfig.BoardTimezone != "" {
+		loc, err := time.LoadLocation(taskConfig.BoardTimezone)
+		if err != nil {
+			return fmt.Errorf("board_timezone '%s' を読み込めません: %w", taskConfig.BoardTimezone, err)
+		}
+		a.location = loc
+	}
+	a.useJSON = taskConfig.FutabaSettings != nil && taskConfig.FutabaSettings.UseJSONAPI
+	a.skipUploader = taskConfig.FutabaSettings != nil && taskConfig.FutabaSettings.SkipUploaderFiles
+	if taskConfig.FutabaSettings != nil {
+		a.fallbackSources = taskConfig.FutabaSettings.FallbackSources
+	}
+	if u, err := url.Parse(taskConfig.TargetBoardURL); err == nil {
+		a.boardPath = strings.TrimSuffix(u.Path, "/")
+		a.boardHost = u.Hostname()
+	}
+	if a.compatible && taskConfig.FutabaCompatibleSettings != nil {
+		s := taskConfig.FutabaCompatibleSettings
+		a.cgiName, a.cookieName = s.CGIName, s.CookieName
+		a.srcDir, a.thumbDir = strings.Trim(s.SrcDir, "/"), strings.Trim(s.ThumbDir, "/")
+	}
+	if taskConfig.FutabaBoardQuirks != nil {
+		a.quirks = *taskConfig.FutabaBoardQuirks
+	}
+
+	if a.cookieName == "-" {
+		return authenticate(client, taskConfig)
+	}
+	if s := taskConfig.FutabaCatalogSettings; s != nil && s.Auto {
+		// 以前の巡回で有効だった表示設定から始める
+		a.autoCatalog = true
+		a.catalogBoardURL = taskConfig.TargetBoardURL
+		a.autoShape = rememberedFutabaAutoShape(a.autoShapeKey())
+		if err := a.setCatalogCookie(client, taskConfig.TargetBoardURL, futabaAutoCatalogShapes[a.autoShape]); err != nil {
+			return err
+		}
+		return authenticate(client, taskConfig)
+	}
+
 	// FutabaCatalogSettingsが設定されていない場合はデフォルト値を使用
 	if taskConfig.FutabaCatalogSettings == nil {
 		log.Println("INFO: FutabaCatalogSettingsが設定されていないため、デフォルト値(9x100x20)を使用します")
@@ -65,35 +172,74 @@ func (a *FutabaAdapter) Prepare(client *network.Client, taskConfig config.Task)
 		titleLength = 20
 	}
 
-	cookieValue := fmt.Sprintf("%dx%dx%dx0x0", cols, rows, titleLength)
+	shape := futabaCatalogShape{Cols: cols, Rows: rows, TitleLength: titleLength}
+	if err := a.setCatalogCookie(client, taskConfig.TargetBoardURL, shape); err != nil {
+		return err
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、ふたばちゃんねるのアダプタが対応している機能を返します。
+// futaba_board_quirks でサムネイルのない板とした場合は、サムネイルに対応していないものとします。
+func (a *FutabaAdapter) Capabilities() Capabilities {
+	return Capabilities{JSONAPI: true, Thumbnails: !a.quirks.NoThumbnails, DeletionMarkers: true, ResNumbers: true}
+}
+
+// setCatalogCookie は、カタログの表示設定 shape の Cookie（ふたばでは cxyl）を板に設定します。
+func (a *FutabaAdapter) setCatalogCookie(client *network.Client, boardURL string, shape futabaCatalogShape) error {
 	cookie := &http.Cookie{
 		Name:   "cxyl",
-		Value:  cookieValue,
+		Value:  shape.cookieValue(),
 		Path:   "/",
 		Domain: ".2chan.net",
 	}
+	if a.compatible {
+		// クローンの板は 2chan.net 以外のホストで動作するため、板のホストに対して設定する
+		cookie.Domain = ""
+		if a.cookieName != "" {
+			cookie.Name = a.cookieName
+		}
+	}
 	log.Println("DEBUG: futaba_adapterが生成したCookieを設定します:", cookie)
-	return client.SetCookie(taskConfig.TargetBoardURL, cookie)
+	return client.SetCookie(boardURL, cookie)
 }
 
-// BuildCatalogURL は、ふたばのカタログURLを構築します。
+// BuildCatalogURL は、ふたばのカタログURL（futaba.php?mode=cat）を構築します。JSON API を使用する場合は futaba.php?mode=json です。
+// ふたば互換アダプタでは futaba_compatible_settings.cgi_name のスクリプトを使用します。
+// 板のスクリプトが別のホストにある場合（futaba_board_quirks.cgi_host）は、そのホストのスクリプトを使用します。
 func (a *FutabaAdapter) BuildCatalogURL(baseURL string) (string, error) {
 	u, err := url.Parse(baseURL)
 	if err != nil {
 		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
 	}
-	u.Path = path.Join(u.Path, "futaba.php")
+	if a.quirks.CGIHost != "" {
+		u.Host = a.quirks.CGIHost
+	}
+	u.Path = path.Join(u.Path, a.scriptName())
 	q := url.Values{}
-	q.Set("mode", "cat")
+	if a.useJSON {
+		q.Set("mode", "json")
+	} else {
+		q.Set("mode", "cat")
+	}
 	u.RawQuery = q.Encode()
 	return u.String(), nil
 }
 
 // ParseCatalog は、カタログHTMLを解析し、スレッド情報のスライスを返します。
 // 正規表現を用いてリンクと、その周辺のテキスト（タイトルとして使用）を抽出します。
+// JSON API のレスポンスの場合は parseJSONCatalog で解析し、JSON に対応していない板が返したHTMLはそのまま解析します。
 func (a *FutabaAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
-	// Shift_JIS -> UTF-8 変換
-	utf8BodyStr, err := decodeShiftJIS(htmlBody)
+	a.lastCatalogJSON = isFutabaJSON(htmlBody)
+	if a.lastCatalogJSON {
+		return a.parseJSONCatalog(htmlBody)
+	}
+	if a.useJSON {
+		log.Println("INFO: 板が JSON API に対応していないため、カタログをHTMLから取得します")
+	}
+
+	// Shift_JIS（ミラーによっては UTF-8・EUC-JP） -> UTF-8 変換
+	utf8BodyStr, err := decodeHTML(htmlBody, japanese.ShiftJIS)
 	if err != nil {
 		return nil, fmt.Errorf("文字コード変換に失敗しました: %w", err)
 	}
@@ -148,16 +294,21 @@ func (a *FutabaAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error
 			Title:    title,
 			URL:      href,
 			ResCount: 0,
-			Date:     time.Now(),
+			Date:     time.Now(), // カタログには投稿日時がないため仮の値。スレッドHTMLの取得後に ExtractThreadDate の値で置き換える
 		})
 	}
 
 	return threads, nil
 }
 
-// ParseThreadHTML は、スレッドHTMLを Shift_JIS -> UTF-8 に変換して文字列として返します。
+// ParseThreadHTML は、スレッドHTMLを UTF-8 に変換して文字列として返します。
+// ふたばは Shift_JIS ですが、文字コードの異なるミラーにも対応できるよう、文字コードは内容から判定します。
+// JSON API のレスポンス（res/<スレッドID>.json）は renderJSONThread でHTMLに変換します。
 func (a *FutabaAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
-	return decodeShiftJIS(htmlBody)
+	if isFutabaJSON(htmlBody) {
+		return a.renderJSONThread(htmlBody)
+	}
+	return decodeHTML(htmlBody, japanese.ShiftJIS)
 }
 
 // ExtractMediaFiles は、スレッドHTML文字列から正規表現を用いてメディアリンクを抽出します。
@@ -170,19 +321,41 @@ func (a *FutabaAdapter) ExtractMediaFiles(htmlContent string, threadURL string)
 	// <a ... href="src/123456789.jpg" ...> のようなパターンを探す
 	// 引用符はシングル/ダブル両対応
 	hrefPattern := regexp.MustCompile(`href=["']?([^"']+)["']?`)
-	matches := hrefPattern.FindAllStringSubmatch(htmlContent, -1)
+	var rawHrefs []string
+	for _, m := range hrefPattern.FindAllStringSubmatch(htmlContent, -1) {
+		if len(m) >= 2 {
+			rawHrefs = append(rawHrefs, m[1])
+		}
+	}
+	linkCount := len(rawHrefs)
+	// 遅延読み込み（data-src）や srcset にのみ書かれたメディアも対象にする
+	rawHrefs = append(rawHrefs, extractLazyMediaURLs(htmlContent)...)
+	// JSON API から生成したHTMLでは、リンクのレス番号が分かる
+	resNumbers := make(map[string]int)
+	for _, m := range futabaResMediaLinkPattern.FindAllStringSubmatch(htmlContent, -1) {
+		resNumbers[m[1]], _ = strconv.Atoi(m[2])
+	}
 
 	var media []model.MediaInfo
 	seen := make(map[string]bool)
 
-	for _, m := range matches {
-		if len(m) < 2 {
+	for i, rawHref := range rawHrefs {
+		// ファイル名がふたばのメディア形式かチェック
+		m := a.mediaFilePattern().FindStringSubmatch(filepath.Base(rawHref))
+		if m == nil {
+			// 本文からリンクされたアップローダのファイル（fu1234567.jpg など）
+			if i < linkCount {
+				if hrefURL, err := url.Parse(rawHref); err == nil {
+					if mf, ok := a.uploaderMedia(base.ResolveReference(hrefURL), resNumbers[rawHref]); ok && !seen[mf.URL] {
+						seen[mf.URL] = true
+						media = append(media, mf)
+					}
+				}
+			}
 			continue
 		}
-		rawHref := m[1]
-
-		// ファイル名がふたばのメディア形式かチェック
-		if !futabaMediaPattern.MatchString(filepath.Base(rawHref)) {
+		// 遅延読み込みの画像の多くはサムネイルであり、サムネイルはフルサイズのURLから導出するため対象外とする
+		if i >= linkCount && m[2] == "s" {
 			continue
 		}
 
@@ -200,23 +373,15 @@ func (a *FutabaAdapter) ExtractMediaFiles(htmlContent string, threadURL string)
 		seen[absString] = true
 
 		// サムネイルURLの推測
-		// ふたばの標準: src/1234567890.jpg -> thumb/1234567890s.jpg
+		// ふたばの標準: src/1234567890.jpg -> thumb/1234567890s.jpg（板ごとの違いは futaba_board_quirks）
 		originalFilename := filepath.Base(absURL.Path)
 		thumbnailURL := ""
-
-		// ファイル名から拡張子を分離
-		ext := filepath.Ext(originalFilename)
-		nameWithoutExt := strings.TrimSuffix(originalFilename, ext)
-
-		// サムネイル用のファイル名を生成 (例: 1234567890 -> 1234567890s)
-		// ふたばのサムネイルは常にjpgなので拡張子を.jpgに固定
-		thumbFilename := nameWithoutExt + "s.jpg"
-
-		// サムネイルのURLを構築
-		thumbPath := strings.Replace(absURL.Path, "/src/", "/thumb/", 1)
-		thumbPath = strings.Replace(thumbPath, originalFilename, thumbFilename, 1)
-		thumbURL, _ := url.Parse(thumbPath)
-		if thumbURL != nil {
+		if !a.hasBoardThumbnail(originalFilename) {
+			// 音声・文書などサムネイルが生成されない形式
+			media = append(media, model.MediaInfo{URL: absString, OriginalFilename: originalFilename, ResNumber: resNumbers[rawHref]})
+			continue
+		}
+		if thumbURL, err := url.Parse(a.thumbnailPath(absURL.Path)); err == nil {
 			thumbnailURL = base.ResolveReference(thumbURL).String()
 		}
 
@@ -224,14 +389,25 @@ func (a *FutabaAdapter) ExtractMediaFiles(htmlContent string, threadURL string)
 			URL:              absString,
 			OriginalFilename: originalFilename,
 			ThumbnailURL:     thumbnailURL,
-			// ResNumber: レス番号の抽出は正規表現だと困難なため、0とするか別途解析が必要
-			ResNumber: 0,
+			// ResNumber: HTMLからのレス番号の抽出は正規表現だと困難なため、JSON API から生成したHTML以外では0とする
+			ResNumber: resNumbers[rawHref],
 		})
 	}
 
+	// HTMLに埋め込まれた画像（data URI）も img/ に書き出せるよう、メディアとして扱う
+	media = append(media, extractDataURIMedia(htmlContent)...)
+
 	return media, nil
 }
 
+// mediaFilePattern は、アーカイブ対象のメディアファイル名のパターンを返します。
+func (a *FutabaAdapter) mediaFilePattern() *regexp.Regexp {
+	if a.mediaPattern != nil {
+		return a.mediaPattern
+	}
+	return futabaMediaPattern
+}
+
 // ReconstructHTML は、収集済みメディアのURL→ローカルファイル名のマッピングに基づいてリンクを書き換えます。
 // 文字列置換を使用します。
 func (a *FutabaAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
@@ -240,13 +416,39 @@ func (a *FutabaAdapter) ReconstructHTML(htmlContent string, thread model.ThreadI
 	htmlContent = regexp.MustCompile(`(?is)<script.*?>.*?</script>`).ReplaceAllString(htmlContent, "")
 	htmlContent = regexp.MustCompile(`(?is)<style.*?>.*?</style>`).ReplaceAllString(htmlContent, "")
 	htmlContent = regexp.MustCompile(`(?i)<link\s+rel=["']?stylesheet["']?[^>]*>`).ReplaceAllString(htmlContent, "")
+	// 広告枠・バナー・トラッキングピクセルの削除
+	if !a.keepAds {
+		htmlContent = stripAdBlocks(htmlContent, futabaAdPatterns)
+	}
 
 	// 2. リンクの書き換え
 	// 単純な文字列置換を行う。URLの一部が他のURLに含まれる場合のリスクはあるが、
 	// ふたばのファイル名はユニーク性が高いため衝突しにくい。
 	for _, mf := range mediaFiles {
+		// ブロック対象のメディア（広告・スパム画像）は、リンクと画像ごと取り除く
+		if mf.Blocked {
+			htmlContent = removeBlockedMedia(htmlContent, mf)
+			continue
+		}
+
+		// 埋め込み画像は data URI 全体を保存したファイルへのリンクに置き換える
+		if IsDataURI(mf.URL) {
+			if mf.LocalPath != "" {
+				htmlContent = strings.ReplaceAll(htmlContent, mf.URL, localLinkPath(mf.LocalPath, "img", filepath.Base(mf.LocalPath)))
+			}
+			continue
+		}
+
 		filename := filepath.Base(mf.URL)
 
+		// アップローダのファイルはリンクの属性ごと置き換える（ファイル名が src/ の相対パスと衝突しないように先に処理する）
+		if a.isUploaderFile(mf) {
+			if mf.LocalPath != "" {
+				htmlContent = replaceUploaderLinks(htmlContent, filename, localLinkPath(mf.LocalPath, "img", filepath.Base(mf.LocalPath)))
+			}
+			continue
+		}
+
 		// LocalPathが設定されていない場合のfallback: 元のファイル名を使用
 		localFilename := filepath.Base(mf.LocalPath)
 		if localFilename == "" || localFilename == "." {
@@ -257,39 +459,28 @@ func (a *FutabaAdapter) ReconstructHTML(htmlContent string, thread model.ThreadI
 		// フルサイズ画像へのリンク (href=".../123.jpg") -> href="img/123.jpg"
 		// 注意: 単純置換だと誤爆の可能性があるため、ファイル名単位で置換する
 		// ただし、URL全体で置換するのが最も安全
-		targetPath := filepath.ToSlash(filepath.Join("img", localFilename))
+		targetPath := localLinkPath(mf.LocalPath, "img", localFilename)
 
 		// 完全なURLを置換 (https://may.2chan.net/b/src/123.jpg)
 		htmlContent = strings.ReplaceAll(htmlContent, mf.URL, targetPath)
 
 		// 絶対パスを置換 (/b/src/123.jpg)
-		absPath := "/b/src/" + filename
+		absPath := a.srcSitePath() + "/" + filename
 		htmlContent = strings.ReplaceAll(htmlContent, absPath, targetPath)
 
 		// 相対パスを置換 (src/123.jpg)
-		relPath := "src/" + filename
+		relPath := a.srcDirName() + "/" + filename
 		htmlContent = strings.ReplaceAll(htmlContent, relPath, targetPath)
 
 		// サムネイル (thumb/...) -> thumb/localFilename
-		// LocalThumbPathが設定されている場合はそれを使用、なければ推測
-		var thumbLocalFilename string
+		// LocalThumbPathが設定されている場合はそれを使用、なければ推測（123.jpg -> 123s.jpg）
+		thumbFilename := a.thumbnailName(filename)
+		thumbLocalFilename := thumbFilename
 		if mf.LocalThumbPath != "" {
 			thumbLocalFilename = filepath.Base(mf.LocalThumbPath)
-		} else {
-			// 推測: 123.jpg -> 123s.jpg
-			// ふたばのサムネイルは常にjpgなので拡張子を.jpgに固定
-			ext := filepath.Ext(filename)
-			nameWithoutExt := strings.TrimSuffix(filename, ext)
-			thumbLocalFilename = nameWithoutExt + "s.jpg"
 		}
 
-		thumbLocal := filepath.ToSlash(filepath.Join("thumb", thumbLocalFilename))
-
-		// サムネイルの元のパターンを置換
-		// ふたばのサムネイルは常にjpgなので拡張子を.jpgに固定
-		ext := filepath.Ext(filename)
-		nameWithoutExt := strings.TrimSuffix(filename, ext)
-		thumbFilename := nameWithoutExt + "s.jpg"
+		thumbLocal := localLinkPath(mf.LocalThumbPath, "thumb", thumbLocalFilename)
 
 		// ThumbnailURLが設定されている場合は、完全なURLを置換
 		if mf.ThumbnailURL != "" {
@@ -297,15 +488,26 @@ func (a *FutabaAdapter) ReconstructHTML(htmlContent string, thread model.ThreadI
 		}
 
 		// 絶対パスを置換 (/b/thumb/123s.jpg)
-		absThumbPath := "/b/thumb/" + thumbFilename
+		absThumbPath := a.thumbSitePath() + "/" + thumbFilename
 		htmlContent = strings.ReplaceAll(htmlContent, absThumbPath, thumbLocal)
 
 		// 相対パスを置換 (thumb/123s.jpg)
-		relThumbPath := "thumb/" + thumbFilename
+		relThumbPath := a.thumbDirName() + "/" + thumbFilename
 		htmlContent = strings.ReplaceAll(htmlContent, relThumbPath, thumbLocal)
+
+		// アニメーション画像の静止サムネイルには、マウスオーバーでフルサイズに切り替える属性を付与
+		if mf.IsAnimated && thumbLocal != targetPath {
+			htmlContent = markAnimatedThumbnail(htmlContent, thumbLocal, targetPath)
+		}
 	}
 
-	// 3. ヘッダーの調整
+	// 3. 遅延読み込みの画像（data-src, srcset）をローカルのファイルで表示できるようにする
+	htmlContent = resolveLazyImages(htmlContent)
+
+	// 4. レスごとのアンカーの付与とスレッド内リンクの書き換え
+	htmlContent = addPostAnchors(htmlContent, thread.ID)
+
+	// 5. ヘッダーの調整
 	// meta charsetなどをUTF-8に
 	htmlContent = regexp.MustCompile(`(?i)<meta\s+http-equiv=["']?Content-Type["']?[^>]*>`).ReplaceAllString(htmlContent, "")
 	htmlContent = regexp.MustCompile(`(?i)<meta\s+charset=["']?[^"'>]+["']?>`).ReplaceAllString(htmlContent, "")
@@ -320,11 +522,177 @@ func (a *FutabaAdapter) ReconstructHTML(htmlContent string, thread model.ThreadI
 	return htmlContent, nil
 }
 
-func decodeShiftJIS(b []byte) (string, error) {
-	reader := transform.NewReader(bytes.NewReader(b), japanese.ShiftJIS.NewDecoder())
-	decoded, err := io.ReadAll(reader)
-	if err != nil {
-		return "", err
+// ExtractOPText は、スレッドHTMLの最初の <blockquote> からOP本文のテキストを抽出します。
+// 改行タグは空白に置き換え、HTMLタグと実体参照を取り除いて連続する空白を1つにまとめます。
+func (a *FutabaAdapter) ExtractOPText(htmlContent string) string {
+	m := opBlockquotePattern.FindStringSubmatch(htmlContent)
+	if len(m) < 2 {
+		return ""
+	}
+	return plainText(m[1])
+}
+
+// ExtractThreadDate は、スレッドHTMLの最初の投稿日時（OPの投稿日時）を板のタイムゾーンで返します。
+// ふたばの日時は "25/11/18(火)12:34:56" の形式で、年は下2桁です。
+func (a *FutabaAdapter) ExtractThreadDate(htmlContent string) (time.Time, bool) {
+	m := futabaPostDatePattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return time.Time{}, false
+	}
+	return parseFutabaDate(m[1:], a.boardLocation())
+}
+
+// boardLocation は、投稿日時を解釈するタイムゾーン（board_timezone、既定は日本時間）を返します。
+func (a *FutabaAdapter) boardLocation() *time.Location {
+	if a.location != nil {
+		return a.location
+	}
+	return futabaLocation
+}
+
+// parseFutabaDate は、年（下2桁）・月・日・時・分・秒の文字列から日時を作成します。
+// time.Date は範囲外の値を繰り上げるため、作成した日時の各要素が元の値と一致しない場合は不正な日時とみなします。
+func parseFutabaDate(fields []string, loc *time.Location) (time.Time, bool) {
+	var v [6]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return time.Time{}, false
+		}
+		v[i] = n
+	}
+	t := time.Date(2000+v[0], time.Month(v[1]), v[2], v[3], v[4], v[5], 0, loc)
+	if t.Year() != 2000+v[0] || int(t.Month()) != v[1] || t.Day() != v[2] ||
+		t.Hour() != v[3] || t.Minute() != v[4] || t.Second() != v[5] {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// markAnimatedThumbnail は、src が thumbLocal の img タグにアニメーション画像であることを示す
+// data属性と、マウスオーバー時にフルサイズ（animatedPath）へ切り替えるインラインハンドラを付与します。
+func markAnimatedThumbnail(htmlContent, thumbLocal, animatedPath string) string {
+	for _, quote := range []string{`"`, `'`} {
+		src := "src=" + quote + thumbLocal + quote
+		marked := src + ` data-animated-src="` + animatedPath + `" class="giba-animated"` +
+			` onmouseover="this.src=this.dataset.animatedSrc"` +
+			` onmouseout="this.src='` + thumbLocal + `'"`
+		htmlContent = strings.ReplaceAll(htmlContent, src, marked)
+	}
+	return htmlContent
+}
+
+// PostAnchorID は、再構成HTML内でレスに付与するアンカーのIDを返します（例: "p123"）。
+// 外部のメモや検索インデックスから index.htm#p123 の形式でレスを直接参照できます。
+func PostAnchorID(resNumber string) string {
+	return "p" + resNumber
+}
+
+// addPostAnchors は、各レスの最初のレス番号（No.123）の直前に id="p123" のアンカーを挿入し、
+// スレッド内のレスを指すリンクをそのアンカーへのリンクに書き換えます。
+// 引用（>No.123）やタグ内の文字列はレス本体ではないため対象外とし、既にアンカーがあるレスには追加しません。
+func addPostAnchors(htmlContent, threadID string) string {
+	anchored := make(map[string]bool)
+	for _, m := range existingAnchorPattern.FindAllStringSubmatch(htmlContent, -1) {
+		anchored[m[1]] = true
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(htmlContent) + 1024)
+	last := 0
+	forEachPostNumber(htmlContent, func(start int, resNum string) {
+		if anchored[resNum] {
+			return
+		}
+		anchored[resNum] = true
+		sb.WriteString(htmlContent[last:start])
+		sb.WriteString(`<a id="` + PostAnchorID(resNum) + `" class="giba-anchor"></a>`)
+		last = start
+	})
+	sb.WriteString(htmlContent[last:])
+	htmlContent = sb.String()
+
+	// ページ内リンク（#r123, #delcheck123）と、自スレッドのURLへのリンク（res/<スレッドID>.htm#123）を #p123 に統一
+	htmlContent = inPageLinkPattern.ReplaceAllString(htmlContent, `href=${1}#p${2}${3}`)
+	if threadID != "" {
+		threadLinkPattern := regexp.MustCompile(`href=(["']?)[^"'\s>]*res/` + regexp.QuoteMeta(threadID) + `\.htm#(?:r|p|delcheck)?(\d+)(["']?)`)
+		htmlContent = threadLinkPattern.ReplaceAllString(htmlContent, `href=${1}#p${2}${3}`)
+	}
+	return htmlContent
+}
+
+// forEachPostNumber は、HTML内のレス本体のレス番号（No.123）ごとに、その開始位置とレス番号で fn を呼び出します。
+// 引用（>No.123）やタグ内の文字列は対象外です。同じレス番号が複数回現れる場合はそれぞれ呼び出します。
+func forEachPostNumber(htmlContent string, fn func(start int, resNum string)) {
+	scanned, inTag := 0, false
+	for _, loc := range postNumberPattern.FindAllStringSubmatchIndex(htmlContent, -1) {
+		start, resNum := loc[0], htmlContent[loc[2]:loc[3]]
+
+		// 前回の位置からの差分だけを走査して、タグの内側かどうかを追跡する
+		segment := htmlContent[scanned:start]
+		if lt, gt := strings.LastIndex(segment, "<"), strings.LastIndex(segment, ">"); lt != gt {
+			inTag = lt > gt
+		}
+		scanned = start
+
+		if inTag || isQuote(htmlContent[:start]) {
+			continue
+		}
+		fn(start, resNum)
+	}
+}
+
+// CountPosts は、スレッドHTMLのレス数（OPを含む）を返します。
+// 引用（>No.123）は数えず、同じレス番号は1件として数えます。
+func (a *FutabaAdapter) CountPosts(htmlContent string) int {
+	seen := make(map[string]bool)
+	forEachPostNumber(htmlContent, func(_ int, resNum string) {
+		seen[resNum] = true
+	})
+	return len(seen)
+}
+
+// isQuote は、直前のテキストが引用記号（>）で終わっているかを判定します。
+func isQuote(before string) bool {
+	before = strings.TrimRight(before, " ")
+	return strings.HasSuffix(before, "&gt;") || strings.HasSuffix(before, "＞")
+}
+
+// localLinkPath は、再構成HTML内で使用するスレッドディレクトリからの相対リンクを返します。
+// ローカルファイルが img/ または thumb/ に保存されている場合はそのディレクトリを使用し、
+// それ以外の場合は defaultDir を使用します。サムネイルのみ・フルサイズのみのアーカイブで
+// リンク先を実在するファイルに合わせるためのものです。
+func localLinkPath(localPath, defaultDir, filename string) string {
+	dir := defaultDir
+	if localPath != "" {
+		switch parent := filepath.Base(filepath.Dir(localPath)); parent {
+		case "img", "thumb":
+			dir = parent
+		}
+	}
+	return filepath.ToSlash(filepath.Join(dir, filename))
+}
+
+// removeBlockedMedia は、ブロック対象のメディアを指すリンク（中の画像を含む）と画像をHTMLから取り除きます。
+// メディアはフルサイズのファイル名（サムネイルはその 's' 付きの名前）で照合します。
+func removeBlockedMedia(htmlContent string, mf model.MediaInfo) string {
+	if IsDataURI(mf.URL) {
+		// 埋め込み画像はファイル名で照合できないため、データ自体を取り除く
+		return strings.ReplaceAll(htmlContent, mf.URL, "")
+	}
+	names := []string{filepath.Base(mf.URL)}
+	if mf.ThumbnailURL != "" {
+		names = append(names, filepath.Base(mf.ThumbnailURL))
+	}
+	for _, name := range names {
+		if name == "" || name == "." || name == "/" {
+			continue
+		}
+		quoted := regexp.QuoteMeta(name)
+		link := regexp.MustCompile(`(?is)<a\s[^>]*href=["']?[^"'>\s]*` + quoted + `["']?[^>]*>.*?</a>`)
+		htmlContent = link.ReplaceAllString(htmlContent, "")
+		img := regexp.MustCompile(`(?i)<(?:img|source)\s[^>]*(?:src|srcset)=["']?[^"'>]*` + quoted + `[^>]*>`)
+		htmlContent = img.ReplaceAllString(htmlContent, "")
 	}
-	return string(decoded), nil
+	return htmlContent
 }
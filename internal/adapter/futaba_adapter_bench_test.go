@@ -0,0 +1,88 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+// ベンチマークは testdata に依存せず、合成したHTMLを使用します。
+// 実行例: go test ./internal/adapter -run '^$' -bench . -benchmem
+
+const benchThreadURL = "https://may.2chan.net/b/res/1000000000.htm"
+
+// benchCatalogHTML は、指定件数のスレッドを含むカタログHTMLを生成します。
+func benchCatalogHTML(threads int) []byte {
+	var sb strings.Builder
+	sb.WriteString(`<html><head><title>catalog</title></head><body><table id="cattable"><tr>`)
+	for i := 0; i < threads; i++ {
+		id := 1000000000 + i
+		fmt.Fprintf(&sb, `<td><a href="res/%d.htm" target="_blank"><img src="/b/cat/%ds.jpg"></a><br><small>thread title %d<br>line</small><br><font size=2>%d</font></td>`, id, id, i, i%100)
+	}
+	sb.WriteString(`</tr></table></body></html>`)
+	return []byte(sb.String())
+}
+
+// benchThreadHTML は、指定レス数・すべてのレスに画像を含むスレッドHTMLを生成します。
+func benchThreadHTML(posts int) string {
+	var sb strings.Builder
+	sb.WriteString(`<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><style>body{}</style><script>var x=1;</script></head><body>`)
+	for i := 0; i < posts; i++ {
+		id := 1700000000000 + int64(i)
+		fmt.Fprintf(&sb, `<table border=0><tr><td class=rtd><span class="cnw">No.%d</span><br><a href="/b/src/%d.jpg" target="_blank"><img src="/b/thumb/%ds.jpg" border=0 align=left width=250 height=188></a><blockquote>post body %d</blockquote></td></tr></table>`, id, id, id, i)
+	}
+	sb.WriteString(`</body></html>`)
+	return sb.String()
+}
+
+func BenchmarkFutabaAdapter_ParseCatalog(b *testing.B) {
+	adapter := NewFutabaAdapter()
+	body := benchCatalogHTML(500)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.ParseCatalog(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFutabaAdapter_ExtractMediaFiles(b *testing.B) {
+	adapter := NewFutabaAdapter()
+	htmlContent := benchThreadHTML(1000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(htmlContent)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.ExtractMediaFiles(htmlContent, benchThreadURL); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFutabaAdapter_ReconstructHTML(b *testing.B) {
+	adapter := NewFutabaAdapter()
+	htmlContent := benchThreadHTML(1000)
+	mediaFiles, err := adapter.ExtractMediaFiles(htmlContent, benchThreadURL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range mediaFiles {
+		mediaFiles[i].LocalPath = "img/" + mediaFiles[i].OriginalFilename
+	}
+	thread := model.ThreadInfo{ID: "1000000000", Title: "bench", URL: benchThreadURL}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(htmlContent)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.ReconstructHTML(htmlContent, thread, mediaFiles); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
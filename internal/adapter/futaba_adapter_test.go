@@ -174,3 +174,371 @@ func TestFutabaAdapter_ExtractMediaFiles_EdgeCases(t *testing.T) {
 		t.Error(".mp4 ファイルが見つかりませんでした。")
 	}
 }
+
+// --- Test for localLinkPath ---
+
+func TestLocalLinkPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		localPath  string
+		defaultDir string
+		filename   string
+		want       string
+	}{
+		{"img配下", filepath.Join("archive", "img", "1.jpg"), "img", "1.jpg", "img/1.jpg"},
+		{"サムネイルのみ", filepath.Join("archive", "thumb", "1s.jpg"), "img", "1s.jpg", "thumb/1s.jpg"},
+		{"サムネイル位置にフルサイズ", filepath.Join("archive", "img", "1.jpg"), "thumb", "1.jpg", "img/1.jpg"},
+		{"不明なディレクトリ", "./media/1.jpg", "img", "1.jpg", "img/1.jpg"},
+		{"LocalPath未設定", "", "thumb", "1s.jpg", "thumb/1s.jpg"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := localLinkPath(tt.localPath, tt.defaultDir, tt.filename); got != tt.want {
+				t.Errorf("localLinkPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// --- Test for addPostAnchors ---
+
+func TestAddPostAnchors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		html     string
+		threadID string
+		want     string
+	}{
+		{
+			name: "レスごとにアンカーを付与",
+			html: `<span class="cno">No.100</span><span class="cno">No.101</span>`,
+			want: `<span class="cno"><a id="p100" class="giba-anchor"></a>No.100</span><span class="cno"><a id="p101" class="giba-anchor"></a>No.101</span>`,
+		},
+		{
+			name: "引用とタグ内は対象外",
+			html: `<span title="No.5">No.100</span><blockquote>&gt;No.100 &gt; No.99</blockquote>`,
+			want: `<span title="No.5"><a id="p100" class="giba-anchor"></a>No.100</span><blockquote>&gt;No.100 &gt; No.99</blockquote>`,
+		},
+		{
+			name: "付与済みのアンカーは重複させない",
+			html: `<a id="p100" class="giba-anchor"></a>No.100`,
+			want: `<a id="p100" class="giba-anchor"></a>No.100`,
+		},
+		{
+			name:     "スレッド内リンクの書き換え",
+			html:     `<a href="#r101">a</a><a href='#delcheck102'>b</a><a href="https://may.2chan.net/b/res/100.htm#r103">c</a><a href="res/999.htm#r1">d</a>`,
+			threadID: "100",
+			want:     `<a href="#p101">a</a><a href='#p102'>b</a><a href="#p103">c</a><a href="res/999.htm#r1">d</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := addPostAnchors(tt.html, tt.threadID); got != tt.want {
+				t.Errorf("addPostAnchors() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// --- Test for data URI ---
+
+func TestFutabaAdapter_DataURIMedia(t *testing.T) {
+	t.Parallel()
+
+	// 1x1の透過GIF
+	const gif = "data:image/gif;base64,R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7"
+	htmlContent := `<img src="` + gif + `"><img src='` + gif + `'><img src="data:image/png;base64,!!!">`
+
+	adapter := NewFutabaAdapter()
+	media, err := adapter.ExtractMediaFiles(htmlContent, "https://may.2chan.net/b/res/1.htm")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	if len(media) != 1 || media[0].URL != gif || !IsDataURI(media[0].URL) {
+		t.Fatalf("ExtractMediaFiles() = %+v, want 1件の埋め込み画像", media)
+	}
+	if name := media[0].OriginalFilename; !strings.HasPrefix(name, "inline_") || !strings.HasSuffix(name, ".gif") {
+		t.Errorf("OriginalFilename = %q, want inline_<hash>.gif", name)
+	}
+	if data, err := DecodeDataURI(media[0].URL); err != nil || string(data[:6]) != "GIF89a" {
+		t.Errorf("DecodeDataURI() = %q, %v", data, err)
+	}
+
+	media[0].LocalPath = filepath.Join("archive", "img", media[0].OriginalFilename)
+	got, err := adapter.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "1"}, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	if strings.Contains(got, gif) || strings.Count(got, "img/"+media[0].OriginalFilename) != 2 {
+		t.Errorf("ReconstructHTML() = %q, want data URI をローカルファイルへのリンクに置換", got)
+	}
+}
+
+// --- Test for lazy-loading / srcset ---
+
+func TestBestSrcsetCandidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		srcset string
+		want   string
+	}{
+		{"a.jpg 320w, b.jpg 1280w, c.jpg 640w", "b.jpg"},
+		{"a.jpg, b.jpg 2x", "b.jpg"},
+		{"only.jpg", "only.jpg"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.srcset, func(t *testing.T) {
+			t.Parallel()
+			if got := bestSrcsetCandidate(tt.srcset); got != tt.want {
+				t.Errorf("bestSrcsetCandidate(%q) = %q, want %q", tt.srcset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFutabaAdapter_LazyMedia(t *testing.T) {
+	t.Parallel()
+
+	htmlContent := `<img src="/img/blank.gif" data-src="/b/thumb/1700000000001s.jpg" loading="lazy">` +
+		`<img srcset="https://cdn.example.com/1700000000002_small.jpg 320w, /b/src/1700000000002.png 1280w">` +
+		`<picture><source data-srcset="/b/src/1700000000003.webp 2x"><img src="x.gif"></picture>`
+
+	adapter := NewFutabaAdapter()
+	media, err := adapter.ExtractMediaFiles(htmlContent, "https://may.2chan.net/b/res/1.htm")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	var urls []string
+	for _, m := range media {
+		urls = append(urls, m.URL)
+	}
+	want := []string{"https://may.2chan.net/b/src/1700000000002.png", "https://may.2chan.net/b/src/1700000000003.webp"}
+	if strings.Join(urls, " ") != strings.Join(want, " ") {
+		t.Fatalf("ExtractMediaFiles() URLs = %v, want %v", urls, want)
+	}
+
+	got := resolveLazyImages(`<img src="/img/blank.gif" data-src="thumb/1s.jpg" loading="lazy">` +
+		`<img srcset="https://cdn.example.com/2_small.jpg 320w, img/2.png 1280w">` +
+		`<source data-srcset="https://cdn.example.com/3.webp 2x">`)
+	wantHTML := `<img src="thumb/1s.jpg" data-src="thumb/1s.jpg" loading="lazy">` +
+		`<img srcset="img/2.png 1280w">` +
+		`<source>`
+	if got != wantHTML {
+		t.Errorf("resolveLazyImages() =\n%s\nwant\n%s", got, wantHTML)
+	}
+}
+
+func TestFutabaAdapter_MediaExtensions(t *testing.T) {
+	t.Parallel()
+
+	htmlContent := `<a href="/b/src/1700000000001.jpg">a</a>` +
+		`<a href="/b/src/1700000000002.flac">b</a>` +
+		`<a href="/b/src/1700000000003.pdf">c</a>`
+
+	tests := []struct {
+		name       string
+		extensions []string
+		want       []string
+	}{
+		{name: "既定値", extensions: nil, want: []string{"1700000000001.jpg", "1700000000002.flac", "1700000000003.pdf"}},
+		{name: "許可リスト", extensions: []string{"JPG", ".flac"}, want: []string{"1700000000001.jpg", "1700000000002.flac"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			adapter := &FutabaAdapter{}
+			if tt.extensions != nil {
+				pattern, err := buildFutabaMediaPattern(tt.extensions)
+				if err != nil {
+					t.Fatalf("buildFutabaMediaPattern() error = %v", err)
+				}
+				adapter.mediaPattern = pattern
+			}
+			media, err := adapter.ExtractMediaFiles(htmlContent, "https://may.2chan.net/b/res/1.htm")
+			if err != nil {
+				t.Fatalf("ExtractMediaFiles() error = %v", err)
+			}
+			var got []string
+			for _, m := range media {
+				got = append(got, m.OriginalFilename)
+				if wantThumb := m.OriginalFilename == "1700000000001.jpg"; (m.ThumbnailURL != "") != wantThumb {
+					t.Errorf("%s: ThumbnailURL = %q", m.OriginalFilename, m.ThumbnailURL)
+				}
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("ExtractMediaFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := buildFutabaMediaPattern([]string{"jpg|.*"}); err == nil {
+		t.Error("buildFutabaMediaPattern() は不正な拡張子を拒否すべきです")
+	}
+}
+
+func TestRemoveBlockedMedia(t *testing.T) {
+	t.Parallel()
+
+	htmlContent := `<a href="/b/src/1700000000001.jpg" target="_blank"><img src="/b/thumb/1700000000001s.jpg"></a>` +
+		`<a href="/b/src/1700000000002.jpg" target="_blank">1700000000002.jpg</a>`
+	media := []model.MediaInfo{
+		{URL: "https://may.2chan.net/b/src/1700000000001.jpg", ThumbnailURL: "https://may.2chan.net/b/thumb/1700000000001s.jpg", Blocked: true},
+		{URL: "https://may.2chan.net/b/src/1700000000002.jpg", LocalPath: "img/1700000000002.jpg"},
+	}
+
+	got, err := NewFutabaAdapter().ReconstructHTML(htmlContent, model.ThreadInfo{ID: "1"}, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	if strings.Contains(got, "1700000000001") {
+		t.Errorf("ブロック対象のメディアが残っています: %s", got)
+	}
+	if !strings.Contains(got, `href="img/1700000000002.jpg"`) {
+		t.Errorf("ブロック対象でないメディアのリンクが書き換えられていません: %s", got)
+	}
+}
+
+func TestStripAdBlocks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "入れ子を含む広告ブロック",
+			in:   `<p>a</p><div id="rightad"><div class="inner"><a href="x">ad</a></div></div><p>b</p>`,
+			want: `<p>a</p><p>b</p>`,
+		},
+		{
+			name: "classのトークンで判定",
+			in:   `<div class="thread"><DIV CLASS="ad-banner">x</DIV><div class="header">h</div></div>`,
+			want: `<div class="thread"><div class="header">h</div></div>`,
+		},
+		{
+			name: "トラッキングピクセルと広告iframe",
+			in:   `<img src="/t.gif" width="1" height="1"><img src="thumb/1s.jpg" width="126" height="100"><iframe src="https://ad.doubleclick.net/x"></iframe>`,
+			want: `<img src="thumb/1s.jpg" width="126" height="100">`,
+		},
+		{
+			name: "AdSenseとnoscript",
+			in:   `<ins class="adsbygoogle" data-ad-slot="1"></ins><noscript><img src="https://example.com/p"></noscript>本文`,
+			want: `本文`,
+		},
+		{
+			name: "終了タグのない要素は開始タグのみ削除",
+			in:   `<div id="bottomad">本文`,
+			want: `本文`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := stripAdBlocks(tt.in, futabaAdPatterns); got != tt.want {
+				t.Errorf("stripAdBlocks() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	// strip_ads: false のタスクでは広告を残す
+	adapter := &FutabaAdapter{keepAds: true}
+	got, err := adapter.ReconstructHTML(`<div id="rightad">ad</div>`, model.ThreadInfo{ID: "1"}, nil)
+	if err != nil || !strings.Contains(got, "rightad") {
+		t.Errorf("ReconstructHTML() = %q, %v; 広告が残っていません", got, err)
+	}
+}
+
+func TestFutabaAdapter_ExtractThreadDate(t *testing.T) {
+	t.Parallel()
+
+	utc := time.UTC
+	tests := []struct {
+		name     string
+		html     string
+		location *time.Location
+		want     time.Time
+		wantOK   bool
+	}{
+		{
+			name:   "OPの日時を日本時間で解釈",
+			html:   `<span class="cnw">25/11/18(火)12:34:56</span> No.123<blockquote>本文</blockquote><span class="cnw">25/11/19(水)00:00:01</span>`,
+			want:   time.Date(2025, 11, 18, 3, 34, 56, 0, utc),
+			wantOK: true,
+		},
+		{
+			name:     "板のタイムゾーンを指定",
+			html:     `25/01/02(木)03:04:05 No.1`,
+			location: utc,
+			want:     time.Date(2025, 1, 2, 3, 4, 5, 0, utc),
+			wantOK:   true,
+		},
+		{name: "存在しない日付", html: `25/02/30(日)12:00:00`},
+		{name: "日時なし", html: `<blockquote>本文</blockquote>`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			adapter := &FutabaAdapter{location: tt.location}
+			got, ok := adapter.ExtractThreadDate(tt.html)
+			if ok != tt.wantOK {
+				t.Fatalf("ExtractThreadDate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("ExtractThreadDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFutabaAdapter_CountPosts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		html string
+		want int
+	}{
+		{
+			name: "OPとレス",
+			html: `<span class="cno">No.100</span><blockquote>本文</blockquote><span class="cno">No.101</span><blockquote>レス</blockquote><span class="cno">No.102</span>`,
+			want: 3,
+		},
+		{
+			name: "引用・タグ内・重複は数えない",
+			html: `<span title="No.5">No.100</span><a id="p100" class="giba-anchor"></a>No.100<blockquote>&gt;No.100 ＞No.99</blockquote>No.101`,
+			want: 2,
+		},
+		{name: "レスなし", html: `<blockquote>本文</blockquote>`, want: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := (&FutabaAdapter{}).CountPosts(tt.html); got != tt.want {
+				t.Errorf("CountPosts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package adapter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// futabaNoticePattern は、板のトップページの投稿フォームの下にある注意書き（添付できるファイル・削除の基準など）です。
+var futabaNoticePattern = regexp.MustCompile(`(?is)<td class=["']?chui["']?[^>]*>(.*?)</td>`)
+
+// BoardMetaPages は、板のトップページ（<板のURL>/futaba.htm）のURLを返します。
+// ふたば互換の掲示板ではトップページのファイル名が板ごとに異なるため、板のURLをそのまま返します。
+func (a *FutabaAdapter) BoardMetaPages(baseURL string) ([]string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	if a.cgiName == "" {
+		u = u.JoinPath("futaba.htm")
+	}
+	return []string{u.String()}, nil
+}
+
+// ExtractBoardNotice は、板のトップページの注意書きを、項目ごとの行に分けたプレーンテキストで返します。
+func (a *FutabaAdapter) ExtractBoardNotice(htmlContent string) string {
+	var notices []string
+	for _, m := range futabaNoticePattern.FindAllStringSubmatch(htmlContent, -1) {
+		text := strings.NewReplacer("<li>", "\n", "<LI>", "\n").Replace(m[1])
+		for _, line := range strings.Split(multilineText(text), "\n") {
+			if line != "" {
+				notices = append(notices, line)
+			}
+		}
+	}
+	return strings.Join(notices, "\n")
+}
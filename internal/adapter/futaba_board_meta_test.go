@@ -0,0 +1,53 @@
+package adapter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFutabaAdapter_BoardMetaPages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		adapter *FutabaAdapter
+		want    []string
+	}{
+		{name: "ふたば", adapter: &FutabaAdapter{}, want: []string{"https://may.2chan.net/b/futaba.htm"}},
+		{name: "ふたば互換", adapter: &FutabaAdapter{cgiName: "pixmicat.php"}, want: []string{"https://clone.example.com/b/"}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			baseURL := "https://may.2chan.net/b/"
+			if tt.adapter.cgiName != "" {
+				baseURL = "https://clone.example.com/b/"
+			}
+			got, err := tt.adapter.BoardMetaPages(baseURL)
+			if err != nil {
+				t.Fatalf("BoardMetaPages() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BoardMetaPages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFutabaAdapter_ExtractBoardNotice(t *testing.T) {
+	t.Parallel()
+
+	const page = `<form action="futaba.php"><table>
+<tr><td class="chui"><ul style="margin-left:0">
+<li>添付可能：GIF, JPG, PNG, WEBP, WEBM, MP4. 3000KBまで.</li>
+<li>削除依頼は<a href="/del.php">こちら</a>&amp;ルールを守ってください</li>
+</ul></td></tr></table></form>`
+	want := "添付可能：GIF, JPG, PNG, WEBP, WEBM, MP4. 3000KBまで.\n削除依頼はこちら&ルールを守ってください"
+	if got := (&FutabaAdapter{}).ExtractBoardNotice(page); got != want {
+		t.Errorf("ExtractBoardNotice() = %q, want %q", got, want)
+	}
+	if got := (&FutabaAdapter{}).ExtractBoardNotice("<html></html>"); got != "" {
+		t.Errorf("ExtractBoardNotice() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,111 @@
+package adapter
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// futabaCatalogShape は、カタログの表示設定（cxyl Cookie の 横x縦x文字数）です。
+type futabaCatalogShape struct {
+	Cols, Rows, TitleLength int
+}
+
+// String は、表示設定を 9x100x20 の形式で返します。
+func (s futabaCatalogShape) String() string {
+	return fmt.Sprintf("%dx%dx%d", s.Cols, s.Rows, s.TitleLength)
+}
+
+// cookieValue は、表示設定の Cookie の値（9x100x20x0x0）を返します。
+func (s futabaCatalogShape) cookieValue() string {
+	return s.String() + "x0x0"
+}
+
+// futabaAutoCatalogShapes は、futaba_catalog_settings.auto で試す表示設定です。先頭ほど多くのスレッドと長いタイトルを表示します。
+// 板の上限を超える値の Cookie は無視されるため、無視された場合は順に小さい値で取得し直します。
+var futabaAutoCatalogShapes = []futabaCatalogShape{
+	{Cols: 20, Rows: 100, TitleLength: 100},
+	{Cols: 15, Rows: 100, TitleLength: 50},
+	{Cols: 9, Rows: 100, TitleLength: 20},
+}
+
+const (
+	// futabaDefaultTitleLength は、表示設定の Cookie がない場合のカタログのタイトルの文字数です。
+	futabaDefaultTitleLength = 4
+	// futabaAutoMinTitles は、表示設定が無視されたかを判断するのに必要な、本文のあるスレッドの件数です。
+	futabaAutoMinTitles = 10
+)
+
+// futabaAutoShapes は、板（ホストとパス）ごとに、以前の巡回で有効だった表示設定の位置を保持します。
+// 巡回のたびに上限を超える設定から試し直さないように使用します。
+var (
+	futabaAutoShapesMu sync.Mutex
+	futabaAutoShapes   = map[string]int{}
+)
+
+// rememberedFutabaAutoShape は、板 key で以前に有効だった表示設定の位置を返します。記録がない場合は 0 です。
+func rememberedFutabaAutoShape(key string) int {
+	futabaAutoShapesMu.Lock()
+	defer futabaAutoShapesMu.Unlock()
+	return futabaAutoShapes[key]
+}
+
+// rememberFutabaAutoShape は、板 key で有効だった表示設定の位置を記録します。
+func rememberFutabaAutoShape(key string, index int) {
+	futabaAutoShapesMu.Lock()
+	defer futabaAutoShapesMu.Unlock()
+	futabaAutoShapes[key] = index
+}
+
+// autoShapeKey は、表示設定を記録する板のキー（may.2chan.net/b）を返します。
+func (a *FutabaAdapter) autoShapeKey() string {
+	return a.boardHost + a.boardPath
+}
+
+// RetryCatalog は、futaba_catalog_settings.auto のタスクで、カタログのタイトルが表示設定の Cookie がない場合の長さで切り詰められていた場合に、
+// 次に小さい表示設定の Cookie を設定して true を返します。JSON API のカタログはタイトルが切り詰められないため、取得し直しません。
+// 有効だった表示設定はログに出力し、次の巡回でもその設定から始めます。
+func (a *FutabaAdapter) RetryCatalog(client *network.Client, threads []model.ThreadInfo) bool {
+	if !a.autoCatalog || a.lastCatalogJSON {
+		return false
+	}
+	shape := futabaAutoCatalogShapes[a.autoShape]
+	titles, longest := catalogTitleStats(threads)
+	if titles < futabaAutoMinTitles || longest > futabaDefaultTitleLength {
+		log.Printf("INFO: カタログの表示設定 %s が有効です (スレッド %d 件、タイトル最長 %d 文字)", shape, len(threads), longest)
+		rememberFutabaAutoShape(a.autoShapeKey(), a.autoShape)
+		return false
+	}
+	if a.autoShape+1 >= len(futabaAutoCatalogShapes) {
+		log.Printf("WARN: カタログの表示設定の Cookie が無視されています。タイトルは %d 文字で切り詰められます (スレッド %d 件)", longest, len(threads))
+		rememberFutabaAutoShape(a.autoShapeKey(), 0)
+		return false
+	}
+	next := futabaAutoCatalogShapes[a.autoShape+1]
+	if err := a.setCatalogCookie(client, a.catalogBoardURL, next); err != nil {
+		log.Printf("WARN: カタログの表示設定 %s の Cookie を設定できません: %v", next, err)
+		return false
+	}
+	log.Printf("INFO: カタログの表示設定 %s が無視されたため、%s で取得し直します", shape, next)
+	a.autoShape++
+	return true
+}
+
+// catalogTitleStats は、本文から取得したタイトルのあるスレッドの件数と、そのタイトルの最大の文字数を返します。
+// 本文がなく "Thread <スレッドID>" としたタイトルは数えません。
+func catalogTitleStats(threads []model.ThreadInfo) (titles, longest int) {
+	for _, thread := range threads {
+		title := strings.TrimSpace(thread.Title)
+		if title == "" || title == "Thread "+thread.ID {
+			continue
+		}
+		titles++
+		longest = max(longest, utf8.RuneCountInString(title))
+	}
+	return titles, longest
+}
@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/network"
+)
+
+func TestFutabaAdapter_RetryCatalog(t *testing.T) {
+	t.Parallel()
+
+	// 表示設定の Cookie が無視された、タイトルが4文字で切り詰められたカタログ
+	var truncated, full strings.Builder
+	for i := 0; i < futabaAutoMinTitles; i++ {
+		fmt.Fprintf(&truncated, `<td><a href="res/%d.htm">img</a><small>スレッド</small></td>`, 100+i)
+		fmt.Fprintf(&full, `<td><a href="res/%d.htm">img</a><small>スレッドのタイトル</small></td>`, 100+i)
+	}
+
+	client, err := network.NewClient(config.NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewFutabaCompatibleAdapter().(*FutabaAdapter)
+	task := config.Task{
+		TargetBoardURL:        "https://auto.example.com/b/",
+		FutabaCatalogSettings: &config.FutabaCatalogSettings{Auto: true},
+	}
+	if err := a.Prepare(client, task); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	cookieValue := func() string {
+		cookies, err := client.Cookies(task.TargetBoardURL)
+		if err != nil || len(cookies) != 1 {
+			t.Fatalf("Cookies() = %v, %v", cookies, err)
+		}
+		return cookies[0].Value
+	}
+	if got, want := cookieValue(), futabaAutoCatalogShapes[0].cookieValue(); got != want {
+		t.Errorf("cookie = %q, want %q", got, want)
+	}
+
+	// 切り詰められている間は次の表示設定で取得し直し、すべて試したら諦める
+	for i := 1; i <= len(futabaAutoCatalogShapes); i++ {
+		threads, err := a.ParseCatalog([]byte(truncated.String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry := a.RetryCatalog(client, threads)
+		if want := i < len(futabaAutoCatalogShapes); retry != want {
+			t.Fatalf("RetryCatalog() #%d = %v, want %v", i, retry, want)
+		}
+		if retry {
+			if got, want := cookieValue(), futabaAutoCatalogShapes[i].cookieValue(); got != want {
+				t.Errorf("cookie #%d = %q, want %q", i, got, want)
+			}
+		}
+	}
+
+	// タイトルが切り詰められていない場合は、その表示設定を次の巡回でも使用する
+	a = NewFutabaCompatibleAdapter().(*FutabaAdapter)
+	if err := a.Prepare(client, task); err != nil {
+		t.Fatal(err)
+	}
+	threads, _ := a.ParseCatalog([]byte(truncated.String()))
+	if !a.RetryCatalog(client, threads) {
+		t.Fatal("RetryCatalog() = false, want true")
+	}
+	threads, _ = a.ParseCatalog([]byte(full.String()))
+	if a.RetryCatalog(client, threads) {
+		t.Fatal("RetryCatalog() = true, want false")
+	}
+	a = NewFutabaCompatibleAdapter().(*FutabaAdapter)
+	if err := a.Prepare(client, task); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cookieValue(), futabaAutoCatalogShapes[1].cookieValue(); got != want {
+		t.Errorf("cookie after remember = %q, want %q", got, want)
+	}
+}
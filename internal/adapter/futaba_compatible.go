@@ -0,0 +1,30 @@
+package adapter
+
+// NewFutabaCompatibleAdapter は、スクリプト名・ディレクトリ名・Cookie 名をタスクごとに変更できる、ふたば互換の掲示板（pixmicat などのクローン）用の FutabaAdapter を返します。
+func NewFutabaCompatibleAdapter() SiteAdapter {
+	return &FutabaAdapter{compatible: true}
+}
+
+// scriptName は、カタログを表示するスクリプト名を返します。
+func (a *FutabaAdapter) scriptName() string {
+	if a.cgiName != "" {
+		return a.cgiName
+	}
+	return "futaba.php"
+}
+
+// srcDirName は、板のURLからの添付ファイルのディレクトリ名を返します。
+func (a *FutabaAdapter) srcDirName() string {
+	if a.srcDir != "" {
+		return a.srcDir
+	}
+	return "src"
+}
+
+// thumbDirName は、板のURLからのサムネイルのディレクトリ名を返します。
+func (a *FutabaAdapter) thumbDirName() string {
+	if a.thumbDir != "" {
+		return a.thumbDir
+	}
+	return "thumb"
+}
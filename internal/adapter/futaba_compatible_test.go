@@ -0,0 +1,98 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+func TestFutabaCompatibleAdapter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		settings    *config.FutabaCompatibleSettings
+		wantCatalog string
+		wantThumb   string
+		wantCookie  string
+	}{
+		{
+			name:        "既定値",
+			wantCatalog: "https://clone.example.com/b/futaba.php?mode=cat",
+			wantThumb:   "https://clone.example.com/b/thumb/1700000000123s.jpg",
+			wantCookie:  "cxyl",
+		},
+		{
+			name:        "pixmicat",
+			settings:    &config.FutabaCompatibleSettings{CGIName: "pixmicat.php", SrcDir: "img/src", ThumbDir: "/img/thumb/", CookieName: "pixmicat_cat"},
+			wantCatalog: "https://clone.example.com/b/pixmicat.php?mode=cat",
+			wantThumb:   "https://clone.example.com/b/img/thumb/1700000000123s.jpg",
+			wantCookie:  "pixmicat_cat",
+		},
+		{
+			name:        "Cookie なし",
+			settings:    &config.FutabaCompatibleSettings{CookieName: "-"},
+			wantCatalog: "https://clone.example.com/b/futaba.php?mode=cat",
+			wantThumb:   "https://clone.example.com/b/thumb/1700000000123s.jpg",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client, err := network.NewClient(config.NetworkSettings{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := NewFutabaCompatibleAdapter()
+			task := config.Task{TargetBoardURL: "https://clone.example.com/b/", FutabaCompatibleSettings: tt.settings}
+			if err := a.Prepare(client, task); err != nil {
+				t.Fatalf("Prepare() error = %v", err)
+			}
+
+			catalogURL, err := a.BuildCatalogURL(task.TargetBoardURL)
+			if err != nil || catalogURL != tt.wantCatalog {
+				t.Errorf("BuildCatalogURL() = %q, %v, want %q", catalogURL, err, tt.wantCatalog)
+			}
+
+			cookies, err := client.Cookies(task.TargetBoardURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotCookie string
+			for _, c := range cookies {
+				gotCookie = c.Name
+			}
+			if gotCookie != tt.wantCookie {
+				t.Errorf("cookie = %q, want %q", gotCookie, tt.wantCookie)
+			}
+
+			srcDir := "src"
+			if tt.settings != nil && tt.settings.SrcDir != "" {
+				srcDir = tt.settings.SrcDir
+			}
+			mediaURL := "https://clone.example.com/b/" + srcDir + "/1700000000123.jpg"
+			html := `<a href="/b/` + srcDir + `/1700000000123.jpg" target="_blank"><img src="` + strings.TrimPrefix(tt.wantThumb, "https://clone.example.com") + `"></a>`
+			media, err := a.ExtractMediaFiles(html, "https://clone.example.com/b/res/1700000000.htm")
+			if err != nil {
+				t.Fatalf("ExtractMediaFiles() error = %v", err)
+			}
+			if len(media) != 1 || media[0].URL != mediaURL || media[0].ThumbnailURL != tt.wantThumb {
+				t.Fatalf("ExtractMediaFiles() = %+v, want %s (thumb %s)", media, mediaURL, tt.wantThumb)
+			}
+
+			media[0].LocalPath = "img/1700000000123.jpg"
+			media[0].LocalThumbPath = "thumb/1700000000123s.jpg"
+			got, err := a.ReconstructHTML(html, model.ThreadInfo{ID: "1700000000"}, media)
+			if err != nil {
+				t.Fatalf("ReconstructHTML() error = %v", err)
+			}
+			if !strings.Contains(got, `href="img/1700000000123.jpg"`) || !strings.Contains(got, `src="thumb/1700000000123s.jpg"`) {
+				t.Errorf("ReconstructHTML() = %s, want local links", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package adapter
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+// FallbackMediaURLs は、掲示板から取得できなかったメディアを探す保管サイトのURLを、futaba_settings.fallback_sources の順に返します。
+// テンプレートの {host}（may.2chan.net）・{server}（may）・{board}（b）・{thread_id}・{dir}（src または thumb。ふたば互換アダプタでは板のディレクトリ名）・{filename} を置き換えます。
+func (a *FutabaAdapter) FallbackMediaURLs(thread model.ThreadInfo, mediaURL string) []string {
+	if len(a.fallbackSources) == 0 || IsDataURI(mediaURL) {
+		return nil
+	}
+	u, err := url.Parse(mediaURL)
+	if err != nil {
+		return nil
+	}
+	filename := path.Base(u.Path)
+	if filename == "" || filename == "." || filename == "/" {
+		return nil
+	}
+	dir := a.srcDirName()
+	if strings.Contains(u.Path, "/"+a.thumbDirName()+"/") {
+		dir = a.thumbDirName()
+	}
+	server, _, _ := strings.Cut(a.boardHost, ".")
+	replacer := strings.NewReplacer(
+		"{host}", a.boardHost,
+		"{server}", server,
+		"{board}", strings.TrimPrefix(a.boardPath, "/"),
+		"{thread_id}", thread.ID,
+		"{dir}", dir,
+		"{filename}", filename,
+	)
+	urls := make([]string, 0, len(a.fallbackSources))
+	for _, source := range a.fallbackSources {
+		urls = append(urls, replacer.Replace(source))
+	}
+	return urls
+}
@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"reflect"
+	"testing"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestFutabaAdapter_FallbackMediaURLs(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{
+		boardHost: "may.2chan.net",
+		boardPath: "/b",
+		fallbackSources: []string{
+			"https://mirror1.example.com/{server}/{board}/{thread_id}/{dir}/{filename}",
+			"https://mirror2.example.com/{host}/{filename}",
+		},
+	}
+	thread := model.ThreadInfo{ID: "1234567890"}
+	tests := []struct {
+		name     string
+		adapter  *FutabaAdapter
+		mediaURL string
+		want     []string
+	}{
+		{
+			name:     "フルサイズ",
+			adapter:  a,
+			mediaURL: "https://may.2chan.net/b/src/1700000000123.jpg",
+			want: []string{
+				"https://mirror1.example.com/may/b/1234567890/src/1700000000123.jpg",
+				"https://mirror2.example.com/may.2chan.net/1700000000123.jpg",
+			},
+		},
+		{
+			name:     "サムネイル",
+			adapter:  a,
+			mediaURL: "https://may.2chan.net/b/thumb/1700000000123s.jpg",
+			want: []string{
+				"https://mirror1.example.com/may/b/1234567890/thumb/1700000000123s.jpg",
+				"https://mirror2.example.com/may.2chan.net/1700000000123s.jpg",
+			},
+		},
+		{name: "保管サイトの指定なし", adapter: &FutabaAdapter{}, mediaURL: "https://may.2chan.net/b/src/1.jpg"},
+		{name: "埋め込み画像", adapter: a, mediaURL: "data:image/png;base64,AAAA"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.adapter.FallbackMediaURLs(thread, tt.mediaURL); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FallbackMediaURLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package adapter
+
+import (
+	"regexp"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+var (
+	// ID表示（ID:aBcD1234）。IP表示の板（IP:1.2.3.*）は対象外
+	futabaPosterIDPattern = regexp.MustCompile(`ID:([0-9A-Za-z./+]{4,})`)
+	// おみくじの結果（【大吉】など）
+	futabaFortunePattern = regexp.MustCompile(`【(大吉|中吉|小吉|末小吉|末吉|半吉|吉|小凶|半凶|末凶|大凶|凶)】`)
+	// 削除されたレス。ふたばは削除されたレスを class="deleted" の table で表示し、削除の理由を本文に表示する
+	futabaDeletedPattern = regexp.MustCompile(`class=["']?(?:[^"'>]*\s)?deleted\b|書き込みをした人によって削除されました|スレッドを立てた人によって削除されました|削除依頼によって隔離されました`)
+	// お絵かきのアニメーション（再生用ファイル）
+	futabaOekakiReplayPattern = regexp.MustCompile(`\d{13,}\.(?:spch|pch)\b`)
+)
+
+// ExtractPostFeatures は、スレッドHTMLの各レスから、ID表示・おみくじ・削除・お絵かきのアニメーションを抽出します。
+// ID表示は本文（<blockquote>）より前の投稿者の情報からのみ抽出し、本文中の「ID:」は対象外とします。
+func (a *FutabaAdapter) ExtractPostFeatures(htmlContent string) []model.PostFeatures {
+	var features []model.PostFeatures
+	for _, post := range futabaPostSegments(htmlContent) {
+		f := model.PostFeatures{ResNumber: post.resNumber}
+		if m := futabaPosterIDPattern.FindStringSubmatch(post.header()); m != nil {
+			f.PosterID = m[1]
+		}
+		if m := futabaFortunePattern.FindStringSubmatch(post.html); m != nil {
+			f.Fortune = m[1]
+		}
+		f.Deleted = futabaDeletedPattern.MatchString(post.html)
+		f.Oekaki = futabaOekakiReplayPattern.FindString(post.html)
+		if f != (model.PostFeatures{ResNumber: post.resNumber}) {
+			features = append(features, f)
+		}
+	}
+	return features
+}
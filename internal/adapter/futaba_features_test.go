@@ -0,0 +1,83 @@
+package adapter
+
+import (
+	"reflect"
+	"testing"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+const futabaTestFeaturesHTML = `<html><body>
+<form action="futaba.php"><table><tr><td>ID:notapost</td></tr></table></form>
+<div class="thre">
+<a href="/b/src/1700000000000.png" target="_blank"><img src="/b/thumb/1700000000000s.jpg"></a>
+<a href="/b/src/1700000000000.spch" target="_blank">アニメ</a>
+<span class="cnw">25/11/18(火)12:00:00 ID:aBcD1234</span> <span class="cno">No.1000</span>
+<blockquote>お絵かきスレ</blockquote>
+<table border=0><tr><td class=rts>…</td><td class=rtd>
+<span class="cnw">25/11/18(火)12:01:00 ID:eFgH5678</span> <span class="cno">No.1001</span>
+<blockquote><font color="#ff0000">【大吉】</font> &gt;No.1000 ID:zzzzzzzz</blockquote>
+</td></tr></table>
+<table border=0 class=deleted><tr><td class=rts>…</td><td class=rtd>
+<span class="cnw">25/11/18(火)12:02:00</span> <span class="cno">No.1002</span>
+<blockquote>書き込みをした人によって削除されました</blockquote>
+</td></tr></table>
+<table border=0><tr><td class=rts>…</td><td class=rtd>
+<span class="cnw">25/11/18(火)12:03:00</span> <span class="cno">No.1003</span>
+<blockquote>ふつうのレス ID:zzzzzzzz</blockquote>
+</td></tr></table>
+</div>
+</body></html>`
+
+func TestFutabaAdapter_ExtractPostFeatures(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{}
+	got := a.ExtractPostFeatures(futabaTestFeaturesHTML)
+	// 本文中の「ID:」や引用（>No.1000）、表示のないレス（No.1003）、投稿フォームの table は対象外
+	want := []model.PostFeatures{
+		{ResNumber: 1000, PosterID: "aBcD1234", Oekaki: "1700000000000.spch"},
+		{ResNumber: 1001, PosterID: "eFgH5678", Fortune: "大吉"},
+		{ResNumber: 1002, Deleted: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractPostFeatures() = %+v, want %+v", got, want)
+	}
+
+	media, err := a.ExtractMediaFiles(futabaTestFeaturesHTML, "https://may.2chan.net/b/res/1000.htm")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	// お絵かきのアニメーションはサムネイルのないメディアとして保存する
+	var replay *model.MediaInfo
+	for i := range media {
+		if media[i].OriginalFilename == "1700000000000.spch" {
+			replay = &media[i]
+		}
+	}
+	if replay == nil || replay.ThumbnailURL != "" {
+		t.Errorf("ExtractMediaFiles() = %+v, want the oekaki replay without a thumbnail", media)
+	}
+}
+
+func TestFutabaAdapter_JSONPostFeatures(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{useJSON: true, boardPath: "/b"}
+	htmlContent, err := a.ParseThreadHTML([]byte(`{"res": {
+  "1200": {"now": "25/11/18(火)12:00:00", "name": "としあき", "com": "スレ本文", "id": "ID:aBcD1234"},
+  "1201": {"now": "25/11/18(火)12:05:00", "name": "としあき", "com": "【凶】", "id": "eFgH5678"},
+  "1202": {"now": "25/11/18(火)12:06:00", "name": "としあき", "com": "", "del": "del"}
+}}`))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+	want := []model.PostFeatures{
+		{ResNumber: 1200, PosterID: "aBcD1234"},
+		{ResNumber: 1201, PosterID: "eFgH5678", Fortune: "凶"},
+		{ResNumber: 1202, Deleted: true},
+	}
+	if got := a.ExtractPostFeatures(htmlContent); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractPostFeatures() = %+v, want %+v\nHTML: %s", got, want, htmlContent)
+	}
+}
@@ -0,0 +1,87 @@
+package adapter
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+var (
+	// ブラウザで保存したページに残る元のURL（<!-- saved from url=(0045)https://may.2chan.net/b/res/123.htm -->）
+	futabaSavedFromPattern = regexp.MustCompile(`<!--\s*saved from url=\(\d+\)(\S+?)\s*-->`)
+	// 元のURL・保存したファイル名（123.htm）に含まれるスレッド番号
+	futabaSavedResPattern      = regexp.MustCompile(`/res/(\d+)\.html?$`)
+	futabaSavedFileNamePattern = regexp.MustCompile(`^(\d+)\.html?$`)
+	// OPの削除用チェックボックス（<input type=checkbox name="123" value=delete id=delcheck123>）
+	futabaDelCheckPattern = regexp.MustCompile(`id=["']?delcheck(\d+)`)
+	// 保存したHTMLのリンク（href・src 属性の値）
+	futabaSavedLinkPattern = regexp.MustCompile(`(href|src)=(["']?)([^"'\s>]+)`)
+)
+
+// ParseSavedThread は、ふたクロ・赤福やブラウザで保存したふたばのスレッドのHTMLから、スレッドの情報を取得します。
+// スレッド番号は、保存元のURL（saved from url）、ファイル名（123.htm）、OPの削除用チェックボックス、最初のレス番号の順に探します。
+// 保存元のURLが別の板のスレッドの場合は取り込みません。
+// 保存したツールによって画像の置き場所（src/・<スレッド番号>/・<スレッド番号>_files/ など）が異なるため、
+// ふたばの画像のファイル名（1700000000123.jpg・1700000000123s.jpg）へのリンクは、置き場所に関わらず掲示板上の src/・thumb/ のURLに戻します。
+func (a *FutabaAdapter) ParseSavedThread(htmlContent, fileName string) (model.ThreadInfo, string, bool) {
+	if !opBlockquotePattern.MatchString(htmlContent) {
+		return model.ThreadInfo{}, "", false
+	}
+
+	var id string
+	if m := futabaSavedFromPattern.FindStringSubmatch(htmlContent); m != nil {
+		if u, err := url.Parse(m[1]); err == nil && u.Host != "" {
+			if (a.boardHost != "" && u.Hostname() != a.boardHost) || !strings.HasPrefix(u.Path, a.boardPath+"/res/") {
+				return model.ThreadInfo{}, "", false
+			}
+			if rm := futabaSavedResPattern.FindStringSubmatch(u.Path); rm != nil {
+				id = rm[1]
+			}
+		}
+	}
+	if id == "" {
+		if m := futabaSavedFileNamePattern.FindStringSubmatch(path.Base(strings.ReplaceAll(fileName, `\`, "/"))); m != nil {
+			id = m[1]
+		} else if m := futabaDelCheckPattern.FindStringSubmatch(htmlContent); m != nil {
+			id = m[1]
+		} else if m := postNumberPattern.FindStringSubmatch(htmlContent); m != nil {
+			id = m[1]
+		}
+	}
+	if id == "" {
+		return model.ThreadInfo{}, "", false
+	}
+
+	thread := model.ThreadInfo{ID: id, URL: "res/" + id + ".htm"}
+	if date, ok := a.ExtractThreadDate(htmlContent); ok {
+		thread.Date = date
+	}
+	return thread, a.restoreSavedLinks(htmlContent), true
+}
+
+// restoreSavedLinks は、ふたばの画像のファイル名へのリンクを、板の src/（サムネイルは thumb/）のURLに書き換えます。
+func (a *FutabaAdapter) restoreSavedLinks(htmlContent string) string {
+	return futabaSavedLinkPattern.ReplaceAllStringFunc(htmlContent, func(match string) string {
+		m := futabaSavedLinkPattern.FindStringSubmatch(match)
+		value := m[3]
+		if IsDataURI(value) {
+			return match
+		}
+		if i := strings.IndexAny(value, "?#"); i >= 0 {
+			value = value[:i]
+		}
+		name := path.Base(strings.ReplaceAll(value, `\`, "/"))
+		fm := a.mediaFilePattern().FindStringSubmatch(name)
+		if fm == nil || fm[0] != name {
+			return match
+		}
+		dir := a.srcDirName()
+		if fm[2] == "s" {
+			dir = a.thumbDirName()
+		}
+		return m[1] + "=" + m[2] + a.boardPath + "/" + dir + "/" + name
+	})
+}
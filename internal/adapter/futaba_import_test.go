@@ -0,0 +1,62 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFutabaAdapter_ParseSavedThread(t *testing.T) {
+	t.Parallel()
+
+	const body = `<form><a href="1700000000_files/1700000000123.jpg" target="_blank"><img src='1700000000_files\1700000000123s.jpg'></a>
+<input type=checkbox name="1700000000" value=delete id=delcheck1700000000>25/11/18(火)12:34:56 No.1700000000
+<blockquote>スレ本文</blockquote>
+<a href="https://may.2chan.net/b/src/1700000000456.png?x=1">1700000000456.png</a>
+<img src="data:image/png;base64,AAAA"> <a href="res/1699999999.htm">別スレ</a></form>`
+
+	tests := []struct {
+		name     string
+		html     string
+		fileName string
+		wantID   string
+		wantOK   bool
+	}{
+		{name: "保存元のURL", html: "<!-- saved from url=(0044)https://may.2chan.net/b/res/1700000001.htm -->\n" + body, fileName: "thread.html", wantID: "1700000001", wantOK: true},
+		{name: "ファイル名", html: body, fileName: "1700000002.htm", wantID: "1700000002", wantOK: true},
+		{name: "削除用チェックボックス", html: body, fileName: "thread.html", wantID: "1700000000", wantOK: true},
+		{name: "別の板", html: "<!-- saved from url=(0044)https://may.2chan.net/g/res/1700000001.htm -->\n" + body, fileName: "1700000001.htm"},
+		{name: "別のサーバー", html: "<!-- saved from url=(0044)https://img.2chan.net/b/res/1700000001.htm -->\n" + body, fileName: "1700000001.htm"},
+		{name: "スレッドではない", html: "<html><body>カタログ</body></html>", fileName: "1700000003.htm"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := &FutabaAdapter{boardPath: "/b", boardHost: "may.2chan.net"}
+			thread, restored, ok := a.ParseSavedThread(tt.html, tt.fileName)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseSavedThread() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if thread.ID != tt.wantID || thread.URL != "res/"+tt.wantID+".htm" {
+				t.Errorf("thread = %+v, want ID %s", thread, tt.wantID)
+			}
+			if thread.Date.IsZero() {
+				t.Error("thread.Date is zero, want the OP date")
+			}
+			for _, want := range []string{
+				`href="/b/src/1700000000123.jpg"`,
+				`src='/b/thumb/1700000000123s.jpg'`,
+				`href="/b/src/1700000000456.png"`,
+				`src="data:image/png;base64,AAAA"`,
+				`href="res/1699999999.htm"`,
+			} {
+				if !strings.Contains(restored, want) {
+					t.Errorf("restored does not contain %q:\n%s", want, restored)
+				}
+			}
+		})
+	}
+}
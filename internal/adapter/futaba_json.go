@@ -0,0 +1,212 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+// futabaJSONPost は、ふたばの JSON API（futaba.php?mode=json、res/<スレッドID>.json）の投稿のうち、アーカイブに使用する項目です。
+// 投稿は "res" オブジェクトにレス番号をキーとして格納されています。
+type futabaJSONPost struct {
+	No    string `json:"-"` // "res" のキー
+	Now   string `json:"now"`
+	Name  string `json:"name"`
+	Sub   string `json:"sub"`
+	Com   string `json:"com"`
+	Ext   string `json:"ext"`
+	Tim   string `json:"tim"`
+	Src   string `json:"src"`
+	Thumb string `json:"thumb"`
+	Fsize int64  `json:"fsize"`
+	ID    string `json:"id"`  // ID表示（ID:xxxxxxxx）。ID表示のない板・レスでは空
+	Del   string `json:"del"` // 削除されたレスでは空以外
+}
+
+// isFutabaJSON は、ボディが JSON API のレスポンスかどうかを判定します。
+// JSON に対応していない板は mode=json を無視して通常のHTMLを返すため、HTMLへのフォールバックの判定に使用します。
+func isFutabaJSON(body []byte) bool {
+	body = bytes.TrimPrefix(bytes.TrimSpace(body), []byte("\xef\xbb\xbf"))
+	return len(body) > 0 && body[0] == '{'
+}
+
+// decodeFutabaJSONPosts は、JSON API のレスポンスの "res" に含まれる投稿を、レスポンスに書かれた順に返します。
+func decodeFutabaJSONPosts(body []byte) ([]futabaJSONPost, error) {
+	body = bytes.TrimPrefix(bytes.TrimSpace(body), []byte("\xef\xbb\xbf"))
+	var response struct {
+		Res json.RawMessage `json:"res"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Res) == 0 || string(response.Res) == "null" {
+		return nil, nil
+	}
+
+	// map に読み込むと順序が失われるため、カタログの並び（勢い順など）を保つようにトークン単位で読み込む
+	dec := json.NewDecoder(bytes.NewReader(response.Res))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("res がオブジェクトではありません")
+	}
+	var posts []futabaJSONPost
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		no, _ := tok.(string)
+		if _, err := strconv.ParseInt(no, 10, 64); err != nil {
+			return nil, fmt.Errorf("レス番号 %q が不正です", no)
+		}
+		var post futabaJSONPost
+		if err := dec.Decode(&post); err != nil {
+			return nil, fmt.Errorf("レス %s の解析に失敗しました: %w", no, err)
+		}
+		post.No = no
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// date は、投稿日時を返します。now を解釈できない場合は、ファイルの保存名（tim、UNIX 時間のミリ秒）を使用します。
+func (p futabaJSONPost) date(loc *time.Location) (time.Time, bool) {
+	if m := futabaPostDatePattern.FindStringSubmatch(p.Now); m != nil {
+		if t, ok := parseFutabaDate(m[1:], loc); ok {
+			return t, true
+		}
+	}
+	if ms, err := strconv.ParseInt(p.Tim, 10, 64); err == nil && ms > 0 {
+		return time.UnixMilli(ms), true
+	}
+	return time.Time{}, false
+}
+
+// jsonMediaPaths は、投稿の添付ファイルとサムネイルのサイト内の絶対パス（/b/src/...、/b/thumb/...）を返します。添付ファイルがない場合は空文字列です。
+// src・thumb がない場合は、tim と ext から板の添付ファイル・サムネイルのディレクトリ以下のパスを組み立てます。
+func (a *FutabaAdapter) jsonMediaPaths(p futabaJSONPost) (string, string) {
+	src, thumb := p.Src, p.Thumb
+	if src == "" && p.Tim != "" && p.Ext != "" {
+		src = a.srcSitePath() + "/" + p.Tim + p.Ext
+	}
+	if src == "" {
+		return "", ""
+	}
+	if thumb == "" && p.Tim != "" && a.hasBoardThumbnail(src) {
+		thumb = a.thumbSitePath() + "/" + a.thumbnailName(p.Tim+p.Ext)
+	}
+	return src, thumb
+}
+
+// parseJSONCatalog は、futaba.php?mode=json のレスポンスからスレッド情報のスライスを返します。
+// タイトルはHTMLのカタログと同じく本文の先頭とし、スレッドのURLは res/<スレッドID>.json です。
+func (a *FutabaAdapter) parseJSONCatalog(body []byte) ([]model.ThreadInfo, error) {
+	posts, err := decodeFutabaJSONPosts(body)
+	if err != nil {
+		return nil, fmt.Errorf("カタログの JSON の解析に失敗しました: %w", err)
+	}
+	threads := make([]model.ThreadInfo, 0, len(posts))
+	for _, post := range posts {
+		title := plainText(post.Com)
+		if title == "" {
+			title = plainText(post.Sub)
+		}
+		if title == "" {
+			title = fmt.Sprintf("Thread %s", post.No)
+		}
+		date, ok := post.date(a.boardLocation())
+		if !ok {
+			date = time.Now()
+		}
+		threads = append(threads, model.ThreadInfo{
+			ID:    post.No,
+			Title: title,
+			URL:   "res/" + post.No + ".json",
+			Date:  date,
+		})
+	}
+	return threads, nil
+}
+
+// renderJSONThread は、res/<スレッドID>.json のレスポンスを、ふたばのスレッドHTMLと同じ構造のHTMLに変換します。
+// 以降の処理（メディアの抽出・リンクの書き換え・レスのアンカー）はHTMLから取得した場合と共通です。
+// 添付ファイルへのリンクには data-res 属性でレス番号を付与します。本文・名前・題名は API がHTMLとして返す値をそのまま使用します。
+func (a *FutabaAdapter) renderJSONThread(body []byte) (string, error) {
+	posts, err := decodeFutabaJSONPosts(body)
+	if err != nil {
+		return "", fmt.Errorf("スレッドの JSON の解析に失敗しました: %w", err)
+	}
+	if len(posts) == 0 {
+		return "", fmt.Errorf("スレッドの JSON に投稿がありません")
+	}
+	// レス番号は投稿順に増えるため、番号順に並べるとOPが先頭になる
+	sort.SliceStable(posts, func(i, j int) bool {
+		ni, _ := strconv.ParseInt(posts[i].No, 10, 64)
+		nj, _ := strconv.ParseInt(posts[j].No, 10, 64)
+		return ni < nj
+	})
+
+	op := posts[0]
+	title := op.Sub
+	if title == "" {
+		title = "No." + op.No
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>" + title + "</title>\n</head>\n<body>\n")
+	threClass := "thre"
+	if op.Del != "" {
+		threClass += " deleted"
+	}
+	sb.WriteString(`<div class="` + threClass + `" data-res="` + op.No + "\">\n")
+	a.writeJSONPost(&sb, op)
+	for i, post := range posts[1:] {
+		// 削除されたレスは、ふたばのHTMLと同じく class="deleted" の table で表示する
+		if post.Del != "" {
+			sb.WriteString(`<table border="0" class="deleted">`)
+		} else {
+			sb.WriteString(`<table border="0">`)
+		}
+		sb.WriteString(`<tr><td class="rts">…</td><td class="rtd">`)
+		sb.WriteString(`<span id="delcheck` + post.No + `" class="rsc">` + strconv.Itoa(i+1) + `</span>`)
+		a.writeJSONPost(&sb, post)
+		sb.WriteString("</td></tr></table>\n")
+	}
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+// writeJSONPost は、1件の投稿（添付ファイル・投稿者の情報・本文）を書き出します。
+// ID表示は投稿日時の後に、ふたばのHTMLと同じ「ID:xxxxxxxx」の形式で書き出します。
+func (a *FutabaAdapter) writeJSONPost(sb *strings.Builder, post futabaJSONPost) {
+	if src, thumb := a.jsonMediaPaths(post); src != "" {
+		fmt.Fprintf(sb, `<a href="%s" target="_blank" data-res="%s">`, html.EscapeString(src), post.No)
+		if thumb != "" {
+			fmt.Fprintf(sb, `<img src="%s" border="0" alt="%d B">`, html.EscapeString(thumb), post.Fsize)
+		} else {
+			sb.WriteString(html.EscapeString(src[strings.LastIndex(src, "/")+1:]))
+		}
+		sb.WriteString("</a>\n")
+	}
+	if post.Sub != "" {
+		sb.WriteString(`<span class="csb">` + post.Sub + `</span>`)
+	}
+	sb.WriteString(`Name <span class="cnm">` + post.Name + `</span> `)
+	sb.WriteString(`<span class="cnw">` + post.Now + `</span> `)
+	if id := post.ID; id != "" {
+		if !strings.HasPrefix(id, "ID:") {
+			id = "ID:" + id
+		}
+		sb.WriteString(`<span class="cnw">` + id + `</span> `)
+	}
+	sb.WriteString(`<span class="cno">No.` + post.No + "</span>\n")
+	sb.WriteString("<blockquote>" + post.Com + "</blockquote>\n")
+}
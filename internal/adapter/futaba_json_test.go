@@ -0,0 +1,146 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+const futabaTestJSONCatalog = `{"die": "12:00頃消えます", "maxres": "", "res": {
+  "1300": {"now": "25/11/18(火)13:00:00", "name": "としあき", "sub": "無念", "com": "新しい<br>スレ", "ext": ".png", "tim": "1763438400000",
+           "src": "/b/src/1763438400000.png", "thumb": "/b/thumb/1763438400000s.jpg", "fsize": 2048},
+  "1200": {"now": "不明な形式", "name": "としあき", "sub": "", "com": "", "tim": "1763434800000"}
+}}`
+
+const futabaTestJSONThread = `{"res": {
+  "1202": {"now": "25/11/18(火)12:10:00", "name": "としあき", "com": "音声", "ext": ".ogg", "tim": "1763435400000", "fsize": 100},
+  "1200": {"now": "25/11/18(火)12:00:00", "name": "としあき", "sub": "無念", "com": "スレ本文", "ext": ".jpg", "tim": "1763434800000",
+           "src": "/b/src/1763434800000.jpg", "thumb": "/b/thumb/1763434800000s.jpg", "fsize": 4096},
+  "1201": {"now": "25/11/18(火)12:05:00", "name": "としあき", "com": "<a href=\"#r1200\">&gt;&gt;1200</a> 返信"}
+}}`
+
+func TestFutabaAdapter_JSONCatalog(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{useJSON: true, boardPath: "/b"}
+	catalogURL, err := a.BuildCatalogURL("https://may.2chan.net/b/")
+	if err != nil {
+		t.Fatalf("BuildCatalogURL() error = %v", err)
+	}
+	if catalogURL != "https://may.2chan.net/b/futaba.php?mode=json" {
+		t.Errorf("BuildCatalogURL() = %q", catalogURL)
+	}
+
+	threads, err := a.ParseCatalog([]byte(futabaTestJSONCatalog))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	tests := []struct {
+		id, title, url string
+		date           time.Time
+	}{
+		// JSON に書かれた順（カタログの並び）を保つ
+		{id: "1300", title: "新しい スレ", url: "res/1300.json", date: time.Date(2025, 11, 18, 13, 0, 0, 0, futabaLocation)},
+		{id: "1200", title: "Thread 1200", url: "res/1200.json", date: time.UnixMilli(1763434800000)},
+	}
+	if len(threads) != len(tests) {
+		t.Fatalf("len(threads) = %d, want %d", len(threads), len(tests))
+	}
+	for i, tt := range tests {
+		got := threads[i]
+		if got.ID != tt.id || got.Title != tt.title || got.URL != tt.url || !got.Date.Equal(tt.date) {
+			t.Errorf("threads[%d] = %+v, want %+v", i, got, tt)
+		}
+	}
+}
+
+func TestFutabaAdapter_JSONCatalogFallback(t *testing.T) {
+	t.Parallel()
+
+	// JSON に対応していない板が返した通常のHTMLは、従来どおりHTMLとして解析する
+	a := &FutabaAdapter{useJSON: true}
+	threads, err := a.ParseCatalog([]byte(`<html><body><a href="res/1200.htm">スレ</a><small>本文</small></body></html>`))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	if len(threads) != 1 || threads[0].URL != "res/1200.htm" {
+		t.Errorf("ParseCatalog() = %+v, want res/1200.htm", threads)
+	}
+}
+
+func TestFutabaAdapter_JSONThread(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{useJSON: true, boardPath: "/b"}
+	htmlContent, err := a.ParseThreadHTML([]byte(futabaTestJSONThread))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+
+	if got := a.CountPosts(htmlContent); got != 3 {
+		t.Errorf("CountPosts() = %d, want 3", got)
+	}
+	if got := a.ExtractOPText(htmlContent); got != "スレ本文" {
+		t.Errorf("ExtractOPText() = %q, want %q", got, "スレ本文")
+	}
+	wantDate := time.Date(2025, 11, 18, 12, 0, 0, 0, futabaLocation)
+	if got, ok := a.ExtractThreadDate(htmlContent); !ok || !got.Equal(wantDate) {
+		t.Errorf("ExtractThreadDate() = %v, %v, want %v", got, ok, wantDate)
+	}
+
+	media, err := a.ExtractMediaFiles(htmlContent, "https://may.2chan.net/b/res/1200.json")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	want := []model.MediaInfo{
+		{URL: "https://may.2chan.net/b/src/1763434800000.jpg", ThumbnailURL: "https://may.2chan.net/b/thumb/1763434800000s.jpg", OriginalFilename: "1763434800000.jpg", ResNumber: 1200},
+		// src がない投稿は tim と ext から補完し、音声にはサムネイルがない
+		{URL: "https://may.2chan.net/b/src/1763435400000.ogg", OriginalFilename: "1763435400000.ogg", ResNumber: 1202},
+	}
+	if len(media) != len(want) {
+		t.Fatalf("ExtractMediaFiles() = %+v, want %+v", media, want)
+	}
+	for i := range want {
+		if media[i] != want[i] {
+			t.Errorf("media[%d] = %+v, want %+v", i, media[i], want[i])
+		}
+	}
+
+	thread := model.ThreadInfo{ID: "1200"}
+	media[0].LocalPath = "img/1763434800000.jpg"
+	media[0].LocalThumbPath = "thumb/1763434800000s.jpg"
+	reconstructed, err := a.ReconstructHTML(htmlContent, thread, media[:1])
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	for _, s := range []string{`href="img/1763434800000.jpg"`, `src="thumb/1763434800000s.jpg"`, `id="p1201"`, `href="#p1200"`} {
+		if !strings.Contains(reconstructed, s) {
+			t.Errorf("ReconstructHTML() に %s が含まれていません:\n%s", s, reconstructed)
+		}
+	}
+}
+
+func TestFutabaAdapter_JSONThreadErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "投稿なし", body: `{"res": {}}`},
+		{name: "不正なレス番号", body: `{"res": {"abc": {"com": "x"}}}`},
+		{name: "壊れたJSON", body: `{"res": {"1": `},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := &FutabaAdapter{useJSON: true, boardPath: "/b"}
+			if _, err := a.ParseThreadHTML([]byte(tt.body)); err == nil {
+				t.Error("ParseThreadHTML() error = nil, want error")
+			}
+		})
+	}
+}
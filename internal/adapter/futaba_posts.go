@@ -0,0 +1,120 @@
+package adapter
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+var (
+	// スレッドの本体（OP）と、レスを囲む table
+	futabaThreadStartPattern = regexp.MustCompile(`class=["']?thre\b`)
+	futabaPostTablePattern   = regexp.MustCompile(`(?i)<table\b`)
+	// 題名と名前。古い形式のHTMLでは <font color="#cc1105"><b>題名</b></font>・<font color="#117743"><b>名前</b></font> で表示される
+	futabaSubjectPattern = regexp.MustCompile(`(?is)<span class=["']?csb["']?>(.*?)</span>|<font color=["']?#cc1105["']?><b>(.*?)</b></font>`)
+	futabaNamePattern    = regexp.MustCompile(`(?is)<span class=["']?cnm["']?>(.*?)</span>|<font color=["']?#117743["']?><b>(.*?)</b></font>`)
+	brTagPattern         = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// futabaPostSegment は、スレッドHTMLのうち1件のレスの部分です。
+type futabaPostSegment struct {
+	resNumber int
+	html      string
+}
+
+// header は、レスの本文（<blockquote>）より前の部分（添付ファイル・題名・名前・日時など）を返します。
+func (s futabaPostSegment) header() string {
+	if i := strings.Index(strings.ToLower(s.html), "<blockquote"); i >= 0 {
+		return s.html[:i]
+	}
+	return s.html
+}
+
+// futabaPostSegments は、スレッドHTMLをレスごとに区切り、レス番号順に返します。
+// レスはOP（class="thre" から最初の table まで）と、以降の table ごとに区切ります。レス番号のない table（投稿フォームなど）は含めません。
+func futabaPostSegments(htmlContent string) []futabaPostSegment {
+	start := 0
+	if loc := futabaThreadStartPattern.FindStringIndex(htmlContent); loc != nil {
+		start = loc[0]
+	}
+	bounds := []int{start}
+	for _, loc := range futabaPostTablePattern.FindAllStringIndex(htmlContent[start:], -1) {
+		bounds = append(bounds, start+loc[0])
+	}
+	bounds = append(bounds, len(htmlContent))
+
+	var segments []futabaPostSegment
+	seen := make(map[int]bool)
+	for i := 0; i+1 < len(bounds); i++ {
+		segment := htmlContent[bounds[i]:bounds[i+1]]
+		resNum := 0
+		forEachPostNumber(segment, func(_ int, n string) {
+			if resNum == 0 {
+				resNum, _ = strconv.Atoi(n)
+			}
+		})
+		if resNum == 0 || seen[resNum] {
+			continue
+		}
+		seen[resNum] = true
+		segments = append(segments, futabaPostSegment{resNumber: resNum, html: segment})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].resNumber < segments[j].resNumber })
+	return segments
+}
+
+// ParsePosts は、スレッドHTMLのレスを、名前・トリップ・ID表示・題名・本文・投稿日時・添付ファイルに分けて返します。
+// 名前のうち「◆」以降はトリップとして扱います。本文は最初の <blockquote> の内容で、改行タグは改行に置き換えます。
+func (a *FutabaAdapter) ParsePosts(htmlContent, threadURL string) ([]model.Post, error) {
+	segments := futabaPostSegments(htmlContent)
+	posts := make([]model.Post, 0, len(segments))
+	for _, segment := range segments {
+		header := segment.header()
+		post := model.Post{ResNumber: segment.resNumber}
+		if m := futabaSubjectPattern.FindStringSubmatch(header); m != nil {
+			post.Subject = plainText(m[1] + m[2])
+		}
+		if m := futabaNamePattern.FindStringSubmatch(header); m != nil {
+			name, trip, _ := strings.Cut(plainText(m[1]+m[2]), "◆")
+			post.Author, post.Trip = strings.TrimSpace(name), strings.TrimSpace(trip)
+		}
+		if m := futabaPosterIDPattern.FindStringSubmatch(header); m != nil {
+			post.PosterID = m[1]
+		}
+		if m := futabaPostDatePattern.FindStringSubmatch(header); m != nil {
+			post.Date, _ = parseFutabaDate(m[1:], a.boardLocation())
+		}
+		if m := opBlockquotePattern.FindStringSubmatch(segment.html); m != nil {
+			post.Body = multilineText(m[1])
+		}
+
+		media, err := a.ExtractMediaFiles(segment.html, threadURL)
+		if err != nil {
+			return nil, fmt.Errorf("レス %d の添付ファイルの抽出に失敗しました: %w", segment.resNumber, err)
+		}
+		for i := range media {
+			media[i].ResNumber = segment.resNumber
+		}
+		post.Media = media
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// multilineText は、HTMLの断片から改行を保ったプレーンテキストを返します。
+// 改行タグを改行に置き換え、HTMLタグと実体参照を取り除いて、各行の前後の空白を取り除きます。
+func multilineText(fragment string) string {
+	text := brTagPattern.ReplaceAllString(fragment, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,94 @@
+package adapter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFutabaAdapter_ParsePosts(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{}
+	posts, err := a.ParsePosts(futabaTestFeaturesHTML, "https://may.2chan.net/b/res/1000.htm")
+	if err != nil {
+		t.Fatalf("ParsePosts() error = %v", err)
+	}
+	if len(posts) != 4 {
+		t.Fatalf("len(posts) = %d, want 4", len(posts))
+	}
+	op := posts[0]
+	if op.ResNumber != 1000 || op.PosterID != "aBcD1234" || op.Body != "お絵かきスレ" ||
+		!op.Date.Equal(time.Date(2025, 11, 18, 12, 0, 0, 0, futabaLocation)) {
+		t.Errorf("posts[0] = %+v", op)
+	}
+	// OPの画像とお絵かきのアニメーションはOPの添付ファイル
+	if len(op.Media) != 2 || op.Media[0].URL != "https://may.2chan.net/b/src/1700000000000.png" || op.Media[0].ResNumber != 1000 {
+		t.Errorf("posts[0].Media = %+v", op.Media)
+	}
+	if got := posts[1].Body; got != "【大吉】 >No.1000 ID:zzzzzzzz" {
+		t.Errorf("posts[1].Body = %q", got)
+	}
+	if posts[3].ResNumber != 1003 || posts[3].PosterID != "" || len(posts[3].Media) != 0 {
+		t.Errorf("posts[3] = %+v", posts[3])
+	}
+}
+
+func TestFutabaAdapter_ParsePostsJSON(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{useJSON: true, boardPath: "/b"}
+	htmlContent, err := a.ParseThreadHTML([]byte(futabaTestJSONThread))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+	posts, err := a.ParsePosts(htmlContent, "https://may.2chan.net/b/res/1200.json")
+	if err != nil {
+		t.Fatalf("ParsePosts() error = %v", err)
+	}
+
+	tests := []struct {
+		res     int
+		subject string
+		body    string
+		media   string
+		minute  int
+	}{
+		{res: 1200, subject: "無念", body: "スレ本文", media: "https://may.2chan.net/b/src/1763434800000.jpg"},
+		{res: 1201, body: ">>1200 返信", minute: 5},
+		{res: 1202, body: "音声", media: "https://may.2chan.net/b/src/1763435400000.ogg", minute: 10},
+	}
+	if len(posts) != len(tests) {
+		t.Fatalf("len(posts) = %d, want %d", len(posts), len(tests))
+	}
+	for i, tt := range tests {
+		got := posts[i]
+		if got.ResNumber != tt.res || got.Author != "としあき" || got.Subject != tt.subject || got.Body != tt.body ||
+			!got.Date.Equal(time.Date(2025, 11, 18, 12, tt.minute, 0, 0, futabaLocation)) {
+			t.Errorf("posts[%d] = %+v", i, got)
+		}
+		if tt.media == "" {
+			if len(got.Media) != 0 {
+				t.Errorf("posts[%d].Media = %+v, want none", i, got.Media)
+			}
+		} else if len(got.Media) != 1 || got.Media[0].URL != tt.media {
+			t.Errorf("posts[%d].Media = %+v, want %s", i, got.Media, tt.media)
+		}
+	}
+}
+
+func TestMultilineText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in, want string
+	}{
+		{in: "一行目<br>二行目<BR />  三行目 ", want: "一行目\n二行目\n三行目"},
+		{in: `<font color="#789922">&gt;引用</font><br>本文&amp;`, want: ">引用\n本文&"},
+		{in: "<br>", want: ""},
+	}
+	for _, tt := range tests {
+		if got := multilineText(tt.in); got != tt.want {
+			t.Errorf("multilineText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package adapter
+
+import (
+	"path"
+	"strings"
+)
+
+// 板ごとの違い（タスクの futaba_board_quirks、または adapter_settings の board_quirks）を反映したパスとファイル名です。
+// 未設定の項目は、ふたばの既定値（板のURL以下の src/・thumb/、サムネイルは <ファイル名>s.jpg）を使用します。
+
+// boardSitePath は、板のサイト内のパス（例: /b）を返します。板のパスが不明な場合は /b とみなします。
+func (a *FutabaAdapter) boardSitePath() string {
+	if a.boardPath == "" {
+		return "/b"
+	}
+	return a.boardPath
+}
+
+// srcSitePath は、添付ファイルを置くディレクトリのサイト内の絶対パス（例: /b/src）を返します。
+func (a *FutabaAdapter) srcSitePath() string {
+	if a.quirks.SrcPath != "" {
+		return strings.TrimSuffix(a.quirks.SrcPath, "/")
+	}
+	return a.boardSitePath() + "/" + a.srcDirName()
+}
+
+// thumbSitePath は、サムネイルを置くディレクトリのサイト内の絶対パス（例: /b/thumb）を返します。
+func (a *FutabaAdapter) thumbSitePath() string {
+	if a.quirks.ThumbPath != "" {
+		return strings.TrimSuffix(a.quirks.ThumbPath, "/")
+	}
+	return a.boardSitePath() + "/" + a.thumbDirName()
+}
+
+// hasBoardThumbnail は、板が filename のサムネイルを生成するかを判定します。
+func (a *FutabaAdapter) hasBoardThumbnail(filename string) bool {
+	return !a.quirks.NoThumbnails && hasThumbnail(filename)
+}
+
+// thumbnailName は、添付ファイル filename（例: 123.png）のサムネイルのファイル名（例: 123s.jpg）を返します。
+func (a *FutabaAdapter) thumbnailName(filename string) string {
+	ext := "jpg"
+	if a.quirks.ThumbExtension != "" {
+		ext = strings.TrimPrefix(a.quirks.ThumbExtension, ".")
+	}
+	return strings.TrimSuffix(filename, path.Ext(filename)) + "s." + ext
+}
+
+// thumbnailPath は、添付ファイルのURLのパス mediaPath（例: /b/src/123.png）から、サムネイルのパス（例: /b/thumb/123s.jpg）を返します。
+// 添付ファイルが板の添付ファイルのディレクトリにない場合（ミラーなど）は、パスの中のディレクトリ名を置き換えます。
+func (a *FutabaAdapter) thumbnailPath(mediaPath string) string {
+	dir, name := path.Split(mediaPath)
+	if dir == a.srcSitePath()+"/" {
+		return a.thumbSitePath() + "/" + a.thumbnailName(name)
+	}
+	return strings.Replace(dir, "/"+a.srcDirName()+"/", "/"+a.thumbDirName()+"/", 1) + a.thumbnailName(name)
+}
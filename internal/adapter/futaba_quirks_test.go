@@ -0,0 +1,101 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestFutabaAdapter_BoardQuirks(t *testing.T) {
+	t.Parallel()
+
+	const threadURL = "https://dec.2chan.net/up/res/100.htm"
+	tests := []struct {
+		name      string
+		quirks    config.FutabaBoardQuirks
+		html      string
+		wantURL   string
+		wantThumb string
+	}{
+		{
+			name:      "既定値",
+			html:      `<a href="/up/src/1700000000000.png">`,
+			wantURL:   "https://dec.2chan.net/up/src/1700000000000.png",
+			wantThumb: "https://dec.2chan.net/up/thumb/1700000000000s.jpg",
+		},
+		{
+			name:      "別のディレクトリ",
+			quirks:    config.FutabaBoardQuirks{SrcPath: "/img/up/src", ThumbPath: "/img/up/thumb"},
+			html:      `<a href="/img/up/src/1700000000000.png">`,
+			wantURL:   "https://dec.2chan.net/img/up/src/1700000000000.png",
+			wantThumb: "https://dec.2chan.net/img/up/thumb/1700000000000s.jpg",
+		},
+		{
+			name:      "サムネイルの拡張子",
+			quirks:    config.FutabaBoardQuirks{ThumbExtension: "png"},
+			html:      `<a href="/up/src/1700000000000.gif">`,
+			wantURL:   "https://dec.2chan.net/up/src/1700000000000.gif",
+			wantThumb: "https://dec.2chan.net/up/thumb/1700000000000s.png",
+		},
+		{
+			name:    "サムネイルなし",
+			quirks:  config.FutabaBoardQuirks{NoThumbnails: true},
+			html:    `<a href="/up/src/1700000000000.jpg">`,
+			wantURL: "https://dec.2chan.net/up/src/1700000000000.jpg",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := &FutabaAdapter{boardPath: "/up", quirks: tt.quirks}
+			media, err := a.ExtractMediaFiles(tt.html, threadURL)
+			if err != nil {
+				t.Fatalf("ExtractMediaFiles() error = %v", err)
+			}
+			if len(media) != 1 || media[0].URL != tt.wantURL || media[0].ThumbnailURL != tt.wantThumb {
+				t.Fatalf("ExtractMediaFiles() = %+v, want %s (thumb %q)", media, tt.wantURL, tt.wantThumb)
+			}
+
+			// 再構築したHTMLには、板のサイト内のパスが残らない
+			thumbPath := strings.TrimPrefix(tt.wantThumb, "https://dec.2chan.net")
+			page := `<a href="` + strings.TrimPrefix(tt.wantURL, "https://dec.2chan.net") + `"><img src="` + thumbPath + `"></a>`
+			if thumbPath == "" {
+				page = `<a href="` + strings.TrimPrefix(tt.wantURL, "https://dec.2chan.net") + `">`
+			}
+			media[0].LocalPath = "img/" + media[0].OriginalFilename
+			got, err := a.ReconstructHTML(page, model.ThreadInfo{ID: "100"}, media)
+			if err != nil {
+				t.Fatalf("ReconstructHTML() error = %v", err)
+			}
+			if strings.Contains(got, "/src/") || (thumbPath != "" && strings.Contains(got, "/thumb/")) {
+				t.Errorf("ReconstructHTML() = %s", got)
+			}
+		})
+	}
+}
+
+func TestFutabaAdapter_BoardQuirksJSON(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{useJSON: true, boardPath: "/up", quirks: config.FutabaBoardQuirks{SrcPath: "/img/up/src", ThumbExtension: "png"}}
+	src, thumb := a.jsonMediaPaths(futabaJSONPost{Tim: "1700000000000", Ext: ".gif"})
+	if src != "/img/up/src/1700000000000.gif" || thumb != "/up/thumb/1700000000000s.png" {
+		t.Errorf("jsonMediaPaths() = %q, %q", src, thumb)
+	}
+}
+
+func TestFutabaAdapter_BuildCatalogURL_CGIHost(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{quirks: config.FutabaBoardQuirks{CGIHost: "cgi.2chan.net"}}
+	got, err := a.BuildCatalogURL("https://dec.2chan.net/up/")
+	if err != nil {
+		t.Fatalf("BuildCatalogURL() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "https://cgi.2chan.net/up/futaba.php?") {
+		t.Errorf("BuildCatalogURL() = %s", got)
+	}
+}
@@ -0,0 +1,72 @@
+package adapter
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+// futabaUploaderPrefixes は、ふたばのスレッドからリンクされるアップローダのファイル名の接頭辞です。
+// fu・f は板に付属するアップローダ（dec.2chan.net の up2・up）、su・sa・ss・sq・sp は外部のアップローダ（あぷ・あぷ小 など）です。
+// 本文に書かれたファイル名は掲示板がアップローダへのリンクにするため、リンクから取得します。
+var futabaUploaderPrefixes = []string{"fu", "f", "su", "sa", "ss", "sq", "sp"}
+
+// futabaUploaderPattern は、既定の拡張子のアップローダのファイル名のパターンです。
+var futabaUploaderPattern = mustBuildFutabaUploaderPattern(DefaultFutabaMediaExtensions)
+
+// buildFutabaUploaderPattern は、extensions のいずれかの拡張子を持つアップローダのファイル名（例: fu1234567.jpg）のパターンを返します。
+// extensions は buildFutabaMediaPattern で検証済みであることを前提とします。
+func buildFutabaUploaderPattern(extensions []string) (*regexp.Regexp, error) {
+	if len(extensions) == 0 {
+		extensions = DefaultFutabaMediaExtensions
+	}
+	normalized := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		normalized = append(normalized, regexp.QuoteMeta(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))))
+	}
+	return regexp.Compile(`^(?:` + strings.Join(futabaUploaderPrefixes, "|") + `)\d+\.(?i:` + strings.Join(normalized, "|") + `)$`)
+}
+
+func mustBuildFutabaUploaderPattern(extensions []string) *regexp.Regexp {
+	pattern, err := buildFutabaUploaderPattern(extensions)
+	if err != nil {
+		panic(err)
+	}
+	return pattern
+}
+
+// uploaderFilePattern は、アーカイブ対象のアップローダのファイル名のパターンを返します。
+func (a *FutabaAdapter) uploaderFilePattern() *regexp.Regexp {
+	if a.uploaderPattern != nil {
+		return a.uploaderPattern
+	}
+	return futabaUploaderPattern
+}
+
+// uploaderMedia は、リンク先 absURL がアップローダのファイル（<アップローダ>/src/fu1234567.jpg など）であればメディア情報を返します。
+// アップローダのサムネイルはアップローダごとに配置が異なるため、フルサイズのファイルのみを取得します。
+func (a *FutabaAdapter) uploaderMedia(absURL *url.URL, resNumber int) (model.MediaInfo, bool) {
+	if a.skipUploader || !strings.Contains(absURL.Path, "/src/") {
+		return model.MediaInfo{}, false
+	}
+	filename := filepath.Base(absURL.Path)
+	if !a.uploaderFilePattern().MatchString(filename) {
+		return model.MediaInfo{}, false
+	}
+	return model.MediaInfo{URL: absURL.String(), OriginalFilename: filename, ResNumber: resNumber}, true
+}
+
+// isUploaderFile は、mf がアップローダのファイルかを判定します。
+func (a *FutabaAdapter) isUploaderFile(mf model.MediaInfo) bool {
+	return mf.ThumbnailURL == "" && !IsDataURI(mf.URL) && a.uploaderFilePattern().MatchString(filepath.Base(mf.URL))
+}
+
+// replaceUploaderLinks は、アップローダのファイル filename を指すリンク（href・src）をローカルのファイル target へのリンクに書き換えます。
+// アップローダへのリンクは //dec.2chan.net/up2/src/... のようなプロトコル相対URLで書かれることが多いため、属性の値ごと置き換えます。
+func replaceUploaderLinks(htmlContent, filename, target string) string {
+	pattern := regexp.MustCompile(`(href|src)=(["']?)[^"'\s>]*/src/` + regexp.QuoteMeta(filename) + `(["']?)`)
+	return pattern.ReplaceAllString(htmlContent, `${1}=${2}`+strings.ReplaceAll(target, "$", "$$")+`${3}`)
+}
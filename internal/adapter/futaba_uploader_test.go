@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+const futabaUploaderTestThread = `<html><head></head><body>
+<a href="/b/src/1700000000123.jpg" target="_blank"><img src="/b/thumb/1700000000123s.jpg"></a>
+<blockquote>うｐした <a href="//dec.2chan.net/up2/src/fu1234567.jpg" target="_blank">fu1234567.jpg</a><br>
+<a href="http://dec.2chan.net/up/src/f12345.png" target="_blank">f12345.png</a><br>
+<a href='http://www.nijibox5.com/futabafiles/tubu/src/su123456.mp4'>su123456.mp4</a><br>
+<a href="//dec.2chan.net/up2/src/fu1234567.jpg">fu1234567.jpg</a>
+<a href="/b/res/f123.htm">f123.htm</a> <a href="//dec.2chan.net/up2/fu7654321.jpg">一覧</a>
+<a href="//dec.2chan.net/up2/src/fu7777777.exe">fu7777777.exe</a></blockquote>
+</body></html>`
+
+func TestFutabaAdapter_ExtractMediaFiles_Uploader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings *config.FutabaSettings
+		want     []string
+	}{
+		{
+			name: "アップローダのファイルも取得",
+			want: []string{
+				"https://may.2chan.net/b/src/1700000000123.jpg",
+				"https://dec.2chan.net/up2/src/fu1234567.jpg",
+				"http://dec.2chan.net/up/src/f12345.png",
+				"http://www.nijibox5.com/futabafiles/tubu/src/su123456.mp4",
+			},
+		},
+		{
+			name:     "skip_uploader_files",
+			settings: &config.FutabaSettings{SkipUploaderFiles: true},
+			want:     []string{"https://may.2chan.net/b/src/1700000000123.jpg"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := &FutabaAdapter{skipUploader: tt.settings != nil && tt.settings.SkipUploaderFiles}
+			media, err := a.ExtractMediaFiles(futabaUploaderTestThread, "https://may.2chan.net/b/res/1700000000.htm")
+			if err != nil {
+				t.Fatalf("ExtractMediaFiles() error = %v", err)
+			}
+			var got []string
+			for _, mf := range media {
+				got = append(got, mf.URL)
+				if strings.Contains(mf.URL, "/up") || strings.Contains(mf.URL, "nijibox") {
+					if mf.ThumbnailURL != "" {
+						t.Errorf("%s: ThumbnailURL = %q, want empty", mf.URL, mf.ThumbnailURL)
+					}
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("URLs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFutabaAdapter_ReconstructHTML_Uploader(t *testing.T) {
+	t.Parallel()
+
+	a := &FutabaAdapter{}
+	thread := model.ThreadInfo{ID: "1700000000"}
+	media := []model.MediaInfo{
+		{
+			URL:            "https://may.2chan.net/b/src/1700000000123.jpg",
+			ThumbnailURL:   "https://may.2chan.net/b/thumb/1700000000123s.jpg",
+			LocalPath:      "/archive/1700000000/img/1700000000123.jpg",
+			LocalThumbPath: "/archive/1700000000/thumb/1700000000123s.jpg",
+		},
+		{URL: "https://dec.2chan.net/up2/src/fu1234567.jpg", LocalPath: "/archive/1700000000/img/fu1234567.jpg"},
+		{URL: "http://www.nijibox5.com/futabafiles/tubu/src/su123456.mp4", LocalPath: "/archive/1700000000/img/su123456.mp4"},
+		// 保存に失敗したファイルはリンクを書き換えない
+		{URL: "http://dec.2chan.net/up/src/f12345.png"},
+	}
+	got, err := a.ReconstructHTML(futabaUploaderTestThread, thread, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		`<a href="img/fu1234567.jpg" target="_blank">fu1234567.jpg</a>`,
+		`<a href="img/fu1234567.jpg">fu1234567.jpg</a>`,
+		`<a href='img/su123456.mp4'>su123456.mp4</a>`,
+		`<a href="http://dec.2chan.net/up/src/f12345.png"`,
+		`<a href="img/1700000000123.jpg" target="_blank"><img src="thumb/1700000000123s.jpg"></a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ReconstructHTML() does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "up2/img/") {
+		t.Errorf("ReconstructHTML() rewrote the uploader URL partially:\n%s", got)
+	}
+}
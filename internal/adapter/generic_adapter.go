@@ -0,0 +1,332 @@
+package adapter
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// postIDNumberPattern は、レスの要素の id 属性からレス番号を取り出します（例: "reply_123" → 123）。
+var postIDNumberPattern = regexp.MustCompile(`(\d+)\D*$`)
+
+// GenericAdapter は、タスクの generic_settings のCSSセレクタでHTMLを解析する汎用のサイトアダプタです。
+// vichan・wakaba 系などの小規模な掲示板を、専用のアダプタを書かずにアーカイブするためのものです。
+type GenericAdapter struct {
+	settings  config.GenericSettings
+	boardURL  *url.URL
+	threadID  *regexp.Regexp
+	mediaExts map[string]bool
+}
+
+// NewGenericAdapter は、GenericAdapterの新しいインスタンスを返します。Prepare でタスクの設定を読み込むまでは使用できません。
+func NewGenericAdapter() SiteAdapter {
+	return &GenericAdapter{}
+}
+
+// Prepare は、generic_settings のセレクタと正規表現を検証して読み込みます。タスクに認証設定（auth）がある場合は、続けてログインします。
+func (a *GenericAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	if taskConfig.GenericSettings == nil || taskConfig.GenericSettings.ThreadLinkSelector == "" {
+		return fmt.Errorf("generic_settings の thread_link_selector が設定されていません")
+	}
+	a.settings = *taskConfig.GenericSettings
+	for name, selector := range map[string]string{
+		"thread_link_selector": a.settings.ThreadLinkSelector,
+		"title_selector":       a.settings.TitleSelector,
+		"post_selector":        a.settings.PostSelector,
+		"media_link_selector":  a.settings.MediaLinkSelector,
+		"thumbnail_selector":   a.settings.ThumbnailSelector,
+	} {
+		if selector == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(selector); err != nil {
+			return fmt.Errorf("generic_settings の %s %q を解釈できません: %w", name, selector, err)
+		}
+	}
+
+	pattern := a.settings.ThreadIDPattern
+	if pattern == "" {
+		pattern = config.DefaultGenericThreadIDPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("generic_settings の thread_id_pattern が不正です: %w", err)
+	}
+	a.threadID = re
+
+	boardURL, err := url.Parse(taskConfig.TargetBoardURL)
+	if err != nil {
+		return fmt.Errorf("target_board_url の解析に失敗しました: %w", err)
+	}
+	a.boardURL = boardURL
+
+	extensions := taskConfig.MediaExtensions
+	if len(extensions) == 0 {
+		extensions = DefaultFutabaMediaExtensions
+	}
+	a.mediaExts = make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		a.mediaExts[strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))] = true
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、汎用のアダプタが対応している機能を返します。サムネイルは thumbnail_selector を設定した場合のみ抽出できます。
+// レスの表記は掲示板ごとに異なるため、レス番号と削除済みの印には対応していないものとします。
+func (a *GenericAdapter) Capabilities() Capabilities {
+	return Capabilities{Thumbnails: a.settings.ThumbnailSelector != ""}
+}
+
+// BuildCatalogURL は、板のURLに catalog_path を加えたカタログのURLを返します。
+func (a *GenericAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	if a.settings.CatalogPath == "" {
+		return u.String(), nil
+	}
+	ref, err := url.Parse(a.settings.CatalogPath)
+	if err != nil {
+		return "", fmt.Errorf("catalog_path の解析に失敗しました: %w", err)
+	}
+	return u.ResolveReference(ref).String(), nil
+}
+
+// ParseCatalog は、thread_link_selector に一致するリンクをスレッドとして返します。
+// スレッドのURLは板のURLからの相対パスに、タイトルはリンクの文字列（空の場合は title 属性）にします。
+func (a *GenericAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	doc, err := a.parseDocument(htmlBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var threads []model.ThreadInfo
+	seen := make(map[string]bool)
+	doc.Find(a.settings.ThreadLinkSelector).Each(func(_ int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok {
+			return
+		}
+		m := a.threadID.FindStringSubmatch(href)
+		if len(m) < 2 || m[1] == "" || seen[m[1]] {
+			return
+		}
+		seen[m[1]] = true
+
+		title := strings.Join(strings.Fields(s.Text()), " ")
+		if title == "" {
+			title = strings.TrimSpace(s.AttrOr("title", ""))
+		}
+		if title == "" {
+			title = fmt.Sprintf("Thread %s", m[1])
+		}
+		threads = append(threads, model.ThreadInfo{
+			ID:    m[1],
+			Title: title,
+			URL:   a.relativeToBoard(href),
+			Date:  time.Now(), // カタログには投稿日時がないため仮の値
+		})
+	})
+	return threads, nil
+}
+
+// relativeToBoard は、カタログ内のリンクを板のURLからの相対パスにします（ArchiveSingleThread は板のURLにこのパスを連結します）。
+func (a *GenericAdapter) relativeToBoard(href string) string {
+	ref, err := url.Parse(href)
+	if err != nil || a.boardURL == nil {
+		return href
+	}
+	abs := a.boardURL.ResolveReference(ref)
+	boardPath := a.boardURL.Path
+	if !strings.HasSuffix(boardPath, "/") {
+		boardPath = path.Dir(boardPath) + "/"
+	}
+	if abs.Host == a.boardURL.Host && strings.HasPrefix(abs.Path, boardPath) {
+		return strings.TrimPrefix(abs.Path, boardPath)
+	}
+	// 板の外のパス（/res/123.html のような絶対パス）は、板のURLからたどれる形にする
+	depth := strings.Count(strings.Trim(boardPath, "/"), "/") + 1
+	if strings.Trim(boardPath, "/") == "" {
+		depth = 0
+	}
+	return strings.Repeat("../", depth) + strings.TrimPrefix(abs.Path, "/")
+}
+
+// ParseThreadHTML は、スレッドHTMLを UTF-8 に変換して返します。文字コードは内容から判定します。
+func (a *GenericAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	return decodeHTML(htmlBody, unicode.UTF8)
+}
+
+// ExtractMediaFiles は、media_link_selector に一致するリンクのうち、アーカイブ対象の拡張子のものをメディアとして返します。
+// サムネイルはリンク内の thumbnail_selector の要素の src、レス番号はリンクを含む post_selector の要素の id から取得します。
+func (a *GenericAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	base, err := url.Parse(threadURL)
+	if err != nil {
+		return nil, fmt.Errorf("スレッドURLの解析に失敗しました: %w", err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, fmt.Errorf("スレッドHTMLの解析に失敗しました: %w", err)
+	}
+
+	selector := a.settings.MediaLinkSelector
+	if selector == "" {
+		selector = "a[href]"
+	}
+	thumbSelector := a.settings.ThumbnailSelector
+	if thumbSelector == "" {
+		thumbSelector = "img"
+	}
+
+	var media []model.MediaInfo
+	seen := make(map[string]bool)
+	doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok {
+			return
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			return
+		}
+		abs := base.ResolveReference(ref)
+		if !a.isMediaPath(abs.Path) || seen[abs.String()] {
+			return
+		}
+		seen[abs.String()] = true
+
+		info := model.MediaInfo{URL: abs.String(), OriginalFilename: path.Base(abs.Path)}
+		if src, ok := s.Find(thumbSelector).First().Attr("src"); ok && !IsDataURI(src) {
+			if thumbRef, err := url.Parse(src); err == nil {
+				info.ThumbnailURL = base.ResolveReference(thumbRef).String()
+			}
+		}
+		if a.settings.PostSelector != "" {
+			if id, ok := s.Closest(a.settings.PostSelector).Attr("id"); ok {
+				if m := postIDNumberPattern.FindStringSubmatch(id); m != nil {
+					info.ResNumber, _ = strconv.Atoi(m[1])
+				}
+			}
+		}
+		media = append(media, info)
+	})
+	return media, nil
+}
+
+// isMediaPath は、URLのパスの拡張子がアーカイブ対象かを判定します。
+func (a *GenericAdapter) isMediaPath(p string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(p), "."))
+	if a.mediaExts == nil {
+		for _, e := range DefaultFutabaMediaExtensions {
+			if e == ext {
+				return true
+			}
+		}
+		return false
+	}
+	return a.mediaExts[ext]
+}
+
+// ReconstructHTML は、メディアとサムネイルを指す href・src を保存したローカルファイルへのパスに書き換え、script 要素を取り除きます。
+// HTML内のURLはスレッドのURLを基準に絶対URLにしてから照合するため、相対パス・絶対パスのどちらで書かれていても書き換えられます。
+func (a *GenericAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return "", fmt.Errorf("スレッドHTMLの解析に失敗しました: %w", err)
+	}
+	base := a.boardURL
+	if base == nil {
+		base = &url.URL{}
+	}
+	base = base.JoinPath(thread.URL)
+
+	local := make(map[string]string)
+	blocked := make(map[string]bool)
+	for _, mf := range mediaFiles {
+		if mf.Blocked {
+			blocked[mf.URL] = true
+			if mf.ThumbnailURL != "" {
+				blocked[mf.ThumbnailURL] = true
+			}
+			continue
+		}
+		if mf.LocalPath != "" {
+			local[mf.URL] = localLinkPath(mf.LocalPath, "img", path.Base(mf.LocalPath))
+		}
+		if mf.ThumbnailURL != "" && mf.LocalThumbPath != "" {
+			local[mf.ThumbnailURL] = localLinkPath(mf.LocalThumbPath, "thumb", path.Base(mf.LocalThumbPath))
+		}
+	}
+
+	doc.Find("script").Remove()
+	doc.Find("a[href], img[src]").Each(func(_ int, s *goquery.Selection) {
+		attr := "href"
+		if goquery.NodeName(s) == "img" {
+			attr = "src"
+		}
+		value, _ := s.Attr(attr)
+		ref, err := url.Parse(value)
+		if err != nil {
+			return
+		}
+		abs := base.ResolveReference(ref).String()
+		if blocked[abs] {
+			s.Remove()
+			return
+		}
+		if localPath, ok := local[abs]; ok {
+			s.SetAttr(attr, localPath)
+		}
+	})
+	return doc.Html()
+}
+
+// ExtractOPText は、title_selector の要素の文字列を返します（タイトルが定型文の場合の代わりのタイトルに使用されます）。
+func (a *GenericAdapter) ExtractOPText(htmlContent string) string {
+	if a.settings.TitleSelector == "" {
+		return ""
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(doc.Find(a.settings.TitleSelector).First().Text()), " ")
+}
+
+// CountPosts は、post_selector に一致する要素の数を返します。post_selector が未設定の場合は0（不明）です。
+func (a *GenericAdapter) CountPosts(htmlContent string) int {
+	if a.settings.PostSelector == "" {
+		return 0
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return 0
+	}
+	return doc.Find(a.settings.PostSelector).Length()
+}
+
+// parseDocument は、HTMLを UTF-8 に変換してから解析します。
+func (a *GenericAdapter) parseDocument(htmlBody []byte) (*goquery.Document, error) {
+	body, err := decodeHTML(htmlBody, unicode.UTF8)
+	if err != nil {
+		return nil, fmt.Errorf("文字コード変換に失敗しました: %w", err)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("HTMLの解析に失敗しました: %w", err)
+	}
+	return doc, nil
+}
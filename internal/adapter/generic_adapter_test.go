@@ -0,0 +1,170 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+const genericTestCatalog = `<html><body><div id="catalog">
+<div class="thread"><a class="thread-link" href="res/123.html">  Welcome
+ thread </a></div>
+<div class="thread"><a class="thread-link" href="/b/res/456.html" title="Untitled"></a></div>
+<div class="thread"><a class="thread-link" href="res/123.html#q5">duplicate</a></div>
+<div class="thread"><a class="thread-link" href="index.html">no id</a></div>
+<a href="res/789.html">not a thread link</a>
+</div></body></html>`
+
+const genericTestThread = `<html><head><script>track()</script></head><body>
+<h1 class="subject">Welcome thread</h1>
+<div class="post op" id="op_123"><a href="/b/src/1001.png"><img src="/b/thumb/1001.png"></a><div class="body">hello</div></div>
+<div class="post reply" id="reply_124"><a href="../src/1002.webm"><img src="data:image/gif;base64,R0lGODlhAQABAAAAACw="></a></div>
+<div class="post reply" id="reply_125"><a href="https://example.com/b/src/1003.jpg">file</a><a href="https://example.com/b/src/readme.txt">text</a></div>
+</body></html>`
+
+func newTestGenericAdapter(t *testing.T, settings config.GenericSettings) SiteAdapter {
+	t.Helper()
+	a := NewGenericAdapter()
+	task := config.Task{TargetBoardURL: "https://example.com/b/", GenericSettings: &settings}
+	if err := a.Prepare(nil, task); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	return a
+}
+
+func TestGenericAdapter_ParseCatalog(t *testing.T) {
+	t.Parallel()
+
+	a := newTestGenericAdapter(t, config.GenericSettings{ThreadLinkSelector: "a.thread-link", ThreadIDPattern: `res/(\d+)\.html`})
+	threads, err := a.ParseCatalog([]byte(genericTestCatalog))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	if len(threads) != 2 {
+		t.Fatalf("len(threads) = %d, want 2: %+v", len(threads), threads)
+	}
+	tests := []struct {
+		id, title, url string
+	}{
+		{id: "123", title: "Welcome thread", url: "res/123.html"},
+		{id: "456", title: "Untitled", url: "res/456.html"},
+	}
+	for i, tt := range tests {
+		got := threads[i]
+		if got.ID != tt.id || got.Title != tt.title || got.URL != tt.url {
+			t.Errorf("threads[%d] = %+v, want %+v", i, got, tt)
+		}
+	}
+}
+
+func TestGenericAdapter_BuildCatalogURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		catalogPath string
+		want        string
+	}{
+		{name: "未設定は板のURL", want: "https://example.com/b/"},
+		{name: "相対パス", catalogPath: "catalog.html", want: "https://example.com/b/catalog.html"},
+		{name: "絶対パス", catalogPath: "/catalog.php?board=b", want: "https://example.com/catalog.php?board=b"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			a := newTestGenericAdapter(t, config.GenericSettings{ThreadLinkSelector: "a", CatalogPath: tt.catalogPath})
+			got, err := a.BuildCatalogURL("https://example.com/b/")
+			if err != nil {
+				t.Fatalf("BuildCatalogURL() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BuildCatalogURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenericAdapter_Thread(t *testing.T) {
+	t.Parallel()
+
+	a := newTestGenericAdapter(t, config.GenericSettings{
+		ThreadLinkSelector: "a.thread-link",
+		TitleSelector:      "h1.subject",
+		PostSelector:       "div.post",
+	})
+	htmlContent, err := a.ParseThreadHTML([]byte(genericTestThread))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+
+	media, err := a.ExtractMediaFiles(htmlContent, "https://example.com/b/res/123.html")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	if len(media) != 3 {
+		t.Fatalf("len(media) = %d, want 3 (対象外の拡張子は除く): %+v", len(media), media)
+	}
+	if media[0].URL != "https://example.com/b/src/1001.png" || media[0].ThumbnailURL != "https://example.com/b/thumb/1001.png" || media[0].ResNumber != 123 {
+		t.Errorf("media[0] = %+v", media[0])
+	}
+	if media[1].URL != "https://example.com/b/src/1002.webm" || media[1].ThumbnailURL != "" || media[1].ResNumber != 124 {
+		t.Errorf("media[1] = %+v (data URI のサムネイルは対象外)", media[1])
+	}
+	if media[2].URL != "https://example.com/b/src/1003.jpg" || media[2].ResNumber != 125 {
+		t.Errorf("media[2] = %+v", media[2])
+	}
+
+	if got := a.(PostCounter).CountPosts(htmlContent); got != 3 {
+		t.Errorf("CountPosts() = %d, want 3", got)
+	}
+	if got := a.(OPTextExtractor).ExtractOPText(htmlContent); got != "Welcome thread" {
+		t.Errorf("ExtractOPText() = %q", got)
+	}
+
+	media[0].LocalPath = "/archive/123/img/1001.png"
+	media[0].LocalThumbPath = "/archive/123/thumb/1001.png"
+	media[1].Blocked = true
+	media[2].LocalPath = "/archive/123/img/1003.jpg"
+	reconstructed, err := a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "123", URL: "res/123.html"}, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	for _, want := range []string{`href="img/1001.png"`, `src="thumb/1001.png"`, `href="img/1003.jpg"`, `href="https://example.com/b/src/readme.txt"`} {
+		if !strings.Contains(reconstructed, want) {
+			t.Errorf("再構成したHTMLに %s がありません", want)
+		}
+	}
+	for _, unwanted := range []string{"1002.webm", "<script>"} {
+		if strings.Contains(reconstructed, unwanted) {
+			t.Errorf("再構成したHTMLに %s が残っています", unwanted)
+		}
+	}
+}
+
+func TestGenericAdapter_Prepare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings *config.GenericSettings
+	}{
+		{name: "設定なし", settings: nil},
+		{name: "リンクのセレクタなし", settings: &config.GenericSettings{}},
+		{name: "不正なセレクタ", settings: &config.GenericSettings{ThreadLinkSelector: "a[href"}},
+		{name: "不正なサムネイルのセレクタ", settings: &config.GenericSettings{ThreadLinkSelector: "a", ThumbnailSelector: "img::"}},
+		{name: "不正な正規表現", settings: &config.GenericSettings{ThreadLinkSelector: "a", ThreadIDPattern: `(\d+`}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			task := config.Task{TargetBoardURL: "https://example.com/b/", GenericSettings: tt.settings}
+			if err := NewGenericAdapter().Prepare(nil, task); err == nil {
+				t.Error("Prepare() がエラーを返しません")
+			}
+		})
+	}
+}
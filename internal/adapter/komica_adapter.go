@@ -0,0 +1,341 @@
+package adapter
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+var (
+	// カタログ（スレッド一覧・各ページ）のスレッドへのリンク（pixmicat.php?res=123）とリンクの文字列
+	komicaThreadLinkPattern = regexp.MustCompile(`(?is)<a\s[^>]*href=["']?[^"'>]*pixmicat\.php\?res=(\d+)[^"'>]*["']?[^>]*>(.*?)</a>`)
+	// スレッド一覧のリンクの文字列のうち、タイトルではないもの（No.123・回應 など）
+	komicaLinkLabelPattern = regexp.MustCompile(`^(?:No\.\s*)?\d*$|^(?:回應|回应|返信|Reply|檢視|查看)$`)
+	// サムネイル付きの添付ファイル（<a href="src/..."><img src="thumb/..."></a>）
+	komicaThumbLinkPattern = regexp.MustCompile(`(?is)<a\s[^>]*href=["']([^"']+)["'][^>]*>\s*<img\s[^>]*src=["']([^"']+)["']`)
+	// 添付ファイルへのリンク（サムネイルのない形式や、ファイル名のリンク）
+	komicaHrefPattern = regexp.MustCompile(`href=["']([^"']+)["']`)
+	// HTML内の URL を持つ属性（リンクの書き換えに使用）
+	komicaURLAttrPattern = regexp.MustCompile(`(href|src)=(["'])([^"']+)["']`)
+	// 投稿の要素（<div class="post threadpost" id="r123">）
+	komicaPostPattern = regexp.MustCompile(`id=["']r(\d+)["']`)
+	// OPの本文
+	komicaQuotePattern = regexp.MustCompile(`(?is)<div class=["']quote["'][^>]*>(.*?)</div>`)
+	// 投稿日時（23/11/15(三)12:34:56、2023/11/15(三) 12:34 など）
+	komicaPostDatePattern = regexp.MustCompile(`(\d{2}|\d{4})/(\d{1,2})/(\d{1,2})\s*\([^)]{1,3}\)\s*(\d{1,2}):(\d{2})(?::(\d{2}))?`)
+)
+
+// komicaLocation は、Komica の投稿日時の既定のタイムゾーン（台湾時間）です。
+var komicaLocation = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Taipei"); err == nil {
+		return loc
+	}
+	return time.FixedZone("CST", 8*60*60)
+}()
+
+// KomicaAdapter は、Komica（komica.org）など pixmicat 系の掲示板のサイトアダプタです。
+// カタログはスレッド一覧（pixmicat.php?mode=module&load=mod_threadlist）、スレッドは pixmicat.php?res=<スレッドID> から取得します。
+// 文字コードは UTF-8 と Big5 のどちらにも対応します。添付ファイルとサムネイルは別のサーバーに置かれることがあるため、
+// サムネイルのURLはファイル名から推測せず、HTML内のリンクと画像の組から取得します。
+type KomicaAdapter struct {
+	boardURL *url.URL
+	location *time.Location
+	// extensions は、タスクの media_extensions から生成したアーカイブ対象の拡張子です（nil の場合は DefaultFutabaMediaExtensions）。
+	extensions map[string]bool
+}
+
+// NewKomicaAdapter は、KomicaAdapterの新しいインスタンスを返します。
+func NewKomicaAdapter() SiteAdapter {
+	return &KomicaAdapter{}
+}
+
+// Prepare は、板のURL・アーカイブ対象の拡張子・タイムゾーンを設定します。タスクに認証設定（auth）がある場合は、続けてログインします。
+func (a *KomicaAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	u, err := url.Parse(taskConfig.TargetBoardURL)
+	if err != nil {
+		return fmt.Errorf("target_board_url の解析に失敗しました: %w", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("target_board_url にはサイトを含む板のURLを指定してください (url=%s)", taskConfig.TargetBoardURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	a.boardURL = u
+
+	extensions := taskConfig.MediaExtensions
+	if len(extensions) == 0 {
+		extensions = DefaultFutabaMediaExtensions
+	}
+	a.extensions = make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if !extensionPattern.MatchString(ext) {
+			return fmt.Errorf("メディアの拡張子 %q が不正です（英数字のみ指定できます）", ext)
+		}
+		a.extensions[ext] = true
+	}
+
+	a.location = komicaLocation
+	if taskConfig.BoardTimezone != "" {
+		loc, err := time.LoadLocation(taskConfig.BoardTimezone)
+		if err != nil {
+			return fmt.Errorf("board_timezone '%s' を読み込めません: %w", taskConfig.BoardTimezone, err)
+		}
+		a.location = loc
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、Komicaのアダプタが対応している機能を返します。
+func (a *KomicaAdapter) Capabilities() Capabilities {
+	return Capabilities{Thumbnails: true, ResNumbers: true}
+}
+
+// BuildCatalogURL は、pixmicat のスレッド一覧のURLを構築します。
+func (a *KomicaAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	u.Path = path.Join(u.Path, "pixmicat.php")
+	q := url.Values{}
+	q.Set("mode", "module")
+	q.Set("load", "mod_threadlist")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// ParseCatalog は、スレッド一覧（または板の各ページ）のHTMLから pixmicat.php?res=<スレッドID> へのリンクを抽出します。
+// タイトルはリンクの文字列とし、No.123 や「回應」のようなリンクしかない場合は仮のタイトルにします（スレッドの取得後に本文の先頭で補われます）。
+func (a *KomicaAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	body, err := decodeKomicaHTML(htmlBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var threads []model.ThreadInfo
+	index := make(map[string]int)
+	for _, m := range komicaThreadLinkPattern.FindAllStringSubmatch(body, -1) {
+		id := m[1]
+		title := plainText(m[2])
+		if komicaLinkLabelPattern.MatchString(title) {
+			title = ""
+		}
+		if i, ok := index[id]; ok {
+			if threads[i].Title == "" {
+				threads[i].Title = title
+			}
+			continue
+		}
+		index[id] = len(threads)
+		threads = append(threads, model.ThreadInfo{
+			ID:    id,
+			Title: title,
+			URL:   "pixmicat.php?res=" + id,
+			Date:  time.Now(), // スレッド一覧には投稿日時がないため仮の値。スレッドの取得後に ExtractThreadDate の値で置き換える
+		})
+	}
+	for i := range threads {
+		if threads[i].Title == "" {
+			threads[i].Title = fmt.Sprintf("Thread %s", threads[i].ID)
+		}
+	}
+	return threads, nil
+}
+
+// ParseThreadHTML は、スレッドHTMLを UTF-8 に変換して返します。
+func (a *KomicaAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	return decodeKomicaHTML(htmlBody)
+}
+
+// decodeKomicaHTML は、Komica のHTMLを UTF-8 に変換します。
+// UTF-8 として正しい場合や文字コードの宣言がある場合はそれに従い、それ以外は Big5 とみなします
+// （decodeHTML の推定は日本語の文字コードを前提としているため使用しません）。
+func decodeKomicaHTML(body []byte) (string, error) {
+	if utf8.Valid(body) || declaredCharset(body) != nil {
+		return decodeHTML(body, traditionalchinese.Big5)
+	}
+	decoded, err := traditionalchinese.Big5.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", fmt.Errorf("文字コードの変換に失敗しました: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// ExtractMediaFiles は、スレッドHTMLから添付ファイルとそのサムネイルを抽出します。
+// レス番号は、リンクを含む投稿の要素（id="r123"）から求めます。
+func (a *KomicaAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	base, err := url.Parse(threadURL)
+	if err != nil {
+		return nil, fmt.Errorf("スレッドURLの解析に失敗しました: %w", err)
+	}
+	posts := komicaPostPattern.FindAllStringSubmatchIndex(htmlContent, -1)
+	resNumberAt := func(offset int) int {
+		i := sort.Search(len(posts), func(i int) bool { return posts[i][0] > offset })
+		if i == 0 {
+			return 0
+		}
+		n, _ := strconv.Atoi(htmlContent[posts[i-1][2]:posts[i-1][3]])
+		return n
+	}
+
+	var media []model.MediaInfo
+	seen := make(map[string]int) // メディアのURL → media の添字
+	add := func(rawHref, rawThumb string, offset int) {
+		mediaURL, ok := a.resolveMediaURL(base, rawHref)
+		if !ok {
+			return
+		}
+		thumbURL := ""
+		if rawThumb != "" {
+			if u, err := base.Parse(html.UnescapeString(rawThumb)); err == nil {
+				thumbURL = u.String()
+			}
+		}
+		if i, ok := seen[mediaURL]; ok {
+			if media[i].ThumbnailURL == "" {
+				media[i].ThumbnailURL = thumbURL
+			}
+			return
+		}
+		seen[mediaURL] = len(media)
+		media = append(media, model.MediaInfo{
+			URL:              mediaURL,
+			ThumbnailURL:     thumbURL,
+			OriginalFilename: path.Base(mediaURL),
+			ResNumber:        resNumberAt(offset),
+		})
+	}
+	for _, m := range komicaThumbLinkPattern.FindAllStringSubmatchIndex(htmlContent, -1) {
+		add(htmlContent[m[2]:m[3]], htmlContent[m[4]:m[5]], m[0])
+	}
+	for _, m := range komicaHrefPattern.FindAllStringSubmatchIndex(htmlContent, -1) {
+		add(htmlContent[m[2]:m[3]], "", m[0])
+	}
+	return media, nil
+}
+
+// resolveMediaURL は、リンク rawHref が添付ファイル（src/ 以下のアーカイブ対象の拡張子のファイル）であれば絶対URLを返します。
+func (a *KomicaAdapter) resolveMediaURL(base *url.URL, rawHref string) (string, bool) {
+	u, err := base.Parse(html.UnescapeString(rawHref))
+	if err != nil || !strings.Contains(u.Path, "/src/") {
+		return "", false
+	}
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), "."))
+	if a.extensions != nil && !a.extensions[ext] {
+		return "", false
+	}
+	if a.extensions == nil && !extensionPattern.MatchString(ext) {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// ReconstructHTML は、添付ファイルとサムネイルへのリンクを保存したローカルファイルへのリンクに書き換えます。
+// リンクは相対パス・プロトコル相対URL（//img.komica1.org/...）のいずれでも書かれるため、属性ごとに絶対URLに解決して照合します。
+func (a *KomicaAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	htmlContent = regexp.MustCompile(`(?is)<script.*?>.*?</script>`).ReplaceAllString(htmlContent, "")
+	htmlContent = regexp.MustCompile(`(?i)<meta\s+http-equiv=["']?Content-Type["']?[^>]*>`).ReplaceAllString(htmlContent, "")
+	htmlContent = regexp.MustCompile(`(?i)<meta\s+charset=["']?[^"'>]+["']?\s*/?>`).ReplaceAllString(htmlContent, "")
+
+	local := make(map[string]string)
+	var animated []model.MediaInfo
+	for _, mf := range mediaFiles {
+		if mf.Blocked {
+			htmlContent = removeBlockedMedia(htmlContent, mf)
+			continue
+		}
+		if mf.LocalPath == "" {
+			continue
+		}
+		local[mf.URL] = localLinkPath(mf.LocalPath, "img", path.Base(mf.LocalPath))
+		if mf.ThumbnailURL != "" && mf.LocalThumbPath != "" {
+			local[mf.ThumbnailURL] = localLinkPath(mf.LocalThumbPath, "thumb", path.Base(mf.LocalThumbPath))
+			if mf.IsAnimated {
+				animated = append(animated, mf)
+			}
+		}
+	}
+
+	base := a.boardURL
+	if base == nil {
+		base = &url.URL{}
+	}
+	threadPath, query, _ := strings.Cut(thread.URL, "?")
+	base = base.JoinPath(threadPath)
+	base.RawQuery = query
+	htmlContent = komicaURLAttrPattern.ReplaceAllStringFunc(htmlContent, func(attr string) string {
+		m := komicaURLAttrPattern.FindStringSubmatch(attr)
+		u, err := base.Parse(html.UnescapeString(m[3]))
+		if err != nil {
+			return attr
+		}
+		if target, ok := local[u.String()]; ok {
+			return m[1] + "=" + m[2] + target + m[2]
+		}
+		return attr
+	})
+	for _, mf := range animated {
+		htmlContent = markAnimatedThumbnail(htmlContent, local[mf.ThumbnailURL], local[mf.URL])
+	}
+
+	// 本文中のレスへのリンク（#r123）をレスごとのアンカーへのリンクにする
+	htmlContent = addPostAnchors(htmlContent, thread.ID)
+	if strings.Contains(htmlContent, "<head>") {
+		htmlContent = strings.Replace(htmlContent, "<head>", "<head>\n<meta charset=\"UTF-8\">", 1)
+	}
+	return htmlContent, nil
+}
+
+// ExtractOPText は、最初の投稿の本文（<div class="quote">）をプレーンテキストで返します。
+func (a *KomicaAdapter) ExtractOPText(htmlContent string) string {
+	m := komicaQuotePattern.FindStringSubmatch(htmlContent)
+	if len(m) < 2 {
+		return ""
+	}
+	return plainText(m[1])
+}
+
+// ExtractThreadDate は、スレッドHTMLの最初の投稿日時（OPの投稿日時）を板のタイムゾーンで返します。
+// 年は下2桁と4桁のどちらも、秒は省略されていても解釈します。
+func (a *KomicaAdapter) ExtractThreadDate(htmlContent string) (time.Time, bool) {
+	m := komicaPostDatePattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return time.Time{}, false
+	}
+	fields := append([]string{}, m[1:7]...)
+	if len(fields[0]) == 4 {
+		fields[0] = fields[0][2:]
+	}
+	if fields[5] == "" {
+		fields[5] = "0"
+	}
+	loc := a.location
+	if loc == nil {
+		loc = komicaLocation
+	}
+	return parseFutabaDate(fields, loc)
+}
+
+// CountPosts は、スレッドの投稿数（OPを含む）を返します。
+func (a *KomicaAdapter) CountPosts(htmlContent string) int {
+	seen := make(map[string]bool)
+	for _, m := range komicaPostPattern.FindAllStringSubmatch(htmlContent, -1) {
+		seen[m[1]] = true
+	}
+	return len(seen)
+}
@@ -0,0 +1,214 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+const komicaTestCatalog = `<html><head><meta charset="utf-8"></head><body><table>
+<tr><td><a href="pixmicat.php?res=1001">No.1001</a></td><td><a href="pixmicat.php?res=1001&amp;page_num=all">今日的晚餐</a></td></tr>
+<tr><td><a href="pixmicat.php?res=1002">回應</a></td></tr>
+<tr><td><a href="index.htm">返回</a></td></tr>
+</table></body></html>`
+
+const komicaTestThread = `<html><head><meta charset="utf-8"><title>綜合</title><script>var x = 1;</script></head><body>
+<div class="post threadpost" id="r1001">
+<div class="file-text">檔名：<a href="//img.komica1.org/mo/src/1700000000123.jpg" target="_blank">1700000000123.jpg</a></div>
+<a href="//img.komica1.org/mo/src/1700000000123.jpg" target="_blank"><img src="//img.komica1.org/mo/thumb/1700000000123s.jpg" class="img"></a>
+<span class="now">23/11/15(三)12:34:56</span> <span class="qlink">No.1001</span>
+<div class="quote">今日的<br>晚餐</div>
+</div>
+<div class="post reply" id="r1002">
+<span class="now">23/11/15(三)12:40:00</span> <span class="qlink">No.1002</span>
+<a href="src/1700000000200.webm" target="_blank">1700000000200.webm</a>
+<div class="quote"><a href="#r1001">&gt;&gt;No.1001</a> 好吃</div>
+</div>
+<div class="post reply" id="r1003">
+<a href="https://img.komica1.org/mo/src/1700000000300.exe">1700000000300.exe</a>
+<span class="qlink">No.1003</span>
+</div>
+</body></html>`
+
+func newTestKomicaAdapter(t *testing.T, task config.Task) *KomicaAdapter {
+	t.Helper()
+	a := NewKomicaAdapter().(*KomicaAdapter)
+	if err := a.Prepare(nil, task); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	return a
+}
+
+func TestKomicaAdapter_BuildCatalogURL(t *testing.T) {
+	t.Parallel()
+
+	a := NewKomicaAdapter()
+	got, err := a.BuildCatalogURL("https://gita.komica1.org/00b/")
+	if err != nil {
+		t.Fatalf("BuildCatalogURL() error = %v", err)
+	}
+	want := "https://gita.komica1.org/00b/pixmicat.php?load=mod_threadlist&mode=module"
+	if got != want {
+		t.Errorf("BuildCatalogURL() = %q, want %q", got, want)
+	}
+}
+
+func TestKomicaAdapter_ParseCatalog(t *testing.T) {
+	t.Parallel()
+
+	a := newTestKomicaAdapter(t, config.Task{TargetBoardURL: "https://gita.komica1.org/00b/"})
+	threads, err := a.ParseCatalog([]byte(komicaTestCatalog))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	want := []model.ThreadInfo{
+		{ID: "1001", Title: "今日的晚餐", URL: "pixmicat.php?res=1001"},
+		{ID: "1002", Title: "Thread 1002", URL: "pixmicat.php?res=1002"},
+	}
+	if len(threads) != len(want) {
+		t.Fatalf("len(threads) = %d, want %d (%+v)", len(threads), len(want), threads)
+	}
+	for i, tt := range want {
+		got := threads[i]
+		if got.ID != tt.ID || got.Title != tt.Title || got.URL != tt.URL {
+			t.Errorf("threads[%d] = %+v, want %+v", i, got, tt)
+		}
+	}
+}
+
+func TestKomicaAdapter_ParseThreadHTML_Big5(t *testing.T) {
+	t.Parallel()
+
+	const text = `<html><body><div class="quote">今日的晚餐</div></body></html>`
+	big5, err := traditionalchinese.Big5.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatalf("Big5 への変換に失敗しました: %v", err)
+	}
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "UTF-8", body: []byte(text)},
+		{name: "Big5（宣言なし）", body: big5},
+		{name: "Big5（宣言あり）", body: append([]byte(`<meta charset="big5">`), big5...)},
+	}
+	a := NewKomicaAdapter()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := a.ParseThreadHTML(tt.body)
+			if err != nil {
+				t.Fatalf("ParseThreadHTML() error = %v", err)
+			}
+			if !strings.Contains(got, "今日的晚餐") {
+				t.Errorf("ParseThreadHTML() = %q, want to contain 今日的晚餐", got)
+			}
+		})
+	}
+}
+
+func TestKomicaAdapter_ExtractMediaFiles(t *testing.T) {
+	t.Parallel()
+
+	a := newTestKomicaAdapter(t, config.Task{TargetBoardURL: "https://gita.komica1.org/00b/"})
+	media, err := a.ExtractMediaFiles(komicaTestThread, "https://gita.komica1.org/00b/pixmicat.php?res=1001")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	want := []model.MediaInfo{
+		{
+			URL:              "https://img.komica1.org/mo/src/1700000000123.jpg",
+			ThumbnailURL:     "https://img.komica1.org/mo/thumb/1700000000123s.jpg",
+			OriginalFilename: "1700000000123.jpg",
+			ResNumber:        1001,
+		},
+		{
+			URL:              "https://gita.komica1.org/00b/src/1700000000200.webm",
+			OriginalFilename: "1700000000200.webm",
+			ResNumber:        1002,
+		},
+	}
+	if len(media) != len(want) {
+		t.Fatalf("len(media) = %d, want %d (%+v)", len(media), len(want), media)
+	}
+	for i, tt := range want {
+		if media[i] != tt {
+			t.Errorf("media[%d] = %+v, want %+v", i, media[i], tt)
+		}
+	}
+}
+
+func TestKomicaAdapter_ReconstructHTML(t *testing.T) {
+	t.Parallel()
+
+	a := newTestKomicaAdapter(t, config.Task{TargetBoardURL: "https://gita.komica1.org/00b/"})
+	thread := model.ThreadInfo{ID: "1001", URL: "pixmicat.php?res=1001"}
+	media, err := a.ExtractMediaFiles(komicaTestThread, "https://gita.komica1.org/00b/pixmicat.php?res=1001")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	media[0].LocalPath = "/archive/1001/img/1700000000123.jpg"
+	media[0].LocalThumbPath = "/archive/1001/thumb/1700000000123s.jpg"
+	media[1].Blocked = true
+
+	got, err := a.ReconstructHTML(komicaTestThread, thread, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	for _, want := range []string{
+		`href="img/1700000000123.jpg"`,
+		`src="thumb/1700000000123s.jpg"`,
+		`<meta charset="UTF-8">`,
+		`id="p1001"`,
+		`href="#p1001"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ReconstructHTML() does not contain %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"//img.komica1.org/mo/src/1700000000123.jpg", "<script", "1700000000200.webm"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("ReconstructHTML() contains %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestKomicaAdapter_ThreadInfo(t *testing.T) {
+	t.Parallel()
+
+	a := newTestKomicaAdapter(t, config.Task{TargetBoardURL: "https://gita.komica1.org/00b/"})
+	if got, want := a.ExtractOPText(komicaTestThread), "今日的 晚餐"; got != want {
+		t.Errorf("ExtractOPText() = %q, want %q", got, want)
+	}
+	if got, want := a.CountPosts(komicaTestThread), 3; got != want {
+		t.Errorf("CountPosts() = %d, want %d", got, want)
+	}
+
+	tests := []struct {
+		name string
+		html string
+		want time.Time
+		ok   bool
+	}{
+		{name: "下2桁の年", html: `23/11/15(三)12:34:56`, want: time.Date(2023, 11, 15, 12, 34, 56, 0, komicaLocation), ok: true},
+		{name: "4桁の年・秒なし", html: `2023/11/15(三) 12:34`, want: time.Date(2023, 11, 15, 12, 34, 0, 0, komicaLocation), ok: true},
+		{name: "不正な日付", html: `23/13/15(三)12:34:56`, ok: false},
+		{name: "日時なし", html: `<div></div>`, ok: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := a.ExtractThreadDate(tt.html)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("ExtractThreadDate() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package adapter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// lazySrcPattern は、遅延読み込み用の属性に置かれた画像URL（data-src="..." など）を検出します。
+	lazySrcPattern = regexp.MustCompile(`(?i)\s(?:data-src|data-original|data-lazy-src)\s*=\s*["']([^"']+)["']`)
+	// srcsetPattern は、srcset / data-srcset 属性の値を検出します。
+	srcsetPattern = regexp.MustCompile(`(?i)\s(?:data-)?srcset\s*=\s*["']([^"']+)["']`)
+	// imageTagPattern は、遅延読み込みの解決対象となる img / source タグです。
+	imageTagPattern = regexp.MustCompile(`(?is)<(?:img|source)\b[^>]*>`)
+)
+
+// lazySrcAttributes は、遅延読み込みの実装で本来の画像URLを保持するために使われる属性です（優先順）。
+var lazySrcAttributes = []string{"data-src", "data-original", "data-lazy-src"}
+
+// extractLazyMediaURLs は、遅延読み込み用の属性と srcset から、メディアの候補URLを抽出します。
+// srcset からは最も解像度の高い候補のみを返します。
+func extractLazyMediaURLs(htmlContent string) []string {
+	var urls []string
+	for _, m := range lazySrcPattern.FindAllStringSubmatch(htmlContent, -1) {
+		urls = append(urls, strings.TrimSpace(m[1]))
+	}
+	for _, m := range srcsetPattern.FindAllStringSubmatch(htmlContent, -1) {
+		if best := bestSrcsetCandidate(m[1]); best != "" {
+			urls = append(urls, best)
+		}
+	}
+	return urls
+}
+
+// srcsetCandidate は、srcset の候補の1つです。
+type srcsetCandidate struct {
+	url        string
+	descriptor string // "2x" や "640w"。省略時は空
+}
+
+// parseSrcset は、srcset 属性の値を候補ごとに分解します。
+func parseSrcset(value string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		c := srcsetCandidate{url: fields[0]}
+		if len(fields) > 1 {
+			c.descriptor = fields[1]
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates
+}
+
+// bestSrcsetCandidate は、srcset のうち最も大きい（幅または倍率が最大の）候補のURLを返します。
+// 記述子のない候補は 1x として扱います。
+func bestSrcsetCandidate(value string) string {
+	best, bestScore := "", -1.0
+	for _, c := range parseSrcset(value) {
+		score := 1.0
+		if d := strings.ToLower(c.descriptor); len(d) > 1 {
+			if n, err := strconv.ParseFloat(d[:len(d)-1], 64); err == nil {
+				score = n
+				if strings.HasSuffix(d, "x") {
+					// 倍率は幅と比較できないため、一般的な表示幅を掛けて近似する
+					score = n * 1000
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = c.url, score
+		}
+	}
+	return best
+}
+
+// resolveLazyImages は、遅延読み込みの画像をアーカイブ上でそのまま表示できるように書き換えます。
+// data-src などに保持された画像URLを src に移し、srcset からはローカルに保存されていない候補を取り除きます。
+// ローカルの候補が残らない srcset は、src が使われるよう属性ごと削除します。
+// メディアのリンクをローカルパスに置換した後に呼び出します。
+func resolveLazyImages(htmlContent string) string {
+	return imageTagPattern.ReplaceAllStringFunc(htmlContent, func(tag string) string {
+		for _, attr := range lazySrcAttributes {
+			if value, ok := tagAttribute(tag, attr); ok && isLocalLink(value) {
+				tag = setTagAttribute(tag, "src", value)
+				break
+			}
+		}
+
+		// data-srcset は srcset として扱う（srcset が既にある場合は srcset を優先）
+		if value, ok := tagAttribute(tag, "data-srcset"); ok {
+			tag = removeTagAttribute(tag, "data-srcset")
+			if _, exists := tagAttribute(tag, "srcset"); !exists {
+				tag = setTagAttribute(tag, "srcset", value)
+			}
+		}
+		if value, ok := tagAttribute(tag, "srcset"); ok {
+			var local []string
+			for _, c := range parseSrcset(value) {
+				if isLocalLink(c.url) {
+					local = append(local, strings.TrimSpace(c.url+" "+c.descriptor))
+				}
+			}
+			if len(local) == 0 {
+				tag = removeTagAttribute(tag, "srcset")
+			} else {
+				tag = setTagAttribute(tag, "srcset", strings.Join(local, ", "))
+			}
+		}
+		return tag
+	})
+}
+
+// isLocalLink は、リンクがアーカイブ内のファイル（img/ や thumb/ などの相対パス）を指しているかを判定します。
+func isLocalLink(link string) bool {
+	link = strings.TrimSpace(link)
+	if link == "" || strings.HasPrefix(link, "/") || IsDataURI(link) {
+		return false
+	}
+	return !strings.Contains(link, "://")
+}
+
+// attributePatterns は、resolveLazyImages で扱う属性の正規表現です。タグごとに再コンパイルしないよう事前に用意します。
+var attributePatterns = func() map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp)
+	for _, name := range append([]string{"src", "srcset", "data-srcset"}, lazySrcAttributes...) {
+		patterns[name] = compileAttributePattern(name)
+	}
+	return patterns
+}()
+
+// attributePattern は、タグ内の指定された属性（name="value" / name='value' / name=value）を検出する正規表現を返します。
+func attributePattern(name string) *regexp.Regexp {
+	if re, ok := attributePatterns[name]; ok {
+		return re
+	}
+	return compileAttributePattern(name)
+}
+
+func compileAttributePattern(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+}
+
+// tagAttribute は、タグから属性の値を取り出します。
+func tagAttribute(tag, name string) (string, bool) {
+	m := attributePattern(name).FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	return strings.Trim(m[1], `"'`), true
+}
+
+// setTagAttribute は、タグの属性の値を設定します。属性がない場合はタグ名の直後に追加します。
+func setTagAttribute(tag, name, value string) string {
+	attr := " " + name + `="` + strings.ReplaceAll(value, `"`, "&quot;") + `"`
+	re := attributePattern(name)
+	if loc := re.FindStringIndex(tag); loc != nil {
+		return tag[:loc[0]] + attr + tag[loc[1]:]
+	}
+	end := strings.IndexAny(tag, " \t\r\n/>")
+	if end < 0 {
+		return tag
+	}
+	return tag[:end] + attr + tag[end:]
+}
+
+// removeTagAttribute は、タグから属性を削除します。
+func removeTagAttribute(tag, name string) string {
+	return attributePattern(name).ReplaceAllString(tag, "")
+}
@@ -0,0 +1,49 @@
+package adapter
+
+import (
+	"strings"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// commonMaintenanceMarkers は、多くの掲示板・ホスティングに共通する、メンテナンス中・障害中のページの表示です。
+var commonMaintenanceMarkers = []string{
+	"ただいまメンテナンス中",
+	"只今メンテナンス中",
+	"メンテナンス中です",
+	"Service Temporarily Unavailable",
+	"503 Service Unavailable",
+}
+
+// futabaMaintenanceMarkers は、ふたばのメンテナンス中・混雑時のページの表示です。
+var futabaMaintenanceMarkers = append([]string{
+	"サーバーが混雑しています",
+	"サーバが混雑しています",
+	"アクセスが集中しています",
+}, commonMaintenanceMarkers...)
+
+// findMaintenanceMarker は、text に含まれる最初の markers の表示を返します。
+func findMaintenanceMarker(text string, markers []string) (string, bool) {
+	for _, marker := range markers {
+		if strings.Contains(text, marker) {
+			return marker, true
+		}
+	}
+	return "", false
+}
+
+// DetectMaintenance は、ふたばのメンテナンス中・混雑時のページを判別します。
+// レス番号（No.123）やカタログのスレッドへのリンクがあるページは、本文に同じ語句が書かれていても通常のページとして扱います。
+func (a *FutabaAdapter) DetectMaintenance(body []byte) (string, bool) {
+	if isFutabaJSON(body) {
+		return "", false
+	}
+	text, err := decodeHTML(body, japanese.ShiftJIS)
+	if err != nil {
+		return "", false
+	}
+	if postNumberPattern.MatchString(text) || catalogLinkPattern.MatchString(text) {
+		return "", false
+	}
+	return findMaintenanceMarker(text, futabaMaintenanceMarkers)
+}
@@ -0,0 +1,44 @@
+package adapter
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestFutabaAdapter_DetectMaintenance(t *testing.T) {
+	t.Parallel()
+
+	sjis := func(s string) []byte {
+		b, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	tests := []struct {
+		name       string
+		body       []byte
+		wantMarker string
+	}{
+		{name: "メンテナンス", body: sjis(`<html><body><h1>只今メンテナンス中です</h1></body></html>`), wantMarker: "只今メンテナンス中"},
+		{name: "混雑", body: sjis(`<html><body>サーバーが混雑しています。しばらくお待ちください</body></html>`), wantMarker: "サーバーが混雑しています"},
+		{name: "プロキシのエラー", body: []byte(`<html><title>503 Service Unavailable</title></html>`), wantMarker: "503 Service Unavailable"},
+		// レスがあるページは、本文に同じ語句があっても通常のスレッド
+		{name: "スレッド", body: sjis(`<div class="thre">No.123<blockquote>只今メンテナンス中です</blockquote></div>`)},
+		{name: "カタログ", body: sjis(`<table><td><a href="res/123.htm">メンテナンス中です</a></td></table>`)},
+		{name: "JSON", body: []byte(`{"res": {}, "die": "メンテナンス中です"}`)},
+		{name: "空のページ", body: []byte(`<html></html>`)},
+	}
+	a := &FutabaAdapter{}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			marker, ok := a.DetectMaintenance(tt.body)
+			if ok != (tt.wantMarker != "") || marker != tt.wantMarker {
+				t.Errorf("DetectMaintenance() = %q, %v, want %q", marker, ok, tt.wantMarker)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package adapter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultFutabaMediaExtensions は、ふたばアダプタが既定でアーカイブするメディアの拡張子です。
+// タスクの media_extensions、または adapter_settings で変更できます。
+var DefaultFutabaMediaExtensions = []string{
+	"jpg", "jpeg", "png", "webp", "gif", "webm", "mp4", "mp3", "wav",
+	"flac", "ogg", "opus", "pdf", "zip", "spch", "pch",
+}
+
+// noThumbnailExtensions は、掲示板側でサムネイルが生成されない形式です。
+// これらのファイルについては、存在しないサムネイルの取得を試みません。
+var noThumbnailExtensions = map[string]bool{
+	"flac": true,
+	"ogg":  true,
+	"opus": true,
+	"pdf":  true,
+	"zip":  true,
+	"spch": true, // お絵かきのアニメーション（しぃペインター）
+	"pch":  true, // お絵かきのアニメーション（PaintBBS）
+}
+
+// extensionPattern は、拡張子として受け付ける文字列です（英数字のみ）。
+var extensionPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// buildFutabaMediaPattern は、指定された拡張子のふたばのメディアファイル名
+// （13桁以上の数字 + 任意の 's' + 拡張子）を検出する正規表現を生成します。
+func buildFutabaMediaPattern(extensions []string) (*regexp.Regexp, error) {
+	if len(extensions) == 0 {
+		extensions = DefaultFutabaMediaExtensions
+	}
+	normalized := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		if !extensionPattern.MatchString(ext) {
+			return nil, fmt.Errorf("メディアの拡張子 %q が不正です（英数字のみ指定できます）", ext)
+		}
+		normalized = append(normalized, ext)
+	}
+	return regexp.Compile(`(\d{13,})(s?)\.(` + strings.Join(normalized, "|") + `)`)
+}
+
+// hasThumbnail は、ファイル名の形式に掲示板側のサムネイルがあるかを判定します。
+func hasThumbnail(filename string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepathExt(filename), "."))
+	return !noThumbnailExtensions[ext]
+}
+
+// filepathExt は、URLパスやファイル名から拡張子を返します（クエリ文字列は含みません）。
+func filepathExt(name string) string {
+	name, _, _ = strings.Cut(name, "?")
+	if i := strings.LastIndex(name, "."); i >= 0 && !strings.Contains(name[i:], "/") {
+		return name[i:]
+	}
+	return ""
+}
@@ -0,0 +1,255 @@
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// PluginProtocolVersion は、外部プログラムのサイトアダプタとの通信形式のバージョンです。
+const PluginProtocolVersion = 1
+
+// 外部プログラムに渡すメソッド名
+const (
+	pluginMethodPrepare           = "prepare"
+	pluginMethodBuildCatalogURL   = "build_catalog_url"
+	pluginMethodParseCatalog      = "parse_catalog"
+	pluginMethodParseThreadHTML   = "parse_thread_html"
+	pluginMethodExtractMediaFiles = "extract_media_files"
+	pluginMethodReconstructHTML   = "reconstruct_html"
+)
+
+// PluginAdapter は、adapter_plugins に定義した外部プログラムに処理を委ねるサイトアダプタです。
+//
+// 呼び出しごとにプログラムを起動し、標準入力に1つのJSONのリクエストを書き込んで、標準出力から1つのJSONのレスポンスを読み取ります。
+//
+//	リクエスト: {"protocol_version": 1, "method": "parse_catalog", "task": {...}, "params": {...}}
+//	レスポンス: {"result": ...} または {"error": "メッセージ"}
+//
+// 標準エラー出力はログに記録します。HTMLなどのバイト列（body）は Base64 でエンコードされます。
+type PluginAdapter struct {
+	name   string
+	plugin config.AdapterPlugin
+	task   pluginTask
+}
+
+// pluginTask は、リクエストごとに外部プログラムに渡すタスクの設定です。
+type pluginTask struct {
+	TaskName        string         `json:"task_name"`
+	TargetBoardURL  string         `json:"target_board_url"`
+	MediaExtensions []string       `json:"media_extensions,omitempty"`
+	BoardTimezone   string         `json:"board_timezone,omitempty"`
+	Settings        map[string]any `json:"settings,omitempty"`
+}
+
+// pluginThread は、外部プログラムとやり取りするスレッドの情報です。
+type pluginThread struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	URL      string    `json:"url"`
+	ResCount int       `json:"res_count,omitempty"`
+	Date     time.Time `json:"date,omitempty"`
+}
+
+// pluginMedia は、外部プログラムとやり取りするメディアの情報です。
+type pluginMedia struct {
+	URL              string `json:"url"`
+	ThumbnailURL     string `json:"thumbnail_url,omitempty"`
+	OriginalFilename string `json:"original_filename,omitempty"`
+	ResNumber        int    `json:"res_number,omitempty"`
+	LocalPath        string `json:"local_path,omitempty"`
+	LocalThumbPath   string `json:"local_thumb_path,omitempty"`
+	Blocked          bool   `json:"blocked,omitempty"`
+}
+
+type pluginRequest struct {
+	ProtocolVersion int        `json:"protocol_version"`
+	Method          string     `json:"method"`
+	Task            pluginTask `json:"task"`
+	Params          any        `json:"params,omitempty"`
+}
+
+type pluginResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// NewPluginAdapter は、外部プログラム plugin をサイトアダプタ name として使用する PluginAdapter を返します。
+func NewPluginAdapter(name string, plugin config.AdapterPlugin) SiteAdapter {
+	return &PluginAdapter{name: name, plugin: plugin}
+}
+
+// Prepare は、タスクの設定を保持して外部プログラムの prepare を呼び出します（設定の検証などに使用されます）。
+// タスクに認証設定（auth）がある場合は、GIBAの HTTP クライアントでログインします。
+func (a *PluginAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	a.task = pluginTask{
+		TaskName:        taskConfig.TaskName,
+		TargetBoardURL:  taskConfig.TargetBoardURL,
+		MediaExtensions: taskConfig.MediaExtensions,
+		BoardTimezone:   taskConfig.BoardTimezone,
+		Settings:        taskConfig.PluginSettings,
+	}
+	if err := a.call(pluginMethodPrepare, nil, nil); err != nil {
+		return err
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、adapter_plugins の capabilities に指定された機能を返します。
+// 省略時は、サムネイルとレス番号に対応しているものとします。
+func (a *PluginAdapter) Capabilities() Capabilities {
+	if a.plugin.Capabilities == nil {
+		return Capabilities{Thumbnails: true, ResNumbers: true}
+	}
+	var caps Capabilities
+	for _, capability := range a.plugin.Capabilities {
+		switch capability {
+		case config.PluginCapabilityJSONAPI:
+			caps.JSONAPI = true
+		case config.PluginCapabilityThumbnails:
+			caps.Thumbnails = true
+		case config.PluginCapabilityDeletionMarkers:
+			caps.DeletionMarkers = true
+		case config.PluginCapabilityResNumbers:
+			caps.ResNumbers = true
+		}
+	}
+	return caps
+}
+
+// BuildCatalogURL は、外部プログラムが返すカタログのURLを返します。
+func (a *PluginAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	var catalogURL string
+	err := a.call(pluginMethodBuildCatalogURL, map[string]any{"base_url": baseURL}, &catalogURL)
+	return catalogURL, err
+}
+
+// ParseCatalog は、外部プログラムにカタログのボディを渡し、スレッドの一覧を受け取ります。
+func (a *PluginAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	var threads []pluginThread
+	if err := a.call(pluginMethodParseCatalog, map[string]any{"body": htmlBody}, &threads); err != nil {
+		return nil, err
+	}
+	result := make([]model.ThreadInfo, 0, len(threads))
+	for _, t := range threads {
+		if t.ID == "" || t.URL == "" {
+			return nil, fmt.Errorf("アダプタ '%s' が id または url のないスレッドを返しました", a.name)
+		}
+		if t.Date.IsZero() {
+			t.Date = time.Now() // 投稿日時がない場合は他のアダプタと同じく仮の値
+		}
+		result = append(result, model.ThreadInfo{ID: t.ID, Title: t.Title, URL: t.URL, ResCount: t.ResCount, Date: t.Date})
+	}
+	return result, nil
+}
+
+// ParseThreadHTML は、外部プログラムにスレッドのボディを渡し、保存用のHTMLを受け取ります。
+func (a *PluginAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	var htmlContent string
+	err := a.call(pluginMethodParseThreadHTML, map[string]any{"body": htmlBody}, &htmlContent)
+	return htmlContent, err
+}
+
+// ExtractMediaFiles は、外部プログラムが返すメディアの一覧を返します。
+func (a *PluginAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	var media []pluginMedia
+	if err := a.call(pluginMethodExtractMediaFiles, map[string]any{"html": htmlContent, "thread_url": threadURL}, &media); err != nil {
+		return nil, err
+	}
+	result := make([]model.MediaInfo, 0, len(media))
+	for _, m := range media {
+		if m.URL == "" {
+			return nil, fmt.Errorf("アダプタ '%s' が url のないメディアを返しました", a.name)
+		}
+		result = append(result, model.MediaInfo{URL: m.URL, ThumbnailURL: m.ThumbnailURL, OriginalFilename: m.OriginalFilename, ResNumber: m.ResNumber})
+	}
+	return result, nil
+}
+
+// ReconstructHTML は、外部プログラムに保存先のパスを含むメディアの一覧を渡し、リンクを書き換えたHTMLを受け取ります。
+func (a *PluginAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	media := make([]pluginMedia, 0, len(mediaFiles))
+	for _, mf := range mediaFiles {
+		m := pluginMedia{URL: mf.URL, ThumbnailURL: mf.ThumbnailURL, OriginalFilename: mf.OriginalFilename, ResNumber: mf.ResNumber, Blocked: mf.Blocked}
+		if mf.LocalPath != "" {
+			m.LocalPath = localLinkPath(mf.LocalPath, "img", filepath.Base(mf.LocalPath))
+		}
+		if mf.LocalThumbPath != "" {
+			m.LocalThumbPath = localLinkPath(mf.LocalThumbPath, "thumb", filepath.Base(mf.LocalThumbPath))
+		}
+		media = append(media, m)
+	}
+	params := map[string]any{
+		"html":        htmlContent,
+		"thread":      pluginThread{ID: thread.ID, Title: thread.Title, URL: thread.URL, ResCount: thread.ResCount, Date: thread.Date},
+		"media_files": media,
+	}
+	var reconstructed string
+	err := a.call(pluginMethodReconstructHTML, params, &reconstructed)
+	return reconstructed, err
+}
+
+// call は、外部プログラムを起動して method を呼び出し、レスポンスの result を out にデコードします（out が nil の場合は読み捨てます）。
+func (a *PluginAdapter) call(method string, params any, out any) error {
+	request, err := json.Marshal(pluginRequest{ProtocolVersion: PluginProtocolVersion, Method: method, Task: a.task, Params: params})
+	if err != nil {
+		return fmt.Errorf("アダプタ '%s' へのリクエストの作成に失敗しました (method=%s): %w", a.name, method, err)
+	}
+
+	timeout := a.plugin.TimeoutSec
+	if timeout <= 0 {
+		timeout = config.DefaultPluginTimeoutSec
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, a.plugin.Command, a.plugin.Args...)
+	cmd.Stdin = bytes.NewReader(request)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	for _, line := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+		if line != "" {
+			log.Printf("[plugin:%s] %s", a.name, line)
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("アダプタ '%s' が %d 秒以内に終了しませんでした (method=%s)", a.name, timeout, method)
+	}
+	if runErr != nil && stdout.Len() == 0 {
+		return fmt.Errorf("アダプタ '%s' の実行に失敗しました (method=%s): %w", a.name, method, runErr)
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return fmt.Errorf("アダプタ '%s' のレスポンスを解析できません (method=%s): %w", a.name, method, err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("アダプタ '%s' がエラーを返しました (method=%s): %w", a.name, method, errors.New(response.Error))
+	}
+	if runErr != nil {
+		return fmt.Errorf("アダプタ '%s' が異常終了しました (method=%s): %w", a.name, method, runErr)
+	}
+	if out == nil {
+		return nil
+	}
+	if len(response.Result) == 0 {
+		return fmt.Errorf("アダプタ '%s' のレスポンスに result がありません (method=%s)", a.name, method)
+	}
+	if err := json.Unmarshal(response.Result, out); err != nil {
+		return fmt.Errorf("アダプタ '%s' の result の形式が不正です (method=%s): %w", a.name, method, err)
+	}
+	return nil
+}
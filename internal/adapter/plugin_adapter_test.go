@@ -0,0 +1,178 @@
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// pluginHelperArg は、テストのバイナリを外部プログラムのサイトアダプタとして起動したことを示す引数です。
+const pluginHelperArg = "giba-plugin-helper"
+
+// TestPluginHelperProcess は、テストのバイナリを外部プログラムとして起動した場合にのみ動作する、テスト用のアダプタです。
+// 最後の引数で動作（normal / error / broken / slow）を切り替えます。
+func TestPluginHelperProcess(t *testing.T) {
+	args := os.Args
+	if len(args) < 2 || args[len(args)-2] != pluginHelperArg {
+		return
+	}
+	mode := args[len(args)-1]
+	defer os.Exit(0)
+
+	var req struct {
+		ProtocolVersion int            `json:"protocol_version"`
+		Method          string         `json:"method"`
+		Task            map[string]any `json:"task"`
+		Params          map[string]any `json:"params"`
+	}
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid request:", err)
+		os.Exit(2)
+	}
+	fmt.Fprintln(os.Stderr, "called", req.Method)
+	switch mode {
+	case "error":
+		fmt.Print(`{"error": "board is closed"}`)
+		return
+	case "broken":
+		fmt.Print(`not json`)
+		return
+	case "slow":
+		time.Sleep(5 * time.Second)
+		return
+	}
+
+	var result any
+	switch req.Method {
+	case "prepare":
+		if req.Task["target_board_url"] != "https://example.com/b/" {
+			fmt.Printf(`{"error": "unexpected task: %v"}`, req.Task)
+			return
+		}
+	case "build_catalog_url":
+		result = req.Params["base_url"].(string) + "catalog.json"
+	case "parse_catalog":
+		result = []map[string]any{{"id": "42", "title": "hello", "url": "res/42.html", "res_count": 3}}
+	case "parse_thread_html":
+		result = "<p>" + req.Params["body"].(string) + "</p>"
+	case "extract_media_files":
+		result = []map[string]any{{"url": req.Params["thread_url"].(string) + "/1.jpg", "thumbnail_url": "https://example.com/t/1s.jpg", "res_number": 2}}
+	case "reconstruct_html":
+		media := req.Params["media_files"].([]any)[0].(map[string]any)
+		result = fmt.Sprintf("%s|%v|%v|%v", req.Params["html"], req.Params["thread"].(map[string]any)["id"], media["local_path"], media["local_thumb_path"])
+	default:
+		fmt.Printf(`{"error": "unknown method %s"}`, req.Method)
+		return
+	}
+	out, _ := json.Marshal(map[string]any{"result": result})
+	os.Stdout.Write(out)
+}
+
+func newTestPluginAdapter(t *testing.T, mode string, timeoutSec int) SiteAdapter {
+	t.Helper()
+	plugin := config.AdapterPlugin{
+		Command:    os.Args[0],
+		Args:       []string{"-test.run=^TestPluginHelperProcess$", pluginHelperArg, mode},
+		TimeoutSec: timeoutSec,
+	}
+	return NewPluginAdapter("helper", plugin)
+}
+
+func TestPluginAdapter(t *testing.T) {
+	t.Parallel()
+
+	a := newTestPluginAdapter(t, "normal", 0)
+	if err := a.Prepare(nil, config.Task{TaskName: "t", TargetBoardURL: "https://example.com/b/"}); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+
+	catalogURL, err := a.BuildCatalogURL("https://example.com/b/")
+	if err != nil || catalogURL != "https://example.com/b/catalog.json" {
+		t.Errorf("BuildCatalogURL() = %q, %v", catalogURL, err)
+	}
+
+	threads, err := a.ParseCatalog([]byte("<html>"))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	if len(threads) != 1 || threads[0].ID != "42" || threads[0].URL != "res/42.html" || threads[0].ResCount != 3 || threads[0].Date.IsZero() {
+		t.Errorf("ParseCatalog() = %+v", threads)
+	}
+
+	// body は Base64 で渡される
+	htmlContent, err := a.ParseThreadHTML([]byte("abc"))
+	if err != nil || htmlContent != "<p>YWJj</p>" {
+		t.Errorf("ParseThreadHTML() = %q, %v", htmlContent, err)
+	}
+
+	media, err := a.ExtractMediaFiles(htmlContent, "https://example.com/b/res/42")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	if len(media) != 1 || media[0].URL != "https://example.com/b/res/42/1.jpg" || media[0].ThumbnailURL != "https://example.com/t/1s.jpg" || media[0].ResNumber != 2 {
+		t.Errorf("ExtractMediaFiles() = %+v", media)
+	}
+
+	media[0].LocalPath = "/archive/42/img/1.jpg"
+	media[0].LocalThumbPath = "/archive/42/thumb/1s.jpg"
+	reconstructed, err := a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "42"}, media)
+	if err != nil || reconstructed != "<p>YWJj</p>|42|img/1.jpg|thumb/1s.jpg" {
+		t.Errorf("ReconstructHTML() = %q, %v", reconstructed, err)
+	}
+}
+
+func TestPluginAdapter_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		mode       string
+		timeoutSec int
+		wantErr    string
+	}{
+		{name: "エラーを返す", mode: "error", wantErr: "board is closed"},
+		{name: "JSONでない出力", mode: "broken", wantErr: "レスポンスを解析できません"},
+		{name: "タイムアウト", mode: "slow", timeoutSec: 1, wantErr: "終了しませんでした"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := newTestPluginAdapter(t, tt.mode, tt.timeoutSec).BuildCatalogURL("https://example.com/b/")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildCatalogURL() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewPluginAdapter("missing", config.AdapterPlugin{Command: "giba-no-such-plugin"}).BuildCatalogURL("https://example.com/"); err == nil {
+		t.Error("存在しないプログラムでエラーになりません")
+	}
+}
+
+func TestRegisterPlugins(t *testing.T) {
+	if err := RegisterPlugins(map[string]config.AdapterPlugin{"futaba": {Command: "x"}}); err == nil {
+		t.Error("組み込みのアダプタと同じ名前でエラーになりません")
+	}
+	if err := RegisterPlugins(map[string]config.AdapterPlugin{"myboard": {Command: "x"}}); err != nil {
+		t.Fatalf("RegisterPlugins() error = %v", err)
+	}
+	t.Cleanup(func() { _ = RegisterPlugins(nil) })
+
+	got, err := GetAdapter("myboard")
+	if err != nil {
+		t.Fatalf("GetAdapter(\"myboard\") error = %v", err)
+	}
+	if _, ok := got.(*PluginAdapter); !ok {
+		t.Errorf("GetAdapter(\"myboard\") = %T, want *PluginAdapter", got)
+	}
+	if _, err := GetAdapter("futaba"); err != nil {
+		t.Errorf("GetAdapter(\"futaba\") error = %v", err)
+	}
+}
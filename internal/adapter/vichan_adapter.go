@@ -0,0 +1,356 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+var (
+	// ParseThreadHTML が生成するHTML内の添付ファイル（フルサイズへのリンクとサムネイル）
+	vichanFileLinkPattern = regexp.MustCompile(`<a class="fileThumb" href="([^"]+)" data-res="(\d+)"[^>]*><img[^>]* src="([^"]+)"`)
+)
+
+// vichanSpoilerStyle は、スポイラー指定のサムネイルをぼかして表示し、マウスオーバーで元に戻すスタイルです。
+const vichanSpoilerStyle = `<style>img.spoiler{filter:blur(12px);transition:filter .2s}img.spoiler:hover{filter:none}</style>`
+
+// VichanAdapter は、vichan 系の掲示板（8kun・lainchan など）の JSON API（catalog.json と res/<スレッドID>.json）を使用するサイトアダプタです。
+// タスクの target_board_url には板のURL（例: https://lainchan.org/λ/）を指定します。
+// スレッドの JSON は ParseThreadHTML で閲覧用のHTMLに変換し、以降はHTMLとして処理します。
+type VichanAdapter struct {
+	siteURL      *url.URL // 板のあるサイトのルート
+	board        string   // 板のディレクトリ名
+	mediaBaseURL string   // file_store のメディアの配信元
+	// extensions は、タスクの media_extensions から生成したアーカイブ対象の拡張子です（nil の場合はすべて）。
+	extensions map[string]bool
+}
+
+// NewVichanAdapter は、VichanAdapterの新しいインスタンスを返します。
+func NewVichanAdapter() SiteAdapter {
+	return &VichanAdapter{}
+}
+
+// vichanTim は、添付ファイルの保存名です。vichan は数字の文字列、8kun は SHA-256 の16進数、古い実装では数値で返すため、どちらも文字列として扱います。
+type vichanTim string
+
+// UnmarshalJSON は、文字列と数値のどちらの tim も受け付けます。
+func (t *vichanTim) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*t = vichanTim(s)
+		return nil
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	*t = vichanTim(data)
+	return nil
+}
+
+// vichanFile は、投稿の添付ファイル（最初のファイルと extra_files）です。
+type vichanFile struct {
+	Tim         vichanTim `json:"tim"`
+	Ext         string    `json:"ext"`
+	Filename    string    `json:"filename"`
+	Fpath       int       `json:"fpath"` // 1 の場合は file_store に保存されている（8kun）
+	Spoiler     int       `json:"spoiler"`
+	ThumbWidth  int       `json:"tn_w"`
+	ThumbHeight int       `json:"tn_h"`
+}
+
+// vichanPost は、vichan API の投稿（カタログのスレッドを含む）のうち、アーカイブに使用する項目です。
+type vichanPost struct {
+	vichanFile
+	No         int64        `json:"no"`
+	Time       int64        `json:"time"`
+	Name       string       `json:"name"`
+	Trip       string       `json:"trip"`
+	Subject    string       `json:"sub"`
+	Comment    string       `json:"com"`
+	Replies    int          `json:"replies"`
+	ExtraFiles []vichanFile `json:"extra_files"`
+}
+
+// files は、投稿の削除されていない添付ファイルを返します。
+func (p vichanPost) files() []vichanFile {
+	var files []vichanFile
+	for _, f := range append([]vichanFile{p.vichanFile}, p.ExtraFiles...) {
+		if f.Tim != "" && f.Ext != "" && f.Ext != "deleted" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// mediaName は、添付ファイルの配信元でのファイル名（例: 1700000000123.png）を返します。
+func (f vichanFile) mediaName() string {
+	return string(f.Tim) + f.Ext
+}
+
+// thumbName は、サムネイルの配信元でのファイル名を返します。画像は元と同じ拡張子、動画などは jpg のサムネイルが生成されます。
+func (f vichanFile) thumbName() string {
+	switch strings.ToLower(f.Ext) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+		return f.mediaName()
+	}
+	return string(f.Tim) + ".jpg"
+}
+
+// Prepare は、板のURLとアーカイブ対象の拡張子を設定します。タスクに認証設定（auth）がある場合は、続けてログインします。
+func (a *VichanAdapter) Prepare(client *network.Client, taskConfig config.Task) error {
+	u, err := url.Parse(taskConfig.TargetBoardURL)
+	if err != nil {
+		return fmt.Errorf("target_board_url の解析に失敗しました: %w", err)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("target_board_url にはサイトを含む板のURLを指定してください (url=%s)", taskConfig.TargetBoardURL)
+	}
+	a.board = strings.Trim(u.Path, "/")
+	if a.board == "" {
+		return fmt.Errorf("target_board_url に板のディレクトリを含めてください（例: https://lainchan.org/λ/）")
+	}
+	a.siteURL = &url.URL{Scheme: u.Scheme, Host: u.Host}
+	a.mediaBaseURL = a.siteURL.String()
+	if taskConfig.VichanSettings != nil && taskConfig.VichanSettings.MediaBaseURL != "" {
+		a.mediaBaseURL = strings.TrimSuffix(taskConfig.VichanSettings.MediaBaseURL, "/")
+	}
+
+	a.extensions = nil
+	if len(taskConfig.MediaExtensions) > 0 {
+		a.extensions = make(map[string]bool, len(taskConfig.MediaExtensions))
+		for _, ext := range taskConfig.MediaExtensions {
+			ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+			if !extensionPattern.MatchString(ext) {
+				return fmt.Errorf("メディアの拡張子 %q が不正です（英数字のみ指定できます）", ext)
+			}
+			a.extensions[ext] = true
+		}
+	}
+	return authenticate(client, taskConfig)
+}
+
+// Capabilities は、vichan系のアダプタが対応している機能を返します。削除されたレスはAPIの応答から消えるため、削除済みの印はありません。
+func (a *VichanAdapter) Capabilities() Capabilities {
+	return Capabilities{JSONAPI: true, Thumbnails: true, ResNumbers: true}
+}
+
+// BuildCatalogURL は、板の catalog.json のURLを構築します。
+func (a *VichanAdapter) BuildCatalogURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("ベースURLの解析に失敗しました: %w", err)
+	}
+	return u.JoinPath("catalog.json").String(), nil
+}
+
+// ParseCatalog は、catalog.json（ページごとのスレッドの配列）を解析し、スレッド情報のスライスを返します。
+// スレッドのURLは板のURLからの相対パス（res/<スレッドID>.json）です。
+func (a *VichanAdapter) ParseCatalog(htmlBody []byte) ([]model.ThreadInfo, error) {
+	var pages []struct {
+		Threads []vichanPost `json:"threads"`
+	}
+	if err := json.Unmarshal(htmlBody, &pages); err != nil {
+		return nil, fmt.Errorf("catalog.json の解析に失敗しました: %w", err)
+	}
+
+	var threads []model.ThreadInfo
+	for _, page := range pages {
+		for _, th := range page.Threads {
+			id := strconv.FormatInt(th.No, 10)
+			title := plainText(th.Subject)
+			if title == "" {
+				title = plainText(th.Comment)
+			}
+			if title == "" {
+				title = fmt.Sprintf("Thread %s", id)
+			}
+			threads = append(threads, model.ThreadInfo{
+				ID:       id,
+				Title:    title,
+				URL:      "res/" + id + ".json",
+				ResCount: th.Replies + 1, // OPを含む
+				Date:     time.Unix(th.Time, 0),
+			})
+		}
+	}
+	return threads, nil
+}
+
+// mediaURLs は、添付ファイルのフルサイズとサムネイルのURLを返します。
+// 通常は <サイト>/<板>/src/ と <サイト>/<板>/thumb/、file_store のファイルは <配信元>/file_store/ と <配信元>/file_store/thumb/ です。
+func (a *VichanAdapter) mediaURLs(f vichanFile) (string, string) {
+	if f.Fpath == 1 {
+		return a.mediaBaseURL + "/file_store/" + f.mediaName(), a.mediaBaseURL + "/file_store/thumb/" + f.thumbName()
+	}
+	board := a.siteURL.JoinPath(a.board)
+	return board.JoinPath("src", f.mediaName()).String(), board.JoinPath("thumb", f.thumbName()).String()
+}
+
+// ParseThreadHTML は、スレッドの JSON を閲覧用のHTMLに変換します。
+// 添付ファイルへのリンクとサムネイルは配信元の絶対URLとし、スポイラー指定のサムネイルは class="spoiler" でぼかして表示します。
+// 本文（com）・名前・題名は API がHTMLとして返す値をそのまま使用します。
+func (a *VichanAdapter) ParseThreadHTML(htmlBody []byte) (string, error) {
+	if a.siteURL == nil {
+		return "", fmt.Errorf("Prepare が呼び出されていません")
+	}
+	var thread struct {
+		Posts []vichanPost `json:"posts"`
+	}
+	if err := json.Unmarshal(htmlBody, &thread); err != nil {
+		return "", fmt.Errorf("スレッドの JSON の解析に失敗しました: %w", err)
+	}
+	if len(thread.Posts) == 0 {
+		return "", fmt.Errorf("スレッドの JSON に投稿がありません")
+	}
+
+	op := thread.Posts[0]
+	title := op.Subject
+	if title == "" {
+		title = "No." + strconv.FormatInt(op.No, 10)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"UTF-8\">\n<title>" + title + "</title>\n" + vichanSpoilerStyle + "\n</head>\n<body>\n")
+	sb.WriteString(`<div class="thread" id="t` + strconv.FormatInt(op.No, 10) + "\">\n")
+	for i, post := range thread.Posts {
+		class := "reply"
+		if i == 0 {
+			class = "op"
+		}
+		no := strconv.FormatInt(post.No, 10)
+		sb.WriteString(`<div class="post ` + class + `" id="p` + no + "\">\n")
+		sb.WriteString(`<div class="postInfo">`)
+		if post.Subject != "" {
+			sb.WriteString(`<span class="subject">` + post.Subject + `</span> `)
+		}
+		sb.WriteString(`<span class="name">` + post.Name + `</span>`)
+		if post.Trip != "" {
+			sb.WriteString(`<span class="trip">` + post.Trip + `</span>`)
+		}
+		postTime := time.Unix(post.Time, 0).UTC()
+		sb.WriteString(` <span class="dateTime" data-utc="` + strconv.FormatInt(post.Time, 10) + `">` + postTime.Format("2006-01-02 15:04:05 UTC") + `</span> `)
+		sb.WriteString(`<span class="postNum">No.` + no + "</span></div>\n")
+		for _, f := range post.files() {
+			mediaURL, thumbURL := a.mediaURLs(f)
+			imgClass := ""
+			if f.Spoiler != 0 {
+				imgClass = ` class="spoiler"`
+			}
+			fmt.Fprintf(&sb, `<div class="file"><a class="fileThumb" href="%s" data-res="%s" title="%s%s">`+
+				`<img%s src="%s" width="%d" height="%d" alt="%s"></a></div>`+"\n",
+				html.EscapeString(mediaURL), no, html.EscapeString(html.UnescapeString(f.Filename)), html.EscapeString(f.Ext),
+				imgClass, html.EscapeString(thumbURL), f.ThumbWidth, f.ThumbHeight, html.EscapeString(f.mediaName()))
+		}
+		sb.WriteString(`<blockquote class="postMessage">` + post.Comment + "</blockquote>\n</div>\n")
+	}
+	sb.WriteString("</div>\n</body>\n</html>\n")
+	return sb.String(), nil
+}
+
+// ExtractMediaFiles は、ParseThreadHTML で生成したHTMLから添付ファイルを抽出します。
+func (a *VichanAdapter) ExtractMediaFiles(htmlContent string, threadURL string) ([]model.MediaInfo, error) {
+	var media []model.MediaInfo
+	seen := make(map[string]bool)
+	for _, m := range vichanFileLinkPattern.FindAllStringSubmatch(htmlContent, -1) {
+		mediaURL := html.UnescapeString(m[1])
+		if seen[mediaURL] {
+			continue
+		}
+		seen[mediaURL] = true
+		name := path.Base(mediaURL)
+		if a.extensions != nil && !a.extensions[strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))] {
+			continue
+		}
+		resNumber, _ := strconv.Atoi(m[2])
+		media = append(media, model.MediaInfo{
+			URL:              mediaURL,
+			ThumbnailURL:     html.UnescapeString(m[3]),
+			OriginalFilename: name,
+			ResNumber:        resNumber,
+		})
+	}
+	return media, nil
+}
+
+// ReconstructHTML は、添付ファイルへのリンクとサムネイルを保存したローカルファイルへのリンクに書き換えます。
+func (a *VichanAdapter) ReconstructHTML(htmlContent string, thread model.ThreadInfo, mediaFiles []model.MediaInfo) (string, error) {
+	for _, mf := range mediaFiles {
+		if mf.Blocked {
+			htmlContent = vichanRemoveFile(htmlContent, mf.URL)
+			continue
+		}
+		if mf.LocalPath != "" {
+			targetPath := localLinkPath(mf.LocalPath, "img", path.Base(mf.LocalPath))
+			htmlContent = strings.ReplaceAll(htmlContent, `href="`+html.EscapeString(mf.URL)+`"`, `href="`+targetPath+`"`)
+			if mf.LocalThumbPath != "" && mf.ThumbnailURL != "" {
+				thumbLocal := localLinkPath(mf.LocalThumbPath, "thumb", path.Base(mf.LocalThumbPath))
+				htmlContent = strings.ReplaceAll(htmlContent, `src="`+html.EscapeString(mf.ThumbnailURL)+`"`, `src="`+thumbLocal+`"`)
+				if mf.IsAnimated && thumbLocal != targetPath {
+					htmlContent = markAnimatedThumbnail(htmlContent, thumbLocal, targetPath)
+				}
+			}
+		}
+	}
+	// 本文中のレスへのリンク（#123 など）は、掲示板ごとに形式が異なるためそのまま残す
+	return htmlContent, nil
+}
+
+// vichanRemoveFile は、ブロック対象の添付ファイルの要素（<div class="file">）をHTMLから取り除きます。
+func vichanRemoveFile(htmlContent, mediaURL string) string {
+	href := `href="` + html.EscapeString(mediaURL) + `"`
+	for {
+		idx := strings.Index(htmlContent, href)
+		if idx < 0 {
+			return htmlContent
+		}
+		start := strings.LastIndex(htmlContent[:idx], `<div class="file">`)
+		end := strings.Index(htmlContent[idx:], "</div>")
+		if start < 0 || end < 0 {
+			return htmlContent
+		}
+		rest := strings.TrimPrefix(htmlContent[idx+end+len("</div>"):], "\n")
+		htmlContent = htmlContent[:start] + rest
+	}
+}
+
+// ExtractOPText は、最初の投稿の本文をプレーンテキストで返します。
+func (a *VichanAdapter) ExtractOPText(htmlContent string) string {
+	m := opBlockquotePattern.FindStringSubmatch(htmlContent)
+	if len(m) < 2 {
+		return ""
+	}
+	return plainText(m[1])
+}
+
+// ExtractThreadDate は、最初の投稿の日時を返します。API の日時は UNIX 時間のため、板のタイムゾーンに依存しません。
+func (a *VichanAdapter) ExtractThreadDate(htmlContent string) (time.Time, bool) {
+	m := fourchanPostTimePattern.FindStringSubmatch(htmlContent)
+	if m == nil {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// CountPosts は、スレッドの投稿数（OPを含む）を返します。
+func (a *VichanAdapter) CountPosts(htmlContent string) int {
+	return len(fourchanPostPattern.FindAllStringIndex(htmlContent, -1))
+}
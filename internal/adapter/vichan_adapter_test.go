@@ -0,0 +1,152 @@
+package adapter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+const vichanTestCatalog = `[
+  {"page": 0, "threads": [
+    {"no": 10, "sub": "Lain &amp; Wired", "com": "present day", "replies": 5, "time": 1700000000},
+    {"no": 20, "com": "no subject<br>here", "replies": 0, "time": 1700000100}
+  ]}
+]`
+
+const vichanTestThread = `{"posts": [
+  {"no": 10, "time": 1700000000, "name": "Anonymous", "trip": "!abc", "sub": "Lain &amp; Wired", "com": "present day",
+   "tim": "1700000000123", "ext": ".png", "filename": "lain", "tn_w": 200, "tn_h": 150,
+   "extra_files": [{"tim": "1700000000124", "ext": ".webm", "filename": "clip", "spoiler": 1}]},
+  {"no": 11, "time": 1700000100, "name": "Anonymous", "com": "<a href=\"#10\">&gt;&gt;10</a> hash",
+   "tim": "0a1b2c3d4e5f", "ext": ".jpg", "filename": "kun", "fpath": 1},
+  {"no": 12, "time": 1700000200, "name": "Anonymous", "com": "deleted", "tim": "1700000000125", "ext": "deleted"},
+  {"no": 13, "time": 1700000300, "name": "Anonymous", "com": "numeric tim", "tim": 1700000000126, "ext": ".gif"}
+]}`
+
+func newTestVichanAdapter(t *testing.T, task config.Task) SiteAdapter {
+	t.Helper()
+	a := NewVichanAdapter()
+	if err := a.Prepare(nil, task); err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	return a
+}
+
+func TestVichanAdapter_ParseCatalog(t *testing.T) {
+	t.Parallel()
+
+	a := newTestVichanAdapter(t, config.Task{TargetBoardURL: "https://lainchan.org/lambda/"})
+	threads, err := a.ParseCatalog([]byte(vichanTestCatalog))
+	if err != nil {
+		t.Fatalf("ParseCatalog() error = %v", err)
+	}
+	if len(threads) != 2 {
+		t.Fatalf("len(threads) = %d, want 2", len(threads))
+	}
+	tests := []struct {
+		id, title, url string
+		resCount       int
+	}{
+		{id: "10", title: "Lain & Wired", url: "res/10.json", resCount: 6},
+		{id: "20", title: "no subject here", url: "res/20.json", resCount: 1},
+	}
+	for i, tt := range tests {
+		got := threads[i]
+		if got.ID != tt.id || got.Title != tt.title || got.URL != tt.url || got.ResCount != tt.resCount {
+			t.Errorf("threads[%d] = %+v, want %+v", i, got, tt)
+		}
+	}
+
+	catalogURL, err := a.BuildCatalogURL("https://lainchan.org/lambda/")
+	if err != nil || catalogURL != "https://lainchan.org/lambda/catalog.json" {
+		t.Errorf("BuildCatalogURL() = %q, %v", catalogURL, err)
+	}
+}
+
+func TestVichanAdapter_Thread(t *testing.T) {
+	t.Parallel()
+
+	a := newTestVichanAdapter(t, config.Task{
+		TargetBoardURL: "https://8kun.top/tech/",
+		VichanSettings: &config.VichanSettings{MediaBaseURL: "https://media.example.com/"},
+	})
+	htmlContent, err := a.ParseThreadHTML([]byte(vichanTestThread))
+	if err != nil {
+		t.Fatalf("ParseThreadHTML() error = %v", err)
+	}
+	if !strings.Contains(htmlContent, `<img class="spoiler" src="https://8kun.top/tech/thumb/1700000000124.jpg"`) {
+		t.Error("スポイラーのサムネイルに class=\"spoiler\" がありません")
+	}
+
+	media, err := a.ExtractMediaFiles(htmlContent, "https://8kun.top/tech/res/10.json")
+	if err != nil {
+		t.Fatalf("ExtractMediaFiles() error = %v", err)
+	}
+	want := []model.MediaInfo{
+		{URL: "https://8kun.top/tech/src/1700000000123.png", ThumbnailURL: "https://8kun.top/tech/thumb/1700000000123.png", OriginalFilename: "1700000000123.png", ResNumber: 10},
+		{URL: "https://8kun.top/tech/src/1700000000124.webm", ThumbnailURL: "https://8kun.top/tech/thumb/1700000000124.jpg", OriginalFilename: "1700000000124.webm", ResNumber: 10},
+		{URL: "https://media.example.com/file_store/0a1b2c3d4e5f.jpg", ThumbnailURL: "https://media.example.com/file_store/thumb/0a1b2c3d4e5f.jpg", OriginalFilename: "0a1b2c3d4e5f.jpg", ResNumber: 11},
+		{URL: "https://8kun.top/tech/src/1700000000126.gif", ThumbnailURL: "https://8kun.top/tech/thumb/1700000000126.gif", OriginalFilename: "1700000000126.gif", ResNumber: 13},
+	}
+	if len(media) != len(want) {
+		t.Fatalf("len(media) = %d, want %d (削除済みのファイルは対象外): %+v", len(media), len(want), media)
+	}
+	for i := range want {
+		if media[i] != want[i] {
+			t.Errorf("media[%d] = %+v, want %+v", i, media[i], want[i])
+		}
+	}
+
+	if got := a.(PostCounter).CountPosts(htmlContent); got != 4 {
+		t.Errorf("CountPosts() = %d, want 4", got)
+	}
+	if got := a.(OPTextExtractor).ExtractOPText(htmlContent); got != "present day" {
+		t.Errorf("ExtractOPText() = %q", got)
+	}
+	if got, ok := a.(ThreadDateExtractor).ExtractThreadDate(htmlContent); !ok || !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("ExtractThreadDate() = %v, %v", got, ok)
+	}
+
+	media[0].LocalPath = "/archive/10/img/1700000000123.png"
+	media[0].LocalThumbPath = "/archive/10/thumb/1700000000123.png"
+	media[1].Blocked = true
+	reconstructed, err := a.ReconstructHTML(htmlContent, model.ThreadInfo{ID: "10"}, media)
+	if err != nil {
+		t.Fatalf("ReconstructHTML() error = %v", err)
+	}
+	for _, want := range []string{`href="img/1700000000123.png"`, `src="thumb/1700000000123.png"`, `href="https://media.example.com/file_store/0a1b2c3d4e5f.jpg"`} {
+		if !strings.Contains(reconstructed, want) {
+			t.Errorf("再構成したHTMLに %s がありません", want)
+		}
+	}
+	if strings.Contains(reconstructed, "1700000000124") {
+		t.Error("ブロック対象のメディアが残っています")
+	}
+}
+
+func TestVichanAdapter_Prepare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		boardURL string
+		wantErr  bool
+	}{
+		{name: "板のURL", boardURL: "https://lainchan.org/λ/"},
+		{name: "板なし", boardURL: "https://lainchan.org/", wantErr: true},
+		{name: "相対URL", boardURL: "/b/", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := NewVichanAdapter().Prepare(nil, config.Task{TargetBoardURL: tt.boardURL})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Prepare() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
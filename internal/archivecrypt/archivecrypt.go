@@ -0,0 +1,381 @@
+// Package archivecrypt は、保存したメディアとHTMLの暗号化（AES-256-GCM）を提供します。
+// 共有ドライブやクラウド同期フォルダに置いたアーカイブを、鍵を持たない第三者が閲覧できないようにします。
+//
+// 暗号化したファイルは元と同じ名前のまま、先頭のヘッダーで暗号化の有無を判定します。
+// ヘッダーには鍵そのものではなく鍵のシークレット名を記録し、復号時にシークレットストアから鍵を取得します。
+//
+//	"GIBAENC1" | シークレット名の長さ (1バイト) | シークレット名 | ソルト (16バイト) | チャンク...
+//
+// 本文は 64KiB ごとのチャンクに分けて暗号化するため、動画のシーク再生のように一部だけを復号できます。
+// ファイルごとの鍵は、マスター鍵とソルトから HMAC-SHA256 で導出します。
+package archivecrypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"GoImageBoardArchiver/internal/secrets"
+)
+
+const (
+	// KeySize は、マスター鍵の長さ（バイト）です。
+	KeySize = 32
+
+	magic     = "GIBAENC1"
+	saltSize  = 16
+	chunkSize = 64 * 1024
+	tagSize   = 16
+	// keyInfo は、ファイルごとの鍵の導出に使用する文字列です。
+	keyInfo = "GIBA archive encryption v1"
+)
+
+// ErrDecrypt は、暗号化されたファイルを復号できない（鍵が違う、またはファイルが改ざん・破損している）ことを表します。
+var ErrDecrypt = errors.New("ファイルを復号できません")
+
+// LookupKey は、鍵のシークレット名から鍵を返します。既定ではシークレットストアの値を ParseKey で解釈します。
+// テストで差し替えられるよう変数にしています。
+var LookupKey = func(name string) ([]byte, error) {
+	value, err := secrets.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(value)
+}
+
+// GenerateKey は、新しい鍵を Base64 の文字列で返します。
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("鍵の生成に失敗しました: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey は、Base64（標準・URL用のどちらも可）でエンコードされた 32 バイトの鍵を解釈します。
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(s); err == nil && len(key) == KeySize {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("鍵は %d バイトを Base64 でエンコードした文字列で指定してください（giba keygen で生成できます）", KeySize)
+}
+
+// header は、暗号化したファイルの先頭に書き込む情報です。
+type header struct {
+	keyName string
+	salt    []byte
+}
+
+func (h header) bytes() []byte {
+	b := make([]byte, 0, len(magic)+1+len(h.keyName)+saltSize)
+	b = append(b, magic...)
+	b = append(b, byte(len(h.keyName)))
+	b = append(b, h.keyName...)
+	return append(b, h.salt...)
+}
+
+// aead は、マスター鍵とソルトからファイルごとの鍵を導出し、AES-GCM を返します。
+func (h header) aead(key []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(keyInfo))
+	mac.Write(h.salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readHeader は、r の先頭からヘッダーを読み込みます。暗号化されていないファイルの場合は ok が false です。
+func readHeader(r io.ReaderAt) (h header, size int, ok bool, err error) {
+	prefix := make([]byte, len(magic)+1)
+	if n, err := r.ReadAt(prefix, 0); n < len(prefix) {
+		if err == io.EOF || err == nil {
+			return header{}, 0, false, nil
+		}
+		return header{}, 0, false, err
+	}
+	if string(prefix[:len(magic)]) != magic {
+		return header{}, 0, false, nil
+	}
+	nameLen := int(prefix[len(magic)])
+	rest := make([]byte, nameLen+saltSize)
+	if _, err := r.ReadAt(rest, int64(len(prefix))); err != nil {
+		return header{}, 0, false, fmt.Errorf("%w: ヘッダーが不完全です", ErrDecrypt)
+	}
+	h = header{keyName: string(rest[:nameLen]), salt: rest[nameLen:]}
+	return h, len(prefix) + len(rest), true, nil
+}
+
+// nonce は、チャンクの番号と最後のチャンクかどうかから nonce を作成します。
+// 最後のチャンクを区別することで、末尾のチャンクを切り詰める改ざんを検出します。
+func nonce(index int64, last bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n, uint64(index))
+	if last {
+		n[11] = 1
+	}
+	return n
+}
+
+// Encrypt は、src の内容を鍵 key（シークレット名 keyName）で暗号化して dst に書き込みます。
+func Encrypt(dst io.Writer, src io.Reader, keyName string, key []byte) error {
+	if keyName == "" || len(keyName) > 255 {
+		return fmt.Errorf("鍵のシークレット名は1〜255バイトで指定してください (name=%q)", keyName)
+	}
+	h := header{keyName: keyName, salt: make([]byte, saltSize)}
+	if _, err := rand.Read(h.salt); err != nil {
+		return fmt.Errorf("ソルトの生成に失敗しました: %w", err)
+	}
+	aead, err := h.aead(key)
+	if err != nil {
+		return err
+	}
+	headerBytes := h.bytes()
+	if _, err := dst.Write(headerBytes); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(src, chunkSize)
+	buf := make([]byte, chunkSize)
+	sealed := make([]byte, 0, chunkSize+tagSize)
+	for index := int64(0); ; index++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		// 続きがない場合は最後のチャンクとする（空のファイルも1つのチャンクになる）
+		_, peekErr := br.Peek(1)
+		last := peekErr != nil
+		sealed = aead.Seal(sealed[:0], nonce(index, last), buf[:n], headerBytes)
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			if peekErr != io.EOF {
+				return peekErr
+			}
+			return nil
+		}
+	}
+}
+
+// IsEncrypted は、path のファイルが暗号化されているかどうかを返します。
+func IsEncrypted(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, _, ok, err := readHeader(f)
+	return ok, err
+}
+
+// EncryptFile は、path のファイルをシークレット keyName の鍵で暗号化したファイルに置き換えます。
+// 既に暗号化されているファイルはそのままにします。
+func EncryptFile(path, keyName string, key []byte) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if _, _, ok, err := readHeader(in); err != nil || ok {
+		return err
+	}
+
+	// 書き込み途中のファイルを Web UI で配信しないよう、"." で始まる一時ファイルに書き込んでから置き換える
+	tmpPath := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".enc.tmp")
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := Encrypt(out, in, keyName, key); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("暗号化に失敗しました (path=%s): %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	in.Close()
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("暗号化したファイルへの置き換えに失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
+// File は、Open で開いたファイルです。暗号化されたファイルの場合は、読み込み時に復号します。
+type File struct {
+	io.ReadSeeker
+	f         *os.File
+	size      int64
+	encrypted bool
+}
+
+// Size は、復号後の内容のサイズを返します。
+func (f *File) Size() int64 { return f.size }
+
+// Encrypted は、ファイルが暗号化されているかどうかを返します。
+func (f *File) Encrypted() bool { return f.encrypted }
+
+// Close は、ファイルを閉じます。
+func (f *File) Close() error { return f.f.Close() }
+
+// Open は、path のファイルを開きます。暗号化されたファイルはヘッダーのシークレット名から LookupKey で鍵を取得し、
+// 復号しながら読み込みます。暗号化されていないファイルはそのまま読み込みます。
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	h, headerLen, ok, err := readHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w (path=%s)", err, path)
+	}
+	if !ok {
+		return &File{ReadSeeker: f, f: f, size: info.Size()}, nil
+	}
+
+	key, err := LookupKey(h.keyName)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("暗号化の鍵 '%s' を取得できません (path=%s): %w", h.keyName, path, err)
+	}
+	r, err := newReader(f, info.Size(), h, headerLen, key)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%w (path=%s)", err, path)
+	}
+	return &File{ReadSeeker: r, f: f, size: r.size, encrypted: true}, nil
+}
+
+// ReadFile は、os.ReadFile と同様に path の内容を返します。暗号化されたファイルは復号した内容を返します。
+func ReadFile(path string) ([]byte, error) {
+	f, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data := make([]byte, 0, f.Size())
+	buf := bytes.NewBuffer(data)
+	if _, err := io.Copy(buf, f); err != nil {
+		return nil, fmt.Errorf("%w (path=%s)", err, path)
+	}
+	return buf.Bytes(), nil
+}
+
+// reader は、暗号化されたファイルを復号しながら読み込む io.ReadSeeker です。
+type reader struct {
+	r         io.ReaderAt
+	aead      cipher.AEAD
+	aad       []byte
+	headerLen int64
+	chunks    int64
+	size      int64 // 復号後のサイズ
+	offset    int64
+
+	// 直前に復号したチャンク
+	cached      int64
+	plain       []byte
+	cipherchunk []byte
+}
+
+func newReader(r io.ReaderAt, fileSize int64, h header, headerLen int, key []byte) (*reader, error) {
+	aead, err := h.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	payload := fileSize - int64(headerLen)
+	if payload < tagSize {
+		return nil, fmt.Errorf("%w: ファイルが短すぎます", ErrDecrypt)
+	}
+	chunks := (payload + chunkSize + tagSize - 1) / (chunkSize + tagSize)
+	lastLen := payload - (chunks-1)*(chunkSize+tagSize)
+	if lastLen < tagSize {
+		return nil, fmt.Errorf("%w: 最後のチャンクが不完全です", ErrDecrypt)
+	}
+	return &reader{
+		r:           r,
+		aead:        aead,
+		aad:         h.bytes(),
+		headerLen:   int64(headerLen),
+		chunks:      chunks,
+		size:        payload - chunks*tagSize,
+		cached:      -1,
+		cipherchunk: make([]byte, chunkSize+tagSize),
+	}, nil
+}
+
+// chunk は、index 番目のチャンクを復号して返します。
+func (r *reader) chunk(index int64) ([]byte, error) {
+	if index == r.cached {
+		return r.plain, nil
+	}
+	buf := r.cipherchunk
+	if index == r.chunks-1 {
+		buf = buf[:r.size-index*chunkSize+tagSize]
+	}
+	if _, err := r.r.ReadAt(buf, r.headerLen+index*(chunkSize+tagSize)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	plain, err := r.aead.Open(r.plain[:0], nonce(index, index == r.chunks-1), buf, r.aad)
+	if err != nil {
+		r.cached = -1
+		return nil, fmt.Errorf("%w: 鍵が違うか、ファイルが改ざん・破損しています", ErrDecrypt)
+	}
+	r.cached, r.plain = index, plain
+	return plain, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	plain, err := r.chunk(r.offset / chunkSize)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, plain[r.offset%chunkSize:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("whence が不正です")
+	}
+	if offset < 0 {
+		return 0, errors.New("負の位置にはシークできません")
+	}
+	r.offset = offset
+	return offset, nil
+}
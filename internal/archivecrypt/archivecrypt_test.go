@@ -0,0 +1,196 @@
+package archivecrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setTestKey は、シークレット name に新しい鍵を設定し、その鍵を返します。
+// 環境変数を変更するため、呼び出すテストは並列実行しない。
+func setTestKey(t *testing.T, name string) []byte {
+	t.Helper()
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIBA_SECRET_"+name, encoded)
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptFile_RoundTrip(t *testing.T) {
+	key := setTestKey(t, "ARCHIVE_KEY")
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "空", size: 0},
+		{name: "1チャンク未満", size: 100},
+		{name: "ちょうど1チャンク", size: chunkSize},
+		{name: "複数チャンク", size: chunkSize*2 + 123},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := make([]byte, tt.size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "1700000000000.jpg")
+			if err := os.WriteFile(path, plain, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := EncryptFile(path, "archive_key", key); err != nil {
+				t.Fatalf("EncryptFile() error = %v", err)
+			}
+			raw, _ := os.ReadFile(path)
+			if tt.size > 0 && bytes.Contains(raw, plain) {
+				t.Error("暗号化したファイルに元の内容が含まれています")
+			}
+			// 暗号化済みのファイルは二重に暗号化しない
+			if err := EncryptFile(path, "archive_key", key); err != nil {
+				t.Fatalf("EncryptFile() 2回目 error = %v", err)
+			}
+			if again, _ := os.ReadFile(path); !bytes.Equal(again, raw) {
+				t.Error("暗号化済みのファイルが書き換えられました")
+			}
+
+			got, err := ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("ReadFile() の内容が元と一致しません (len=%d, want %d)", len(got), len(plain))
+			}
+		})
+	}
+}
+
+func TestOpen_Seek(t *testing.T) {
+	key := setTestKey(t, "ARCHIVE_KEY")
+
+	plain := make([]byte, chunkSize*3+10)
+	for i := range plain {
+		plain[i] = byte(i % 251)
+	}
+	path := filepath.Join(t.TempDir(), "video.webm")
+	if err := os.WriteFile(path, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncryptFile(path, "archive_key", key); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer f.Close()
+	if !f.Encrypted() || f.Size() != int64(len(plain)) {
+		t.Fatalf("Encrypted() = %v, Size() = %d, want true, %d", f.Encrypted(), f.Size(), len(plain))
+	}
+	// チャンクの境界をまたぐ範囲を読み込む（動画のシーク再生）
+	for _, offset := range []int64{chunkSize - 5, chunkSize * 3, 0} {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, 20)
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Fatalf("offset %d: ReadFull() error = %v", offset, err)
+		}
+		if !bytes.Equal(buf[:n], plain[offset:offset+int64(n)]) {
+			t.Errorf("offset %d: 内容が一致しません", offset)
+		}
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	key := setTestKey(t, "ARCHIVE_KEY")
+	setTestKey(t, "OTHER_KEY")
+
+	dir := t.TempDir()
+	plainPath := filepath.Join(dir, "index.htm")
+	if err := os.WriteFile(plainPath, []byte("<html></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 暗号化されていないファイルはそのまま読み込む
+	if got, err := ReadFile(plainPath); err != nil || string(got) != "<html></html>" {
+		t.Errorf("ReadFile() = %q, %v", got, err)
+	}
+
+	encrypt := func(name, keyName string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, bytes.Repeat([]byte("x"), chunkSize+1), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := EncryptFile(path, keyName, key); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// ヘッダーのシークレット名の鍵が、暗号化に使用した鍵と異なる
+	if _, err := ReadFile(encrypt("wrong.jpg", "other_key")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("鍵が違う: error = %v, want ErrDecrypt", err)
+	}
+
+	// 最後のチャンクを切り詰める
+	truncated := encrypt("truncated.jpg", "archive_key")
+	info, _ := os.Stat(truncated)
+	if err := os.Truncate(truncated, info.Size()-(1+tagSize)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(truncated); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("切り詰め: error = %v, want ErrDecrypt", err)
+	}
+
+	// 内容を書き換える
+	tampered := encrypt("tampered.jpg", "archive_key")
+	data, _ := os.ReadFile(tampered)
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(tampered, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(tampered); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("改ざん: error = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "標準", input: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="},
+		{name: "URL用・パディングなし", input: "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8"},
+		{name: "前後の空白", input: " AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=\n"},
+		{name: "短すぎる", input: "AAECAw==", wantErr: true},
+		{name: "パスフレーズ", input: "my secret password", wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			key, err := ParseKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(key) != KeySize {
+				t.Errorf("len(key) = %d, want %d", len(key), KeySize)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// boardPresetsJSON は、実行ファイルに同梱する既知の掲示板の一覧です。
+//
+//go:embed board_presets.json
+var boardPresetsJSON []byte
+
+// BoardPreset は、既知の掲示板のおすすめのタスク設定です。giba add-board と Web UI の「既知の板から追加」で使用します。
+type BoardPreset struct {
+	ID          string `json:"id"`          // giba add-board で指定する識別子（例: "may-b"）
+	Name        string `json:"name"`        // 表示名
+	Description string `json:"description"` // 対象のスレッドと設定の説明
+	Task        Task   `json:"task"`        // タスク名と保存先を除くタスク設定
+}
+
+var (
+	boardPresetsOnce sync.Once
+	boardPresets     []BoardPreset
+	boardPresetsErr  error
+)
+
+// BoardPresets は、同梱の既知の掲示板の一覧を返します。
+func BoardPresets() ([]BoardPreset, error) {
+	boardPresetsOnce.Do(func() {
+		if err := json.Unmarshal(boardPresetsJSON, &boardPresets); err != nil {
+			boardPresetsErr = fmt.Errorf("同梱の掲示板の一覧の解析に失敗しました: %w", err)
+		}
+	})
+	return boardPresets, boardPresetsErr
+}
+
+// FindBoardPreset は、id の既知の掲示板を返します。
+func FindBoardPreset(id string) (BoardPreset, error) {
+	presets, err := BoardPresets()
+	if err != nil {
+		return BoardPreset{}, err
+	}
+	for _, preset := range presets {
+		if preset.ID == id {
+			return preset, nil
+		}
+	}
+	return BoardPreset{}, fmt.Errorf("既知の掲示板 '%s' はありません（giba add-board で一覧を表示できます）", id)
+}
+
+// NewTask は、プリセットの設定で taskName のタスクを作成します。タスクは有効な状態で作成されます。
+func (p BoardPreset) NewTask(taskName string) Task {
+	task := p.Task
+	task.TaskName = taskName
+	enabled := true
+	task.Enabled = &enabled
+	if p.Task.FutabaCatalogSettings != nil {
+		settings := *p.Task.FutabaCatalogSettings
+		task.FutabaCatalogSettings = &settings
+	}
+	return task
+}
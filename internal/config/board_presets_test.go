@@ -0,0 +1,40 @@
+package config
+
+import "testing"
+
+func TestBoardPresets(t *testing.T) {
+	t.Parallel()
+
+	presets, err := BoardPresets()
+	if err != nil {
+		t.Fatalf("BoardPresets() error = %v", err)
+	}
+	if len(presets) == 0 {
+		t.Fatal("同梱の掲示板がありません")
+	}
+	seen := make(map[string]bool)
+	for _, preset := range presets {
+		if preset.ID == "" || preset.Name == "" || preset.Task.TargetBoardURL == "" {
+			t.Errorf("必須の項目がありません: %+v", preset)
+		}
+		if seen[preset.ID] {
+			t.Errorf("ID %q が重複しています", preset.ID)
+		}
+		seen[preset.ID] = true
+
+		task := preset.NewTask(preset.Name)
+		if task.Enabled == nil || !*task.Enabled || task.TaskName != preset.Name {
+			t.Errorf("NewTask(%q) = %+v", preset.Name, task)
+		}
+		if err := validateTask(task); err != nil {
+			t.Errorf("プリセット %q の設定が不正です: %v", preset.ID, err)
+		}
+	}
+
+	if _, err := FindBoardPreset(presets[0].ID); err != nil {
+		t.Errorf("FindBoardPreset(%q) error = %v", presets[0].ID, err)
+	}
+	if _, err := FindBoardPreset("unknown-board"); err == nil {
+		t.Error("存在しないIDでエラーになりません")
+	}
+}
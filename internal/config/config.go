@@ -2,19 +2,61 @@
 // その読み込み、解決（テンプレートのマージなど）に関する機能を提供します。
 package config
 
+import "time"
+
 // Config は config.json ファイル全体を表すルート構造体です。
 type Config struct {
-	ConfigVersion             string          `json:"config_version"`
-	GlobalSaveRootDirectory   string          `json:"global_save_root_directory,omitempty"`
-	WebUITheme                string          `json:"web_ui_theme,omitempty"`
-	Network                   NetworkSettings `json:"network"`
-	GlobalMaxConcurrentTasks  int             `json:"global_max_concurrent_tasks"`
-	SafetyStopMinDiskGB       float64         `json:"safety_stop_min_disk_gb"`
-	NotificationWebhookURL    string          `json:"notification_webhook_url,omitempty"`
-	TaskTemplates             map[string]Task `json:"task_templates"`
-	Tasks                     []Task          `json:"tasks"`
-	EnableLogFile             bool            `json:"enable_log_file"`
-	LogFilePath               string          `json:"log_file_path,omitempty"`
+	ConfigVersion            string                     `json:"config_version"`
+	GlobalSaveRootDirectory  string                     `json:"global_save_root_directory,omitempty"`
+	WebUITheme               string                     `json:"web_ui_theme,omitempty"`
+	Network                  NetworkSettings            `json:"network"`
+	GlobalMaxConcurrentTasks int                        `json:"global_max_concurrent_tasks"`
+	SafetyStopMinDiskGB      float64                    `json:"safety_stop_min_disk_gb"`
+	NotificationWebhookURL   string                     `json:"notification_webhook_url,omitempty"`
+	TaskTemplates            map[string]Task            `json:"task_templates"`
+	TaskGroups               map[string]TaskGroup       `json:"task_groups,omitempty"`
+	AdapterSettings          map[string]AdapterSettings `json:"adapter_settings,omitempty"`       // サイトアダプタごとの既定の設定
+	BlockedMediaPatterns     []string                   `json:"blocked_media_patterns,omitempty"` // すべてのタスクでダウンロードしないメディアのパターン
+	Tasks                    []Task                     `json:"tasks"`
+	EnableLogFile            bool                       `json:"enable_log_file"`
+	LogFilePath              string                     `json:"log_file_path,omitempty"`
+	CheckForUpdates          bool                       `json:"check_for_updates,omitempty"` // 起動時と1日ごとに新しいリリースを確認する
+	HeartbeatFile            string                     `json:"heartbeat_file,omitempty"`    // 巡回のたびに現在時刻を書き込む生存確認用ファイル
+	StatusFile               string                     `json:"status_file,omitempty"`       // 状態が変わるたびにタスクごとの状態をJSONで書き込むファイル
+	UsageStatsFile           string                     `json:"usage_stats_file,omitempty"`  // 累計の利用統計（スレッド数・容量・稼働時間）を記録するファイル（省略時は記録しない）
+	SecretsFile              string                     `json:"secrets_file,omitempty"`      // 認証に使用するパスワードなどを保存したファイル（省略時は設定ファイルと同じディレクトリの secrets.json）
+	Timezone                 string                     `json:"timezone,omitempty"`          // 日付の計算に使用するタイムゾーン（IANA名。省略時はマシンのローカル時刻）
+	Sharing                  *SharingSettings           `json:"sharing,omitempty"`           // アーカイブを閲覧専用で公開する共有モード（省略時は無効）
+	ArchiveHook              *ArchiveHookSettings       `json:"archive_hook,omitempty"`      // 外部からスレッドのアーカイブを依頼する受信用エンドポイント（省略時は無効）
+	AdapterPlugins           map[string]AdapterPlugin   `json:"adapter_plugins,omitempty"`   // 外部プログラムで実装したサイトアダプタ（キーは site_adapter に指定する名前）
+}
+
+// Location は、timezone の設定値に対応するタイムゾーンを返します。
+// 空の場合や読み込めない場合（設定の読み込み時に検証済み）は、マシンのローカル時刻を返します。
+func Location(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// TaskGroup は、複数のタスクで共有する実行枠を定義します。
+type TaskGroup struct {
+	// MaxConcurrentTasks は、グループ内で同時に巡回を実行できるタスク数の上限です（0以下で無制限）。
+	MaxConcurrentTasks int `json:"max_concurrent_tasks"`
+}
+
+// AdapterSettings は、同じサイトアダプタを使用するタスクに共通の既定値です。
+type AdapterSettings struct {
+	// MediaExtensions は、アーカイブ対象とするメディアの拡張子です。タスクの media_extensions が空の場合に使用されます。
+	MediaExtensions []string `json:"media_extensions,omitempty"`
+	// BoardQuirks は、ふたば（互換）アダプタの板ごとのパスとサムネイルの違いです。キーは板のホスト名とパス（例: "may.2chan.net/b"、BoardKey で計算）です。
+	// タスクの futaba_board_quirks が未設定の場合に、タスクの板に一致するものが使用されます。
+	BoardQuirks map[string]FutabaBoardQuirks `json:"board_quirks,omitempty"`
 }
 
 // NetworkSettings は、HTTPリクエストに関するグローバルな設定を保持します。
@@ -27,32 +69,132 @@ type NetworkSettings struct {
 
 // Task は単一のアーカイブタスクを定義します。
 type Task struct {
-	Enabled                  *bool               `json:"enabled,omitempty"`
-	TaskName                 string              `json:"task_name,omitempty"`
-	UseTemplate              string              `json:"use_template,omitempty"`
-	SiteAdapter              string              `json:"site_adapter,omitempty"`
-	TargetBoardURL           string              `json:"target_board_url,omitempty"`
-	SaveRootDirectory        string              `json:"save_root_directory,omitempty"`
-	DirectoryFormat          string              `json:"directory_format,omitempty"`
-	FilenameFormat           string              `json:"filename_format,omitempty"`
-	SearchKeyword            string              `json:"search_keyword,omitempty"`
-	ExcludeKeywords          []string            `json:"exclude_keywords,omitempty"`
-	MinimumMediaCount        int                 `json:"minimum_media_count,omitempty"`
-	WatchIntervalMillis      int                 `json:"watch_interval_ms,omitempty"`
-	MaxConcurrentDownloads   int                 `json:"max_concurrent_downloads,omitempty"`
-	PostContentFilters       *PostContentFilters `json:"post_content_filters,omitempty"`
-	RetryCount               int                 `json:"retry_count,omitempty"`
-	RetryWaitMillis          int                 `json:"retry_wait_ms,omitempty"`
-	RequestTimeoutMillis     int                 `json:"request_timeout_ms,omitempty"`
-	RequestIntervalMillis    int                 `json:"request_interval_ms,omitempty"`
-	NotifyOnComplete         bool                `json:"notify_on_complete,omitempty"`
-	NotifyOnError            bool                `json:"notify_on_error,omitempty"`
-	EnableHistorySkip        bool                `json:"enable_history_skip,omitempty"`
-	EnableResumeSupport      bool                `json:"enable_resume_support,omitempty"`
-	EnableLogFile            bool                `json:"enable_log_file,omitempty"`
-	LogLevel                 string              `json:"log_level,omitempty"`
-	EnableMetadataIndex      bool                `json:"enable_metadata_index,omitempty"`
-	FutabaCatalogSettings    *FutabaCatalogSettings `json:"futaba_catalog_settings,omitempty"`
+	Enabled                *bool                  `json:"enabled,omitempty"`
+	TaskName               string                 `json:"task_name,omitempty"`
+	UseTemplate            string                 `json:"use_template,omitempty"`
+	SiteAdapter            string                 `json:"site_adapter,omitempty"`
+	TargetBoardURL         string                 `json:"target_board_url,omitempty"`
+	SaveRootDirectory      string                 `json:"save_root_directory,omitempty"`
+	DirectoryFormat        string                 `json:"directory_format,omitempty"`
+	FilenameFormat         string                 `json:"filename_format,omitempty"`
+	SearchKeyword          string                 `json:"search_keyword,omitempty"`
+	ExcludeKeywords        []string               `json:"exclude_keywords,omitempty"`
+	MinimumMediaCount      int                    `json:"minimum_media_count,omitempty"`
+	WatchIntervalMillis    int                    `json:"watch_interval_ms,omitempty"`
+	MaxConcurrentDownloads int                    `json:"max_concurrent_downloads,omitempty"` // 旧設定。読み込み時に MaxConcurrentThreads へ引き継がれます
+	PostContentFilters     *PostContentFilters    `json:"post_content_filters,omitempty"`
+	RetryCount             int                    `json:"retry_count,omitempty"`
+	RetryWaitMillis        int                    `json:"retry_wait_ms,omitempty"`
+	RequestTimeoutMillis   int                    `json:"request_timeout_ms,omitempty"`
+	RequestIntervalMillis  int                    `json:"request_interval_ms,omitempty"`
+	NotifyOnComplete       bool                   `json:"notify_on_complete,omitempty"`
+	NotifyOnError          bool                   `json:"notify_on_error,omitempty"`
+	EnableHistorySkip      bool                   `json:"enable_history_skip,omitempty"`
+	EnableResumeSupport    bool                   `json:"enable_resume_support,omitempty"`
+	EnableLogFile          bool                   `json:"enable_log_file,omitempty"`
+	LogLevel               string                 `json:"log_level,omitempty"`
+	EnableMetadataIndex    bool                   `json:"enable_metadata_index,omitempty"`
+	FutabaCatalogSettings  *FutabaCatalogSettings `json:"futaba_catalog_settings,omitempty"`
+	// FutabaSettings は、ふたばアダプタ（site_adapter: "futaba"）のカタログ・スレッドの取得方法の設定です。
+	FutabaSettings *FutabaSettings `json:"futaba_settings,omitempty"`
+	// FutabaCompatibleSettings は、ふたば互換アダプタ（site_adapter: "futaba_compatible"）の板のスクリプト名・ディレクトリ名・Cookie 名です。
+	FutabaCompatibleSettings *FutabaCompatibleSettings `json:"futaba_compatible_settings,omitempty"`
+	// FutabaBoardQuirks は、ふたば（互換）アダプタの板のパスとサムネイルの違いです。未設定の場合は adapter_settings の board_quirks から板に一致するものを使用します。
+	FutabaBoardQuirks *FutabaBoardQuirks `json:"futaba_board_quirks,omitempty"`
+	// FivechSettings は、5ch アダプタ（site_adapter: "fivech"）の設定です。
+	FivechSettings *FivechSettings `json:"fivech_settings,omitempty"`
+	// VichanSettings は、vichan 系アダプタ（site_adapter: "vichan"）の設定です。
+	VichanSettings *VichanSettings `json:"vichan_settings,omitempty"`
+	// GenericSettings は、汎用アダプタ（site_adapter: "generic"）でHTMLを解析するためのセレクタです。
+	GenericSettings *GenericSettings `json:"generic_settings,omitempty"`
+	// BooruSettings は、booru アダプタ（site_adapter: "booru"）のAPIの種類と検索するタグです。
+	BooruSettings *BooruSettings `json:"booru_settings,omitempty"`
+	// PluginSettings は、外部プログラムのサイトアダプタ（adapter_plugins）にそのまま渡す任意の設定です。
+	PluginSettings map[string]any `json:"plugin_settings,omitempty"`
+	// DownloadThumbnails が false の場合、サムネイルをダウンロードしません（未設定時は true）。
+	DownloadThumbnails *bool `json:"download_thumbnails,omitempty"`
+	// ThumbnailsOnly が true の場合、フルサイズのメディアをダウンロードせずサムネイルのみ保存します。
+	ThumbnailsOnly bool `json:"thumbnails_only,omitempty"`
+	// AnimatedThumbnailMode は、アニメーションGIF/APNGのサムネイル処理方法です ("copy", "mark", 空文字で無効)。
+	AnimatedThumbnailMode string `json:"animated_thumbnail_mode,omitempty"`
+	// RetryPolicies はエラー種別ごとのリトライ設定です。未設定の種別は retry_count / retry_wait_ms を使用します。
+	RetryPolicies map[string]RetryPolicy `json:"retry_policies,omitempty"`
+	// NamingConflictPolicy は、タイトル変更などで保存先ディレクトリ名が変わった場合の扱いです ("id", "rename", "duplicate"。未設定時は "duplicate")。
+	NamingConflictPolicy string `json:"naming_conflict_policy,omitempty"`
+	// MaxTitleLength は、{thread_title_safe} の最大長（書記素クラスタ単位）です。0以下の場合は既定値を使用します。
+	MaxTitleLength int `json:"max_title_length,omitempty"`
+	// TitleFallbackLength は、タイトルが空や定型文の場合に代わりに使用するOP本文の文字数です。0の場合は既定値を使用し、負の値で無効になります。
+	TitleFallbackLength int `json:"title_fallback_length,omitempty"`
+	// MinSuccessRatio は、スレッドをアーカイブ済みとみなすために必要なフルサイズメディアのダウンロード成功率（0〜1）です。0の場合は判定しません。
+	MinSuccessRatio float64 `json:"min_success_ratio,omitempty"`
+	// ForceFull は、--force-full 指定時に実行時に設定されます。レジューム情報と既存ファイルを無視してすべて再取得します。
+	// 設定ファイルには保存されません。
+	ForceFull bool `json:"-"`
+	// ConditionalThreadFetch は、監視モードで実行時に設定されます。スレッドHTMLを前回の ETag / Last-Modified を条件とする
+	// 条件付きリクエストで取得し、更新のないスレッドの転送を省きます。設定ファイルには保存されません。
+	ConditionalThreadFetch bool `json:"-"`
+	// MaxConcurrentThreads は、このタスクで同時に処理するスレッド数の上限です（未設定時は4）。
+	MaxConcurrentThreads int `json:"max_concurrent_threads,omitempty"`
+	// MaxConcurrentFilesPerThread は、1スレッド内で同時にダウンロードするファイル数の上限です（未設定時は1）。
+	MaxConcurrentFilesPerThread int `json:"max_concurrent_files_per_thread,omitempty"`
+	// Group は、タスクが属するグループ名です。task_groups で定義した同時実行数の上限がグループ全体に適用されます。
+	Group string `json:"group,omitempty"`
+	// MediaExtensions は、アーカイブ対象とするメディアの拡張子です（例: ["jpg", "png", "flac"]）。空の場合は adapter_settings、またはアダプタの既定値を使用します。
+	MediaExtensions []string `json:"media_extensions,omitempty"`
+	// PaginationMode は、レス数の多いスレッドの表示方法です ("pages": index_p1.htm などに分割, "virtual": 画面外のレスの描画を省略, 空文字で無効)。
+	PaginationMode string `json:"pagination_mode,omitempty"`
+	// PostsPerPage は、pagination_mode が "pages" の場合の1ページあたりのレス数です（未設定時は500）。
+	PostsPerPage int `json:"posts_per_page,omitempty"`
+	// BlockedMediaPatterns は、ダウンロードしないメディアのパターンです。"sha256:" で始まるものは内容のハッシュ（先頭一致）、それ以外はURLの正規表現として扱います。
+	BlockedMediaPatterns []string `json:"blocked_media_patterns,omitempty"`
+	// StartOffset は、監視モードで実行時に設定されます。複数のタスクの最初のサイクルが同時に始まらないよう、開始前にこの時間だけ待機します。
+	StartOffset time.Duration `json:"-"`
+	// GlobalBlockedMediaPatterns は、設定ファイル全体の blocked_media_patterns です（読み込み時に設定され、保存されません）。
+	GlobalBlockedMediaPatterns []string `json:"-"`
+	// StripAds が false の場合、再構成したHTMLから広告枠やトラッキング用の要素を取り除きません（未設定時は true）。
+	StripAds *bool `json:"strip_ads,omitempty"`
+	// KeepRawHTML が true の場合、取得したスレッドHTMLを変換せずに raw.html.gz として index.htm と同じディレクトリに保存します。
+	KeepRawHTML bool `json:"keep_raw_html,omitempty"`
+	// LogFilePath は、このタスクのログを書き込む専用のファイルです。全体のログにも引き続き出力されます（未設定で enable_log_file が true の場合は logs/<タスク名>.log）。
+	LogFilePath string `json:"log_file_path,omitempty"`
+	// ArchiveHeader が true の場合、再構成したHTMLの先頭に元のURL・アーカイブ日時などを示すヘッダーを挿入し、スレッドのディレクトリに README.txt を保存します。
+	ArchiveHeader bool `json:"archive_header,omitempty"`
+	// Auth は、パスワード付き・ログインが必要な掲示板の認証設定です。サイトアダプタの Prepare で実行されます。
+	Auth *AuthSettings `json:"auth,omitempty"`
+	// BoardTimezone は、掲示板が投稿日時を表示するタイムゾーン（IANA名、例: "Asia/Tokyo"）です。空の場合はアダプタの既定値を使用します。
+	BoardTimezone string `json:"board_timezone,omitempty"`
+	// Timezone は、{year}/{month}/{day} などの日付の計算に使用するタイムゾーン（IANA名、例: "Asia/Tokyo"）です。空の場合は設定ファイル直下の timezone、それもなければマシンのローカル時刻を使用します。
+	Timezone string `json:"timezone,omitempty"`
+	// RollupPages は、保存先のルートに生成する期間ごとの一覧ページの種類です（"weekly": 週ごと, "monthly": 月ごと）。空の場合は生成しません。
+	RollupPages []string `json:"rollup_pages,omitempty"`
+	// Tags は、静的サイト（giba site build）でタスクのスレッドを分類するタグです。
+	Tags []string `json:"tags,omitempty"`
+	// RenderStrategies は、スレッドの保存形式（"latest": index.htm, "full": archive_full.html, "json": thread_data.json, "single_file": archive_single.html）です。空の場合は "latest" と "full" です。
+	RenderStrategies []string `json:"render_strategies,omitempty"`
+	// AdaptivePolling が true の場合、監視モードで変化のないスレッドほど確認の間隔を延ばし、更新の続くスレッドは下限の間隔で確認します。
+	AdaptivePolling bool `json:"adaptive_polling,omitempty"`
+	// PollMinIntervalMillis は、adaptive_polling の確認間隔の下限（ミリ秒）です。0の場合は watch_interval_ms です。
+	PollMinIntervalMillis int `json:"poll_min_interval_ms,omitempty"`
+	// PollMaxIntervalMillis は、adaptive_polling の確認間隔の上限（ミリ秒）です。0の場合は下限の8倍です。
+	PollMaxIntervalMillis int `json:"poll_max_interval_ms,omitempty"`
+	// WatchJitterPercent は、監視モードの待機時間を前後に揺らす割合（%）です。同じ間隔のタスクのカタログ取得が同時に集中しないようにします。
+	// 0より大きい場合は、監視の開始時にもタスクごとの開始を間隔のこの割合の範囲でずらします。
+	WatchJitterPercent int `json:"watch_jitter_percent,omitempty"`
+	// MaintenanceBackoffMillis は、掲示板のメンテナンス中の表示を検出した場合にタスクを一時停止する時間（ミリ秒）です。0の場合は DefaultMaintenanceBackoffMillis です。
+	// 検出が続く場合は検出のたびに2倍にし、8倍を上限とします。
+	MaintenanceBackoffMillis int `json:"maintenance_backoff_ms,omitempty"`
+	// BoardMeta を設定すると、板のルール・注意書きのページとカタログの設定を、保存先のルートの _board_meta/ に定期的に保存します。
+	BoardMeta *BoardMetaSettings `json:"board_meta,omitempty"`
+	// ResumeFlushEvery は、レジュームファイル（.resume.json）にダウンロード済みのファイルを反映する間隔（ファイル数）です。0の場合は DefaultResumeFlushEvery です。
+	ResumeFlushEvery int `json:"resume_flush_every,omitempty"`
+	// ResumeFlushIntervalMillis は、resume_flush_every に達しなくてもレジュームファイルを更新する間隔（ミリ秒）です。0の場合は DefaultResumeFlushIntervalMillis です。
+	ResumeFlushIntervalMillis int `json:"resume_flush_interval_ms,omitempty"`
+	// FsyncPolicy は、書き込んだファイルをディスクに同期（fsync）する対象です（"none", "resume", "all"）。空の場合は "none" です。
+	FsyncPolicy string `json:"fsync_policy,omitempty"`
+	// Encryption は、保存したメディアとHTMLの暗号化の方式です（"aes-gcm"）。空の場合は暗号化しません。
+	Encryption string `json:"encryption,omitempty"`
+	// EncryptionKeySecret は、暗号化の鍵（32バイトの Base64）を保存したシークレットの名前です。
+	EncryptionKeySecret string `json:"encryption_key_secret,omitempty"`
 }
 
 // PostContentFilters はスレッド本文の内容に基づくフィルタ条件を定義します。
@@ -71,4 +213,248 @@ type FutabaCatalogSettings struct {
 	Rows int `json:"rows"`
 	// TitleLength はスレッドタイトルの最大表示文字数です (cl)。
 	TitleLength int `json:"title_length"`
+	// Auto が true の場合、Cols・Rows・TitleLength の代わりに板が対応する最大の表示件数とタイトルの文字数を要求します。
+	// 板が Cookie を無視した（タイトルが既定の長さで切り詰められた）場合は、より小さい値で再取得します。
+	Auto bool `json:"auto,omitempty"`
+}
+
+// FutabaSettings は、ふたばちゃんねるのカタログとスレッドの取得方法を定義します。
+type FutabaSettings struct {
+	// UseJSONAPI が true の場合、カタログを futaba.php?mode=json、スレッドを res/<スレッドID>.json から取得します。
+	// レス番号・本文・ファイルの情報をHTMLの解析に頼らずに取得できます。JSON に対応していない板では自動的にHTMLから取得します。
+	UseJSONAPI bool `json:"use_json_api"`
+	// SkipUploaderFiles が true の場合、本文からリンクされたアップローダのファイル（fu1234567.jpg、su・sa などの接頭辞）を取得しません。
+	SkipUploaderFiles bool `json:"skip_uploader_files,omitempty"`
+	// FallbackSources は、スレッドが落ちるなどして掲示板から取得できなかった（404 の）メディアを探す、外部の保管サイトのURLのテンプレートです。
+	// {host}・{server}・{board}・{thread_id}・{dir}（src または thumb）・{filename} を置き換えて、指定した順に試します。
+	FallbackSources []string `json:"fallback_sources,omitempty"`
+}
+
+// FutabaCompatibleSettings は、ふたば互換の掲示板（pixmicat などのクローン）のスクリプト名・画像の置き場所・Cookie 名を定義します。
+// 省略した項目はふたばと同じ値を使用します。
+type FutabaCompatibleSettings struct {
+	// CGIName は、カタログを表示するスクリプト名です（既定: futaba.php。例: pixmicat.php）。
+	CGIName string `json:"cgi_name,omitempty"`
+	// SrcDir は、板のURLからの添付ファイルのディレクトリ名です（既定: src）。
+	SrcDir string `json:"src_dir,omitempty"`
+	// ThumbDir は、板のURLからのサムネイルのディレクトリ名です（既定: thumb）。
+	ThumbDir string `json:"thumb_dir,omitempty"`
+	// CookieName は、カタログの表示件数を指定する Cookie の名前です（既定: cxyl）。"-" の場合は Cookie を送信しません。
+	CookieName string `json:"cookie_name,omitempty"`
+}
+
+// FutabaBoardQuirks は、ふたばの板ごとのパス・サムネイル・スクリプトのホストの違いを定義します。
+// 空の項目は既定値（板のURL以下の src/・thumb/、サムネイルは <ファイル名>s.jpg、スクリプトは板と同じホスト）を使用します。
+type FutabaBoardQuirks struct {
+	// SrcPath は、添付ファイルを置くディレクトリのサイト内の絶対パスです（例: "/b/src"）。
+	SrcPath string `json:"src_path,omitempty"`
+	// ThumbPath は、サムネイルを置くディレクトリのサイト内の絶対パスです（例: "/b/thumb"）。
+	ThumbPath string `json:"thumb_path,omitempty"`
+	// ThumbExtension は、サムネイルの拡張子です（既定: "jpg"）。
+	ThumbExtension string `json:"thumb_extension,omitempty"`
+	// NoThumbnails が true の場合、板はサムネイルを生成しないものとして、サムネイルを取得しません。
+	NoThumbnails bool `json:"no_thumbnails,omitempty"`
+	// CGIHost は、カタログを表示するスクリプト（futaba.php）のホスト名です。空の場合は板のホストです。
+	CGIHost string `json:"cgi_host,omitempty"`
+}
+
+// BoardMetaSettings は、板のルール・注意書きなど、スレッドの外にある板の情報を保存する設定です。
+// アーカイブを後から読む人が、当時の板の決まりや雰囲気を知る手がかりにします。
+type BoardMetaSettings struct {
+	// Pages は、アダプタが対応するページ（ふたばの板のトップページなど）に加えて保存するページ（ルール・ガイドなど）のURLです。
+	Pages []string `json:"pages,omitempty"`
+	// RefreshDays は、前回の保存から保存し直すまでの日数です。0の場合は DefaultBoardMetaRefreshDays です。
+	RefreshDays int `json:"refresh_days,omitempty"`
+}
+
+// FivechSettings は、5ch/2ch 互換の掲示板のスレッドの取得方法を定義します。
+type FivechSettings struct {
+	// UseReadCGI が true の場合、dat（<板>/dat/<スレッドID>.dat）の代わりに read.cgi のHTMLからスレッドを取得します。
+	// dat の取得が制限されている掲示板で使用します。
+	UseReadCGI bool `json:"use_read_cgi"`
+}
+
+// VichanSettings は、vichan 系の掲示板（8kun・lainchan など）のメディアの配信元を定義します。
+type VichanSettings struct {
+	// MediaBaseURL は、file_store に保存されたメディア（API の fpath が 1 のファイル）の配信元です（例: "https://media.128ducks.com"）。
+	// 空の場合は板と同じサイトから取得します。
+	MediaBaseURL string `json:"media_base_url,omitempty"`
+}
+
+// booru_settings.api に指定できる値です。
+const (
+	BooruAPIDanbooru = "danbooru" // Danbooru（/posts.json）
+	BooruAPIGelbooru = "gelbooru" // Gelbooru 0.2 系（index.php?page=dapi）
+)
+
+// MaxBooruPageLimit は、booru_settings.limit に指定できる最大値です（Gelbooru の API の上限）。
+const MaxBooruPageLimit = 100
+
+// BooruSettings は、booru 系の画像掲示板（Danbooru・Gelbooru）をタグの検索結果でアーカイブするための設定です。
+// 検索結果の各投稿を1つのスレッドとし、投稿の画像を1件のメディアとして保存します。
+type BooruSettings struct {
+	// API は、掲示板のAPIの種類（"danbooru" または "gelbooru"）です。
+	API string `json:"api"`
+	// Tags は、検索するタグ（空白区切り、例: "touhou rating:general"）です。
+	Tags string `json:"tags"`
+	// Limit は、1ページあたりの投稿数です（既定: 100、最大 100）。
+	Limit int `json:"limit,omitempty"`
+	// MaxPages は、巡回のたびに取得する検索結果のページ数です（既定: 1）。新しい投稿から順に取得します。
+	MaxPages int `json:"max_pages,omitempty"`
+	// Login は、APIの認証に使用するユーザー名（Danbooru）またはユーザーID（Gelbooru）です。${secret:名前} を指定できます。
+	Login string `json:"login,omitempty"`
+	// APIKey は、APIキーです。${secret:名前} を指定できます。
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// SiteAdapterBooru は、booru_settings のタグの検索結果をアーカイブする booru アダプタの名前です。
+const SiteAdapterBooru = "booru"
+
+// SiteAdapterGeneric は、generic_settings のセレクタでHTMLを解析する汎用アダプタの名前です。
+const SiteAdapterGeneric = "generic"
+
+// DefaultGenericThreadIDPattern は、generic_settings の thread_id_pattern の既定値です（スレッドのURLの最後の数字）。
+const DefaultGenericThreadIDPattern = `(\d+)\D*$`
+
+// GenericSettings は、vichan・wakaba 系などの掲示板をGoのコードを書かずにアーカイブするための、CSSセレクタと正規表現です。
+// セレクタは goquery（CSS3 セレクタ）の形式で指定します。
+type GenericSettings struct {
+	// CatalogPath は、板のURLからのカタログページの相対パスです（例: "catalog.html"）。空の場合は板のURLそのものです。
+	CatalogPath string `json:"catalog_path,omitempty"`
+	// ThreadLinkSelector は、カタログ内のスレッドへのリンク（a 要素）のセレクタです（必須。例: "a[href*='/res/']"）。
+	ThreadLinkSelector string `json:"thread_link_selector"`
+	// ThreadIDPattern は、スレッドのURLからスレッドIDを取り出す正規表現です（最初のグループ。省略時は URL の最後の数字）。
+	ThreadIDPattern string `json:"thread_id_pattern,omitempty"`
+	// TitleSelector は、スレッドページのタイトルのセレクタです。カタログのリンクの文字列が空の場合にタイトルとして使用します。
+	TitleSelector string `json:"title_selector,omitempty"`
+	// PostSelector は、スレッドページの各レスの要素のセレクタです。要素の id 属性の数字をレス番号とします。
+	PostSelector string `json:"post_selector,omitempty"`
+	// MediaLinkSelector は、フルサイズのメディアへのリンク（a 要素）のセレクタです（省略時はアーカイブ対象の拡張子へのすべてのリンク）。
+	MediaLinkSelector string `json:"media_link_selector,omitempty"`
+	// ThumbnailSelector は、メディアのリンク内のサムネイル（img 要素）のセレクタです（省略時は "img"）。
+	ThumbnailSelector string `json:"thumbnail_selector,omitempty"`
+}
+
+// DefaultPluginTimeoutSec は、adapter_plugins の timeout_sec の既定値です。
+const DefaultPluginTimeoutSec = 60
+
+// AdapterPlugin は、標準入出力のJSONで通信する外部プログラムのサイトアダプタの起動方法です。
+// Python や Node.js などで実装したアダプタを、GIBAを再ビルドせずに使用できます。
+type AdapterPlugin struct {
+	// Command は、実行するプログラムです（例: "python3"）。
+	Command string `json:"command"`
+	// Args は、プログラムに渡す引数です（例: ["plugins/myboard.py"]）。
+	Args []string `json:"args,omitempty"`
+	// TimeoutSec は、1回の呼び出しでプログラムの終了を待つ秒数です（省略時は DefaultPluginTimeoutSec）。
+	TimeoutSec int `json:"timeout_sec,omitempty"`
+	// Capabilities は、プログラムが対応している機能です（"json_api", "thumbnails", "deletion_markers", "res_numbers"）。
+	// 省略時は "thumbnails" と "res_numbers" に対応しているものとします。
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// 外部プログラムのサイトアダプタが対応している機能 (AdapterPlugin.Capabilities)
+const (
+	PluginCapabilityJSONAPI         = "json_api"
+	PluginCapabilityThumbnails      = "thumbnails"
+	PluginCapabilityDeletionMarkers = "deletion_markers"
+	PluginCapabilityResNumbers      = "res_numbers"
+)
+
+// RetryPolicy は、エラー種別ごとのリトライ動作を定義します。
+// retry_policies のキーには "timeout", "server_error", "rate_limited", "write_failure", "other" を指定できます。
+type RetryPolicy struct {
+	// RetryCount は最大リトライ回数です。
+	RetryCount int `json:"retry_count"`
+	// WaitMillis は初回リトライまでの待機時間です。
+	WaitMillis int `json:"wait_ms"`
+	// BackoffMultiplier はリトライごとに待機時間に掛ける倍率です（1以下の場合は一定間隔）。
+	BackoffMultiplier float64 `json:"backoff_multiplier,omitempty"`
+	// RequeueNextCycle が true の場合、リトライ上限に達したファイルを次回の監視サイクルで再取得します。
+	RequeueNextCycle bool `json:"requeue_next_cycle,omitempty"`
+}
+
+// 一覧ページの期間 (Task.RollupPages)
+const (
+	RollupWeekly  = "weekly"  // ISO週（月曜始まり）ごと
+	RollupMonthly = "monthly" // 月ごと
+)
+
+// スレッドの保存形式 (Task.RenderStrategies)
+const (
+	RenderLatest     = "latest"      // 最新状態のHTML（index.htm）
+	RenderFull       = "full"        // 削除されたレス・画像を含む完全版（archive_full.html）
+	RenderJSON       = "json"        // レスとメディアの一覧（thread_data.json）
+	RenderSingleFile = "single_file" // 画像とCSSを埋め込んだ1ファイルの完全版（archive_single.html）
+)
+
+// レジュームファイルの更新間隔の既定値 (Task.ResumeFlushEvery, Task.ResumeFlushIntervalMillis)
+const (
+	DefaultResumeFlushEvery          = 10
+	DefaultResumeFlushIntervalMillis = 5000
+)
+
+// ディスクへの同期の対象 (Task.FsyncPolicy)
+const (
+	FsyncNone   = "none"   // 同期しない（OSの書き込みキャッシュに任せる）
+	FsyncResume = "resume" // レジュームファイルの更新時のみ同期する
+	FsyncAll    = "all"    // ダウンロードしたファイルとレジュームファイルを毎回同期する
+)
+
+// EncryptionAESGCM は、Task.Encryption に指定できる暗号化の方式（AES-256-GCM）です。
+const EncryptionAESGCM = "aes-gcm"
+
+// DefaultMaintenanceBackoffMillis は、Task.MaintenanceBackoffMillis の既定値（10分）です。
+const DefaultMaintenanceBackoffMillis = 10 * 60 * 1000
+
+// DefaultBoardMetaRefreshDays は、BoardMetaSettings.RefreshDays の既定値（1週間）です。
+const DefaultBoardMetaRefreshDays = 7
+
+// MaxWatchJitterPercent は、Task.WatchJitterPercent に指定できる最大値です。
+const MaxWatchJitterPercent = 50
+
+// 認証方式 (AuthSettings.Type)
+const (
+	AuthTypeForm   = "form"   // ログインフォームに値をPOSTし、発行されたセッションCookieを使用する
+	AuthTypeCookie = "cookie" // ブラウザなどで取得済みのCookieを設定する
+)
+
+// AuthSettings は、パスワード付き・会員制の掲示板にアクセスするための認証設定です。
+// fields・cookies の値に含まれる "${secret:名前}" は、シークレットストア（環境変数 GIBA_SECRET_<名前> または
+// secrets.json）の値に置き換えられるため、パスワードなどを config.json に直接書く必要はありません。
+type AuthSettings struct {
+	// Type は認証方式です ("form" または "cookie")。
+	Type string `json:"type"`
+	// LoginURL は、"form" の場合にPOSTするログインフォームの送信先です。
+	LoginURL string `json:"login_url,omitempty"`
+	// Fields は、"form" の場合にログインフォームに送信する値です。
+	Fields map[string]string `json:"fields,omitempty"`
+	// Cookies は、"cookie" の場合に板のドメインに設定するCookieです。"form" の場合もログイン前に設定されます。
+	Cookies map[string]string `json:"cookies,omitempty"`
+	// SuccessCookie は、ログインに成功すると発行されるCookieの名前です。指定した場合、発行されなければ失敗とみなします。
+	SuccessCookie string `json:"success_cookie,omitempty"`
+	// FailureText は、ログインに失敗した場合にのみレスポンスに含まれる文字列です（例: "パスワードが違います"）。
+	FailureText string `json:"failure_text,omitempty"`
+}
+
+// ArchiveHookSettings は、ブラウザのユーザースクリプトや拡張機能から、閲覧中のスレッドのアーカイブを依頼するエンドポイント（/api/hooks/archive）の設定です。
+// 設定画面のWeb UIは起動のたびにポートが変わるため、固定のアドレスで待ち受ける別のサーバーで受け付けます。
+type ArchiveHookSettings struct {
+	// ListenAddr は、受信用のサーバーが待ち受けるアドレスです（例: "127.0.0.1:8091"）。
+	ListenAddr string `json:"listen_addr"`
+	// Token は、依頼に必要なトークンです（Authorization: Bearer または X-GIBA-Token ヘッダーで送信）。${secret:名前} を指定できます。
+	Token string `json:"token"`
+}
+
+// SharingSettings は、Web UIの共有モードの設定です。
+// 共有モードは設定画面や操作用のAPIを持たない別のサーバーで、アーカイブの一覧・検索・スレッドの閲覧のみを提供します。
+type SharingSettings struct {
+	// ListenAddr は、共有モードのサーバーが待ち受けるアドレスです（例: "0.0.0.0:8090"）。
+	ListenAddr string `json:"listen_addr"`
+	// Users は、閲覧を許可するユーザー名とパスワードです（Basic認証）。パスワードには ${secret:名前} を指定できます。
+	Users map[string]string `json:"users,omitempty"`
+	// AllowAnonymous が true の場合、認証なしで閲覧できます。users を指定しない場合は明示的に有効にする必要があります。
+	AllowAnonymous bool `json:"allow_anonymous,omitempty"`
+	// TLSCertFile と TLSKeyFile を指定した場合は HTTPS で待ち受けます。
+	TLSCertFile string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile  string `json:"tls_key_file,omitempty"`
 }
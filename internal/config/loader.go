@@ -4,67 +4,177 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 )
 
 // taskPatch は、タスク設定をデコードするための中間ヘルパー構造体です。
 // ポインタ型を使用しているのは、JSONに存在しないフィールド（未設定）と、
 // ゼロ値（例: 0や空文字列）が設定されているケースを区別するためです。
 type taskPatch struct {
-	Enabled                *bool                  `json:"enabled,omitempty"`
-	TaskName               *string                `json:"task_name,omitempty"`
-	UseTemplate            string                 `json:"use_template,omitempty"`
-	SiteAdapter            *string                `json:"site_adapter,omitempty"`
-	TargetBoardURL         *string                `json:"target_board_url,omitempty"`
-	SaveRootDirectory      *string                `json:"save_root_directory,omitempty"`
-	DirectoryFormat        *string                `json:"directory_format,omitempty"`
-	FilenameFormat         *string                `json:"filename_format,omitempty"`
-	SearchKeyword          *string                `json:"search_keyword,omitempty"`
-	ExcludeKeywords        *[]string              `json:"exclude_keywords,omitempty"`
-	MinimumMediaCount      *int                   `json:"minimum_media_count,omitempty"`
-	WatchIntervalMillis    *int                   `json:"watch_interval_ms,omitempty"`
-	MaxConcurrentDownloads *int                   `json:"max_concurrent_downloads,omitempty"`
-	PostContentFilters     *PostContentFilters    `json:"post_content_filters,omitempty"`
-	RetryCount             *int                   `json:"retry_count,omitempty"`
-	RetryWaitMillis        *int                   `json:"retry_wait_ms,omitempty"`
-	RequestTimeoutMillis   *int                   `json:"request_timeout_ms,omitempty"`
-	RequestIntervalMillis  *int                   `json:"request_interval_ms,omitempty"`
-	NotifyOnComplete       *bool                  `json:"notify_on_complete,omitempty"`
-	NotifyOnError          *bool                  `json:"notify_on_error,omitempty"`
-	EnableHistorySkip      *bool                  `json:"enable_history_skip,omitempty"`
-	EnableResumeSupport    *bool                  `json:"enable_resume_support,omitempty"`
-	EnableLogFile          *bool                  `json:"enable_log_file,omitempty"`
-	LogLevel               *string                `json:"log_level,omitempty"`
-	EnableMetadataIndex    *bool                  `json:"enable_metadata_index,omitempty"`
-	FutabaCatalogSettings  *FutabaCatalogSettings `json:"futaba_catalog_settings,omitempty"`
+	Enabled                     *bool                     `json:"enabled,omitempty"`
+	TaskName                    *string                   `json:"task_name,omitempty"`
+	UseTemplate                 string                    `json:"use_template,omitempty"`
+	SiteAdapter                 *string                   `json:"site_adapter,omitempty"`
+	TargetBoardURL              *string                   `json:"target_board_url,omitempty"`
+	SaveRootDirectory           *string                   `json:"save_root_directory,omitempty"`
+	DirectoryFormat             *string                   `json:"directory_format,omitempty"`
+	FilenameFormat              *string                   `json:"filename_format,omitempty"`
+	SearchKeyword               *string                   `json:"search_keyword,omitempty"`
+	ExcludeKeywords             *[]string                 `json:"exclude_keywords,omitempty"`
+	MinimumMediaCount           *int                      `json:"minimum_media_count,omitempty"`
+	WatchIntervalMillis         *int                      `json:"watch_interval_ms,omitempty"`
+	MaxConcurrentDownloads      *int                      `json:"max_concurrent_downloads,omitempty"`
+	PostContentFilters          *PostContentFilters       `json:"post_content_filters,omitempty"`
+	RetryCount                  *int                      `json:"retry_count,omitempty"`
+	RetryWaitMillis             *int                      `json:"retry_wait_ms,omitempty"`
+	RequestTimeoutMillis        *int                      `json:"request_timeout_ms,omitempty"`
+	RequestIntervalMillis       *int                      `json:"request_interval_ms,omitempty"`
+	NotifyOnComplete            *bool                     `json:"notify_on_complete,omitempty"`
+	NotifyOnError               *bool                     `json:"notify_on_error,omitempty"`
+	EnableHistorySkip           *bool                     `json:"enable_history_skip,omitempty"`
+	EnableResumeSupport         *bool                     `json:"enable_resume_support,omitempty"`
+	EnableLogFile               *bool                     `json:"enable_log_file,omitempty"`
+	LogLevel                    *string                   `json:"log_level,omitempty"`
+	EnableMetadataIndex         *bool                     `json:"enable_metadata_index,omitempty"`
+	FutabaCatalogSettings       *FutabaCatalogSettings    `json:"futaba_catalog_settings,omitempty"`
+	FutabaSettings              *FutabaSettings           `json:"futaba_settings,omitempty"`
+	FutabaCompatibleSettings    *FutabaCompatibleSettings `json:"futaba_compatible_settings,omitempty"`
+	FutabaBoardQuirks           *FutabaBoardQuirks        `json:"futaba_board_quirks,omitempty"`
+	FivechSettings              *FivechSettings           `json:"fivech_settings,omitempty"`
+	VichanSettings              *VichanSettings           `json:"vichan_settings,omitempty"`
+	GenericSettings             *GenericSettings          `json:"generic_settings,omitempty"`
+	BooruSettings               *BooruSettings            `json:"booru_settings,omitempty"`
+	PluginSettings              map[string]any            `json:"plugin_settings,omitempty"`
+	DownloadThumbnails          *bool                     `json:"download_thumbnails,omitempty"`
+	ThumbnailsOnly              *bool                     `json:"thumbnails_only,omitempty"`
+	AnimatedThumbnailMode       *string                   `json:"animated_thumbnail_mode,omitempty"`
+	RetryPolicies               *map[string]RetryPolicy   `json:"retry_policies,omitempty"`
+	NamingConflictPolicy        *string                   `json:"naming_conflict_policy,omitempty"`
+	MaxTitleLength              *int                      `json:"max_title_length,omitempty"`
+	TitleFallbackLength         *int                      `json:"title_fallback_length,omitempty"`
+	MinSuccessRatio             *float64                  `json:"min_success_ratio,omitempty"`
+	MaxConcurrentThreads        *int                      `json:"max_concurrent_threads,omitempty"`
+	MaxConcurrentFilesPerThread *int                      `json:"max_concurrent_files_per_thread,omitempty"`
+	Group                       *string                   `json:"group,omitempty"`
+	MediaExtensions             *[]string                 `json:"media_extensions,omitempty"`
+	PaginationMode              *string                   `json:"pagination_mode,omitempty"`
+	PostsPerPage                *int                      `json:"posts_per_page,omitempty"`
+	BlockedMediaPatterns        *[]string                 `json:"blocked_media_patterns,omitempty"`
+	StripAds                    *bool                     `json:"strip_ads,omitempty"`
+	KeepRawHTML                 *bool                     `json:"keep_raw_html,omitempty"`
+	LogFilePath                 *string                   `json:"log_file_path,omitempty"`
+	ArchiveHeader               *bool                     `json:"archive_header,omitempty"`
+	Auth                        *AuthSettings             `json:"auth,omitempty"`
+	BoardTimezone               *string                   `json:"board_timezone,omitempty"`
+	Timezone                    *string                   `json:"timezone,omitempty"`
+	RollupPages                 *[]string                 `json:"rollup_pages,omitempty"`
+	Tags                        *[]string                 `json:"tags,omitempty"`
+	RenderStrategies            *[]string                 `json:"render_strategies,omitempty"`
+	AdaptivePolling             *bool                     `json:"adaptive_polling,omitempty"`
+	PollMinIntervalMillis       *int                      `json:"poll_min_interval_ms,omitempty"`
+	PollMaxIntervalMillis       *int                      `json:"poll_max_interval_ms,omitempty"`
+	WatchJitterPercent          *int                      `json:"watch_jitter_percent,omitempty"`
+	MaintenanceBackoffMillis    *int                      `json:"maintenance_backoff_ms,omitempty"`
+	BoardMeta                   *BoardMetaSettings        `json:"board_meta,omitempty"`
+	ResumeFlushEvery            *int                      `json:"resume_flush_every,omitempty"`
+	ResumeFlushIntervalMillis   *int                      `json:"resume_flush_interval_ms,omitempty"`
+	FsyncPolicy                 *string                   `json:"fsync_policy,omitempty"`
+	Encryption                  *string                   `json:"encryption,omitempty"`
+	EncryptionKeySecret         *string                   `json:"encryption_key_secret,omitempty"`
 }
 
 // rawConfig は、設定ファイルをデコードするための中間構造体です。
 type rawConfig struct {
-	ConfigVersion           string          `json:"config_version"`
-	GlobalSaveRootDirectory string          `json:"global_save_root_directory,omitempty"`
-	WebUITheme              string          `json:"web_ui_theme,omitempty"`
-	Network                 NetworkSettings `json:"network"`
-	GlobalMaxConcurrentTasks int            `json:"global_max_concurrent_tasks"`
-	SafetyStopMinDiskGB     float64         `json:"safety_stop_min_disk_gb"`
-	NotificationWebhookURL  string          `json:"notification_webhook_url"`
-	TaskTemplates           map[string]Task `json:"task_templates"`
-	Tasks                   []taskPatch     `json:"tasks"`
-	EnableLogFile           bool            `json:"enable_log_file"`
-	LogFilePath             string          `json:"log_file_path,omitempty"`
+	ConfigVersion            string                     `json:"config_version"`
+	GlobalSaveRootDirectory  string                     `json:"global_save_root_directory,omitempty"`
+	WebUITheme               string                     `json:"web_ui_theme,omitempty"`
+	Network                  NetworkSettings            `json:"network"`
+	GlobalMaxConcurrentTasks int                        `json:"global_max_concurrent_tasks"`
+	SafetyStopMinDiskGB      float64                    `json:"safety_stop_min_disk_gb"`
+	NotificationWebhookURL   string                     `json:"notification_webhook_url"`
+	TaskTemplates            map[string]Task            `json:"task_templates"`
+	Tasks                    []taskPatch                `json:"tasks"`
+	TaskGroups               map[string]TaskGroup       `json:"task_groups,omitempty"`
+	AdapterSettings          map[string]AdapterSettings `json:"adapter_settings,omitempty"`
+	BlockedMediaPatterns     []string                   `json:"blocked_media_patterns,omitempty"`
+	EnableLogFile            bool                       `json:"enable_log_file"`
+	LogFilePath              string                     `json:"log_file_path,omitempty"`
+	CheckForUpdates          bool                       `json:"check_for_updates,omitempty"`
+	HeartbeatFile            string                     `json:"heartbeat_file,omitempty"`
+	StatusFile               string                     `json:"status_file,omitempty"`
+	UsageStatsFile           string                     `json:"usage_stats_file,omitempty"`
+	SecretsFile              string                     `json:"secrets_file,omitempty"`
+	Timezone                 string                     `json:"timezone,omitempty"`
+	Sharing                  *SharingSettings           `json:"sharing,omitempty"`
+	ArchiveHook              *ArchiveHookSettings       `json:"archive_hook,omitempty"`
+	AdapterPlugins           map[string]AdapterPlugin   `json:"adapter_plugins,omitempty"`
 }
 
 // LoadAndResolve は、指定されたパスから設定ファイルを読み込み、解析と解決を行います。
+// 設定ファイル内の相対パス（保存先・ログファイルなど）は、起動したディレクトリではなく設定ファイルのディレクトリを基準に解決します。
 func LoadAndResolve(path string) (*Config, error) {
-	absPath, _ := filepath.Abs(path)
-	cwd, _ := os.Getwd()
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルのパス '%s' を絶対パスに変換できません: %w", path, err)
+	}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
+		// 作業ディレクトリは、相対パスで指定した設定ファイルが見つからない原因を調べるためにエラーに含める
+		cwd, cwdErr := os.Getwd()
+		if cwdErr != nil {
+			cwd = fmt.Sprintf("取得できません: %v", cwdErr)
+		}
 		return nil, fmt.Errorf("設定ファイル '%s' の読み込みに失敗しました (Abs: '%s', Cwd: '%s'): %w", path, absPath, cwd, err)
 	}
-	return ParseAndResolve(data)
+	cfg, err := ParseAndResolve(data)
+	if err != nil {
+		return nil, err
+	}
+	resolveRelativePaths(cfg, filepath.Dir(absPath))
+	return cfg, nil
+}
+
+// resolveRelativePaths は、設定に含まれるファイル・ディレクトリの相対パスを baseDir を基準とした絶対パスにします。
+// 空のパス（未設定）はそのままにします。
+func resolveRelativePaths(cfg *Config, baseDir string) {
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(baseDir, *p)
+		}
+	}
+	resolve(&cfg.GlobalSaveRootDirectory)
+	resolve(&cfg.LogFilePath)
+	resolve(&cfg.HeartbeatFile)
+	resolve(&cfg.StatusFile)
+	resolve(&cfg.UsageStatsFile)
+	resolve(&cfg.SecretsFile)
+	if cfg.Sharing != nil {
+		resolve(&cfg.Sharing.TLSCertFile)
+		resolve(&cfg.Sharing.TLSKeyFile)
+	}
+	for i := range cfg.Tasks {
+		resolve(&cfg.Tasks[i].SaveRootDirectory)
+		resolve(&cfg.Tasks[i].LogFilePath)
+	}
+	for name, template := range cfg.TaskTemplates {
+		resolve(&template.SaveRootDirectory)
+		resolve(&template.LogFilePath)
+		cfg.TaskTemplates[name] = template
+	}
+	// プログラム名のみの command（例: "python3"）は PATH から探すため、ディレクトリを含む場合のみ解決する
+	for name, plugin := range cfg.AdapterPlugins {
+		if strings.ContainsAny(plugin.Command, `/\`) {
+			resolve(&plugin.Command)
+			cfg.AdapterPlugins[name] = plugin
+		}
+	}
 }
 
 // ParseAndResolve は、設定データのバイトスライスを解析し、テンプレートを解決して最終的な設定を返します。
@@ -92,19 +202,47 @@ func ParseAndResolve(data []byte) (*Config, error) {
 		return nil, fmt.Errorf("サポートされていない設定バージョン '%s' です。'%s' が必要です。", rawCfg.ConfigVersion, compatibleVersion)
 	}
 
+	if err := validateBlockedMediaPatterns(rawCfg.BlockedMediaPatterns); err != nil {
+		return nil, fmt.Errorf("blocked_media_patterns の設定が不正です: %w", err)
+	}
+	if err := validateTimezone(rawCfg.Timezone); err != nil {
+		return nil, fmt.Errorf("timezone の設定が不正です: %w", err)
+	}
+	if err := validateSharingSettings(rawCfg.Sharing); err != nil {
+		return nil, fmt.Errorf("sharing の設定が不正です: %w", err)
+	}
+	if err := validateArchiveHookSettings(rawCfg.ArchiveHook); err != nil {
+		return nil, fmt.Errorf("archive_hook の設定が不正です: %w", err)
+	}
+	if err := validateAdapterPlugins(rawCfg.AdapterPlugins); err != nil {
+		return nil, fmt.Errorf("adapter_plugins の設定が不正です: %w", err)
+	}
+
 	// 新しいConfig構造体に合わせて初期化
 	resolvedConfig := &Config{
-		ConfigVersion:           rawCfg.ConfigVersion,
-		GlobalSaveRootDirectory: rawCfg.GlobalSaveRootDirectory,
-		WebUITheme:              rawCfg.WebUITheme,
-		Network:                 rawCfg.Network,
+		ConfigVersion:            rawCfg.ConfigVersion,
+		GlobalSaveRootDirectory:  rawCfg.GlobalSaveRootDirectory,
+		WebUITheme:               rawCfg.WebUITheme,
+		Network:                  rawCfg.Network,
 		GlobalMaxConcurrentTasks: rawCfg.GlobalMaxConcurrentTasks,
-		SafetyStopMinDiskGB:     rawCfg.SafetyStopMinDiskGB,
-		NotificationWebhookURL:  rawCfg.NotificationWebhookURL,
-		TaskTemplates:           rawCfg.TaskTemplates,
-		EnableLogFile:           rawCfg.EnableLogFile,
-		LogFilePath:             rawCfg.LogFilePath,
-		Tasks:                   make([]Task, 0, len(rawCfg.Tasks)),
+		SafetyStopMinDiskGB:      rawCfg.SafetyStopMinDiskGB,
+		NotificationWebhookURL:   rawCfg.NotificationWebhookURL,
+		TaskTemplates:            rawCfg.TaskTemplates,
+		TaskGroups:               rawCfg.TaskGroups,
+		AdapterSettings:          rawCfg.AdapterSettings,
+		BlockedMediaPatterns:     rawCfg.BlockedMediaPatterns,
+		EnableLogFile:            rawCfg.EnableLogFile,
+		LogFilePath:              rawCfg.LogFilePath,
+		CheckForUpdates:          rawCfg.CheckForUpdates,
+		HeartbeatFile:            rawCfg.HeartbeatFile,
+		StatusFile:               rawCfg.StatusFile,
+		UsageStatsFile:           rawCfg.UsageStatsFile,
+		SecretsFile:              rawCfg.SecretsFile,
+		Timezone:                 rawCfg.Timezone,
+		Sharing:                  rawCfg.Sharing,
+		ArchiveHook:              rawCfg.ArchiveHook,
+		AdapterPlugins:           rawCfg.AdapterPlugins,
+		Tasks:                    make([]Task, 0, len(rawCfg.Tasks)),
 	}
 
 	for _, patch := range rawCfg.Tasks {
@@ -122,6 +260,108 @@ func ParseAndResolve(data []byte) (*Config, error) {
 		}
 		applyPatch(&resolvedTask, &patch) // パッチで上書き
 
+		// 旧設定 max_concurrent_downloads はスレッド単位の並列数として扱う
+		if resolvedTask.MaxConcurrentThreads == 0 && resolvedTask.MaxConcurrentDownloads > 0 {
+			resolvedTask.MaxConcurrentThreads = resolvedTask.MaxConcurrentDownloads
+		}
+
+		// 対象の拡張子が未設定の場合は、サイトアダプタごとの既定値を使用する
+		if len(resolvedTask.MediaExtensions) == 0 {
+			resolvedTask.MediaExtensions = rawCfg.AdapterSettings[resolvedTask.SiteAdapter].MediaExtensions
+		}
+		// 板ごとの違いが未設定の場合は、サイトアダプタごとの設定から板に一致するものを使用する
+		if resolvedTask.FutabaBoardQuirks == nil {
+			if quirks, ok := rawCfg.AdapterSettings[resolvedTask.SiteAdapter].BoardQuirks[BoardKey(resolvedTask.TargetBoardURL)]; ok {
+				resolvedTask.FutabaBoardQuirks = &quirks
+			}
+		}
+
+		if err := validateBlockedMediaPatterns(resolvedTask.BlockedMediaPatterns); err != nil {
+			return nil, fmt.Errorf("タスク '%s' の blocked_media_patterns の設定が不正です: %w", resolvedTask.TaskName, err)
+		}
+		resolvedTask.GlobalBlockedMediaPatterns = rawCfg.BlockedMediaPatterns
+		if err := validateAuthSettings(resolvedTask.Auth); err != nil {
+			return nil, fmt.Errorf("タスク '%s' の auth の設定が不正です: %w", resolvedTask.TaskName, err)
+		}
+		for _, period := range resolvedTask.RollupPages {
+			if period != RollupWeekly && period != RollupMonthly {
+				return nil, fmt.Errorf("タスク '%s' の rollup_pages の値 %q は不明です（%q または %q を指定してください）", resolvedTask.TaskName, period, RollupWeekly, RollupMonthly)
+			}
+		}
+		for _, strategy := range resolvedTask.RenderStrategies {
+			switch strategy {
+			case RenderLatest, RenderFull, RenderJSON, RenderSingleFile:
+			default:
+				return nil, fmt.Errorf("タスク '%s' の render_strategies の値 %q は不明です（%q, %q, %q, %q のいずれかを指定してください）",
+					resolvedTask.TaskName, strategy, RenderLatest, RenderFull, RenderJSON, RenderSingleFile)
+			}
+		}
+		if resolvedTask.PollMinIntervalMillis < 0 || resolvedTask.PollMaxIntervalMillis < 0 {
+			return nil, fmt.Errorf("タスク '%s' の poll_min_interval_ms と poll_max_interval_ms には0以上の値を指定してください", resolvedTask.TaskName)
+		}
+		if resolvedTask.PollMinIntervalMillis > 0 && resolvedTask.PollMaxIntervalMillis > 0 && resolvedTask.PollMaxIntervalMillis < resolvedTask.PollMinIntervalMillis {
+			return nil, fmt.Errorf("タスク '%s' の poll_max_interval_ms (%d) が poll_min_interval_ms (%d) より小さくなっています",
+				resolvedTask.TaskName, resolvedTask.PollMaxIntervalMillis, resolvedTask.PollMinIntervalMillis)
+		}
+		if resolvedTask.WatchJitterPercent < 0 || resolvedTask.WatchJitterPercent > MaxWatchJitterPercent {
+			return nil, fmt.Errorf("タスク '%s' の watch_jitter_percent には0から%dまでの値を指定してください（指定値: %d）", resolvedTask.TaskName, MaxWatchJitterPercent, resolvedTask.WatchJitterPercent)
+		}
+		if resolvedTask.MaintenanceBackoffMillis < 0 {
+			return nil, fmt.Errorf("タスク '%s' の maintenance_backoff_ms には0以上の値を指定してください（指定値: %d）", resolvedTask.TaskName, resolvedTask.MaintenanceBackoffMillis)
+		}
+		if resolvedTask.ResumeFlushEvery < 0 || resolvedTask.ResumeFlushIntervalMillis < 0 {
+			return nil, fmt.Errorf("タスク '%s' の resume_flush_every と resume_flush_interval_ms には0以上の値を指定してください", resolvedTask.TaskName)
+		}
+		switch resolvedTask.FsyncPolicy {
+		case "", FsyncNone, FsyncResume, FsyncAll:
+		default:
+			return nil, fmt.Errorf("タスク '%s' の fsync_policy の値 %q は不明です（%q, %q, %q のいずれかを指定してください）",
+				resolvedTask.TaskName, resolvedTask.FsyncPolicy, FsyncNone, FsyncResume, FsyncAll)
+		}
+		if err := validateEncryption(resolvedTask); err != nil {
+			return nil, fmt.Errorf("タスク '%s' の暗号化の設定が不正です: %w", resolvedTask.TaskName, err)
+		}
+		if resolvedTask.FutabaSettings != nil {
+			if err := validateFallbackSources(resolvedTask.FutabaSettings.FallbackSources); err != nil {
+				return nil, fmt.Errorf("タスク '%s' の futaba_settings.fallback_sources の設定が不正です: %w", resolvedTask.TaskName, err)
+			}
+		}
+		if resolvedTask.FutabaCompatibleSettings != nil {
+			if err := validateFutabaCompatibleSettings(resolvedTask.FutabaCompatibleSettings); err != nil {
+				return nil, fmt.Errorf("タスク '%s' の futaba_compatible_settings の設定が不正です: %w", resolvedTask.TaskName, err)
+			}
+		}
+		if resolvedTask.FutabaBoardQuirks != nil {
+			if err := validateFutabaBoardQuirks(resolvedTask.FutabaBoardQuirks); err != nil {
+				return nil, fmt.Errorf("タスク '%s' の futaba_board_quirks の設定が不正です: %w", resolvedTask.TaskName, err)
+			}
+		}
+		if resolvedTask.BoardMeta != nil {
+			if err := validateBoardMetaSettings(resolvedTask.BoardMeta); err != nil {
+				return nil, fmt.Errorf("タスク '%s' の board_meta の設定が不正です: %w", resolvedTask.TaskName, err)
+			}
+		}
+		if resolvedTask.SiteAdapter == SiteAdapterGeneric {
+			if err := validateGenericSettings(resolvedTask.GenericSettings); err != nil {
+				return nil, fmt.Errorf("タスク '%s' の generic_settings の設定が不正です: %w", resolvedTask.TaskName, err)
+			}
+		}
+		if resolvedTask.SiteAdapter == SiteAdapterBooru {
+			if err := validateBooruSettings(resolvedTask.BooruSettings); err != nil {
+				return nil, fmt.Errorf("タスク '%s' の booru_settings の設定が不正です: %w", resolvedTask.TaskName, err)
+			}
+		}
+		if err := validateTimezone(resolvedTask.BoardTimezone); err != nil {
+			return nil, fmt.Errorf("タスク '%s' の board_timezone の設定が不正です: %w", resolvedTask.TaskName, err)
+		}
+		// タスクの timezone が未設定の場合は、設定ファイル直下の timezone を使用する
+		if resolvedTask.Timezone == "" {
+			resolvedTask.Timezone = rawCfg.Timezone
+		}
+		if err := validateTimezone(resolvedTask.Timezone); err != nil {
+			return nil, fmt.Errorf("タスク '%s' の timezone の設定が不正です: %w", resolvedTask.TaskName, err)
+		}
+
 		// Enabledフィールドが未設定の場合、デフォルトでtrueにする
 		if resolvedTask.Enabled == nil {
 			defaultValue := true
@@ -134,6 +374,142 @@ func ParseAndResolve(data []byte) (*Config, error) {
 	return resolvedConfig, nil
 }
 
+// BlockedMediaHashPrefix は、blocked_media_patterns でメディアの内容のハッシュを指定する場合の接頭辞です。
+const BlockedMediaHashPrefix = "sha256:"
+
+// minBlockedHashLength は、ハッシュの先頭一致で指定する場合に必要な最小の桁数です。
+// 短すぎる指定で無関係なファイルをブロックしないようにします。
+const minBlockedHashLength = 8
+
+// validateBlockedMediaPatterns は、blocked_media_patterns の各パターンが有効なハッシュまたは正規表現かを検証します。
+func validateBlockedMediaPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if hash, ok := strings.CutPrefix(pattern, BlockedMediaHashPrefix); ok {
+			if len(hash) < minBlockedHashLength || len(hash) > 64 {
+				return fmt.Errorf("ハッシュ %q は%d〜64桁で指定してください", pattern, minBlockedHashLength)
+			}
+			if strings.Trim(strings.ToLower(hash), "0123456789abcdef") != "" {
+				return fmt.Errorf("ハッシュ %q は16進数で指定してください", pattern)
+			}
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("URLの正規表現 %q を解析できません: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateAuthSettings は、認証設定に方式ごとの必須項目が指定されているかを検証します（nil は認証なし）。
+func validateAuthSettings(auth *AuthSettings) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case AuthTypeForm:
+		if auth.LoginURL == "" || len(auth.Fields) == 0 {
+			return fmt.Errorf("type が %q の場合は login_url と fields を指定してください", AuthTypeForm)
+		}
+	case AuthTypeCookie:
+		if len(auth.Cookies) == 0 {
+			return fmt.Errorf("type が %q の場合は cookies を指定してください", AuthTypeCookie)
+		}
+	default:
+		return fmt.Errorf("type %q は不明です（%q または %q を指定してください）", auth.Type, AuthTypeForm, AuthTypeCookie)
+	}
+	return nil
+}
+
+// validateGenericSettings は、汎用アダプタの設定に必須のセレクタがあり、正規表現が正しいかを検証します。
+// セレクタの構文はアダプタの準備（Prepare）で検証します。
+func validateGenericSettings(settings *GenericSettings) error {
+	if settings == nil || settings.ThreadLinkSelector == "" {
+		return fmt.Errorf("site_adapter が %q の場合は generic_settings の thread_link_selector を指定してください", SiteAdapterGeneric)
+	}
+	if settings.ThreadIDPattern != "" {
+		re, err := regexp.Compile(settings.ThreadIDPattern)
+		if err != nil {
+			return fmt.Errorf("thread_id_pattern の正規表現が不正です: %w", err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("thread_id_pattern にはスレッドIDを囲むグループ（例: res/(\\d+)）を含めてください")
+		}
+	}
+	return nil
+}
+
+// validateAdapterPlugins は、外部プログラムのサイトアダプタの定義を検証します。
+func validateAdapterPlugins(plugins map[string]AdapterPlugin) error {
+	for name, plugin := range plugins {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("アダプタ名が空です")
+		}
+		if plugin.Command == "" {
+			return fmt.Errorf("%s: command を指定してください", name)
+		}
+		if plugin.TimeoutSec < 0 {
+			return fmt.Errorf("%s: timeout_sec には0以上の値を指定してください", name)
+		}
+		for _, capability := range plugin.Capabilities {
+			switch capability {
+			case PluginCapabilityJSONAPI, PluginCapabilityThumbnails, PluginCapabilityDeletionMarkers, PluginCapabilityResNumbers:
+			default:
+				return fmt.Errorf("%s: capabilities に不明な機能 '%s' が指定されています", name, capability)
+			}
+		}
+	}
+	return nil
+}
+
+// validateSharingSettings は、共有モードの設定を検証します（nil は無効）。
+// 意図せず誰でも閲覧できる状態にならないよう、users がない場合は allow_anonymous の明示を求めます。
+func validateSharingSettings(sharing *SharingSettings) error {
+	if sharing == nil {
+		return nil
+	}
+	if sharing.ListenAddr == "" {
+		return errors.New("listen_addr を指定してください")
+	}
+	if len(sharing.Users) == 0 && !sharing.AllowAnonymous {
+		return errors.New("users を指定するか、認証なしで公開する場合は allow_anonymous を true にしてください")
+	}
+	for user := range sharing.Users {
+		if user == "" || strings.Contains(user, ":") {
+			return fmt.Errorf("ユーザー名 %q は使用できません（空文字と : は使用できません）", user)
+		}
+	}
+	if (sharing.TLSCertFile == "") != (sharing.TLSKeyFile == "") {
+		return errors.New("tls_cert_file と tls_key_file は両方指定してください")
+	}
+	return nil
+}
+
+// validateArchiveHookSettings は、アーカイブの依頼を受け付けるエンドポイントの設定を検証します（nil は無効）。
+// 誰でもアーカイブを依頼できる状態にならないよう、トークンを必須とします。
+func validateArchiveHookSettings(hook *ArchiveHookSettings) error {
+	if hook == nil {
+		return nil
+	}
+	if hook.ListenAddr == "" {
+		return errors.New("listen_addr を指定してください")
+	}
+	if strings.TrimSpace(hook.Token) == "" {
+		return errors.New("token を指定してください")
+	}
+	return nil
+}
+
+// validateTimezone は、タイムゾーン名（IANA名）を読み込めるかを検証します（空は未設定）。
+func validateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("タイムゾーン %q を読み込めません: %w", name, err)
+	}
+	return nil
+}
+
 // applyPatch は、patchの非nilフィールドをtargetに上書きします。
 func applyPatch(target *Task, patch *taskPatch) {
 	target.UseTemplate = patch.UseTemplate
@@ -213,6 +589,279 @@ func applyPatch(target *Task, patch *taskPatch) {
 	if patch.FutabaCatalogSettings != nil {
 		target.FutabaCatalogSettings = patch.FutabaCatalogSettings
 	}
+	if patch.FutabaSettings != nil {
+		target.FutabaSettings = patch.FutabaSettings
+	}
+	if patch.FutabaCompatibleSettings != nil {
+		target.FutabaCompatibleSettings = patch.FutabaCompatibleSettings
+	}
+	if patch.FutabaBoardQuirks != nil {
+		target.FutabaBoardQuirks = patch.FutabaBoardQuirks
+	}
+	if patch.FivechSettings != nil {
+		target.FivechSettings = patch.FivechSettings
+	}
+	if patch.VichanSettings != nil {
+		target.VichanSettings = patch.VichanSettings
+	}
+	if patch.GenericSettings != nil {
+		target.GenericSettings = patch.GenericSettings
+	}
+	if patch.BooruSettings != nil {
+		target.BooruSettings = patch.BooruSettings
+	}
+	if patch.PluginSettings != nil {
+		target.PluginSettings = patch.PluginSettings
+	}
+	if patch.DownloadThumbnails != nil {
+		target.DownloadThumbnails = patch.DownloadThumbnails
+	}
+	if patch.ThumbnailsOnly != nil {
+		target.ThumbnailsOnly = *patch.ThumbnailsOnly
+	}
+	if patch.AnimatedThumbnailMode != nil {
+		target.AnimatedThumbnailMode = *patch.AnimatedThumbnailMode
+	}
+	if patch.RetryPolicies != nil {
+		target.RetryPolicies = *patch.RetryPolicies
+	}
+	if patch.NamingConflictPolicy != nil {
+		target.NamingConflictPolicy = *patch.NamingConflictPolicy
+	}
+	if patch.MaxTitleLength != nil {
+		target.MaxTitleLength = *patch.MaxTitleLength
+	}
+	if patch.TitleFallbackLength != nil {
+		target.TitleFallbackLength = *patch.TitleFallbackLength
+	}
+	if patch.MinSuccessRatio != nil {
+		target.MinSuccessRatio = *patch.MinSuccessRatio
+	}
+	if patch.MaxConcurrentThreads != nil {
+		target.MaxConcurrentThreads = *patch.MaxConcurrentThreads
+	}
+	if patch.MaxConcurrentFilesPerThread != nil {
+		target.MaxConcurrentFilesPerThread = *patch.MaxConcurrentFilesPerThread
+	}
+	if patch.Group != nil {
+		target.Group = *patch.Group
+	}
+	if patch.MediaExtensions != nil {
+		target.MediaExtensions = *patch.MediaExtensions
+	}
+	if patch.PaginationMode != nil {
+		target.PaginationMode = *patch.PaginationMode
+	}
+	if patch.PostsPerPage != nil {
+		target.PostsPerPage = *patch.PostsPerPage
+	}
+	if patch.BlockedMediaPatterns != nil {
+		target.BlockedMediaPatterns = *patch.BlockedMediaPatterns
+	}
+	if patch.StripAds != nil {
+		target.StripAds = patch.StripAds
+	}
+	if patch.KeepRawHTML != nil {
+		target.KeepRawHTML = *patch.KeepRawHTML
+	}
+	if patch.LogFilePath != nil {
+		target.LogFilePath = *patch.LogFilePath
+	}
+	if patch.ArchiveHeader != nil {
+		target.ArchiveHeader = *patch.ArchiveHeader
+	}
+	if patch.Auth != nil {
+		target.Auth = patch.Auth
+	}
+	if patch.BoardTimezone != nil {
+		target.BoardTimezone = *patch.BoardTimezone
+	}
+	if patch.Timezone != nil {
+		target.Timezone = *patch.Timezone
+	}
+	if patch.RollupPages != nil {
+		target.RollupPages = *patch.RollupPages
+	}
+	if patch.Tags != nil {
+		target.Tags = *patch.Tags
+	}
+	if patch.RenderStrategies != nil {
+		target.RenderStrategies = *patch.RenderStrategies
+	}
+	if patch.AdaptivePolling != nil {
+		target.AdaptivePolling = *patch.AdaptivePolling
+	}
+	if patch.PollMinIntervalMillis != nil {
+		target.PollMinIntervalMillis = *patch.PollMinIntervalMillis
+	}
+	if patch.PollMaxIntervalMillis != nil {
+		target.PollMaxIntervalMillis = *patch.PollMaxIntervalMillis
+	}
+	if patch.WatchJitterPercent != nil {
+		target.WatchJitterPercent = *patch.WatchJitterPercent
+	}
+	if patch.MaintenanceBackoffMillis != nil {
+		target.MaintenanceBackoffMillis = *patch.MaintenanceBackoffMillis
+	}
+	if patch.BoardMeta != nil {
+		target.BoardMeta = patch.BoardMeta
+	}
+	if patch.ResumeFlushEvery != nil {
+		target.ResumeFlushEvery = *patch.ResumeFlushEvery
+	}
+	if patch.ResumeFlushIntervalMillis != nil {
+		target.ResumeFlushIntervalMillis = *patch.ResumeFlushIntervalMillis
+	}
+	if patch.FsyncPolicy != nil {
+		target.FsyncPolicy = *patch.FsyncPolicy
+	}
+	if patch.Encryption != nil {
+		target.Encryption = *patch.Encryption
+	}
+	if patch.EncryptionKeySecret != nil {
+		target.EncryptionKeySecret = *patch.EncryptionKeySecret
+	}
+}
+
+// validateEncryption は、タスクの encryption と encryption_key_secret を検証します。
+// 鍵の値はシークレットストアの設定後に取得するため、ここではシークレットの名前のみを確認します。
+func validateEncryption(task Task) error {
+	switch task.Encryption {
+	case "":
+		if task.EncryptionKeySecret != "" {
+			return fmt.Errorf("encryption_key_secret を使用するには encryption に %q を指定してください", EncryptionAESGCM)
+		}
+		return nil
+	case EncryptionAESGCM:
+	default:
+		return fmt.Errorf("encryption の値 %q には対応していません（%q を指定してください）", task.Encryption, EncryptionAESGCM)
+	}
+	if task.EncryptionKeySecret == "" {
+		return fmt.Errorf("encryption_key_secret に鍵を保存したシークレットの名前を指定してください")
+	}
+	if len(task.EncryptionKeySecret) > 255 {
+		return fmt.Errorf("encryption_key_secret の名前が長すぎます（255バイトまで）")
+	}
+	return nil
+}
+
+// fallbackSourcePlaceholderPattern は、fallback_sources のテンプレートの置き換え対象（{filename} など）です。
+var fallbackSourcePlaceholderPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// FallbackSourcePlaceholders は、fallback_sources のテンプレートで使用できる置き換え対象です。
+var FallbackSourcePlaceholders = []string{"host", "server", "board", "thread_id", "dir", "filename"}
+
+// validateFallbackSources は、fallback_sources の各テンプレートが http(s) のURLで、{filename} を含み、不明な置き換え対象がないことを確認します。
+func validateFallbackSources(sources []string) error {
+	for _, source := range sources {
+		for _, m := range fallbackSourcePlaceholderPattern.FindAllStringSubmatch(source, -1) {
+			if !slices.Contains(FallbackSourcePlaceholders, m[1]) {
+				return fmt.Errorf("%q の {%s} は不明です（%s を使用できます）", source, m[1], "{"+strings.Join(FallbackSourcePlaceholders, "}・{")+"}")
+			}
+		}
+		if !strings.Contains(source, "{filename}") {
+			return fmt.Errorf("%q に {filename} がありません", source)
+		}
+		u, err := url.Parse(fallbackSourcePlaceholderPattern.ReplaceAllString(source, "x"))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%q は http:// または https:// で始まるURLではありません", source)
+		}
+	}
+	return nil
+}
+
+// validateBoardMetaSettings は、board_meta のページが http(s) のURLで、保存し直すまでの日数が0以上であることを確認します。
+func validateBoardMetaSettings(s *BoardMetaSettings) error {
+	for _, page := range s.Pages {
+		u, err := url.Parse(page)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%q は http:// または https:// で始まるURLではありません", page)
+		}
+	}
+	if s.RefreshDays < 0 {
+		return fmt.Errorf("refresh_days には0以上の値を指定してください（指定値: %d）", s.RefreshDays)
+	}
+	return nil
+}
+
+// BoardKey は、板のURLから adapter_settings の board_quirks のキー（ホスト名と、前後の "/" を除いたパス。例: "may.2chan.net/b"）を返します。
+func BoardKey(boardURL string) string {
+	u, err := url.Parse(boardURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname()) + "/" + strings.Trim(u.Path, "/")
+}
+
+// thumbExtensionPattern は、futaba_board_quirks の thumb_extension に使用できる文字列です（先頭の "." は省略可）。
+var thumbExtensionPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// validateFutabaBoardQuirks は、futaba_board_quirks のパスがサイト内の絶対パス、拡張子が英数字、ホストがホスト名であることを確認します。
+func validateFutabaBoardQuirks(q *FutabaBoardQuirks) error {
+	for _, field := range []struct{ name, value string }{
+		{"src_path", q.SrcPath},
+		{"thumb_path", q.ThumbPath},
+	} {
+		if field.value == "" {
+			continue
+		}
+		rel := strings.Trim(field.value, "/")
+		if !strings.HasPrefix(field.value, "/") || (rel != "" && !futabaCompatiblePathPattern.MatchString(rel)) || slices.Contains(strings.Split(rel, "/"), "..") {
+			return fmt.Errorf("%s の値 %q はサイト内の絶対パス（例: /b/src）として使用できません", field.name, field.value)
+		}
+	}
+	if q.ThumbExtension != "" && !thumbExtensionPattern.MatchString(strings.TrimPrefix(q.ThumbExtension, ".")) {
+		return fmt.Errorf("thumb_extension の値 %q は拡張子として使用できません", q.ThumbExtension)
+	}
+	if q.CGIHost != "" && (strings.ContainsAny(q.CGIHost, "/:?# ") || strings.TrimSpace(q.CGIHost) == "") {
+		return fmt.Errorf("cgi_host の値 %q はホスト名として使用できません（スキームやパスは含めません）", q.CGIHost)
+	}
+	return nil
+}
+
+// futabaCompatiblePathPattern は、futaba_compatible_settings のスクリプト名・ディレクトリ名に使用できる文字列です（"/" 区切りで複数階層も可）。
+var futabaCompatiblePathPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+(?:/[A-Za-z0-9._~-]+)*$`)
+
+// validateFutabaCompatibleSettings は、futaba_compatible_settings のスクリプト名・ディレクトリ名が板のURLからの相対パスとして使用できることを確認します。
+func validateFutabaCompatibleSettings(s *FutabaCompatibleSettings) error {
+	for _, field := range []struct{ name, value string }{
+		{"cgi_name", s.CGIName},
+		{"src_dir", s.SrcDir},
+		{"thumb_dir", s.ThumbDir},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if !futabaCompatiblePathPattern.MatchString(field.value) || slices.Contains(strings.Split(field.value, "/"), "..") {
+			return fmt.Errorf("%s の値 %q は板のURLからの相対パスとして使用できません", field.name, field.value)
+		}
+	}
+	if s.SrcDir != "" && s.SrcDir == s.ThumbDir {
+		return fmt.Errorf("src_dir と thumb_dir に同じディレクトリ %q は指定できません", s.SrcDir)
+	}
+	if s.CookieName != "" && s.CookieName != "-" && strings.ContainsAny(s.CookieName, " \t;=,\"") {
+		return fmt.Errorf("cookie_name の値 %q は Cookie の名前として使用できません", s.CookieName)
+	}
+	return nil
+}
+
+// validateBooruSettings は、booru アダプタの設定にAPIの種類があり、ページの指定が範囲内かを検証します。
+func validateBooruSettings(s *BooruSettings) error {
+	if s == nil {
+		return fmt.Errorf("site_adapter が %q の場合は booru_settings の api と tags を指定してください", SiteAdapterBooru)
+	}
+	switch s.API {
+	case BooruAPIDanbooru, BooruAPIGelbooru:
+	default:
+		return fmt.Errorf("api の値 %q は不明です（%q, %q のいずれかを指定してください）", s.API, BooruAPIDanbooru, BooruAPIGelbooru)
+	}
+	if s.Limit < 0 || s.Limit > MaxBooruPageLimit {
+		return fmt.Errorf("limit には0から%dまでの値を指定してください（指定値: %d）", MaxBooruPageLimit, s.Limit)
+	}
+	if s.MaxPages < 0 {
+		return fmt.Errorf("max_pages には0以上の値を指定してください（指定値: %d）", s.MaxPages)
+	}
+	return nil
 }
 
 // computeLineAndColumn は、バイトオフセットから行番号と列番号（1始まり）を計算します。
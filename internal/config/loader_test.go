@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -72,3 +74,612 @@ func TestConfigLoadingWithFutabaSettings(t *testing.T) {
 		t.Errorf("タスク3: TitleLengthが期待値と異なります。期待値: 30, 実際値: %d", task3.FutabaCatalogSettings.TitleLength)
 	}
 }
+
+func TestParseAndResolve_ConcurrencySettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		taskJSON    string
+		wantThreads int
+		wantFiles   int
+	}{
+		{name: "未設定", taskJSON: `{"task_name": "a"}`, wantThreads: 0, wantFiles: 0},
+		{name: "旧設定はスレッド並列数に引き継ぐ", taskJSON: `{"task_name": "a", "max_concurrent_downloads": 3}`, wantThreads: 3, wantFiles: 0},
+		{name: "新設定を優先", taskJSON: `{"task_name": "a", "max_concurrent_downloads": 3, "max_concurrent_threads": 5, "max_concurrent_files_per_thread": 2}`, wantThreads: 5, wantFiles: 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [` + tt.taskJSON + `]}`)
+			cfg, err := ParseAndResolve(data)
+			if err != nil {
+				t.Fatalf("ParseAndResolve() error = %v", err)
+			}
+			task := cfg.Tasks[0]
+			if task.MaxConcurrentThreads != tt.wantThreads {
+				t.Errorf("MaxConcurrentThreads = %d, want %d", task.MaxConcurrentThreads, tt.wantThreads)
+			}
+			if task.MaxConcurrentFilesPerThread != tt.wantFiles {
+				t.Errorf("MaxConcurrentFilesPerThread = %d, want %d", task.MaxConcurrentFilesPerThread, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_MediaExtensions(t *testing.T) {
+	t.Parallel()
+
+	settings := `"adapter_settings": {"futaba": {"media_extensions": ["jpg", "flac"]}}`
+	tests := []struct {
+		name     string
+		taskJSON string
+		want     []string
+	}{
+		{name: "アダプタの既定値を使用", taskJSON: `{"task_name": "a", "site_adapter": "futaba"}`, want: []string{"jpg", "flac"}},
+		{name: "タスクの設定を優先", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "media_extensions": ["pdf"]}`, want: []string{"pdf"}},
+		{name: "設定のないアダプタ", taskJSON: `{"task_name": "a", "site_adapter": "other"}`, want: nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", ` + settings + `, "tasks": [` + tt.taskJSON + `]}`)
+			cfg, err := ParseAndResolve(data)
+			if err != nil {
+				t.Fatalf("ParseAndResolve() error = %v", err)
+			}
+			if got := cfg.Tasks[0].MediaExtensions; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MediaExtensions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_BlockedMediaPatterns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		global  string
+		task    string
+		wantErr bool
+	}{
+		{name: "ハッシュと正規表現", global: `["sha256:0123abcd"]`, task: `["/ad/.*\\.gif$"]`},
+		{name: "短すぎるハッシュ", global: `["sha256:abc"]`, task: `[]`, wantErr: true},
+		{name: "16進数でないハッシュ", global: `[]`, task: `["sha256:zzzzzzzz"]`, wantErr: true},
+		{name: "不正な正規表現", global: `["("]`, task: `[]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "blocked_media_patterns": ` + tt.global +
+				`, "tasks": [{"task_name": "a", "blocked_media_patterns": ` + tt.task + `}]}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			task := cfg.Tasks[0]
+			if !reflect.DeepEqual(task.GlobalBlockedMediaPatterns, cfg.BlockedMediaPatterns) || len(task.BlockedMediaPatterns) != 1 {
+				t.Errorf("Global = %v, Task = %v", task.GlobalBlockedMediaPatterns, task.BlockedMediaPatterns)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_Auth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		auth    string
+		wantErr bool
+	}{
+		{name: "フォーム", auth: `{"type": "form", "login_url": "https://example.com/login", "fields": {"pass": "${secret:board}"}}`},
+		{name: "Cookie", auth: `{"type": "cookie", "cookies": {"session": "${secret:session}"}}`},
+		{name: "フォームのURLなし", auth: `{"type": "form", "fields": {"pass": "x"}}`, wantErr: true},
+		{name: "Cookieの指定なし", auth: `{"type": "cookie"}`, wantErr: true},
+		{name: "不明な方式", auth: `{"type": "basic"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "auth": ` + tt.auth + `}]}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Tasks[0].Auth == nil {
+				t.Error("Auth が設定されていません")
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_Timezone(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		global   string
+		taskJSON string
+		want     string
+		wantErr  bool
+	}{
+		{name: "未設定", global: `""`, taskJSON: `{"task_name": "a"}`, want: ""},
+		{name: "設定ファイル直下の値を継承", global: `"UTC"`, taskJSON: `{"task_name": "a"}`, want: "UTC"},
+		{name: "タスクの設定を優先", global: `"UTC"`, taskJSON: `{"task_name": "a", "timezone": "Asia/Tokyo"}`, want: "Asia/Tokyo"},
+		{name: "不明なタイムゾーン（直下）", global: `"Mars/Olympus"`, taskJSON: `{"task_name": "a"}`, wantErr: true},
+		{name: "不明なタイムゾーン（タスク）", global: `""`, taskJSON: `{"task_name": "a", "timezone": "Mars/Olympus"}`, wantErr: true},
+		{name: "不明な板のタイムゾーン", global: `""`, taskJSON: `{"task_name": "a", "board_timezone": "Mars/Olympus"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "timezone": ` + tt.global + `, "tasks": [` + tt.taskJSON + `]}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Tasks[0].Timezone != tt.want {
+				t.Errorf("Timezone = %q, want %q", cfg.Tasks[0].Timezone, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_RollupPages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		rollup  string
+		wantErr bool
+	}{
+		{name: "週と月", rollup: `["weekly", "monthly"]`},
+		{name: "未設定", rollup: `[]`},
+		{name: "不明な期間", rollup: `["daily"]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "rollup_pages": ` + tt.rollup + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_RenderStrategies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		strategies string
+		wantErr    bool
+	}{
+		{name: "JSONと完全版", strategies: `["json", "full"]`},
+		{name: "すべて", strategies: `["latest", "full", "json", "single_file"]`},
+		{name: "未設定", strategies: `[]`},
+		{name: "不明な形式", strategies: `["pdf"]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "render_strategies": ` + tt.strategies + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_AdaptivePolling(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		polling string
+		wantErr bool
+	}{
+		{name: "下限と上限", polling: `"adaptive_polling": true, "poll_min_interval_ms": 60000, "poll_max_interval_ms": 3600000`},
+		{name: "既定値", polling: `"adaptive_polling": true`},
+		{name: "上限が下限より小さい", polling: `"poll_min_interval_ms": 60000, "poll_max_interval_ms": 1000`, wantErr: true},
+		{name: "負の値", polling: `"poll_min_interval_ms": -1`, wantErr: true},
+		{name: "ジッター", polling: `"watch_jitter_percent": 20`},
+		{name: "ジッターが大きすぎる", polling: `"watch_jitter_percent": 80`, wantErr: true},
+		{name: "レジュームの反映間隔", polling: `"resume_flush_every": 50, "resume_flush_interval_ms": 10000, "fsync_policy": "resume"`},
+		{name: "反映件数が負", polling: `"resume_flush_every": -1`, wantErr: true},
+		{name: "不明なfsyncポリシー", polling: `"fsync_policy": "always"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", ` + tt.polling + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_Encryption(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		encryption string
+		wantErr    bool
+	}{
+		{name: "暗号化", encryption: `"encryption": "aes-gcm", "encryption_key_secret": "archive-key"`},
+		{name: "暗号化の鍵なし", encryption: `"encryption": "aes-gcm"`, wantErr: true},
+		{name: "未対応の暗号化方式", encryption: `"encryption": "age", "encryption_key_secret": "archive-key"`, wantErr: true},
+		{name: "方式なしの鍵", encryption: `"encryption_key_secret": "archive-key"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", ` + tt.encryption + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_FallbackSources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		sources string
+		wantErr bool
+	}{
+		{name: "テンプレート", sources: `["https://mirror.example.com/{server}/{board}/{thread_id}/{dir}/{filename}"]`},
+		{name: "未指定", sources: `[]`},
+		{name: "ファイル名なし", sources: `["https://mirror.example.com/{thread_id}/"]`, wantErr: true},
+		{name: "不明な置き換え対象", sources: `["https://mirror.example.com/{year}/{filename}"]`, wantErr: true},
+		{name: "URLではない", sources: `["mirror/{filename}"]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "futaba_settings": {"fallback_sources": ` + tt.sources + `}}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_FutabaCompatibleSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings string
+		wantErr  bool
+	}{
+		{name: "pixmicat", settings: `{"cgi_name": "pixmicat.php", "src_dir": "img/src", "thumb_dir": "img/thumb", "cookie_name": "pixmicat_cat"}`},
+		{name: "Cookie なし", settings: `{"cookie_name": "-"}`},
+		{name: "親ディレクトリ", settings: `{"src_dir": "../src"}`, wantErr: true},
+		{name: "クエリを含むスクリプト名", settings: `{"cgi_name": "futaba.php?mode=cat"}`, wantErr: true},
+		{name: "同じディレクトリ", settings: `{"src_dir": "img", "thumb_dir": "img"}`, wantErr: true},
+		{name: "不正な Cookie 名", settings: `{"cookie_name": "a=b"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "site_adapter": "futaba_compatible", "futaba_compatible_settings": ` + tt.settings + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_FutabaBoardQuirks(t *testing.T) {
+	t.Parallel()
+
+	settings := `"adapter_settings": {"futaba": {"board_quirks": {"dec.2chan.net/up": {"src_path": "/img/up/src", "thumb_extension": "png"}}}}`
+	tests := []struct {
+		name     string
+		taskJSON string
+		want     *FutabaBoardQuirks
+		wantErr  bool
+	}{
+		{name: "板に一致する設定", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "target_board_url": "https://DEC.2chan.net/up/"}`, want: &FutabaBoardQuirks{SrcPath: "/img/up/src", ThumbExtension: "png"}},
+		{name: "一致する板なし", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "target_board_url": "https://may.2chan.net/b/"}`},
+		{name: "タスクの設定を優先", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "target_board_url": "https://dec.2chan.net/up/", "futaba_board_quirks": {"no_thumbnails": true}}`, want: &FutabaBoardQuirks{NoThumbnails: true}},
+		{name: "相対パス", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "futaba_board_quirks": {"src_path": "up/src"}}`, wantErr: true},
+		{name: "親ディレクトリ", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "futaba_board_quirks": {"thumb_path": "/up/../thumb"}}`, wantErr: true},
+		{name: "不正な拡張子", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "futaba_board_quirks": {"thumb_extension": "j.pg"}}`, wantErr: true},
+		{name: "URLのホスト", taskJSON: `{"task_name": "a", "site_adapter": "futaba", "futaba_board_quirks": {"cgi_host": "https://cgi.2chan.net"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", ` + settings + `, "tasks": [` + tt.taskJSON + `]}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Tasks[0].FutabaBoardQuirks; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FutabaBoardQuirks = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_BoardMeta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings string
+		wantErr  bool
+	}{
+		{name: "既定値", settings: `{}`},
+		{name: "ページと日数", settings: `{"pages": ["https://example.com/rules.html"], "refresh_days": 30}`},
+		{name: "相対URL", settings: `{"pages": ["guide.htm"]}`, wantErr: true},
+		{name: "負の日数", settings: `{"refresh_days": -1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "site_adapter": "futaba", "board_meta": ` + tt.settings + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_BooruSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings string
+		wantErr  bool
+	}{
+		{name: "danbooru", settings: `, "booru_settings": {"api": "danbooru", "tags": "touhou", "max_pages": 3}`},
+		{name: "gelbooru", settings: `, "booru_settings": {"api": "gelbooru", "tags": "touhou", "limit": 50}`},
+		{name: "設定なし", wantErr: true},
+		{name: "不明なAPI", settings: `, "booru_settings": {"api": "moebooru", "tags": "touhou"}`, wantErr: true},
+		{name: "件数の上限超過", settings: `, "booru_settings": {"api": "danbooru", "tags": "touhou", "limit": 101}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "site_adapter": "booru"` + tt.settings + `}]}`)
+			if _, err := ParseAndResolve(data); (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_Sharing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		sharing string
+		wantErr bool
+	}{
+		{name: "ユーザーあり", sharing: `{"listen_addr": "0.0.0.0:8090", "users": {"family": "${secret:share}"}}`},
+		{name: "認証なしを明示", sharing: `{"listen_addr": ":8090", "allow_anonymous": true}`},
+		{name: "アドレスなし", sharing: `{"users": {"a": "b"}}`, wantErr: true},
+		{name: "認証の指定なし", sharing: `{"listen_addr": ":8090"}`, wantErr: true},
+		{name: "コロンを含むユーザー名", sharing: `{"listen_addr": ":8090", "users": {"a:b": "c"}}`, wantErr: true},
+		{name: "証明書のみ", sharing: `{"listen_addr": ":8090", "allow_anonymous": true, "tls_cert_file": "cert.pem"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "sharing": ` + tt.sharing + `, "tasks": []}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Sharing == nil {
+				t.Error("Sharing が設定されていません")
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_ArchiveHook(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		hook    string
+		wantErr bool
+	}{
+		{name: "トークンあり", hook: `{"listen_addr": "127.0.0.1:8091", "token": "${secret:archive_hook_token}"}`},
+		{name: "アドレスなし", hook: `{"token": "abc"}`, wantErr: true},
+		{name: "トークンなし", hook: `{"listen_addr": "127.0.0.1:8091"}`, wantErr: true},
+		{name: "空白のみのトークン", hook: `{"listen_addr": "127.0.0.1:8091", "token": "  "}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "archive_hook": ` + tt.hook + `, "tasks": []}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.ArchiveHook == nil {
+				t.Error("ArchiveHook が設定されていません")
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_GenericSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings string
+		wantErr  bool
+	}{
+		{name: "リンクのセレクタのみ", settings: `{"thread_link_selector": "a.thread"}`},
+		{name: "スレッドIDの正規表現あり", settings: `{"thread_link_selector": "a", "thread_id_pattern": "res/(\\d+)\\.html"}`},
+		{name: "設定なし", settings: `null`, wantErr: true},
+		{name: "リンクのセレクタなし", settings: `{"post_selector": "div.post"}`, wantErr: true},
+		{name: "不正な正規表現", settings: `{"thread_link_selector": "a", "thread_id_pattern": "(\\d+"}`, wantErr: true},
+		{name: "グループのない正規表現", settings: `{"thread_link_selector": "a", "thread_id_pattern": "\\d+"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "tasks": [{"task_name": "a", "site_adapter": "generic", "generic_settings": ` + tt.settings + `}]}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && cfg.Tasks[0].GenericSettings == nil {
+				t.Error("GenericSettings が設定されていません")
+			}
+		})
+	}
+}
+
+func TestParseAndResolve_AdapterPlugins(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		plugins string
+		wantErr bool
+	}{
+		{name: "コマンドと引数", plugins: `{"myboard": {"command": "python3", "args": ["plugins/myboard.py"], "timeout_sec": 30}}`},
+		{name: "コマンドなし", plugins: `{"myboard": {"args": ["a.py"]}}`, wantErr: true},
+		{name: "負のタイムアウト", plugins: `{"myboard": {"command": "node", "timeout_sec": -1}}`, wantErr: true},
+		{name: "対応している機能", plugins: `{"myboard": {"command": "node", "capabilities": ["json_api", "res_numbers"]}}`},
+		{name: "不明な機能", plugins: `{"myboard": {"command": "node", "capabilities": ["video"]}}`, wantErr: true},
+		{name: "空の名前", plugins: `{"": {"command": "node"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data := []byte(`{"config_version": "1.0", "adapter_plugins": ` + tt.plugins + `, "tasks": [{"task_name": "a", "site_adapter": "myboard", "plugin_settings": {"board": "b"}}]}`)
+			cfg, err := ParseAndResolve(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAndResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (len(cfg.AdapterPlugins) != 1 || cfg.Tasks[0].PluginSettings["board"] != "b") {
+				t.Errorf("AdapterPlugins = %+v, PluginSettings = %+v", cfg.AdapterPlugins, cfg.Tasks[0].PluginSettings)
+			}
+		})
+	}
+}
+
+func TestLoadAndResolve_RelativePaths(t *testing.T) {
+	t.Parallel()
+
+	configDir := t.TempDir()
+	absRoot := filepath.Join(t.TempDir(), "abs")
+	data := `{
+  "config_version": "1.0",
+  "global_save_root_directory": "archives",
+  "log_file_path": "logs/giba.log",
+  "status_file": "state/status.json",
+  "usage_stats_file": "state/usage_stats.json",
+  "secrets_file": "../shared/secrets.json",
+  "task_templates": {"base": {"save_root_directory": "template_archives"}},
+  "adapter_plugins": {
+    "local": {"command": "plugins/myboard"},
+    "path": {"command": "python3", "args": ["plugins/myboard.py"]}
+  },
+  "tasks": [
+    {"task_name": "relative", "save_root_directory": "archives/may", "log_file_path": "logs/may.log"},
+    {"task_name": "absolute", "save_root_directory": ` + strconv.Quote(absRoot) + `},
+    {"task_name": "template", "use_template": "base"},
+    {"task_name": "unset"}
+  ]
+}`
+	path := filepath.Join(configDir, "config.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 起動したディレクトリ（テストのパッケージのディレクトリ）ではなく、設定ファイルのディレクトリを基準にする
+	cfg, err := LoadAndResolve(path)
+	if err != nil {
+		t.Fatalf("LoadAndResolve() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "global_save_root_directory", got: cfg.GlobalSaveRootDirectory, want: filepath.Join(configDir, "archives")},
+		{name: "log_file_path", got: cfg.LogFilePath, want: filepath.Join(configDir, "logs", "giba.log")},
+		{name: "status_file", got: cfg.StatusFile, want: filepath.Join(configDir, "state", "status.json")},
+		{name: "usage_stats_file", got: cfg.UsageStatsFile, want: filepath.Join(configDir, "state", "usage_stats.json")},
+		{name: "secrets_file", got: cfg.SecretsFile, want: filepath.Join(filepath.Dir(configDir), "shared", "secrets.json")},
+		{name: "heartbeat_file（未設定）", got: cfg.HeartbeatFile, want: ""},
+		{name: "タスクの保存先", got: cfg.Tasks[0].SaveRootDirectory, want: filepath.Join(configDir, "archives", "may")},
+		{name: "タスクのログファイル", got: cfg.Tasks[0].LogFilePath, want: filepath.Join(configDir, "logs", "may.log")},
+		{name: "絶対パスの保存先", got: cfg.Tasks[1].SaveRootDirectory, want: absRoot},
+		{name: "テンプレートの保存先", got: cfg.Tasks[2].SaveRootDirectory, want: filepath.Join(configDir, "template_archives")},
+		{name: "テンプレートの定義", got: cfg.TaskTemplates["base"].SaveRootDirectory, want: filepath.Join(configDir, "template_archives")},
+		{name: "未設定の保存先", got: cfg.Tasks[3].SaveRootDirectory, want: ""},
+		{name: "ディレクトリを含むプラグインの command", got: cfg.AdapterPlugins["local"].Command, want: filepath.Join(configDir, "plugins", "myboard")},
+		{name: "PATH から探すプラグインの command", got: cfg.AdapterPlugins["path"].Command, want: "python3"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// 相対パスで指定した設定ファイルも、そのディレクトリを基準にする
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relPath, err := filepath.Rel(cwd, path)
+	if err != nil {
+		t.Skipf("設定ファイルへの相対パスを作成できません: %v", err)
+	}
+	cfg, err = LoadAndResolve(relPath)
+	if err != nil {
+		t.Fatalf("LoadAndResolve(%q) error = %v", relPath, err)
+	}
+	if want := filepath.Join(configDir, "archives", "may"); cfg.Tasks[0].SaveRootDirectory != want {
+		t.Errorf("相対パスで読み込んだタスクの保存先 = %q, want %q", cfg.Tasks[0].SaveRootDirectory, want)
+	}
+}
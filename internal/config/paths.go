@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// AppDirName は、プラットフォームの設定ディレクトリの下に作成する、GIBAのディレクトリの名前です。
+const AppDirName = "GIBA"
+
+// DefaultBaseDir は、設定ファイル・ログ・履歴などを置く既定のディレクトリを返します。
+// Linux などでは $XDG_CONFIG_HOME/GIBA（未設定の場合は ~/.config/GIBA）、Windows では %APPDATA%\GIBA、
+// macOS では ~/Library/Application Support/GIBA です。
+func DefaultBaseDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("設定ディレクトリを特定できません: %w", err)
+	}
+	return filepath.Join(dir, AppDirName), nil
+}
+
+// BaseDirOptions は、ResolveBaseDir で基準のディレクトリを決めるための起動時の指定です。
+type BaseDirOptions struct {
+	WorkDir        string // -workdir の指定
+	Portable       bool   // --portable の指定
+	ExecutablePath string // 実行ファイルのパス（--portable の場合の基準）
+	CurrentDir     string // 起動時の作業ディレクトリ
+	ConfigPath     string // -config の指定（相対パスの場合は CurrentDir を基準に存在を確認する）
+}
+
+// ResolveBaseDir は、相対パス（設定ファイル・ログ・履歴・既定の保存先など）の基準にするディレクトリを、次の順に決めます。
+//  1. -workdir の指定
+//  2. --portable の場合は実行ファイルのディレクトリ
+//  3. 起動時の作業ディレクトリに設定ファイルがある場合はそのディレクトリ（以前のバージョンの配置との互換性のため）
+//  4. DefaultBaseDir
+func ResolveBaseDir(opts BaseDirOptions) (string, error) {
+	switch {
+	case opts.WorkDir != "":
+		return opts.WorkDir, nil
+	case opts.Portable:
+		return filepath.Dir(opts.ExecutablePath), nil
+	}
+	configPath := opts.ConfigPath
+	if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(opts.CurrentDir, configPath)
+	}
+	if _, err := os.Stat(configPath); err == nil {
+		return opts.CurrentDir, nil
+	}
+	return DefaultBaseDir()
+}
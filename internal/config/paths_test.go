@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveBaseDir(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	t.Setenv("HOME", configHome)
+	defaultDir, err := DefaultBaseDir()
+	if err != nil {
+		t.Skipf("このプラットフォームでは設定ディレクトリを特定できません: %v", err)
+	}
+
+	withConfig := t.TempDir()
+	if err := os.WriteFile(filepath.Join(withConfig, "config.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := t.TempDir()
+	exePath := filepath.Join(t.TempDir(), "giba.exe")
+
+	tests := []struct {
+		name string
+		opts BaseDirOptions
+		want string
+	}{
+		{name: "作業ディレクトリの指定を優先", opts: BaseDirOptions{WorkDir: "/srv/giba", Portable: true, CurrentDir: withConfig, ConfigPath: "config.json"}, want: "/srv/giba"},
+		{name: "ポータブル", opts: BaseDirOptions{Portable: true, ExecutablePath: exePath, CurrentDir: withConfig, ConfigPath: "config.json"}, want: filepath.Dir(exePath)},
+		{name: "作業ディレクトリに設定ファイルがある", opts: BaseDirOptions{CurrentDir: withConfig, ConfigPath: "config.json"}, want: withConfig},
+		{name: "設定ファイルがない", opts: BaseDirOptions{CurrentDir: empty, ConfigPath: "config.json"}, want: defaultDir},
+		{name: "絶対パスの設定ファイル", opts: BaseDirOptions{CurrentDir: empty, ConfigPath: filepath.Join(withConfig, "config.json")}, want: empty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveBaseDir(tt.opts)
+			if err != nil {
+				t.Fatalf("ResolveBaseDir() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveBaseDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// PresetVersion は、タスクのプリセットファイルの形式のバージョンです。
+const PresetVersion = "1"
+
+// secretReferencePattern は、設定値全体がシークレットの参照 "${secret:名前}" であるかを判定します。
+var secretReferencePattern = regexp.MustCompile(`^\$\{secret:[^}]+\}$`)
+
+// TaskPreset は、他の利用者と共有するためのタスク定義（giba task export / import）です。
+// テンプレートを展開した単独のタスクで、認証情報と保存先などの環境に依存する値は含みません。
+type TaskPreset struct {
+	PresetVersion string    `json:"preset_version"`
+	ExportedBy    string    `json:"exported_by,omitempty"` // エクスポートしたGIBAのバージョン
+	ExportedAt    time.Time `json:"exported_at"`
+	// Secrets は、インポート後に secrets.json または環境変数で設定が必要なシークレットの名前です。
+	Secrets []string `json:"secrets,omitempty"`
+	Task    Task     `json:"task"`
+}
+
+// NewTaskPreset は、読み込み済みの設定の taskName のタスクからプリセットを作成します。
+// auth の fields と cookies の値は、シークレットの参照でないものも含めて "${secret:名前}" の参照に置き換え、
+// 保存先・ログファイルのパスとグループは取り除きます。
+func NewTaskPreset(cfg *Config, taskName, exportedBy string) (*TaskPreset, error) {
+	for _, task := range cfg.Tasks {
+		if task.TaskName != taskName {
+			continue
+		}
+		preset := &TaskPreset{PresetVersion: PresetVersion, ExportedBy: exportedBy, ExportedAt: time.Now(), Task: task}
+		preset.Task.UseTemplate = "" // テンプレートは展開済み
+		preset.Task.SaveRootDirectory = ""
+		preset.Task.LogFilePath = ""
+		preset.Task.Group = ""
+		preset.Task.MaxConcurrentDownloads = 0 // 読み込み時に max_concurrent_threads へ引き継ぎ済み
+		if task.Auth != nil {
+			auth := *task.Auth
+			auth.Fields = stripSecretValues(auth.Fields, &preset.Secrets)
+			auth.Cookies = stripSecretValues(auth.Cookies, &preset.Secrets)
+			preset.Task.Auth = &auth
+		}
+		sort.Strings(preset.Secrets)
+		return preset, nil
+	}
+	return nil, fmt.Errorf("タスク '%s' は設定ファイルにありません", taskName)
+}
+
+// stripSecretValues は、values の各値をシークレットの参照に置き換えたコピーを返し、参照の名前を secrets に追加します。
+// 既にシークレットの参照である値は、その参照をそのまま残します。
+func stripSecretValues(values map[string]string, secrets *[]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	stripped := make(map[string]string, len(values))
+	for key, value := range values {
+		if secretReferencePattern.MatchString(value) {
+			stripped[key] = value
+			continue
+		}
+		stripped[key] = "${secret:" + key + "}"
+		*secrets = append(*secrets, key)
+	}
+	return stripped
+}
+
+// ParseTaskPreset は、プリセットファイルの内容を解析し、タスクの設定を検証します。
+func ParseTaskPreset(data []byte) (*TaskPreset, error) {
+	var preset TaskPreset
+	if err := json.Unmarshal(data, &preset); err != nil {
+		return nil, fmt.Errorf("プリセットの解析に失敗しました: %w", err)
+	}
+	if preset.PresetVersion != PresetVersion {
+		return nil, fmt.Errorf("プリセットの形式のバージョン %q には対応していません（対応: %q）", preset.PresetVersion, PresetVersion)
+	}
+	if preset.Task.TaskName == "" {
+		return nil, fmt.Errorf("プリセットに task_name がありません")
+	}
+	if err := validateTask(preset.Task); err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
+// validateTask は、task だけを含む設定として読み込み、設定ファイルに追加できるかを検証します。
+func validateTask(task Task) error {
+	taskJSON, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("タスクのシリアライズに失敗しました: %w", err)
+	}
+	if _, err := ParseAndResolve([]byte(`{"config_version": "1.0", "tasks": [` + string(taskJSON) + `]}`)); err != nil {
+		return fmt.Errorf("タスクの設定が不正です: %w", err)
+	}
+	return nil
+}
+
+// AppendTaskToFile は、設定ファイル path の tasks の末尾に task を追加します。
+// 同じ名前のタスクが既にある場合はエラーを返します。設定ファイルのそれ以外の内容はそのまま残します（キーの順序は整列されます）。
+func AppendTaskToFile(path string, task Task) error {
+	if err := validateTask(task); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("設定ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("設定ファイルの解析に失敗しました (path=%s): %w", path, err)
+	}
+	var tasks []json.RawMessage
+	if raw, ok := root["tasks"]; ok {
+		if err := json.Unmarshal(raw, &tasks); err != nil {
+			return fmt.Errorf("設定ファイルの tasks の解析に失敗しました (path=%s): %w", path, err)
+		}
+	}
+	for _, raw := range tasks {
+		var existing struct {
+			TaskName string `json:"task_name"`
+		}
+		if err := json.Unmarshal(raw, &existing); err == nil && existing.TaskName == task.TaskName {
+			return fmt.Errorf("タスク '%s' は既に設定ファイルにあります。別の名前を指定してください", task.TaskName)
+		}
+	}
+
+	taskJSON, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("タスクのシリアライズに失敗しました: %w", err)
+	}
+	tasks = append(tasks, taskJSON)
+	if root["tasks"], err = json.Marshal(tasks); err != nil {
+		return fmt.Errorf("タスクのシリアライズに失敗しました: %w", err)
+	}
+	updated, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return fmt.Errorf("設定ファイルのシリアライズに失敗しました: %w", err)
+	}
+	// 追加後の設定ファイル全体が読み込めることを確認してから置き換える
+	if _, err := ParseAndResolve(updated); err != nil {
+		return fmt.Errorf("タスクを追加した設定ファイルを読み込めません: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("設定ファイルの情報の取得に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(updated, '\n'), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("設定ファイルの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("設定ファイルの更新に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTaskPreset_ExportImport(t *testing.T) {
+	t.Parallel()
+
+	configJSON := `{
+  "config_version": "1.0",
+  "task_templates": {"futaba": {"site_adapter": "futaba", "minimum_media_count": 5, "save_root_directory": "/home/user/archives"}},
+  "tasks": [{
+    "task_name": "会員板",
+    "use_template": "futaba",
+    "target_board_url": "https://example.com/b/",
+    "group": "local",
+    "auth": {"type": "form", "login_url": "https://example.com/login", "fields": {"user": "alice", "pass": "${secret:board-pass}"}}
+  }]
+}`
+	cfg, err := ParseAndResolve([]byte(configJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preset, err := NewTaskPreset(cfg, "会員板", "test")
+	if err != nil {
+		t.Fatalf("NewTaskPreset() error = %v", err)
+	}
+	task := preset.Task
+	if task.SiteAdapter != "futaba" || task.MinimumMediaCount != 5 || task.UseTemplate != "" {
+		t.Errorf("テンプレートが展開されていません: %+v", task)
+	}
+	if task.SaveRootDirectory != "" || task.Group != "" {
+		t.Errorf("環境に依存する値が残っています: save_root_directory=%q, group=%q", task.SaveRootDirectory, task.Group)
+	}
+	if task.Auth.Fields["user"] != "${secret:user}" || task.Auth.Fields["pass"] != "${secret:board-pass}" {
+		t.Errorf("認証情報が取り除かれていません: %v", task.Auth.Fields)
+	}
+	if len(preset.Secrets) != 1 || preset.Secrets[0] != "user" {
+		t.Errorf("Secrets = %v, want [user]", preset.Secrets)
+	}
+	if cfg.Tasks[0].Auth.Fields["user"] != "alice" {
+		t.Error("NewTaskPreset() が元の設定を変更しました")
+	}
+	if _, err := NewTaskPreset(cfg, "存在しない", "test"); err == nil {
+		t.Error("存在しないタスクでエラーになりません")
+	}
+
+	data, err := json.Marshal(preset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "alice") {
+		t.Errorf("プリセットに認証情報が含まれています: %s", data)
+	}
+	imported, err := ParseTaskPreset(data)
+	if err != nil {
+		t.Fatalf("ParseTaskPreset() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"config_version": "1.0", "tasks": [{"task_name": "既存"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendTaskToFile(path, imported.Task); err != nil {
+		t.Fatalf("AppendTaskToFile() error = %v", err)
+	}
+	loaded, err := LoadAndResolve(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Tasks) != 2 || loaded.Tasks[1].TaskName != "会員板" || loaded.Tasks[1].TargetBoardURL != "https://example.com/b/" {
+		t.Errorf("追加後のタスク = %+v", loaded.Tasks)
+	}
+	if err := AppendTaskToFile(path, imported.Task); err == nil {
+		t.Error("同じ名前のタスクを追加できてしまいます")
+	}
+}
+
+func TestParseTaskPreset_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "バージョン違い", data: `{"preset_version": "99", "task": {"task_name": "a"}}`},
+		{name: "タスク名なし", data: `{"preset_version": "1", "task": {}}`},
+		{name: "不正な設定", data: `{"preset_version": "1", "task": {"task_name": "a", "rollup_pages": ["daily"]}}`},
+		{name: "JSONでない", data: `task`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseTaskPreset([]byte(tt.data)); err == nil {
+				t.Error("ParseTaskPreset() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ProfilesDirectory は、プロファイル（--profile）ごとのディレクトリを置くディレクトリの名前です。
+const ProfilesDirectory = "profiles"
+
+// ProfileDir は、baseDir を基準としたプロファイル name のディレクトリのパスを返します。
+// プロファイルのディレクトリは、そのプロファイルの設定ファイル・ログ・検証履歴・アーカイブなどの相対パスの基準になります。
+// name はディレクトリ名としてそのまま使用するため、パスの区切り文字や "." / ".." は使用できません。
+func ProfileDir(baseDir, name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return "", fmt.Errorf("プロファイル名が空です")
+	}
+	if trimmed != name || trimmed == "." || trimmed == ".." || strings.ContainsAny(name, `/\:*?"<>|`) {
+		return "", fmt.Errorf("プロファイル名 %q は使用できません（パスの区切り文字などを含まない名前にしてください）", name)
+	}
+	return filepath.Join(baseDir, ProfilesDirectory, name), nil
+}
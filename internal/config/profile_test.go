@@ -0,0 +1,38 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileDir(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		profile string
+		want    string
+		wantErr bool
+	}{
+		{name: "英数字", profile: "sfw", want: filepath.Join("base", "profiles", "sfw")},
+		{name: "日本語", profile: "二次裏", want: filepath.Join("base", "profiles", "二次裏")},
+		{name: "空", profile: "", wantErr: true},
+		{name: "前後の空白", profile: " sfw", wantErr: true},
+		{name: "親ディレクトリ", profile: "..", wantErr: true},
+		{name: "区切り文字", profile: "a/b", wantErr: true},
+		{name: "Windowsの区切り文字", profile: `a\b`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ProfileDir("base", tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProfileDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ProfileDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/version"
+)
+
+// archiveReadmeFileName は、archive_header が有効な場合にスレッドのディレクトリに保存する説明ファイルの名前です。
+const archiveReadmeFileName = "README.txt"
+
+// archiveTimeLayout は、ヘッダーと README.txt に記載する日時の書式です。
+const archiveTimeLayout = "2006-01-02 15:04:05 -0700"
+
+// bodyOpenTagPattern は、ヘッダーを挿入する位置（<body> の開始タグ）です。
+var bodyOpenTagPattern = regexp.MustCompile(`(?i)<body[^>]*>`)
+
+// archiveInfo は、アーカイブ自体を説明する情報です。再構成したHTMLのヘッダーと README.txt に記載します。
+// 数年後に開いても、どこから・いつ・何で保存したものかが分かるようにします。
+type archiveInfo struct {
+	ThreadID     string
+	Title        string
+	URL          string // 元のスレッドのURL
+	ArchivedAt   time.Time
+	MediaCount   int // 保存対象のメディア数
+	BlockedCount int // blocked_media_patterns により保存しなかったメディア数
+	Version      version.Info
+}
+
+// newArchiveInfo は、スレッドと再構成に使用したメディアから archiveInfo を作成します。
+func newArchiveInfo(task config.Task, thread model.ThreadInfo, mediaFiles []model.MediaInfo, now time.Time) archiveInfo {
+	info := archiveInfo{
+		ThreadID:   thread.ID,
+		Title:      thread.Title,
+		URL:        threadPageURL(task, thread),
+		ArchivedAt: now,
+		Version:    version.Get(),
+	}
+	for _, m := range mediaFiles {
+		if m.Blocked {
+			info.BlockedCount++
+		} else {
+			info.MediaCount++
+		}
+	}
+	return info
+}
+
+// threadPageURL は、スレッドの絶対URLを返します。求められない場合はスレッドの相対URLをそのまま返します。
+func threadPageURL(task config.Task, thread model.ThreadInfo) string {
+	base, err := url.Parse(task.TargetBoardURL)
+	if err != nil {
+		return thread.URL
+	}
+	return joinThreadURL(base, thread.URL).String()
+}
+
+// joinThreadURL は、板のURL base にスレッドの相対URL threadPath を連結します。
+// pixmicat.php?res=123 のようにクエリを含む場合は、パスのみを連結してクエリを引き継ぎます。
+func joinThreadURL(base *url.URL, threadPath string) *url.URL {
+	threadPath, query, hasQuery := strings.Cut(threadPath, "?")
+	u := base.JoinPath(threadPath)
+	if hasQuery {
+		u.RawQuery = query
+	}
+	return u
+}
+
+// mediaSummary は、メディア数を「12 件（除外 1 件）」の形式で返します。
+func (a archiveInfo) mediaSummary() string {
+	if a.BlockedCount > 0 {
+		return fmt.Sprintf("%d 件（除外 %d 件）", a.MediaCount, a.BlockedCount)
+	}
+	return fmt.Sprintf("%d 件", a.MediaCount)
+}
+
+// headerHTML は、再構成したHTMLの先頭に挿入するヘッダーを返します。
+// 削除レスの検出やページ分割がレスと誤認しないよう、レス番号の形式の文字列や table 要素は含めません。
+func (a archiveInfo) headerHTML() string {
+	escapedURL := html.EscapeString(a.URL)
+	return fmt.Sprintf(`<div class="giba-archive-header" style="margin:0 0 8px;padding:4px 8px;border:1px solid #ccc;background:#f6f6f6;color:#333;font-size:small">`+
+		`アーカイブ元: <a href="%s">%s</a> | 最終更新: %s | メディア: %s | %s</div>`,
+		escapedURL, escapedURL, html.EscapeString(a.ArchivedAt.Format(archiveTimeLayout)),
+		html.EscapeString(a.mediaSummary()), html.EscapeString("GIBA "+a.Version.Version))
+}
+
+// injectArchiveHeader は、htmlContent の <body> の直後にヘッダーを挿入します。<body> がない場合は先頭に挿入します。
+func injectArchiveHeader(htmlContent string, info archiveInfo) string {
+	header := info.headerHTML()
+	loc := bodyOpenTagPattern.FindStringIndex(htmlContent)
+	if loc == nil {
+		return header + htmlContent
+	}
+	return htmlContent[:loc[1]] + header + htmlContent[loc[1]:]
+}
+
+// readmeText は、スレッドのディレクトリに保存する README.txt の内容を返します。
+func (a archiveInfo) readmeText(task config.Task) string {
+	var b strings.Builder
+	b.WriteString("GoImageBoardArchiver (GIBA) によるスレッドのアーカイブ\n")
+	b.WriteString("==================================================\n\n")
+	fmt.Fprintf(&b, "タイトル:   %s\n", a.Title)
+	fmt.Fprintf(&b, "スレッドID: %s\n", a.ThreadID)
+	fmt.Fprintf(&b, "元のURL:    %s\n", a.URL)
+	fmt.Fprintf(&b, "最終更新:   %s\n", a.ArchivedAt.Format(archiveTimeLayout))
+	fmt.Fprintf(&b, "メディア:   %s\n", a.mediaSummary())
+	fmt.Fprintf(&b, "保存ツール: %s\n", a.Version.String())
+
+	b.WriteString("\nファイル構成\n")
+	b.WriteString("  index.htm          最新のスレッド（削除されたレスは含みません）\n")
+	b.WriteString("  archive_full.html  削除されたレスも含む完全版\n")
+	if task.PaginationMode == PaginationPages {
+		b.WriteString("  index_pN.htm       レスをページに分割したスレッド\n")
+	}
+	if !task.ThumbnailsOnly {
+		b.WriteString("  img/               フルサイズのメディア\n")
+	}
+	b.WriteString("  thumb/             サムネイル\n")
+	b.WriteString("  css/               スタイルシート\n")
+	b.WriteString("  thread.json        スレッドの情報（URL・タイトルの履歴など）\n")
+	if task.KeepRawHTML {
+		fmt.Fprintf(&b, "  %-18s 取得したままのスレッドHTML（gzip）\n", RawHTMLFileName)
+	}
+	b.WriteString("\nブラウザで index.htm を開くと、インターネットに接続せずに閲覧できます。\n")
+	return b.String()
+}
+
+// saveArchiveReadme は、README.txt をスレッドのディレクトリに保存します。
+func saveArchiveReadme(threadSavePath string, task config.Task, info archiveInfo) error {
+	path := filepath.Join(threadSavePath, archiveReadmeFileName)
+	if err := os.WriteFile(path, []byte(info.readmeText(task)), 0644); err != nil {
+		return fmt.Errorf("%sの保存に失敗しました (path=%s): %w", archiveReadmeFileName, path, err)
+	}
+	return nil
+}
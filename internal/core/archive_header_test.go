@@ -0,0 +1,134 @@
+package core
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestNewArchiveInfo(t *testing.T) {
+	t.Parallel()
+
+	task := config.Task{TargetBoardURL: "https://may.2chan.net/b/"}
+	thread := model.ThreadInfo{ID: "123", Title: "テスト", URL: "res/123.htm"}
+	media := []model.MediaInfo{{URL: "a.jpg"}, {URL: "b.jpg"}, {URL: "ad.gif", Blocked: true}}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	info := newArchiveInfo(task, thread, media, now)
+	if info.URL != "https://may.2chan.net/b/res/123.htm" {
+		t.Errorf("URL = %q", info.URL)
+	}
+	if info.MediaCount != 2 || info.BlockedCount != 1 {
+		t.Errorf("MediaCount = %d, BlockedCount = %d, want 2, 1", info.MediaCount, info.BlockedCount)
+	}
+	if got := info.mediaSummary(); got != "2 件（除外 1 件）" {
+		t.Errorf("mediaSummary() = %q", got)
+	}
+	if !info.ArchivedAt.Equal(now) {
+		t.Errorf("ArchivedAt = %v, want %v", info.ArchivedAt, now)
+	}
+}
+
+func TestInjectArchiveHeader(t *testing.T) {
+	t.Parallel()
+
+	info := archiveInfo{
+		ThreadID:   "123",
+		URL:        "https://example.com/b/res/123.htm?a=1&b=2",
+		ArchivedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		MediaCount: 3,
+	}
+
+	tests := []struct {
+		name       string
+		input      string
+		wantPrefix string
+	}{
+		{"bodyの直後", `<html><head></head><BODY class="x"><p>本文</p></body></html>`, `<html><head></head><BODY class="x"><div class="giba-archive-header"`},
+		{"bodyがない場合は先頭", `<p>本文</p>`, `<div class="giba-archive-header"`},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := injectArchiveHeader(tt.input, info)
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("injectArchiveHeader() = %q, want prefix %q", got, tt.wantPrefix)
+			}
+			for _, want := range []string{"a=1&amp;b=2", "2024-05-01 12:00:00 +0000", "3 件", "<p>本文</p>"} {
+				if !strings.Contains(got, want) {
+					t.Errorf("injectArchiveHeader() に %q が含まれていません: %q", want, got)
+				}
+			}
+		})
+	}
+
+	// ヘッダーがレスとして扱われると削除レスの検出やページ分割が誤動作する
+	if posts := splitThreadPosts(info.headerHTML()).Posts; len(posts) != 0 {
+		t.Errorf("ヘッダーがレスとして分割されました: %q", posts)
+	}
+}
+
+func TestSaveArchiveReadme(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	info := archiveInfo{
+		ThreadID:   "123",
+		Title:      "テストスレ",
+		URL:        "https://example.com/b/res/123.htm",
+		ArchivedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		MediaCount: 4,
+	}
+	task := config.Task{KeepRawHTML: true, ThumbnailsOnly: true}
+	if err := saveArchiveReadme(dir, task, info); err != nil {
+		t.Fatalf("saveArchiveReadme() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, archiveReadmeFileName))
+	if err != nil {
+		t.Fatalf("README.txt を読み込めません: %v", err)
+	}
+	text := string(data)
+	for _, want := range []string{"テストスレ", "123", "https://example.com/b/res/123.htm", "2024-05-01 12:00:00 +0000", "4 件", "GIBA ", RawHTMLFileName} {
+		if !strings.Contains(text, want) {
+			t.Errorf("README.txt に %q が含まれていません:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "img/") {
+		t.Errorf("サムネイルのみの場合は img/ を記載しないはずです:\n%s", text)
+	}
+}
+
+func TestJoinThreadURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		base       string
+		threadPath string
+		want       string
+	}{
+		{name: "パスのみ", base: "https://may.2chan.net/b/", threadPath: "res/123.htm", want: "https://may.2chan.net/b/res/123.htm"},
+		{name: "クエリ付き", base: "https://gita.komica1.org/00b/", threadPath: "pixmicat.php?res=1001", want: "https://gita.komica1.org/00b/pixmicat.php?res=1001"},
+		{name: "スレッドにクエリがなければ板のURLのクエリを維持", base: "https://example.com/b/?lang=ja", threadPath: "res/1.htm", want: "https://example.com/b/res/1.htm?lang=ja"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			base, err := url.Parse(tt.base)
+			if err != nil {
+				t.Fatalf("url.Parse() error = %v", err)
+			}
+			if got := joinThreadURL(base, tt.threadPath).String(); got != tt.want {
+				t.Errorf("joinThreadURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
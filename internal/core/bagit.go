@@ -0,0 +1,233 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/version"
+)
+
+// BagIt（RFC 8493）のタグファイル
+const (
+	bagItDeclarationFile = "bagit.txt"
+	bagInfoFile          = "bag-info.txt"
+	bagManifestFile      = "manifest-sha256.txt"
+	bagTagManifestFile   = "tagmanifest-sha256.txt"
+	bagPayloadDir        = "data"
+)
+
+// BagItSummary は、BagIt 形式でのエクスポートの集計です。
+type BagItSummary struct {
+	Threads int   // 収録したスレッド数
+	Files   int   // 収録したファイル数（ペイロード）
+	Bytes   int64 // 収録したファイルの合計サイズ
+}
+
+// ExportBagIt は、アーカイブ済みのスレッドを BagIt 形式のバッグとして outDir に書き出します。
+// 各スレッドは data/<タスク名>/<保存先からの相対パス>/ にコピーし、manifest-sha256.txt・bag-info.txt・tagmanifest-sha256.txt を作成します。
+// targets にはスレッドID・スレッドURL・タスク名を指定でき、空の場合はすべてのタスクのスレッドを対象とします。
+// 内部の状態（.snapshot.json などの . で始まるファイルとディレクトリ）と書き込み途中のファイル（*.tmp）は収録しません。
+// outDir は存在しないか空のディレクトリである必要があります。
+func ExportBagIt(ctx context.Context, cfg *config.Config, outDir string, targets []string, logger *log.Logger) (BagItSummary, error) {
+	var summary BagItSummary
+
+	jobs, err := bagItTargets(cfg, targets, logger)
+	if err != nil {
+		return summary, err
+	}
+	if len(jobs) == 0 {
+		return summary, fmt.Errorf("エクスポートするスレッドがありません")
+	}
+	if entries, err := os.ReadDir(outDir); err == nil && len(entries) > 0 {
+		return summary, fmt.Errorf("出力先 %s は空のディレクトリではありません", outDir)
+	}
+	if err := os.MkdirAll(filepath.Join(outDir, bagPayloadDir), 0755); err != nil {
+		return summary, fmt.Errorf("出力先の作成に失敗しました (path=%s): %w", outDir, err)
+	}
+
+	var manifest []string
+	seen := make(map[string]bool)
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		if seen[job.threadDir] {
+			continue
+		}
+		seen[job.threadDir] = true
+
+		rel, err := filepath.Rel(job.task.SaveRootDirectory, job.threadDir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			rel = filepath.Base(job.threadDir)
+		}
+		payloadDir := filepath.Join(bagPayloadDir, SanitizeFilename(job.task.TaskName), rel)
+		lines, files, bytes, err := copyBagPayload(job.threadDir, outDir, payloadDir, logger)
+		if err != nil {
+			return summary, fmt.Errorf("スレッド %s の収録に失敗しました (path=%s): %w", job.threadID, job.threadDir, err)
+		}
+		manifest = append(manifest, lines...)
+		summary.Threads++
+		summary.Files += files
+		summary.Bytes += bytes
+	}
+	sort.Strings(manifest)
+
+	if err := writeBagTagFiles(outDir, manifest, summary); err != nil {
+		return summary, err
+	}
+	logger.Printf("INFO: BagIt 形式で %d 件のスレッドを書き出しました (path=%s, ファイル: %d, %d バイト)", summary.Threads, outDir, summary.Files, summary.Bytes)
+	return summary, nil
+}
+
+// bagItTargets は、エクスポートの対象のスレッドの保存先を返します。
+// タスク名に一致する引数はそのタスクのすべてのスレッド、それ以外はスレッドIDまたはスレッドURLとして扱います。
+func bagItTargets(cfg *config.Config, targets []string, logger *log.Logger) ([]reprocessTarget, error) {
+	var jobs []reprocessTarget
+	if len(targets) == 0 {
+		for _, task := range cfg.Tasks {
+			jobs = append(jobs, taskThreadTargets(task, logger)...)
+		}
+		return jobs, nil
+	}
+	for _, target := range targets {
+		if task, ok := findTask(cfg, target); ok {
+			jobs = append(jobs, taskThreadTargets(task, logger)...)
+			continue
+		}
+		resolved, err := ResolveRearchiveTarget(cfg, target)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.ThreadDir == "" {
+			return nil, fmt.Errorf("スレッド %s はまだアーカイブされていません", resolved.Thread.ID)
+		}
+		jobs = append(jobs, reprocessTarget{task: resolved.Task, threadID: resolved.Thread.ID, threadDir: resolved.ThreadDir})
+	}
+	return jobs, nil
+}
+
+// findTask は、名前が name のタスクを返します。
+func findTask(cfg *config.Config, name string) (config.Task, bool) {
+	for _, task := range cfg.Tasks {
+		if task.TaskName == name {
+			return task, true
+		}
+	}
+	return config.Task{}, false
+}
+
+// copyBagPayload は、threadDir のファイルをバッグの payloadDir（outDir からの相対パス）にコピーし、
+// manifest-sha256.txt の行・ファイル数・合計サイズを返します。ハッシュはコピーと同時に計算します。
+func copyBagPayload(threadDir, outDir, payloadDir string, logger *log.Logger) ([]string, int, int64, error) {
+	var lines []string
+	files := 0
+	var total int64
+	err := filepath.WalkDir(threadDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if path != threadDir && strings.HasPrefix(name, ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || strings.HasSuffix(name, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(threadDir, path)
+		if err != nil {
+			return err
+		}
+		bagPath := filepath.Join(payloadDir, rel)
+		sum, size, err := copyFileSHA256(path, filepath.Join(outDir, bagPath), logger)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, sum+"  "+encodeBagPath(bagPath))
+		files++
+		total += size
+		return nil
+	})
+	return lines, files, total, err
+}
+
+// copyFileSHA256 は、src を dst にコピーし、内容の SHA-256（16進数）とサイズを返します。
+func copyFileSHA256(src, dst string, logger *log.Logger) (string, int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", 0, err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", 0, err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", 0, err
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), in)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := out.Sync(); err != nil {
+		return "", 0, err
+	}
+	// 収録したファイルの更新日時は元のファイルに合わせる（揃えられなくても内容は収録済みのため、失敗として扱わない）
+	info, err := in.Stat()
+	if err != nil {
+		logger.Printf("WARNING: 収録元のファイル情報の取得に失敗したため、更新日時を設定しません (path=%s): %v", src, err)
+	} else if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		logger.Printf("WARNING: 収録したファイルの更新日時の設定に失敗しました (path=%s): %v", dst, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// encodeBagPath は、マニフェストに記載するパスを、区切りを / にして改行と % をエンコードした形式にします（RFC 8493 2.1.3）。
+func encodeBagPath(path string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(filepath.ToSlash(path))
+}
+
+// writeBagTagFiles は、bagit.txt・manifest-sha256.txt・bag-info.txt と、それらのハッシュを記載した tagmanifest-sha256.txt を書き出します。
+func writeBagTagFiles(outDir string, manifest []string, summary BagItSummary) error {
+	tagFiles := []struct {
+		name    string
+		content string
+	}{
+		{name: bagItDeclarationFile, content: "BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n"},
+		{name: bagManifestFile, content: strings.Join(manifest, "\n") + "\n"},
+		{name: bagInfoFile, content: fmt.Sprintf("Bagging-Date: %s\nBag-Software-Agent: %s\nPayload-Oxum: %d.%d\nExternal-Description: GoImageBoardArchiver archive of %d threads\n",
+			time.Now().Format("2006-01-02"), version.Get().String(), summary.Bytes, summary.Files, summary.Threads)},
+	}
+
+	var tagManifest []string
+	for _, tag := range tagFiles {
+		path := filepath.Join(outDir, tag.name)
+		if err := os.WriteFile(path, []byte(tag.content), 0644); err != nil {
+			return fmt.Errorf("%s の書き込みに失敗しました (path=%s): %w", tag.name, path, err)
+		}
+		sum := sha256.Sum256([]byte(tag.content))
+		tagManifest = append(tagManifest, hex.EncodeToString(sum[:])+"  "+tag.name)
+	}
+	path := filepath.Join(outDir, bagTagManifestFile)
+	if err := os.WriteFile(path, []byte(strings.Join(tagManifest, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("%s の書き込みに失敗しました (path=%s): %w", bagTagManifestFile, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestExportBagIt(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeTestThreadDir(t, filepath.Join(root, "123_a"), "123", 1, "1700000000001.jpg")
+	if err := os.WriteFile(filepath.Join(root, "123_a", "index.htm"), []byte("<html></html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTestThreadDir(t, filepath.Join(root, "456_b"), "456", 1, "1700000000002.jpg")
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "A", SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: root},
+	}}
+	logger := log.New(io.Discard, "", 0)
+
+	outDir := filepath.Join(t.TempDir(), "bag")
+	summary, err := ExportBagIt(context.Background(), cfg, outDir, []string{"123"}, logger)
+	if err != nil {
+		t.Fatalf("ExportBagIt() error = %v", err)
+	}
+	if summary.Threads != 1 || summary.Files != 2 || summary.Bytes != int64(len("x")+len("<html></html>")) {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	// マニフェストのハッシュが収録したファイルと一致し、内部の状態（.snapshot.json）は収録しない
+	manifest, err := os.ReadFile(filepath.Join(outDir, bagManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(manifest)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("manifest-sha256.txt = %q", manifest)
+	}
+	for _, line := range lines {
+		sum, path, ok := strings.Cut(line, "  ")
+		if !ok || !strings.HasPrefix(path, "data/A/123_a/") {
+			t.Errorf("不正なマニフェストの行: %q", line)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(path)))
+		if err != nil {
+			t.Errorf("収録したファイルがありません: %v", err)
+			continue
+		}
+		if got := sha256.Sum256(data); hex.EncodeToString(got[:]) != sum {
+			t.Errorf("%s のハッシュが一致しません", path)
+		}
+	}
+	if strings.Contains(string(manifest), ".snapshot.json") {
+		t.Errorf("内部の状態が収録されています: %s", manifest)
+	}
+
+	info, err := os.ReadFile(filepath.Join(outDir, bagInfoFile))
+	if err != nil || !strings.Contains(string(info), "Payload-Oxum: 14.2\n") {
+		t.Errorf("bag-info.txt = %q, %v", info, err)
+	}
+	for _, name := range []string{bagItDeclarationFile, bagTagManifestFile} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("%s がありません: %v", name, err)
+		}
+	}
+
+	// 空でない出力先には書き出さない
+	if _, err := ExportBagIt(context.Background(), cfg, outDir, nil, logger); err == nil {
+		t.Error("空でない出力先でエラーになりません")
+	}
+
+	// タスク名を指定するとそのタスクのすべてのスレッドを収録する
+	summary, err = ExportBagIt(context.Background(), cfg, filepath.Join(t.TempDir(), "bag"), []string{"A"}, logger)
+	if err != nil || summary.Threads != 2 {
+		t.Errorf("タスク名を指定した ExportBagIt() = %+v, %v", summary, err)
+	}
+}
+
+func TestEncodeBagPath(t *testing.T) {
+	t.Parallel()
+
+	if got, want := encodeBagPath(filepath.Join("data", "a%b", "c\nd.txt")), "data/a%25b/c%0Ad.txt"; got != want {
+		t.Errorf("encodeBagPath() = %q, want %q", got, want)
+	}
+}
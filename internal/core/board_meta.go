@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// boardMetaDirName は、保存先のルートに作成する板の情報（ルール・注意書き・カタログの設定）のディレクトリ名です。
+const boardMetaDirName = "_board_meta"
+
+// boardMetaFileName は、保存した日ごとのディレクトリに書き込む、板の情報の記録のファイル名です。
+const boardMetaFileName = "board_meta.json"
+
+// boardMetaMu は、板の情報の保存を直列化します。同じ保存先・同じ板のタスクが、同じ日のディレクトリに同時に書き込まないようにします。
+var boardMetaMu sync.Mutex
+
+// BoardMetaRecord は、ある時点の板の情報です。_board_meta/<ホスト名>/<板>/<日付>/board_meta.json に保存されます。
+type BoardMetaRecord struct {
+	FetchedAt       time.Time                     `json:"fetched_at"`
+	TaskName        string                        `json:"task_name"`
+	SiteAdapter     string                        `json:"site_adapter"`
+	BoardURL        string                        `json:"board_url"`
+	CatalogURL      string                        `json:"catalog_url,omitempty"`
+	CatalogSettings *config.FutabaCatalogSettings `json:"catalog_settings,omitempty"` // カタログの取得に使用した表示設定
+	Notice          string                        `json:"notice,omitempty"`           // 板の注意書き・お知らせ（アダプタが対応している場合）
+	Pages           []BoardMetaPage               `json:"pages"`
+}
+
+// BoardMetaPage は、保存した板のページ1件です。
+type BoardMetaPage struct {
+	URL   string `json:"url"`
+	File  string `json:"file,omitempty"`  // board_meta.json と同じディレクトリに保存したファイル名
+	Error string `json:"error,omitempty"` // 取得できなかった場合の理由
+}
+
+// boardMetaDir は、task の板の情報を保存するディレクトリ（<保存先>/_board_meta/<ホスト名>/<板のパス>）を返します。
+// 板のパスのうち ".." などディレクトリの外を指す部分は使用しません。
+func boardMetaDir(task config.Task) string {
+	parts := []string{task.SaveRootDirectory, boardMetaDirName}
+	for _, segment := range strings.Split(config.BoardKey(task.TargetBoardURL), "/") {
+		if segment != "" && segment != "." && segment != ".." {
+			parts = append(parts, SanitizeFilename(segment))
+		}
+	}
+	return filepath.Join(parts...)
+}
+
+// latestBoardMeta は、dir に保存されている最も新しい板の情報を返します。まだ保存されていない場合は nil を返します。
+func latestBoardMeta(dir string) (*BoardMetaRecord, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("板の情報のディレクトリを読み込めませんでした (path=%s): %w", dir, err)
+	}
+	// 日付（YYYY-MM-DD）のディレクトリ名は、文字列の順が日付の順になる
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() > entries[j].Name() })
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name(), boardMetaFileName))
+		if err != nil {
+			continue
+		}
+		var record BoardMetaRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue // 壊れた記録は無視し、次に保存し直す
+		}
+		return &record, nil
+	}
+	return nil, nil
+}
+
+// archiveBoardMeta は、タスクの board_meta が設定されている場合に、板のルール・注意書きのページとカタログの設定を
+// 保存先の _board_meta/<ホスト名>/<板>/<日付>/ に保存します。前回の保存から refresh_days（既定: 7日）が経っていない場合は何もしません。
+// 保存した日ごとにディレクトリを分けるため、板の決まりの移り変わりを後から確認できます。
+// すべてのページを取得できなかった場合は何も保存せず、次の巡回で再試行します。
+func archiveBoardMeta(ctx context.Context, client *network.Client, siteAdapter adapter.SiteAdapter, task config.Task, now time.Time, logger *log.Logger) error {
+	settings := task.BoardMeta
+	if settings == nil {
+		return nil
+	}
+	refreshDays := settings.RefreshDays
+	if refreshDays <= 0 {
+		refreshDays = config.DefaultBoardMetaRefreshDays
+	}
+
+	boardMetaMu.Lock()
+	defer boardMetaMu.Unlock()
+
+	dir := boardMetaDir(task)
+	latest, err := latestBoardMeta(dir)
+	if err != nil {
+		return err
+	}
+	if latest != nil && now.Sub(latest.FetchedAt) < time.Duration(refreshDays)*24*time.Hour {
+		return nil
+	}
+
+	record := BoardMetaRecord{
+		FetchedAt:       now,
+		TaskName:        task.TaskName,
+		SiteAdapter:     task.SiteAdapter,
+		BoardURL:        task.TargetBoardURL,
+		CatalogSettings: task.FutabaCatalogSettings,
+	}
+	if catalogURL, err := siteAdapter.BuildCatalogURL(task.TargetBoardURL); err == nil {
+		record.CatalogURL = catalogURL
+	}
+	var pages []string
+	source, hasSource := siteAdapter.(adapter.BoardMetaSource)
+	if hasSource {
+		adapterPages, err := source.BoardMetaPages(task.TargetBoardURL)
+		if err != nil {
+			return fmt.Errorf("板の情報のページのURLの構築に失敗しました: %w", err)
+		}
+		pages = append(pages, adapterPages...)
+	}
+	pages = append(pages, settings.Pages...)
+
+	snapshotDir := filepath.Join(dir, inTaskTimezone(task, now).Format("2006-01-02"))
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return fmt.Errorf("板の情報のディレクトリの作成に失敗しました (path=%s): %w", snapshotDir, err)
+	}
+	saved := 0
+	for i, pageURL := range pages {
+		page := BoardMetaPage{URL: pageURL}
+		body, err := client.Get(ctx, pageURL)
+		if err == nil {
+			err = checkMaintenance(siteAdapter, pageURL, []byte(body))
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Printf("WARNING: 板の情報のページを取得できませんでした (url=%s): %v", pageURL, err)
+			page.Error = err.Error()
+			record.Pages = append(record.Pages, page)
+			continue
+		}
+
+		// 取得したままのバイト列を保存し、ページの文字コードの宣言と内容を一致させる
+		page.File = fmt.Sprintf("page_%d.htm", i+1)
+		if err := os.WriteFile(filepath.Join(snapshotDir, page.File), []byte(body), 0644); err != nil {
+			return fmt.Errorf("板の情報のページの保存に失敗しました (url=%s): %w", pageURL, err)
+		}
+		saved++
+		if hasSource && record.Notice == "" {
+			if htmlContent, err := siteAdapter.ParseThreadHTML([]byte(body)); err == nil {
+				record.Notice = source.ExtractBoardNotice(htmlContent)
+			}
+		}
+		record.Pages = append(record.Pages, page)
+	}
+	if len(pages) > 0 && saved == 0 {
+		os.Remove(snapshotDir) // 空のディレクトリのみ削除される
+		return fmt.Errorf("板の情報のページを1件も取得できませんでした")
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("板の情報のシリアライズに失敗しました: %w", err)
+	}
+	path := filepath.Join(snapshotDir, boardMetaFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("板の情報の書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("板の情報の更新に失敗しました (path=%s): %w", path, err)
+	}
+	logger.Printf("板の情報を %s に保存しました (ページ: %d / %d)", snapshotDir, saved, len(pages))
+	return nil
+}
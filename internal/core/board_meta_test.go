@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/network"
+	"GoImageBoardArchiver/internal/testutil/mockboard"
+)
+
+func TestArchiveBoardMeta(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.SetNotice("二次元画像専用の板です")
+	board.AddThread("1001", "ルール", mockboard.Post{No: 1001, Text: "本文"})
+
+	task, settings := newE2ETask(t, board, "board-meta")
+	task.Timezone = "Asia/Tokyo"
+	task.BoardMeta = &config.BoardMetaSettings{Pages: []string{board.BoardURL() + "res/1001.htm", board.BoardURL() + "rules.htm"}}
+	client, err := network.NewClient(settings)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+	if err != nil {
+		t.Fatalf("GetAdapter() error = %v", err)
+	}
+	logger := log.New(io.Discard, "", 0)
+	ctx := context.Background()
+	dir := boardMetaDir(task)
+
+	now := time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)
+	if err := archiveBoardMeta(ctx, client, siteAdapter, task, now, logger); err != nil {
+		t.Fatalf("archiveBoardMeta() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "2025-11-18", boardMetaFileName))
+	if err != nil {
+		t.Fatalf("%s が保存されていません: %v", boardMetaFileName, err)
+	}
+	var record BoardMetaRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("%s の解析に失敗しました: %v", boardMetaFileName, err)
+	}
+	if record.Notice != "二次元画像専用の板です" || record.CatalogURL == "" || len(record.Pages) != 3 {
+		t.Fatalf("record = %+v", record)
+	}
+	// 取得できなかったページは理由を記録し、取得できたページは保存する
+	if record.Pages[0].File != "page_1.htm" || record.Pages[1].File != "page_2.htm" || record.Pages[2].Error == "" {
+		t.Errorf("Pages = %+v", record.Pages)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2025-11-18", "page_1.htm")); err != nil {
+		t.Errorf("板のトップページが保存されていません: %v", err)
+	}
+
+	// refresh_days（既定: 7日）が経つまでは取得し直さない
+	if err := archiveBoardMeta(ctx, client, siteAdapter, task, now.Add(6*24*time.Hour), logger); err != nil {
+		t.Fatalf("archiveBoardMeta() error = %v", err)
+	}
+	if got := board.Requests(mockboard.BoardPath + "futaba.htm"); got != 1 {
+		t.Errorf("トップページの取得回数 = %d, want 1", got)
+	}
+	if err := archiveBoardMeta(ctx, client, siteAdapter, task, now.Add(7*24*time.Hour), logger); err != nil {
+		t.Fatalf("archiveBoardMeta() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2025-11-25", boardMetaFileName)); err != nil {
+		t.Errorf("1週間後の板の情報が保存されていません: %v", err)
+	}
+
+	// すべてのページを取得できなかった場合は何も保存しない
+	board.SetNotFound(mockboard.BoardPath+"futaba.htm", true)
+	task.BoardMeta = &config.BoardMetaSettings{}
+	if err := archiveBoardMeta(ctx, client, siteAdapter, task, now.Add(14*24*time.Hour), logger); err == nil {
+		t.Error("archiveBoardMeta() error = nil, want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2025-12-02")); !os.IsNotExist(err) {
+		t.Errorf("取得できなかった日のディレクトリが残っています: %v", err)
+	}
+}
+
+func TestBoardMetaDir(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		boardURL string
+		want     string
+	}{
+		{boardURL: "https://May.2chan.net/b/", want: filepath.Join("root", boardMetaDirName, "may.2chan.net", "b")},
+		{boardURL: "https://example.com/a/../../b", want: filepath.Join("root", boardMetaDirName, "example.com", "a", "b")},
+	}
+	for _, tt := range tests {
+		task := config.Task{SaveRootDirectory: "root", TargetBoardURL: tt.boardURL}
+		if got := boardMetaDir(task); got != tt.want {
+			t.Errorf("boardMetaDir(%q) = %q, want %q", tt.boardURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// catalogCacheTTL は、1回の巡回サイクルとみなす時間幅です。
+// この時間内に同じ板のカタログを要求したタスクは、最初の取得結果を共有します。
+const catalogCacheTTL = 1 * time.Minute
+
+// catalogCacheKey は、カタログ取得結果を共有できる単位を表します。
+// 同じ板でもカタログ表示設定（Cookie）や認証の設定（auth）が異なると内容が変わるため、Variant で区別します。
+// ブラウザのCookie（BrowserPage）はスレッド単位のアーカイブにのみ使用され、カタログの取得には使用されません。
+type catalogCacheKey struct {
+	Host    string
+	Board   string
+	Variant string
+}
+
+// catalogCache は、複数タスク間でカタログ取得結果を共有するキャッシュです。
+type catalogCache struct {
+	*flightCache[catalogCacheKey, []model.ThreadInfo]
+}
+
+// sharedCatalogCache は、プロセス内の全タスクで共有されるカタログキャッシュです。
+var sharedCatalogCache = newCatalogCache(catalogCacheTTL)
+
+// newCatalogCache は、カタログキャッシュを生成します。
+// 最初のタスクの取得がシャットダウンなどで中断された場合（context のエラー）は、待っていたタスクが取得し直します。
+func newCatalogCache(ttl time.Duration) *catalogCache {
+	return &catalogCache{newFlightCache[catalogCacheKey, []model.ThreadInfo](ttl, isContextError)}
+}
+
+// catalogCacheKeyForTask は、タスク設定からキャッシュキーを生成します。
+func catalogCacheKeyForTask(task config.Task) (catalogCacheKey, error) {
+	u, err := url.Parse(task.TargetBoardURL)
+	if err != nil {
+		return catalogCacheKey{}, fmt.Errorf("板URLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
+	}
+
+	variant := task.SiteAdapter
+	if s := task.FutabaCatalogSettings; s != nil && s.Auto {
+		variant += "/auto"
+	} else if s != nil {
+		variant = fmt.Sprintf("%s/%dx%dx%d", variant, s.Cols, s.Rows, s.TitleLength)
+	}
+	// booru のカタログはタグの検索結果のため、検索条件ごとに区別する
+	if s := task.BooruSettings; s != nil {
+		variant = fmt.Sprintf("%s/%s/%s/%dx%d", variant, s.API, s.Tags, s.Limit, s.MaxPages)
+	}
+
+	// ログインが必要な板は、アカウントやCookieによって表示されるカタログが異なりうるため、認証の設定ごとに区別する。
+	// 値そのものをキーに残さないよう、ハッシュの先頭のみを使用する
+	if task.Auth != nil {
+		data, err := json.Marshal(task.Auth)
+		if err != nil {
+			return catalogCacheKey{}, fmt.Errorf("認証設定のシリアライズに失敗しました (task=%s): %w", task.TaskName, err)
+		}
+		sum := sha256.Sum256(data)
+		variant += "/auth:" + hex.EncodeToString(sum[:8])
+	}
+
+	return catalogCacheKey{
+		Host:    strings.ToLower(u.Host),
+		Board:   strings.Trim(u.Path, "/"),
+		Variant: variant,
+	}, nil
+}
+
+// get は、キーに対応するカタログ取得結果を返します。
+// 有効期限内の結果があればそれを返し、取得中であれば完了を待ちます。
+// どちらでもなければ fetch を呼び出し、その結果を他のタスクと共有します。
+// 失敗した結果はキャッシュせず、次の呼び出しで再取得します。
+// 返されるスライスは呼び出し元ごとのコピーです。
+func (c *catalogCache) get(ctx context.Context, key catalogCacheKey, fetch func() ([]model.ThreadInfo, error)) ([]model.ThreadInfo, error) {
+	threads, _, err := c.do(ctx, key, fetch)
+	return copyThreads(threads), err
+}
+
+// isContextError は、err がキャンセル・タイムアウトによるものかを判定します。
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func copyThreads(threads []model.ThreadInfo) []model.ThreadInfo {
+	if threads == nil {
+		return nil
+	}
+	return append([]model.ThreadInfo(nil), threads...)
+}
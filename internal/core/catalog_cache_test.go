@@ -0,0 +1,193 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestCatalogCache_SharesConcurrentFetch(t *testing.T) {
+	t.Parallel()
+
+	cache := newCatalogCache(time.Minute)
+	key := catalogCacheKey{Host: "may.2chan.net", Board: "b"}
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	fetch := func() ([]model.ThreadInfo, error) {
+		calls.Add(1)
+		<-release
+		return []model.ThreadInfo{{ID: "1"}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			threads, err := cache.get(context.Background(), key, fetch)
+			if err != nil || len(threads) != 1 {
+				t.Errorf("get() = %v, %v", threads, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("fetch呼び出し回数 = %d, want 1", got)
+	}
+}
+
+func TestCatalogCache_Expiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newCatalogCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	key := catalogCacheKey{Host: "may.2chan.net", Board: "b"}
+	fetchErr := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		err       error
+		wantCalls int32
+	}{
+		{name: "初回は取得する", wantCalls: 1},
+		{name: "有効期限内は共有する", advance: 30 * time.Second, wantCalls: 1},
+		{name: "有効期限切れで再取得する", advance: time.Minute, err: fetchErr, wantCalls: 2},
+		{name: "失敗はキャッシュしない", wantCalls: 3},
+	}
+
+	var calls atomic.Int32
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		_, err := cache.get(context.Background(), key, func() ([]model.ThreadInfo, error) {
+			calls.Add(1)
+			return nil, tt.err
+		})
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.err)
+		}
+		if got := calls.Load(); got != tt.wantCalls {
+			t.Errorf("%s: fetch呼び出し回数 = %d, want %d", tt.name, got, tt.wantCalls)
+		}
+	}
+}
+
+func TestCatalogCache_DoesNotShareCanceledFetch(t *testing.T) {
+	t.Parallel()
+
+	cache := newCatalogCache(time.Minute)
+	key := catalogCacheKey{Host: "may.2chan.net", Board: "b"}
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// 最初のタスクの取得はシャットダウンで中断される
+	firstDone := make(chan error, 1)
+	go func() {
+		_, err := cache.get(context.Background(), key, func() ([]model.ThreadInfo, error) {
+			close(started)
+			<-release
+			return nil, context.Canceled
+		})
+		firstDone <- err
+	}()
+	<-started
+
+	// 待っていたタスクは中断を共有せず、自分で取得し直す
+	waiterDone := make(chan []model.ThreadInfo, 1)
+	go func() {
+		threads, err := cache.get(context.Background(), key, func() ([]model.ThreadInfo, error) {
+			return []model.ThreadInfo{{ID: "1"}}, nil
+		})
+		if err != nil {
+			t.Errorf("待っていたタスクの get() error = %v", err)
+		}
+		waiterDone <- threads
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("最初のタスクの get() error = %v, want context.Canceled", err)
+	}
+	if threads := <-waiterDone; len(threads) != 1 {
+		t.Errorf("待っていたタスクの get() = %v, want 1件", threads)
+	}
+}
+
+func TestCatalogCacheKeyForTask(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a, b config.Task
+		same bool
+	}{
+		{
+			name: "同じ板は同じキー",
+			a:    config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/"},
+			b:    config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://MAY.2chan.net/b"},
+			same: true,
+		},
+		{
+			name: "別の板は別のキー",
+			a:    config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/"},
+			b:    config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://img.2chan.net/b/"},
+		},
+		{
+			name: "カタログ設定が異なれば別のキー",
+			a:    config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/"},
+			b: config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/",
+				FutabaCatalogSettings: &config.FutabaCatalogSettings{Cols: 9, Rows: 100, TitleLength: 20}},
+		},
+		{
+			name: "認証の有無で別のキー",
+			a:    config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/"},
+			b: config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/",
+				Auth: &config.AuthSettings{Type: config.AuthTypeCookie, Cookies: map[string]string{"session": "a"}}},
+		},
+		{
+			name: "Cookieが異なれば別のキー",
+			a: config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/",
+				Auth: &config.AuthSettings{Type: config.AuthTypeCookie, Cookies: map[string]string{"session": "a"}}},
+			b: config.Task{SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/",
+				Auth: &config.AuthSettings{Type: config.AuthTypeCookie, Cookies: map[string]string{"session": "b"}}},
+		},
+		{
+			name: "同じ認証設定は同じキー",
+			a: config.Task{TaskName: "a", SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/",
+				Auth: &config.AuthSettings{Type: config.AuthTypeCookie, Cookies: map[string]string{"session": "a"}}},
+			b: config.Task{TaskName: "b", SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/",
+				Auth: &config.AuthSettings{Type: config.AuthTypeCookie, Cookies: map[string]string{"session": "a"}}},
+			same: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ka, err := catalogCacheKeyForTask(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kb, err := catalogCacheKeyForTask(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (ka == kb) != tt.same {
+				t.Errorf("key a=%+v b=%+v, same=%v want %v", ka, kb, ka == kb, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// cacheValidators は、スナップショットに記録されているスレッドHTMLの検証子を返します。
+func (s *ThreadSnapshot) cacheValidators() network.CacheValidators {
+	return network.CacheValidators{ETag: s.ETag, LastModified: s.LastModifiedHeader}
+}
+
+// setCacheValidators は、スレッドHTMLの検証子を記録します。記録していた値から変わった場合に true を返します。
+func (s *ThreadSnapshot) setCacheValidators(v network.CacheValidators) bool {
+	if s.cacheValidators() == v {
+		return false
+	}
+	s.ETag, s.LastModifiedHeader = v.ETag, v.LastModified
+	return true
+}
+
+// previousThreadValidators は、スレッドHTMLを条件付きリクエストで取得するための、前回の取得時の検証子を返します。
+// 条件付きリクエストを使用しない場合（監視モード以外、完全再取得、再アーカイブの要求中）や、
+// 保存済みのスナップショットが見つからない場合は空の検証子を返します。
+//
+// 保存先はスレッドHTMLを取得した後に確定するため（定型文のタイトルは本文から補われる）、ここではカタログのタイトルから
+// 求めたディレクトリと、同じスレッドIDの既存ディレクトリからスナップショットを探します。見つからない場合は通常の取得になるだけです。
+func previousThreadValidators(task config.Task, thread model.ThreadInfo) network.CacheValidators {
+	if !task.ConditionalThreadFetch || task.ForceFull {
+		return network.CacheValidators{}
+	}
+
+	var dirs []string
+	if generated, err := generateDirectoryPath(task.SaveRootDirectory, task.DirectoryFormat, thread, task.MaxTitleLength); err == nil {
+		dirs = append(dirs, generated)
+	}
+	if thread.ID != "" && task.NamingConflictPolicy != "" && task.NamingConflictPolicy != NamingPolicyDuplicate {
+		if existing, err := sharedThreadDirIndex.lookup(task.SaveRootDirectory, thread.ID); err == nil {
+			dirs = append(dirs, existing...)
+		}
+	}
+
+	for _, dir := range dirs {
+		snapshot, err := LoadThreadSnapshot(dir)
+		if err != nil || snapshot == nil || snapshot.ThreadID != thread.ID {
+			continue
+		}
+		if snapshot.ForceRefresh {
+			return network.CacheValidators{}
+		}
+		return snapshot.cacheValidators()
+	}
+	return network.CacheValidators{}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// FilenameFormatHash は、ファイル内容のハッシュでメディアファイルを命名するプリセット名です。
+// 同じ内容のファイルは常に同じ名前になるため、重複の検出がしやすく、
+// 板がタイムスタンプ由来のファイル名を再利用した場合の衝突も防げます。
+const FilenameFormatHash = "hash"
+
+// filenameFormatPresets は、filename_format に指定できるプリセット名と、その展開後のフォーマットです。
+var filenameFormatPresets = map[string]string{
+	FilenameFormatHash: "{sha256_8}.{ext}",
+}
+
+// resolveFilenameFormat は、プリセット名であれば展開後のフォーマットを、それ以外はそのまま返します。
+func resolveFilenameFormat(format string) string {
+	if preset, ok := filenameFormatPresets[format]; ok {
+		return preset
+	}
+	return format
+}
+
+// usesContentHash は、ファイル名フォーマットがファイル内容のハッシュを使用するかを判定します。
+// この場合、ファイル名はダウンロード後にしか決まりません。
+func usesContentHash(format string) bool {
+	return strings.Contains(resolveFilenameFormat(format), "{sha256_8}")
+}
+
+// fileSHA256 は、ファイルのSHA-256を16進数で返します。暗号化されたファイルは復号した内容のハッシュを返します。
+func fileSHA256(path string) (string, error) {
+	f, err := archivecrypt.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("ハッシュ計算用のファイルを開けませんでした (path=%s): %w", path, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("ハッシュの計算に失敗しました (path=%s): %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// renameByContentHash は、ダウンロード済みのファイルのハッシュを計算し、ハッシュを含むファイル名にリネームします。
+// 同じ名前のファイルが既にある場合は内容も同じであるため、ダウンロードしたファイルを削除して既存のファイルを使用します。
+// リネーム後のパスを返します。
+func renameByContentHash(format string, thread model.ThreadInfo, media *model.MediaInfo, downloadedPath string) (string, error) {
+	sum, err := fileSHA256(downloadedPath)
+	if err != nil {
+		return downloadedPath, err
+	}
+	media.SHA256 = sum
+
+	name, err := generateFileName(format, thread, *media)
+	if err != nil {
+		return downloadedPath, err
+	}
+	finalPath := filepath.Join(filepath.Dir(downloadedPath), name)
+	if finalPath == downloadedPath {
+		return finalPath, nil
+	}
+
+	if _, err := os.Stat(finalPath); err == nil {
+		os.Remove(downloadedPath)
+	} else if err := os.Rename(downloadedPath, finalPath); err != nil {
+		return downloadedPath, fmt.Errorf("ハッシュ名へのリネームに失敗しました (%s -> %s): %w", downloadedPath, finalPath, err)
+	}
+	sharedDownloadCache.relocate(downloadedPath, finalPath)
+	return finalPath, nil
+}
+
+// mediaHashes は、ハッシュを計算したメディアのURLとハッシュを返します。
+// スナップショットに記録し、次回以降にハッシュを含むファイル名の保存済みのファイルを特定するために使用します。
+func mediaHashes(mediaFiles []model.MediaInfo) map[string]string {
+	var known map[string]string
+	for _, m := range mediaFiles {
+		if m.SHA256 == "" || m.Blocked {
+			continue
+		}
+		if known == nil {
+			known = make(map[string]string)
+		}
+		known[m.URL] = m.SHA256
+	}
+	return known
+}
+
+// restoreMediaHashes は、スナップショットに記録したハッシュ（mediaHashes）をハッシュが未計算のメディアに設定します。
+func restoreMediaHashes(mediaFiles []model.MediaInfo, known map[string]string) {
+	for i := range mediaFiles {
+		if sum, ok := known[mediaFiles[i].URL]; ok && mediaFiles[i].SHA256 == "" {
+			mediaFiles[i].SHA256 = sum
+		}
+	}
+}
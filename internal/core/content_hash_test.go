@@ -0,0 +1,76 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestGenerateFileName_ContentHash(t *testing.T) {
+	t.Parallel()
+
+	media := model.MediaInfo{OriginalFilename: "1700000000000.png"}
+	hashed := media
+	hashed.SHA256 = "0123456789abcdef"
+
+	tests := []struct {
+		name   string
+		format string
+		media  model.MediaInfo
+		want   string
+	}{
+		{"プリセット", FilenameFormatHash, hashed, "01234567.png"},
+		{"プレースホルダー", "{thread_id}_{sha256_8}.{ext}", hashed, "42_01234567.png"},
+		{"ハッシュ未計算は元のファイル名で代用", FilenameFormatHash, media, "1700000000000.png"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := generateFileName(tt.format, model.ThreadInfo{ID: "42"}, tt.media)
+			if err != nil || got != tt.want {
+				t.Errorf("generateFileName(%q) = %q, %v, want %q", tt.format, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameByContentHash(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// sha256("same") = 0967115f...
+	first := write("1700000000000.png", "same")
+	media := &model.MediaInfo{URL: "https://example.com/src/1700000000000.png", OriginalFilename: "1700000000000.png"}
+	got, err := renameByContentHash(FilenameFormatHash, model.ThreadInfo{}, media, first)
+	if err != nil {
+		t.Fatalf("renameByContentHash() error = %v", err)
+	}
+	if want := filepath.Join(dir, "0967115f.png"); got != want {
+		t.Errorf("renameByContentHash() = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("リネーム前のファイルが残っています")
+	}
+
+	// 同じ内容のファイルは同じ名前になり、既存のファイルが使われる
+	second := write("1700000000001.png", "same")
+	media2 := &model.MediaInfo{URL: "https://example.com/src/1700000000001.png", OriginalFilename: "1700000000001.png"}
+	if got2, err := renameByContentHash(FilenameFormatHash, model.ThreadInfo{}, media2, second); err != nil || got2 != got {
+		t.Errorf("同じ内容のファイル = %q, %v, want %q", got2, err, got)
+	}
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Errorf("重複したファイルが残っています")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !windows
+
+package core
+
+import "errors"
+
+// freeDiskSpace は、空き容量の取得に対応していないOSではエラーを返します。
+func freeDiskSpace(string) (uint64, error) {
+	return 0, errors.New("このOSでは空き容量を取得できません")
+}
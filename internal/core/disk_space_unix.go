@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package core
+
+import "syscall"
+
+// freeDiskSpace は、path を含むファイルシステムで一般ユーザーが使用できる空き容量（バイト）を返します。
+func freeDiskSpace(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
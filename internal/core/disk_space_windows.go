@@ -0,0 +1,23 @@
+//go:build windows
+
+package core
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// freeDiskSpace は、path を含むドライブで呼び出し元のユーザーが使用できる空き容量（バイト）を返します。
+func freeDiskSpace(path string) (uint64, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	getDiskFreeSpaceEx := syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+	var available uint64
+	ret, _, callErr := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if ret == 0 {
+		return 0, callErr
+	}
+	return available, nil
+}
@@ -0,0 +1,261 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// 診断の各項目の結果です。
+const (
+	DiagnosisOK      = "ok"      // 期待どおりに解析できた
+	DiagnosisWarning = "warning" // 解析できなかったが、板の状態（メンテナンス中・文字だけのレス）でも起こり得る
+	DiagnosisFailed  = "failed"  // 取得・解析に失敗した。マークアップの変更でアダプタが動作していない可能性が高い
+)
+
+// doctorThreadSamples は、診断で取得するスレッドの最大数です。メディアのないスレッドを選んだ場合に備えて、レス数の多い順に試します。
+const doctorThreadSamples = 3
+
+// DiagnosisCheck は、アダプタの診断の1項目の結果です。
+type DiagnosisCheck struct {
+	Name   string `json:"name"`   // 項目の識別子（catalog_threads など）
+	Status string `json:"status"` // DiagnosisOK / DiagnosisWarning / DiagnosisFailed
+	Detail string `json:"detail"`
+}
+
+// AdapterDiagnosis は、タスクのサイトアダプタで実際のカタログとスレッドを取得・解析した結果です。
+// 掲示板のマークアップが変わってアダプタが空のスレッドをアーカイブするようになったことを、巡回より前に検知するために使用します。
+type AdapterDiagnosis struct {
+	TaskName    string `json:"task_name"`
+	SiteAdapter string `json:"site_adapter"`
+	CatalogURL  string `json:"catalog_url,omitempty"`
+	ThreadURL   string `json:"thread_url,omitempty"` // 最後に診断したスレッドのURL
+	Threads     int    `json:"threads"`              // カタログから抽出したスレッド数
+	Titles      int    `json:"titles"`               // タイトルを抽出できたスレッド数
+	Posts       int    `json:"posts"`                // スレッドから抽出したレス数
+	Media       int    `json:"media"`                // スレッドから抽出したメディア数
+	// CatalogLayout・ThreadLayout は、ページの構造のフィンガープリントです。正常だった時の値と比べると、マークアップの変更を確認できます。
+	CatalogLayout string               `json:"catalog_layout,omitempty"`
+	ThreadLayout  string               `json:"thread_layout,omitempty"`
+	Capabilities  adapter.Capabilities `json:"capabilities"` // アダプタが対応している機能
+	Checks        []DiagnosisCheck     `json:"checks"`
+}
+
+// Healthy は、失敗した項目がないかを判定します。
+func (d AdapterDiagnosis) Healthy() bool {
+	for _, c := range d.Checks {
+		if c.Status == DiagnosisFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// add は、診断の項目を追加します。
+func (d *AdapterDiagnosis) add(name, status, format string, args ...any) {
+	d.Checks = append(d.Checks, DiagnosisCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// DiagnoseAdapters は、taskNames のタスク（省略時は有効なすべてのタスク）のサイトアダプタを診断します。
+// 診断はタスクの順に1件ずつ行い、存在しないタスク名を指定した場合はエラーを返します。
+func DiagnoseAdapters(ctx context.Context, cfg *config.Config, taskNames []string, logger *log.Logger) ([]AdapterDiagnosis, error) {
+	var tasks []config.Task
+	if len(taskNames) == 0 {
+		for _, task := range cfg.Tasks {
+			if task.Enabled != nil && *task.Enabled {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	for _, name := range taskNames {
+		task, ok := findTask(cfg, name)
+		if !ok {
+			return nil, fmt.Errorf("タスク '%s' が見つかりません", name)
+		}
+		tasks = append(tasks, task)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("診断するタスクがありません")
+	}
+
+	diagnoses := make([]AdapterDiagnosis, 0, len(tasks))
+	for _, task := range tasks {
+		if err := ctx.Err(); err != nil {
+			return diagnoses, err
+		}
+		logger.Printf("タスク '%s' のサイトアダプタ (%s) を診断します", task.TaskName, task.SiteAdapter)
+		diagnoses = append(diagnoses, DiagnoseAdapter(ctx, cfg.Network, task))
+	}
+	return diagnoses, nil
+}
+
+// DiagnoseAdapter は、task のカタログと、カタログのうちレス数の多いスレッドを取得し、
+// スレッド・タイトル・レス・メディアを抽出できるかを診断します。保存先には何も書き込みません。
+func DiagnoseAdapter(ctx context.Context, settings config.NetworkSettings, task config.Task) AdapterDiagnosis {
+	d := AdapterDiagnosis{TaskName: task.TaskName, SiteAdapter: task.SiteAdapter}
+
+	client, err := network.NewClient(settings)
+	if err != nil {
+		d.add("prepare", DiagnosisFailed, "ネットワーククライアントの初期化に失敗しました: %v", err)
+		return d
+	}
+	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+	if err != nil {
+		d.add("prepare", DiagnosisFailed, "サイトアダプタの取得に失敗しました: %v", err)
+		return d
+	}
+	if err := siteAdapter.Prepare(client, task); err != nil {
+		d.add("prepare", DiagnosisFailed, "サイト固有設定の適用に失敗しました: %v", err)
+		return d
+	}
+	d.Capabilities = siteAdapter.Capabilities()
+
+	threads, ok := d.diagnoseCatalog(ctx, client, siteAdapter, task)
+	if !ok || len(threads) == 0 {
+		return d
+	}
+	d.diagnoseThreads(ctx, client, siteAdapter, task, threads)
+	return d
+}
+
+// diagnoseCatalog は、カタログを取得・解析し、スレッドとタイトルを抽出できたかを記録します。取得できなかった場合は false を返します。
+func (d *AdapterDiagnosis) diagnoseCatalog(ctx context.Context, client *network.Client, siteAdapter adapter.SiteAdapter, task config.Task) ([]model.ThreadInfo, bool) {
+	catalogURL, err := siteAdapter.BuildCatalogURL(task.TargetBoardURL)
+	if err != nil {
+		d.add("catalog_fetch", DiagnosisFailed, "カタログURLの構築に失敗しました: %v", err)
+		return nil, false
+	}
+	d.CatalogURL = catalogURL
+
+	var body string
+	var threads []model.ThreadInfo
+	for {
+		body, err = client.Get(ctx, catalogURL)
+		if err != nil {
+			d.add("catalog_fetch", DiagnosisFailed, "カタログの取得に失敗しました: %v", err)
+			return nil, false
+		}
+		if d.maintenance("catalog_fetch", siteAdapter, catalogURL, body) {
+			return nil, false
+		}
+		threads, err = siteAdapter.ParseCatalog([]byte(body))
+		if err != nil {
+			d.add("catalog_threads", DiagnosisFailed, "カタログの解析に失敗しました: %v", err)
+			return nil, false
+		}
+		retrier, ok := siteAdapter.(adapter.CatalogRetrier)
+		if !ok || !retrier.RetryCatalog(client, threads) {
+			break
+		}
+	}
+	d.add("catalog_fetch", DiagnosisOK, "%d バイトを取得しました", len(body))
+	d.CatalogLayout = structuralFingerprint(body)
+
+	d.Threads = len(threads)
+	for _, thread := range threads {
+		if thread.Title != "" {
+			d.Titles++
+		}
+	}
+	if d.Threads == 0 {
+		d.add("catalog_threads", DiagnosisFailed, "カタログからスレッドを1件も抽出できませんでした (構造: %s)", d.CatalogLayout)
+		return threads, true
+	}
+	d.add("catalog_threads", DiagnosisOK, "%d 件のスレッドを抽出しました", d.Threads)
+	if d.Titles == 0 {
+		d.add("catalog_titles", DiagnosisFailed, "スレッドのタイトルを1件も抽出できませんでした (構造: %s)", d.CatalogLayout)
+	} else {
+		d.add("catalog_titles", DiagnosisOK, "%d / %d 件のスレッドのタイトルを抽出しました", d.Titles, d.Threads)
+	}
+	return threads, true
+}
+
+// diagnoseThreads は、レス数の多い順に最大 doctorThreadSamples 件のスレッドを取得し、メディアを抽出できたスレッドで診断を終えます。
+// すべてのスレッドでメディアを抽出できなかった場合は、最後に取得したスレッドの結果を記録します。
+func (d *AdapterDiagnosis) diagnoseThreads(ctx context.Context, client *network.Client, siteAdapter adapter.SiteAdapter, task config.Task, threads []model.ThreadInfo) {
+	base, err := url.Parse(task.TargetBoardURL)
+	if err != nil {
+		d.add("thread_fetch", DiagnosisFailed, "ターゲットボードURLの解析に失敗しました: %v", err)
+		return
+	}
+	samples := append([]model.ThreadInfo(nil), threads...)
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].ResCount > samples[j].ResCount })
+	samples = samples[:min(len(samples), doctorThreadSamples)]
+
+	for i, thread := range samples {
+		threadURL := joinThreadURL(base, thread.URL).String()
+		d.ThreadURL = threadURL
+		body, err := client.Get(ctx, threadURL)
+		if err != nil {
+			d.add("thread_fetch", DiagnosisFailed, "スレッド %s の取得に失敗しました: %v", thread.ID, err)
+			return
+		}
+		if d.maintenance("thread_fetch", siteAdapter, threadURL, body) {
+			return
+		}
+		htmlContent, err := siteAdapter.ParseThreadHTML([]byte(body))
+		if err != nil {
+			d.add("thread_posts", DiagnosisFailed, "スレッド %s の解析に失敗しました: %v", thread.ID, err)
+			return
+		}
+		media, err := siteAdapter.ExtractMediaFiles(htmlContent, threadURL)
+		if err != nil {
+			d.add("thread_media", DiagnosisFailed, "スレッド %s のメディアの抽出に失敗しました: %v", thread.ID, err)
+			return
+		}
+		if len(media) == 0 && i+1 < len(samples) {
+			continue
+		}
+
+		d.ThreadLayout = structuralFingerprint(htmlContent)
+		d.Posts, d.Media = diagnosisPostCount(siteAdapter, htmlContent, threadURL), len(media)
+		d.add("thread_fetch", DiagnosisOK, "スレッド %s (%d バイト) を取得しました", thread.ID, len(body))
+		switch {
+		case d.Posts > 0:
+			d.add("thread_posts", DiagnosisOK, "%d 件のレスを抽出しました", d.Posts)
+		case !d.Capabilities.ResNumbers:
+			// レス番号のないアダプタでは、レス数を数えられないのは異常ではない
+			d.add("thread_posts", DiagnosisOK, "アダプタがレス番号に対応していないため、レス数は確認しません")
+		default:
+			d.add("thread_posts", DiagnosisWarning, "スレッド %s からレスを抽出できませんでした (構造: %s)", thread.ID, d.ThreadLayout)
+		}
+		if d.Media == 0 {
+			d.add("thread_media", DiagnosisFailed, "%d 件のスレッドのいずれからもメディアを抽出できませんでした (構造: %s)", len(samples), d.ThreadLayout)
+		} else {
+			d.add("thread_media", DiagnosisOK, "%d 件のメディアを抽出しました", d.Media)
+		}
+		return
+	}
+}
+
+// maintenance は、取得したページがメンテナンス中の表示の場合に警告を記録して true を返します。
+// メンテナンス中はアダプタの解析を確認できないため、以降の診断は行いません。
+func (d *AdapterDiagnosis) maintenance(name string, siteAdapter adapter.SiteAdapter, pageURL, body string) bool {
+	err := checkMaintenance(siteAdapter, pageURL, []byte(body))
+	if err == nil {
+		return false
+	}
+	d.add(name, DiagnosisWarning, "板がメンテナンス中のため診断できません: %v", err)
+	return true
+}
+
+// diagnosisPostCount は、スレッドのレス数を返します。アダプタが対応している場合は構造化したレス、レス数の数え方の順に使用し、
+// どちらにも対応していない場合はレス番号の数を返します。
+func diagnosisPostCount(siteAdapter adapter.SiteAdapter, htmlContent, threadURL string) int {
+	if parser, ok := siteAdapter.(adapter.PostParser); ok {
+		if posts, err := parser.ParsePosts(htmlContent, threadURL); err == nil {
+			return len(posts)
+		}
+	}
+	if counter, ok := siteAdapter.(adapter.PostCounter); ok {
+		return counter.CountPosts(htmlContent)
+	}
+	return len(extractResNumbers(htmlContent))
+}
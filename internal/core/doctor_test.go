@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/testutil/mockboard"
+)
+
+// diagnosisStatus は、診断の項目 name の結果を返します。項目がない場合は空文字列です。
+func diagnosisStatus(d AdapterDiagnosis, name string) string {
+	for _, c := range d.Checks {
+		if c.Name == name {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func TestDiagnoseAdapter(t *testing.T) {
+	tests := []struct {
+		name        string
+		threads     func(board *mockboard.Server)
+		wantHealthy bool
+		wantFailed  string
+		wantThread  string
+	}{
+		{
+			name: "正常",
+			threads: func(board *mockboard.Server) {
+				board.AddThread("1001", "画像スレ", mockboard.Post{No: 1001, Text: "本文", Media: e2eMedia("1700000000001.jpg")})
+				// レス数の多い文字だけのスレッドを先に試し、メディアのあるスレッドで診断する
+				board.AddThread("1002", "雑談", mockboard.Post{No: 1002, Text: "本文"}, mockboard.Post{No: 1003, Text: "レス"})
+			},
+			wantHealthy: true,
+			wantThread:  "res/1001.htm",
+		},
+		{
+			name:       "スレッドなし",
+			threads:    func(board *mockboard.Server) {},
+			wantFailed: "catalog_threads",
+		},
+		{
+			name: "メディアなし",
+			threads: func(board *mockboard.Server) {
+				board.AddThread("1002", "雑談", mockboard.Post{No: 1002, Text: "本文"})
+			},
+			wantFailed: "thread_media",
+			wantThread: "res/1002.htm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := mockboard.New()
+			defer board.Close()
+			tt.threads(board)
+			task, network := newE2ETask(t, board, "doctor")
+
+			d := DiagnoseAdapter(context.Background(), network, task)
+			if d.Healthy() != tt.wantHealthy {
+				t.Fatalf("Healthy() = %v, want %v (%+v)", d.Healthy(), tt.wantHealthy, d.Checks)
+			}
+			if tt.wantFailed != "" && diagnosisStatus(d, tt.wantFailed) != DiagnosisFailed {
+				t.Errorf("%s = %q, want %q (%+v)", tt.wantFailed, diagnosisStatus(d, tt.wantFailed), DiagnosisFailed, d.Checks)
+			}
+			if !strings.HasSuffix(d.ThreadURL, tt.wantThread) {
+				t.Errorf("ThreadURL = %q, want suffix %q", d.ThreadURL, tt.wantThread)
+			}
+			if tt.wantHealthy && (d.Threads != 2 || d.Titles != 2 || d.Posts == 0 || d.Media != 1 || d.CatalogLayout == "") {
+				t.Errorf("diagnosis = %+v", d)
+			}
+		})
+	}
+}
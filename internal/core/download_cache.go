@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// downloadCacheTTL は、同じURLのダウンロード結果を共有する時間幅です。
+// 1回の巡回サイクル中に複数のスレッドで貼られたバナーやスタンプ画像を、1回の取得で済ませるために使用します。
+const downloadCacheTTL = 10 * time.Minute
+
+// downloadCache は、スレッド・タスク間で同じURLのダウンロード結果を共有するキャッシュです。
+// 保存済みのファイルをハードリンク（できない場合はコピー）することで、同じURLへの重複したリクエストを避けます。
+// 共有する値は保存済みのファイルのパスです。
+type downloadCache struct {
+	*flightCache[string, string]
+}
+
+// sharedDownloadCache は、プロセス内の全タスクで共有されるダウンロードキャッシュです。
+var sharedDownloadCache = newDownloadCache(downloadCacheTTL)
+
+// newDownloadCache は、ダウンロードキャッシュを生成します。
+// 失敗の原因は保存先の書き込みなど呼び出し元ごとに異なりうるため、待っていた呼び出し元は失敗を共有せずに取得し直します。
+func newDownloadCache(ttl time.Duration) *downloadCache {
+	return &downloadCache{newFlightCache[string, string](ttl, func(error) bool { return true })}
+}
+
+// downloadCacheKey は、task で url を取得する場合のキャッシュのキーを返します。
+// 暗号化（encryption）が有効なタスクのファイルは保存後に暗号化されるため、暗号化の有無と鍵ごとにキーを分け、
+// 暗号化しないタスクに暗号文を、別の鍵のタスクに復号できないファイルを共有しないようにします。
+func downloadCacheKey(task config.Task, url string) string {
+	if task.Encryption == "" {
+		return url
+	}
+	return url + "\x00" + task.Encryption + ":" + task.EncryptionKeySecret
+}
+
+// fetch は、key（downloadCacheKey）のURLの内容を destPath に保存します。
+// 有効期限内に同じURLを保存済みであればそのファイルを destPath へリンクし、取得中であれば完了を待ってから共有します。
+// どちらでもなければ download を呼び出し、保存先を他のスレッドと共有します。
+// 失敗した結果は共有せず、待っていた呼び出し元は改めて取得します。
+// 保存済みのファイルを共有した場合は shared が true になります。
+func (c *downloadCache) fetch(ctx context.Context, key, destPath string, download func() error) (shared bool, err error) {
+	src, shared, err := c.do(ctx, key, func() (string, error) {
+		return destPath, download()
+	})
+	if err != nil || !shared || src == destPath {
+		return shared, err
+	}
+	if err := linkOrCopyFile(src, destPath); err != nil {
+		// 共有元のファイルが削除・移動された場合などは、改めて取得する
+		log.Printf("WARNING: 取得済みファイルの共有に失敗したため、再取得します (src=%s, dest=%s): %v", src, destPath, err)
+		return false, download()
+	}
+	return true, nil
+}
+
+// relocate は、取得済みのファイルが from から to へ移動されたことを記録し、以降の共有で to を使用します。
+func (c *downloadCache) relocate(from, to string) {
+	c.update(func(path string) string {
+		if path == from {
+			return to
+		}
+		return path
+	})
+}
+
+// linkOrCopyFile は、src を dst にハードリンクします。
+// 別ドライブへの保存などでハードリンクを作成できない場合はコピーします。
+func linkOrCopyFile(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("共有元のファイルがありません (path=%s): %w", src, err)
+	}
+	// 既存の不完全なファイルを削除（リンク先を上書きしないよう、書き込みではなく削除してから作成する）
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("既存ファイルの削除に失敗しました (path=%s): %w", dst, err)
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("ファイルのコピーに失敗しました (src=%s, dest=%s): %w", src, dst, err)
+	}
+	return nil
+}
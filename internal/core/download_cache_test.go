@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadCache_SharesConcurrentFetch(t *testing.T) {
+	t.Parallel()
+
+	cache := newDownloadCache(time.Minute)
+	dir := t.TempDir()
+	const url = "https://may.2chan.net/b/src/banner.gif"
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		dest := filepath.Join(dir, fmt.Sprintf("thread%d.gif", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.fetch(context.Background(), url, dest, func() error {
+				calls.Add(1)
+				<-release
+				return os.WriteFile(dest, []byte("banner"), 0644)
+			})
+			if err != nil {
+				t.Errorf("fetch() error = %v", err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("download呼び出し回数 = %d, want 1", got)
+	}
+	for i := 0; i < 5; i++ {
+		data, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("thread%d.gif", i)))
+		if err != nil || string(data) != "banner" {
+			t.Errorf("thread%d.gif = %q, %v", i, data, err)
+		}
+	}
+}
+
+func TestDownloadCache_Refetch(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fetchErr := errors.New("boom")
+
+	tests := []struct {
+		name       string
+		firstErr   error
+		advance    time.Duration
+		removeSrc  bool
+		moveSrc    bool
+		wantShared bool
+		wantCalls  int32
+	}{
+		{name: "有効期限内は共有", wantShared: true, wantCalls: 1},
+		{name: "有効期限切れは再取得", advance: time.Minute, wantCalls: 2},
+		{name: "失敗は共有しない", firstErr: fetchErr, wantCalls: 2},
+		{name: "共有元が削除されていれば再取得", removeSrc: true, wantCalls: 2},
+		{name: "移動を記録した共有元は共有", moveSrc: true, wantShared: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			current := now
+			cache := newDownloadCache(time.Minute)
+			cache.now = func() time.Time { return current }
+			dir := t.TempDir()
+			first, second := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.jpg")
+			var calls atomic.Int32
+			download := func(dest string, err error) func() error {
+				return func() error {
+					calls.Add(1)
+					if err != nil {
+						return err
+					}
+					return os.WriteFile(dest, []byte("data"), 0644)
+				}
+			}
+
+			if _, err := cache.fetch(context.Background(), "https://example.com/a.jpg", first, download(first, tt.firstErr)); !errors.Is(err, tt.firstErr) {
+				t.Fatalf("1回目の fetch() error = %v, want %v", err, tt.firstErr)
+			}
+			current = current.Add(tt.advance)
+			if tt.removeSrc {
+				os.Remove(first)
+			}
+			if tt.moveSrc {
+				moved := filepath.Join(dir, "moved.jpg")
+				if err := os.Rename(first, moved); err != nil {
+					t.Fatal(err)
+				}
+				cache.relocate(first, moved)
+			}
+
+			shared, err := cache.fetch(context.Background(), "https://example.com/a.jpg", second, download(second, nil))
+			if err != nil {
+				t.Fatalf("2回目の fetch() error = %v", err)
+			}
+			if shared != tt.wantShared {
+				t.Errorf("shared = %v, want %v", shared, tt.wantShared)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("download呼び出し回数 = %d, want %d", got, tt.wantCalls)
+			}
+			if data, err := os.ReadFile(second); err != nil || string(data) != "data" {
+				t.Errorf("2つ目の保存先 = %q, %v", data, err)
+			}
+		})
+	}
+}
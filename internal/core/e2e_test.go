@@ -0,0 +1,541 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/testutil/mockboard"
+)
+
+// e2eMedia は、ふたば形式のファイル名を持つテスト用のメディアを返します。
+func e2eMedia(name string) *mockboard.Media {
+	return &mockboard.Media{Name: name, Data: []byte("data:" + name)}
+}
+
+// newE2ETask は、偽の板をアーカイブするタスクとネットワーク設定を返します。
+// 巡回サイクルをまたいでカタログやダウンロード結果が共有されないよう、共有キャッシュをテストの間だけ無効にします。
+func newE2ETask(t *testing.T, board *mockboard.Server, name string) (config.Task, config.NetworkSettings) {
+	t.Helper()
+
+	catalogCache, downloadCache := sharedCatalogCache, sharedDownloadCache
+	sharedCatalogCache, sharedDownloadCache = newCatalogCache(0), newDownloadCache(0)
+	t.Cleanup(func() { sharedCatalogCache, sharedDownloadCache = catalogCache, downloadCache })
+
+	boardURL, err := url.Parse(board.BoardURL())
+	if err != nil {
+		t.Fatalf("板のURLの解析に失敗しました: %v", err)
+	}
+	enabled := true
+	task := config.Task{
+		Enabled:           &enabled,
+		TaskName:          name,
+		SiteAdapter:       "futaba",
+		TargetBoardURL:    board.BoardURL(),
+		SaveRootDirectory: t.TempDir(),
+		DirectoryFormat:   "{thread_id}",
+	}
+	network := config.NetworkSettings{PerDomainIntervalMillis: map[string]int{boardURL.Hostname(): 1}}
+	return task, network
+}
+
+// runE2ECycle は、タスクを監視モードなしで1サイクル実行し、送信された状態を返します。
+func runE2ECycle(t *testing.T, task config.Task, network config.NetworkSettings) []AppStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	statusCh := make(chan AppStatus)
+	done := make(chan []AppStatus)
+	go func() {
+		var statuses []AppStatus
+		for s := range statusCh {
+			statuses = append(statuses, s)
+		}
+		done <- statuses
+	}()
+	ExecuteTask(ctx, task, network, 0, false, statusCh)
+	close(statusCh)
+	return <-done
+}
+
+// archivedThreadIDs は、状態のうちアーカイブ完了を通知したスレッドのIDを返します。
+func archivedThreadIDs(statuses []AppStatus) []string {
+	var ids []string
+	for _, s := range statuses {
+		if s.Archived != nil {
+			ids = append(ids, s.Archived.ThreadID)
+		}
+	}
+	return ids
+}
+
+// taskErrors は、最近のエラーのうち taskName のものを返します。
+func taskErrors(taskName string) []ErrorRecord {
+	var records []ErrorRecord
+	for _, rec := range RecentErrors() {
+		if rec.TaskName == taskName {
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+func readE2EFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s を読み込めませんでした: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestE2E_ArchiveUpdateAndDeletion(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1001", "テストスレ",
+		mockboard.Post{No: 1001, Text: "スレ本文", Media: e2eMedia("1700000000001.jpg")},
+		mockboard.Post{No: 1002, Text: "消されるレス"},
+		mockboard.Post{No: 1003, Text: "画像付きレス", Media: e2eMedia("1700000000002.png")},
+	)
+	task, network := newE2ETask(t, board, "e2e-update")
+	threadDir := filepath.Join(task.SaveRootDirectory, "1001")
+
+	// 1回目: スレッドとメディア・サムネイルをすべて保存する
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 || got[0] != "1001" {
+		t.Fatalf("1回目のアーカイブ完了 = %v, want [1001]", got)
+	}
+	for _, name := range []string{"1700000000001.jpg", "1700000000002.png"} {
+		if got := readE2EFile(t, filepath.Join(threadDir, "img", name)); got != "data:"+name {
+			t.Errorf("img/%s の内容 = %q", name, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(threadDir, "thumb", "1700000000001s.jpg")); err != nil {
+		t.Errorf("サムネイルが保存されていません: %v", err)
+	}
+	index := readE2EFile(t, filepath.Join(threadDir, "index.htm"))
+	for _, want := range []string{"スレ本文", "消されるレス", `href="img/1700000000001.jpg"`, `src="thumb/1700000000001s.jpg"`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.htm に %q が含まれていません", want)
+		}
+	}
+	snapshot, err := LoadThreadSnapshot(threadDir)
+	if err != nil || snapshot == nil || snapshot.LastMediaCount != 2 {
+		t.Fatalf("スナップショット = %+v, %v, want LastMediaCount=2", snapshot, err)
+	}
+
+	// 2回目: 更新がなければスレッドのページのみを取得し、メディアは再取得しない
+	mediaRequests := board.Requests(mockboard.MediaPath("1700000000001.jpg"))
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 0 {
+		t.Errorf("更新なしのサイクルのアーカイブ完了 = %v, want なし", got)
+	}
+	if got := board.Requests(mockboard.ThreadPath("1001")); got != 2 {
+		t.Errorf("スレッドのページの取得回数 = %d, want 2", got)
+	}
+	if got := board.Requests(mockboard.MediaPath("1700000000001.jpg")); got != mediaRequests {
+		t.Errorf("更新なしのサイクルでメディアを再取得しました (%d -> %d)", mediaRequests, got)
+	}
+
+	// 3回目: 新しいレスのメディアを保存し、削除されたレスは完全版にのみ残す
+	board.DeletePost("1001", 1002)
+	board.AddPost("1001", mockboard.Post{No: 1004, Text: "追加されたレス", Media: e2eMedia("1700000000003.gif")})
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 || got[0] != "1001" {
+		t.Fatalf("3回目のアーカイブ完了 = %v, want [1001]", got)
+	}
+	if _, err := os.Stat(filepath.Join(threadDir, "img", "1700000000003.gif")); err != nil {
+		t.Errorf("追加されたメディアが保存されていません: %v", err)
+	}
+	index = readE2EFile(t, filepath.Join(threadDir, "index.htm"))
+	if !strings.Contains(index, "追加されたレス") || strings.Contains(index, "消されるレス") {
+		t.Errorf("index.htm は最新の内容のみを含むべきです:\n%s", index)
+	}
+	full := readE2EFile(t, filepath.Join(threadDir, "archive_full.html"))
+	if !strings.Contains(full, "追加されたレス") || !strings.Contains(full, "消されるレス") {
+		t.Errorf("archive_full.html は削除されたレスも含むべきです:\n%s", full)
+	}
+	if snapshot, _ := LoadThreadSnapshot(threadDir); snapshot == nil || snapshot.LastMediaCount != 3 {
+		t.Errorf("更新後のスナップショット = %+v, want LastMediaCount=3", snapshot)
+	}
+	if errs := taskErrors(task.TaskName); len(errs) != 0 {
+		t.Errorf("最近のエラー = %+v, want なし", errs)
+	}
+}
+
+func TestE2E_EditedPostIsReprocessed(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1051", "編集スレ",
+		mockboard.Post{No: 1051, Text: "スレ本文", Media: e2eMedia("1700000000051.jpg")},
+		mockboard.Post{No: 1052, Text: "編集前のレス"},
+	)
+	task, network := newE2ETask(t, board, "e2e-edit")
+	threadDir := filepath.Join(task.SaveRootDirectory, "1051")
+
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Fatalf("1回目のアーカイブ完了 = %v, want [1051]", got)
+	}
+	// レス数・メディア数が変わらなくても、本文の編集は内容のハッシュで検知する
+	board.EditPost("1051", 1052, "編集後のレス")
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 || got[0] != "1051" {
+		t.Fatalf("編集後のアーカイブ完了 = %v, want [1051]", got)
+	}
+	if index := readE2EFile(t, filepath.Join(threadDir, "index.htm")); !strings.Contains(index, "編集後のレス") {
+		t.Errorf("index.htm に編集後の本文が含まれていません:\n%s", index)
+	}
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 0 {
+		t.Errorf("編集後に変化のないサイクルのアーカイブ完了 = %v, want なし", got)
+	}
+}
+
+func TestE2E_HashFilenamesAreNotRefetched(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1061", "ハッシュ名スレ",
+		mockboard.Post{No: 1061, Text: "スレ本文", Media: e2eMedia("1700000000061.jpg")},
+	)
+	task, network := newE2ETask(t, board, "e2e-hash-resume")
+	task.FilenameFormat = FilenameFormatHash
+	task.EnableResumeSupport = true
+	threadDir := filepath.Join(task.SaveRootDirectory, "1061")
+
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Fatalf("1回目のアーカイブ完了 = %v, want [1061]", got)
+	}
+	sum := sha256.Sum256(e2eMedia("1700000000061.jpg").Data)
+	hashName := hex.EncodeToString(sum[:])[:8] + ".jpg"
+	if _, err := os.Stat(filepath.Join(threadDir, "img", hashName)); err != nil {
+		t.Fatalf("ハッシュ名のファイルが保存されていません: %v", err)
+	}
+
+	// 新しいレスで更新されても、ハッシュ名で保存済みのメディアは再取得しない
+	mediaRequests := board.Requests(mockboard.MediaPath("1700000000061.jpg"))
+	board.AddPost("1061", mockboard.Post{No: 1062, Text: "追加されたレス", Media: e2eMedia("1700000000062.png")})
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Fatalf("2回目のアーカイブ完了 = %v, want [1061]", got)
+	}
+	if got := board.Requests(mockboard.MediaPath("1700000000061.jpg")); got != mediaRequests {
+		t.Errorf("保存済みのメディアを再取得しました (%d -> %d)", mediaRequests, got)
+	}
+	if index := readE2EFile(t, filepath.Join(threadDir, "index.htm")); !strings.Contains(index, `href="img/`+hashName+`"`) {
+		t.Errorf("index.htm が保存済みのファイル %s を参照していません:\n%s", hashName, index)
+	}
+}
+
+// このテストはシークレットの環境変数を変更するため、並列実行しない。
+func TestE2E_EncryptedTaskDoesNotShareCiphertext(t *testing.T) {
+	key, err := archivecrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIBA_SECRET_ARCHIVE_KEY", key)
+
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1071", "暗号化スレ", mockboard.Post{No: 1071, Text: "スレ本文", Media: e2eMedia("1700000000071.jpg")})
+	encryptedTask, network := newE2ETask(t, board, "e2e-encrypted")
+	encryptedTask.Encryption, encryptedTask.EncryptionKeySecret = config.EncryptionAESGCM, "archive_key"
+	plainTask, _ := newE2ETask(t, board, "e2e-plain")
+	otherPlainTask, _ := newE2ETask(t, board, "e2e-plain-other")
+	// 同じ巡回サイクル内と同じように、タスク間でダウンロード結果を共有させる
+	sharedDownloadCache = newDownloadCache(time.Hour)
+
+	for _, task := range []config.Task{encryptedTask, plainTask, otherPlainTask} {
+		if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+			t.Fatalf("%s のアーカイブ完了 = %v, want [1071]", task.TaskName, got)
+		}
+	}
+
+	encryptedPath := filepath.Join(encryptedTask.SaveRootDirectory, "1071", "img", "1700000000071.jpg")
+	if encrypted, err := archivecrypt.IsEncrypted(encryptedPath); err != nil || !encrypted {
+		t.Errorf("暗号化するタスクのメディアが暗号化されていません (%v)", err)
+	}
+	for _, task := range []config.Task{plainTask, otherPlainTask} {
+		path := filepath.Join(task.SaveRootDirectory, "1071", "img", "1700000000071.jpg")
+		if got := readE2EFile(t, path); got != "data:1700000000071.jpg" {
+			t.Errorf("%s のメディアの内容 = %q, want 暗号化されていない内容", task.TaskName, got)
+		}
+	}
+	// 暗号化しないタスクどうしは共有し、暗号化するタスクとは共有しない
+	if got := board.Requests(mockboard.MediaPath("1700000000071.jpg")); got != 2 {
+		t.Errorf("メディアの取得回数 = %d, want 2", got)
+	}
+}
+
+func TestE2E_VerificationRepairRefetchesBrokenFiles(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1081", "修復スレ",
+		mockboard.Post{No: 1081, Text: "スレ本文", Media: e2eMedia("1700000000081.jpg")},
+		mockboard.Post{No: 1082, Text: "画像付きレス", Media: e2eMedia("1700000000082.png")},
+	)
+	task, network := newE2ETask(t, board, "e2e-repair")
+	threadDir := filepath.Join(task.SaveRootDirectory, "1081")
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Fatalf("アーカイブ完了 = %v, want [1081]", got)
+	}
+
+	// 書き込みの途中で失われたファイルを再現する
+	brokenPath := filepath.Join(threadDir, "img", "1700000000081.jpg")
+	if err := os.WriteFile(brokenPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	intactRequests := board.Requests(mockboard.MediaPath("1700000000082.png"))
+
+	repairer, err := prepareRepair(task, network)
+	if err != nil {
+		t.Fatalf("prepareRepair() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var result VerificationResult
+	verifyThreadDir(ctx, task, threadDir, "1081", repairer, true, make(map[string]time.Time), &result)
+
+	if result.TotalRepaired != 1 || result.TotalFailed != 0 {
+		t.Errorf("修復成功 = %d, 修復失敗 = %d, want 1, 0", result.TotalRepaired, result.TotalFailed)
+	}
+	if len(result.Issues) != 1 || !result.Issues[0].Repaired {
+		t.Errorf("検証結果の問題 = %+v, want 修復済みの1件", result.Issues)
+	}
+	if got := readE2EFile(t, brokenPath); got != "data:1700000000081.jpg" {
+		t.Errorf("修復後のファイルの内容 = %q", got)
+	}
+	// 破損していないファイルは取得し直さない
+	if got := board.Requests(mockboard.MediaPath("1700000000082.png")); got != intactRequests {
+		t.Errorf("破損していないメディアを再取得しました (%d -> %d)", intactRequests, got)
+	}
+}
+
+func TestE2E_RemovedMediaIsPreserved(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1101", "画像削除スレ",
+		mockboard.Post{No: 1101, Text: "スレ本文", Media: e2eMedia("1700000000101.jpg")},
+		mockboard.Post{No: 1102, Text: "画像が消されるレス", Media: e2eMedia("1700000000102.png")},
+	)
+	task, network := newE2ETask(t, board, "e2e-removed-media")
+	threadDir := filepath.Join(task.SaveRootDirectory, "1101")
+
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Fatalf("1回目のアーカイブ完了 = %v, want [1101]", got)
+	}
+
+	// レスは残したまま画像のみを削除し、更新対象になるよう画像付きのレスを追加する
+	board.RemoveMedia("1101", 1102)
+	board.AddPost("1101", mockboard.Post{No: 1103, Text: "追加1", Media: e2eMedia("1700000000103.jpg")})
+	board.AddPost("1101", mockboard.Post{No: 1104, Text: "追加2", Media: e2eMedia("1700000000104.jpg")})
+	for cycle := 2; cycle <= 3; cycle++ {
+		if cycle == 3 {
+			// 以降の更新でも、削除された画像を完全版から参照し続ける
+			board.AddPost("1101", mockboard.Post{No: 1105, Text: "追加3", Media: e2eMedia("1700000000105.jpg")})
+		}
+		if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+			t.Fatalf("%d回目のアーカイブ完了 = %v, want [1101]", cycle, got)
+		}
+
+		if got := readE2EFile(t, filepath.Join(threadDir, "img", "1700000000102.png")); got != "data:1700000000102.png" {
+			t.Errorf("%d回目: 削除された画像のファイルの内容 = %q", cycle, got)
+		}
+		if _, err := os.Stat(filepath.Join(threadDir, "thumb", "1700000000102s.jpg")); err != nil {
+			t.Errorf("%d回目: 削除された画像のサムネイルがありません: %v", cycle, err)
+		}
+		index := readE2EFile(t, filepath.Join(threadDir, "index.htm"))
+		if strings.Contains(index, "img/1700000000102.png") || !strings.Contains(index, "画像が消されるレス") {
+			t.Errorf("%d回目: index.htm は最新の内容（画像なしのレス）のみを含むべきです:\n%s", cycle, index)
+		}
+		full := readE2EFile(t, filepath.Join(threadDir, "archive_full.html"))
+		if n := strings.Count(full, `href="img/1700000000102.png"`); n != 1 {
+			t.Errorf("%d回目: archive_full.html の削除された画像へのリンク = %d 件, want 1:\n%s", cycle, n, full)
+		}
+		if !strings.Contains(full, `src="thumb/1700000000102s.jpg"`) {
+			t.Errorf("%d回目: archive_full.html が削除された画像のサムネイルを参照していません", cycle)
+		}
+	}
+}
+
+func TestE2E_MissingMediaAndThreadGone(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.SetLatency(20 * time.Millisecond)
+	board.AddThread("2001", "メディア欠落スレ",
+		mockboard.Post{No: 2001, Text: "本文", Media: e2eMedia("1700000000101.jpg")},
+		mockboard.Post{No: 2002, Text: "消えた画像", Media: e2eMedia("1700000000102.jpg")},
+	)
+	board.AddThread("2003", "落ちたスレ",
+		mockboard.Post{No: 2003, Text: "本文", Media: e2eMedia("1700000000103.jpg")},
+	)
+	// カタログに残っている間にページが消えたスレッドと、取得できないメディア
+	board.SetNotFound(mockboard.ThreadPath("2003"), true)
+	board.SetNotFound(mockboard.MediaPath("1700000000102.jpg"), true)
+	task, network := newE2ETask(t, board, "e2e-gone")
+
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 || got[0] != "2001" {
+		t.Fatalf("アーカイブ完了 = %v, want [2001]", got)
+	}
+	threadDir := filepath.Join(task.SaveRootDirectory, "2001")
+	if _, err := os.Stat(filepath.Join(threadDir, "img", "1700000000101.jpg")); err != nil {
+		t.Errorf("取得できたメディアが保存されていません: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(threadDir, "img", "1700000000102.jpg")); !os.IsNotExist(err) {
+		t.Errorf("404 のメディアのファイルが残っています: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(task.SaveRootDirectory, "2003")); !os.IsNotExist(err) {
+		t.Errorf("落ちたスレッドのディレクトリが作成されました: %v", err)
+	}
+	// スレッドが落ちたことは正常系として扱い、エラーとして記録しない
+	if errs := taskErrors(task.TaskName); len(errs) != 0 {
+		t.Errorf("最近のエラー = %+v, want なし", errs)
+	}
+
+	// カタログから消えたスレッドは取得せず、保存済みのアーカイブはそのまま残す
+	board.DeleteThread("2001")
+	threadRequests := board.Requests(mockboard.ThreadPath("2001"))
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 0 {
+		t.Errorf("削除後のアーカイブ完了 = %v, want なし", got)
+	}
+	if got := board.Requests(mockboard.ThreadPath("2001")); got != threadRequests {
+		t.Errorf("カタログから消えたスレッドを取得しました (%d -> %d)", threadRequests, got)
+	}
+	if _, err := os.Stat(filepath.Join(threadDir, "index.htm")); err != nil {
+		t.Errorf("保存済みのアーカイブが失われました: %v", err)
+	}
+}
+
+func TestE2E_ConditionalThreadFetch(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("3001", "条件付き取得スレ",
+		mockboard.Post{No: 3001, Text: "本文", Media: e2eMedia("1700000000201.jpg")},
+	)
+	task, network := newE2ETask(t, board, "e2e-conditional")
+	// 監視モードと同じく、スレッドHTMLを条件付きリクエストで取得する
+	task.ConditionalThreadFetch = true
+	threadPath := mockboard.ThreadPath("3001")
+
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Fatalf("1回目のアーカイブ完了 = %v, want [3001]", got)
+	}
+	snapshot, err := LoadThreadSnapshot(filepath.Join(task.SaveRootDirectory, "3001"))
+	if err != nil || snapshot == nil || snapshot.ETag == "" || snapshot.LastModifiedHeader == "" {
+		t.Fatalf("スナップショットに検証子が記録されていません: %+v, %v", snapshot, err)
+	}
+
+	// 更新がなければ 304 となり、本文を取得しない
+	runE2ECycle(t, task, network)
+	if got := board.NotModifiedResponses(threadPath); got != 1 {
+		t.Errorf("304 の応答回数 = %d, want 1", got)
+	}
+
+	// 更新されたスレッドは本文を取得してアーカイブする
+	board.AddPost("3001", mockboard.Post{No: 3002, Text: "追加", Media: e2eMedia("1700000000202.jpg")})
+	if got := archivedThreadIDs(runE2ECycle(t, task, network)); len(got) != 1 {
+		t.Errorf("更新後のアーカイブ完了 = %v, want [3001]", got)
+	}
+	if got := board.NotModifiedResponses(threadPath); got != 1 {
+		t.Errorf("更新後の 304 の応答回数 = %d, want 1", got)
+	}
+
+	// 監視モード以外では条件付きリクエストを使用しない
+	task.ConditionalThreadFetch = false
+	runE2ECycle(t, task, network)
+	if got := board.NotModifiedResponses(threadPath); got != 1 {
+		t.Errorf("通常の取得での 304 の応答回数 = %d, want 1", got)
+	}
+}
+
+func TestE2E_CatalogFailureEndsRunOnce(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.SetNotFound(mockboard.BoardPath+"futaba.php", true)
+	task, network := newE2ETask(t, board, "e2e-catalog-failure")
+
+	// 手動実行では、カタログの取得に失敗したサイクルを繰り返さずに終了する
+	start := time.Now()
+	runE2ECycle(t, task, network)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("カタログの取得に失敗した手動実行の所要時間 = %v, want 10s 以内", elapsed)
+	}
+}
+
+func TestE2E_CatalogFailureIsReportedOncePerRetry(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.SetNotFound(mockboard.BoardPath+"futaba.php", true)
+	task, network := newE2ETask(t, board, "e2e-catalog-retry")
+	task.WatchIntervalMillis = 200
+	before := len(taskErrors(task.TaskName))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+	statusCh := make(chan AppStatus)
+	done := make(chan int)
+	go func() {
+		reported := 0
+		for s := range statusCh {
+			if s.HasError {
+				reported++
+			}
+		}
+		done <- reported
+	}()
+	ExecuteTask(ctx, task, network, 0, true, statusCh)
+	close(statusCh)
+
+	// 監視モードでは巡回の間隔ごとに1回だけ再試行し、その都度1件だけ記録・通知する（700ms / 200ms）
+	reported := <-done
+	if reported < 1 || reported > 4 {
+		t.Errorf("エラーの通知 = %d 件, want 1〜4 件", reported)
+	}
+	if got := len(taskErrors(task.TaskName)) - before; got != reported {
+		t.Errorf("最近のエラー = %d 件, want 通知と同じ %d 件", got, reported)
+	}
+}
+
+func TestE2E_CancellationIsPrompt(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.SetLatency(100 * time.Millisecond)
+	posts := make([]mockboard.Post, 20)
+	for i := range posts {
+		posts[i] = mockboard.Post{No: int64(4001 + i), Text: "本文", Media: e2eMedia(fmt.Sprintf("17000000003%02d.jpg", i))}
+	}
+	board.AddThread("4001", "中断スレ", posts...)
+	task, network := newE2ETask(t, board, "e2e-cancel")
+	task.RequestIntervalMillis = 1000
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ExecuteTask(ctx, task, network, 0, false, nil)
+	}()
+
+	// 最初のメディアの取得が始まった時点でキャンセルする
+	deadline := time.Now().Add(10 * time.Second)
+	for board.Requests(mockboard.MediaPath(posts[0].Media.Name)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("メディアの取得が始まりませんでした")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("キャンセル後にタスクが速やかに終了しませんでした")
+	}
+	// 中断したスレッドは完了として記録せず、次回の実行で取得し直す
+	if snapshot, err := LoadThreadSnapshot(filepath.Join(task.SaveRootDirectory, "4001")); err != nil || snapshot != nil {
+		t.Errorf("中断したスレッドのスナップショット = %+v, %v, want なし", snapshot, err)
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/config"
+)
+
+// encryptThreadFiles は、タスクで暗号化（encryption）が有効な場合に、スレッドディレクトリのHTMLとメディアを暗号化します。
+// 対象は .htm / .html のファイル、取得したままのHTML（raw.html.gz）、img/ と thumb/ 以下のファイルです。
+// 暗号化済みのファイルはそのままにするため、更新のたびに新しく保存したファイルだけが暗号化されます。
+// 内部状態のファイル（"." で始まるもの）と thread.json・README.txt・css/ は暗号化しません。
+func encryptThreadFiles(task config.Task, threadSavePath string) error {
+	if task.Encryption == "" {
+		return nil
+	}
+	key, err := archivecrypt.LookupKey(task.EncryptionKeySecret)
+	if err != nil {
+		return fmt.Errorf("暗号化の鍵 '%s' を取得できません: %w", task.EncryptionKeySecret, err)
+	}
+	return filepath.WalkDir(threadSavePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != threadSavePath {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isEncryptedThreadFile(threadSavePath, path) {
+			return nil
+		}
+		return archivecrypt.EncryptFile(path, task.EncryptionKeySecret, key)
+	})
+}
+
+// isEncryptedThreadFile は、path が暗号化の対象のファイルかどうかを判定します。
+func isEncryptedThreadFile(threadSavePath, path string) bool {
+	rel, err := filepath.Rel(threadSavePath, path)
+	if err != nil {
+		return false
+	}
+	if top, _, found := strings.Cut(filepath.ToSlash(rel), "/"); found && (top == "img" || top == "thumb") {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".htm", ".html":
+		return true
+	}
+	return filepath.Base(path) == RawHTMLFileName
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/config"
+)
+
+// このテストはシークレットの環境変数を変更するため、並列実行しない。
+func TestEncryptThreadFiles(t *testing.T) {
+	key, err := archivecrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIBA_SECRET_ARCHIVE_KEY", key)
+
+	dir := t.TempDir()
+	files := map[string]bool{ // パス → 暗号化の対象か
+		"index.htm":                true,
+		"archive_full.html":        true,
+		"page/2.htm":               true,
+		"img/1700000000000.jpg":    true,
+		"thumb/1700000000000s.jpg": true,
+		RawHTMLFileName:            true,
+		"thread.json":              false,
+		"README.txt":               false,
+		"css/futaba.css":           false,
+		".snapshot.json":           false,
+		".giba/history.log":        false,
+	}
+	for name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("content of "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 暗号化が無効なタスクでは何もしない
+	if err := encryptThreadFiles(config.Task{}, dir); err != nil {
+		t.Fatalf("encryptThreadFiles() error = %v", err)
+	}
+	if encrypted, _ := archivecrypt.IsEncrypted(filepath.Join(dir, "index.htm")); encrypted {
+		t.Fatal("暗号化が無効なタスクでファイルが暗号化されました")
+	}
+
+	task := config.Task{Encryption: config.EncryptionAESGCM, EncryptionKeySecret: "archive_key"}
+	if err := encryptThreadFiles(task, dir); err != nil {
+		t.Fatalf("encryptThreadFiles() error = %v", err)
+	}
+	for name, want := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		encrypted, err := archivecrypt.IsEncrypted(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encrypted != want {
+			t.Errorf("%s: 暗号化 = %v, want %v", name, encrypted, want)
+		}
+		// 暗号化の有無に関わらず、元の内容を読み込める
+		if got, err := archivecrypt.ReadFile(path); err != nil || string(got) != "content of "+name {
+			t.Errorf("%s: ReadFile() = %q, %v", name, got, err)
+		}
+	}
+
+	// 鍵を取得できない場合はエラー
+	missing := config.Task{Encryption: config.EncryptionAESGCM, EncryptionKeySecret: "missing_key"}
+	if err := encryptThreadFiles(missing, dir); err == nil {
+		t.Error("鍵がない場合に encryptThreadFiles() がエラーを返しませんでした")
+	}
+}
@@ -0,0 +1,343 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// runOnceProgressName は、手動実行の進捗を表す説明の先頭に付ける名前です。
+const runOnceProgressName = "手動実行"
+
+// statsInterval は、タスクの状態に変化がなくてもセッション統計を配信する間隔です。
+const statsInterval = 10 * time.Second
+
+var (
+	// ErrEngineNotRunning は、開始前または停止後のエンジンを操作したことを表します。
+	ErrEngineNotRunning = errors.New("エンジンは実行されていません")
+	// ErrRunInProgress は、手動実行が既に実行中であることを表します。
+	ErrRunInProgress = errors.New("手動実行は既に実行中です")
+)
+
+// Engine は、タスクの手動実行・監視モード・一時停止の状態を管理し、状態の変化を購読者に配信するコアエンジンです。
+// CLI・システムトレイ・サービス（デーモン）は、いずれもこの型の上の薄いフロントエンドとして動作します。
+//
+// タスクや各操作が送信した状態は1つのゴルーチンに集められ、監視・一時停止の状態とセッション統計、
+// タスクごとの状態の一覧（CurrentStatus で取得できるもの）を付けて、すべての購読者に同じ順序で配信されます。
+type Engine struct {
+	cfg *config.Config
+
+	// opMu は、開始・停止・監視の切り替え・手動実行の開始と終了を直列化します。
+	opMu        sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	started     bool
+	stopped     bool
+	runningOnce bool
+	watchCancel context.CancelFunc // 監視タスクの実行中のみ設定される
+	watchWg     sync.WaitGroup
+	tasksWg     sync.WaitGroup // 監視タスクと手動実行（in に送信するもの）すべて
+
+	in        chan AppStatus
+	forwarded chan struct{}
+
+	// stateMu は、配信する状態に付加するフラグと統計を保護します。
+	stateMu  sync.Mutex
+	watching bool // 監視モードが有効か（手動実行中で監視タスクを止めている間も true のまま）
+	paused   bool
+	stats    SessionStats
+
+	subMu       sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// subscriber は、Subscribe で登録された状態の配信先です。
+type subscriber struct {
+	ch   chan AppStatus
+	done chan struct{}
+	once sync.Once
+}
+
+// NewEngine は、cfg のタスクを実行するエンジンを生成します。Start を呼び出すまでタスクは実行されません。
+func NewEngine(cfg *config.Config) *Engine {
+	return &Engine{
+		cfg:         cfg,
+		stats:       SessionStats{StartTime: time.Now()},
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Start は、実行枠とハートビートの設定を適用し、状態の配信を開始します。
+// ctx がキャンセルされると、実行中のタスクはすべて終了します（配信の終了には Stop を呼び出します）。
+func (e *Engine) Start(ctx context.Context) error {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+	if e.started {
+		return errors.New("エンジンは既に開始されています")
+	}
+	e.started = true
+
+	ConfigureTaskLimits(EffectiveMaxConcurrentTasks(e.cfg.GlobalMaxConcurrentTasks), e.cfg.TaskGroups)
+	sharedPauseGate.set(false)
+	ConfigureHeartbeatFile(e.cfg.HeartbeatFile)
+	ConfigureStatusFile(e.cfg.StatusFile)
+	if err := ConfigureUsageStatsFile(e.cfg.UsageStatsFile); err != nil {
+		log.Printf("WARNING: 利用統計を記録しません: %v", err)
+	}
+	var taskNames []string
+	for _, task := range e.cfg.Tasks {
+		if task.Enabled != nil && *task.Enabled {
+			taskNames = append(taskNames, task.TaskName)
+		}
+	}
+	sharedStatusBoard.reset(taskNames)
+
+	e.ctx, e.cancel = context.WithCancel(ctx)
+	e.in = make(chan AppStatus, 16)
+	e.forwarded = make(chan struct{})
+	go e.forward()
+
+	detail := "待機中"
+	if len(e.cfg.Tasks) == 0 {
+		detail = "タスクなし"
+	}
+	e.in <- AppStatus{State: StateIdle, Detail: detail}
+	return nil
+}
+
+// Stop は、監視モードと手動実行を終了し、すべてのタスクの終了を待ってから配信を停止します。
+// 購読者のチャネルは閉じられます。Start を呼び出していない場合や2回目以降の呼び出しでは何もしません。
+func (e *Engine) Stop() {
+	e.opMu.Lock()
+	if !e.started || e.stopped {
+		e.opMu.Unlock()
+		return
+	}
+	e.stopped = true
+	e.cancel()
+	e.stopWatchLocked()
+	e.opMu.Unlock()
+
+	e.tasksWg.Wait()
+	close(e.in)
+	<-e.forwarded
+	sharedUsageStats.flush()
+
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	for sub := range e.subscribers {
+		close(sub.ch)
+		delete(e.subscribers, sub)
+	}
+}
+
+// Subscribe は、エンジンの状態を受信するチャネルと、購読を解除する関数を返します。
+// 配信は受信側を待つため、購読者はチャネルを読み続けるか、読み終える前に購読を解除する必要があります。
+// チャネルは Stop で閉じられます（購読を解除した場合は閉じられません）。
+func (e *Engine) Subscribe() (<-chan AppStatus, func()) {
+	sub := &subscriber{ch: make(chan AppStatus, 64), done: make(chan struct{})}
+	e.subMu.Lock()
+	e.subscribers[sub] = struct{}{}
+	e.subMu.Unlock()
+
+	return sub.ch, func() {
+		sub.once.Do(func() { close(sub.done) })
+		e.subMu.Lock()
+		delete(e.subscribers, sub)
+		e.subMu.Unlock()
+	}
+}
+
+// RunOnce は、有効なすべてのタスクを一度だけ実行し、終了するまで待ちます。
+// 監視モードが有効な場合は、実行中は監視タスクを止め、終了後に再開します。
+// 各タスクの状態に加えて、全体の進捗が「手動実行: 完了 n/m タスク」のようなアプリケーション全体の状態として配信されます。
+func (e *Engine) RunOnce() error {
+	e.opMu.Lock()
+	if !e.started || e.stopped {
+		e.opMu.Unlock()
+		return ErrEngineNotRunning
+	}
+	if e.runningOnce {
+		e.opMu.Unlock()
+		return ErrRunInProgress
+	}
+	e.runningOnce = true
+	if e.watchCancel != nil {
+		e.stopWatchLocked()
+		log.Println("監視タスクを一時停止して手動実行を開始します")
+	}
+	e.tasksWg.Add(1)
+	ctx := e.ctx
+	e.opMu.Unlock()
+	defer e.tasksWg.Done()
+
+	RunTasks(ctx, e.cfg.Tasks, e.cfg.Network, e.cfg.SafetyStopMinDiskGB, false, runOnceProgressName, e.in)
+
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+	e.runningOnce = false
+	if !e.stopped && e.isWatching() {
+		log.Println("監視タスクを再開します")
+		e.startWatchLocked()
+	}
+	return nil
+}
+
+// SetWatching は、監視モードを有効または無効にします。
+// 手動実行中に有効にした場合、監視タスクは手動実行の終了後に開始されます。
+func (e *Engine) SetWatching(on bool) error {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+	return e.setWatchingLocked(on)
+}
+
+// ToggleWatch は、監視モードの有効・無効を切り替え、切り替え後の状態を返します。
+func (e *Engine) ToggleWatch() (bool, error) {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+	on := !e.isWatching()
+	return on, e.setWatchingLocked(on)
+}
+
+func (e *Engine) setWatchingLocked(on bool) error {
+	if !e.started || e.stopped {
+		return ErrEngineNotRunning
+	}
+
+	e.stateMu.Lock()
+	e.watching = on
+	e.stateMu.Unlock()
+
+	e.stopWatchLocked()
+	if on {
+		log.Println("監視モードを開始します...")
+		if !e.runningOnce {
+			e.startWatchLocked()
+		}
+		e.in <- AppStatus{State: StateWatching, Detail: "監視モード有効"}
+	} else {
+		log.Println("監視モードを停止します...")
+		e.in <- AppStatus{State: StateIdle, Detail: "監視モード無効"}
+	}
+	return nil
+}
+
+// TogglePause は、一時停止の状態を切り替え、切り替え後の状態を返します。
+// 一時停止中も実行中のスレッドはそのまま完了させ、新しい巡回サイクルとスレッドは再開まで開始しません。
+func (e *Engine) TogglePause() (bool, error) {
+	e.opMu.Lock()
+	defer e.opMu.Unlock()
+	if !e.started || e.stopped {
+		return false, ErrEngineNotRunning
+	}
+
+	e.stateMu.Lock()
+	e.paused = !e.paused
+	paused := e.paused
+	e.stateMu.Unlock()
+	sharedPauseGate.set(paused)
+
+	if paused {
+		e.in <- AppStatus{State: StatePaused, Detail: "全活動を一時停止しました"}
+	} else {
+		e.in <- AppStatus{State: StateIdle, Detail: "活動を再開しました"}
+	}
+	return paused, nil
+}
+
+// startWatchLocked は、有効なすべてのタスクを監視モードで開始します。opMu を保持して呼び出します。
+func (e *Engine) startWatchLocked() {
+	watchCtx, cancel := context.WithCancel(e.ctx)
+	e.watchCancel = cancel
+	for _, task := range staggerTaskStarts(e.cfg.Tasks) {
+		if task.Enabled == nil || !*task.Enabled {
+			continue
+		}
+		e.watchWg.Add(1)
+		e.tasksWg.Add(1)
+		go func(t config.Task) {
+			defer e.tasksWg.Done()
+			defer e.watchWg.Done()
+			ExecuteTask(watchCtx, t, e.cfg.Network, e.cfg.SafetyStopMinDiskGB, true, e.in)
+			e.in <- AppStatus{TaskName: t.TaskName, State: StateIdle, Detail: "停止"}
+		}(task)
+	}
+}
+
+// stopWatchLocked は、実行中の監視タスクを終了させ、終了を待ちます。opMu を保持して呼び出します。
+func (e *Engine) stopWatchLocked() {
+	if e.watchCancel == nil {
+		return
+	}
+	e.watchCancel()
+	e.watchWg.Wait()
+	e.watchCancel = nil
+}
+
+func (e *Engine) isWatching() bool {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	return e.watching
+}
+
+// forward は、in に送信された状態を購読者に配信します。状態に変化がない間も、セッション統計を定期的に配信します。
+func (e *Engine) forward() {
+	defer close(e.forwarded)
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case s, ok := <-e.in:
+			if !ok {
+				return
+			}
+			e.publish(s)
+		case <-ticker.C:
+			// Detail を空にして、直前の全体の説明を維持したままセッション統計のみを更新する
+			e.publish(AppStatus{State: StateIdle})
+		}
+	}
+}
+
+// publish は、s にエンジン全体の状態を付加して、すべての購読者に送信します。
+func (e *Engine) publish(s AppStatus) {
+	e.stateMu.Lock()
+	if s.Archived != nil {
+		e.stats.ThreadsArchived++
+		e.stats.PostsArchived += s.Archived.NewPosts
+		e.stats.FilesDownloaded += s.Archived.FilesDownloaded
+		e.stats.TotalBytesWritten += s.Archived.BytesWritten
+		board := s.Archived.TaskName
+		if task, ok := findTask(e.cfg, s.Archived.TaskName); ok && config.BoardKey(task.TargetBoardURL) != "" {
+			board = config.BoardKey(task.TargetBoardURL)
+		}
+		sharedUsageStats.record(board, *s.Archived)
+	}
+	s.IsWatching = e.watching
+	s.IsPaused = e.paused
+	s.ConfigLoaded = true
+	s.SessionInfo = e.stats.FormatSessionInfo()
+	e.stateMu.Unlock()
+
+	snap := sharedStatusBoard.update(s)
+	s.Snapshot = &snap
+
+	e.subMu.Lock()
+	subs := make([]*subscriber, 0, len(e.subscribers))
+	for sub := range e.subscribers {
+		subs = append(subs, sub)
+	}
+	e.subMu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.ch <- s:
+		case <-sub.done:
+		}
+	}
+}
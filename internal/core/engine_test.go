@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/testutil/mockboard"
+)
+
+// nextStatus は、ch から次の状態を受信します。一定時間内に受信できない場合はテストを失敗させます。
+func nextStatus(t *testing.T, ch <-chan AppStatus) AppStatus {
+	t.Helper()
+	select {
+	case s, ok := <-ch:
+		if !ok {
+			t.Fatal("状態のチャネルが閉じられました")
+		}
+		return s
+	case <-time.After(5 * time.Second):
+		t.Fatal("状態を受信できませんでした")
+	}
+	return AppStatus{}
+}
+
+func TestEngine_Lifecycle(t *testing.T) {
+	// 有効なタスクがない設定で、状態の遷移と配信のみを確認する
+	disabled := false
+	cfg := &config.Config{Tasks: []config.Task{{TaskName: "off", Enabled: &disabled}}}
+	e := NewEngine(cfg)
+
+	if err := e.RunOnce(); !errors.Is(err, ErrEngineNotRunning) {
+		t.Fatalf("開始前の RunOnce() error = %v, want ErrEngineNotRunning", err)
+	}
+
+	statusCh, _ := e.Subscribe()
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if s := nextStatus(t, statusCh); s.State != StateIdle || !s.ConfigLoaded || s.SessionInfo == "" {
+		t.Errorf("開始時の状態 = %+v", s)
+	}
+
+	on, err := e.ToggleWatch()
+	if err != nil || !on {
+		t.Fatalf("ToggleWatch() = %v, %v, want true, nil", on, err)
+	}
+	if s := nextStatus(t, statusCh); s.State != StateWatching || !s.IsWatching {
+		t.Errorf("監視モード有効時の状態 = %+v", s)
+	}
+
+	// 手動実行中も監視モードの状態は維持され、進捗は全体の状態として配信される
+	if err := e.RunOnce(); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	var last AppStatus
+	for last.State != StateIdle {
+		last = nextStatus(t, statusCh)
+		if last.TaskName != "" || !strings.HasPrefix(last.Detail, runOnceProgressName) {
+			t.Fatalf("手動実行の進捗 = %+v", last)
+		}
+		if !last.IsWatching {
+			t.Errorf("手動実行中に IsWatching が false になりました: %+v", last)
+		}
+		if last.Snapshot == nil || last.Snapshot.Summary.Detail != last.Detail {
+			t.Errorf("Snapshot が手動実行の進捗を反映していません: %+v", last.Snapshot)
+		}
+	}
+
+	paused, err := e.TogglePause()
+	if err != nil || !paused {
+		t.Fatalf("TogglePause() = %v, %v, want true, nil", paused, err)
+	}
+	if s := nextStatus(t, statusCh); s.State != StatePaused || !s.IsPaused {
+		t.Errorf("一時停止時の状態 = %+v", s)
+	}
+
+	e.Stop()
+	e.Stop() // 2回目は何もしない
+	if _, ok := <-statusCh; ok {
+		t.Error("Stop() 後に状態のチャネルが閉じられていません")
+	}
+	if _, err := e.ToggleWatch(); !errors.Is(err, ErrEngineNotRunning) {
+		t.Errorf("停止後の ToggleWatch() error = %v, want ErrEngineNotRunning", err)
+	}
+}
+
+func TestEngine_UnsubscribeDoesNotBlock(t *testing.T) {
+	cfg := &config.Config{}
+	e := NewEngine(cfg)
+	_, unsubscribe := e.Subscribe()
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// 読まれない購読者がいても、購読を解除すれば配信は滞らない
+	unsubscribe()
+	for i := 0; i < 100; i++ {
+		if _, err := e.TogglePause(); err != nil {
+			t.Fatalf("TogglePause() error = %v", err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() が終了しませんでした")
+	}
+}
+
+func TestEngine_PauseStartsNoNewThreads(t *testing.T) {
+	board := mockboard.New()
+	defer board.Close()
+	board.AddThread("1091", "一時停止スレ", mockboard.Post{No: 1091, Text: "スレ本文"})
+	task, network := newE2ETask(t, board, "e2e-pause")
+	e := NewEngine(&config.Config{Tasks: []config.Task{task}, Network: network})
+	if err := e.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer e.Stop()
+
+	if paused, err := e.TogglePause(); err != nil || !paused {
+		t.Fatalf("TogglePause() = %v, %v, want true, nil", paused, err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- e.RunOnce() }()
+
+	// 一時停止中は巡回サイクルを開始せず、スレッドを取得しない
+	select {
+	case err := <-done:
+		t.Fatalf("一時停止中に RunOnce() が終了しました: %v", err)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if got := board.Requests(mockboard.ThreadPath("1091")); got != 0 {
+		t.Errorf("一時停止中のスレッドの取得回数 = %d, want 0", got)
+	}
+
+	// 再開すると、待機していた巡回サイクルが続行する
+	if paused, err := e.TogglePause(); err != nil || paused {
+		t.Fatalf("TogglePause() = %v, %v, want false, nil", paused, err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("再開後に RunOnce() が終了しませんでした")
+	}
+	if got := board.Requests(mockboard.ThreadPath("1091")); got != 1 {
+		t.Errorf("再開後のスレッドの取得回数 = %d, want 1", got)
+	}
+}
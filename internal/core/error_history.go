@@ -0,0 +1,138 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/errs"
+)
+
+// maxErrorHistory は、保持する最近のエラーの最大件数です。古いものから破棄されます。
+const maxErrorHistory = 50
+
+// ErrorClass は、エラーの分類です。errs パッケージのエラー種別から判定します。
+type ErrorClass string
+
+const (
+	ErrorClassLayoutChanged ErrorClass = "layout_changed" // サイトの構造の変化
+	ErrorClassDiskFull      ErrorClass = "disk_full"      // ディスクの空き容量不足
+	ErrorClassRateLimited   ErrorClass = "rate_limited"   // レート制限
+	ErrorClassTimeout       ErrorClass = "timeout"        // タイムアウト
+	ErrorClassServer        ErrorClass = "server"         // サーバーエラー（5xx）
+	ErrorClassWriteFailed   ErrorClass = "write_failed"   // ファイルの書き込み失敗
+	ErrorClassSetup         ErrorClass = "setup"          // タスクの初期化（ネットワーク・アダプタの設定）の失敗
+	ErrorClassAuth          ErrorClass = "auth"           // 掲示板の認証（ログイン）の失敗
+	ErrorClassMaintenance   ErrorClass = "maintenance"    // 掲示板のメンテナンス
+	ErrorClassOther         ErrorClass = "other"          // その他
+)
+
+// Label は、分類の表示名を返します。
+func (c ErrorClass) Label() string {
+	switch c {
+	case ErrorClassLayoutChanged:
+		return "構造変化"
+	case ErrorClassDiskFull:
+		return "容量不足"
+	case ErrorClassRateLimited:
+		return "レート制限"
+	case ErrorClassTimeout:
+		return "タイムアウト"
+	case ErrorClassServer:
+		return "サーバーエラー"
+	case ErrorClassWriteFailed:
+		return "書き込み失敗"
+	case ErrorClassSetup:
+		return "初期化失敗"
+	case ErrorClassAuth:
+		return "認証失敗"
+	case ErrorClassMaintenance:
+		return "メンテナンス"
+	default:
+		return "エラー"
+	}
+}
+
+// classifyError は、err の分類を返します。
+func classifyError(err error) ErrorClass {
+	switch {
+	case errors.Is(err, errs.ErrLayoutChanged):
+		return ErrorClassLayoutChanged
+	case errors.Is(err, errs.ErrDiskFull):
+		return ErrorClassDiskFull
+	case errors.Is(err, errs.ErrRateLimited):
+		return ErrorClassRateLimited
+	case errors.Is(err, errs.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, errs.ErrServer):
+		return ErrorClassServer
+	case errors.Is(err, errs.ErrWriteFailed):
+		return ErrorClassWriteFailed
+	case errors.Is(err, errs.ErrAuthFailed):
+		return ErrorClassAuth
+	case errors.Is(err, errs.ErrMaintenance):
+		return ErrorClassMaintenance
+	default:
+		return ErrorClassOther
+	}
+}
+
+// ErrorRecord は、タスクの実行中に発生したエラー1件の記録です。
+type ErrorRecord struct {
+	Time     time.Time  `json:"time"`
+	TaskName string     `json:"task_name"`
+	ThreadID string     `json:"thread_id,omitempty"` // スレッドの処理中に発生した場合のみ
+	Class    ErrorClass `json:"class"`
+	Message  string     `json:"message"`
+}
+
+// errorHistory は、最近のエラーを上限件数まで保持します。
+type errorHistory struct {
+	mu      sync.Mutex
+	records []ErrorRecord // 古い順
+	limit   int
+	now     func() time.Time
+}
+
+// sharedErrorHistory は、プロセス内の全タスクで共有される最近のエラーの記録です。
+// ログにしか残らなかった失敗に気付けるよう、システムトレイと Web UI から参照されます。
+var sharedErrorHistory = newErrorHistory(maxErrorHistory)
+
+func newErrorHistory(limit int) *errorHistory {
+	return &errorHistory{limit: limit, now: time.Now}
+}
+
+// RecentErrors は、最近のエラーを新しい順に返します。
+func RecentErrors() []ErrorRecord {
+	return sharedErrorHistory.recent()
+}
+
+// record は、エラーを記録し、記録した内容を返します。上限を超えた場合は最も古いものを破棄します。
+func (h *errorHistory) record(taskName, threadID string, class ErrorClass, err error) ErrorRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rec := ErrorRecord{
+		Time:     h.now(),
+		TaskName: taskName,
+		ThreadID: threadID,
+		Class:    class,
+		Message:  err.Error(),
+	}
+	h.records = append(h.records, rec)
+	if over := len(h.records) - h.limit; over > 0 {
+		h.records = append([]ErrorRecord(nil), h.records[over:]...)
+	}
+	return rec
+}
+
+func (h *errorHistory) recent() []ErrorRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ErrorRecord, len(h.records))
+	for i, rec := range h.records {
+		out[len(h.records)-1-i] = rec
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/errs"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{fmt.Errorf("カタログ: %w", errs.ErrLayoutChanged), ErrorClassLayoutChanged},
+		{errs.ErrDiskFull, ErrorClassDiskFull},
+		{fmt.Errorf("%w: 429", errs.ErrRateLimited), ErrorClassRateLimited},
+		{fmt.Errorf("%w: GET", errs.ErrTimeout), ErrorClassTimeout},
+		{fmt.Errorf("取得: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{fmt.Errorf("%w: 503", errs.ErrServer), ErrorClassServer},
+		{fmt.Errorf("%w (path=a)", errs.ErrWriteFailed), ErrorClassWriteFailed},
+		{fmt.Errorf("%w: ログイン", errs.ErrAuthFailed), ErrorClassAuth},
+		{errors.New("不明"), ErrorClassOther},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorHistory_Bounded(t *testing.T) {
+	t.Parallel()
+
+	h := newErrorHistory(3)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := 0
+	h.now = func() time.Time {
+		n++
+		return base.Add(time.Duration(n) * time.Minute)
+	}
+
+	for i := 1; i <= 5; i++ {
+		h.record("task", fmt.Sprint(i), ErrorClassOther, fmt.Errorf("error %d", i))
+	}
+
+	got := h.recent()
+	if len(got) != 3 {
+		t.Fatalf("len(recent()) = %d, want 3", len(got))
+	}
+	// 新しい順に、上限を超えた古いものは破棄される
+	for i, want := range []string{"5", "4", "3"} {
+		if got[i].ThreadID != want {
+			t.Errorf("recent()[%d].ThreadID = %q, want %q", i, got[i].ThreadID, want)
+		}
+	}
+	if got[0].Message != "error 5" || !got[0].Time.After(got[1].Time) {
+		t.Errorf("recent()[0] = %+v", got[0])
+	}
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightCache は、同じキーの取得を1回にまとめ、成功した結果を有効期限まで共有するキャッシュです。
+// カタログ（catalogCache）とメディアのダウンロード（downloadCache）で、タスク・スレッド間の重複したリクエストを避けるために使用します。
+type flightCache[K comparable, V any] struct {
+	mu      sync.Mutex
+	entries map[K]*flightEntry[V]
+	ttl     time.Duration
+	now     func() time.Time
+	// refetch は、取得中の結果を待っていた呼び出し元が、失敗した結果を共有せずに取得し直すべきエラーかを判定します。
+	refetch func(err error) bool
+}
+
+// flightEntry は、1回分の取得結果です。
+// done が閉じられるまでは取得中であり、同じキーを要求した他の呼び出し元は完了を待ってから結果を共有します。
+type flightEntry[V any] struct {
+	done      chan struct{}
+	value     V
+	err       error
+	fetchedAt time.Time
+}
+
+func newFlightCache[K comparable, V any](ttl time.Duration, refetch func(err error) bool) *flightCache[K, V] {
+	return &flightCache[K, V]{
+		entries: make(map[K]*flightEntry[V]),
+		ttl:     ttl,
+		now:     time.Now,
+		refetch: refetch,
+	}
+}
+
+// do は、key の取得結果を返します。
+// 有効期限内の結果があればそれを返し、取得中であれば完了を待ちます（shared が true）。
+// どちらでもなければ fetch を呼び出し、その結果を他の呼び出し元と共有します。
+// 失敗した結果はキャッシュしません。待っていた呼び出し元は、refetch が true を返すエラーであれば取得し直し、
+// それ以外のエラーはそのまま共有します。待っている間に ctx がキャンセルされた場合は ctx のエラーを返します。
+func (c *flightCache[K, V]) do(ctx context.Context, key K, fetch func() (V, error)) (value V, shared bool, err error) {
+	for {
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if ok && entry.finished() && !c.freshLocked(entry) {
+			ok = false
+		}
+		if !ok {
+			c.evictExpiredLocked()
+			entry = &flightEntry[V]{done: make(chan struct{})}
+			c.entries[key] = entry
+			c.mu.Unlock()
+
+			value, err = fetch()
+			c.mu.Lock()
+			entry.value, entry.err, entry.fetchedAt = value, err, c.now()
+			c.mu.Unlock()
+			close(entry.done)
+			return value, false, err
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			var zero V
+			return zero, false, ctx.Err()
+		}
+		c.mu.Lock()
+		value, err = entry.value, entry.err
+		c.mu.Unlock()
+		if err != nil && c.refetch != nil && c.refetch(err) {
+			// 失敗したエントリは期限切れとして扱われるため、次の周回で取得し直す（他の呼び出し元の取得中であればそれを待つ）
+			continue
+		}
+		return value, true, err
+	}
+}
+
+// update は、取得済みのすべての結果に fn を適用します。取得中のエントリは対象外です。
+func (c *flightCache[K, V]) update(fn func(value V) V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, entry := range c.entries {
+		if entry.finished() {
+			entry.value = fn(entry.value)
+		}
+	}
+}
+
+// freshLocked は、取得済みのエントリが成功しており、有効期限内かを判定します。c.mu を保持した状態で呼び出します。
+func (c *flightCache[K, V]) freshLocked(entry *flightEntry[V]) bool {
+	return entry.err == nil && c.now().Sub(entry.fetchedAt) < c.ttl
+}
+
+// evictExpiredLocked は、有効期限切れ・失敗した取得済みエントリを削除します。c.mu を保持した状態で呼び出します。
+func (c *flightCache[K, V]) evictExpiredLocked() {
+	for key, entry := range c.entries {
+		if entry.finished() && !c.freshLocked(entry) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// finished は、取得が完了しているかを返します。
+func (e *flightEntry[V]) finished() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// healthGracePeriod は、次の巡回予定時刻を過ぎてから異常とみなすまでの猶予です。
+// 大きなスレッドのダウンロードなどで巡回サイクルが長引いても誤検知しないよう、余裕を持たせています。
+const healthGracePeriod = 30 * time.Minute
+
+// TaskHealth は、タスクごとの生存確認の状態です。
+type TaskHealth struct {
+	TaskName  string    `json:"task_name"`
+	LastBeat  time.Time `json:"last_beat"`
+	Deadline  time.Time `json:"deadline"` // この時刻までに次のハートビートがなければ停止しているとみなす
+	IsHealthy bool      `json:"healthy"`
+}
+
+// HealthReport は、プロセス全体の生存確認の結果です。
+type HealthReport struct {
+	Healthy bool         `json:"healthy"`
+	Tasks   []TaskHealth `json:"tasks"`
+}
+
+// healthMonitor は、実行中のタスクのハートビートを記録し、停止（ハング）を検出します。
+type healthMonitor struct {
+	mu            sync.Mutex
+	tasks         map[string]TaskHealth
+	heartbeatFile string
+	now           func() time.Time
+}
+
+// sharedHealthMonitor は、プロセス内の全タスクで共有される生存確認の状態です。
+var sharedHealthMonitor = &healthMonitor{tasks: make(map[string]TaskHealth), now: time.Now}
+
+// ConfigureHeartbeatFile は、ハートビートのたびに現在時刻を書き込むファイルを設定します（空文字で無効）。
+// 監視スクリプトはファイルの更新日時を見ることで、プロセスが停止していないかを確認できます。
+func ConfigureHeartbeatFile(path string) {
+	sharedHealthMonitor.mu.Lock()
+	defer sharedHealthMonitor.mu.Unlock()
+	sharedHealthMonitor.heartbeatFile = path
+}
+
+// CurrentHealth は、現在の生存確認の結果を返します。
+// 期限までにハートビートがないタスクが1つでもあれば Healthy は false になります。
+func CurrentHealth() HealthReport {
+	return sharedHealthMonitor.report()
+}
+
+// beat は、タスクが活動中であることを記録します。expectedWithin 以内に次のハートビートがなければ異常とみなします。
+func (m *healthMonitor) beat(taskName string, expectedWithin time.Duration) {
+	m.mu.Lock()
+	now := m.now()
+	m.tasks[taskName] = TaskHealth{
+		TaskName: taskName,
+		LastBeat: now,
+		Deadline: now.Add(expectedWithin + healthGracePeriod),
+	}
+	heartbeatFile := m.heartbeatFile
+	m.mu.Unlock()
+
+	if heartbeatFile != "" {
+		if err := touchHeartbeatFile(heartbeatFile, now); err != nil {
+			log.Printf("WARNING: ハートビートファイルの更新に失敗しました: %v", err)
+		}
+	}
+}
+
+// remove は、終了したタスクを生存確認の対象から外します。
+func (m *healthMonitor) remove(taskName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tasks, taskName)
+}
+
+func (m *healthMonitor) report() HealthReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	report := HealthReport{Healthy: true, Tasks: make([]TaskHealth, 0, len(m.tasks))}
+	for _, h := range m.tasks {
+		h.IsHealthy = now.Before(h.Deadline)
+		if !h.IsHealthy {
+			report.Healthy = false
+		}
+		report.Tasks = append(report.Tasks, h)
+	}
+	sort.Slice(report.Tasks, func(i, j int) bool { return report.Tasks[i].TaskName < report.Tasks[j].TaskName })
+	return report
+}
+
+// touchHeartbeatFile は、ハートビートファイルに時刻を書き込みます。
+// 書き込み途中のファイルを監視側が読まないよう、一時ファイルに書いてからリネームします。
+func touchHeartbeatFile(path string, now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("ハートビートファイルのディレクトリ作成に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(now.Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return fmt.Errorf("ハートビートファイルの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("ハートビートファイルの更新に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
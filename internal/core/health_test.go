@@ -0,0 +1,68 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	heartbeatFile := filepath.Join(t.TempDir(), "state", "heartbeat")
+	m := &healthMonitor{tasks: make(map[string]TaskHealth), heartbeatFile: heartbeatFile, now: func() time.Time { return now }}
+
+	if !m.report().Healthy {
+		t.Fatal("タスクがない場合は正常であるべきです")
+	}
+
+	m.beat("a", 10*time.Minute)
+	m.beat("b", time.Hour)
+
+	data, err := os.ReadFile(heartbeatFile)
+	if err != nil {
+		t.Fatalf("ハートビートファイルが作成されていません: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != now.Format(time.RFC3339) {
+		t.Errorf("ハートビートファイルの内容 = %q, want %q", got, now.Format(time.RFC3339))
+	}
+
+	tests := []struct {
+		name        string
+		elapsed     time.Duration
+		wantHealthy bool
+		wantStale   []string
+	}{
+		{name: "期限内", elapsed: 10*time.Minute + healthGracePeriod - time.Second, wantHealthy: true},
+		{name: "短い間隔のタスクのみ期限切れ", elapsed: 10*time.Minute + healthGracePeriod, wantHealthy: false, wantStale: []string{"a"}},
+		{name: "両方期限切れ", elapsed: time.Hour + healthGracePeriod, wantHealthy: false, wantStale: []string{"a", "b"}},
+	}
+
+	base := now
+	for _, tt := range tests {
+		now = base.Add(tt.elapsed)
+		report := m.report()
+		if report.Healthy != tt.wantHealthy {
+			t.Errorf("%s: Healthy = %v, want %v", tt.name, report.Healthy, tt.wantHealthy)
+		}
+		var stale []string
+		for _, h := range report.Tasks {
+			if !h.IsHealthy {
+				stale = append(stale, h.TaskName)
+			}
+		}
+		if strings.Join(stale, ",") != strings.Join(tt.wantStale, ",") {
+			t.Errorf("%s: 停止中のタスク = %v, want %v", tt.name, stale, tt.wantStale)
+		}
+	}
+
+	// 終了したタスクは判定の対象外になる
+	m.remove("a")
+	m.remove("b")
+	if !m.report().Healthy {
+		t.Error("全タスク終了後は正常であるべきです")
+	}
+}
@@ -2,8 +2,11 @@
 package core
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"html"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,9 +14,21 @@ import (
 	"strings"
 	"time"
 
+	"GoImageBoardArchiver/internal/adapter"
 	"GoImageBoardArchiver/internal/model"
 )
 
+// resNumberPatterns は、ふたばのレス番号パターンです: "No.1234567890" または data-res="1234567890"。
+// スレッドごとに再コンパイルしないよう、パッケージ初期化時に一度だけコンパイルします。
+var resNumberPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`No\.(\d+)`),
+	regexp.MustCompile(`data-res="(\d+)"`),
+	regexp.MustCompile(`id="r(\d+)"`),
+}
+
+// localMediaLinkPattern は、再構成したHTML内の保存済みメディアへのリンク（中のサムネイルを含む）です。
+var localMediaLinkPattern = regexp.MustCompile(`(?s)<a[^>]+href="((?:img|thumb)/[^"]+)"[^>]*>.*?</a>`)
+
 // ThreadSnapshot は、スレッドの状態スナップショットを表します。
 type ThreadSnapshot struct {
 	ThreadID       string    `json:"thread_id"`
@@ -22,6 +37,77 @@ type ThreadSnapshot struct {
 	LastMediaCount int       `json:"last_media_count"`
 	LastModified   time.Time `json:"last_modified"`
 	IsComplete     bool      `json:"is_complete"` // スレッドが落ちた（404）場合にtrue
+	// ForceRefresh が true の場合、メディア数に関わらず次回のアーカイブでスレッド全体を再取得します。
+	ForceRefresh bool `json:"force_refresh,omitempty"`
+	// TitleHistory は、これまでに観測したスレッドタイトルの履歴です。
+	// カタログの表示設定（cxyl）によってタイトルの切り詰め方が異なるため、すべて記録しておきます。
+	TitleHistory []TitleObservation `json:"title_history,omitempty"`
+	// BlockedMedia は、内容のハッシュが blocked_media_patterns に一致したメディアのURLとそのSHA-256です。
+	// 次回以降、同じURLのメディアをダウンロードせずにブロックするために記録します。
+	BlockedMedia map[string]string `json:"blocked_media,omitempty"`
+	// MediaHashes は、ファイル名に {sha256_8} を使用するタスクで保存したメディアのURLとそのSHA-256です。
+	// ハッシュを含むファイル名はダウンロード後にしか決まらないため、次回以降に保存済みのファイルを特定するために記録します。
+	MediaHashes map[string]string `json:"media_hashes,omitempty"`
+	// ETag と LastModifiedHeader は、前回スレッドHTMLを取得したときのレスポンスの ETag / Last-Modified ヘッダーです。
+	// 監視モードでは、次回の取得をこれらを条件とする条件付きリクエストにします。
+	ETag               string `json:"etag,omitempty"`
+	LastModifiedHeader string `json:"last_modified_header,omitempty"`
+	// CreatedAt は、スレッドの作成日時（OPの投稿日時）です。アダプタが日時を取得できなかった場合は nil です。
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// ContentHash は、前回保存したスレッドの内容を正規化したハッシュです（threadContentHash）。
+	// メディア数・レス数が変わらないレスの編集や削除を検知するために使用します。
+	ContentHash string `json:"content_hash,omitempty"`
+	// PostFeatures は、ID表示・おみくじ・削除などの掲示板固有の表示があるレスの情報です。
+	// スレッドから消えたレスの情報も残すため、前回の記録に今回抽出した情報を重ねて記録します。
+	PostFeatures []model.PostFeatures `json:"post_features,omitempty"`
+}
+
+// TitleObservation は、観測したスレッドタイトルとその観測期間です。
+type TitleObservation struct {
+	Title     string    `json:"title"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ObserveTitle は、タイトルの観測をスナップショットに記録します。
+// これまでに観測していないタイトルを追加した場合に true を返します。
+func (s *ThreadSnapshot) ObserveTitle(title string, now time.Time) bool {
+	if strings.TrimSpace(title) == "" {
+		return false
+	}
+	for i := range s.TitleHistory {
+		if s.TitleHistory[i].Title == title {
+			s.TitleHistory[i].LastSeen = now
+			return false
+		}
+	}
+	s.TitleHistory = append(s.TitleHistory, TitleObservation{Title: title, FirstSeen: now, LastSeen: now})
+	return true
+}
+
+// LatestTitle は、最後に観測したタイトルを返します。履歴がない場合は空文字を返します。
+func (s *ThreadSnapshot) LatestTitle() string {
+	var latest TitleObservation
+	for _, obs := range s.TitleHistory {
+		if latest.Title == "" || obs.LastSeen.After(latest.LastSeen) {
+			latest = obs
+		}
+	}
+	return latest.Title
+}
+
+// DisplayTitle は、表示用のタイトルとして観測履歴のうち最も長いものを返します。
+// 長さが同じ場合は最後に観測したものを優先します。履歴がない場合は空文字を返します。
+func (s *ThreadSnapshot) DisplayTitle() string {
+	var best TitleObservation
+	bestLen := -1
+	for _, obs := range s.TitleHistory {
+		n := len([]rune(obs.Title))
+		if n > bestLen || (n == bestLen && obs.LastSeen.After(best.LastSeen)) {
+			best, bestLen = obs, n
+		}
+	}
+	return best.Title
 }
 
 // LoadThreadSnapshot は、既存のスナップショットファイルを読み込みます。
@@ -59,11 +145,17 @@ func SaveThreadSnapshot(threadSavePath string, snapshot *ThreadSnapshot) error {
 }
 
 // NeedsUpdate は、スレッドが更新されているかどうかを判定します。
-func NeedsUpdate(snapshot *ThreadSnapshot, currentMediaCount int) bool {
+// currentPostCount はアダプタがレス数の取得に対応していない場合は0です。レス数を記録していない古いスナップショットとは比較しません。
+// currentHash は threadContentHash の値で、内容のハッシュを記録していない古いスナップショットとは比較しません。
+func NeedsUpdate(snapshot *ThreadSnapshot, currentMediaCount, currentPostCount int, currentHash string) bool {
 	if snapshot == nil {
 		return true // 初回アーカイブ
 	}
 
+	if snapshot.ForceRefresh {
+		return true // 再アーカイブが要求されている
+	}
+
 	if snapshot.IsComplete {
 		return false // 既に完了済み（スレッドが落ちている）
 	}
@@ -73,9 +165,28 @@ func NeedsUpdate(snapshot *ThreadSnapshot, currentMediaCount int) bool {
 		return true
 	}
 
+	// 画像のないレスのみが増えた場合も更新が必要
+	if snapshot.LastPostCount > 0 && currentPostCount > snapshot.LastPostCount {
+		return true
+	}
+
+	// 件数が変わらなくても、レスの編集や削除で内容が変わった場合は更新が必要
+	if snapshot.ContentHash != "" && currentHash != "" && currentHash != snapshot.ContentHash {
+		return true
+	}
+
 	return false
 }
 
+// threadPostCount は、スレッドHTMLのレス数を取得します。アダプタがレス数の取得に対応していない場合は0を返します。
+func threadPostCount(siteAdapter adapter.SiteAdapter, htmlContent string) int {
+	counter, ok := siteAdapter.(adapter.PostCounter)
+	if !ok {
+		return 0
+	}
+	return counter.CountPosts(htmlContent)
+}
+
 // ExtractPostsFromHTML は、HTMLコンテンツからレス情報を抽出します。
 // 削除されたレスの検知のために使用します。
 func _(_ string, mediaFiles []model.MediaInfo) []Post {
@@ -109,16 +220,9 @@ type Post struct {
 // detectAndExtractDeletedContent は、旧HTMLと新HTMLを比較して削除されたレスを抽出します。
 func detectAndExtractDeletedContent(oldHTML, newHTML, threadID string, logger *log.Logger) string {
 	// 簡易的な実装: レス番号（No.XXXXXXXX）のパターンを抽出して比較
-	oldResNumbers := extractResNumbers(oldHTML)
-	newResNumbers := extractResNumbers(newHTML)
-
-	// 削除されたレス番号を検出
-	deletedResNumbers := make([]string, 0)
-	for resNum := range oldResNumbers {
-		if _, exists := newResNumbers[resNum]; !exists {
-			logger.Printf("INFO: 削除されたレスを検知しました (thread_id=%s, res_number=%s)", threadID, resNum)
-			deletedResNumbers = append(deletedResNumbers, resNum)
-		}
+	_, deletedResNumbers, _ := diffResNumbers(oldHTML, newHTML)
+	for _, resNum := range deletedResNumbers {
+		logger.Printf("INFO: 削除されたレスを検知しました (thread_id=%s, res_number=%s)", threadID, resNum)
 	}
 
 	if len(deletedResNumbers) == 0 {
@@ -132,58 +236,151 @@ func detectAndExtractDeletedContent(oldHTML, newHTML, threadID string, logger *l
 	return deletedHTML
 }
 
+// detectRemovedMedia は、レスは残っているものの画像が元のスレッドから削除されたメディアへのリンクを、旧完全版HTMLから抽出します。
+// 新しいHTMLにも削除されたレス（deletedPostsHTML）にも含まれず、ファイルが保存済みのメディアのみを対象とします。
+// 以前のサイクルで抽出したリンクも旧完全版HTMLに残っているため、一度削除された画像は以降も完全版から参照され続けます。
+func detectRemovedMedia(oldHTML, newHTML, deletedPostsHTML, threadSavePath, threadID string, logger *log.Logger) string {
+	var result strings.Builder
+	seen := make(map[string]bool)
+	for _, match := range localMediaLinkPattern.FindAllStringSubmatch(oldHTML, -1) {
+		link := match[1]
+		quoted := `"` + link + `"`
+		if seen[link] || strings.Contains(newHTML, quoted) || strings.Contains(deletedPostsHTML, quoted) {
+			continue
+		}
+		seen[link] = true
+		localPath := filepath.Join(threadSavePath, filepath.FromSlash(html.UnescapeString(link)))
+		if info, err := os.Stat(localPath); err != nil || info.IsDir() {
+			continue
+		}
+		logger.Printf("INFO: スレッドから削除された画像を検知しました (thread_id=%s, file=%s)", threadID, link)
+		result.WriteString(match[0])
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
 // extractPostsHTML は、指定されたレス番号のHTMLを抽出します。
 func extractPostsHTML(html string, resNumbers []string) string {
 	var result strings.Builder
 
 	for _, resNum := range resNumbers {
-		// ふたばのレス構造: <table>...</table> または <div class="reply">...</div>
-		// レス番号を含むブロックを抽出
-		patterns := []string{
-			// tableベースのレイアウト
-			`(?s)<table[^>]*>.*?No\.` + resNum + `.*?</table>`,
-			// divベースのレイアウト
-			`(?s)<div[^>]*class="[^"]*reply[^"]*"[^>]*>.*?No\.` + resNum + `.*?</div>`,
-			// blockquoteを含む場合
-			`(?s)<blockquote[^>]*>.*?No\.` + resNum + `.*?</blockquote>`,
-		}
-
-		for _, pattern := range patterns {
-			re := regexp.MustCompile(pattern)
-			matches := re.FindAllString(html, -1)
-			for _, match := range matches {
-				result.WriteString(match)
-				result.WriteString("\n")
-			}
+		for _, match := range findPostHTML(html, resNum) {
+			result.WriteString(match)
+			result.WriteString("\n")
 		}
 	}
 
 	return result.String()
 }
 
+// findPostHTML は、指定されたレス番号を含むレスのHTMLブロックを返します。
+func findPostHTML(html, resNum string) []string {
+	// ふたばのレス構造: <table>...</table> または <div class="reply">...</div>
+	// レス番号を含むブロックを抽出（No.10 が No.100 に一致しないよう、番号の後ろは単語境界とする）
+	patterns := []string{
+		// tableベースのレイアウト
+		`(?s)<table[^>]*>.*?No\.` + resNum + `\b.*?</table>`,
+		// divベースのレイアウト
+		`(?s)<div[^>]*class="[^"]*reply[^"]*"[^>]*>.*?No\.` + resNum + `\b.*?</div>`,
+		// blockquoteを含む場合
+		`(?s)<blockquote[^>]*>.*?No\.` + resNum + `\b.*?</blockquote>`,
+	}
+
+	var blocks []string
+	for _, pattern := range patterns {
+		re := regexp.MustCompile(pattern)
+		blocks = append(blocks, re.FindAllString(html, -1)...)
+	}
+	return blocks
+}
+
 // mergeDeletedPostsIntoHTML は、削除されたレスを含む完全版HTMLを生成します。
+// 結果を文字列として必要とする呼び出し元向けです。ファイルに保存する場合は、
+// 結合済みの文字列を生成しない writeMergedHTML を使用してください。
 func mergeDeletedPostsIntoHTML(newHTML, deletedPostsHTML string) (string, error) {
 	if deletedPostsHTML == "" {
 		// 削除されたレスがない場合は新しいHTMLをそのまま返す
 		return newHTML, nil
 	}
 
+	var sb strings.Builder
+	if err := writeMergedHTML(&sb, newHTML, deletedPostsHTML); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeMergedHTML は、削除されたレスのセクションを </body> の直前に挿入しながら newHTML を w に書き出します。
+// newHTML を分割して順に書き込むだけで、結合済みの完全版HTML（newHTML と同程度の大きさ）のコピーは作りません。
+// ただし newHTML と deletedPostsHTML はメモリ上に保持したままで、トークン単位のストリーミング処理ではないため、
+// 削減できるピークメモリは結合済みのコピー1つ分です。
+func writeMergedHTML(w io.Writer, newHTML, deletedPostsHTML string) error {
+	return writeArchiveFullHTML(w, newHTML, deletedPostsHTML, "")
+}
+
+// writeArchiveFullHTML は、削除されたレスのセクションと、スレッドから削除された画像のセクションを
+// </body> の直前に挿入しながら newHTML を w に書き出します。
+func writeArchiveFullHTML(w io.Writer, newHTML, deletedPostsHTML, removedMediaHTML string) error {
+	if deletedPostsHTML == "" && removedMediaHTML == "" {
+		_, err := io.WriteString(w, newHTML)
+		return err
+	}
+
 	// 削除されたレスに「削除済み」マーカーを追加
-	markedDeletedPosts := markAsDeleted(deletedPostsHTML)
+	section := createDeletedSection(markAsDeleted(deletedPostsHTML)) + createRemovedMediaSection(removedMediaHTML)
 
-	// 新しいHTMLに削除されたレスを挿入
 	// 戦略: </body>タグの前に削除されたレスセクションを追加
-	bodyCloseIndex := strings.LastIndex(newHTML, "</body>")
-	if bodyCloseIndex == -1 {
-		// </body>が見つからない場合は末尾に追加
-		return newHTML + "\n" + createDeletedSection(markedDeletedPosts), nil
+	// </body>が見つからない場合は末尾に追加
+	head, tail, sep := newHTML, "", "\n"
+	if bodyCloseIndex := strings.LastIndex(newHTML, "</body>"); bodyCloseIndex != -1 {
+		head, tail, sep = newHTML[:bodyCloseIndex], newHTML[bodyCloseIndex:], ""
+	}
+
+	for _, part := range []string{head, sep, section, tail} {
+		if _, err := io.WriteString(w, part); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	result := newHTML[:bodyCloseIndex] +
-		createDeletedSection(markedDeletedPosts) +
-		newHTML[bodyCloseIndex:]
+// writeFileBuffered は、バッファ付きライターを介してファイルを書き出します。
+// 大きなHTMLを一度に []byte へ変換せずに書き込むために使用します。
+// 書き込み中に中断しても既存のファイルが壊れないよう、同じディレクトリの一時ファイルに書き出して
+// ディスクに同期してから置き換えます。
+func writeFileBuffered(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("ファイルの作成に失敗しました (path=%s): %w", tmp, err)
+	}
 
-	return result, nil
+	bw := bufio.NewWriterSize(f, 64*1024)
+	if err := write(bw); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("ファイルの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("ファイルの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("ファイルの同期に失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("ファイルのクローズに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("ファイルの置き換えに失敗しました (path=%s): %w", path, err)
+	}
+	return nil
 }
 
 // markAsDeleted は、削除されたレスに視覚的なマーカーを追加します。
@@ -218,19 +415,28 @@ func createDeletedSection(deletedPostsHTML string) string {
 `, deletedPostsHTML)
 }
 
+// createRemovedMediaSection は、スレッドから削除された画像のセクションを作成します。
+func createRemovedMediaSection(removedMediaHTML string) string {
+	if removedMediaHTML == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(`
+<!-- スレッドから削除された画像のセクション -->
+<hr style="border: 2px dashed #ff8800; margin: 20px 0;">
+<div id="removed-media-section" style="background: #fffaf0; padding: 20px; margin: 20px 0;">
+<h2 style="color: #ff8800;">🖼️ スレッドから削除された画像</h2>
+<p style="color: #666;">以下の画像は元のスレッドから削除されましたが、アーカイブに保存されています。</p>
+%s
+</div>
+`, removedMediaHTML)
+}
+
 // extractResNumbers は、HTMLからレス番号を抽出します。
 func extractResNumbers(html string) map[string]bool {
 	resNumbers := make(map[string]bool)
 
-	// ふたばのレス番号パターン: "No.1234567890" または data-res="1234567890"
-	patterns := []string{
-		`No\.(\d+)`,
-		`data-res="(\d+)"`,
-		`id="r(\d+)"`,
-	}
-
-	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
+	for _, re := range resNumberPatterns {
 		matches := re.FindAllStringSubmatch(html, -1)
 		for _, match := range matches {
 			if len(match) > 1 {
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestWriteMergedHTML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		newHTML      string
+		deletedPosts string
+	}{
+		{name: "削除レスなし", newHTML: "<html><body><p>a</p></body></html>", deletedPosts: ""},
+		{name: "body閉じタグの前に挿入", newHTML: "<html><body><p>a</p></body></html>", deletedPosts: "<div>gone</div>"},
+		{name: "body閉じタグなし", newHTML: "<p>a</p>", deletedPosts: "<div>gone</div>"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := mergeDeletedPostsIntoHTML(tt.newHTML, tt.deletedPosts)
+			if err != nil {
+				t.Fatalf("mergeDeletedPostsIntoHTML() error = %v", err)
+			}
+
+			var sb strings.Builder
+			if err := writeMergedHTML(&sb, tt.newHTML, tt.deletedPosts); err != nil {
+				t.Fatalf("writeMergedHTML() error = %v", err)
+			}
+			if got := sb.String(); got != want {
+				t.Errorf("writeMergedHTML() = %q, want %q", got, want)
+			}
+			if tt.deletedPosts != "" && !strings.Contains(want, "deleted-posts-section") {
+				t.Errorf("削除レスセクションが挿入されていません: %q", want)
+			}
+		})
+	}
+}
+
+// hugeThreadHTML は、ベンチマーク用に指定レス数の巨大なスレッドHTMLを生成します。
+func hugeThreadHTML(posts int) string {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>bench</title></head><body>")
+	for i := 0; i < posts; i++ {
+		fmt.Fprintf(&sb, `<table data-res="%d"><tr><td>No.%d %s</td></tr></table>`, 1000000+i, 1000000+i, strings.Repeat("本文", 40))
+	}
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// reportPeakHeap は、op の1回の実行で増えたヒープの最大値を peak-heap-B として報告します。
+// 実行中はGCを止めるため、増加量は op の実行中に確保したメモリの総量、つまりピーク時のヒープの増加量になります。
+// allocs/op や B/op は確保の回数と総量のみで、同時に保持していた量（ピーク）は分からないため、別途計測します。
+func reportPeakHeap(b *testing.B, op func() error) {
+	b.Helper()
+	defer debug.SetGCPercent(debug.SetGCPercent(-1))
+
+	var peak uint64
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		b.StartTimer()
+
+		if err := op(); err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		runtime.ReadMemStats(&after)
+		if after.HeapAlloc > before.HeapAlloc && after.HeapAlloc-before.HeapAlloc > peak {
+			peak = after.HeapAlloc - before.HeapAlloc
+		}
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(peak), "peak-heap-B")
+}
+
+// BenchmarkMergeDeletedPosts_String は、結合済みの文字列を生成してから書き出す方式のピークメモリを計測します。
+func BenchmarkMergeDeletedPosts_String(b *testing.B) {
+	newHTML := hugeThreadHTML(5000)
+	deleted := hugeThreadHTML(200)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(newHTML)))
+	b.ResetTimer()
+
+	reportPeakHeap(b, func() error {
+		merged, err := mergeDeletedPostsIntoHTML(newHTML, deleted)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(io.Discard, merged)
+		return err
+	})
+}
+
+// BenchmarkMergeDeletedPosts_Streaming は、writeMergedHTML で直接書き出す方式のピークメモリを計測します。
+// peak-heap-B が String 版より結合済みのコピー1つ分（newHTML の大きさ程度）小さくなります。
+// 入力の newHTML 自体はどちらの方式でも保持するため、計測の対象に含みません。
+func BenchmarkMergeDeletedPosts_Streaming(b *testing.B) {
+	newHTML := hugeThreadHTML(5000)
+	deleted := hugeThreadHTML(200)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(newHTML)))
+	b.ResetTimer()
+
+	reportPeakHeap(b, func() error {
+		return writeMergedHTML(io.Discard, newHTML, deleted)
+	})
+}
+
+func TestWriteFileBuffered_KeepsExistingFileOnError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "index.htm")
+	if err := writeFileBuffered(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "<html>前回</html>")
+		return err
+	}); err != nil {
+		t.Fatalf("writeFileBuffered() error = %v", err)
+	}
+
+	// 書き込みの途中で失敗しても、既存のファイルは書きかけの内容に置き換わらない
+	if err := writeFileBuffered(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, "<html>書きかけ"); err != nil {
+			return err
+		}
+		return fmt.Errorf("中断")
+	}); err == nil {
+		t.Fatal("writeFileBuffered() error = nil, want 中断")
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != "<html>前回</html>" {
+		t.Errorf("既存のファイル = %q, %v, want 前回の内容", got, err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("一時ファイルが残っています: %v", err)
+	}
+}
+
+func TestDetectRemovedMedia(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"img/1.jpg", "img/2.jpg", "img/3.jpg", "thumb/4s.jpg"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	link := func(href string) string { return `<a href="` + href + `" target="_blank"><img src="thumb/x.jpg"></a>` }
+	oldHTML := "<body>" + link("img/1.jpg") + link("img/2.jpg") + link("img/3.jpg") + link("thumb/4s.jpg") + link("img/5.jpg") + link("img/2.jpg") + "</body>"
+	newHTML := "<body>" + link("img/1.jpg") + "</body>"
+	deletedPosts := "<div>" + link("img/3.jpg") + "</div>"
+
+	got := detectRemovedMedia(oldHTML, newHTML, deletedPosts, dir, "100", log.New(io.Discard, "", 0))
+	// 1.jpg は新しいHTMLに、3.jpg は削除されたレスに含まれ、5.jpg は保存されていないため対象外。2.jpg は1回のみ
+	want := link("img/2.jpg") + "\n" + link("thumb/4s.jpg") + "\n"
+	if got != want {
+		t.Errorf("detectRemovedMedia() = %q, want %q", got, want)
+	}
+
+	var sb strings.Builder
+	if err := writeArchiveFullHTML(&sb, newHTML, "", got); err != nil {
+		t.Fatalf("writeArchiveFullHTML() error = %v", err)
+	}
+	if full := sb.String(); !strings.Contains(full, `id="removed-media-section"`) || !strings.HasSuffix(full, "</body>") || !strings.Contains(full, link("img/2.jpg")) {
+		t.Errorf("writeArchiveFullHTML() = %q", full)
+	}
+}
+
+func TestNeedsUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		snapshot   *ThreadSnapshot
+		mediaCount int
+		postCount  int
+		hash       string
+		want       bool
+	}{
+		{name: "初回", snapshot: nil, want: true},
+		{name: "変化なし", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10}, mediaCount: 3, postCount: 10, want: false},
+		{name: "メディアの増加", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10}, mediaCount: 4, postCount: 10, want: true},
+		{name: "画像のないレスのみの増加", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10}, mediaCount: 3, postCount: 11, want: true},
+		{name: "レス数を記録していないスナップショット", snapshot: &ThreadSnapshot{LastMediaCount: 3}, mediaCount: 3, postCount: 11, want: false},
+		{name: "レス数の取得に未対応", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10}, mediaCount: 3, postCount: 0, want: false},
+		{name: "完了済み", snapshot: &ThreadSnapshot{LastPostCount: 10, IsComplete: true}, postCount: 20, want: false},
+		{name: "内容が同じ", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10, ContentHash: "aaa"}, mediaCount: 3, postCount: 10, hash: "aaa", want: false},
+		{name: "件数が同じで内容の変更", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10, ContentHash: "aaa"}, mediaCount: 3, postCount: 10, hash: "bbb", want: true},
+		{name: "ハッシュを記録していないスナップショット", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10}, mediaCount: 3, postCount: 10, hash: "bbb", want: false},
+		{name: "内容のハッシュに未対応", snapshot: &ThreadSnapshot{LastMediaCount: 3, LastPostCount: 10, ContentHash: "aaa"}, mediaCount: 3, postCount: 10, hash: "", want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := NeedsUpdate(tt.snapshot, tt.mediaCount, tt.postCount, tt.hash); got != tt.want {
+				t.Errorf("NeedsUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadSnapshot_TitleHistory(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		titles      []string
+		wantAdded   []bool
+		wantDisplay string
+	}{
+		{name: "履歴なし", wantDisplay: ""},
+		{name: "同じタイトルは追加しない", titles: []string{"スレ", "スレ"}, wantAdded: []bool{true, false}, wantDisplay: "スレ"},
+		{name: "最も長いタイトルを表示", titles: []string{"晩ごはん", "晩ごはんスレッド", "晩ごは"}, wantAdded: []bool{true, true, true}, wantDisplay: "晩ごはんスレッド"},
+		{name: "同じ長さなら最新を表示", titles: []string{"あいう", "かきく"}, wantAdded: []bool{true, true}, wantDisplay: "かきく"},
+		{name: "空白のみは記録しない", titles: []string{" "}, wantAdded: []bool{false}, wantDisplay: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			snapshot := &ThreadSnapshot{ThreadID: "1"}
+			for i, title := range tt.titles {
+				if got := snapshot.ObserveTitle(title, base.Add(time.Duration(i)*time.Minute)); got != tt.wantAdded[i] {
+					t.Errorf("ObserveTitle(%q) = %v, want %v", title, got, tt.wantAdded[i])
+				}
+			}
+			if got := snapshot.DisplayTitle(); got != tt.wantDisplay {
+				t.Errorf("DisplayTitle() = %q, want %q", got, tt.wantDisplay)
+			}
+		})
+	}
+}
+
+func TestThreadPostFeatures(t *testing.T) {
+	t.Parallel()
+
+	htmlContent := `<div class="thre"><span class="cnw">25/11/18(火)12:00:00 ID:aBcD1234</span> No.1000<blockquote>本文</blockquote>` +
+		`<table border=0 class=deleted><tr><td>No.1002<blockquote>削除</blockquote></td></tr></table></div>`
+	previous := []model.PostFeatures{
+		{ResNumber: 1001, Fortune: "大吉"}, // スレッドから消えたレス
+		{ResNumber: 1002, PosterID: "old"},
+	}
+
+	got := threadPostFeatures(adapter.NewFutabaAdapter(), htmlContent, previous)
+	want := []model.PostFeatures{
+		{ResNumber: 1000, PosterID: "aBcD1234"},
+		{ResNumber: 1001, Fortune: "大吉"},
+		{ResNumber: 1002, Deleted: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("threadPostFeatures() = %+v, want %+v", got, want)
+	}
+
+	// 対応していないアダプタでは前回の記録をそのまま使用する
+	if got := threadPostFeatures(adapter.NewGenericAdapter(), htmlContent, previous); !reflect.DeepEqual(got, previous) {
+		t.Errorf("threadPostFeatures(generic) = %+v, want %+v", got, previous)
+	}
+}
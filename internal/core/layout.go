@@ -0,0 +1,91 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+)
+
+var (
+	layoutTagPattern   = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+	layoutClassPattern = regexp.MustCompile(`(?i)\bclass=["']?([^"'>]+)`)
+)
+
+// structuralFingerprint は、ページの構造を表す軽量なフィンガープリントを返します。
+// 本文に依存しないよう、出現する「タグ名.クラス名」の種類の集合のみをハッシュ化します。
+// 掲示板ソフトの更新でマークアップが変わると値が変化するため、解析失敗の原因調査に使用します。
+func structuralFingerprint(html string) string {
+	seen := make(map[string]struct{})
+	for _, m := range layoutTagPattern.FindAllStringSubmatch(html, -1) {
+		tag := strings.ToLower(m[1])
+		classes := ""
+		if cm := layoutClassPattern.FindStringSubmatch(m[2]); len(cm) > 1 {
+			classes = strings.Join(strings.Fields(cm[1]), ".")
+		}
+		seen[tag+"."+classes] = struct{}{}
+	}
+
+	tokens := make([]string, 0, len(seen))
+	for token := range seen {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	sum := sha256.Sum256([]byte(strings.Join(tokens, "\n")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// pageLayout は、ある板・ページ種別について最後に正常に解析できたときの状態です。
+type pageLayout struct {
+	healthy     bool
+	fingerprint string
+}
+
+// layoutMonitor は、正常に解析できていた板で突然何も抽出できなくなったことを検知します。
+type layoutMonitor struct {
+	mu    sync.Mutex
+	pages map[string]*pageLayout
+}
+
+// sharedLayoutMonitor は、プロセス内の全タスクで共有される監視状態です。
+var sharedLayoutMonitor = &layoutMonitor{pages: make(map[string]*pageLayout)}
+
+// layoutKey は、アダプタ・板・ページ種別（"catalog" / "thread"）ごとの監視キーを返します。
+func layoutKey(task config.Task, page string) string {
+	key, err := catalogCacheKeyForTask(task)
+	if err != nil {
+		return fmt.Sprintf("%s|%s|%s", task.SiteAdapter, task.TargetBoardURL, page)
+	}
+	return fmt.Sprintf("%s|%s/%s|%s", task.SiteAdapter, key.Host, key.Board, page)
+}
+
+// check は、解析結果の件数を記録し、以前は抽出できていたのに0件になった場合に
+// errs.ErrLayoutChanged をラップしたエラーを返します。一度も抽出できていない板では判定しません。
+func (m *layoutMonitor) check(key, html string, extracted int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	page, ok := m.pages[key]
+	if !ok {
+		page = &pageLayout{}
+		m.pages[key] = page
+	}
+
+	if extracted > 0 {
+		page.healthy = true
+		page.fingerprint = structuralFingerprint(html)
+		return nil
+	}
+	if !page.healthy || strings.TrimSpace(html) == "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %s から何も抽出できませんでした (前回の構造=%s, 今回の構造=%s)",
+		errs.ErrLayoutChanged, key, page.fingerprint, structuralFingerprint(html))
+}
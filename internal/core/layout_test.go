@@ -0,0 +1,47 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"GoImageBoardArchiver/internal/errs"
+)
+
+func TestStructuralFingerprint(t *testing.T) {
+	t.Parallel()
+
+	a := structuralFingerprint(`<div class="thre"><blockquote>本文A</blockquote></div>`)
+	b := structuralFingerprint(`<div class="thre"><blockquote>別の本文</blockquote><blockquote>B</blockquote></div>`)
+	c := structuralFingerprint(`<article class="post"><p>本文A</p></article>`)
+
+	if a != b {
+		t.Errorf("本文だけが異なるページのフィンガープリントが一致しません: %s != %s", a, b)
+	}
+	if a == c {
+		t.Errorf("構造が異なるページのフィンガープリントが一致しました: %s", a)
+	}
+}
+
+func TestLayoutMonitor_Check(t *testing.T) {
+	t.Parallel()
+
+	const html = `<html><body><div class="thre"></div></body></html>`
+	tests := []struct {
+		name      string
+		extracted int
+		wantErr   bool
+	}{
+		{name: "未確認の板で0件は判定しない", extracted: 0},
+		{name: "抽出できれば正常", extracted: 3},
+		{name: "正常だった板で0件は構造変化", extracted: 0, wantErr: true},
+		{name: "再び抽出できれば正常", extracted: 1},
+	}
+
+	monitor := &layoutMonitor{pages: make(map[string]*pageLayout)}
+	for _, tt := range tests {
+		err := monitor.check("futaba|may.2chan.net/b|thread", html, tt.extracted)
+		if got := errors.Is(err, errs.ErrLayoutChanged); got != tt.wantErr {
+			t.Errorf("%s: check() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
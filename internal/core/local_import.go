@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// errAlreadyImported は、スレッドが既にタスクの保存先にある（スナップショットがある）ことを表します。
+var errAlreadyImported = errors.New("既にアーカイブ済みです")
+
+// errNotSavedThread は、HTMLファイルがタスクの板のスレッドとして認識できなかったことを表します。
+var errNotSavedThread = errors.New("タスクの板のスレッドではありません")
+
+// utf8BOM は、取り込んだHTMLを raw.html.gz に保存するときに付ける UTF-8 の BOM です。
+// 再処理の際に、元のHTMLに残る文字コードの宣言（Shift_JIS など）より優先して UTF-8 と判定させるために付けます。
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ImportSummary は、既存のローカルのアーカイブの取り込みの集計です。
+type ImportSummary struct {
+	Imported int // 取り込んだスレッド数
+	Skipped  int // 取り込み済み、またはスレッドのHTMLでないため取り込まなかったHTMLファイル数
+	Failed   int // 取り込みに失敗したHTMLファイル数
+	Media    int // コピーしたメディア（サムネイルを含む）のファイル数
+	Missing  int // ローカルに見つからなかったメディアの数
+}
+
+// ImportLocalArchive は、他のツール（ふたクロ・赤福など）で保存したスレッドを srcDir から探し、タスク taskName の保存先に取り込みます。
+// srcDir 以下の .htm / .html のうちタスクの板のスレッドと認識できたものを、GIBA のディレクトリ構成（index.htm・img/・thumb/）、
+// スナップショット（.snapshot.json）・thread.json・履歴に変換します。取り込んだHTMLは raw.html.gz に保存し、再処理できるようにします。
+// メディアは srcDir 以下からファイル名で探してコピーするため、元のツールの画像の置き場所には依存しません。srcDir のファイルは変更しません。
+// 取り込んだスレッドはスナップショットに現在のメディア数・レス数を記録するため、監視モードでは新しいレスがない限り再取得しません。
+func ImportLocalArchive(ctx context.Context, cfg *config.Config, taskName, srcDir string, logger *log.Logger) (ImportSummary, error) {
+	var summary ImportSummary
+
+	task, ok := findTask(cfg, taskName)
+	if !ok {
+		return summary, fmt.Errorf("タスク '%s' が見つかりません", taskName)
+	}
+	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+	if err != nil {
+		return summary, fmt.Errorf("サイトアダプタの取得に失敗しました (task=%s): %w", task.TaskName, err)
+	}
+	importer, ok := siteAdapter.(adapter.SavedThreadImporter)
+	if !ok {
+		return summary, fmt.Errorf("サイトアダプタ '%s' は既存のアーカイブの取り込みに対応していません", task.SiteAdapter)
+	}
+	client, err := network.NewClient(cfg.Network)
+	if err != nil {
+		return summary, fmt.Errorf("ネットワーククライアントの初期化に失敗しました: %w", err)
+	}
+	if err := siteAdapter.Prepare(client, task); err != nil {
+		return summary, fmt.Errorf("サイト固有設定の適用に失敗しました (task=%s): %w", task.TaskName, err)
+	}
+
+	htmlFiles, localFiles, err := scanSavedArchive(srcDir)
+	if err != nil {
+		return summary, err
+	}
+	if len(htmlFiles) == 0 {
+		return summary, fmt.Errorf("%s に取り込むHTMLファイルがありません", srcDir)
+	}
+
+	for _, htmlPath := range htmlFiles {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		copied, missing, err := importSavedThread(task, siteAdapter, importer, htmlPath, localFiles, logger)
+		switch {
+		case errors.Is(err, errAlreadyImported), errors.Is(err, errNotSavedThread):
+			logger.Printf("INFO: %s を取り込みませんでした: %v", htmlPath, err)
+			summary.Skipped++
+		case err != nil:
+			logger.Printf("WARNING: %s の取り込みに失敗しました: %v", htmlPath, err)
+			summary.Failed++
+		default:
+			summary.Imported++
+			summary.Media += copied
+			summary.Missing += missing
+		}
+	}
+	logger.Printf("INFO: %s からタスク '%s' に %d 件のスレッドを取り込みました (スキップ: %d, 失敗: %d, メディア: %d, 見つからないメディア: %d)",
+		srcDir, task.TaskName, summary.Imported, summary.Skipped, summary.Failed, summary.Media, summary.Missing)
+	return summary, nil
+}
+
+// scanSavedArchive は、srcDir 以下のHTMLファイルのパスと、それ以外のファイルのファイル名からパスへの対応を返します。
+// 同じファイル名が複数ある場合は、パスの辞書順で最初のものを使用します。
+func scanSavedArchive(srcDir string) ([]string, map[string]string, error) {
+	var htmlFiles []string
+	localFiles := make(map[string]string)
+	err := filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".htm", ".html":
+			htmlFiles = append(htmlFiles, p)
+		default:
+			if _, ok := localFiles[d.Name()]; !ok {
+				localFiles[d.Name()] = p
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("取り込み元 '%s' の走査に失敗しました: %w", srcDir, err)
+	}
+	sort.Strings(htmlFiles)
+	return htmlFiles, localFiles, nil
+}
+
+// importSavedThread は、保存されたスレッドのHTMLファイル1件を取り込み、コピーしたメディアの数と見つからなかったメディアの数を返します。
+func importSavedThread(task config.Task, siteAdapter adapter.SiteAdapter, importer adapter.SavedThreadImporter, htmlPath string, localFiles map[string]string, logger *log.Logger) (int, int, error) {
+	raw, err := os.ReadFile(htmlPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	htmlContent, err := siteAdapter.ParseThreadHTML(raw)
+	if err != nil {
+		return 0, 0, fmt.Errorf("HTMLの解析に失敗しました: %w", err)
+	}
+	thread, restored, ok := importer.ParseSavedThread(htmlContent, filepath.Base(htmlPath))
+	if !ok {
+		return 0, 0, errNotSavedThread
+	}
+	thread.Title = fallbackTitle(task, siteAdapter, "", restored)
+	if !thread.Date.IsZero() {
+		thread.Date = inTaskTimezone(task, thread.Date)
+	}
+
+	threadSavePath, err := resolveThreadSavePath(task, thread, logger)
+	if err != nil {
+		return 0, 0, fmt.Errorf("保存パスの生成に失敗しました (thread_id=%s): %w", thread.ID, err)
+	}
+	if snapshot, err := LoadThreadSnapshot(threadSavePath); err != nil || snapshot != nil {
+		return 0, 0, fmt.Errorf("%w (thread_id=%s, path=%s)", errAlreadyImported, thread.ID, threadSavePath)
+	}
+
+	boardURL, err := url.Parse(task.TargetBoardURL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("ターゲットボードURLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
+	}
+	threadURL := joinThreadURL(boardURL, thread.URL)
+	mediaFiles, err := siteAdapter.ExtractMediaFiles(restored, threadURL.String())
+	if err != nil {
+		return 0, 0, fmt.Errorf("メディアファイルの抽出に失敗しました: %w", err)
+	}
+
+	imgSavePath := filepath.Join(threadSavePath, "img")
+	thumbSavePath := filepath.Join(threadSavePath, "thumb")
+	for _, dir := range []string{imgSavePath, thumbSavePath, filepath.Join(threadSavePath, "css")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, 0, fmt.Errorf("ディレクトリの作成に失敗しました (path=%s): %w", dir, err)
+		}
+	}
+	if err := copyFile("css/futaba.css", filepath.Join(threadSavePath, "css", "futaba.css")); err != nil {
+		logger.Printf("WARNING: futaba.cssのコピーに失敗しました: %v", err)
+	}
+
+	copied := 0
+	for _, media := range mediaFiles {
+		if adapter.IsDataURI(media.URL) {
+			continue // HTMLに埋め込まれた画像は再処理で書き出す
+		}
+		if src, ok := localFiles[mediaURLBase(media.URL)]; ok {
+			name := mediaURLBase(media.URL)
+			if !usesContentHash(task.FilenameFormat) {
+				if generated, err := generateFileName(task.FilenameFormat, thread, media); err == nil && generated != "" {
+					name = generated
+				}
+			}
+			if err := copyFile(src, filepath.Join(imgSavePath, name)); err != nil {
+				return copied, 0, fmt.Errorf("メディアのコピーに失敗しました (src=%s): %w", src, err)
+			}
+			copied++
+		}
+		if media.ThumbnailURL == "" {
+			continue
+		}
+		if src, ok := localFiles[mediaURLBase(media.ThumbnailURL)]; ok {
+			if err := copyFile(src, filepath.Join(thumbSavePath, mediaURLBase(media.ThumbnailURL))); err != nil {
+				return copied, 0, fmt.Errorf("サムネイルのコピーに失敗しました (src=%s): %w", src, err)
+			}
+			copied++
+		}
+	}
+
+	if err := saveRawHTML(threadSavePath, append(append([]byte(nil), utf8BOM...), restored...)); err != nil {
+		return copied, 0, err
+	}
+	now := time.Now()
+	snapshot := &ThreadSnapshot{
+		ThreadID:       thread.ID,
+		LastChecked:    now,
+		LastMediaCount: len(mediaFiles),
+		LastPostCount:  threadPostCount(siteAdapter, restored),
+		LastModified:   now,
+	}
+	if info, err := os.Stat(htmlPath); err == nil {
+		snapshot.LastModified = info.ModTime()
+	}
+	snapshot.ObserveTitle(thread.Title, now)
+	if !thread.Date.IsZero() {
+		snapshot.CreatedAt = &thread.Date
+	}
+	if err := SaveThreadSnapshot(threadSavePath, snapshot); err != nil {
+		return copied, 0, err
+	}
+
+	missing, err := reprocessThread(task, siteAdapter, thread.ID, threadSavePath, logger)
+	if err != nil {
+		return copied, missing, fmt.Errorf("HTMLの再構成に失敗しました (path=%s): %w", threadSavePath, err)
+	}
+	if err := encryptThreadFiles(task, threadSavePath); err != nil {
+		return copied, missing, fmt.Errorf("暗号化に失敗しました (path=%s): %w", threadSavePath, err)
+	}
+	historyPath := filepath.Join(threadSavePath, ".giba", "history.log")
+	if err := appendToHistory(historyPath, thread.ID); err != nil {
+		return copied, missing, fmt.Errorf("履歴への追記に失敗しました (history_file=%s, thread_id=%s): %w", historyPath, thread.ID, err)
+	}
+	if err := updateRollupPages(task, thread, thread.Title, threadSavePath, inTaskTimezone(task, now)); err != nil {
+		logger.Printf("WARNING: 一覧ページの更新に失敗しました: %v", err)
+	}
+	logger.Printf("INFO: スレッド %s を取り込みました (src=%s, path=%s, メディア: %d, 見つからないメディア: %d)", thread.ID, htmlPath, threadSavePath, copied, missing)
+	return copied, missing, nil
+}
+
+// mediaURLBase は、メディアのURLのパスのファイル名を返します。
+func mediaURLBase(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(rawURL)
+}
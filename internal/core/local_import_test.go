@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestImportLocalArchive(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		// ふたクロのようにスレッドごとのフォルダに画像を置く形式
+		"1700000000.htm": `<html><head><meta charset="Shift_JIS"></head><body>` +
+			`<a href="1700000000/1700000000123.jpg" target="_blank"><img src="1700000000/1700000000123s.jpg"></a>` +
+			`<input type=checkbox name="1700000000" value=delete id=delcheck1700000000>25/11/18(火)12:34:56 No.1700000000` +
+			`<blockquote>取り込むスレ</blockquote>` +
+			`<a href="1700000000/1700000000456.png">1700000000456.png</a> 25/11/18(火)12:35:00 No.1700000001<blockquote>レス</blockquote>` +
+			`</body></html>`,
+		"1700000000/1700000000123.jpg":  "jpeg",
+		"1700000000/1700000000123s.jpg": "thumb",
+		// 赤福のように保存元のURLが残る形式で、別の板のスレッド
+		"other/1700000009.htm": `<!-- saved from url=(0044)https://may.2chan.net/g/res/1700000009.htm -->` +
+			`<html><body>No.1700000009<blockquote>別の板</blockquote></body></html>`,
+		"catalog.html": `<html><body>カタログ</body></html>`,
+	})
+	root := t.TempDir()
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "A", SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: root, DirectoryFormat: "{thread_id}"},
+	}}
+	logger := log.New(io.Discard, "", 0)
+
+	summary, err := ImportLocalArchive(context.Background(), cfg, "A", src, logger)
+	if err != nil {
+		t.Fatalf("ImportLocalArchive() error = %v", err)
+	}
+	if want := (ImportSummary{Imported: 1, Skipped: 2, Media: 2, Missing: 1}); summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+
+	threadDir := filepath.Join(root, "1700000000")
+	index, err := os.ReadFile(filepath.Join(threadDir, "index.htm"))
+	if err != nil {
+		t.Fatalf("index.htm was not generated: %v", err)
+	}
+	for _, want := range []string{`href="img/1700000000123.jpg"`, `src="thumb/1700000000123s.jpg"`, "取り込むスレ"} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.htm does not contain %q:\n%s", want, index)
+		}
+	}
+	for _, name := range []string{"img/1700000000123.jpg", "thumb/1700000000123s.jpg", RawHTMLFileName, threadMetadataFile, ".giba/history.log"} {
+		if _, err := os.Stat(filepath.Join(threadDir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s was not created: %v", name, err)
+		}
+	}
+
+	// 監視モードでは、メディア数・レス数が増えない限り再取得しない
+	snapshot, err := LoadThreadSnapshot(threadDir)
+	if err != nil || snapshot == nil {
+		t.Fatalf("LoadThreadSnapshot() = %v, %v", snapshot, err)
+	}
+	if snapshot.LastMediaCount != 2 || snapshot.LastPostCount != 2 || snapshot.CreatedAt == nil {
+		t.Errorf("snapshot = %+v, want media=2 posts=2 with created_at", snapshot)
+	}
+	if NeedsUpdate(snapshot, 2, 2, "") {
+		t.Error("NeedsUpdate() = true for an imported thread without new posts")
+	}
+
+	// 2回目は取り込み済みのためスキップする
+	summary, err = ImportLocalArchive(context.Background(), cfg, "A", src, logger)
+	if err != nil {
+		t.Fatalf("ImportLocalArchive() second run error = %v", err)
+	}
+	if want := (ImportSummary{Skipped: 3}); summary != want {
+		t.Errorf("second summary = %+v, want %+v", summary, want)
+	}
+
+	if _, err := ImportLocalArchive(context.Background(), cfg, "missing", src, logger); err == nil {
+		t.Error("ImportLocalArchive(unknown task) error = nil, want error")
+	}
+}
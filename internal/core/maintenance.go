@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+)
+
+// checkMaintenance は、アダプタが対応している場合に、取得したページ body がメンテナンス中の表示かどうかを判定します。
+// メンテナンス中の場合は errs.ErrMaintenance をラップしたエラーを返します。
+func checkMaintenance(siteAdapter adapter.SiteAdapter, pageURL string, body []byte) error {
+	detector, ok := siteAdapter.(adapter.MaintenanceDetector)
+	if !ok {
+		return nil
+	}
+	if marker, ok := detector.DetectMaintenance(body); ok {
+		return fmt.Errorf("%w: メンテナンス中の表示 '%s' を検出しました (url=%s)", errs.ErrMaintenance, marker, pageURL)
+	}
+	return nil
+}
+
+// maintenanceBackoff は、メンテナンス中の表示の検出が consecutive 回続いた場合に、タスクを一時停止する時間を返します。
+// maintenance_backoff_ms（未設定時は10分）から検出のたびに2倍にし、その8倍を上限とします。
+func maintenanceBackoff(task config.Task, consecutive int) time.Duration {
+	base := time.Duration(task.MaintenanceBackoffMillis) * time.Millisecond
+	if base <= 0 {
+		base = config.DefaultMaintenanceBackoffMillis * time.Millisecond
+	}
+	wait := base
+	for i := 1; i < consecutive && wait < 8*base; i++ {
+		wait *= 2
+	}
+	return min(wait, 8*base)
+}
+
+// pauseForMaintenance は、メンテナンス中の表示を検出したことをログとUIに通知し、maintenanceBackoff の時間だけタスクを一時停止します。
+// 待機中にシャットダウンした場合は ctx.Err() を返します。
+func pauseForMaintenance(ctx context.Context, task config.Task, err error, consecutive int, isWatchMode bool, statusCh chan<- AppStatus, logger *log.Logger) error {
+	wait := maintenanceBackoff(task, consecutive)
+	resumeAt := time.Now().Add(wait)
+	logger.Printf("WARNING: 掲示板がメンテナンス中のため、タスクを %v 一時停止します（再開予定: %s）: %v", wait.Round(time.Second), resumeAt.Format("15:04:05"), err)
+	rec := sharedErrorHistory.record(task.TaskName, "", ErrorClassMaintenance, err)
+	sharedHealthMonitor.beat(task.TaskName, wait)
+	if statusCh != nil {
+		statusCh <- AppStatus{
+			TaskName:   task.TaskName,
+			State:      StatePaused,
+			Detail:     fmt.Sprintf("掲示板のメンテナンス中のため一時停止しています（%s に再開）", resumeAt.Format("15:04")),
+			IsWatching: isWatchMode,
+			Error:      &rec,
+		}
+	}
+	return sleepContext(ctx, wait)
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+func TestMaintenanceBackoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		backoffMs   int
+		consecutive int
+		want        time.Duration
+	}{
+		{name: "既定値", consecutive: 1, want: 10 * time.Minute},
+		{name: "2回目は2倍", backoffMs: 60000, consecutive: 2, want: 2 * time.Minute},
+		{name: "上限は8倍", backoffMs: 60000, consecutive: 10, want: 8 * time.Minute},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			task := config.Task{MaintenanceBackoffMillis: tt.backoffMs}
+			if got := maintenanceBackoff(task, tt.consecutive); got != tt.want {
+				t.Errorf("maintenanceBackoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenancePage(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>Service Temporarily Unavailable</body></html>`))
+	}))
+	defer server.Close()
+
+	task := config.Task{
+		TaskName:          "maintenance",
+		SiteAdapter:       "futaba",
+		TargetBoardURL:    server.URL + "/b/",
+		SaveRootDirectory: t.TempDir(),
+	}
+	client, err := network.NewClient(config.NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	siteAdapter := adapter.NewFutabaAdapter()
+
+	// カタログ・スレッドとも、メンテナンスの表示を処理せずに errs.ErrMaintenance を返す
+	if _, err := fetchCatalog(context.Background(), task, client, siteAdapter); !errors.Is(err, errs.ErrMaintenance) {
+		t.Errorf("fetchCatalog() error = %v, want ErrMaintenance", err)
+	}
+	result := ArchiveSingleThread(context.Background(), client, siteAdapter, task, model.ThreadInfo{ID: "123", URL: "res/123.htm"}, log.New(io.Discard, "", 0))
+	if !errors.Is(result.Error, errs.ErrMaintenance) {
+		t.Errorf("ArchiveSingleThread() error = %v, want ErrMaintenance", result.Error)
+	}
+	if classifyError(result.Error) != ErrorClassMaintenance {
+		t.Errorf("classifyError() = %v, want %v", classifyError(result.Error), ErrorClassMaintenance)
+	}
+}
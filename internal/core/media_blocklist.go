@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// mediaBlocklist は、ダウンロードしない既知の広告・スパム画像のパターンです。
+type mediaBlocklist struct {
+	hashPrefixes []string         // SHA-256（16進数・小文字）の先頭部分
+	urlPatterns  []*regexp.Regexp // メディアのURLに一致する正規表現
+}
+
+// compiledBlocklists は、パターンの組み合わせごとにコンパイル済みのブロックリストを保持します。
+// ファイルごとに正規表現をコンパイルし直さないよう、プロセス全体で共有します。
+var compiledBlocklists sync.Map // map[string]*mediaBlocklist
+
+// newMediaBlocklist は、blocked_media_patterns の各パターンからブロックリストを生成します。
+// "sha256:" で始まるパターンは内容のハッシュの先頭一致、それ以外はURLの正規表現として扱います。
+func newMediaBlocklist(patterns []string) (*mediaBlocklist, error) {
+	b := &mediaBlocklist{}
+	for _, pattern := range patterns {
+		if hash, ok := strings.CutPrefix(pattern, config.BlockedMediaHashPrefix); ok {
+			b.hashPrefixes = append(b.hashPrefixes, strings.ToLower(hash))
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("URLの正規表現 %q を解析できません: %w", pattern, err)
+		}
+		b.urlPatterns = append(b.urlPatterns, re)
+	}
+	return b, nil
+}
+
+// taskMediaBlocklist は、設定全体とタスクの blocked_media_patterns を合わせたブロックリストを返します。
+// パターンが1つもない場合は nil を返します。
+func taskMediaBlocklist(task config.Task) (*mediaBlocklist, error) {
+	patterns := append(append([]string(nil), task.GlobalBlockedMediaPatterns...), task.BlockedMediaPatterns...)
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	key := strings.Join(patterns, "\x00")
+	if cached, ok := compiledBlocklists.Load(key); ok {
+		return cached.(*mediaBlocklist), nil
+	}
+	b, err := newMediaBlocklist(patterns)
+	if err != nil {
+		return nil, err
+	}
+	compiledBlocklists.Store(key, b)
+	return b, nil
+}
+
+// blocksURL は、URLがブロック対象の正規表現に一致するかを判定します。
+func (b *mediaBlocklist) blocksURL(mediaURL string) bool {
+	if b == nil {
+		return false
+	}
+	for _, re := range b.urlPatterns {
+		if re.MatchString(mediaURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHashes は、内容のハッシュによるパターンがあるかを返します。
+func (b *mediaBlocklist) hasHashes() bool {
+	return b != nil && len(b.hashPrefixes) > 0
+}
+
+// blocksHash は、SHA-256（16進数）がブロック対象のハッシュに一致するかを判定します。
+func (b *mediaBlocklist) blocksHash(sum string) bool {
+	if b == nil || sum == "" {
+		return false
+	}
+	sum = strings.ToLower(sum)
+	for _, prefix := range b.hashPrefixes {
+		if strings.HasPrefix(sum, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// partitionBlockedMedia は、ブロック対象のメディアを取り除いた一覧と、取り除いたメディアを返します。
+// URLがブロックリストの正規表現に一致するもの、knownBlocked（過去にハッシュで検出したURLとそのハッシュ）のうち
+// 現在もブロックリストのハッシュに一致するもの、Blocked が設定済みのものをブロック対象とし、返すメディアには Blocked を設定します。
+func partitionBlockedMedia(mediaFiles []model.MediaInfo, blocklist *mediaBlocklist, knownBlocked map[string]string) (kept, blocked []model.MediaInfo) {
+	kept = make([]model.MediaInfo, 0, len(mediaFiles))
+	for _, m := range mediaFiles {
+		sum, known := knownBlocked[m.URL]
+		if m.Blocked || (known && blocklist.blocksHash(sum)) || blocklist.blocksURL(m.URL) {
+			if known && m.SHA256 == "" {
+				m.SHA256 = sum
+			}
+			m.Blocked = true
+			blocked = append(blocked, m)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept, blocked
+}
+
+// hashBlockedMedia は、ブロックしたメディアのうち、内容のハッシュで検出したもののURLとハッシュを返します。
+// スナップショットに記録し、次回以降は同じURLを再びダウンロードしないようにするために使用します。
+func hashBlockedMedia(blocklist *mediaBlocklist, blocked []model.MediaInfo) map[string]string {
+	var known map[string]string
+	for _, m := range blocked {
+		if !blocklist.blocksHash(m.SHA256) {
+			continue
+		}
+		if known == nil {
+			known = make(map[string]string)
+		}
+		known[m.URL] = m.SHA256
+	}
+	return known
+}
+
+// blockByContentHash は、ダウンロード済みのファイルの内容がブロック対象のハッシュに一致する場合にファイルを削除し、
+// メディアに Blocked を設定します。ブロックした場合に true を返します。
+func blockByContentHash(blocklist *mediaBlocklist, media *model.MediaInfo, path string) (bool, error) {
+	if !blocklist.hasHashes() {
+		return false, nil
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return false, err
+	}
+	if !blocklist.blocksHash(sum) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil {
+		return false, fmt.Errorf("ブロック対象のファイルの削除に失敗しました (path=%s): %w", path, err)
+	}
+	media.SHA256 = sum
+	media.Blocked = true
+	media.LocalPath = ""
+	return true, nil
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestPartitionBlockedMedia(t *testing.T) {
+	t.Parallel()
+
+	blocklist, err := taskMediaBlocklist(config.Task{
+		GlobalBlockedMediaPatterns: []string{`/ad/`},
+		BlockedMediaPatterns:       []string{"sha256:ABCDEF12"},
+	})
+	if err != nil {
+		t.Fatalf("taskMediaBlocklist() error = %v", err)
+	}
+
+	media := []model.MediaInfo{
+		{URL: "https://example.com/b/src/1.jpg"},
+		{URL: "https://example.com/ad/banner.png"},
+		{URL: "https://example.com/b/src/2.jpg"},
+		{URL: "https://example.com/b/src/3.jpg"},
+	}
+	known := map[string]string{
+		"https://example.com/b/src/2.jpg": "abcdef1234",   // 現在のパターンに一致する
+		"https://example.com/b/src/3.jpg": "0123456789ab", // パターンから削除されたハッシュ
+	}
+
+	kept, blocked := partitionBlockedMedia(media, blocklist, known)
+	if len(kept) != 2 || kept[0].URL != media[0].URL || kept[1].URL != media[3].URL {
+		t.Errorf("kept = %+v", kept)
+	}
+	if len(blocked) != 2 || !blocked[0].Blocked || blocked[1].SHA256 != "abcdef1234" {
+		t.Fatalf("blocked = %+v", blocked)
+	}
+
+	// URLの正規表現で除外したものは、スナップショットに記録しない
+	got := hashBlockedMedia(blocklist, blocked)
+	if len(got) != 1 || got["https://example.com/b/src/2.jpg"] != "abcdef1234" {
+		t.Errorf("hashBlockedMedia() = %v", got)
+	}
+
+	if _, err := taskMediaBlocklist(config.Task{BlockedMediaPatterns: []string{"("}}); err == nil {
+		t.Error("不正な正規表現でエラーになりません")
+	}
+	if b, err := taskMediaBlocklist(config.Task{}); b != nil || err != nil {
+		t.Errorf("パターンがない場合は nil を返すはずです: %v, %v", b, err)
+	}
+}
+
+func TestBlockByContentHash(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("spam image")
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		patterns    []string
+		wantBlocked bool
+	}{
+		{name: "ハッシュに一致", patterns: []string{"sha256:" + hash[:12]}, wantBlocked: true},
+		{name: "ハッシュに不一致", patterns: []string{"sha256:00000000"}, wantBlocked: false},
+		{name: "URLのパターンのみ", patterns: []string{`spam`}, wantBlocked: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "1.jpg")
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			blocklist, err := newMediaBlocklist(tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			media := model.MediaInfo{URL: "https://example.com/b/src/1.jpg", LocalPath: path}
+			blocked, err := blockByContentHash(blocklist, &media, path)
+			if err != nil {
+				t.Fatalf("blockByContentHash() error = %v", err)
+			}
+			if blocked != tt.wantBlocked || media.Blocked != tt.wantBlocked {
+				t.Errorf("blocked = %v, media.Blocked = %v, want %v", blocked, media.Blocked, tt.wantBlocked)
+			}
+			_, statErr := os.Stat(path)
+			if tt.wantBlocked != os.IsNotExist(statErr) {
+				t.Errorf("ファイルの削除状態が不正です: %v", statErr)
+			}
+		})
+	}
+}
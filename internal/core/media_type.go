@@ -0,0 +1,147 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// アニメーションサムネイルの処理モード
+const (
+	AnimatedThumbnailCopy = "copy" // フルサイズのアニメーション画像を thumb/ にコピーし、サムネイルとして使用する
+	AnimatedThumbnailMark = "mark" // HTML上でマークし、マウスオーバー時にフルサイズを表示する
+)
+
+var (
+	gifMagic  = []byte("GIF8")
+	pngMagic  = []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+	riffMagic = []byte("RIFF")
+	webpMagic = []byte("WEBP")
+	// GIFのGraphic Control Extension (フレームごとに1つ出現する)
+	gifGraphicControl = []byte{0x00, 0x21, 0xF9, 0x04}
+)
+
+// animatedImageExtensions は、アニメーション画像の可能性がある拡張子です。これ以外のファイルは読み込まずに判定を省略します。
+var animatedImageExtensions = map[string]bool{".gif": true, ".png": true, ".apng": true, ".webp": true}
+
+// animatedHeaderLimit は、APNG の acTL・WebP の ANIM を探すために読み込むファイル先頭の最大サイズです。
+// いずれも画像データより前に置かれるため、先頭だけで判定できます。
+const animatedHeaderLimit = 64 * 1024
+
+// IsAnimatedImage は、ファイル内容からアニメーション画像かどうかを判定します。
+// 完全なデコードは行わず、GIFはフレーム制御ブロックの数、APNGは IDAT より前の acTL チャンク、
+// WebP は ANIM チャンクの有無で判定します。
+func IsAnimatedImage(data []byte) bool {
+	switch {
+	case bytes.HasPrefix(data, gifMagic):
+		return bytes.Count(data, gifGraphicControl) > 1
+	case bytes.HasPrefix(data, pngMagic):
+		actl := bytes.Index(data, []byte("acTL"))
+		idat := bytes.Index(data, []byte("IDAT"))
+		return actl >= 0 && (idat < 0 || actl < idat)
+	case len(data) >= 12 && bytes.HasPrefix(data, riffMagic) && bytes.Equal(data[8:12], webpMagic):
+		return bytes.Contains(data, []byte("ANIM"))
+	default:
+		return false
+	}
+}
+
+// applyAnimatedThumbnail は、ダウンロード済みのフルサイズファイルがアニメーション画像であれば
+// animated_thumbnail_mode に従ってサムネイルを差し替え、またはマークします。
+func applyAnimatedThumbnail(task config.Task, media *model.MediaInfo, thumbSavePath string, logger *log.Logger) {
+	if task.AnimatedThumbnailMode == "" || media.LocalPath == "" {
+		return
+	}
+
+	if !animatedImageExtensions[strings.ToLower(filepath.Ext(media.LocalPath))] {
+		return
+	}
+	animated, err := isAnimatedFile(media.LocalPath)
+	if err != nil {
+		// フルサイズ未取得（サムネイルのみモードやダウンロード失敗）の場合は何もしない
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Printf("WARNING: アニメーション画像の判定に失敗しました (path=%s): %v", media.LocalPath, err)
+		}
+		return
+	}
+	if !animated {
+		return
+	}
+	media.IsAnimated = true
+
+	switch task.AnimatedThumbnailMode {
+	case AnimatedThumbnailCopy:
+		dest := filepath.Join(thumbSavePath, filepath.Base(media.LocalPath))
+		if err := copyFile(media.LocalPath, dest); err != nil {
+			logger.Printf("WARNING: アニメーション画像のサムネイル位置へのコピーに失敗しました (src=%s, dest=%s): %v", media.LocalPath, dest, err)
+			return
+		}
+		media.LocalThumbPath = dest
+	case AnimatedThumbnailMark:
+		// マークは再構成時にアダプタが IsAnimated を参照して行う
+	default:
+		logger.Printf("WARNING: 不明な animated_thumbnail_mode '%s' です。無視します。", task.AnimatedThumbnailMode)
+	}
+}
+
+// isAnimatedFile は、path のファイルがアニメーション画像かどうかを、ファイル全体をメモリに読み込まずに判定します。
+// 暗号化されたファイルは復号しながら読み込みます。
+func isAnimatedFile(path string) (bool, error) {
+	f, err := archivecrypt.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, animatedHeaderLimit))
+	if err != nil {
+		return false, fmt.Errorf("ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	if !bytes.HasPrefix(head, gifMagic) {
+		return IsAnimatedImage(head), nil
+	}
+	// GIFの2つ目のフレームは1つ目の画像データの後にあるため、先頭に続けて順に読み込んで探す
+	animated, err := gifHasMultipleFrames(io.MultiReader(bytes.NewReader(head), f))
+	if err != nil {
+		return false, fmt.Errorf("ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	return animated, nil
+}
+
+// gifHasMultipleFrames は、r を先頭から読み込み、GIFのフレーム制御ブロックが2つ以上あるかを判定します。
+// 2つ目が見つかった時点で読み込みを終えます。
+func gifHasMultipleFrames(r io.Reader) (bool, error) {
+	buf := make([]byte, 32*1024)
+	// ブロックの区切りが読み込みの境界をまたぐ場合に備え、前回の末尾を残して続けて探す
+	keep := len(gifGraphicControl) - 1
+	window := make([]byte, 0, keep+len(buf))
+	count := 0
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			window = append(window, buf[:n]...)
+			count += bytes.Count(window, gifGraphicControl)
+			if count > 1 {
+				return true, nil
+			}
+			if len(window) > keep {
+				window = append(window[:0], window[len(window)-keep:]...)
+			}
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
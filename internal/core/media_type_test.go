@@ -0,0 +1,125 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestIsAnimatedImage(t *testing.T) {
+	t.Parallel()
+
+	gifFrame := []byte{0x00, 0x21, 0xF9, 0x04, 0x00, 0x0a, 0x00, 0x00}
+	pngHeader := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"静止GIF", append([]byte("GIF89a"), gifFrame...), false},
+		{"アニメーションGIF", append(append([]byte("GIF89a"), gifFrame...), gifFrame...), true},
+		{"静止PNG", append(append([]byte{}, pngHeader...), []byte("IHDR....IDAT")...), false},
+		{"APNG", append(append([]byte{}, pngHeader...), []byte("IHDR....acTL....IDAT")...), true},
+		{"静止WebP", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), false},
+		{"アニメーションWebP", []byte("RIFF\x00\x00\x00\x00WEBPVP8XANIM"), true},
+		{"JPEG", []byte{0xFF, 0xD8, 0xFF, 0xE0}, false},
+		{"空", nil, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsAnimatedImage(tt.data); got != tt.want {
+				t.Errorf("IsAnimatedImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyAnimatedThumbnail(t *testing.T) {
+	t.Parallel()
+
+	gifFrame := []byte{0x00, 0x21, 0xF9, 0x04, 0x00, 0x0a, 0x00, 0x00}
+	animatedGIF := append(append([]byte("GIF89a"), gifFrame...), gifFrame...)
+	// 2つ目のフレームが先頭の読み込み範囲より後にあるGIF
+	largeGIF := append(append(append([]byte("GIF89a"), gifFrame...), make([]byte, animatedHeaderLimit*2)...), gifFrame...)
+	staticGIF := append([]byte("GIF89a"), gifFrame...)
+
+	tests := []struct {
+		name         string
+		mode         string
+		file         string
+		data         []byte
+		wantAnimated bool
+		wantCopied   bool
+	}{
+		{"copyモードはサムネイル位置にコピー", AnimatedThumbnailCopy, "1.gif", animatedGIF, true, true},
+		{"markモードはマークのみ", AnimatedThumbnailMark, "1.gif", animatedGIF, true, false},
+		{"2つ目のフレームが後方にあるGIF", AnimatedThumbnailCopy, "1.gif", largeGIF, true, true},
+		{"静止画像は対象外", AnimatedThumbnailCopy, "1.gif", staticGIF, false, false},
+		{"対象外の拡張子は判定しない", AnimatedThumbnailCopy, "1.jpg", animatedGIF, false, false},
+		{"無効", "", "1.gif", animatedGIF, false, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			src := filepath.Join(dir, "img", tt.file)
+			thumbDir := filepath.Join(dir, "thumb")
+			for _, d := range []string{filepath.Dir(src), thumbDir} {
+				if err := os.MkdirAll(d, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(src, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			media := model.MediaInfo{LocalPath: src}
+			applyAnimatedThumbnail(config.Task{AnimatedThumbnailMode: tt.mode}, &media, thumbDir, log.New(io.Discard, "", 0))
+			if media.IsAnimated != tt.wantAnimated {
+				t.Errorf("IsAnimated = %v, want %v", media.IsAnimated, tt.wantAnimated)
+			}
+			dest := filepath.Join(thumbDir, tt.file)
+			_, err := os.Stat(dest)
+			if copied := err == nil; copied != tt.wantCopied {
+				t.Errorf("サムネイル位置へのコピー = %v, want %v", copied, tt.wantCopied)
+			}
+			if tt.wantCopied && media.LocalThumbPath != dest {
+				t.Errorf("LocalThumbPath = %q, want %q", media.LocalThumbPath, dest)
+			}
+		})
+	}
+}
+
+func TestGIFHasMultipleFrames_AcrossReads(t *testing.T) {
+	t.Parallel()
+
+	// フレーム制御ブロックが読み込みの境界をまたいでも数える
+	data := append([]byte("GIF89a"), gifGraphicControl...)
+	data = append(data, make([]byte, 32*1024-len(data)-2)...)
+	data = append(data, gifGraphicControl...)
+	got, err := gifHasMultipleFrames(iotest.OneByteReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("gifHasMultipleFrames() error = %v", err)
+	}
+	if !got {
+		t.Error("gifHasMultipleFrames() = false, want true")
+	}
+	got, err = gifHasMultipleFrames(bytes.NewReader(data))
+	if err != nil || !got {
+		t.Errorf("gifHasMultipleFrames() = %v, %v, want true, nil", got, err)
+	}
+}
@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/mirror"
+)
+
+// MirrorVerificationReportPath は、直近のミラーとの照合で見つかった差分の一覧を保存するファイルです（カレントディレクトリ基準）。
+const MirrorVerificationReportPath = "mirror_verification_report.json"
+
+// ミラーとの差分の種類
+const (
+	MirrorMissing        = "missing_on_mirror" // ローカルにあり、ミラーにない
+	MirrorExtra          = "missing_locally"   // ミラーにあり、ローカルにない
+	MirrorContentDiffers = "content_differs"   // 両方にあるが、サイズまたはハッシュが異なる
+)
+
+// MirrorDifference は、ローカルのアーカイブとミラーの1ファイルの差分です。
+type MirrorDifference struct {
+	TaskName string `json:"task_name"`
+	// Path は、タスクの保存先（ミラーでは保存先のディレクトリ名）からのスラッシュ区切りの相対パスです。
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// MirrorVerificationReport は、ミラーとの照合の結果です。
+type MirrorVerificationReport struct {
+	GeneratedAt  time.Time          `json:"generated_at"`
+	Mirror       string             `json:"mirror"`
+	TotalChecked int                `json:"total_checked"` // 照合したファイル数（ローカルとミラーの和集合）
+	Differences  []MirrorDifference `json:"differences"`
+}
+
+// RunMirrorVerification は、filter に一致するタスクのアーカイブを target のミラーと照合し、差分を報告します。
+// ミラーには、各タスクの保存先（save_root_directory）をそのディレクトリ名で複製してある前提です
+// （例: 保存先が D:\archive\futaba_b なら、ミラーの futaba_b/ 以下）。
+// 照合は読み取りのみで、ローカル・ミラーのどちらのファイルも変更しません。
+// 期間の指定は、ローカルにあるスレッドの最終更新日時で判定します（ミラーにのみあるスレッドは常に対象）。
+func RunMirrorVerification(ctx context.Context, cfg *config.Config, filter VerificationFilter, target string) error {
+	store, err := mirror.Open(target)
+	if err != nil {
+		return err
+	}
+	log.Printf("ミラーとの照合を開始します (ミラー: %s)", store)
+	if filter.hasPeriod() {
+		log.Printf("対象期間: %s 〜 %s (スレッドの最終更新日時)", formatFilterTime(filter.Since), formatFilterTime(filter.Until))
+	}
+
+	report := &MirrorVerificationReport{Mirror: store.String()}
+	for _, task := range cfg.Tasks {
+		if filter.TaskName != "" && task.TaskName != filter.TaskName {
+			continue
+		}
+		log.Printf("タスク '%s' をミラーと照合します...", task.TaskName)
+		checked, diffs, err := compareTaskWithMirror(ctx, task, store, filter)
+		report.TotalChecked += checked
+		report.Differences = append(report.Differences, diffs...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("ERROR: タスク '%s' のミラーとの照合中にエラーが発生しました: %v", task.TaskName, err)
+		}
+	}
+
+	report.GeneratedAt = time.Now()
+	if err := SaveMirrorVerificationReport(MirrorVerificationReportPath, report); err != nil {
+		log.Printf("ERROR: ミラーとの照合結果の保存に失敗しました: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, d := range report.Differences {
+		counts[d.Kind]++
+	}
+	log.Println("========================================")
+	log.Println("ミラーとの照合完了")
+	log.Printf("照合したファイル数: %d", report.TotalChecked)
+	log.Printf("ミラーにないファイル: %d", counts[MirrorMissing])
+	log.Printf("ローカルにないファイル: %d", counts[MirrorExtra])
+	log.Printf("内容が異なるファイル: %d", counts[MirrorContentDiffers])
+	if len(report.Differences) > 0 {
+		log.Println("詳細:")
+		for _, d := range report.Differences {
+			line := fmt.Sprintf("[%s] %s: %s", d.TaskName, d.Kind, d.Path)
+			if d.Detail != "" {
+				line += " (" + d.Detail + ")"
+			}
+			log.Println(line)
+		}
+		log.Printf("差分の一覧は %s に保存しました。", MirrorVerificationReportPath)
+	}
+	log.Println("========================================")
+	return nil
+}
+
+// compareTaskWithMirror は、タスクの保存先とミラーの同名のディレクトリを照合し、照合したファイル数と差分を返します。
+// サイズが同じファイルは内容のハッシュで比較します（ミラーの一覧に MD5 がある場合は MD5、それ以外は SHA-256）。
+// 暗号化したアーカイブも、保存されているバイト列のまま比較します。
+func compareTaskWithMirror(ctx context.Context, task config.Task, store mirror.Store, filter VerificationFilter) (int, []MirrorDifference, error) {
+	if task.SaveRootDirectory == "" {
+		return 0, nil, fmt.Errorf("タスク '%s' の save_root_directory が設定されていません", task.TaskName)
+	}
+	local, err := listMirrorableFiles(task.SaveRootDirectory)
+	if err != nil {
+		return 0, nil, err
+	}
+	remoteDir := filepath.Base(filepath.Clean(task.SaveRootDirectory))
+	remoteEntries, err := store.List(ctx, remoteDir)
+	if err != nil {
+		return 0, nil, fmt.Errorf("ミラーの一覧の取得に失敗しました: %w", err)
+	}
+	remote := make(map[string]mirror.Entry, len(remoteEntries))
+	for name, entry := range remoteEntries {
+		rel := strings.TrimPrefix(name, remoteDir+"/")
+		if isMirrorableName(rel) {
+			remote[rel] = entry
+		}
+	}
+
+	// 期間外のスレッドのファイルは、ローカル・ミラーのどちらにあっても照合しない
+	threadIncluded := make(map[string]bool)
+	included := func(rel string) bool {
+		if !filter.hasPeriod() {
+			return true
+		}
+		top, _, nested := strings.Cut(rel, "/")
+		if !nested {
+			return true // 保存先の直下のファイル（一覧ページなど）
+		}
+		if ok, seen := threadIncluded[top]; seen {
+			return ok
+		}
+		ok := true
+		if updatedAt, found := threadUpdatedAt(filepath.Join(task.SaveRootDirectory, top)); found {
+			ok = filter.includes(updatedAt)
+		}
+		threadIncluded[top] = ok
+		return ok
+	}
+
+	names := make([]string, 0, len(local)+len(remote))
+	for name := range local {
+		names = append(names, name)
+	}
+	for name := range remote {
+		if _, ok := local[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	checked := 0
+	var diffs []MirrorDifference
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return checked, diffs, err
+		}
+		if !included(name) {
+			continue
+		}
+		checked++
+		localSize, inLocal := local[name]
+		remoteEntry, inRemote := remote[name]
+		diff := MirrorDifference{TaskName: task.TaskName, Path: name}
+		switch {
+		case !inRemote:
+			diff.Kind = MirrorMissing
+		case !inLocal:
+			diff.Kind = MirrorExtra
+		case localSize != remoteEntry.Size:
+			diff.Kind = MirrorContentDiffers
+			diff.Detail = fmt.Sprintf("サイズ: ローカル %d バイト, ミラー %d バイト", localSize, remoteEntry.Size)
+		default:
+			same, err := sameMirrorContent(ctx, store, filepath.Join(task.SaveRootDirectory, filepath.FromSlash(name)), remoteDir+"/"+name, remoteEntry)
+			if err != nil {
+				if ctx.Err() != nil {
+					return checked, diffs, ctx.Err()
+				}
+				log.Printf("WARNING: %s の照合に失敗しました: %v", name, err)
+				diff.Kind = MirrorContentDiffers
+				diff.Detail = fmt.Sprintf("照合に失敗しました: %v", err)
+			} else if !same {
+				diff.Kind = MirrorContentDiffers
+				diff.Detail = "ハッシュが異なります"
+			}
+		}
+		if diff.Kind != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	return checked, diffs, nil
+}
+
+// listMirrorableFiles は、root 以下の照合の対象のファイルを、スラッシュ区切りの相対パスとサイズの組で返します。
+func listMirrorableFiles(root string) (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if !isMirrorableName(filepath.ToSlash(rel)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存先 '%s' の一覧の取得に失敗しました: %w", root, err)
+	}
+	return files, nil
+}
+
+// isMirrorableName は、相対パス rel のファイルが照合の対象かを判定します。
+// 内部状態（.giba、.resume.json など . で始まるもの）と書き込み途中の一時ファイル（.tmp）は、複製の時点で異なるのが普通のため対象外です。
+func isMirrorableName(rel string) bool {
+	if rel == "" || strings.HasSuffix(rel, ".tmp") {
+		return false
+	}
+	for _, part := range strings.Split(rel, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMirrorContent は、ローカルのファイル localPath とミラーのファイル remoteName の内容が同じかを返します。
+func sameMirrorContent(ctx context.Context, store mirror.Store, localPath, remoteName string, remoteEntry mirror.Entry) (bool, error) {
+	newHash := sha256.New
+	if remoteEntry.MD5 != "" {
+		newHash = md5.New
+	}
+	localSum, err := hashFile(localPath, newHash())
+	if err != nil {
+		return false, err
+	}
+	if remoteEntry.MD5 != "" {
+		return localSum == remoteEntry.MD5, nil
+	}
+
+	r, err := store.Open(ctx, remoteName)
+	if err != nil {
+		if errors.Is(err, mirror.ErrNotExist) {
+			return false, fmt.Errorf("一覧の取得後にミラーから削除されました")
+		}
+		return false, err
+	}
+	defer r.Close()
+	h := newHash()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, fmt.Errorf("ミラーのファイルの読み込みに失敗しました: %w", err)
+	}
+	return localSum == hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile は、ファイルの内容（暗号化されている場合も復号しないバイト列）のハッシュを16進数で返します。
+func hashFile(path string, h hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("ハッシュの計算に失敗しました (path=%s): %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SaveMirrorVerificationReport は、ミラーとの照合結果をJSONファイルとして保存します。
+func SaveMirrorVerificationReport(path string, report *MirrorVerificationReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("ミラーとの照合結果のシリアライズに失敗しました: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("ミラーとの照合結果の書き込みに失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
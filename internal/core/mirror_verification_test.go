@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/mirror"
+)
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCompareTaskWithMirror(t *testing.T) {
+	t.Parallel()
+
+	localRoot := filepath.Join(t.TempDir(), "futaba_b")
+	writeTestFiles(t, localRoot, map[string]string{
+		"index.htm":          "list",
+		"100/index.htm":      "same",
+		"100/img/1.jpg":      "local only",
+		"100/img/2.jpg":      "aaaa",
+		"100/img/3.jpg":      "size",
+		"100/.resume.json":   "[]",
+		"100/index.htm.tmp":  "partial",
+		".giba/pending.json": "{}",
+		"200/index.htm":      "old thread",
+		"200/thread.json":    `{"thread_id": "200", "updated_at": "2020-01-01T00:00:00Z"}`,
+	})
+	mirrorRoot := t.TempDir()
+	writeTestFiles(t, filepath.Join(mirrorRoot, "futaba_b"), map[string]string{
+		"index.htm":          "list",
+		"100/index.htm":      "same",
+		"100/img/2.jpg":      "bbbb",
+		"100/img/3.jpg":      "different size",
+		"100/img/4.jpg":      "mirror only",
+		".giba/pending.json": "{\"x\":1}",
+		"300/index.htm":      "deleted locally",
+	})
+
+	store, err := mirror.Open(mirrorRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := config.Task{TaskName: "b", SaveRootDirectory: localRoot}
+
+	tests := []struct {
+		name        string
+		filter      VerificationFilter
+		wantChecked int
+		want        []MirrorDifference
+	}{
+		{
+			name:        "期間の指定なし",
+			wantChecked: 9,
+			want: []MirrorDifference{
+				{TaskName: "b", Path: "100/img/1.jpg", Kind: MirrorMissing},
+				{TaskName: "b", Path: "100/img/2.jpg", Kind: MirrorContentDiffers, Detail: "ハッシュが異なります"},
+				{TaskName: "b", Path: "100/img/3.jpg", Kind: MirrorContentDiffers, Detail: "サイズ: ローカル 4 バイト, ミラー 14 バイト"},
+				{TaskName: "b", Path: "100/img/4.jpg", Kind: MirrorExtra},
+				{TaskName: "b", Path: "200/index.htm", Kind: MirrorMissing},
+				{TaskName: "b", Path: "200/thread.json", Kind: MirrorMissing},
+				{TaskName: "b", Path: "300/index.htm", Kind: MirrorExtra},
+			},
+		},
+		{
+			// スレッド 200 は thread.json の updated_at が期間外。ローカルにないスレッド 300 は常に対象
+			name:        "期間外のスレッドは対象外",
+			filter:      VerificationFilter{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			wantChecked: 7,
+			want: []MirrorDifference{
+				{TaskName: "b", Path: "100/img/1.jpg", Kind: MirrorMissing},
+				{TaskName: "b", Path: "100/img/2.jpg", Kind: MirrorContentDiffers, Detail: "ハッシュが異なります"},
+				{TaskName: "b", Path: "100/img/3.jpg", Kind: MirrorContentDiffers, Detail: "サイズ: ローカル 4 バイト, ミラー 14 バイト"},
+				{TaskName: "b", Path: "100/img/4.jpg", Kind: MirrorExtra},
+				{TaskName: "b", Path: "300/index.htm", Kind: MirrorExtra},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			checked, diffs, err := compareTaskWithMirror(context.Background(), task, store, tt.filter)
+			if err != nil {
+				t.Fatalf("compareTaskWithMirror() error = %v", err)
+			}
+			if checked != tt.wantChecked {
+				t.Errorf("checked = %d, want %d", checked, tt.wantChecked)
+			}
+			if !reflect.DeepEqual(diffs, tt.want) {
+				t.Errorf("diffs = %+v\nwant %+v", diffs, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMirrorableName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{rel: "123/index.htm", want: true},
+		{rel: "123/img/1.jpg", want: true},
+		{rel: ".giba/pending.json", want: false},
+		{rel: "123/.resume.json", want: false},
+		{rel: "123/raw.html.gz.tmp", want: false},
+		{rel: "", want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.rel, func(t *testing.T) {
+			t.Parallel()
+			if got := isMirrorableName(tt.rel); got != tt.want {
+				t.Errorf("isMirrorableName(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// 保存先ディレクトリ名の衝突ポリシー（naming_conflict_policy の値）
+const (
+	// NamingPolicyByID は、一度作成したスレッドのディレクトリをタイトルが変わっても使い続けます。
+	NamingPolicyByID = "id"
+	// NamingPolicyRenameFollow は、既存のディレクトリを新しいタイトルに合わせてリネームします。
+	NamingPolicyRenameFollow = "rename"
+	// NamingPolicyDuplicate は、従来通りタイトルごとに別のディレクトリを作成します。
+	NamingPolicyDuplicate = "duplicate"
+)
+
+// threadDirIndex は、保存ルートごとに「スレッドID → ディレクトリ一覧」を保持するインデックスです。
+// 初回参照時に保存ルート配下の .snapshot.json を走査して構築し、以降はメモリ上で更新します。
+type threadDirIndex struct {
+	mu    sync.Mutex
+	roots map[string]map[string][]string
+}
+
+// sharedThreadDirIndex は、プロセス内の全タスクで共有されるインデックスです。
+var sharedThreadDirIndex = &threadDirIndex{roots: make(map[string]map[string][]string)}
+
+// lookup は、指定されたスレッドIDのディレクトリ一覧を返します。
+func (x *threadDirIndex) lookup(rootDir, threadID string) ([]string, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	dirs, err := x.rootLocked(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), dirs[threadID]...), nil
+}
+
+// set は、指定されたスレッドIDのディレクトリを dir のみに置き換えます。
+func (x *threadDirIndex) set(rootDir, threadID, dir string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if dirs, ok := x.roots[rootDir]; ok {
+		dirs[threadID] = []string{dir}
+	}
+}
+
+func (x *threadDirIndex) rootLocked(rootDir string) (map[string][]string, error) {
+	if dirs, ok := x.roots[rootDir]; ok {
+		return dirs, nil
+	}
+	dirs, err := scanThreadDirs(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	x.roots[rootDir] = dirs
+	return dirs, nil
+}
+
+// scanThreadDirs は、保存ルート配下のスナップショットを走査し、スレッドIDごとのディレクトリ一覧を返します。
+func scanThreadDirs(rootDir string) (map[string][]string, error) {
+	dirs := make(map[string][]string)
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == rootDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "img", "thumb", "css":
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != ".snapshot.json" {
+			return nil
+		}
+
+		threadDir := filepath.Dir(path)
+		snapshot, err := LoadThreadSnapshot(threadDir)
+		if err != nil || snapshot == nil || snapshot.ThreadID == "" {
+			return nil // 壊れたスナップショットは無視する
+		}
+		dirs[snapshot.ThreadID] = append(dirs[snapshot.ThreadID], threadDir)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存ディレクトリの走査に失敗しました (root=%s): %w", rootDir, err)
+	}
+	return dirs, nil
+}
+
+// resolveThreadSavePath は、衝突ポリシーに従ってスレッドの保存先ディレクトリを決定します。
+// 同じスレッドIDの既存ディレクトリが別名で存在する場合、ポリシーに応じて再利用・リネーム・統合を行います。
+func resolveThreadSavePath(task config.Task, thread model.ThreadInfo, logger *log.Logger) (string, error) {
+	generated, err := generateDirectoryPath(task.SaveRootDirectory, task.DirectoryFormat, thread, task.MaxTitleLength)
+	if err != nil {
+		return "", err
+	}
+
+	policy := task.NamingConflictPolicy
+	if policy == "" || policy == NamingPolicyDuplicate || thread.ID == "" {
+		return generated, nil
+	}
+	if policy != NamingPolicyByID && policy != NamingPolicyRenameFollow {
+		logger.Printf("WARNING: 不明な naming_conflict_policy '%s' のため 'duplicate' として扱います", policy)
+		return generated, nil
+	}
+
+	existing, err := sharedThreadDirIndex.lookup(task.SaveRootDirectory, thread.ID)
+	if err != nil {
+		logger.Printf("WARNING: 既存ディレクトリの検索に失敗したため、新しいパスを使用します: %v", err)
+		return generated, nil
+	}
+	others := make([]string, 0, len(existing))
+	for _, dir := range existing {
+		if dir != generated {
+			others = append(others, dir)
+		}
+	}
+	if len(others) == 0 {
+		sharedThreadDirIndex.set(task.SaveRootDirectory, thread.ID, generated)
+		return generated, nil
+	}
+
+	target := generated
+	if policy == NamingPolicyByID {
+		target = pickPrimaryThreadDir(existing)
+	}
+
+	if err := mergeThreadDirsInto(target, existing, logger); err != nil {
+		return "", fmt.Errorf("分割されたアーカイブの統合に失敗しました (thread_id=%s, target=%s): %w", thread.ID, target, err)
+	}
+	sharedThreadDirIndex.set(task.SaveRootDirectory, thread.ID, target)
+	return target, nil
+}
+
+// pickPrimaryThreadDir は、統合先とするディレクトリを選びます。
+// 最も多くのメディアを保存済みのディレクトリを優先し、同数の場合はパスの辞書順で決定します。
+func pickPrimaryThreadDir(dirs []string) string {
+	sorted := append([]string(nil), dirs...)
+	sort.Strings(sorted)
+
+	best, bestCount := sorted[0], -1
+	for _, dir := range sorted {
+		count := 0
+		if snapshot, err := LoadThreadSnapshot(dir); err == nil && snapshot != nil {
+			count = snapshot.LastMediaCount
+		}
+		if count > bestCount {
+			best, bestCount = dir, count
+		}
+	}
+	return best
+}
+
+// mergeThreadDirsInto は、同じスレッドの複数のディレクトリを target に統合します。
+// target が存在しない場合は、最初のディレクトリをリネームして target とします。
+func mergeThreadDirsInto(target string, dirs []string, logger *log.Logger) error {
+	sources := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if dir != target {
+			sources = append(sources, dir)
+		}
+	}
+	sort.Strings(sources)
+
+	if _, err := os.Stat(target); os.IsNotExist(err) && len(sources) > 0 {
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(sources[0], target); err != nil {
+			return fmt.Errorf("ディレクトリのリネームに失敗しました (%s -> %s): %w", sources[0], target, err)
+		}
+		logger.Printf("INFO: タイトル変更に合わせてディレクトリをリネームしました: %s -> %s", sources[0], target)
+		sources = sources[1:]
+	}
+
+	for _, src := range sources {
+		if err := mergeThreadDir(target, src); err != nil {
+			return err
+		}
+		logger.Printf("INFO: 分割されたアーカイブを統合しました: %s -> %s", src, target)
+	}
+	return nil
+}
+
+// mergeThreadDir は、src 内のファイルを dst に移動し、src を削除します。
+// 同名のファイルが両方にある場合は、更新日時が新しい方を残します。
+func mergeThreadDir(dst, src string) error {
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(destPath, 0755)
+		}
+
+		if destInfo, err := os.Stat(destPath); err == nil {
+			srcInfo, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !srcInfo.ModTime().After(destInfo.ModTime()) {
+				return nil // dst 側の方が新しいので残す
+			}
+		}
+		if err := os.Rename(path, destPath); err != nil {
+			return fmt.Errorf("ファイルの移動に失敗しました (%s -> %s): %w", path, destPath, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("統合元ディレクトリの削除に失敗しました (path=%s): %w", src, err)
+	}
+	return nil
+}
+
+// ReconcileSplitArchives は、保存ルート配下で同じスレッドIDを持つ複数のディレクトリを検出し、統合します。
+// 統合先の選択は naming_conflict_policy が "id" の場合と同じ規則に従います。統合したスレッド数を返します。
+func ReconcileSplitArchives(rootDir string, logger *log.Logger) (int, error) {
+	dirs, err := scanThreadDirs(rootDir)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(dirs))
+	for id, threadDirs := range dirs {
+		if len(threadDirs) > 1 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	merged := 0
+	for _, id := range ids {
+		target := pickPrimaryThreadDir(dirs[id])
+		if err := mergeThreadDirsInto(target, dirs[id], logger); err != nil {
+			logger.Printf("ERROR: スレッド %s のアーカイブ統合に失敗しました: %v", id, err)
+			continue
+		}
+		sharedThreadDirIndex.set(rootDir, id, target)
+		merged++
+	}
+	return merged, nil
+}
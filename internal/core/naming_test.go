@@ -0,0 +1,102 @@
+package core
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// writeTestThreadDir は、スナップショットと画像1枚を持つスレッドディレクトリを作成します。
+func writeTestThreadDir(t *testing.T, dir, threadID string, mediaCount int, imgName string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "img"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveThreadSnapshot(dir, &ThreadSnapshot{ThreadID: threadID, LastMediaCount: mediaCount}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "img", imgName), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveThreadSavePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		policy     string
+		wantDir    string
+		wantGone   string
+		wantImages []string
+	}{
+		{name: "duplicateは新しいディレクトリを作る", policy: NamingPolicyDuplicate, wantDir: "123_new"},
+		{name: "idは既存ディレクトリを使い続ける", policy: NamingPolicyByID, wantDir: "123_old", wantImages: []string{"a.jpg"}},
+		{name: "renameは既存ディレクトリをリネームする", policy: NamingPolicyRenameFollow, wantDir: "123_new", wantGone: "123_old", wantImages: []string{"a.jpg"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			root := t.TempDir()
+			writeTestThreadDir(t, filepath.Join(root, "123_old"), "123", 1, "a.jpg")
+
+			task := config.Task{SaveRootDirectory: root, DirectoryFormat: "{thread_id}_{thread_title_safe}", NamingConflictPolicy: tt.policy}
+			thread := model.ThreadInfo{ID: "123", Title: "new"}
+			got, err := resolveThreadSavePath(task, thread, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("resolveThreadSavePath() error = %v", err)
+			}
+			if want := filepath.Join(root, tt.wantDir); got != want {
+				t.Errorf("resolveThreadSavePath() = %s, want %s", got, want)
+			}
+			if tt.wantGone != "" {
+				if _, err := os.Stat(filepath.Join(root, tt.wantGone)); !os.IsNotExist(err) {
+					t.Errorf("%s が残っています", tt.wantGone)
+				}
+			}
+			for _, img := range tt.wantImages {
+				if _, err := os.Stat(filepath.Join(got, "img", img)); err != nil {
+					t.Errorf("img/%s が見つかりません: %v", img, err)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileSplitArchives(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeTestThreadDir(t, filepath.Join(root, "123_a"), "123", 1, "a.jpg")
+	writeTestThreadDir(t, filepath.Join(root, "123_b"), "123", 2, "b.jpg")
+	writeTestThreadDir(t, filepath.Join(root, "456_c"), "456", 1, "c.jpg")
+
+	merged, err := ReconcileSplitArchives(root, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("ReconcileSplitArchives() error = %v", err)
+	}
+	if merged != 1 {
+		t.Errorf("merged = %d, want 1", merged)
+	}
+
+	// メディア数の多い 123_b に統合される
+	for _, img := range []string{"a.jpg", "b.jpg"} {
+		if _, err := os.Stat(filepath.Join(root, "123_b", "img", img)); err != nil {
+			t.Errorf("123_b/img/%s が見つかりません: %v", img, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "123_a")); !os.IsNotExist(err) {
+		t.Errorf("123_a が残っています")
+	}
+	if _, err := os.Stat(filepath.Join(root, "456_c")); err != nil {
+		t.Errorf("無関係なスレッドが影響を受けました: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// pagination_mode の値です。
+const (
+	PaginationPages   = "pages"   // レスを一定数ごとに index_p1.htm, index_p2.htm … に分割する
+	PaginationVirtual = "virtual" // index.htm のまま、画面外のレスの描画をブラウザに省略させる
+)
+
+// defaultPostsPerPage は、posts_per_page が未設定の場合の1ページあたりのレス数です。
+const defaultPostsPerPage = 500
+
+var (
+	// replyTablePattern は、ふたばのレス1件分の table ブロックです（レスの table は入れ子になりません）。
+	replyTablePattern = regexp.MustCompile(`(?s)<table[^>]*>.*?</table>`)
+	// postNumberInBlockPattern は、レスのブロックからレス番号を取り出します。
+	postNumberInBlockPattern = regexp.MustCompile(`No\.(\d+)\b`)
+	// postAnchorHrefPattern は、ReconstructHTML が書き換えたスレッド内のレスへのリンクです。
+	postAnchorHrefPattern = regexp.MustCompile(`href="#p(\d+)"`)
+	// pageFilePattern は、分割したページのファイル名です。
+	pageFilePattern = regexp.MustCompile(`^index_p(\d+)\.htm$`)
+)
+
+// virtualScrollStyle は、"virtual" モードで挿入するスタイルです。
+// content-visibility により、画面外のレスのレイアウトと描画が省略されます。
+const virtualScrollStyle = `<style>[data-giba-post]{content-visibility:auto;contain-intrinsic-size:auto 120px}</style>`
+
+// threadPosts は、スレッドのHTMLをレス単位に分けたものです。
+// Head + Posts[0] + … + Posts[n-1] + Tail が元のHTMLと一致します。
+type threadPosts struct {
+	Head  string // レスより前の部分（<head>、スレ本文など）
+	Posts []string
+	Tail  string // 最後のレスより後の部分
+	// ResNumbers[i] は Posts[i] のレス番号です。
+	ResNumbers []string
+}
+
+// splitThreadPosts は、スレッドのHTMLをレス（レス番号を含む table）単位に分割します。
+// レスの間にある要素は直前のレスに含めます。レスが見つからない場合は Posts が空になります。
+func splitThreadPosts(htmlContent string) threadPosts {
+	var starts, ends []int
+	var resNumbers []string
+	for _, loc := range replyTablePattern.FindAllStringIndex(htmlContent, -1) {
+		m := postNumberInBlockPattern.FindStringSubmatch(htmlContent[loc[0]:loc[1]])
+		if m == nil {
+			continue
+		}
+		starts = append(starts, loc[0])
+		ends = append(ends, loc[1])
+		resNumbers = append(resNumbers, m[1])
+	}
+	if len(starts) == 0 {
+		return threadPosts{Head: htmlContent}
+	}
+
+	posts := make([]string, len(starts))
+	for i, start := range starts {
+		end := ends[len(ends)-1]
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		posts[i] = htmlContent[start:end]
+	}
+	return threadPosts{
+		Head:       htmlContent[:starts[0]],
+		Posts:      posts,
+		Tail:       htmlContent[ends[len(ends)-1]:],
+		ResNumbers: resNumbers,
+	}
+}
+
+// pageFileName は、n ページ目（1始まり）のファイル名を返します。
+func pageFileName(n int) string {
+	return fmt.Sprintf("index_p%d.htm", n)
+}
+
+// postsPerPage は、タスクの1ページあたりのレス数を返します。
+func postsPerPage(task config.Task) int {
+	if task.PostsPerPage > 0 {
+		return task.PostsPerPage
+	}
+	return defaultPostsPerPage
+}
+
+// applyVirtualScroll は、各レスに目印の属性を付け、画面外のレスの描画を省略するスタイルを挿入します。
+// レスがない場合は htmlContent をそのまま返します。
+func applyVirtualScroll(htmlContent string) string {
+	parts := splitThreadPosts(htmlContent)
+	if len(parts.Posts) == 0 {
+		return htmlContent
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(htmlContent) + len(parts.Posts)*len(" data-giba-post") + len(virtualScrollStyle))
+	head := parts.Head
+	if i := strings.Index(strings.ToLower(head), "</head>"); i != -1 {
+		sb.WriteString(head[:i])
+		sb.WriteString(virtualScrollStyle)
+		sb.WriteString(head[i:])
+	} else {
+		sb.WriteString(virtualScrollStyle)
+		sb.WriteString(head)
+	}
+	for _, post := range parts.Posts {
+		// post は "<table" で始まる
+		sb.WriteString("<table data-giba-post")
+		sb.WriteString(post[len("<table"):])
+	}
+	sb.WriteString(parts.Tail)
+	return sb.String()
+}
+
+// writePaginatedHTML は、レスを perPage 件ごとに分割したHTMLを threadSavePath に index_p1.htm … として書き出します。
+// 各ページにはスレ本文（先頭部分）とページ移動のリンクが含まれ、別のページにあるレスへのリンクはそのページを指すよう書き換えます。
+// レス数が perPage 以下の場合は分割しません。以前の実行で作成した不要なページは削除します。
+// 書き出したページ数を返します。
+func writePaginatedHTML(threadSavePath, htmlContent string, perPage int) (int, error) {
+	parts := splitThreadPosts(htmlContent)
+	pageCount := 0
+	if perPage > 0 && len(parts.Posts) > perPage {
+		pageCount = (len(parts.Posts) + perPage - 1) / perPage
+	}
+
+	pageOf := make(map[string]int)
+	if pageCount > 0 {
+		for i, resNum := range parts.ResNumbers {
+			pageOf[resNum] = i/perPage + 1
+		}
+	}
+
+	for page := 1; page <= pageCount; page++ {
+		first := (page - 1) * perPage
+		last := first + perPage
+		if last > len(parts.Posts) {
+			last = len(parts.Posts)
+		}
+		nav := pagerHTML(page, pageCount)
+		path := filepath.Join(threadSavePath, pageFileName(page))
+		err := writeFileBuffered(path, func(w io.Writer) error {
+			for _, s := range []string{parts.Head, nav} {
+				if _, err := io.WriteString(w, s); err != nil {
+					return err
+				}
+			}
+			for _, post := range parts.Posts[first:last] {
+				if _, err := io.WriteString(w, rewritePostLinks(post, page, pageOf)); err != nil {
+					return err
+				}
+			}
+			for _, s := range []string{nav, parts.Tail} {
+				if _, err := io.WriteString(w, s); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := removeStalePages(threadSavePath, pageCount); err != nil {
+		return pageCount, err
+	}
+	return pageCount, nil
+}
+
+// rewritePostLinks は、レス内の別ページにあるレスへのリンクを、そのページへのリンクに書き換えます。
+func rewritePostLinks(post string, page int, pageOf map[string]int) string {
+	return postAnchorHrefPattern.ReplaceAllStringFunc(post, func(href string) string {
+		resNum := postAnchorHrefPattern.FindStringSubmatch(href)[1]
+		if target, ok := pageOf[resNum]; ok && target != page {
+			return fmt.Sprintf(`href="%s#p%s"`, pageFileName(target), resNum)
+		}
+		return href
+	})
+}
+
+// pagerHTML は、ページ移動のリンクを返します。
+func pagerHTML(current, pageCount int) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="giba-pager" style="margin:8px 0;font-size:small">`)
+	if current > 1 {
+		fmt.Fprintf(&sb, `<a href="%s">&laquo; 前へ</a> `, pageFileName(current-1))
+	}
+	for page := 1; page <= pageCount; page++ {
+		if page == current {
+			fmt.Fprintf(&sb, `<strong>[%d]</strong> `, page)
+		} else {
+			fmt.Fprintf(&sb, `<a href="%s">[%d]</a> `, pageFileName(page), page)
+		}
+	}
+	if current < pageCount {
+		fmt.Fprintf(&sb, `<a href="%s">次へ &raquo;</a> `, pageFileName(current+1))
+	}
+	sb.WriteString(`<a href="index.htm">全体を表示</a></div>`)
+	return sb.String()
+}
+
+// removeStalePages は、threadSavePath にある keep より大きい番号のページを削除します。
+func removeStalePages(threadSavePath string, keep int) error {
+	entries, err := os.ReadDir(threadSavePath)
+	if err != nil {
+		return fmt.Errorf("ディレクトリの読み込みに失敗しました (path=%s): %w", threadSavePath, err)
+	}
+	for _, entry := range entries {
+		if pageNumberOf(entry.Name()) <= keep {
+			continue
+		}
+		path := filepath.Join(threadSavePath, entry.Name())
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("不要になったページの削除に失敗しました (path=%s): %w", path, err)
+		}
+	}
+	return nil
+}
+
+// pageNumberOf は、name が分割したページのファイル名であればページ番号を、そうでなければ 0 を返します。
+func pageNumberOf(name string) int {
+	m := pageFilePattern.FindStringSubmatch(name)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// applyPagination は、タスクの pagination_mode に従って分割したページを書き出します。
+// 失敗してもアーカイブ自体は成功しているため、警告のみを記録します。
+func applyPagination(task config.Task, threadSavePath, htmlContent string, logger *log.Logger) {
+	perPage := 0
+	if task.PaginationMode == PaginationPages {
+		perPage = postsPerPage(task)
+	}
+	pageCount, err := writePaginatedHTML(threadSavePath, htmlContent, perPage)
+	if err != nil {
+		logger.Printf("WARNING: ページ分割したHTMLの保存に失敗しました: %v", err)
+		return
+	}
+	if pageCount > 0 {
+		logger.Printf("INFO: スレッドを %d ページに分割して保存しました（1ページ %d レス）", pageCount, perPage)
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// paginationTestHTML は、スレ本文と5件のレスを持つHTMLです。レス5はレス1を引用しています。
+func paginationTestHTML() string {
+	var sb strings.Builder
+	sb.WriteString(`<html><head><title>t</title></head><body><a id="p100"></a>No.100 スレ本文`)
+	for i := 1; i <= 5; i++ {
+		n := "10" + string(rune('0'+i))
+		sb.WriteString(`<table border=0><tr><td><a id="p` + n + `"></a>No.` + n)
+		if i == 5 {
+			sb.WriteString(` <a href="#p101">&gt;&gt;101</a>`)
+		}
+		sb.WriteString("</td></tr></table>\n")
+	}
+	sb.WriteString(`<div class="footer">end</div></body></html>`)
+	return sb.String()
+}
+
+func TestSplitThreadPosts(t *testing.T) {
+	t.Parallel()
+
+	htmlContent := paginationTestHTML()
+	parts := splitThreadPosts(htmlContent)
+	if len(parts.Posts) != 5 {
+		t.Fatalf("len(Posts) = %d, want 5", len(parts.Posts))
+	}
+	if got := parts.Head + strings.Join(parts.Posts, "") + parts.Tail; got != htmlContent {
+		t.Errorf("分割したHTMLを結合しても元に戻りません:\n%s", got)
+	}
+	if want := []string{"101", "102", "103", "104", "105"}; strings.Join(parts.ResNumbers, ",") != strings.Join(want, ",") {
+		t.Errorf("ResNumbers = %v, want %v", parts.ResNumbers, want)
+	}
+	if !strings.Contains(parts.Head, "スレ本文") || !strings.Contains(parts.Tail, `class="footer"`) || strings.Contains(parts.Tail, "No.") {
+		t.Errorf("Head/Tail が不正です: head=%q tail=%q", parts.Head, parts.Tail)
+	}
+
+	if got := splitThreadPosts("<html>no posts</html>"); len(got.Posts) != 0 || got.Head != "<html>no posts</html>" {
+		t.Errorf("レスのないHTMLの分割結果が不正です: %+v", got)
+	}
+}
+
+func TestWritePaginatedHTML(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "index_p9.htm")
+	if err := os.WriteFile(stale, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pageCount, err := writePaginatedHTML(dir, paginationTestHTML(), 2)
+	if err != nil {
+		t.Fatalf("writePaginatedHTML() error = %v", err)
+	}
+	if pageCount != 3 {
+		t.Fatalf("pageCount = %d, want 3", pageCount)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("不要なページが削除されていません: %v", err)
+	}
+
+	page3, err := os.ReadFile(filepath.Join(dir, "index_p3.htm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"スレ本文", "No.105", `href="index_p1.htm#p101"`, `<a href="index_p2.htm">&laquo; 前へ</a>`, `<strong>[3]</strong>`, `class="footer"`} {
+		if !strings.Contains(string(page3), want) {
+			t.Errorf("index_p3.htm に %q が含まれていません", want)
+		}
+	}
+	if strings.Contains(string(page3), "No.101") {
+		t.Error("index_p3.htm に他のページのレスが含まれています")
+	}
+
+	// レス数がページの上限以下になった場合は、分割したページをすべて削除する
+	if pageCount, err := writePaginatedHTML(dir, paginationTestHTML(), 0); err != nil || pageCount != 0 {
+		t.Fatalf("writePaginatedHTML() = %d, %v, want 0, nil", pageCount, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "index_p*.htm")); len(matches) != 0 {
+		t.Errorf("分割したページが残っています: %v", matches)
+	}
+}
+
+func TestApplyVirtualScroll(t *testing.T) {
+	t.Parallel()
+
+	got := applyVirtualScroll(paginationTestHTML())
+	if !strings.Contains(got, virtualScrollStyle+"</head>") {
+		t.Error("スタイルが </head> の直前に挿入されていません")
+	}
+	if n := strings.Count(got, "<table data-giba-post border=0>"); n != 5 {
+		t.Errorf("目印の付いたレスの数 = %d, want 5", n)
+	}
+	if plain := "<p>no posts</p>"; applyVirtualScroll(plain) != plain {
+		t.Error("レスのないHTMLは変更しないはずです")
+	}
+}
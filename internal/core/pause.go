@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate は、一時停止中に新しい巡回サイクルとスレッドの開始を止めます。
+// 実行中のスレッドは中断せず、開始前の処理のみが再開まで待機します。
+type pauseGate struct {
+	mu     sync.Mutex
+	resume chan struct{} // 一時停止中のみ設定され、再開時に閉じられる
+}
+
+// sharedPauseGate は、プロセス内の全タスクで共有される一時停止の状態です。Engine の TogglePause で切り替えます。
+var sharedPauseGate = &pauseGate{}
+
+// set は、一時停止の状態を設定します。再開すると、待機中の処理はすべて続行します。
+func (g *pauseGate) set(paused bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case paused && g.resume == nil:
+		g.resume = make(chan struct{})
+	case !paused && g.resume != nil:
+		close(g.resume)
+		g.resume = nil
+	}
+}
+
+// wait は、一時停止中であれば再開まで待機します。再開の前に ctx がキャンセルされた場合はエラーを返します。
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	resume := g.resume
+	g.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// stateDirName は、保存先のルートに作成するGIBAの内部状態のディレクトリ名です。
+const stateDirName = ".giba"
+
+// pendingThread は、保留キューに保存する1件のスレッドです。
+type pendingThread struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title,omitempty"`
+	URL      string    `json:"url"`
+	ResCount int       `json:"res_count,omitempty"`
+	Date     time.Time `json:"date,omitempty"`
+}
+
+// pendingQueueFile は、保留キューのファイルの内容です。
+type pendingQueueFile struct {
+	TaskName string          `json:"task_name"`
+	Board    string          `json:"board"`
+	SavedAt  time.Time       `json:"saved_at"`
+	Threads  []pendingThread `json:"threads"`
+}
+
+// pendingQueue は、巡回サイクルで見つかったもののまだアーカイブが完了していないスレッドの一覧（保留キュー）です。
+// サイクルの途中でGIBAが強制終了されても、次回の起動時にカタログの取得を待たずに再開できるよう、変更のたびにファイルへ保存します。
+type pendingQueue struct {
+	path   string
+	task   config.Task
+	logger *log.Logger
+
+	mu      sync.Mutex
+	threads []model.ThreadInfo
+}
+
+// newPendingQueue は、タスクの保留キューを作成します。ファイルは保存先のルートの .giba/queue/<タスク名>.json です。
+func newPendingQueue(task config.Task, logger *log.Logger) *pendingQueue {
+	return &pendingQueue{
+		path:   filepath.Join(task.SaveRootDirectory, stateDirName, "queue", SanitizeFilename(task.TaskName)+".json"),
+		task:   task,
+		logger: logger,
+	}
+}
+
+// resume は、前回の実行で保存された保留キューのスレッドを返します。
+// 対象の板が変更されている場合は、別の板のスレッドを取得しないよう破棄します。
+func (q *pendingQueue) resume() []model.ThreadInfo {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			q.logger.Printf("WARNING: 保留キューの読み込みに失敗しました (path=%s): %v", q.path, err)
+		}
+		return nil
+	}
+	var file pendingQueueFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		q.logger.Printf("WARNING: 保留キューの解析に失敗しました。破棄します (path=%s): %v", q.path, err)
+		return nil
+	}
+	if file.Board != q.task.TargetBoardURL {
+		q.logger.Printf("INFO: 対象の板が変更されたため、保留キュー（%d件）を破棄します。", len(file.Threads))
+		return nil
+	}
+
+	threads := make([]model.ThreadInfo, 0, len(file.Threads))
+	for _, th := range file.Threads {
+		threads = append(threads, model.ThreadInfo{ID: th.ID, Title: th.Title, URL: th.URL, ResCount: th.ResCount, Date: th.Date})
+	}
+	return threads
+}
+
+// set は、保留キューを threads に置き換えて保存します。
+func (q *pendingQueue) set(threads []model.ThreadInfo) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.threads = append([]model.ThreadInfo(nil), threads...)
+	q.saveLocked()
+}
+
+// done は、処理が終わったスレッドを保留キューから取り除いて保存します。
+// シャットダウンで中断したスレッドは次回の起動時に再開できるよう、呼び出し側で done を呼ばずに残します。
+func (q *pendingQueue) done(threadID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, th := range q.threads {
+		if th.ID == threadID {
+			q.threads = append(q.threads[:i], q.threads[i+1:]...)
+			q.saveLocked()
+			return
+		}
+	}
+}
+
+// saveLocked は、保留キューをファイルに保存します。空の場合はファイルを削除します。
+// 保存に失敗してもアーカイブは続行します（次回の起動時に再開できないだけのため）。
+func (q *pendingQueue) saveLocked() {
+	if err := writePendingQueue(q.path, q.task, q.threads); err != nil {
+		q.logger.Printf("WARNING: %v", err)
+	}
+}
+
+// writePendingQueue は、保留キューのファイルを一時ファイルに書いてからリネームして保存します。
+func writePendingQueue(path string, task config.Task, threads []model.ThreadInfo) error {
+	if len(threads) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("保留キューの削除に失敗しました (path=%s): %w", path, err)
+		}
+		return nil
+	}
+
+	file := pendingQueueFile{TaskName: task.TaskName, Board: task.TargetBoardURL, SavedAt: time.Now()}
+	for _, th := range threads {
+		file.Threads = append(file.Threads, pendingThread{ID: th.ID, Title: th.Title, URL: th.URL, ResCount: th.ResCount, Date: th.Date})
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("保留キューのシリアライズに失敗しました: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("保留キューのディレクトリ作成に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("保留キューの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("保留キューの更新に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
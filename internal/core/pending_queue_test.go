@@ -0,0 +1,50 @@
+package core
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestPendingQueue(t *testing.T) {
+	t.Parallel()
+
+	task := config.Task{TaskName: "テスト/板", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: t.TempDir()}
+	logger := log.New(io.Discard, "", 0)
+	threads := []model.ThreadInfo{
+		{ID: "100", Title: "一つ目", URL: "https://may.2chan.net/b/res/100.htm"},
+		{ID: "200", Title: "二つ目", URL: "https://may.2chan.net/b/res/200.htm"},
+		{ID: "300", Title: "三つ目", URL: "https://may.2chan.net/b/res/300.htm"},
+	}
+
+	q := newPendingQueue(task, logger)
+	if got := q.resume(); len(got) != 0 {
+		t.Fatalf("resume() = %v, want empty", got)
+	}
+	q.set(threads)
+	q.done("200")
+
+	// 強制終了後の再起動を想定し、新しいキューから読み込む
+	got := newPendingQueue(task, logger).resume()
+	if len(got) != 2 || got[0].ID != "100" || got[1].ID != "300" || got[1].URL != threads[2].URL || got[0].Title != "一つ目" {
+		t.Errorf("resume() = %+v, want threads 100 and 300 in order", got)
+	}
+
+	// 対象の板が変更された場合は破棄する
+	moved := task
+	moved.TargetBoardURL = "https://img.2chan.net/b/"
+	if got := newPendingQueue(moved, logger).resume(); len(got) != 0 {
+		t.Errorf("resume() after board change = %v, want empty", got)
+	}
+
+	// すべて完了するとファイルを削除する
+	q.done("100")
+	q.done("300")
+	if _, err := os.Stat(q.path); !os.IsNotExist(err) {
+		t.Errorf("queue file still exists after all threads are done: %v", err)
+	}
+}
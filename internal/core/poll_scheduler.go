@@ -0,0 +1,136 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+const (
+	// pollMaxIntervalFactor は、poll_max_interval_ms が未設定の場合の上限の下限に対する倍率です。
+	pollMaxIntervalFactor = 8
+	// pollStallCycles は、確認の間隔を延ばし始めるまでの、変化のない確認の連続回数です。
+	pollStallCycles = 2
+)
+
+// pollState は、1件のスレッドの確認スケジュールです。
+type pollState struct {
+	interval  time.Duration // 現在の確認間隔
+	nextCheck time.Time     // 次に確認する時刻
+	resCount  int           // 前回確認したときのカタログのレス数
+	unchanged int           // 変化のなかった確認の連続回数
+}
+
+// pollScheduler は、監視モードでスレッドごとの確認間隔を更新の頻度に合わせて調整します（adaptive_polling）。
+// 変化のない確認が続いたスレッド（sage・停滞したスレッド）は間隔を倍にしていき（上限あり）、
+// 更新のあったスレッドやカタログのレス数が増えたスレッドは下限の間隔に戻します。
+// 状態はメモリ上のみに保持し、起動時はすべてのスレッドを確認対象とします。
+type pollScheduler struct {
+	minInterval, maxInterval time.Duration
+
+	mu      sync.Mutex
+	threads map[string]*pollState
+}
+
+// newPollScheduler は、タスクの確認スケジュールを作成します。adaptive_polling が無効な場合は nil を返します。
+// nil のスケジューラーはすべてのスレッドを毎サイクル確認します。
+func newPollScheduler(task config.Task) *pollScheduler {
+	if !task.AdaptivePolling {
+		return nil
+	}
+	minInterval, maxInterval := pollIntervalBounds(task)
+	return &pollScheduler{minInterval: minInterval, maxInterval: maxInterval, threads: make(map[string]*pollState)}
+}
+
+// pollIntervalBounds は、タスクの確認間隔の下限と上限を返します。
+func pollIntervalBounds(task config.Task) (time.Duration, time.Duration) {
+	minInterval := time.Duration(task.PollMinIntervalMillis) * time.Millisecond
+	if minInterval <= 0 {
+		minInterval = watchInterval(task)
+	}
+	maxInterval := time.Duration(task.PollMaxIntervalMillis) * time.Millisecond
+	if maxInterval <= 0 {
+		maxInterval = minInterval * pollMaxIntervalFactor
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	return minInterval, maxInterval
+}
+
+// cycleInterval は、巡回サイクルの待機時間を返します。下限の間隔で確認するスレッドがあるため、watch_interval_ms より短くなる場合があります。
+func (s *pollScheduler) cycleInterval(watch time.Duration) time.Duration {
+	if s == nil || s.minInterval >= watch {
+		return watch
+	}
+	return s.minInterval
+}
+
+// due は、threads のうち今回のサイクルで確認するスレッドと、次回以降に見送るスレッドの数を返します。
+// カタログのレス数が前回の確認から増えているスレッドは、間隔に関わらず確認します。
+// カタログに含まれなくなったスレッドの状態は破棄します。
+func (s *pollScheduler) due(threads []model.ThreadInfo, now time.Time) ([]model.ThreadInfo, int) {
+	if s == nil {
+		return threads, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	present := make(map[string]bool, len(threads))
+	var due []model.ThreadInfo
+	for _, th := range threads {
+		present[th.ID] = true
+		st, ok := s.threads[th.ID]
+		if !ok || !now.Before(st.nextCheck) || (th.ResCount > 0 && th.ResCount > st.resCount) {
+			due = append(due, th)
+		}
+	}
+	for id := range s.threads {
+		if !present[id] {
+			delete(s.threads, id)
+		}
+	}
+	return due, len(threads) - len(due)
+}
+
+// record は、スレッドを確認した結果を記録し、次に確認する時刻を決めます。
+// changed が true の場合は下限の間隔に戻し、false の場合は pollStallCycles 回続いた後から間隔を倍にします。
+func (s *pollScheduler) record(thread model.ThreadInfo, changed bool, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.threads[thread.ID]
+	if !ok {
+		st = &pollState{interval: s.minInterval}
+		s.threads[thread.ID] = st
+	}
+	st.resCount = thread.ResCount
+	if changed {
+		st.unchanged = 0
+		st.interval = s.minInterval
+	} else {
+		st.unchanged++
+		if st.unchanged >= pollStallCycles {
+			st.interval *= 2
+			if st.interval > s.maxInterval {
+				st.interval = s.maxInterval
+			}
+		}
+	}
+	st.nextCheck = now.Add(st.interval)
+}
+
+// forget は、スレッドの確認スケジュールを破棄します（スレッドが落ちた場合など）。
+func (s *pollScheduler) forget(threadID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.threads, threadID)
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestPollScheduler(t *testing.T) {
+	t.Parallel()
+
+	task := config.Task{AdaptivePolling: true, WatchIntervalMillis: 60000, PollMinIntervalMillis: 30000, PollMaxIntervalMillis: 100000}
+	s := newPollScheduler(task)
+	if got := s.cycleInterval(watchInterval(task)); got != 30*time.Second {
+		t.Errorf("cycleInterval() = %v, want 30s", got)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	stalled := model.ThreadInfo{ID: "1", ResCount: 10}
+	active := model.ThreadInfo{ID: "2", ResCount: 5}
+	dueIDs := func(threads ...model.ThreadInfo) []string {
+		due, _ := s.due(threads, now)
+		ids := make([]string, 0, len(due))
+		for _, th := range due {
+			ids = append(ids, th.ID)
+		}
+		return ids
+	}
+
+	if got := dueIDs(stalled, active); len(got) != 2 {
+		t.Fatalf("初回は全スレッドが対象: got %v", got)
+	}
+
+	// 変化のない確認が pollStallCycles 回続くと、間隔が倍（30s → 60s → 100s で上限）になる
+	wantIntervals := []time.Duration{30 * time.Second, 60 * time.Second, 100 * time.Second, 100 * time.Second}
+	for i, want := range wantIntervals {
+		s.record(stalled, false, now)
+		s.record(active, true, now)
+		if got := s.threads["1"].interval; got != want {
+			t.Errorf("確認%d回目の間隔 = %v, want %v", i+1, got, want)
+		}
+		now = now.Add(30 * time.Second)
+		if got := dueIDs(stalled, active); (len(got) == 2) != (want == 30*time.Second) {
+			t.Errorf("確認%d回目の後の対象 = %v", i+1, got)
+		}
+	}
+
+	// カタログのレス数が増えたスレッドは間隔に関わらず確認する
+	grown := stalled
+	grown.ResCount = 11
+	if got := dueIDs(grown); len(got) != 1 {
+		t.Errorf("レス数が増えたスレッドが対象になりません: %v", got)
+	}
+
+	// カタログからなくなったスレッドの状態は破棄する
+	dueIDs(active)
+	if _, ok := s.threads["1"]; ok {
+		t.Error("カタログにないスレッドの状態が残っています")
+	}
+
+	// 無効な場合はすべて対象
+	var disabled *pollScheduler
+	if due, deferred := disabled.due([]model.ThreadInfo{stalled}, now); len(due) != 1 || deferred != 0 {
+		t.Errorf("無効なスケジューラーの due() = %v, %d", due, deferred)
+	}
+}
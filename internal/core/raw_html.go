@@ -0,0 +1,50 @@
+package core
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+)
+
+// RawHTMLFileName は、取得したままのスレッドHTMLを保存するファイル名です。
+const RawHTMLFileName = "raw.html.gz"
+
+// saveRawHTML は、取得したままのスレッドHTML（文字コードの変換や再構成を行う前のバイト列）を
+// gzip で圧縮して threadSavePath に保存します。
+// スレッドが落ちた後でも、解析・再構成の改善を再取得なしで適用できるようにするためのものです。
+// 書き込み途中のファイルが残らないよう、writeFileBuffered で一時ファイルに書き出してから置き換えます。
+func saveRawHTML(threadSavePath string, raw []byte) error {
+	path := filepath.Join(threadSavePath, RawHTMLFileName)
+	return writeFileBuffered(path, func(w io.Writer) error {
+		zw := gzip.NewWriter(w)
+		if _, err := zw.Write(raw); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+}
+
+// ReadRawHTML は、saveRawHTML で保存したスレッドHTMLを展開して返します。
+func ReadRawHTML(threadDir string) ([]byte, error) {
+	path := filepath.Join(threadDir, RawHTMLFileName)
+	f, err := archivecrypt.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%sを開けませんでした (path=%s): %w", RawHTMLFileName, path, err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%sの展開に失敗しました (path=%s): %w", RawHTMLFileName, path, err)
+	}
+	defer zr.Close()
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("%sの展開に失敗しました (path=%s): %w", RawHTMLFileName, path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveRawHTML(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	// Shift_JIS のバイト列など、UTF-8 として不正なデータもそのまま保存されること
+	raw := []byte("<html>\x82\xa0\x82\xa2</html>")
+	if err := saveRawHTML(dir, raw); err != nil {
+		t.Fatalf("saveRawHTML() error = %v", err)
+	}
+	// 上書き保存できること
+	raw = append(raw, "<!-- updated -->"...)
+	if err := saveRawHTML(dir, raw); err != nil {
+		t.Fatalf("saveRawHTML() error = %v", err)
+	}
+
+	got, err := ReadRawHTML(dir)
+	if err != nil {
+		t.Fatalf("ReadRawHTML() error = %v", err)
+	}
+	if !bytes.Equal(got, raw) {
+		t.Errorf("ReadRawHTML() = %q, want %q", got, raw)
+	}
+	if _, err := os.Stat(filepath.Join(dir, RawHTMLFileName+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("一時ファイルが残っています: %v", err)
+	}
+
+	if _, err := ReadRawHTML(t.TempDir()); err == nil {
+		t.Error("ReadRawHTML() は保存されていない場合にエラーを返すべきです")
+	}
+}
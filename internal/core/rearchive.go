@@ -0,0 +1,278 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+var (
+	// threadURLPattern は、スレッドURL（.../res/123456789.htm）からスレッドIDを抽出します。
+	threadURLPattern = regexp.MustCompile(`^(.*/)res/(\d+)\.htm`)
+	threadIDPattern  = regexp.MustCompile(`^\d+$`)
+)
+
+// RearchiveTarget は、再アーカイブの対象として解決されたスレッドです。
+type RearchiveTarget struct {
+	Task      config.Task
+	Thread    model.ThreadInfo
+	ThreadDir string // 既存の保存先ディレクトリ（未アーカイブの場合は空）
+}
+
+// OriginalURL は、対象スレッドの元のURLを返します。thread.json に記録されている場合はその値を優先します。
+func (t RearchiveTarget) OriginalURL() string {
+	if t.ThreadDir != "" {
+		if meta, err := LoadThreadMetadata(t.ThreadDir); err == nil && meta != nil && meta.URL != "" {
+			return meta.URL
+		}
+	}
+	return threadPageURL(t.Task, t.Thread)
+}
+
+// ResolveRearchiveTarget は、スレッドIDまたはスレッドURLから、対象のタスクと保存先を特定します。
+// URLの場合は板URLが一致するタスクを、IDの場合は保存先にそのスレッドを持つタスクを対象とします。
+func ResolveRearchiveTarget(cfg *config.Config, target string) (RearchiveTarget, error) {
+	target = strings.TrimSpace(target)
+
+	var threadID, boardURL string
+	switch {
+	case threadIDPattern.MatchString(target):
+		threadID = target
+	default:
+		m := threadURLPattern.FindStringSubmatch(target)
+		if m == nil {
+			return RearchiveTarget{}, fmt.Errorf("スレッドIDまたはスレッドURLを指定してください: %q", target)
+		}
+		boardURL, threadID = m[1], m[2]
+	}
+
+	var candidates []config.Task
+	for _, task := range cfg.Tasks {
+		if boardURL == "" || sameBoard(task.TargetBoardURL, boardURL) {
+			candidates = append(candidates, task)
+		}
+	}
+
+	// 既に保存済みのタスクを優先する
+	for _, task := range candidates {
+		dirs, err := scanThreadDirs(task.SaveRootDirectory)
+		if err != nil {
+			log.Printf("WARNING: タスク '%s' の保存先の走査に失敗しました: %v", task.TaskName, err)
+			continue
+		}
+		if threadDirs := dirs[threadID]; len(threadDirs) > 0 {
+			dir := pickPrimaryThreadDir(threadDirs)
+			return RearchiveTarget{Task: task, Thread: rearchiveThreadInfo(threadID, dir), ThreadDir: dir}, nil
+		}
+	}
+
+	// URL指定で未アーカイブの場合は、板が一致する最初のタスクで新規にアーカイブする
+	if boardURL != "" && len(candidates) > 0 {
+		return RearchiveTarget{Task: candidates[0], Thread: rearchiveThreadInfo(threadID, "")}, nil
+	}
+	return RearchiveTarget{}, fmt.Errorf("スレッド %s を扱うタスクが見つかりません", threadID)
+}
+
+// sameBoard は、2つの板URLが同じ板を指しているかを判定します。
+func sameBoard(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Host, ub.Host) && strings.Trim(ua.Path, "/") == strings.Trim(ub.Path, "/")
+}
+
+// rearchiveThreadInfo は、再アーカイブ用のスレッド情報を組み立てます。
+// 保存先のディレクトリ名が変わらないよう、タイトルは最後に観測したものを使用します。
+func rearchiveThreadInfo(threadID, threadDir string) model.ThreadInfo {
+	thread := model.ThreadInfo{ID: threadID, URL: fmt.Sprintf("res/%s.htm", threadID)}
+	if threadDir == "" {
+		return thread
+	}
+	if snapshot, err := LoadThreadSnapshot(threadDir); err == nil && snapshot != nil {
+		thread.Title = snapshot.LatestTitle()
+		if snapshot.CreatedAt != nil {
+			thread.Date = *snapshot.CreatedAt
+		}
+	}
+	return thread
+}
+
+// MarkForRearchive は、スレッドのスナップショットに強制更新フラグを立て、履歴からエントリを削除します。
+// 次回のアーカイブ時には NeedsUpdate の判定に関わらず、スレッド全体が再取得されます。
+func MarkForRearchive(threadDir, threadID string) error {
+	snapshot, err := LoadThreadSnapshot(threadDir)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		snapshot = &ThreadSnapshot{ThreadID: threadID}
+	}
+	snapshot.ForceRefresh = true
+	snapshot.IsComplete = false
+	if err := SaveThreadSnapshot(threadDir, snapshot); err != nil {
+		return err
+	}
+
+	return removeFromHistory(filepath.Join(threadDir, ".giba", "history.log"), threadID)
+}
+
+// removeFromHistory は、履歴ファイルから指定されたスレッドIDの行を削除します。
+func removeFromHistory(path, threadID string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("履歴ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+
+	var kept []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.TrimSpace(line) != threadID {
+			kept = append(kept, line)
+		}
+	}
+	f.Close()
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("履歴ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+
+	data := ""
+	if len(kept) > 0 {
+		data = strings.Join(kept, "\n") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		return fmt.Errorf("履歴ファイルの書き込みに失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
+// RearchiveThread は、指定されたスレッド（IDまたはURL）のスナップショットと履歴をリセットし、
+// 更新の有無に関わらずスレッド全体を再取得・再ダウンロードします。
+// forceFull が true の場合は、既存のファイルやレジューム情報も無視してすべてのメディアを再ダウンロードします。
+func RearchiveThread(ctx context.Context, cfg *config.Config, target string, forceFull bool, logger *log.Logger) (TaskResult, error) {
+	resolved, err := ResolveRearchiveTarget(cfg, target)
+	if err != nil {
+		return TaskResult{}, err
+	}
+	task := resolved.Task
+	task.ForceFull = forceFull
+
+	if resolved.ThreadDir != "" {
+		if err := MarkForRearchive(resolved.ThreadDir, resolved.Thread.ID); err != nil {
+			return TaskResult{}, fmt.Errorf("スレッド %s の再アーカイブ準備に失敗しました: %w", resolved.Thread.ID, err)
+		}
+	}
+
+	logger.Printf("スレッド %s をタスク '%s' で再アーカイブします (保存先: %s)", resolved.Thread.ID, task.TaskName, resolved.ThreadDir)
+	return archiveResolvedThread(ctx, cfg, nil, task, resolved, nil, logger)
+}
+
+// BrowserPage は、ブラウザの拡張機能から受け取った、ログイン中のブラウザで表示したスレッドのページです。
+// ログインが必要な掲示板でも、GIBA でログインせずにブラウザのセッションでアーカイブできます。
+type BrowserPage struct {
+	HTML      string         // ブラウザで表示したスレッドのHTML（UTF-8）。空の場合は掲示板から取得します
+	Cookies   []*http.Cookie // ブラウザのCookie。スレッドとメディアの取得に使用します
+	UserAgent string         // ブラウザの User-Agent。空の場合は設定の値を使用します
+}
+
+// ArchiveThread は、指定されたスレッド（IDまたはURL）を監視モードと同じ差分の判定でアーカイブします。
+// 保存済みのスレッドに更新がない場合は何もしません。外部からのアーカイブの依頼（/api/hooks/archive）で使用します。
+// page を指定した場合は、スレッドのHTMLを掲示板から取得せずに page.HTML を使用し、ブラウザのCookieでメディアを取得します。
+// client には依頼をまたいで使い回すクライアントを指定します（ドメインごとのリクエスト間隔を依頼の間でも守るため）。
+// nil の場合は新しく作成します。
+func ArchiveThread(ctx context.Context, cfg *config.Config, client *network.Client, target string, page *BrowserPage, logger *log.Logger) (TaskResult, error) {
+	resolved, err := ResolveRearchiveTarget(cfg, target)
+	if err != nil {
+		return TaskResult{}, err
+	}
+	logger.Printf("スレッド %s をタスク '%s' でアーカイブします (保存先: %s)", resolved.Thread.ID, resolved.Task.TaskName, resolved.ThreadDir)
+	return archiveResolvedThread(ctx, cfg, client, resolved.Task, resolved, page, logger)
+}
+
+// archiveResolvedThread は、解決済みのスレッドを task の設定でアーカイブします。page が nil でない場合はブラウザのページとCookieを使用します。
+// client が nil の場合は新しいクライアントを作成します。
+// 外部からの依頼（/api/hooks/archive・/api/rearchive）でも max_concurrent_tasks などの実行枠と一時停止に従います。
+func archiveResolvedThread(ctx context.Context, cfg *config.Config, client *network.Client, task config.Task, resolved RearchiveTarget, page *BrowserPage, logger *log.Logger) (TaskResult, error) {
+	// タイトルが変わっていても既存のディレクトリに保存する
+	if resolved.ThreadDir != "" && (task.NamingConflictPolicy == "" || task.NamingConflictPolicy == NamingPolicyDuplicate) {
+		task.NamingConflictPolicy = NamingPolicyByID
+	}
+
+	if client == nil {
+		newClient, err := network.NewClient(cfg.Network)
+		if err != nil {
+			return TaskResult{}, fmt.Errorf("ネットワーククライアントの初期化に失敗しました: %w", err)
+		}
+		client = newClient
+	}
+	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+	if err != nil {
+		return TaskResult{}, fmt.Errorf("サイトアダプタの取得に失敗しました: %w", err)
+	}
+	if page != nil && page.UserAgent != "" {
+		client.SetUserAgent(page.UserAgent)
+	}
+	if err := siteAdapter.Prepare(client, task); err != nil {
+		return TaskResult{}, fmt.Errorf("サイト固有設定の適用に失敗しました: %w", err)
+	}
+	if page != nil {
+		if err := applyBrowserPage(client, task, resolved.Thread, page); err != nil {
+			return TaskResult{}, err
+		}
+	}
+
+	// 巡回中のタスクと同じく、一時停止中は再開を待ち、全体およびグループの実行枠を確保してからアーカイブする
+	if err := sharedPauseGate.wait(ctx); err != nil {
+		return TaskResult{}, fmt.Errorf("スレッド %s のアーカイブを中止しました: %w", resolved.Thread.ID, err)
+	}
+	releaseSlot, err := sharedTaskLimiter.acquire(ctx, task.Group)
+	if err != nil {
+		return TaskResult{}, fmt.Errorf("スレッド %s のアーカイブを中止しました: %w", resolved.Thread.ID, err)
+	}
+	defer releaseSlot()
+
+	result := ArchiveSingleThread(ctx, client, siteAdapter, task, resolved.Thread, logger)
+	return result, result.Error
+}
+
+// applyBrowserPage は、ブラウザのCookieをクライアントに設定し、スレッドのURLへのリクエストでブラウザのHTMLを返すようにします。
+// Cookie はサイト固有の設定（Prepare）の後に設定し、同じ名前の Cookie はブラウザの値を優先します。
+func applyBrowserPage(client *network.Client, task config.Task, thread model.ThreadInfo, page *BrowserPage) error {
+	boardURL, err := url.Parse(task.TargetBoardURL)
+	if err != nil {
+		return fmt.Errorf("ターゲットボードURLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
+	}
+	threadURL := joinThreadURL(boardURL, thread.URL).String()
+	for _, cookie := range page.Cookies {
+		if cookie.Path == "" {
+			// document.cookie の形式にはパスが含まれないため、スレッドのディレクトリ（res/）に限らずサイト全体で送信する
+			c := *cookie
+			c.Path = "/"
+			cookie = &c
+		}
+		if err := client.SetCookie(threadURL, cookie); err != nil {
+			return fmt.Errorf("ブラウザのCookieの設定に失敗しました: %w", err)
+		}
+	}
+	if page.HTML != "" {
+		// ブラウザのHTMLは UTF-8 のため、元のページの文字コードの宣言（Shift_JIS など）より優先させる
+		client.Preload(threadURL, string(utf8BOM)+page.HTML)
+	}
+	return nil
+}
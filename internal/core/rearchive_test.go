@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+func TestResolveRearchiveTarget(t *testing.T) {
+	t.Parallel()
+
+	rootA, rootB := t.TempDir(), t.TempDir()
+	writeTestThreadDir(t, filepath.Join(rootB, "123_title"), "123", 2, "a.jpg")
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "A", TargetBoardURL: "https://img.2chan.net/b/", SaveRootDirectory: rootA},
+		{TaskName: "B", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: rootB},
+	}}
+
+	tests := []struct {
+		name     string
+		target   string
+		wantTask string
+		wantDir  string
+		wantErr  bool
+	}{
+		{name: "IDで保存済みのタスクを特定", target: "123", wantTask: "B", wantDir: filepath.Join(rootB, "123_title")},
+		{name: "URLで板を特定", target: "https://img.2chan.net/b/res/456.htm", wantTask: "A"},
+		{name: "URLで保存済みを特定", target: "https://may.2chan.net/b/res/123.htm", wantTask: "B", wantDir: filepath.Join(rootB, "123_title")},
+		{name: "未アーカイブのIDはエラー", target: "999", wantErr: true},
+		{name: "不正な入力はエラー", target: "hello", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ResolveRearchiveTarget(cfg, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveRearchiveTarget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Task.TaskName != tt.wantTask || got.ThreadDir != tt.wantDir {
+				t.Errorf("ResolveRearchiveTarget() = (%s, %s), want (%s, %s)", got.Task.TaskName, got.ThreadDir, tt.wantTask, tt.wantDir)
+			}
+		})
+	}
+}
+
+func TestMarkForRearchive(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "123")
+	writeTestThreadDir(t, dir, "123", 5, "a.jpg")
+	historyPath := filepath.Join(dir, ".giba", "history.log")
+	if err := appendToHistory(historyPath, "123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MarkForRearchive(dir, "123"); err != nil {
+		t.Fatalf("MarkForRearchive() error = %v", err)
+	}
+
+	snapshot, err := LoadThreadSnapshot(dir)
+	if err != nil || snapshot == nil {
+		t.Fatalf("LoadThreadSnapshot() = %v, %v", snapshot, err)
+	}
+	if !NeedsUpdate(snapshot, 5, 0, "") {
+		t.Error("再アーカイブ指定後も NeedsUpdate が false です")
+	}
+	data, err := os.ReadFile(historyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("履歴からエントリが削除されていません: %q", data)
+	}
+}
+
+func TestRearchiveTarget_OriginalURL(t *testing.T) {
+	t.Parallel()
+
+	task := config.Task{TargetBoardURL: "https://may.2chan.net/b/"}
+	dir := t.TempDir()
+	snapshot := &ThreadSnapshot{ThreadID: "123"}
+	if err := SaveThreadMetadata(dir, "https://img.2chan.net/b/res/123.htm", snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target RearchiveTarget
+		want   string
+	}{
+		{"thread.jsonのURLを優先", RearchiveTarget{Task: task, Thread: rearchiveThreadInfo("123", ""), ThreadDir: dir}, "https://img.2chan.net/b/res/123.htm"},
+		{"未アーカイブは板URLから組み立てる", RearchiveTarget{Task: task, Thread: rearchiveThreadInfo("456", "")}, "https://may.2chan.net/b/res/456.htm"},
+		{"thread.jsonがない場合も板URLから組み立てる", RearchiveTarget{Task: task, Thread: rearchiveThreadInfo("789", ""), ThreadDir: t.TempDir()}, "https://may.2chan.net/b/res/789.htm"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.target.OriginalURL(); got != tt.want {
+				t.Errorf("OriginalURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyBrowserPage(t *testing.T) {
+	t.Parallel()
+
+	var gotCookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			gotCookie = c.Value
+		}
+		w.Write([]byte("from server"))
+	}))
+	defer server.Close()
+
+	client, err := network.NewClient(config.NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := config.Task{TargetBoardURL: server.URL + "/b/"}
+	thread := model.ThreadInfo{ID: "123", URL: "res/123.htm"}
+	page := &BrowserPage{HTML: "<html>ブラウザ</html>", Cookies: []*http.Cookie{{Name: "session", Value: "abc"}}}
+	if err := applyBrowserPage(client, task, thread, page); err != nil {
+		t.Fatalf("applyBrowserPage() error = %v", err)
+	}
+
+	// スレッドのURLはブラウザのHTML（UTF-8 の BOM 付き）を返し、それ以外はブラウザのCookieで通信する
+	body, err := client.Get(context.Background(), server.URL+"/b/res/123.htm")
+	if err != nil || !strings.HasPrefix(body, string(utf8BOM)) || !strings.HasSuffix(body, page.HTML) {
+		t.Errorf("thread body = %q, %v, want browser HTML", body, err)
+	}
+	if _, err := client.Get(context.Background(), server.URL+"/b/src/1.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if gotCookie != "abc" {
+		t.Errorf("session cookie = %q, want %q", gotCookie, "abc")
+	}
+}
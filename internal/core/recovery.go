@@ -0,0 +1,126 @@
+package core
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// resumeFileName は、ダウンロードが完了していないメディアを記録するレジュームファイルの名前です。
+const resumeFileName = ".resume.json"
+
+// interruptedThread は、レジュームファイルが残っているスレッドのディレクトリです。
+type interruptedThread struct {
+	thread model.ThreadInfo
+	dir    string
+}
+
+// findInterruptedThreads は、保存先のルートに残っているレジュームファイルから、前回の実行でアーカイブが中断したスレッドを探します。
+// カタログに再び現れるのを待たずに起動時に再開できるよう、スナップショット（初回のアーカイブの場合は thread.json）からスレッドを特定します。
+func findInterruptedThreads(task config.Task, logger *log.Logger) []model.ThreadInfo {
+	var threads []model.ThreadInfo
+	seen := make(map[string]bool)
+	for _, it := range scanInterruptedThreads(task, logger) {
+		if !seen[it.thread.ID] {
+			seen[it.thread.ID] = true
+			threads = append(threads, it.thread)
+		}
+	}
+	return threads
+}
+
+// scanInterruptedThreads は、保存先のルート配下でレジュームファイルが残っているディレクトリを、パスの順に返します。
+// スレッドを特定できないディレクトリは警告を出力して対象外とします。
+func scanInterruptedThreads(task config.Task, logger *log.Logger) []interruptedThread {
+	var dirs []string
+	err := filepath.WalkDir(task.SaveRootDirectory, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == task.SaveRootDirectory {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			switch d.Name() {
+			case "img", "thumb", "css", stateDirName, rollupDirName, boardMetaDirName:
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == resumeFileName {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Printf("WARNING: 中断したスレッドの検索に失敗しました (root=%s): %v", task.SaveRootDirectory, err)
+	}
+	sort.Strings(dirs)
+
+	var found []interruptedThread
+	for _, dir := range dirs {
+		var thread model.ThreadInfo
+		if snapshot, err := LoadThreadSnapshot(dir); err == nil && snapshot != nil && snapshot.ThreadID != "" {
+			thread = rearchiveThreadInfo(snapshot.ThreadID, dir)
+		} else if meta, err := LoadThreadMetadata(dir); err == nil && meta != nil && meta.ThreadID != "" {
+			// 保存先のディレクトリ名が変わらないよう、最初のアーカイブ時のタイトルと作成日時を使用する
+			thread = rearchiveThreadInfo(meta.ThreadID, "")
+			thread.Title = meta.Title
+			if meta.CreatedAt != nil {
+				thread.Date = *meta.CreatedAt
+			}
+		} else {
+			logger.Printf("WARNING: レジュームファイルのスレッドを特定できません。再開しません (path=%s)", dir)
+			continue
+		}
+		found = append(found, interruptedThread{thread: thread, dir: dir})
+	}
+	return found
+}
+
+// finalizeInterruptedThread は、アーカイブの途中で落ちたスレッドを、ダウンロード済みのファイルで最終的な状態にします。
+// raw.html.gz（keep_raw_html）がある場合はそこからHTMLを再構成し、ない場合は取得済みのファイルをそのまま残します。
+// いずれの場合もレジュームファイルを削除し、スナップショットを完了済みにして、以降の起動時に再開の対象としません。
+func finalizeInterruptedThread(task config.Task, siteAdapter adapter.SiteAdapter, threadID string, logger *log.Logger) {
+	for _, it := range scanInterruptedThreads(task, logger) {
+		if it.thread.ID != threadID {
+			continue
+		}
+		dir := it.dir
+		resumePath := filepath.Join(dir, resumeFileName)
+
+		missing, err := reprocessThread(task, siteAdapter, threadID, dir, logger)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			logger.Printf("WARNING: スレッド %s は落ちていますが、%s がないためHTMLを再構成できません。取得済みのファイルのみ残します (path=%s)", threadID, RawHTMLFileName, dir)
+		case err != nil:
+			logger.Printf("WARNING: 落ちたスレッド %s のHTMLの再構成に失敗しました (path=%s): %v", threadID, dir, err)
+			continue
+		default:
+			logger.Printf("INFO: 落ちたスレッド %s をダウンロード済みのファイルで再構成しました (path=%s, 見つからないメディア: %d)", threadID, dir, missing)
+		}
+
+		snapshot, err := LoadThreadSnapshot(dir)
+		if err != nil || snapshot == nil {
+			snapshot = &ThreadSnapshot{ThreadID: threadID}
+			snapshot.ObserveTitle(it.thread.Title, time.Now())
+		}
+		snapshot.IsComplete = true
+		snapshot.ForceRefresh = false
+		snapshot.LastChecked = time.Now()
+		if err := SaveThreadSnapshot(dir, snapshot); err != nil {
+			logger.Printf("WARNING: スナップショットの保存に失敗しました: %v", err)
+		}
+		if err := os.Remove(resumePath); err != nil && !os.IsNotExist(err) {
+			logger.Printf("WARNING: レジュームファイルの削除に失敗しました: %v", err)
+		}
+	}
+}
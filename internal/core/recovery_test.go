@@ -0,0 +1,77 @@
+package core
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestFindInterruptedThreads(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	task := config.Task{TaskName: "回復", SaveRootDirectory: root}
+	logger := log.New(io.Discard, "", 0)
+	writeResume := func(dir string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, resumeFileName), []byte("[]"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 更新の途中で中断したスレッド（スナップショットあり）
+	updated := filepath.Join(root, "100_更新中")
+	writeResume(updated)
+	snapshot := &ThreadSnapshot{ThreadID: "100"}
+	snapshot.ObserveTitle("更新中", time.Now())
+	if err := SaveThreadSnapshot(updated, snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// 初回のアーカイブの途中で中断したスレッド（thread.json のみ）
+	first := filepath.Join(root, "2025-11", "200_初回")
+	writeResume(first)
+	provisional := &ThreadSnapshot{ThreadID: "200"}
+	provisional.ObserveTitle("初回", time.Now())
+	if err := SaveThreadMetadata(first, "https://may.2chan.net/b/res/200.htm", provisional); err != nil {
+		t.Fatal(err)
+	}
+
+	// スレッドを特定できないディレクトリと、完了済みのディレクトリは対象外
+	writeResume(filepath.Join(root, "unknown"))
+	done := filepath.Join(root, "300_完了")
+	if err := os.MkdirAll(done, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveThreadSnapshot(done, &ThreadSnapshot{ThreadID: "300"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := findInterruptedThreads(task, logger)
+	if len(got) != 2 || got[0].ID != "100" || got[1].ID != "200" {
+		t.Fatalf("findInterruptedThreads() = %+v, want threads 100 and 200", got)
+	}
+	if got[0].Title != "更新中" || got[1].Title != "初回" {
+		t.Errorf("titles = %q, %q, want 更新中, 初回", got[0].Title, got[1].Title)
+	}
+
+	// 落ちたスレッドは完了済みにし、以降は再開の対象としない（raw.html.gz がないため再構成は行わない）
+	finalizeInterruptedThread(task, nil, "200", logger)
+	if _, err := os.Stat(filepath.Join(first, resumeFileName)); !os.IsNotExist(err) {
+		t.Errorf("resume file still exists after finalize: %v", err)
+	}
+	if s, err := LoadThreadSnapshot(first); err != nil || s == nil || !s.IsComplete {
+		t.Errorf("snapshot after finalize = %+v, %v, want complete", s, err)
+	}
+	if got := findInterruptedThreads(task, logger); len(got) != 1 || got[0].ID != "100" {
+		t.Errorf("findInterruptedThreads() after finalize = %+v, want thread 100 only", got)
+	}
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// スレッドの保存形式ごとのファイル名
+const (
+	latestHTMLFileName = "index.htm"
+	fullHTMLFileName   = "archive_full.html"
+	threadDataFileName = "thread_data.json"
+	singleFileName     = "archive_single.html"
+)
+
+// singleFileMaxInlineBytes は、1ファイル版に埋め込む画像・CSSの1ファイルあたりの上限です。
+// これを超えるファイル（動画など）は、スレッドのディレクトリへの相対リンクのまま残します。
+const singleFileMaxInlineBytes = 8 << 20
+
+var (
+	// localSrcPattern は、1ファイル版で埋め込む画像の参照（src 属性）です。
+	localSrcPattern = regexp.MustCompile(`src="((?:img|thumb)/[^"]+)"`)
+	// localStylesheetPattern は、1ファイル版で <style> に置き換えるスタイルシートの参照です。
+	localStylesheetPattern = regexp.MustCompile(`<link rel="stylesheet" href="(css/[^"]+)">`)
+)
+
+// renderInput は、レンダリング戦略に渡すスレッドの再構成結果です。
+type renderInput struct {
+	task           config.Task
+	thread         model.ThreadInfo
+	htmlContent    string            // ParseThreadHTML で変換済みの取得したままのHTML
+	mediaFiles     []model.MediaInfo // ブロックしたメディアを含む
+	reconstructed  string            // ローカルのファイルを参照するよう再構成した最新のHTML
+	deletedPosts   string            // 削除されたレスのHTML（archive_full.html から抽出）
+	removedMedia   string            // スレッドから削除された画像へのリンク（archive_full.html から抽出）
+	threadSavePath string
+	logger         *log.Logger
+}
+
+// renderStrategy は、再構成したスレッドを1つの形式で保存します。
+// タスクの render_strategies に指定した名前の戦略をすべて実行するため、JSONと完全版のような組み合わせも
+// ArchiveSingleThread を変更せずに選択できます。新しい形式は renderStrategies に登録します。
+type renderStrategy interface {
+	render(in *renderInput) error
+}
+
+// renderStrategies は、render_strategies の値ごとのレンダリング戦略です。
+var renderStrategies = map[string]renderStrategy{
+	config.RenderLatest:     latestOnlyRenderer{},
+	config.RenderFull:       fullWithDeletedRenderer{},
+	config.RenderJSON:       jsonOnlyRenderer{},
+	config.RenderSingleFile: singleFileRenderer{},
+}
+
+// defaultRenderStrategies は、render_strategies が未設定の場合の保存形式です（最新版と完全版）。
+var defaultRenderStrategies = []string{config.RenderLatest, config.RenderFull}
+
+// taskRenderStrategies は、タスクで使用する保存形式の名前を返します。
+func taskRenderStrategies(task config.Task) []string {
+	if len(task.RenderStrategies) == 0 {
+		return defaultRenderStrategies
+	}
+	return task.RenderStrategies
+}
+
+// latestOnlyRenderer は、最新状態のHTML（index.htm）と、設定に応じてページ分割したHTMLを保存します。
+type latestOnlyRenderer struct{}
+
+func (latestOnlyRenderer) render(in *renderInput) error {
+	htmlSavePath := filepath.Join(in.threadSavePath, latestHTMLFileName)
+	if err := writeFileBuffered(htmlSavePath, func(w io.Writer) error {
+		_, err := io.WriteString(w, in.reconstructed)
+		return err
+	}); err != nil {
+		return fmt.Errorf("index.htmの保存に失敗しました (path=%s, size=%d bytes): %w", htmlSavePath, len(in.reconstructed), err)
+	}
+
+	// レス数の多いスレッドは、設定に応じてページに分割したHTMLも保存する
+	applyPagination(in.task, in.threadSavePath, in.reconstructed, in.logger)
+	return nil
+}
+
+// fullWithDeletedRenderer は、削除されたレスとスレッドから削除された画像を含む完全版（archive_full.html）を保存します。
+// 完全版は次回の削除検知の比較元になるため、保存に失敗してもスレッドのアーカイブは失敗とせず、警告のみを記録します。
+type fullWithDeletedRenderer struct{}
+
+func (fullWithDeletedRenderer) render(in *renderInput) error {
+	// 結合済みの文字列を作らず、最新版HTMLに削除レスを挿入しながら直接書き出す
+	if err := writeFileBuffered(filepath.Join(in.threadSavePath, fullHTMLFileName), func(w io.Writer) error {
+		return writeArchiveFullHTML(w, in.reconstructed, in.deletedPosts, in.removedMedia)
+	}); err != nil {
+		in.logger.Printf("WARNING: archive_full.htmlの保存に失敗しました: %v", err)
+	} else {
+		in.logger.Printf("INFO: 完全版アーカイブを archive_full.html に保存しました")
+	}
+	return nil
+}
+
+// threadData は、thread_data.json に保存するスレッドの内容です。HTMLを解析せずに他のツールから利用するためのものです。
+type threadData struct {
+	ThreadID   string            `json:"thread_id"`
+	Title      string            `json:"title"`
+	URL        string            `json:"url"`
+	CreatedAt  *time.Time        `json:"created_at,omitempty"`
+	ArchivedAt time.Time         `json:"archived_at"`
+	Posts      []threadDataPost  `json:"posts"`
+	Media      []threadDataMedia `json:"media"`
+}
+
+// threadDataPost は、thread_data.json の1件のレスです。
+type threadDataPost struct {
+	ResNumber string `json:"res_number"`
+	Deleted   bool   `json:"deleted,omitempty"` // スレッドから削除されたレス（完全版にのみ残っているもの）
+}
+
+// threadDataMedia は、thread_data.json の1件のメディアです。パスはスレッドのディレクトリからの相対パスです。
+type threadDataMedia struct {
+	ResNumber        int    `json:"res_number,omitempty"`
+	URL              string `json:"url"`
+	OriginalFilename string `json:"original_filename,omitempty"`
+	File             string `json:"file,omitempty"`
+	Thumbnail        string `json:"thumbnail,omitempty"`
+	SHA256           string `json:"sha256,omitempty"`
+}
+
+// jsonOnlyRenderer は、レスとメディアの一覧（thread_data.json）を保存します。
+type jsonOnlyRenderer struct{}
+
+func (jsonOnlyRenderer) render(in *renderInput) error {
+	data := threadData{
+		ThreadID:   in.thread.ID,
+		Title:      in.thread.Title,
+		URL:        threadPageURL(in.task, in.thread),
+		ArchivedAt: inTaskTimezone(in.task, time.Now()),
+		Posts:      []threadDataPost{},
+		Media:      []threadDataMedia{},
+	}
+	if !in.thread.Date.IsZero() {
+		date := in.thread.Date
+		data.CreatedAt = &date
+	}
+
+	live := extractResNumbers(in.htmlContent)
+	deleted := extractResNumbers(in.deletedPosts)
+	for _, no := range sortedResNumbers(live, deleted) {
+		data.Posts = append(data.Posts, threadDataPost{ResNumber: no, Deleted: !live[no]})
+	}
+	for _, m := range in.mediaFiles {
+		if m.Blocked {
+			continue
+		}
+		data.Media = append(data.Media, threadDataMedia{
+			ResNumber:        m.ResNumber,
+			URL:              m.URL,
+			OriginalFilename: m.OriginalFilename,
+			File:             relativeMediaPath(in.threadSavePath, m.LocalPath),
+			Thumbnail:        relativeMediaPath(in.threadSavePath, m.LocalThumbPath),
+			SHA256:           m.SHA256,
+		})
+	}
+
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("thread_data.jsonのシリアライズに失敗しました: %w", err)
+	}
+	path := filepath.Join(in.threadSavePath, threadDataFileName)
+	if err := os.WriteFile(path, encoded, 0644); err != nil {
+		return fmt.Errorf("thread_data.jsonの保存に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
+// sortedResNumbers は、複数のレス番号の集合を合わせて番号順に並べます。
+func sortedResNumbers(sets ...map[string]bool) []string {
+	merged := make(map[string]bool)
+	for _, set := range sets {
+		for no := range set {
+			merged[no] = true
+		}
+	}
+	numbers := sortedKeys(merged)
+	sort.SliceStable(numbers, func(i, j int) bool {
+		a, errA := strconv.ParseInt(numbers[i], 10, 64)
+		b, errB := strconv.ParseInt(numbers[j], 10, 64)
+		if errA != nil || errB != nil {
+			return numbers[i] < numbers[j]
+		}
+		return a < b
+	})
+	return numbers
+}
+
+// relativeMediaPath は、保存したファイルのスレッドのディレクトリからの相対パス（スラッシュ区切り）を返します。
+func relativeMediaPath(threadSavePath, localPath string) string {
+	if localPath == "" || !fileExists(localPath) {
+		return ""
+	}
+	rel, err := filepath.Rel(threadSavePath, localPath)
+	if err != nil {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// singleFileRenderer は、完全版に画像とCSSを埋め込み、1つのファイルで閲覧できるようにしたHTML（archive_single.html）を保存します。
+// 大きなファイル（singleFileMaxInlineBytes を超えるもの）とフルサイズのメディアへのリンクは相対リンクのまま残します。
+type singleFileRenderer struct{}
+
+func (singleFileRenderer) render(in *renderInput) error {
+	var full strings.Builder
+	if err := writeArchiveFullHTML(&full, in.reconstructed, in.deletedPosts, in.removedMedia); err != nil {
+		return fmt.Errorf("1ファイル版の生成に失敗しました: %w", err)
+	}
+	content := localStylesheetPattern.ReplaceAllStringFunc(full.String(), func(match string) string {
+		link := localStylesheetPattern.FindStringSubmatch(match)[1]
+		data, ok := readInlineFile(in.threadSavePath, link)
+		if !ok {
+			return match
+		}
+		return "<style>\n" + string(data) + "\n</style>"
+	})
+	content = localSrcPattern.ReplaceAllStringFunc(content, func(match string) string {
+		link := localSrcPattern.FindStringSubmatch(match)[1]
+		data, ok := readInlineFile(in.threadSavePath, link)
+		if !ok {
+			return match
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(link))
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return `src="data:` + mimeType + `;base64,` + base64.StdEncoding.EncodeToString(data) + `"`
+	})
+
+	path := filepath.Join(in.threadSavePath, singleFileName)
+	if err := writeFileBuffered(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, content)
+		return err
+	}); err != nil {
+		return fmt.Errorf("archive_single.htmlの保存に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
+// readInlineFile は、1ファイル版に埋め込むファイルを読み込みます。存在しない場合や大きすぎる場合は false を返します。
+func readInlineFile(threadSavePath, link string) ([]byte, bool) {
+	path := filepath.Join(threadSavePath, filepath.FromSlash(html.UnescapeString(link)))
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > singleFileMaxInlineBytes {
+		return nil, false
+	}
+	data, err := archivecrypt.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestRenderStrategies(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{"css/futaba.css": "body{color:red}", "thumb/1s.jpg": "thumb", "img/1.jpg": "full"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reconstructed := `<html><head><link rel="stylesheet" href="css/futaba.css"></head><body>` +
+		`<div>No.10</div><div>No.11 <a href="img/1.jpg"><img src="thumb/1s.jpg"></a></div></body></html>`
+	in := &renderInput{
+		task:        config.Task{TargetBoardURL: "https://may.2chan.net/b/"},
+		thread:      model.ThreadInfo{ID: "10", Title: "テスト", URL: "https://may.2chan.net/b/res/10.htm"},
+		htmlContent: `<div>No.10</div><div>No.11</div>`,
+		mediaFiles: []model.MediaInfo{
+			{URL: "https://may.2chan.net/b/src/1.jpg", ResNumber: 11, LocalPath: filepath.Join(dir, "img", "1.jpg"), LocalThumbPath: filepath.Join(dir, "thumb", "1s.jpg")},
+			{URL: "https://may.2chan.net/b/src/2.jpg", Blocked: true},
+		},
+		reconstructed:  reconstructed,
+		deletedPosts:   `<div>No.9</div>`,
+		threadSavePath: dir,
+		logger:         log.New(io.Discard, "", 0),
+	}
+
+	for _, name := range []string{config.RenderJSON, config.RenderSingleFile} {
+		if err := renderStrategies[name].render(in); err != nil {
+			t.Fatalf("render(%s) error = %v", name, err)
+		}
+	}
+	if fileExists(filepath.Join(dir, latestHTMLFileName)) || fileExists(filepath.Join(dir, fullHTMLFileName)) {
+		t.Error("HTML files written although latest/full were not selected")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, threadDataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got threadData
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ThreadID != "10" || len(got.Posts) != 3 || got.Posts[0] != (threadDataPost{ResNumber: "9", Deleted: true}) || got.Posts[2].ResNumber != "11" {
+		t.Errorf("thread_data.json posts = %+v", got)
+	}
+	if len(got.Media) != 1 || got.Media[0].File != "img/1.jpg" || got.Media[0].Thumbnail != "thumb/1s.jpg" {
+		t.Errorf("thread_data.json media = %+v", got.Media)
+	}
+
+	single, err := os.ReadFile(filepath.Join(dir, singleFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(single)
+	for _, want := range []string{"<style>\nbody{color:red}\n</style>", `src="data:image/jpeg;base64,dGh1bWI="`, `href="img/1.jpg"`, "No.9"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("archive_single.html does not contain %q:\n%s", want, content)
+		}
+	}
+}
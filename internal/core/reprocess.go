@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+// ReprocessSummary は、保存済みの生HTMLからの再処理の集計です。
+type ReprocessSummary struct {
+	Processed int // 再処理したスレッド数
+	Skipped   int // raw.html.gz がないため再処理しなかったスレッド数
+	Failed    int // 再処理に失敗したスレッド数
+	Missing   int // ローカルに見つからなかったメディアの数（HTMLのリンクは推測したパスになります）
+}
+
+// reprocessTarget は、再処理の対象となるスレッドの保存先です。
+type reprocessTarget struct {
+	task      config.Task
+	threadID  string
+	threadDir string
+}
+
+// ReprocessThreads は、アーカイブ済みスレッドの raw.html.gz（keep_raw_html で保存した取得時のHTML）から、
+// 現在のアダプタで抽出と再構成をやり直し、index.htm・archive_full.html・thread.json を再生成します。
+// ネットワークにはアクセスせず、メディアはダウンロード済みのローカルファイルを参照します。
+// targets にスレッドIDまたはスレッドURLを指定した場合はそのスレッドのみ、空の場合はすべてのタスクの保存先を対象とします。
+func ReprocessThreads(ctx context.Context, cfg *config.Config, targets []string, logger *log.Logger) (ReprocessSummary, error) {
+	var summary ReprocessSummary
+
+	var jobs []reprocessTarget
+	if len(targets) > 0 {
+		for _, target := range targets {
+			resolved, err := ResolveRearchiveTarget(cfg, target)
+			if err != nil {
+				return summary, err
+			}
+			if resolved.ThreadDir == "" {
+				return summary, fmt.Errorf("スレッド %s はまだアーカイブされていません", resolved.Thread.ID)
+			}
+			jobs = append(jobs, reprocessTarget{task: resolved.Task, threadID: resolved.Thread.ID, threadDir: resolved.ThreadDir})
+		}
+	} else {
+		for _, task := range cfg.Tasks {
+			jobs = append(jobs, taskThreadTargets(task, logger)...)
+		}
+	}
+
+	// アダプタはタスクごとに1つ用意する（Prepare はクライアントの設定のみで、通信は行わない）
+	adapters := make(map[string]adapter.SiteAdapter)
+	client, err := network.NewClient(cfg.Network)
+	if err != nil {
+		return summary, fmt.Errorf("ネットワーククライアントの初期化に失敗しました: %w", err)
+	}
+
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		siteAdapter, ok := adapters[job.task.TaskName]
+		if !ok {
+			if siteAdapter, err = adapter.GetAdapter(job.task.SiteAdapter); err != nil {
+				return summary, fmt.Errorf("サイトアダプタの取得に失敗しました (task=%s): %w", job.task.TaskName, err)
+			}
+			if err := siteAdapter.Prepare(client, job.task); err != nil {
+				return summary, fmt.Errorf("サイト固有設定の適用に失敗しました (task=%s): %w", job.task.TaskName, err)
+			}
+			adapters[job.task.TaskName] = siteAdapter
+		}
+
+		missing, err := reprocessThread(job.task, siteAdapter, job.threadID, job.threadDir, logger)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			summary.Skipped++
+		case err != nil:
+			logger.Printf("WARNING: スレッド %s の再処理に失敗しました (path=%s): %v", job.threadID, job.threadDir, err)
+			summary.Failed++
+		default:
+			logger.Printf("INFO: スレッド %s を再処理しました (path=%s, 見つからないメディア: %d)", job.threadID, job.threadDir, missing)
+			summary.Processed++
+			summary.Missing += missing
+		}
+	}
+	return summary, nil
+}
+
+// taskThreadTargets は、タスクの保存先にあるすべてのスレッドの保存先を、スレッドIDの順に返します。
+func taskThreadTargets(task config.Task, logger *log.Logger) []reprocessTarget {
+	dirs, err := scanThreadDirs(task.SaveRootDirectory)
+	if err != nil {
+		logger.Printf("WARNING: タスク '%s' の保存先の走査に失敗しました: %v", task.TaskName, err)
+		return nil
+	}
+	ids := make([]string, 0, len(dirs))
+	for id := range dirs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var targets []reprocessTarget
+	for _, id := range ids {
+		for _, dir := range dirs[id] {
+			targets = append(targets, reprocessTarget{task: task, threadID: id, threadDir: dir})
+		}
+	}
+	return targets
+}
+
+// reprocessThread は、1件のスレッドを raw.html.gz から再処理し、ローカルに見つからなかったメディアの数を返します。
+// raw.html.gz がない場合は fs.ErrNotExist を含むエラーを返します。
+func reprocessThread(task config.Task, siteAdapter adapter.SiteAdapter, threadID, threadDir string, logger *log.Logger) (int, error) {
+	raw, err := ReadRawHTML(threadDir)
+	if err != nil {
+		return 0, err
+	}
+	htmlContent, err := siteAdapter.ParseThreadHTML(raw)
+	if err != nil {
+		return 0, fmt.Errorf("スレッドHTMLの解析に失敗しました (size=%d bytes): %w", len(raw), err)
+	}
+
+	snapshot, err := LoadThreadSnapshot(threadDir)
+	if err != nil {
+		logger.Printf("WARNING: スナップショットの読み込みに失敗しました: %v", err)
+	}
+	thread := rearchiveThreadInfo(threadID, threadDir)
+	thread.Date = inTaskTimezone(task, thread.Date)
+	thread.Title = fallbackTitle(task, siteAdapter, thread.Title, htmlContent)
+	date, hasDate := threadDate(siteAdapter, htmlContent)
+	if hasDate {
+		thread.Date = inTaskTimezone(task, date)
+	}
+
+	threadURL, err := url.Parse(task.TargetBoardURL)
+	if err != nil {
+		return 0, fmt.Errorf("ターゲットボードURLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
+	}
+	threadURL = joinThreadURL(threadURL, thread.URL)
+
+	mediaFiles, err := siteAdapter.ExtractMediaFiles(htmlContent, threadURL.String())
+	if err != nil {
+		return 0, fmt.Errorf("メディアファイルの抽出に失敗しました: %w", err)
+	}
+	blocklist, err := taskMediaBlocklist(task)
+	if err != nil {
+		return 0, fmt.Errorf("blocked_media_patterns の解析に失敗しました: %w", err)
+	}
+	var knownBlocked map[string]string
+	if snapshot != nil {
+		knownBlocked = snapshot.BlockedMedia
+	}
+	mediaFiles, blockedMedia := partitionBlockedMedia(mediaFiles, blocklist, knownBlocked)
+
+	imgSavePath := filepath.Join(threadDir, "img")
+	thumbSavePath := filepath.Join(threadDir, "thumb")
+	missing := 0
+	for i := range mediaFiles {
+		// サムネイルのみモードではフルサイズがないのが正常なため数えない
+		if !resolveLocalMedia(task, thread, &mediaFiles[i], imgSavePath, thumbSavePath, logger) && !task.ThumbnailsOnly {
+			missing++
+		}
+		applyMediaSelection(task, &mediaFiles[i])
+		applyAnimatedThumbnail(task, &mediaFiles[i], thumbSavePath, logger)
+	}
+
+	if err := saveThreadHTML(task, siteAdapter, thread, htmlContent, append(mediaFiles, blockedMedia...), threadDir, true, logger); err != nil {
+		return missing, err
+	}
+
+	if snapshot == nil {
+		snapshot = &ThreadSnapshot{ThreadID: threadID}
+		snapshot.ObserveTitle(thread.Title, time.Now())
+	}
+	if hasDate && snapshot.CreatedAt == nil {
+		snapshot.CreatedAt = &date
+	}
+	snapshot.PostFeatures = threadPostFeatures(siteAdapter, htmlContent, snapshot.PostFeatures)
+	if err := SaveThreadMetadata(threadDir, threadURL.String(), snapshot); err != nil {
+		logger.Printf("WARNING: thread.jsonの保存に失敗しました: %v", err)
+	}
+	return missing, nil
+}
+
+// resolveLocalMedia は、ダウンロード済みのファイルから media の LocalPath・LocalThumbPath を設定します。
+// ファイル名の形式で生成した名前、元のファイル名、URLのファイル名の順に探し、見つかった場合に true を返します。
+// 見つからない場合は、最初の候補のパスを設定します。HTMLに埋め込まれた画像は、なければ書き出します。
+func resolveLocalMedia(task config.Task, thread model.ThreadInfo, media *model.MediaInfo, imgSavePath, thumbSavePath string, logger *log.Logger) bool {
+	if media.ThumbnailURL != "" {
+		media.LocalThumbPath = filepath.Join(thumbSavePath, filepath.Base(media.ThumbnailURL))
+	}
+
+	if adapter.IsDataURI(media.URL) {
+		media.LocalPath = filepath.Join(imgSavePath, SanitizeFilename(media.OriginalFilename))
+		if fileExists(media.LocalPath) {
+			return true
+		}
+		_, saved := saveInlineMedia(media, imgSavePath, logger)
+		return saved
+	}
+
+	var candidates []string
+	if name, err := generateFileName(task.FilenameFormat, thread, *media); err == nil && name != "" {
+		candidates = append(candidates, name)
+	}
+	if media.OriginalFilename != "" {
+		candidates = append(candidates, media.OriginalFilename)
+	}
+	candidates = append(candidates, filepath.Base(media.URL))
+
+	for _, name := range candidates {
+		if path := filepath.Join(imgSavePath, name); fileExists(path) {
+			media.LocalPath = path
+			return true
+		}
+	}
+	media.LocalPath = filepath.Join(imgSavePath, candidates[0])
+	return false
+}
+
+// fileExists は、path に通常のファイルが存在するかを返します。
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestReprocessThreads(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	withRaw := filepath.Join(root, "123_a")
+	writeTestThreadDir(t, withRaw, "123", 2, "1700000000001.jpg")
+	raw := `<html><head><title>t</title></head><body>` +
+		`<a href="/b/src/1700000000001.jpg">1700000000001.jpg</a>` +
+		`<a href="/b/src/1700000000002.png">1700000000002.png</a> 25/11/18(火)12:34:56 No.123</body></html>`
+	if err := saveRawHTML(withRaw, []byte(raw)); err != nil {
+		t.Fatal(err)
+	}
+	writeTestThreadDir(t, filepath.Join(root, "456_b"), "456", 1, "1700000000003.jpg")
+
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "A", SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: root},
+	}}
+	summary, err := ReprocessThreads(context.Background(), cfg, nil, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("ReprocessThreads() error = %v", err)
+	}
+	if summary.Processed != 1 || summary.Skipped != 1 || summary.Failed != 0 || summary.Missing != 1 {
+		t.Errorf("summary = %+v, want processed=1 skipped=1 failed=0 missing=1", summary)
+	}
+
+	index, err := os.ReadFile(filepath.Join(withRaw, "index.htm"))
+	if err != nil {
+		t.Fatalf("index.htm が再生成されていません: %v", err)
+	}
+	if !strings.Contains(string(index), `href="img/1700000000001.jpg"`) {
+		t.Errorf("ローカルのメディアへのリンクに書き換えられていません:\n%s", index)
+	}
+	for _, name := range []string{"archive_full.html", threadMetadataFile} {
+		if _, err := os.Stat(filepath.Join(withRaw, name)); err != nil {
+			t.Errorf("%s が生成されていません: %v", name, err)
+		}
+	}
+	// raw.html.gz から取得したOPの投稿日時を thread.json に記録する
+	meta, err := LoadThreadMetadata(withRaw)
+	if err != nil || meta == nil || meta.CreatedAt == nil {
+		t.Fatalf("LoadThreadMetadata() = %+v, %v; created_at がありません", meta, err)
+	}
+	if want := time.Date(2025, 11, 18, 3, 34, 56, 0, time.UTC); !meta.CreatedAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", meta.CreatedAt, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, "456_b", "index.htm")); !os.IsNotExist(err) {
+		t.Errorf("raw.html.gz のないスレッドは再処理しないはずです: %v", err)
+	}
+
+	if _, err := ReprocessThreads(context.Background(), cfg, []string{"999"}, log.New(io.Discard, "", 0)); err == nil {
+		t.Error("未アーカイブのスレッドを指定した場合はエラーを返すべきです")
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// resumeTracker は、ダウンロードが完了したファイルをまとめてレジュームファイル（.resume.json）に反映します。
+// ファイルごとにレジュームファイル全体を書き直すと、HDDやNASの保存先では小さな書き込みが集中するため、
+// resume_flush_every 件ごと、または resume_flush_interval_ms ごとに1回だけ書き込みます。
+// 反映前に異常終了した場合も、次回のレジューム時に保存済みのファイルはディスク上の存在確認でスキップされるため、再取得は発生しません。
+// 呼び出し側で排他制御を行ってください。
+type resumeTracker struct {
+	path      string
+	every     int
+	interval  time.Duration
+	sync      bool
+	completed map[string]bool // 前回の反映以降に完了したファイルのURL
+	lastFlush time.Time
+	now       func() time.Time
+}
+
+// newResumeTracker は、タスクの設定に従って path のレジュームファイルを更新する resumeTracker を返します。
+func newResumeTracker(task config.Task, path string) *resumeTracker {
+	every := task.ResumeFlushEvery
+	if every <= 0 {
+		every = config.DefaultResumeFlushEvery
+	}
+	intervalMillis := task.ResumeFlushIntervalMillis
+	if intervalMillis <= 0 {
+		intervalMillis = config.DefaultResumeFlushIntervalMillis
+	}
+	return &resumeTracker{
+		path:      path,
+		every:     every,
+		interval:  time.Duration(intervalMillis) * time.Millisecond,
+		sync:      task.FsyncPolicy == config.FsyncResume || task.FsyncPolicy == config.FsyncAll,
+		completed: make(map[string]bool),
+		lastFlush: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// complete は、url のファイルの完了を記録し、件数または経過時間が閾値に達した場合はレジュームファイルに反映します。
+func (r *resumeTracker) complete(url string) error {
+	r.completed[url] = true
+	if len(r.completed) < r.every && r.now().Sub(r.lastFlush) < r.interval {
+		return nil
+	}
+	return r.flush()
+}
+
+// flush は、記録済みの完了をレジュームファイルに反映します。ダウンロードの終了時（中断時を含む）にも呼び出します。
+func (r *resumeTracker) flush() error {
+	if len(r.completed) == 0 {
+		return nil
+	}
+	r.lastFlush = r.now()
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+	var pendingFiles []model.MediaInfo
+	if err := json.Unmarshal(data, &pendingFiles); err != nil {
+		return err
+	}
+
+	var newPendingFiles []model.MediaInfo
+	for _, file := range pendingFiles {
+		if !r.completed[file.URL] {
+			newPendingFiles = append(newPendingFiles, file)
+		}
+	}
+	newData, err := json.MarshalIndent(newPendingFiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(r.path, newData, 0644, r.sync); err != nil {
+		return err
+	}
+	r.completed = make(map[string]bool)
+	return nil
+}
+
+// writeFileSync は、os.WriteFile と同様にファイルを書き込み、sync が true の場合はディスクへの同期（fsync）を待ちます。
+func writeFileSync(path string, data []byte, perm os.FileMode, sync bool) error {
+	if !sync {
+		return os.WriteFile(path, data, perm)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("ディスクへの同期に失敗しました: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// writePendingFiles は、n 件の未完了ファイルを含むレジュームファイルを作成し、そのURLを返します。
+func writePendingFiles(tb testing.TB, path string, n int) []string {
+	tb.Helper()
+	files := make([]model.MediaInfo, n)
+	urls := make([]string, n)
+	for i := range files {
+		urls[i] = fmt.Sprintf("https://example.com/src/%d.jpg", i)
+		files[i] = model.MediaInfo{URL: urls[i]}
+	}
+	data, err := json.Marshal(files)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return urls
+}
+
+func readPendingCount(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []model.MediaInfo
+	if err := json.Unmarshal(data, &files); err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestResumeTracker(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		task        config.Task
+		complete    int
+		advance     time.Duration // 最後の完了の前に進める時間
+		wantPending int           // flush を呼ぶ前の未完了の件数
+	}{
+		{name: "件数に達するまで反映しない", task: config.Task{ResumeFlushEvery: 3}, complete: 2, wantPending: 5},
+		{name: "件数に達すると反映する", task: config.Task{ResumeFlushEvery: 3}, complete: 3, wantPending: 2},
+		{name: "間隔を過ぎると反映する", task: config.Task{ResumeFlushEvery: 100, ResumeFlushIntervalMillis: 1000}, complete: 2, advance: 2 * time.Second, wantPending: 3},
+		{name: "fsyncあり", task: config.Task{ResumeFlushEvery: 1, FsyncPolicy: config.FsyncResume}, complete: 1, wantPending: 4},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), ".resume.json")
+			urls := writePendingFiles(t, path, 5)
+
+			current := time.Now()
+			tracker := newResumeTracker(tt.task, path)
+			tracker.now = func() time.Time { return current }
+			for i := 0; i < tt.complete; i++ {
+				if i == tt.complete-1 {
+					current = current.Add(tt.advance)
+				}
+				if err := tracker.complete(urls[i]); err != nil {
+					t.Fatalf("complete() error = %v", err)
+				}
+			}
+			if got := readPendingCount(t, path); got != tt.wantPending {
+				t.Errorf("未完了の件数 = %d, want %d", got, tt.wantPending)
+			}
+
+			// 終了時の flush で残りがすべて反映される
+			if err := tracker.flush(); err != nil {
+				t.Fatalf("flush() error = %v", err)
+			}
+			if got := readPendingCount(t, path); got != 5-tt.complete {
+				t.Errorf("flush 後の未完了の件数 = %d, want %d", got, 5-tt.complete)
+			}
+		})
+	}
+}
+
+// benchmarkResumeTracker は、500件のファイルの完了をレジュームファイルに反映する時間を計測します。
+//
+//	go test ./internal/core -run '^$' -bench Resume -benchmem
+func benchmarkResumeTracker(b *testing.B, task config.Task) {
+	path := filepath.Join(b.TempDir(), ".resume.json")
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		urls := writePendingFiles(b, path, 500)
+		tracker := newResumeTracker(task, path)
+		b.StartTimer()
+		for _, url := range urls {
+			if err := tracker.complete(url); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := tracker.flush(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkResume_EveryFile は、従来のファイルごとにレジュームファイルを書き直す方式です。
+func BenchmarkResume_EveryFile(b *testing.B) {
+	benchmarkResumeTracker(b, config.Task{ResumeFlushEvery: 1})
+}
+
+// BenchmarkResume_Batched は、既定の resume_flush_every でまとめて書き込む方式です。
+func BenchmarkResume_Batched(b *testing.B) {
+	benchmarkResumeTracker(b, config.Task{})
+}
+
+// BenchmarkResume_EveryFileFsync は、ファイルごとに書き直して fsync する方式です。
+func BenchmarkResume_EveryFileFsync(b *testing.B) {
+	benchmarkResumeTracker(b, config.Task{ResumeFlushEvery: 1, FsyncPolicy: config.FsyncResume})
+}
+
+// BenchmarkResume_BatchedFsync は、まとめて書き込んで fsync する方式です。
+func BenchmarkResume_BatchedFsync(b *testing.B) {
+	benchmarkResumeTracker(b, config.Task{FsyncPolicy: config.FsyncResume})
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+)
+
+// リトライポリシーのエラー種別（retry_policies のキー）
+const (
+	RetryClassTimeout      = "timeout"
+	RetryClassServerError  = "server_error"
+	RetryClassRateLimited  = "rate_limited"
+	RetryClassWriteFailure = "write_failure"
+	RetryClassOther        = "other"
+)
+
+// RetryExhaustedError は、エラー種別ごとのリトライ上限に達したことを表します。
+// Requeue が true の場合、呼び出し側は対象を次回のサイクルで再取得するべきです。
+type RetryExhaustedError struct {
+	Class    string
+	Attempts int
+	Requeue  bool
+	Err      error
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("リトライ上限に達しました (class=%s, attempts=%d): %v", e.Class, e.Attempts, e.Err)
+}
+
+// Unwrap は、最後に発生したエラーを返します。
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// classifyRetryError は、エラーをリトライポリシーの種別に分類します。
+func classifyRetryError(err error) string {
+	switch {
+	case errors.Is(err, errs.ErrTimeout):
+		return RetryClassTimeout
+	case errors.Is(err, errs.ErrRateLimited):
+		return RetryClassRateLimited
+	case errors.Is(err, errs.ErrServer):
+		return RetryClassServerError
+	case errors.Is(err, errs.ErrWriteFailed):
+		return RetryClassWriteFailure
+	default:
+		return RetryClassOther
+	}
+}
+
+// resolveRetryPolicy は、タスク設定から指定された種別のリトライポリシーを返します。
+// 種別ごとの設定がない場合は、従来の retry_count / retry_wait_ms から一定間隔のポリシーを生成します。
+func resolveRetryPolicy(task config.Task, class string) config.RetryPolicy {
+	if policy, ok := task.RetryPolicies[class]; ok {
+		return policy
+	}
+	return config.RetryPolicy{
+		RetryCount: task.RetryCount,
+		WaitMillis: task.RetryWaitMillis,
+	}
+}
+
+// retryWait は、attempt 回目（1始まり）のリトライ前に待機する時間を計算します。
+func retryWait(policy config.RetryPolicy, attempt int) time.Duration {
+	wait := float64(policy.WaitMillis)
+	if policy.BackoffMultiplier > 1 && attempt > 1 {
+		wait *= math.Pow(policy.BackoffMultiplier, float64(attempt-1))
+	}
+	return time.Duration(wait) * time.Millisecond
+}
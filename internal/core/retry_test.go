@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+)
+
+func TestClassifyRetryError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"タイムアウト", fmt.Errorf("%w: x", errs.ErrTimeout), RetryClassTimeout},
+		{"サーバーエラー", fmt.Errorf("%w: x", errs.ErrServer), RetryClassServerError},
+		{"レート制限", fmt.Errorf("%w: x", errs.ErrRateLimited), RetryClassRateLimited},
+		{"書き込み失敗", fmt.Errorf("%w: x", errs.ErrWriteFailed), RetryClassWriteFailure},
+		{"その他", errors.New("unknown"), RetryClassOther},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := classifyRetryError(tt.err); got != tt.want {
+				t.Errorf("classifyRetryError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRetryPolicyAndWait(t *testing.T) {
+	t.Parallel()
+
+	task := config.Task{
+		RetryCount:      3,
+		RetryWaitMillis: 500,
+		RetryPolicies: map[string]config.RetryPolicy{
+			RetryClassTimeout: {RetryCount: 5, WaitMillis: 100, BackoffMultiplier: 2},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		class     string
+		attempt   int
+		wantCount int
+		wantWait  time.Duration
+	}{
+		{"設定済み種別の初回", RetryClassTimeout, 1, 5, 100 * time.Millisecond},
+		{"設定済み種別のバックオフ", RetryClassTimeout, 3, 5, 400 * time.Millisecond},
+		{"未設定種別はタスク設定を使用", RetryClassServerError, 2, 3, 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			policy := resolveRetryPolicy(task, tt.class)
+			if policy.RetryCount != tt.wantCount {
+				t.Errorf("RetryCount = %d, want %d", policy.RetryCount, tt.wantCount)
+			}
+			if got := retryWait(policy, tt.attempt); got != tt.wantWait {
+				t.Errorf("retryWait() = %v, want %v", got, tt.wantWait)
+			}
+		})
+	}
+}
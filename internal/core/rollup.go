@@ -0,0 +1,268 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// rollupDirName は、保存先のルートに作成する期間ごとの一覧ページのディレクトリ名です。
+const rollupDirName = "_archive"
+
+// rollupIndexFile は、一覧ページの目次のファイル名です。
+const rollupIndexFile = "index.html"
+
+// rollupMu は、一覧ページの更新を直列化します。複数のタスクが同じ保存先を共有する場合や、
+// 1つのタスクで複数のスレッドが同時に完了する場合に、データファイルの更新が失われないようにします。
+var rollupMu sync.Mutex
+
+// rollupEntry は、一覧ページに掲載する1件のスレッドです。
+type rollupEntry struct {
+	TaskName   string    `json:"task_name"`
+	Board      string    `json:"board"`
+	ThreadID   string    `json:"thread_id"`
+	Title      string    `json:"title"`
+	Path       string    `json:"path"` // 保存先のルートからのスレッドのディレクトリ（スラッシュ区切り）
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// rollupPeriod は、一覧ページの期間（1週間または1か月）です。
+type rollupPeriod struct {
+	Kind  string // config.RollupWeekly または config.RollupMonthly
+	Key   string // ファイル名に使用するキー（2025-W47, 2025-11）
+	Label string // ページに表示する期間の名前
+}
+
+// newRollupPeriod は、日時 t を含む期間を返します。週は ISO 8601（月曜始まり）で数えます。
+func newRollupPeriod(kind string, t time.Time) rollupPeriod {
+	if kind == config.RollupWeekly {
+		year, week := t.ISOWeek()
+		offset := (int(t.Weekday()) + 6) % 7
+		monday := time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+		sunday := monday.AddDate(0, 0, 6)
+		return rollupPeriod{
+			Kind:  kind,
+			Key:   fmt.Sprintf("%04d-W%02d", year, week),
+			Label: fmt.Sprintf("%d年 第%d週（%d/%d〜%d/%d）", year, week, monday.Month(), monday.Day(), sunday.Month(), sunday.Day()),
+		}
+	}
+	return rollupPeriod{
+		Kind:  config.RollupMonthly,
+		Key:   t.Format("2006-01"),
+		Label: fmt.Sprintf("%d年%d月", t.Year(), t.Month()),
+	}
+}
+
+// updateRollupPages は、アーカイブが完了したスレッドを、完了日時を含む期間の一覧ページに追加（既にあれば更新）します。
+// 一覧ページは保存先のルートの _archive/ に、期間ごとのHTMLと目次（index.html）として生成され、Web UIなしで閲覧できます。
+func updateRollupPages(task config.Task, thread model.ThreadInfo, title, threadSavePath string, archivedAt time.Time) error {
+	if len(task.RollupPages) == 0 {
+		return nil
+	}
+	relPath, err := filepath.Rel(task.SaveRootDirectory, threadSavePath)
+	if err != nil {
+		return fmt.Errorf("スレッドの保存先を保存先のルートからの相対パスにできません (path=%s): %w", threadSavePath, err)
+	}
+	entry := rollupEntry{
+		TaskName:   task.TaskName,
+		Board:      task.TargetBoardURL,
+		ThreadID:   thread.ID,
+		Title:      title,
+		Path:       filepath.ToSlash(relPath),
+		ArchivedAt: archivedAt,
+	}
+
+	rollupMu.Lock()
+	defer rollupMu.Unlock()
+
+	dir := filepath.Join(task.SaveRootDirectory, rollupDirName)
+	for _, kind := range task.RollupPages {
+		period := newRollupPeriod(kind, archivedAt)
+		entries, err := loadRollupEntries(dir, period.Key)
+		if err != nil {
+			return err
+		}
+		entries = upsertRollupEntry(entries, entry)
+		if err := saveRollupEntries(dir, period.Key, entries); err != nil {
+			return err
+		}
+		if err := writeRollupFile(filepath.Join(dir, period.Key+".html"), rollupPageHTML(period, entries)); err != nil {
+			return err
+		}
+	}
+	return writeRollupIndex(dir)
+}
+
+// rollupDataPath は、期間の一覧ページのデータファイルのパスです。
+func rollupDataPath(dir, key string) string {
+	return filepath.Join(dir, ".giba", key+".json")
+}
+
+// loadRollupEntries は、期間の一覧ページのデータを読み込みます。ファイルが存在しない場合は空のリストを返します。
+func loadRollupEntries(dir, key string) ([]rollupEntry, error) {
+	path := rollupDataPath(dir, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("一覧ページのデータの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	var entries []rollupEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("一覧ページのデータの解析に失敗しました (path=%s): %w", path, err)
+	}
+	return entries, nil
+}
+
+// saveRollupEntries は、期間の一覧ページのデータを書き込みます。
+func saveRollupEntries(dir, key string, entries []rollupEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("一覧ページのデータのシリアライズに失敗しました: %w", err)
+	}
+	return writeRollupFile(rollupDataPath(dir, key), data)
+}
+
+// writeRollupFile は、ブラウザで開いている一覧ページが書き込み途中にならないよう、一時ファイルに書いてからリネームします。
+func writeRollupFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("一覧ページのディレクトリ作成に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("一覧ページの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("一覧ページの更新に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
+// upsertRollupEntry は、同じタスク・スレッドの項目を置き換えるか、なければ追加します。
+func upsertRollupEntry(entries []rollupEntry, entry rollupEntry) []rollupEntry {
+	for i := range entries {
+		if entries[i].TaskName == entry.TaskName && entries[i].ThreadID == entry.ThreadID {
+			entries[i] = entry
+			return entries
+		}
+	}
+	return append(entries, entry)
+}
+
+// rollupPageHTML は、期間の一覧ページを生成します。スレッドはタスク（掲示板）ごとにまとめ、新しい順に並べます。
+func rollupPageHTML(period rollupPeriod, entries []rollupEntry) []byte {
+	groups := make(map[string][]rollupEntry)
+	var taskNames []string
+	for _, e := range entries {
+		if _, ok := groups[e.TaskName]; !ok {
+			taskNames = append(taskNames, e.TaskName)
+		}
+		groups[e.TaskName] = append(groups[e.TaskName], e)
+	}
+	sort.Strings(taskNames)
+
+	var b strings.Builder
+	writeRollupHead(&b, period.Label)
+	fmt.Fprintf(&b, "<p><a href=\"%s\">一覧に戻る</a> | %d 件</p>\n", rollupIndexFile, len(entries))
+	for _, name := range taskNames {
+		group := groups[name]
+		sort.Slice(group, func(i, j int) bool { return group[i].ArchivedAt.After(group[j].ArchivedAt) })
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<p class=\"board\">%s</p>\n<ul>\n", html.EscapeString(name), html.EscapeString(group[0].Board))
+		for _, e := range group {
+			title := e.Title
+			if title == "" {
+				title = e.ThreadID
+			}
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a> <span class=\"meta\">No.%s | %s</span></li>\n",
+				html.EscapeString(rollupThreadLink(e.Path)), html.EscapeString(title),
+				html.EscapeString(e.ThreadID), e.ArchivedAt.Format("2006-01-02 15:04"))
+		}
+		b.WriteString("</ul>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return []byte(b.String())
+}
+
+// rollupThreadLink は、一覧ページ（_archive/ 内）からスレッドの index.htm へのリンクを返します。
+// タイトルを含むディレクトリ名に #・?・空白などがあっても壊れないよう、パスとしてエスケープします。
+func rollupThreadLink(path string) string {
+	return (&url.URL{Path: "../" + path + "/index.htm"}).String()
+}
+
+// rollupIndexItem は、目次に掲載する1つの期間です。
+type rollupIndexItem struct {
+	key   string
+	label string
+	count int
+}
+
+// writeRollupIndex は、データファイルのある期間をすべて列挙した目次（index.html）を書き込みます。
+func writeRollupIndex(dir string) error {
+	dataFiles, err := os.ReadDir(filepath.Join(dir, ".giba"))
+	if err != nil {
+		return fmt.Errorf("一覧ページのデータの列挙に失敗しました: %w", err)
+	}
+
+	items := make(map[string][]rollupIndexItem)
+	for _, f := range dataFiles {
+		key, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		entries, err := loadRollupEntries(dir, key)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		kind := config.RollupMonthly
+		if strings.Contains(key, "-W") {
+			kind = config.RollupWeekly
+		}
+		label := newRollupPeriod(kind, entries[0].ArchivedAt).Label
+		items[kind] = append(items[kind], rollupIndexItem{key: key, label: label, count: len(entries)})
+	}
+
+	var b strings.Builder
+	writeRollupHead(&b, "アーカイブ一覧")
+	for _, section := range []struct{ kind, heading string }{
+		{config.RollupMonthly, "月ごと"},
+		{config.RollupWeekly, "週ごと"},
+	} {
+		list := items[section.kind]
+		if len(list) == 0 {
+			continue
+		}
+		// 新しい期間を先頭に並べる（キーは年・週または年・月の順のため、文字列の降順で新しい順になる）
+		sort.Slice(list, func(i, j int) bool { return list[i].key > list[j].key })
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<ul>\n", section.heading)
+		for _, item := range list {
+			fmt.Fprintf(&b, "<li><a href=\"%s.html\">%s</a> <span class=\"meta\">%d 件</span></li>\n",
+				html.EscapeString(item.key), html.EscapeString(item.label), item.count)
+		}
+		b.WriteString("</ul>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	return writeRollupFile(filepath.Join(dir, rollupIndexFile), []byte(b.String()))
+}
+
+// writeRollupHead は、一覧ページ共通のヘッダーを書き込みます。
+func writeRollupHead(b *strings.Builder, title string) {
+	escaped := html.EscapeString(title)
+	fmt.Fprintf(b, "<!DOCTYPE html>\n<html lang=\"ja\">\n<head>\n<meta charset=\"UTF-8\">\n<title>%s - GIBA</title>\n", escaped)
+	b.WriteString("<style>body{font-family:sans-serif;margin:16px;color:#333}h2{margin:20px 0 0;font-size:1.1em}" +
+		".board{margin:2px 0;color:#888;font-size:small}.meta{color:#888;font-size:small}li{margin:2px 0}</style>\n")
+	fmt.Fprintf(b, "</head>\n<body>\n<h1>%s</h1>\n", escaped)
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestNewRollupPeriod(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		kind      string
+		date      time.Time
+		wantKey   string
+		wantLabel string
+	}{
+		{name: "週", kind: config.RollupWeekly, date: time.Date(2025, 11, 19, 10, 0, 0, 0, time.UTC), wantKey: "2025-W47", wantLabel: "2025年 第47週（11/17〜11/23）"},
+		{name: "日曜日は前の月曜からの週", kind: config.RollupWeekly, date: time.Date(2025, 11, 23, 23, 59, 0, 0, time.UTC), wantKey: "2025-W47", wantLabel: "2025年 第47週（11/17〜11/23）"},
+		{name: "年をまたぐ週", kind: config.RollupWeekly, date: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), wantKey: "2025-W01", wantLabel: "2025年 第1週（12/30〜1/5）"},
+		{name: "月", kind: config.RollupMonthly, date: time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC), wantKey: "2025-03", wantLabel: "2025年3月"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := newRollupPeriod(tt.kind, tt.date)
+			if got.Key != tt.wantKey || got.Label != tt.wantLabel {
+				t.Errorf("newRollupPeriod() = (%q, %q), want (%q, %q)", got.Key, got.Label, tt.wantKey, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestUpdateRollupPages(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	rollup := []string{config.RollupWeekly, config.RollupMonthly}
+	taskA := config.Task{TaskName: "A", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: root, RollupPages: rollup}
+	taskB := config.Task{TaskName: "B", TargetBoardURL: "https://img.2chan.net/b/", SaveRootDirectory: root, RollupPages: []string{config.RollupMonthly}}
+	now := time.Date(2025, 11, 19, 10, 0, 0, 0, time.UTC)
+
+	updates := []struct {
+		task  config.Task
+		id    string
+		title string
+		dir   string
+	}{
+		{taskA, "100", "古いタイトル", "A/100_スレ #1"},
+		{taskB, "200", "別の板", "B/200"},
+		{taskA, "100", "新しいタイトル", "A/100_スレ #1"}, // 再アーカイブは同じ項目を更新する
+	}
+	for i, u := range updates {
+		thread := model.ThreadInfo{ID: u.id}
+		if err := updateRollupPages(u.task, thread, u.title, filepath.Join(root, filepath.FromSlash(u.dir)), now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("updateRollupPages() error = %v", err)
+		}
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(root, rollupDirName, name))
+		if err != nil {
+			t.Fatalf("%s が生成されていません: %v", name, err)
+		}
+		return string(data)
+	}
+
+	monthly := read("2025-11.html")
+	for _, want := range []string{"<h2>A</h2>", "<h2>B</h2>", "新しいタイトル", "別の板", `href="../A/100_%E3%82%B9%E3%83%AC%20%231/index.htm"`} {
+		if !strings.Contains(monthly, want) {
+			t.Errorf("月ごとの一覧ページに %q がありません:\n%s", want, monthly)
+		}
+	}
+	if strings.Contains(monthly, "古いタイトル") {
+		t.Error("再アーカイブしたスレッドが重複しています")
+	}
+	if strings.Index(monthly, "<h2>A</h2>") > strings.Index(monthly, "<h2>B</h2>") {
+		t.Error("タスクごとに名前順でまとめられていません")
+	}
+
+	weekly := read("2025-W47.html")
+	if !strings.Contains(weekly, "新しいタイトル") || strings.Contains(weekly, "別の板") {
+		t.Errorf("週ごとの一覧ページは rollup_pages に weekly を含むタスクのみ掲載すべきです:\n%s", weekly)
+	}
+
+	index := read(rollupIndexFile)
+	for _, want := range []string{`href="2025-11.html">2025年11月</a> <span class="meta">2 件`, `href="2025-W47.html">2025年 第47週`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("目次に %q がありません:\n%s", want, index)
+		}
+	}
+}
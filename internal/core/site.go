@@ -0,0 +1,426 @@
+package core
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/version"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultSiteDir は、giba site build の出力先を省略した場合のディレクトリです。
+const DefaultSiteDir = "site"
+
+// siteExcerptLength は、検索対象と一覧に含めるOP本文の長さ（書記素クラスタ数）です。
+const siteExcerptLength = 120
+
+// siteRecentCount は、トップページに表示する最近のスレッドの件数です。
+const siteRecentCount = 50
+
+//go:embed site_assets/*
+var siteAssets embed.FS
+
+// SiteSummary は、静的サイトの生成結果の集計です。
+type SiteSummary struct {
+	Threads int // 掲載したスレッド数
+	Tasks   int // タスクのページ数
+	Tags    int // タグのページ数
+	Skipped int // 出力先からの相対パスにできないなどの理由で掲載しなかったスレッド数
+}
+
+// siteThread は、静的サイトに掲載する1件のスレッドです。
+type siteThread struct {
+	TaskName string
+	Board    string
+	Tags     []string
+	ThreadID string
+	Title    string
+	Excerpt  string    // OP本文の先頭
+	Date     time.Time // スレッドの作成日時（不明な場合は最終更新日時）
+	Link     string    // サイトのルートからスレッドの index.htm への相対URL（未エスケープ）
+}
+
+// siteSearchDoc は、検索インデックスの1件の文書です。JSONのサイズを抑えるため、キーは1文字にしています。
+type siteSearchDoc struct {
+	Title   string   `json:"t"`
+	Task    string   `json:"k"`
+	Tags    []string `json:"g,omitempty"`
+	ID      string   `json:"i"`
+	Date    string   `json:"d"`
+	Link    string   `json:"u"`
+	Excerpt string   `json:"x,omitempty"`
+}
+
+// siteSearchIndex は、あらかじめ生成しておく検索インデックス（search-index.json）です。
+// 日本語は単語の区切りがないため、正規化した文字列の2文字（bigram）ごとに文書番号の一覧を持ちます。
+type siteSearchIndex struct {
+	Docs  []siteSearchDoc  `json:"docs"`
+	Grams map[string][]int `json:"grams"`
+}
+
+// SiteLinkFunc は、保存先のルート root にあるスレッドのディレクトリ threadDir を、
+// サイトのルートからの相対URL（スラッシュ区切り・未エスケープ）に変換します。
+type SiteLinkFunc func(root, threadDir string) (string, error)
+
+// BuildSite は、すべてのタスクの保存先にあるアーカイブから、静的ファイルのみで閲覧できるWebサイトを outDir に生成します。
+// トップページ・タスクごとのページ・タグごとのページと、事前に生成した検索インデックスを使う検索ページを出力します。
+// スレッドは出力先からの相対リンクで参照するため、出力先と保存先の両方を含むディレクトリを静的ファイルサーバーで公開します。
+func BuildSite(ctx context.Context, cfg *config.Config, outDir string, logger *log.Logger) (SiteSummary, error) {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return SiteSummary{}, fmt.Errorf("出力先のパスを解決できません (path=%s): %w", outDir, err)
+	}
+
+	files, summary, err := RenderSite(ctx, cfg, func(_, threadDir string) (string, error) {
+		absDir, err := filepath.Abs(threadDir)
+		if err != nil {
+			return "", err
+		}
+		rel, err := filepath.Rel(absOut, absDir)
+		if err != nil {
+			return "", fmt.Errorf("出力先からの相対パスにできません: %w", err)
+		}
+		return filepath.ToSlash(rel), nil
+	}, logger)
+	if err != nil {
+		return summary, err
+	}
+
+	// 前回の生成で作成したページのうち、タスクやタグの削除で不要になったものが残らないようにする
+	for _, dir := range []string{"tasks", "tags"} {
+		if err := os.RemoveAll(filepath.Join(absOut, dir)); err != nil {
+			return summary, fmt.Errorf("古いページの削除に失敗しました (path=%s): %w", dir, err)
+		}
+	}
+	for name, data := range files {
+		path := filepath.Join(absOut, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return summary, fmt.Errorf("出力先のディレクトリ作成に失敗しました (path=%s): %w", path, err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return summary, fmt.Errorf("ページの書き込みに失敗しました (path=%s): %w", path, err)
+		}
+	}
+	return summary, nil
+}
+
+// RenderSite は、静的サイトのページ・検索インデックス・CSS・JavaScriptを、サイトのルートからのパス（スラッシュ区切り）ごとに生成します。
+// スレッドへのリンクは linkFor で決めるため、ファイルに書き出す（BuildSite）ほか、Web UIの共有モードのように配信することもできます。
+func RenderSite(ctx context.Context, cfg *config.Config, linkFor SiteLinkFunc, logger *log.Logger) (map[string][]byte, SiteSummary, error) {
+	var summary SiteSummary
+	threads, skipped, err := collectSiteThreads(ctx, cfg, linkFor, logger)
+	if err != nil {
+		return nil, summary, err
+	}
+	summary.Threads, summary.Skipped = len(threads), skipped
+	sort.SliceStable(threads, func(i, j int) bool { return threads[i].Date.After(threads[j].Date) })
+
+	byTask := make(map[string][]siteThread)
+	byTag := make(map[string][]siteThread)
+	for _, th := range threads {
+		byTask[th.TaskName] = append(byTask[th.TaskName], th)
+		for _, tag := range th.Tags {
+			byTag[tag] = append(byTag[tag], th)
+		}
+	}
+	taskNames, tagNames := sortedKeys(byTask), sortedKeys(byTag)
+	summary.Tasks, summary.Tags = len(taskNames), len(tagNames)
+
+	index, err := json.Marshal(newSiteSearchIndex(threads))
+	if err != nil {
+		return nil, summary, fmt.Errorf("検索インデックスのシリアライズに失敗しました: %w", err)
+	}
+	files := map[string][]byte{
+		"index.html":        siteIndexHTML(threads, taskNames, tagNames, byTask, byTag),
+		"search.html":       siteSearchHTML(),
+		"search-index.json": index,
+	}
+	for _, name := range taskNames {
+		files["tasks/"+sitePageName(name)] = siteListHTML("タスク: "+name, byTask[name][0].Board, byTask[name])
+	}
+	for _, tag := range tagNames {
+		files["tags/"+sitePageName(tag)] = siteListHTML("タグ: "+tag, "", byTag[tag])
+	}
+	for _, asset := range []string{"search.js", "style.css"} {
+		data, err := siteAssets.ReadFile("site_assets/" + asset)
+		if err != nil {
+			return nil, summary, fmt.Errorf("サイトのファイル %s を読み込めません: %w", asset, err)
+		}
+		files[asset] = data
+	}
+	return files, summary, nil
+}
+
+// ArchiveRoots は、設定されたタスクの保存先のルートを重複なしで、最初に現れた順に返します。
+func ArchiveRoots(cfg *config.Config) []string {
+	var roots []string
+	seen := make(map[string]bool)
+	for _, task := range cfg.Tasks {
+		if task.SaveRootDirectory == "" || seen[task.SaveRootDirectory] {
+			continue
+		}
+		seen[task.SaveRootDirectory] = true
+		roots = append(roots, task.SaveRootDirectory)
+	}
+	return roots
+}
+
+// collectSiteThreads は、すべてのタスクの保存先を走査して掲載するスレッドを集めます。
+// 複数のタスクが同じ保存先を共有する場合は、thread.json の元URLの板が一致するタスクのスレッドとして扱います。
+func collectSiteThreads(ctx context.Context, cfg *config.Config, linkFor SiteLinkFunc, logger *log.Logger) ([]siteThread, int, error) {
+	// サイトアダプタを取得できないタスク（設定の誤りなど）は、そのタスクのスレッドを掲載しない
+	tasksByRoot := make(map[string][]config.Task)
+	adapters := make(map[string]adapter.SiteAdapter)
+	for _, task := range cfg.Tasks {
+		if _, ok := adapters[task.SiteAdapter]; !ok {
+			siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+			if err != nil {
+				logger.Printf("WARNING: タスク '%s' のサイトアダプタを取得できないため、サイトに掲載しません: %v", task.TaskName, err)
+				continue
+			}
+			adapters[task.SiteAdapter] = siteAdapter
+		}
+		tasksByRoot[task.SaveRootDirectory] = append(tasksByRoot[task.SaveRootDirectory], task)
+	}
+
+	var threads []siteThread
+	skipped := 0
+	for _, root := range ArchiveRoots(cfg) {
+		if len(tasksByRoot[root]) == 0 {
+			continue
+		}
+		dirs, err := scanThreadDirs(root)
+		if err != nil {
+			logger.Printf("WARNING: 保存先 %s の走査に失敗しました: %v", root, err)
+			continue
+		}
+		for _, id := range sortedKeys(dirs) {
+			if err := ctx.Err(); err != nil {
+				return nil, 0, err
+			}
+			dir := pickPrimaryThreadDir(dirs[id])
+			th, err := loadSiteThread(tasksByRoot[root], adapters, id, dir)
+			if err == nil {
+				th.Link, err = linkFor(root, dir)
+				th.Link += "/index.htm"
+			}
+			if err != nil {
+				logger.Printf("WARNING: スレッド %s をサイトに掲載できません (path=%s): %v", id, dir, err)
+				skipped++
+				continue
+			}
+			threads = append(threads, th)
+		}
+	}
+	return threads, skipped, nil
+}
+
+// loadSiteThread は、スレッドのディレクトリから掲載する情報を読み込みます。
+// adapters には tasks のすべてのサイトアダプタが含まれている必要があります。
+func loadSiteThread(tasks []config.Task, adapters map[string]adapter.SiteAdapter, threadID, threadDir string) (siteThread, error) {
+	th := siteThread{ThreadID: threadID, Title: "Thread " + threadID}
+	meta, err := LoadThreadMetadata(threadDir)
+	if err != nil {
+		return siteThread{}, err
+	}
+	var sourceURL string
+	if meta != nil {
+		sourceURL = meta.URL
+		if meta.Title != "" {
+			th.Title = meta.Title
+		}
+		th.Date = meta.UpdatedAt
+		if meta.CreatedAt != nil {
+			th.Date = *meta.CreatedAt
+		}
+	} else if snapshot, err := LoadThreadSnapshot(threadDir); err == nil && snapshot != nil {
+		if title := snapshot.DisplayTitle(); title != "" {
+			th.Title = title
+		}
+		th.Date = snapshot.LastChecked
+	}
+
+	task := siteTaskFor(tasks, sourceURL)
+	th.TaskName, th.Board, th.Tags = task.TaskName, task.TargetBoardURL, task.Tags
+	th.Date = inTaskTimezone(task, th.Date)
+
+	// OP本文はアダプタが対応している場合のみ抽出する（index.htm がない場合は本文なしで掲載する）
+	if extractor, ok := adapters[task.SiteAdapter].(adapter.OPTextExtractor); ok {
+		if content, err := archivecrypt.ReadFile(filepath.Join(threadDir, "index.htm")); err == nil {
+			th.Excerpt = TruncateGraphemes(strings.TrimSpace(extractor.ExtractOPText(string(content))), siteExcerptLength)
+		}
+	}
+	return th, nil
+}
+
+// siteTaskFor は、スレッドの元URLの板が一致するタスクを返します。一致するものがない場合は最初のタスクを返します。
+func siteTaskFor(tasks []config.Task, sourceURL string) config.Task {
+	if m := threadURLPattern.FindStringSubmatch(sourceURL); m != nil {
+		for _, task := range tasks {
+			if sameBoard(task.TargetBoardURL, m[1]) {
+				return task
+			}
+		}
+	}
+	return tasks[0]
+}
+
+// newSiteSearchIndex は、スレッドの一覧から検索インデックスを作成します。
+func newSiteSearchIndex(threads []siteThread) siteSearchIndex {
+	index := siteSearchIndex{Docs: make([]siteSearchDoc, 0, len(threads)), Grams: make(map[string][]int)}
+	for i, th := range threads {
+		index.Docs = append(index.Docs, siteSearchDoc{
+			Title: th.Title, Task: th.TaskName, Tags: th.Tags, ID: th.ThreadID,
+			Date: th.Date.Format("2006-01-02"), Link: th.Link, Excerpt: th.Excerpt,
+		})
+		text := strings.Join(append([]string{th.Title, th.Excerpt, th.TaskName, th.ThreadID}, th.Tags...), " ")
+		for _, gram := range siteBigrams(text) {
+			index.Grams[gram] = append(index.Grams[gram], i)
+		}
+	}
+	return index
+}
+
+// siteBigrams は、NFKC正規化・小文字化した文字列に含まれる、空白を含まない2文字の組を重複なしで返します。
+// search.js はクエリを同じ方法で分割し、すべての組を含む文書を検索結果とします。
+func siteBigrams(text string) []string {
+	runes := []rune(strings.ToLower(norm.NFKC.String(text)))
+	seen := make(map[string]struct{})
+	var grams []string
+	for i := 0; i+1 < len(runes); i++ {
+		if unicode.IsSpace(runes[i]) || unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		gram := string(runes[i : i+2])
+		if _, ok := seen[gram]; ok {
+			continue
+		}
+		seen[gram] = struct{}{}
+		grams = append(grams, gram)
+	}
+	return grams
+}
+
+// sitePageName は、タスク名・タグ名からページのファイル名を作成します。
+func sitePageName(name string) string {
+	return SanitizeFilename(name) + ".html"
+}
+
+// sitePageLink は、ページの相対URLを返します。ファイル名に日本語や記号を含んでもリンクが壊れないようエスケープします。
+func sitePageLink(dir, name string) string {
+	return (&url.URL{Path: path.Join(dir, sitePageName(name))}).String()
+}
+
+// siteThreadLink は、depth 階層下のページからスレッドへの相対URLを返します。
+func siteThreadLink(th siteThread, depth int) string {
+	return (&url.URL{Path: strings.Repeat("../", depth) + th.Link}).String()
+}
+
+// siteIndexHTML は、タスク・タグの一覧と最近のスレッドを掲載するトップページを生成します。
+func siteIndexHTML(threads []siteThread, taskNames, tagNames []string, byTask, byTag map[string][]siteThread) []byte {
+	var b strings.Builder
+	writeSiteHead(&b, "アーカイブ", 0)
+	b.WriteString("<h2>タスク</h2>\n<ul>\n")
+	for _, name := range taskNames {
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a> <span class=\"meta\">%d 件</span></li>\n",
+			html.EscapeString(sitePageLink("tasks", name)), html.EscapeString(name), len(byTask[name]))
+	}
+	b.WriteString("</ul>\n")
+	if len(tagNames) > 0 {
+		b.WriteString("<h2>タグ</h2>\n<p class=\"tags\">")
+		for _, tag := range tagNames {
+			fmt.Fprintf(&b, "<a href=\"%s\">%s</a> (%d) ", html.EscapeString(sitePageLink("tags", tag)), html.EscapeString(tag), len(byTag[tag]))
+		}
+		b.WriteString("</p>\n")
+	}
+	b.WriteString("<h2>最近のスレッド</h2>\n")
+	writeSiteThreadList(&b, threads[:min(len(threads), siteRecentCount)], 0)
+	writeSiteFoot(&b)
+	return []byte(b.String())
+}
+
+// siteListHTML は、タスクまたはタグのスレッド一覧ページを月ごとにまとめて生成します。
+func siteListHTML(title, board string, threads []siteThread) []byte {
+	var b strings.Builder
+	writeSiteHead(&b, title, 1)
+	if board != "" {
+		fmt.Fprintf(&b, "<p class=\"meta\">%s</p>\n", html.EscapeString(board))
+	}
+	for start := 0; start < len(threads); {
+		month := threads[start].Date.Format("2006-01")
+		end := start
+		for end < len(threads) && threads[end].Date.Format("2006-01") == month {
+			end++
+		}
+		label := "日付不明"
+		if !threads[start].Date.IsZero() {
+			label = fmt.Sprintf("%d年%d月", threads[start].Date.Year(), threads[start].Date.Month())
+		}
+		fmt.Fprintf(&b, "<h2>%s</h2>\n", label)
+		writeSiteThreadList(&b, threads[start:end], 1)
+		start = end
+	}
+	writeSiteFoot(&b)
+	return []byte(b.String())
+}
+
+// siteSearchHTML は、検索ページを生成します。検索は search.js が search-index.json を読み込んで行います。
+func siteSearchHTML() []byte {
+	var b strings.Builder
+	writeSiteHead(&b, "検索", 0)
+	b.WriteString("<form id=\"search-form\"><input id=\"search-query\" type=\"search\" placeholder=\"タイトル・本文・タスク名・スレッド番号\" autofocus> <button type=\"submit\">検索</button></form>\n" +
+		"<p id=\"search-status\" class=\"meta\"></p>\n<ul id=\"search-results\" class=\"threads\"></ul>\n" +
+		"<noscript><p>検索にはJavaScriptが必要です。</p></noscript>\n<script src=\"search.js\"></script>\n")
+	writeSiteFoot(&b)
+	return []byte(b.String())
+}
+
+// writeSiteThreadList は、スレッドの一覧を書き込みます。depth はページの出力先のルートからの階層です。
+func writeSiteThreadList(b *strings.Builder, threads []siteThread, depth int) {
+	b.WriteString("<ul class=\"threads\">\n")
+	for _, th := range threads {
+		date := ""
+		if !th.Date.IsZero() {
+			date = th.Date.Format("2006-01-02")
+		}
+		fmt.Fprintf(b, "<li><a href=\"%s\">%s</a> <span class=\"meta\">%s | No.%s | %s</span>",
+			html.EscapeString(siteThreadLink(th, depth)), html.EscapeString(th.Title),
+			date, html.EscapeString(th.ThreadID), html.EscapeString(th.TaskName))
+		if th.Excerpt != "" {
+			fmt.Fprintf(b, "<div class=\"excerpt\">%s</div>", html.EscapeString(th.Excerpt))
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+}
+
+// writeSiteHead は、ページ共通のヘッダーとナビゲーションを書き込みます。
+func writeSiteHead(b *strings.Builder, title string, depth int) {
+	prefix := strings.Repeat("../", depth)
+	escaped := html.EscapeString(title)
+	fmt.Fprintf(b, "<!DOCTYPE html>\n<html lang=\"ja\">\n<head>\n<meta charset=\"UTF-8\">\n"+
+		"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n<title>%s - GIBA</title>\n"+
+		"<link rel=\"stylesheet\" href=\"%sstyle.css\">\n</head>\n<body>\n", escaped, prefix)
+	fmt.Fprintf(b, "<nav><a href=\"%sindex.html\">トップ</a> | <a href=\"%ssearch.html\">検索</a></nav>\n<h1>%s</h1>\n", prefix, prefix, escaped)
+}
+
+// writeSiteFoot は、ページ共通のフッターを書き込みます。
+func writeSiteFoot(b *strings.Builder) {
+	fmt.Fprintf(b, "<footer class=\"meta\">%s で生成</footer>\n</body>\n</html>\n", html.EscapeString(version.Get().String()))
+}
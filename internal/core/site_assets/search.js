@@ -0,0 +1,116 @@
+// giba site build で生成した静的サイトの検索。
+// search-index.json（正規化した文字列の2文字ごとの文書番号の一覧）を読み込み、クエリのすべての2文字を含む文書を表示する。
+(function () {
+    'use strict';
+
+    const form = document.getElementById('search-form');
+    const input = document.getElementById('search-query');
+    const status = document.getElementById('search-status');
+    const results = document.getElementById('search-results');
+    const maxResults = 200;
+    let index = null;
+
+    // サーバー側（siteBigrams）と同じく、NFKC正規化と小文字化を行う
+    function normalize(text) {
+        return text.normalize('NFKC').toLowerCase();
+    }
+
+    function bigrams(text) {
+        const chars = Array.from(normalize(text));
+        const grams = new Set();
+        for (let i = 0; i + 1 < chars.length; i++) {
+            if (/\s/.test(chars[i]) || /\s/.test(chars[i + 1])) {
+                continue;
+            }
+            grams.add(chars[i] + chars[i + 1]);
+        }
+        return Array.from(grams);
+    }
+
+    function docText(doc) {
+        return normalize([doc.t, doc.x || '', doc.k, doc.i].concat(doc.g || []).join(' '));
+    }
+
+    // クエリの語（空白区切り）ごとに、2文字の組の文書番号を積集合で絞り込み、最後に部分一致で確認する
+    function search(query) {
+        const words = normalize(query).split(/\s+/).filter(Boolean);
+        if (words.length === 0) {
+            return [];
+        }
+        let candidates = null;
+        for (const word of words) {
+            const grams = bigrams(word);
+            if (grams.length === 0) {
+                continue; // 1文字の語は部分一致のみで判定する
+            }
+            for (const gram of grams) {
+                const ids = new Set(index.grams[gram] || []);
+                candidates = candidates === null ? ids : new Set([...candidates].filter((id) => ids.has(id)));
+            }
+        }
+        const ids = candidates === null ? index.docs.map((_, i) => i) : Array.from(candidates).sort((a, b) => a - b);
+        return ids
+            .map((id) => index.docs[id])
+            .filter((doc) => {
+                const text = docText(doc);
+                return words.every((word) => text.includes(word));
+            });
+    }
+
+    function render(query) {
+        results.textContent = '';
+        if (!query.trim()) {
+            status.textContent = index.docs.length + ' 件のスレッドを検索できます。';
+            return;
+        }
+        const found = search(query);
+        status.textContent = found.length + ' 件見つかりました' + (found.length > maxResults ? '（先頭の ' + maxResults + ' 件を表示）' : '') + '。';
+        for (const doc of found.slice(0, maxResults)) {
+            const li = document.createElement('li');
+            const a = document.createElement('a');
+            a.href = doc.u;
+            a.textContent = doc.t;
+            li.appendChild(a);
+            const meta = document.createElement('span');
+            meta.className = 'meta';
+            meta.textContent = ' ' + doc.d + ' | No.' + doc.i + ' | ' + doc.k;
+            li.appendChild(meta);
+            if (doc.x) {
+                const excerpt = document.createElement('div');
+                excerpt.className = 'excerpt';
+                excerpt.textContent = doc.x;
+                li.appendChild(excerpt);
+            }
+            results.appendChild(li);
+        }
+    }
+
+    form.addEventListener('submit', (e) => {
+        e.preventDefault();
+        const query = input.value;
+        const url = new URL(window.location.href);
+        url.searchParams.set('q', query);
+        window.history.replaceState(null, '', url);
+        if (index) {
+            render(query);
+        }
+    });
+
+    status.textContent = '検索インデックスを読み込んでいます...';
+    fetch('search-index.json')
+        .then((res) => {
+            if (!res.ok) {
+                throw new Error('HTTP ' + res.status);
+            }
+            return res.json();
+        })
+        .then((data) => {
+            index = data;
+            input.value = new URLSearchParams(window.location.search).get('q') || '';
+            render(input.value);
+        })
+        .catch((err) => {
+            // file:// で開いた場合、ブラウザによっては fetch が拒否される
+            status.textContent = '検索インデックスを読み込めませんでした（静的ファイルサーバー経由で開いてください）: ' + err.message;
+        });
+})();
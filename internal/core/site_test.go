@@ -0,0 +1,134 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestBuildSite(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	root := filepath.Join(base, "downloads")
+	outDir := filepath.Join(base, "site")
+
+	// 同じ保存先を2つのタスクで共有し、thread.json の元URLで振り分ける
+	threads := []struct {
+		id, dir, title, url, op string
+		created                 time.Time
+	}{
+		{"100", "2025-11/100_猫スレ", "猫スレ", "https://may.2chan.net/b/res/100.htm", "うちの猫を見てくれ", time.Date(2025, 11, 18, 12, 0, 0, 0, time.UTC)},
+		{"200", "2025-10/200_#犬?", "犬スレ", "https://img.2chan.net/b/res/200.htm", "犬の写真を貼るスレ", time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, th := range threads {
+		dir := filepath.Join(root, filepath.FromSlash(th.dir))
+		writeTestThreadDir(t, dir, th.id, 1, "1.jpg")
+		snapshot := &ThreadSnapshot{ThreadID: th.id, CreatedAt: &th.created}
+		snapshot.ObserveTitle(th.title, th.created)
+		if err := SaveThreadMetadata(dir, th.url, snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "index.htm"), []byte("<blockquote>"+th.op+"</blockquote>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "may", SiteAdapter: "futaba", TargetBoardURL: "https://may.2chan.net/b/", SaveRootDirectory: root, Tags: []string{"動物", "may"}},
+		{TaskName: "img", SiteAdapter: "futaba", TargetBoardURL: "https://img.2chan.net/b/", SaveRootDirectory: root, Tags: []string{"動物"}},
+	}}
+	summary, err := BuildSite(context.Background(), cfg, outDir, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("BuildSite() error = %v", err)
+	}
+	if summary.Threads != 2 || summary.Tasks != 2 || summary.Tags != 2 || summary.Skipped != 0 {
+		t.Errorf("summary = %+v, want threads=2 tasks=2 tags=2 skipped=0", summary)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("%s が生成されていません: %v", name, err)
+		}
+		return string(data)
+	}
+
+	index := read("index.html")
+	for _, want := range []string{`href="tasks/may.html"`, `href="tags/%E5%8B%95%E7%89%A9.html">動物</a> (2)`, `href="../downloads/2025-11/100_%E7%8C%AB%E3%82%B9%E3%83%AC/index.htm"`, "うちの猫を見てくれ"} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html に %q がありません:\n%s", want, index)
+		}
+	}
+	if strings.Index(index, "猫スレ") > strings.Index(index, "犬スレ") {
+		t.Error("最近のスレッドが新しい順に並んでいません")
+	}
+
+	taskPage := read("tasks/img.html")
+	if !strings.Contains(taskPage, "犬スレ") || strings.Contains(taskPage, "猫スレ") || !strings.Contains(taskPage, "2025年10月") {
+		t.Errorf("タスクのページの内容が不正です:\n%s", taskPage)
+	}
+	if !strings.Contains(taskPage, `href="../../downloads/2025-10/200_%23%E7%8A%AC%3F/index.htm"`) {
+		t.Errorf("記号を含むディレクトリへのリンクがエスケープされていません:\n%s", taskPage)
+	}
+	read("tags/動物.html")
+	read("search.html")
+	read("search.js")
+	read("style.css")
+
+	var searchIndex siteSearchIndex
+	if err := json.Unmarshal([]byte(read("search-index.json")), &searchIndex); err != nil {
+		t.Fatalf("search-index.json を解析できません: %v", err)
+	}
+	if len(searchIndex.Docs) != 2 {
+		t.Fatalf("docs = %d, want 2", len(searchIndex.Docs))
+	}
+	// 本文の「写真」を含むのは犬スレのみ
+	ids := searchIndex.Grams["写真"]
+	if len(ids) != 1 || searchIndex.Docs[ids[0]].Title != "犬スレ" {
+		t.Errorf("grams[写真] = %v, want 犬スレのみ", ids)
+	}
+}
+
+func TestBuildSite_UnknownAdapter(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	root := filepath.Join(base, "downloads")
+	dir := filepath.Join(root, "100")
+	writeTestThreadDir(t, dir, "100", 1, "1.jpg")
+	if err := SaveThreadMetadata(dir, "https://example.com/b/res/100.htm", &ThreadSnapshot{ThreadID: "100"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// サイトアダプタを取得できないタスクはスキップし、パニックせずにサイトを生成する
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "unknown", SiteAdapter: "no-such-adapter", TargetBoardURL: "https://example.com/b/", SaveRootDirectory: root},
+	}}
+	summary, err := BuildSite(context.Background(), cfg, filepath.Join(base, "site"), log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("BuildSite() error = %v", err)
+	}
+	if summary.Threads != 0 {
+		t.Errorf("summary = %+v, want threads=0", summary)
+	}
+}
+
+func TestSiteBigrams(t *testing.T) {
+	t.Parallel()
+
+	got := siteBigrams("ＡＢ ab猫")
+	want := []string{"ab", "b猫"} // 全角英字はNFKCで半角・小文字にそろい、重複と空白をまたぐ組は除く
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("siteBigrams() = %v, want %v", got, want)
+	}
+}
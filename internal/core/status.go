@@ -41,7 +41,31 @@ func (s AppState) String() string {
 	}
 }
 
+// MarshalText は、状態をJSONなどで使用する英字の識別子に変換します。
+func (s AppState) MarshalText() ([]byte, error) {
+	switch s {
+	case StateInitializing:
+		return []byte("initializing"), nil
+	case StateIdle:
+		return []byte("idle"), nil
+	case StateWatching:
+		return []byte("watching"), nil
+	case StatePreparing:
+		return []byte("preparing"), nil
+	case StateRunning:
+		return []byte("running"), nil
+	case StatePaused:
+		return []byte("paused"), nil
+	case StateError:
+		return []byte("error"), nil
+	default:
+		return nil, fmt.Errorf("不明な状態です: %d", int(s))
+	}
+}
+
 // AppStatus はコアエンジンからUIへ渡されるアプリケーションの状態を表します。
+// 1件の AppStatus は1つのタスク（TaskName が空の場合はアプリケーション全体）の状態の変化です。
+// エンジンが配信する場合は、変化を反映した後のタスクごとの状態と全体の状態が Snapshot に設定されます。
 type AppStatus struct {
 	TaskName     string   // このステータスを送信したタスクの名前
 	State        AppState // 現在の活動状態
@@ -52,12 +76,31 @@ type AppStatus struct {
 	IsPaused     bool     // アプリケーションが一時停止中かどうか
 	HasError     bool     // 致命的なエラーが発生しているかどうか
 	ConfigLoaded bool     // 設定ファイルが正常に読み込まれているか
+
+	Archived *ArchivedThread // 直前にアーカイブが完了したスレッド（完了通知時のみ設定）
+	Error    *ErrorRecord    // 直前に記録されたエラー（エラー発生時のみ設定。RecentErrors で一覧を取得できる）
+	Snapshot *StatusSnapshot // この状態を反映した後のタスクごとの状態と全体の状態（エンジンが配信する場合のみ設定）
+}
+
+// ArchivedThread は、アーカイブが完了したスレッドの情報を表します。
+// UIの「最近のアーカイブ」一覧などで使用されます。
+type ArchivedThread struct {
+	TaskName        string    // スレッドを処理したタスク名
+	ThreadID        string    // スレッドID
+	Title           string    // スレッドタイトル
+	SavePath        string    // スレッドの保存ディレクトリ
+	PostCount       int       // スレッドのレス数（アダプタが対応していない場合は0）
+	NewPosts        int       // 前回のアーカイブから増えたレス数
+	FilesDownloaded int       // ダウンロードしたファイル数
+	BytesWritten    int64     // 書き込んだバイト数
+	CompletedAt     time.Time // アーカイブ完了時刻
 }
 
 // SessionStats はセッション統計情報を管理します。
 type SessionStats struct {
 	StartTime         time.Time // 起動時刻
 	ThreadsArchived   int       // アーカイブしたスレッド数
+	PostsArchived     int       // アーカイブした新しいレス数（画像のないレスを含む）
 	FilesDownloaded   int       // ダウンロードしたファイル数
 	TotalBytesWritten int64     // 合計ダウンロードサイズ（バイト）
 }
@@ -71,17 +114,22 @@ func (s *SessionStats) FormatSessionInfo() string {
 	// サイズをMB単位に変換
 	sizeMB := float64(s.TotalBytesWritten) / (1024 * 1024)
 
-	return fmt.Sprintf("起動: %dh%dm | スレッド: %d | ファイル: %d | %.1fMB",
-		hours, minutes, s.ThreadsArchived, s.FilesDownloaded, sizeMB)
+	return fmt.Sprintf("起動: %dh%dm | スレッド: %d | レス: %d | ファイル: %d | %.1fMB",
+		hours, minutes, s.ThreadsArchived, s.PostsArchived, s.FilesDownloaded, sizeMB)
 }
 
 // TaskResult は単一スレッドのアーカイブ結果を表します。
 type TaskResult struct {
-	ThreadID        string // スレッドID
-	Success         bool   // 成功したか
-	FilesDownloaded int    // ダウンロードしたファイル数
-	BytesWritten    int64  // 書き込んだバイト数
-	Error           error  // エラー（あれば）
+	ThreadID        string  // スレッドID
+	SavePath        string  // 保存先ディレクトリ（成功時のみ）
+	Title           string  // 保存に使用したタイトル（定型文の場合はOP本文で置き換え済み）
+	Success         bool    // 成功したか
+	FilesDownloaded int     // ダウンロードしたファイル数
+	BytesWritten    int64   // 書き込んだバイト数
+	PostCount       int     // スレッドのレス数（アダプタが対応していない場合は0）
+	NewPosts        int     // 前回のアーカイブから増えたレス数
+	SuccessRatio    float64 // フルサイズメディアのダウンロード成功率（0〜1）
+	Error           error   // エラー（あれば）。errors.Is で errs パッケージのエラー種別を判定できる
 }
 
 // StatsUpdate は統計情報の更新を表します。
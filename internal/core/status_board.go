@@ -0,0 +1,241 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// nextRunPrefix は、監視モードのタスクが次の巡回予定時刻を通知する Detail の接頭辞です（"NEXT_RUN:<Unix秒>"）。
+const nextRunPrefix = "NEXT_RUN:"
+
+// TaskStatus は、タスクごとの最新の状態です。
+type TaskStatus struct {
+	TaskName  string     `json:"task_name"`
+	State     AppState   `json:"state"`
+	Detail    string     `json:"detail"`
+	HasError  bool       `json:"has_error"`
+	NextRun   *time.Time `json:"next_run,omitempty"` // 監視モードで次の巡回を待っている場合の予定時刻
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// StatusSummary は、タスクごとの状態から導出したアプリケーション全体の状態です。
+type StatusSummary struct {
+	State        AppState   `json:"state"`
+	Detail       string     `json:"detail"`
+	TaskCount    int        `json:"task_count"`
+	RunningTasks int        `json:"running_tasks"`
+	ErrorTasks   int        `json:"error_tasks"`
+	NextRun      *time.Time `json:"next_run,omitempty"` // 待機中のタスクのうち最も早い次の巡回予定時刻
+	IsWatching   bool       `json:"watching"`
+	IsPaused     bool       `json:"paused"`
+	SessionInfo  string     `json:"session_info"`
+}
+
+// StatusSnapshot は、ある時点のタスクごとの状態と、そこから導出した全体の状態です。
+type StatusSnapshot struct {
+	Summary   StatusSummary         `json:"summary"`
+	Tasks     map[string]TaskStatus `json:"tasks"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// SortedTasks は、タスクごとの状態をタスク名の順に返します。
+func (s StatusSnapshot) SortedTasks() []TaskStatus {
+	tasks := make([]TaskStatus, 0, len(s.Tasks))
+	for _, t := range s.Tasks {
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].TaskName < tasks[j].TaskName })
+	return tasks
+}
+
+// statusBoard は、エンジンが配信した状態をタスクごとに保持し、全体の状態を導出します。
+type statusBoard struct {
+	mu           sync.Mutex
+	tasks        map[string]TaskStatus
+	globalDetail string // タスクに属さない状態（監視モードの切り替え、手動実行の進捗など）の最新の説明
+	watching     bool
+	paused       bool
+	sessionInfo  string
+	statusFile   string
+	now          func() time.Time
+}
+
+// sharedStatusBoard は、プロセス内で共有される現在の状態です。Web UI や状態ファイルはここから読み出します。
+var sharedStatusBoard = newStatusBoard()
+
+func newStatusBoard() *statusBoard {
+	return &statusBoard{tasks: make(map[string]TaskStatus), now: time.Now}
+}
+
+// ConfigureStatusFile は、状態が変わるたびに現在の状態をJSONで書き込むファイルを設定します（空文字で無効）。
+func ConfigureStatusFile(path string) {
+	sharedStatusBoard.mu.Lock()
+	defer sharedStatusBoard.mu.Unlock()
+	sharedStatusBoard.statusFile = path
+}
+
+// CurrentStatus は、タスクごとの現在の状態と全体の状態を返します。
+func CurrentStatus() StatusSnapshot {
+	return sharedStatusBoard.snapshot()
+}
+
+// reset は、保持している状態を破棄し、taskNames のタスクをアイドル状態として登録します。
+func (b *statusBoard) reset(taskNames []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tasks = make(map[string]TaskStatus, len(taskNames))
+	for _, name := range taskNames {
+		b.tasks[name] = TaskStatus{TaskName: name, State: StateIdle, Detail: "待機中", UpdatedAt: now}
+	}
+	b.globalDetail = ""
+}
+
+// update は、s を反映した後の状態を返します。状態ファイルが設定されている場合は書き込みます。
+// TaskName が空の状態は、アプリケーション全体の説明と監視・一時停止の状態のみを更新します。
+func (b *statusBoard) update(s AppStatus) StatusSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.watching = s.IsWatching
+	b.paused = s.IsPaused
+	if s.SessionInfo != "" {
+		b.sessionInfo = s.SessionInfo
+	}
+
+	if s.TaskName == "" {
+		if s.Detail != "" {
+			b.globalDetail = s.Detail
+		}
+	} else {
+		ts := b.tasks[s.TaskName]
+		ts.TaskName = s.TaskName
+		ts.State = s.State
+		ts.HasError = s.HasError || s.State == StateError
+		ts.UpdatedAt = now
+		ts.NextRun = nil
+		if next, ok := parseNextRun(s.Detail); ok {
+			ts.NextRun = &next
+			ts.Detail = "次の巡回を待機中"
+		} else if s.Detail != "" {
+			ts.Detail = s.Detail
+		}
+		b.tasks[s.TaskName] = ts
+	}
+
+	snap := b.snapshotLocked()
+	if b.statusFile != "" {
+		if err := writeStatusFile(b.statusFile, snap); err != nil {
+			log.Printf("WARNING: 状態ファイルの更新に失敗しました: %v", err)
+		}
+	}
+	return snap
+}
+
+func (b *statusBoard) snapshot() StatusSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.snapshotLocked()
+}
+
+func (b *statusBoard) snapshotLocked() StatusSnapshot {
+	tasks := make(map[string]TaskStatus, len(b.tasks))
+	for name, t := range b.tasks {
+		tasks[name] = t
+	}
+	return StatusSnapshot{
+		Summary:   summarizeStatus(tasks, b.globalDetail, b.watching, b.paused, b.sessionInfo),
+		Tasks:     tasks,
+		UpdatedAt: b.now(),
+	}
+}
+
+// summarizeStatus は、タスクごとの状態から全体の状態を導出します。
+// 実行中のタスクがあれば「実行中」、なければ一時停止・エラー・監視中・アイドルの順に優先します。
+func summarizeStatus(tasks map[string]TaskStatus, globalDetail string, watching, paused bool, sessionInfo string) StatusSummary {
+	summary := StatusSummary{
+		TaskCount:   len(tasks),
+		IsWatching:  watching,
+		IsPaused:    paused,
+		SessionInfo: sessionInfo,
+	}
+
+	var running, failed []string
+	for name, t := range tasks {
+		if t.State == StateRunning || t.State == StatePreparing {
+			running = append(running, name)
+		}
+		if t.HasError {
+			failed = append(failed, name)
+		}
+		if t.NextRun != nil && (summary.NextRun == nil || t.NextRun.Before(*summary.NextRun)) {
+			next := *t.NextRun
+			summary.NextRun = &next
+		}
+	}
+	sort.Strings(running)
+	sort.Strings(failed)
+	summary.RunningTasks = len(running)
+	summary.ErrorTasks = len(failed)
+
+	switch {
+	case len(running) > 0:
+		summary.State = StateRunning
+		summary.Detail = fmt.Sprintf("実行中 (%d/%d): %s", len(running), len(tasks), strings.Join(running, ", "))
+	case paused:
+		summary.State = StatePaused
+		summary.Detail = globalDetail
+	case len(failed) > 0:
+		summary.State = StateError
+		summary.Detail = fmt.Sprintf("エラー: %s", strings.Join(failed, ", "))
+	case watching:
+		summary.State = StateWatching
+		summary.Detail = globalDetail
+	default:
+		summary.State = StateIdle
+		summary.Detail = globalDetail
+	}
+	return summary
+}
+
+// parseNextRun は、"NEXT_RUN:<Unix秒>" 形式の Detail から次の巡回予定時刻を取り出します。
+func parseNextRun(detail string) (time.Time, bool) {
+	ts, ok := strings.CutPrefix(detail, nextRunPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(sec, 0), true
+}
+
+// writeStatusFile は、状態をJSONで書き込みます。
+// 書き込み途中のファイルを監視側が読まないよう、一時ファイルに書いてからリネームします。
+func writeStatusFile(path string, snap StatusSnapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("状態のエンコードに失敗しました: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("状態ファイルのディレクトリ作成に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("状態ファイルの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("状態ファイルの更新に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSummarizeStatus(t *testing.T) {
+	t.Parallel()
+
+	next1 := time.Unix(2000, 0)
+	next2 := time.Unix(1000, 0)
+	tests := []struct {
+		name       string
+		tasks      map[string]TaskStatus
+		watching   bool
+		paused     bool
+		wantState  AppState
+		wantDetail string
+	}{
+		{
+			name:       "実行中のタスクがあれば実行中",
+			tasks:      map[string]TaskStatus{"b": {State: StateRunning}, "a": {State: StateRunning}, "c": {State: StateWatching, HasError: true}},
+			watching:   true,
+			wantState:  StateRunning,
+			wantDetail: "実行中 (2/3): a, b",
+		},
+		{
+			name:       "一時停止はエラーより優先",
+			tasks:      map[string]TaskStatus{"a": {State: StateError, HasError: true}},
+			paused:     true,
+			wantState:  StatePaused,
+			wantDetail: "全体",
+		},
+		{
+			name:       "エラーのタスク",
+			tasks:      map[string]TaskStatus{"a": {State: StateError, HasError: true}, "b": {State: StateIdle}},
+			watching:   true,
+			wantState:  StateError,
+			wantDetail: "エラー: a",
+		},
+		{
+			name:       "監視中",
+			tasks:      map[string]TaskStatus{"a": {State: StateWatching, NextRun: &next1}, "b": {State: StateWatching, NextRun: &next2}},
+			watching:   true,
+			wantState:  StateWatching,
+			wantDetail: "全体",
+		},
+		{
+			name:       "タスクなし",
+			wantState:  StateIdle,
+			wantDetail: "全体",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := summarizeStatus(tt.tasks, "全体", tt.watching, tt.paused, "")
+			if got.State != tt.wantState || got.Detail != tt.wantDetail {
+				t.Errorf("summarizeStatus() = %v %q, want %v %q", got.State, got.Detail, tt.wantState, tt.wantDetail)
+			}
+		})
+	}
+
+	got := summarizeStatus(map[string]TaskStatus{"a": {NextRun: &next1}, "b": {NextRun: &next2}}, "", true, false, "")
+	if got.NextRun == nil || !got.NextRun.Equal(next2) {
+		t.Errorf("NextRun = %v, want 最も早い %v", got.NextRun, next2)
+	}
+}
+
+func TestStatusBoard_Update(t *testing.T) {
+	t.Parallel()
+
+	statusFile := filepath.Join(t.TempDir(), "state", "status.json")
+	b := newStatusBoard()
+	b.statusFile = statusFile
+	b.reset([]string{"a", "b"})
+
+	b.update(AppStatus{TaskName: "a", State: StateRunning, Detail: "タスク 'a' を実行中...", IsWatching: true})
+	snap := b.update(AppStatus{TaskName: "b", State: StateWatching, Detail: "NEXT_RUN:1700000000", IsWatching: true})
+
+	// 1つのタスクの更新で他のタスクの状態は失われない
+	if got := snap.Tasks["a"]; got.State != StateRunning {
+		t.Errorf("タスク a の状態 = %v, want 実行中", got.State)
+	}
+	if got := snap.Tasks["b"]; got.NextRun == nil || got.NextRun.Unix() != 1700000000 || got.Detail == "NEXT_RUN:1700000000" {
+		t.Errorf("タスク b の状態 = %+v, want 次の巡回予定時刻を解析済み", got)
+	}
+	if snap.Summary.State != StateRunning || snap.Summary.TaskCount != 2 || snap.Summary.RunningTasks != 1 {
+		t.Errorf("Summary = %+v", snap.Summary)
+	}
+
+	// 全体の状態の更新はタスクの状態を変えない
+	snap = b.update(AppStatus{State: StateIdle, Detail: "監視モード有効", IsWatching: true})
+	if got := snap.Tasks["a"]; got.State != StateRunning {
+		t.Errorf("全体の更新後のタスク a の状態 = %v, want 実行中", got.State)
+	}
+
+	data, err := os.ReadFile(statusFile)
+	if err != nil {
+		t.Fatalf("状態ファイルの読み込みに失敗しました: %v", err)
+	}
+	var decoded struct {
+		Summary struct {
+			State string `json:"state"`
+		} `json:"summary"`
+		Tasks map[string]struct {
+			State string `json:"state"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("状態ファイルの解析に失敗しました: %v", err)
+	}
+	if decoded.Summary.State != "running" || decoded.Tasks["b"].State != "watching" {
+		t.Errorf("状態ファイル = %s", data)
+	}
+}
@@ -0,0 +1,364 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// archiveLocationsFile は、アーカイブの複製元・複製先の場所を記録するファイルです（各ルートの .giba/ 以下）。
+const archiveLocationsFile = "locations.json"
+
+// syncChunkSize は、帯域制限のために一度にコピーするバイト数です。
+const syncChunkSize = 32 * 1024
+
+// 複製の場所の役割
+const (
+	LocationSource  = "source"  // 複製元
+	LocationReplica = "replica" // 複製先
+)
+
+// SyncOptions は、アーカイブの複製の設定です。
+type SyncOptions struct {
+	// Delete が true の場合、複製元にないファイルを複製先から削除します。
+	Delete bool
+	// BytesPerSecond は、コピーの帯域の上限（バイト/秒）です。0 の場合は制限しません。
+	BytesPerSecond int
+}
+
+// SyncSummary は、アーカイブの複製の集計です。
+type SyncSummary struct {
+	Copied  int   // コピーしたファイル数
+	Skipped int   // 内容が同じためコピーしなかったファイル数
+	Deleted int   // 複製先から削除したファイル数
+	Failed  int   // コピーに失敗したファイル数
+	Bytes   int64 // コピーしたバイト数
+}
+
+// ArchiveLocation は、アーカイブの別の場所（複製元または複製先）です。
+type ArchiveLocation struct {
+	Path         string    `json:"path"`
+	Role         string    `json:"role"`
+	LastSyncedAt time.Time `json:"last_synced_at"`
+}
+
+// ArchiveLocations は、.giba/locations.json の内容です。
+type ArchiveLocations struct {
+	Locations []ArchiveLocation `json:"locations"`
+}
+
+// SyncArchive は、src のアーカイブを dst に一方向に複製します。
+// 内容はSHA-256で比較し、同じファイルはコピーしません。コピーしたファイルもハッシュを照合してから置き換えます。
+// 中断した場合は、書き込み途中のファイル（*.tmp）の続きからコピーを再開します。
+// 内部の状態（.giba など . で始まるもの）は複製しません。opts.Delete が true の場合は、src にないファイルを dst から削除します。
+// すべてのファイルを複製できた場合は、両方の .giba/locations.json に互いの場所を記録します。
+// 記録した複製先は、giba sync で複製先を省略した場合の複製先と、-list の一覧に使用されます。
+func SyncArchive(ctx context.Context, src, dst string, opts SyncOptions, logger *log.Logger) (SyncSummary, error) {
+	var summary SyncSummary
+
+	srcAbs, err := filepath.Abs(src)
+	if err != nil {
+		return summary, fmt.Errorf("複製元のパスを解決できません (path=%s): %w", src, err)
+	}
+	dstAbs, err := filepath.Abs(dst)
+	if err != nil {
+		return summary, fmt.Errorf("複製先のパスを解決できません (path=%s): %w", dst, err)
+	}
+	if info, err := os.Stat(srcAbs); err != nil {
+		return summary, fmt.Errorf("複製元を開けません (path=%s): %w", srcAbs, err)
+	} else if !info.IsDir() {
+		return summary, fmt.Errorf("複製元 %s はディレクトリではありません", srcAbs)
+	}
+	if isSubPath(srcAbs, dstAbs) || isSubPath(dstAbs, srcAbs) {
+		return summary, fmt.Errorf("複製元 %s と複製先 %s は、互いを含まない別のディレクトリを指定してください", srcAbs, dstAbs)
+	}
+
+	srcFiles, err := listMirrorableFiles(srcAbs)
+	if err != nil {
+		return summary, err
+	}
+	names := make([]string, 0, len(srcFiles))
+	for name := range srcFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var limiter *rate.Limiter
+	if opts.BytesPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.BytesPerSecond), syncChunkSize)
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+		srcPath := filepath.Join(srcAbs, filepath.FromSlash(name))
+		dstPath := filepath.Join(dstAbs, filepath.FromSlash(name))
+		n, copied, err := syncFile(ctx, srcPath, dstPath, limiter, logger)
+		if err != nil {
+			if ctx.Err() != nil {
+				return summary, ctx.Err()
+			}
+			logger.Printf("WARNING: %s の複製に失敗しました: %v", name, err)
+			summary.Failed++
+			continue
+		}
+		if copied {
+			summary.Copied++
+			summary.Bytes += n
+		} else {
+			summary.Skipped++
+		}
+	}
+
+	if opts.Delete {
+		deleted, err := deleteExtraFiles(dstAbs, srcFiles, logger)
+		summary.Deleted = deleted
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	if summary.Failed > 0 {
+		return summary, fmt.Errorf("%d 件のファイルを複製できませんでした", summary.Failed)
+	}
+	now := time.Now()
+	if err := recordArchiveLocation(srcAbs, ArchiveLocation{Path: dstAbs, Role: LocationReplica, LastSyncedAt: now}); err != nil {
+		logger.Printf("WARNING: 複製先の記録に失敗しました: %v", err)
+	}
+	if err := recordArchiveLocation(dstAbs, ArchiveLocation{Path: srcAbs, Role: LocationSource, LastSyncedAt: now}); err != nil {
+		logger.Printf("WARNING: 複製元の記録に失敗しました: %v", err)
+	}
+	logger.Printf("INFO: %s を %s に複製しました (コピー: %d, 変更なし: %d, 削除: %d, %d バイト)",
+		srcAbs, dstAbs, summary.Copied, summary.Skipped, summary.Deleted, summary.Bytes)
+	return summary, nil
+}
+
+// isSubPath は、path が dir と同じか dir 以下にあるかを返します。
+func isSubPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// syncFile は、srcPath を dstPath にコピーし、コピーしたバイト数とコピーしたかどうかを返します。
+// dstPath が同じ内容の場合はコピーしません。
+func syncFile(ctx context.Context, srcPath, dstPath string, limiter *rate.Limiter, logger *log.Logger) (int64, bool, error) {
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return 0, false, err
+	}
+	srcSum, err := hashFile(srcPath, sha256.New())
+	if err != nil {
+		return 0, false, err
+	}
+	if info, err := os.Stat(dstPath); err == nil && info.Size() == srcInfo.Size() {
+		if dstSum, err := hashFile(dstPath, sha256.New()); err == nil && dstSum == srcSum {
+			return 0, false, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return 0, false, fmt.Errorf("ディレクトリの作成に失敗しました: %w", err)
+	}
+	tmp := dstPath + ".tmp"
+	n, err := copyResumable(ctx, srcPath, tmp, srcInfo.Size(), limiter)
+	if err != nil {
+		return n, false, err
+	}
+	tmpSum, err := hashFile(tmp, sha256.New())
+	if err != nil {
+		return n, false, err
+	}
+	if tmpSum != srcSum {
+		os.Remove(tmp)
+		return n, false, fmt.Errorf("コピー後のハッシュが一致しません (src=%s, dst=%s)", srcSum, tmpSum)
+	}
+	if err := os.Rename(tmp, dstPath); err != nil {
+		return n, false, fmt.Errorf("ファイルの置き換えに失敗しました: %w", err)
+	}
+	// 更新日時を揃えられなくても内容は複製済みのため、失敗として扱わない
+	if err := os.Chtimes(dstPath, srcInfo.ModTime(), srcInfo.ModTime()); err != nil {
+		logger.Printf("WARNING: 複製したファイルの更新日時の設定に失敗しました (path=%s): %v", dstPath, err)
+	}
+	return n, true, nil
+}
+
+// copyResumable は、srcPath を tmp にコピーし、コピーしたバイト数を返します。
+// tmp が既にあり、その内容が srcPath の先頭と一致する場合は続きからコピーします。
+func copyResumable(ctx context.Context, srcPath, tmp string, size int64, limiter *rate.Limiter) (int64, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	offset := resumableOffset(src, tmp, size)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(tmp, flags, 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	buf := make([]byte, syncChunkSize)
+	for {
+		nr, readErr := src.Read(buf)
+		if nr > 0 {
+			if limiter != nil {
+				if err := limiter.WaitN(ctx, nr); err != nil {
+					out.Close()
+					return written, err
+				}
+			}
+			nw, err := out.Write(buf[:nr])
+			written += int64(nw)
+			if err != nil {
+				out.Close()
+				return written, fmt.Errorf("書き込みに失敗しました (path=%s): %w", tmp, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			out.Close()
+			return written, fmt.Errorf("読み込みに失敗しました (path=%s): %w", srcPath, readErr)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return written, fmt.Errorf("書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	return written, nil
+}
+
+// resumableOffset は、書き込み途中の tmp の内容が src の先頭と一致する場合、そのサイズを返します。一致しない場合は 0 を返します。
+func resumableOffset(src *os.File, tmp string, size int64) int64 {
+	info, err := os.Stat(tmp)
+	if err != nil || info.Size() == 0 || info.Size() > size {
+		return 0
+	}
+	tmpSum, err := hashFile(tmp, sha256.New())
+	if err != nil {
+		return 0
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, src, info.Size()); err != nil {
+		return 0
+	}
+	if hex.EncodeToString(h.Sum(nil)) != tmpSum {
+		return 0
+	}
+	return info.Size()
+}
+
+// deleteExtraFiles は、dst の複製の対象のファイルのうち srcFiles にないものを削除し、削除した数を返します。
+// 削除によって空になったディレクトリも削除します。
+func deleteExtraFiles(dst string, srcFiles map[string]int64, logger *log.Logger) (int, error) {
+	if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	dstFiles, err := listMirrorableFiles(dst)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(dstFiles))
+	for name := range dstFiles {
+		if _, ok := srcFiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	deleted := 0
+	for _, name := range names {
+		path := filepath.Join(dst, filepath.FromSlash(name))
+		if err := os.Remove(path); err != nil {
+			logger.Printf("WARNING: %s の削除に失敗しました: %v", name, err)
+			continue
+		}
+		deleted++
+		for dir := filepath.Dir(path); dir != dst && isSubPath(dst, dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// LoadArchiveLocations は、root の .giba/locations.json を読み込みます。ファイルがない場合は空の一覧を返します。
+func LoadArchiveLocations(root string) (*ArchiveLocations, error) {
+	data, err := os.ReadFile(filepath.Join(root, ".giba", archiveLocationsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &ArchiveLocations{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var locations ArchiveLocations
+	if err := json.Unmarshal(data, &locations); err != nil {
+		return nil, fmt.Errorf("複製の記録の解析に失敗しました (root=%s): %w", root, err)
+	}
+	return &locations, nil
+}
+
+// Replicas は、記録されている複製先を最終複製日時の新しい順に返します。
+func (l *ArchiveLocations) Replicas() []ArchiveLocation {
+	var replicas []ArchiveLocation
+	for _, location := range l.Locations {
+		if location.Role == LocationReplica {
+			replicas = append(replicas, location)
+		}
+	}
+	sort.SliceStable(replicas, func(i, j int) bool { return replicas[i].LastSyncedAt.After(replicas[j].LastSyncedAt) })
+	return replicas
+}
+
+// recordArchiveLocation は、root の .giba/locations.json に location を追加します。同じパスの記録は置き換えます。
+func recordArchiveLocation(root string, location ArchiveLocation) error {
+	locations, err := LoadArchiveLocations(root)
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range locations.Locations {
+		if locations.Locations[i].Path == location.Path {
+			locations.Locations[i] = location
+			replaced = true
+		}
+	}
+	if !replaced {
+		locations.Locations = append(locations.Locations, location)
+	}
+	data, err := json.MarshalIndent(locations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("複製の記録のシリアライズに失敗しました: %w", err)
+	}
+	path := filepath.Join(root, ".giba", archiveLocationsFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("ディレクトリの作成に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("複製の記録の書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
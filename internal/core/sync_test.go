@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncArchive(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "futaba_b")
+	dst := filepath.Join(t.TempDir(), "futaba_b")
+	writeTestFiles(t, src, map[string]string{
+		"index.htm":         "list",
+		"100/index.htm":     "thread",
+		"100/img/1.jpg":     "0123456789",
+		"100/img/2.jpg":     "same",
+		"100/img/3.jpg":     "new content",
+		"100/.resume.json":  "[]",
+		"100/index.htm.tmp": "partial",
+	})
+	writeTestFiles(t, dst, map[string]string{
+		"100/img/1.jpg.tmp": "01234", // 中断したコピーの続き
+		"100/img/2.jpg":     "same",
+		"100/img/3.jpg":     "old content",
+		"100/img/3.jpg.tmp": "xyz", // 内容が一致しない書き込み途中のファイル
+		"200/img/9.jpg":     "deleted on source",
+		".giba/keep.json":   "{}",
+	})
+	logger := log.New(io.Discard, "", 0)
+
+	summary, err := SyncArchive(context.Background(), src, dst, SyncOptions{Delete: true, BytesPerSecond: 1 << 20}, logger)
+	if err != nil {
+		t.Fatalf("SyncArchive() error = %v", err)
+	}
+	want := SyncSummary{Copied: 4, Skipped: 1, Deleted: 1, Bytes: 4 + 6 + 5 + 11}
+	if summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+
+	for name, content := range map[string]string{
+		"index.htm":     "list",
+		"100/index.htm": "thread",
+		"100/img/1.jpg": "0123456789",
+		"100/img/2.jpg": "same",
+		"100/img/3.jpg": "new content",
+	} {
+		data, err := os.ReadFile(filepath.Join(dst, filepath.FromSlash(name)))
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, content)
+		}
+	}
+	for _, name := range []string{"100/.resume.json", "100/index.htm.tmp", "100/img/1.jpg.tmp", "100/img/3.jpg.tmp", "200"} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("%s exists in dst, want removed or not copied (err=%v)", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, ".giba", "keep.json")); err != nil {
+		t.Errorf(".giba/keep.json was removed: %v", err)
+	}
+
+	srcLocations, err := LoadArchiveLocations(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srcLocations.Locations) != 1 || srcLocations.Locations[0].Path != dst || srcLocations.Locations[0].Role != LocationReplica {
+		t.Errorf("src locations = %+v, want replica %s", srcLocations.Locations, dst)
+	}
+	if replicas := srcLocations.Replicas(); len(replicas) != 1 || replicas[0].Path != dst {
+		t.Errorf("src replicas = %+v, want %s", replicas, dst)
+	}
+	dstLocations, err := LoadArchiveLocations(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dstLocations.Locations) != 1 || dstLocations.Locations[0].Path != src || dstLocations.Locations[0].Role != LocationSource {
+		t.Errorf("dst locations = %+v, want source %s", dstLocations.Locations, src)
+	}
+
+	// 2回目は変更がないため何もコピーしない
+	summary, err = SyncArchive(context.Background(), src, dst, SyncOptions{}, logger)
+	if err != nil {
+		t.Fatalf("SyncArchive() second run error = %v", err)
+	}
+	if want := (SyncSummary{Skipped: 5}); summary != want {
+		t.Errorf("second summary = %+v, want %+v", summary, want)
+	}
+	if locations, _ := LoadArchiveLocations(src); len(locations.Locations) != 1 {
+		t.Errorf("src locations after second run = %+v, want 1 entry", locations.Locations)
+	}
+}
+
+func TestSyncArchive_InvalidPaths(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	logger := log.New(io.Discard, "", 0)
+	tests := []struct {
+		name string
+		src  string
+		dst  string
+	}{
+		{name: "同じディレクトリ", src: root, dst: root},
+		{name: "複製元の中", src: root, dst: filepath.Join(root, "backup")},
+		{name: "複製先の中", src: root, dst: filepath.Dir(root)},
+		{name: "存在しない複製元", src: filepath.Join(root, "missing"), dst: t.TempDir()},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := SyncArchive(context.Background(), tt.src, tt.dst, SyncOptions{}, logger); err == nil {
+				t.Error("SyncArchive() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestArchiveLocations_Replicas(t *testing.T) {
+	t.Parallel()
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+	locations := &ArchiveLocations{Locations: []ArchiveLocation{
+		{Path: "/mnt/old", Role: LocationReplica, LastSyncedAt: older},
+		{Path: "/archive", Role: LocationSource, LastSyncedAt: newer},
+		{Path: "/mnt/new", Role: LocationReplica, LastSyncedAt: newer},
+	}}
+
+	var got []string
+	for _, replica := range locations.Replicas() {
+		got = append(got, replica.Path)
+	}
+	// 複製元は含めず、最後に複製した複製先から順に返す
+	if want := []string{"/mnt/new", "/mnt/old"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Replicas() = %v, want %v", got, want)
+	}
+}
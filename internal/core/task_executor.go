@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// RunTasks は、tasks のうち有効なものを ExecuteTask で並行に実行し、すべてのタスクが終了するまで待ちます。
+// 巡回サイクルは ConfigureTaskLimits で設定した実行枠の範囲でのみ同時に実行されます。
+// progressCh が nil でない場合、各タスクの状態に加えて、それらを集約した進捗（TaskName は空、Detail は progressName で始まる）を
+// 1つのストリームとして送信します。終了したタスクについてはアイドル状態を送信します。
+// 監視モードでは、watch_jitter_percent を設定したタスクの開始をずらします。
+func RunTasks(ctx context.Context, tasks []config.Task, globalNetworkSettings config.NetworkSettings, safetyStopMinDiskGB float64, isWatchMode bool, progressName string, progressCh chan<- AppStatus) {
+	if isWatchMode {
+		tasks = staggerTaskStarts(tasks)
+	}
+	var enabled []config.Task
+	for _, task := range tasks {
+		if task.Enabled != nil && *task.Enabled {
+			enabled = append(enabled, task)
+		}
+	}
+
+	var events chan runEvent
+	aggregated := make(chan struct{})
+	if progressCh != nil {
+		events = make(chan runEvent, len(enabled))
+		go func() {
+			defer close(aggregated)
+			aggregateRunProgress(newRunProgress(progressName, len(enabled), isWatchMode), events, progressCh)
+		}()
+	} else {
+		close(aggregated)
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range enabled {
+		if ctx.Err() != nil {
+			// 開始しなかったタスクも終了として数える
+			if events != nil {
+				events <- runEvent{taskName: task.TaskName, finished: true}
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(t config.Task) {
+			defer wg.Done()
+			if events == nil {
+				ExecuteTask(ctx, t, globalNetworkSettings, safetyStopMinDiskGB, isWatchMode, nil)
+				return
+			}
+			// タスクごとのチャネルを経由させ、状態と終了の通知が送信した順に集計されるようにする
+			taskStatusCh := make(chan AppStatus)
+			go func() {
+				defer close(taskStatusCh)
+				ExecuteTask(ctx, t, globalNetworkSettings, safetyStopMinDiskGB, isWatchMode, taskStatusCh)
+			}()
+			for s := range taskStatusCh {
+				events <- runEvent{taskName: t.TaskName, status: s}
+			}
+			events <- runEvent{taskName: t.TaskName, finished: true}
+		}(task)
+	}
+	wg.Wait()
+
+	if events != nil {
+		close(events)
+	}
+	<-aggregated
+}
+
+// runEvent は、RunTasks の各タスクから集計へ送られる通知です。
+type runEvent struct {
+	taskName string
+	status   AppStatus
+	finished bool // タスクが終了した（status は使用しない）
+}
+
+// aggregateRunProgress は、各タスクの通知をそのまま out に送信し、続けて progress に反映した集約の状態を送信します。
+// events が閉じられると、最終的な状態を送信して戻ります。
+func aggregateRunProgress(progress *runProgress, events <-chan runEvent, out chan<- AppStatus) {
+	out <- progress.status()
+	for ev := range events {
+		if ev.finished {
+			progress.finish(ev.taskName)
+			out <- AppStatus{TaskName: ev.taskName, State: StateIdle, Detail: "実行完了"}
+		} else {
+			progress.observe(ev.status)
+			out <- ev.status
+		}
+		out <- progress.status()
+	}
+	out <- progress.final()
+}
+
+// runProgress は、複数タスクの実行状況を1つの AppStatus にまとめるための集計です。
+type runProgress struct {
+	name       string
+	total      int
+	isWatching bool
+	running    map[string]bool // 巡回サイクルを実行中のタスク
+	finished   map[string]bool // 終了したタスク
+	failed     map[string]bool // エラーを報告したタスク
+	archived   int             // アーカイブが完了したスレッド数
+}
+
+func newRunProgress(name string, total int, isWatching bool) *runProgress {
+	return &runProgress{
+		name:       name,
+		total:      total,
+		isWatching: isWatching,
+		running:    make(map[string]bool),
+		finished:   make(map[string]bool),
+		failed:     make(map[string]bool),
+	}
+}
+
+// observe は、タスクから送信された状態を集計に反映します。
+func (p *runProgress) observe(s AppStatus) {
+	if s.TaskName != "" && !p.finished[s.TaskName] {
+		switch s.State {
+		case StateRunning, StatePreparing:
+			p.running[s.TaskName] = true
+		default:
+			delete(p.running, s.TaskName)
+		}
+		if s.HasError || s.State == StateError {
+			p.failed[s.TaskName] = true
+		}
+	}
+	if s.Archived != nil {
+		p.archived++
+	}
+}
+
+// finish は、タスクの終了を集計に反映します。
+func (p *runProgress) finish(taskName string) {
+	p.finished[taskName] = true
+	delete(p.running, taskName)
+}
+
+// status は、現在の集計を表す実行中の状態を返します。
+func (p *runProgress) status() AppStatus {
+	detail := fmt.Sprintf("%s: 完了 %d/%d タスク", p.name, len(p.finished), p.total)
+	if len(p.running) > 0 {
+		detail += fmt.Sprintf(" | 実行中: %s", strings.Join(sortedKeys(p.running), ", "))
+	}
+	if p.archived > 0 {
+		detail += fmt.Sprintf(" | アーカイブ: %d件", p.archived)
+	}
+	return AppStatus{
+		State:        StateRunning,
+		Detail:       detail,
+		IsWatching:   p.isWatching,
+		IsRunning:    true,
+		HasError:     len(p.failed) > 0,
+		ConfigLoaded: true,
+	}
+}
+
+// final は、すべてのタスクが終了した後の状態を返します。
+func (p *runProgress) final() AppStatus {
+	s := AppStatus{
+		State:        StateIdle,
+		Detail:       fmt.Sprintf("%s完了: %d タスク | アーカイブ: %d件", p.name, p.total, p.archived),
+		IsWatching:   p.isWatching,
+		ConfigLoaded: true,
+	}
+	if len(p.failed) > 0 {
+		s.State = StateError
+		s.HasError = true
+		s.Detail += fmt.Sprintf(" | エラー: %s", strings.Join(sortedKeys(p.failed), ", "))
+	}
+	return s
+}
+
+// sortedKeys は、m のキーを昇順に並べて返します。
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAggregateRunProgress(t *testing.T) {
+	t.Parallel()
+
+	events := make(chan runEvent, 8)
+	events <- runEvent{taskName: "a", status: AppStatus{TaskName: "a", State: StateRunning}}
+	events <- runEvent{taskName: "b", status: AppStatus{TaskName: "b", State: StateRunning}}
+	events <- runEvent{taskName: "a", status: AppStatus{TaskName: "a", State: StateRunning, Archived: &ArchivedThread{ThreadID: "1"}}}
+	events <- runEvent{taskName: "a", finished: true}
+	events <- runEvent{taskName: "b", status: AppStatus{TaskName: "b", State: StateError, HasError: true}}
+	events <- runEvent{taskName: "b", finished: true}
+	close(events)
+
+	out := make(chan AppStatus, 16)
+	aggregateRunProgress(newRunProgress("手動実行", 2, false), events, out)
+	close(out)
+
+	var statuses, progress []AppStatus
+	for s := range out {
+		statuses = append(statuses, s)
+		if s.TaskName == "" {
+			progress = append(progress, s)
+		}
+	}
+	// 開始時 + (タスクの状態4件 + 終了2件) × (転送 + 集約) + 最終状態
+	if len(statuses) != 14 {
+		t.Fatalf("送信された状態の数 = %d, want 14", len(statuses))
+	}
+	archived := 0
+	for _, s := range statuses {
+		if s.Archived != nil {
+			archived++
+			if s.TaskName != "a" {
+				t.Errorf("Archived を含む状態の TaskName = %q, want a", s.TaskName)
+			}
+		}
+	}
+	if archived != 1 {
+		t.Errorf("Archived を含む状態の数 = %d, want 1", archived)
+	}
+	for _, s := range progress[:len(progress)-1] {
+		if s.State != StateRunning || !strings.HasPrefix(s.Detail, "手動実行") {
+			t.Errorf("途中の集約の状態 = %+v", s)
+		}
+	}
+	if got := statuses[7]; got.TaskName != "a" || got.State != StateIdle {
+		t.Errorf("終了したタスクの状態 = %+v, want a のアイドル", got)
+	}
+
+	final := statuses[len(statuses)-1]
+	if final.TaskName != "" || final.State != StateError || !final.HasError {
+		t.Errorf("最終状態 = %+v, want 全体のエラー", final)
+	}
+	for _, want := range []string{"2 タスク", "アーカイブ: 1件", "エラー: b"} {
+		if !strings.Contains(final.Detail, want) {
+			t.Errorf("最終状態の Detail = %q, want %q を含む", final.Detail, want)
+		}
+	}
+}
+
+func TestRunProgress_IgnoresStatusAfterFinish(t *testing.T) {
+	t.Parallel()
+
+	p := newRunProgress("手動実行", 1, false)
+	p.observe(AppStatus{TaskName: "a", State: StateRunning})
+	p.finish("a")
+	p.observe(AppStatus{TaskName: "a", State: StateRunning})
+	s := p.status()
+	if strings.Contains(s.Detail, "実行中") {
+		t.Errorf("終了後の状態で実行中として扱われました: %q", s.Detail)
+	}
+	if !strings.Contains(s.Detail, "完了 1/1") {
+		t.Errorf("Detail = %q, want 完了 1/1 を含む", s.Detail)
+	}
+
+	if got := p.final(); got.State != StateIdle || got.HasError {
+		t.Errorf("final() = %v (HasError=%v), want アイドル", got.State, got.HasError)
+	}
+}
+
+func TestEffectiveMaxConcurrentTasks(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct{ in, want int }{{0, 1}, {-1, 1}, {1, 1}, {4, 4}} {
+		if got := EffectiveMaxConcurrentTasks(tc.in); got != tc.want {
+			t.Errorf("EffectiveMaxConcurrentTasks(%d) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"sync"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// taskLimiter は、タスクの巡回サイクルを同時に実行できる数を、全体とグループごとに制限します。
+// 監視モードのタスクが待機中に実行枠を占有しないよう、枠は巡回サイクル単位で確保・解放します。
+type taskLimiter struct {
+	mu     sync.Mutex
+	global chan struct{}            // nil の場合は無制限
+	groups map[string]chan struct{} // 上限が定義されたグループのみ
+}
+
+// sharedTaskLimiter は、プロセス内の全タスクで共有される実行枠です。
+var sharedTaskLimiter = newTaskLimiter(0, nil)
+
+func newTaskLimiter(globalMax int, groups map[string]config.TaskGroup) *taskLimiter {
+	l := &taskLimiter{groups: make(map[string]chan struct{})}
+	if globalMax > 0 {
+		l.global = make(chan struct{}, globalMax)
+	}
+	for name, group := range groups {
+		if group.MaxConcurrentTasks > 0 {
+			l.groups[name] = make(chan struct{}, group.MaxConcurrentTasks)
+		}
+	}
+	return l
+}
+
+// ConfigureTaskLimits は、全体の同時実行数（0以下で無制限）とグループごとの上限を設定します。
+// 設定の再読み込み時に呼び出すと、以降に開始する巡回サイクルから新しい上限が適用されます。
+func ConfigureTaskLimits(globalMax int, groups map[string]config.TaskGroup) {
+	l := newTaskLimiter(globalMax, groups)
+
+	sharedTaskLimiter.mu.Lock()
+	defer sharedTaskLimiter.mu.Unlock()
+	sharedTaskLimiter.global = l.global
+	sharedTaskLimiter.groups = l.groups
+}
+
+// acquire は、指定されたグループと全体の実行枠を確保し、解放用の関数を返します。
+// グループの枠を先に確保するため、グループの上限で待たされているタスクが全体の枠を占有することはありません。
+// 枠が空く前に ctx がキャンセルされた場合はエラーを返します。
+func (l *taskLimiter) acquire(ctx context.Context, group string) (func(), error) {
+	l.mu.Lock()
+	groupSlots := l.groups[group]
+	globalSlots := l.global
+	l.mu.Unlock()
+
+	if err := acquireSlot(ctx, groupSlots); err != nil {
+		return nil, err
+	}
+	if err := acquireSlot(ctx, globalSlots); err != nil {
+		releaseSlot(groupSlots)
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			releaseSlot(globalSlots)
+			releaseSlot(groupSlots)
+		})
+	}, nil
+}
+
+func acquireSlot(ctx context.Context, slots chan struct{}) error {
+	if slots == nil {
+		return nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func releaseSlot(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
+// EffectiveMaxConcurrentTasks は、global_max_concurrent_tasks の値から実際に使用する全体の同時実行数を返します。
+// 未設定（0以下）の場合は 1 とし、CLIモードとシステムトレイで同じ既定値を使用します。
+func EffectiveMaxConcurrentTasks(globalMax int) int {
+	if globalMax <= 0 {
+		return 1
+	}
+	return globalMax
+}
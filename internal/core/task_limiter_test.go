@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestTaskLimiter_GroupCap(t *testing.T) {
+	t.Parallel()
+
+	l := newTaskLimiter(2, map[string]config.TaskGroup{"2chan": {MaxConcurrentTasks: 1}})
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, "2chan")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// 同じグループの2つ目はグループの上限で待たされる
+	shortCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(shortCtx, "2chan"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("同じグループの acquire() error = %v, want DeadlineExceeded", err)
+	}
+
+	// 別のグループ（およびグループなし）は全体の枠が空いていれば実行できる
+	releaseOther, err := l.acquire(ctx, "other")
+	if err != nil {
+		t.Fatalf("別グループの acquire() error = %v", err)
+	}
+
+	// 全体の上限（2）に達しているため、グループなしのタスクも待たされる
+	shortCtx2, cancel2 := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel2()
+	if _, err := l.acquire(shortCtx2, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("全体上限到達時の acquire() error = %v, want DeadlineExceeded", err)
+	}
+
+	// 解放は冪等であり、解放後は再び確保できる
+	release()
+	release()
+	releaseAgain, err := l.acquire(ctx, "2chan")
+	if err != nil {
+		t.Fatalf("解放後の acquire() error = %v", err)
+	}
+	releaseAgain()
+	releaseOther()
+
+	if len(l.global) != 0 || len(l.groups["2chan"]) != 0 {
+		t.Errorf("全ての枠が解放されていません (global=%d, group=%d)", len(l.global), len(l.groups["2chan"]))
+	}
+}
+
+func TestTaskLimiter_Unlimited(t *testing.T) {
+	t.Parallel()
+
+	l := newTaskLimiter(0, map[string]config.TaskGroup{"zero": {MaxConcurrentTasks: 0}})
+	for i := 0; i < 10; i++ {
+		if _, err := l.acquire(context.Background(), "zero"); err != nil {
+			t.Fatalf("上限なしの acquire() error = %v", err)
+		}
+	}
+}
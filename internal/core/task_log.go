@@ -0,0 +1,70 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// defaultTaskLogDir は、タスクの enable_log_file が true で log_file_path が未設定の場合のログの保存先です。
+const defaultTaskLogDir = "logs"
+
+// globalLogWriter は、標準の log パッケージの現在の出力先に書き込みます。
+// 実行中にログファイルの出力を切り替えても（システムトレイのメニューなど）、タスクのログが追従します。
+type globalLogWriter struct{}
+
+func (globalLogWriter) Write(p []byte) (int, error) {
+	return log.Writer().Write(p)
+}
+
+// taskLogFilePath は、タスク専用のログファイルのパスを返します。専用のログファイルを使用しない場合は空文字を返します。
+func taskLogFilePath(task config.Task) string {
+	if task.LogFilePath != "" {
+		return task.LogFilePath
+	}
+	if task.EnableLogFile {
+		return filepath.Join(defaultTaskLogDir, SanitizeFilename(task.TaskName)+".log")
+	}
+	return ""
+}
+
+// newTaskLogger は、"[タスク名] " を接頭辞とするタスクのロガーと、終了時に呼び出す関数を返します。
+// ログは全体のログの出力先に書き込まれ、タスク専用のログファイルが設定されている場合はそのファイルにも追記されます。
+// 専用のログファイルを開けない場合は、警告を出力して全体のログのみに書き込みます。
+func newTaskLogger(task config.Task) (*log.Logger, func()) {
+	prefix := fmt.Sprintf("[%s] ", task.TaskName)
+	var out io.Writer = globalLogWriter{}
+
+	path := taskLogFilePath(task)
+	if path == "" {
+		return log.New(out, prefix, log.LstdFlags), func() {}
+	}
+
+	f, err := openTaskLogFile(path)
+	if err != nil {
+		log.Printf("WARNING: %sタスクのログファイルを開けませんでした。全体のログのみに出力します: %v", prefix, err)
+		return log.New(out, prefix, log.LstdFlags), func() {}
+	}
+	logger := log.New(io.MultiWriter(out, f), prefix, log.LstdFlags)
+	return logger, func() {
+		if err := f.Close(); err != nil {
+			log.Printf("WARNING: %sタスクのログファイルのクローズに失敗しました (path=%s): %v", prefix, path, err)
+		}
+	}
+}
+
+// openTaskLogFile は、タスク専用のログファイルを追記モードで開きます。ディレクトリがない場合は作成します。
+func openTaskLogFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("ログファイルのディレクトリ作成に失敗しました (path=%s): %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("ログファイルを開けませんでした (path=%s): %w", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestTaskLogFilePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		task config.Task
+		want string
+	}{
+		{name: "未設定", task: config.Task{TaskName: "a"}, want: ""},
+		{name: "パス指定", task: config.Task{TaskName: "a", LogFilePath: "x/a.log"}, want: "x/a.log"},
+		{name: "有効化のみなら既定のパス", task: config.Task{TaskName: "a/b", EnableLogFile: true}, want: filepath.Join(defaultTaskLogDir, SanitizeFilename("a/b")+".log")},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := taskLogFilePath(tt.task); got != tt.want {
+				t.Errorf("taskLogFilePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTaskLogger(t *testing.T) {
+	// 標準の log パッケージの出力先を差し替えるため並列実行しない
+	var global bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&global)
+	defer log.SetOutput(prev)
+
+	path := filepath.Join(t.TempDir(), "logs", "a.log")
+	logger, closeLog := newTaskLogger(config.Task{TaskName: "a", LogFilePath: path})
+	logger.Println("タスクのログ")
+	closeLog()
+
+	// 他のタスクのログは専用のファイルに書き込まれない
+	other, closeOther := newTaskLogger(config.Task{TaskName: "b"})
+	other.Println("他のタスクのログ")
+	closeOther()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("タスクのログファイルを読み込めませんでした: %v", err)
+	}
+	if got := string(data); !strings.Contains(got, "[a] ") || !strings.Contains(got, "タスクのログ") || strings.Contains(got, "他のタスク") {
+		t.Errorf("タスクのログファイルの内容 = %q", got)
+	}
+	if got := global.String(); !strings.Contains(got, "[a] ") || !strings.Contains(got, "[b] ") || !strings.Contains(got, "他のタスクのログ") {
+		t.Errorf("全体のログの内容 = %q", got)
+	}
+}
@@ -3,15 +3,18 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"GoImageBoardArchiver/internal/adapter"
 	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
 	"GoImageBoardArchiver/internal/model"
 	"GoImageBoardArchiver/internal/network"
 )
@@ -19,137 +22,414 @@ import (
 // ExecuteTask は、単一のタスクの全ライフサイクルを管理・実行します。
 func ExecuteTask(ctx context.Context, task config.Task, globalNetworkSettings config.NetworkSettings, safetyStopMinDiskGB float64, isWatchMode bool, statusCh chan<- AppStatus) {
 
-	logger := log.New(os.Stdout, fmt.Sprintf("[%s] ", task.TaskName), log.LstdFlags|log.Ltime)
+	// タスクのログは全体のログに加え、設定されている場合はタスク専用のログファイルにも書き込む
+	logger, closeLog := newTaskLogger(task)
+	defer closeLog()
 	logger.Println("タスクを開始します。")
 
 	// --- コンポーネントの初期化 ---
 	client, err := network.NewClient(globalNetworkSettings)
 	if err != nil {
 		logger.Printf("FATAL: ネットワーククライアントの初期化に失敗しました: %v", err)
+		reportTaskError(task, "", ErrorClassSetup, fmt.Errorf("ネットワーククライアントの初期化に失敗しました: %w", err), StateError, isWatchMode, statusCh)
 		return
 	}
 
 	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
 	if err != nil {
 		logger.Printf("FATAL: サイトアダプタの取得に失敗しました: %v", err)
+		reportTaskError(task, "", ErrorClassSetup, fmt.Errorf("サイトアダプタの取得に失敗しました: %w", err), StateError, isWatchMode, statusCh)
 		return
 	}
 
 	if err := siteAdapter.Prepare(client, task); err != nil {
 		logger.Printf("FATAL: サイト固有設定の適用に失敗しました: %v", err)
+		class := ErrorClassSetup
+		if errors.Is(err, errs.ErrAuthFailed) {
+			class = ErrorClassAuth
+		}
+		reportTaskError(task, "", class, fmt.Errorf("サイト固有設定の適用に失敗しました: %w", err), StateError, isWatchMode, statusCh)
 		return
 	}
+	// サムネイルを抽出できないアダプタでサムネイルのみを保存すると、メディアが1件も保存されない
+	if task.ThumbnailsOnly && !siteAdapter.Capabilities().Thumbnails {
+		logger.Printf("WARNING: サイトアダプタ '%s' はサムネイルに対応していないため、thumbnails_only を無効にしてフルサイズのメディアを保存します", task.SiteAdapter)
+		task.ThumbnailsOnly = false
+	}
+
+	// 監視モードでは、スレッドHTMLを前回の取得時の ETag / Last-Modified を条件とする条件付きリクエストで取得する
+	if isWatchMode {
+		task.ConditionalThreadFetch = true
+	}
+
+	// 生存確認: 巡回サイクルの開始・スレッドの完了・待機開始のたびにハートビートを記録する
+	// adaptive_polling が有効な場合は、下限の間隔で確認するスレッドのためにサイクルの間隔を短くする
+	scheduler := newPollScheduler(task)
+	interval := scheduler.cycleInterval(watchInterval(task))
+	defer sharedHealthMonitor.remove(task.TaskName)
+
+	// maintenanceCount は、メンテナンス中の表示を続けて検出した回数です。一時停止の時間を延ばすために使用します。
+	maintenanceCount := 0
+
+	// 前回の実行で中断した保留キューのスレッドと、レジュームファイルが残っているスレッドは、カタログを取得する前にアーカイブする
+	queue := newPendingQueue(task, logger)
+	if resumed := mergeThreads(queue.resume(), findInterruptedThreads(task, logger)); len(resumed) > 0 {
+		logger.Printf("前回の実行で中断した %d 件のスレッドのアーカイブを再開します。", len(resumed))
+		if err := sharedPauseGate.wait(ctx); err != nil {
+			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+			return
+		}
+		releaseSlot, err := sharedTaskLimiter.acquire(ctx, task.Group)
+		if err != nil {
+			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+			return
+		}
+		err = archiveTargetThreads(ctx, client, siteAdapter, task, resumed, queue, scheduler, isWatchMode, statusCh, logger)
+		releaseSlot()
+		if ctx.Err() != nil {
+			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+			return
+		}
+		if err != nil {
+			maintenanceCount++
+			if pauseForMaintenance(ctx, task, err, maintenanceCount, isWatchMode, statusCh, logger) != nil {
+				logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+				return
+			}
+		}
+	}
+
+	// 複数のタスクの最初のカタログ取得が重ならないよう、割り当てられた時間だけ開始を遅らせる
+	if isWatchMode && task.StartOffset > 0 {
+		logger.Printf("他のタスクとカタログの取得が重ならないよう、%v 後に開始します。", task.StartOffset.Round(time.Second))
+		sharedHealthMonitor.beat(task.TaskName, task.StartOffset)
+		if err := sleepContext(ctx, task.StartOffset); err != nil {
+			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+			return
+		}
+	}
 
 	for {
+		sharedHealthMonitor.beat(task.TaskName, interval)
 
-		if err := checkDiskSpace(task.SaveRootDirectory, safetyStopMinDiskGB); err != nil {
-			logger.Printf("CRITICAL: ディスク空き容量のチェックに失敗しました: %v。タスクを一時停止します。", err)
+		if err := checkDiskSpace(task.SaveRootDirectory, safetyStopMinDiskGB); errors.Is(err, errs.ErrDiskFull) {
+			logger.Printf("CRITICAL: %v。タスクを一時停止します。", err)
+			rec := sharedErrorHistory.record(task.TaskName, "", ErrorClassDiskFull, err)
 			if statusCh != nil {
-				statusCh <- AppStatus{TaskName: task.TaskName, State: StateError, Detail: fmt.Sprintf("ディスク容量不足: %v", err), HasError: true}
+				statusCh <- AppStatus{TaskName: task.TaskName, State: StateError, Detail: fmt.Sprintf("ディスク容量不足: %v", err), HasError: true, Error: &rec}
+			}
+			if !isWatchMode {
+				break
+			}
+			// 空き容量が確保されるまで、巡回の間隔をおいて確認し直す
+			if err := sleepContext(ctx, interval); err != nil {
+				logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+				return
 			}
 			continue
+		} else if err != nil {
+			logger.Printf("WARNING: ディスク空き容量のチェックに失敗しました: %v。確認せずに続行します。", err)
+		}
+
+		// 一時停止中は、再開されるまで次の巡回サイクルを開始しない
+		if err := sharedPauseGate.wait(ctx); err != nil {
+			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+			return
 		}
 
 		if statusCh != nil {
 			statusCh <- AppStatus{TaskName: task.TaskName, State: StateRunning, Detail: fmt.Sprintf("タスク '%s' を実行中...", task.TaskName), IsWatching: isWatchMode}
 		}
 
+		// 全体およびグループの実行枠を確保する（サイクル終了時に解放）
+		releaseSlot, err := sharedTaskLimiter.acquire(ctx, task.Group)
+		if err != nil {
+			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+			return
+		}
+
+		// 板のルール・注意書きは、失敗してもスレッドのアーカイブを止めずに次の巡回で再試行する
+		if err := archiveBoardMeta(ctx, client, siteAdapter, task, time.Now(), logger); err != nil && ctx.Err() == nil {
+			logger.Printf("WARNING: 板の情報の保存に失敗しました: %v", err)
+		}
+
 		logger.Println("一次フィルタリングを開始します...")
 		targetThreads, err := primaryFiltering(ctx, task, client, siteAdapter)
 		if err != nil {
-			logger.Printf("ERROR: 一次フィルタリングに失敗しました: %v。次のサイクルで再試行します。", err)
+			releaseSlot()
+			// メンテナンス中の表示はカタログとして処理せず、時間をおいて再試行する
+			if errors.Is(err, errs.ErrMaintenance) {
+				maintenanceCount++
+				if pauseForMaintenance(ctx, task, err, maintenanceCount, isWatchMode, statusCh, logger) != nil {
+					logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+					return
+				}
+				continue
+			}
+			if errors.Is(err, errs.ErrLayoutChanged) {
+				reportLayoutChange(task, err, isWatchMode, statusCh, logger)
+			} else if ctx.Err() == nil {
+				reportTaskError(task, "", classifyError(err), fmt.Errorf("一次フィルタリングに失敗しました: %w", err), StateError, isWatchMode, statusCh)
+			}
+			if ctx.Err() != nil {
+				logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+				return
+			}
+			if !isWatchMode {
+				logger.Printf("ERROR: 一次フィルタリングに失敗しました: %v", err)
+				break
+			}
+			// 失敗したカタログをすぐに取得し直さないよう、巡回の間隔をおいて再試行する
+			logger.Printf("ERROR: 一次フィルタリングに失敗しました: %v。%v 後に再試行します。", err, interval.Round(time.Second))
+			sharedHealthMonitor.beat(task.TaskName, interval)
+			if err := sleepContext(ctx, interval); err != nil {
+				logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+				return
+			}
 			continue
 		}
 
+		// 変化のない確認が続いているスレッドは、次の確認時刻まで見送る
+		targetThreads, deferred := scheduler.due(targetThreads, time.Now())
+		if deferred > 0 {
+			logger.Printf("更新の少ない %d 件のスレッドの確認を次回以降に見送ります。", deferred)
+		}
+
 		if len(targetThreads) == 0 {
 			logger.Println("新しい対象スレッドは見つかりませんでした。")
 			if !isWatchMode {
+				releaseSlot()
 				break
 			}
 		} else {
 			logger.Printf("%d件の新しい対象スレッドが見つかりました。", len(targetThreads))
-
-			var threadWg sync.WaitGroup
-			maxConcurrentDownloads := task.MaxConcurrentDownloads
-			if maxConcurrentDownloads <= 0 {
-				maxConcurrentDownloads = 4
-			}
-			threadSemaphore := make(chan struct{}, maxConcurrentDownloads)
-
-			for _, th := range targetThreads {
-				select {
-				case <-ctx.Done():
-					logger.Println("シャットダウンシグナルにより、新規スレッドの処理を中止します。")
-					goto end_loop
-				default:
+			if err := archiveTargetThreads(ctx, client, siteAdapter, task, targetThreads, queue, scheduler, isWatchMode, statusCh, logger); err != nil {
+				releaseSlot()
+				maintenanceCount++
+				if pauseForMaintenance(ctx, task, err, maintenanceCount, isWatchMode, statusCh, logger) != nil {
+					logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
+					return
 				}
-
-				threadWg.Add(1)
-				threadSemaphore <- struct{}{}
-
-				go func(th model.ThreadInfo) {
-					defer threadWg.Done()
-					defer func() { <-threadSemaphore }()
-					result := ArchiveSingleThread(ctx, client, siteAdapter, task, th, logger)
-					if result.Error != nil {
-						logger.Printf("ERROR: スレッド %s のアーカイブに失敗しました: %v", th.ID, result.Error)
-					}
-				}(th)
+				continue
 			}
-		end_loop:
-
-			threadWg.Wait()
 			logger.Println("今回の実行サイクルが完了しました。")
 		}
+		releaseSlot()
+		maintenanceCount = 0
 
 		if !isWatchMode {
 			break
 		}
 
+		// 完全再ダウンロードは最初のサイクルのみ行う
+		task.ForceFull = false
+
 		// 監視モードの場合、次のチェックまで待機
-		interval := time.Duration(task.WatchIntervalMillis) * time.Millisecond
-		if interval <= 0 {
-			interval = 15 * time.Minute
-		}
-		nextRun := time.Now().Add(interval)
-		logger.Printf("次のチェックまで %v 待機します... (予定: %s)", interval, nextRun.Format("15:04:05"))
+		// watch_jitter_percent が設定されている場合は、他のタスクと同期しないよう待機時間を揺らす
+		wait := jitteredInterval(interval, task.WatchJitterPercent)
+		sharedHealthMonitor.beat(task.TaskName, wait)
+		nextRun := time.Now().Add(wait)
+		logger.Printf("次のチェックまで %v 待機します... (予定: %s)", wait.Round(time.Second), nextRun.Format("15:04:05"))
 
 		if statusCh != nil {
-			// NEXT_RUN:Timestamp 形式で通知
+			// NEXT_RUN:<Unix秒> 形式で通知
 			statusCh <- AppStatus{
 				TaskName:   task.TaskName,
 				State:      StateWatching,
-				Detail:     fmt.Sprintf("NEXT_RUN:%d", nextRun.Unix()),
+				Detail:     fmt.Sprintf("%s%d", nextRunPrefix, nextRun.Unix()),
 				IsWatching: true,
 			}
 		}
 
-		select {
-		case <-ctx.Done():
+		if err := sleepContext(ctx, wait); err != nil {
 			logger.Println("シャットダウンシグナルを受信しました。タスクを終了します。")
 			return
-		case <-time.After(interval):
 		}
 	}
 
 	logger.Println("タスクを終了します。")
 }
 
-func primaryFiltering(ctx context.Context, task config.Task, client *network.Client, siteAdapter adapter.SiteAdapter) ([]model.ThreadInfo, error) {
-	catalogURL, err := siteAdapter.BuildCatalogURL(task.TargetBoardURL)
-	if err != nil {
-		return nil, fmt.Errorf("カタログURLの構築に失敗しました (base_url=%s, adapter=%s): %w", task.TargetBoardURL, task.SiteAdapter, err)
+// archiveTargetThreads は、対象のスレッドを並行してアーカイブし、すべての完了を待ちます。
+// 処理中のスレッドは保留キューに保存し、完了（失敗・スキップを含む）したものから取り除きます。
+// シャットダウンにより開始できなかった、または中断したスレッドは、次回の起動時に再開するため保留キューに残します。
+// 各スレッドの結果（更新の有無）は scheduler に記録し、次に確認する時刻の計算に使用します。
+// スレッドの取得でメンテナンス中の表示を検出した場合は、以降のスレッドを開始せずに errs.ErrMaintenance をラップしたエラーを返します。
+// 検出したスレッドと開始しなかったスレッドは、一時停止の後に再開するため保留キューに残します。
+func archiveTargetThreads(ctx context.Context, client *network.Client, siteAdapter adapter.SiteAdapter, task config.Task, targetThreads []model.ThreadInfo, queue *pendingQueue, scheduler *pollScheduler, isWatchMode bool, statusCh chan<- AppStatus, logger *log.Logger) error {
+	interval := scheduler.cycleInterval(watchInterval(task))
+	queue.set(targetThreads)
+
+	var threadWg sync.WaitGroup
+	threadSemaphore := make(chan struct{}, threadConcurrency(task))
+	var maintenanceMu sync.Mutex
+	var maintenanceErr error
+	maintenance := func() error {
+		maintenanceMu.Lock()
+		defer maintenanceMu.Unlock()
+		return maintenanceErr
 	}
 
-	catalogHTMLString, err := client.Get(ctx, catalogURL)
-	if err != nil {
-		return nil, fmt.Errorf("カタログHTMLの取得に失敗しました (url=%s, task=%s): %w", catalogURL, task.TaskName, err)
+loop:
+	for _, th := range targetThreads {
+		select {
+		case <-ctx.Done():
+			logger.Println("シャットダウンシグナルにより、新規スレッドの処理を中止します。")
+			break loop
+		default:
+		}
+		if maintenance() != nil {
+			logger.Println("掲示板のメンテナンス中の表示を検出したため、新規スレッドの処理を中止します。")
+			break loop
+		}
+
+		// 一時停止中は、実行中のスレッドを続けたまま新しいスレッドを開始しない
+		if err := sharedPauseGate.wait(ctx); err != nil {
+			logger.Println("シャットダウンシグナルにより、新規スレッドの処理を中止します。")
+			break loop
+		}
+
+		// 実行中のスレッドの終了を待つ間もシャットダウンに応じる
+		select {
+		case <-ctx.Done():
+			logger.Println("シャットダウンシグナルにより、新規スレッドの処理を中止します。")
+			break loop
+		case threadSemaphore <- struct{}{}:
+		}
+		threadWg.Add(1)
+
+		go func(th model.ThreadInfo) {
+			defer threadWg.Done()
+			defer func() { <-threadSemaphore }()
+			result := ArchiveSingleThread(ctx, client, siteAdapter, task, th, logger)
+			sharedHealthMonitor.beat(task.TaskName, interval)
+			// シャットダウンで中断したスレッドと、メンテナンス中の表示を検出したスレッドのみ保留キューに残す
+			if (result.Error == nil || ctx.Err() == nil) && !errors.Is(result.Error, errs.ErrMaintenance) {
+				queue.done(th.ID)
+			}
+			switch {
+			case result.Error == nil:
+				// 更新がなくスキップした場合（Success が false）は、確認の間隔を延ばす
+				scheduler.record(th, result.Success, time.Now())
+			case errors.Is(result.Error, errs.ErrFiltered):
+				// フィルタによるスキップは正常系
+				scheduler.record(th, false, time.Now())
+			case errors.Is(result.Error, errs.ErrLayoutChanged):
+				reportLayoutChange(task, result.Error, isWatchMode, statusCh, logger)
+			case errors.Is(result.Error, errs.ErrMaintenance):
+				maintenanceMu.Lock()
+				if maintenanceErr == nil {
+					maintenanceErr = result.Error
+				}
+				maintenanceMu.Unlock()
+			case errors.Is(result.Error, errs.ErrThreadGone):
+				logger.Printf("INFO: スレッド %s は既に落ちています: %v", th.ID, result.Error)
+				scheduler.forget(th.ID)
+				// アーカイブの途中で落ちた場合は、ダウンロード済みのファイルで最終的な状態にする
+				finalizeInterruptedThread(task, siteAdapter, th.ID, logger)
+			case ctx.Err() != nil:
+				logger.Printf("INFO: シャットダウンによりスレッド %s の処理を中断しました。次回の実行で再開します。", th.ID)
+			default:
+				logger.Printf("ERROR: スレッド %s のアーカイブに失敗しました: %v", th.ID, result.Error)
+				if ctx.Err() == nil {
+					reportTaskError(task, th.ID, classifyError(result.Error), result.Error, StateRunning, isWatchMode, statusCh)
+				}
+			}
+			if result.Success && statusCh != nil {
+				statusCh <- AppStatus{
+					TaskName:   task.TaskName,
+					State:      StateRunning,
+					Detail:     fmt.Sprintf("アーカイブ完了: %s", result.Title),
+					IsWatching: isWatchMode,
+					Archived: &ArchivedThread{
+						TaskName:        task.TaskName,
+						ThreadID:        th.ID,
+						Title:           result.Title,
+						SavePath:        result.SavePath,
+						PostCount:       result.PostCount,
+						NewPosts:        result.NewPosts,
+						FilesDownloaded: result.FilesDownloaded,
+						BytesWritten:    result.BytesWritten,
+						CompletedAt:     time.Now(),
+					},
+				}
+			}
+		}(th)
+	}
+
+	threadWg.Wait()
+	return maintenance()
+}
+
+// mergeThreads は、a に b のうち a にないスレッドを順に追加した一覧を返します。
+func mergeThreads(a, b []model.ThreadInfo) []model.ThreadInfo {
+	seen := make(map[string]bool, len(a))
+	for _, th := range a {
+		seen[th.ID] = true
+	}
+	for _, th := range b {
+		if !seen[th.ID] {
+			seen[th.ID] = true
+			a = append(a, th)
+		}
+	}
+	return a
+}
+
+// watchInterval は、監視モードの巡回間隔を返します（未設定時は15分）。
+func watchInterval(task config.Task) time.Duration {
+	interval := time.Duration(task.WatchIntervalMillis) * time.Millisecond
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return interval
+}
+
+// reportLayoutChange は、サイト構造の変化の可能性をログとUIに通知します。
+// 空のスレッドを黙ってアーカイブし続けることを防ぐため、通常のエラーとは区別して扱います。
+func reportLayoutChange(task config.Task, err error, isWatchMode bool, statusCh chan<- AppStatus, logger *log.Logger) {
+	logger.Printf("ALERT: サイトの構造が変更された可能性があります。アダプタ '%s' の更新が必要かもしれません: %v", task.SiteAdapter, err)
+	if task.NotifyOnError {
+		logger.Println("Notification: Site layout may have changed:", task.TargetBoardURL)
 	}
-	catalogHTML := []byte(catalogHTMLString)
+	rec := sharedErrorHistory.record(task.TaskName, "", ErrorClassLayoutChanged, err)
+	if statusCh != nil {
+		statusCh <- AppStatus{
+			TaskName:   task.TaskName,
+			State:      StateError,
+			Detail:     fmt.Sprintf("サイト構造の変化を検知: %s", task.TargetBoardURL),
+			IsWatching: isWatchMode,
+			HasError:   true,
+			Error:      &rec,
+		}
+	}
+}
 
-	candidateThreads, err := siteAdapter.ParseCatalog(catalogHTML)
+// reportTaskError は、エラーを最近のエラーとして記録し、UIに通知します。
+// threadID はスレッドの処理中に発生した場合のみ指定します。
+func reportTaskError(task config.Task, threadID string, class ErrorClass, err error, state AppState, isWatchMode bool, statusCh chan<- AppStatus) {
+	rec := sharedErrorHistory.record(task.TaskName, threadID, class, err)
+	if statusCh == nil {
+		return
+	}
+	detail := fmt.Sprintf("%s: %s", class.Label(), TruncateGraphemes(rec.Message, 60))
+	if threadID != "" {
+		detail = fmt.Sprintf("スレッド %s で%s", threadID, detail)
+	}
+	statusCh <- AppStatus{
+		TaskName:   task.TaskName,
+		State:      state,
+		Detail:     detail,
+		IsWatching: isWatchMode,
+		HasError:   true,
+		Error:      &rec,
+	}
+}
+
+func primaryFiltering(ctx context.Context, task config.Task, client *network.Client, siteAdapter adapter.SiteAdapter) ([]model.ThreadInfo, error) {
+	candidateThreads, err := fetchCatalog(ctx, task, client, siteAdapter)
 	if err != nil {
-		return nil, fmt.Errorf("カタログHTMLの解析に失敗しました (size=%d bytes, task=%s): %w", len(catalogHTML), task.TaskName, err)
+		return nil, err
 	}
 
 	// 履歴チェックは削除（増分アーカイブに対応するため、全スレッドを候補とする）
@@ -175,6 +455,105 @@ func primaryFiltering(ctx context.Context, task config.Task, client *network.Cli
 	return targetThreads, nil
 }
 
+// fetchCatalog は、カタログを取得・解析してスレッド候補の一覧を返します。
+// 同じ板を対象とする複数のタスクが同じサイクル内で実行された場合、
+// 取得は一度だけ行われ、結果は sharedCatalogCache を通じて共有されます。
+func fetchCatalog(ctx context.Context, task config.Task, client *network.Client, siteAdapter adapter.SiteAdapter) ([]model.ThreadInfo, error) {
+	catalogURL, err := siteAdapter.BuildCatalogURL(task.TargetBoardURL)
+	if err != nil {
+		return nil, fmt.Errorf("カタログURLの構築に失敗しました (base_url=%s, adapter=%s): %w", task.TargetBoardURL, task.SiteAdapter, err)
+	}
+
+	key, err := catalogCacheKeyForTask(task)
+	if err != nil {
+		return nil, err
+	}
+
+	return sharedCatalogCache.get(ctx, key, func() ([]model.ThreadInfo, error) {
+		var catalogHTMLString string
+		var threads []model.ThreadInfo
+		for {
+			catalogHTMLString, err = client.Get(ctx, catalogURL)
+			if err != nil {
+				return nil, fmt.Errorf("カタログHTMLの取得に失敗しました (url=%s, task=%s): %w", catalogURL, task.TaskName, err)
+			}
+			catalogHTML := []byte(catalogHTMLString)
+			if err := checkMaintenance(siteAdapter, catalogURL, catalogHTML); err != nil {
+				return nil, err
+			}
+
+			threads, err = siteAdapter.ParseCatalog(catalogHTML)
+			if err != nil {
+				return nil, fmt.Errorf("カタログHTMLの解析に失敗しました (size=%d bytes, task=%s): %w", len(catalogHTML), task.TaskName, err)
+			}
+			// 表示設定が無視された場合は、アダプタが設定を変えて取得し直す
+			retrier, ok := siteAdapter.(adapter.CatalogRetrier)
+			if !ok || !retrier.RetryCatalog(client, threads) {
+				break
+			}
+		}
+		if err := sharedLayoutMonitor.check(layoutKey(task, "catalog"), catalogHTMLString, len(threads)); err != nil {
+			return nil, err
+		}
+		if pager, ok := siteAdapter.(adapter.CatalogPager); ok && len(threads) > 0 {
+			return fetchCatalogPages(ctx, task, client, siteAdapter, pager, threads)
+		}
+		return threads, nil
+	})
+}
+
+// fetchCatalogPages は、複数のページに分かれたカタログの2ページ目以降を取得し、1ページ目の threads に続けて返します。
+// スレッドが1件もないページで取得をやめます。ページの間に新しいスレッドが増えて前のページのスレッドがずれた場合の重複は取り除きます。
+func fetchCatalogPages(ctx context.Context, task config.Task, client *network.Client, siteAdapter adapter.SiteAdapter, pager adapter.CatalogPager, threads []model.ThreadInfo) ([]model.ThreadInfo, error) {
+	pageURLs, err := pager.CatalogPageURLs(task.TargetBoardURL)
+	if err != nil {
+		return nil, fmt.Errorf("カタログのページのURLの構築に失敗しました (base_url=%s, adapter=%s): %w", task.TargetBoardURL, task.SiteAdapter, err)
+	}
+	seen := make(map[string]bool, len(threads))
+	for _, thread := range threads {
+		seen[thread.ID] = true
+	}
+	for _, pageURL := range pageURLs {
+		body, err := client.Get(ctx, pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("カタログのページの取得に失敗しました (url=%s, task=%s): %w", pageURL, task.TaskName, err)
+		}
+		if err := checkMaintenance(siteAdapter, pageURL, []byte(body)); err != nil {
+			return nil, err
+		}
+		page, err := siteAdapter.ParseCatalog([]byte(body))
+		if err != nil {
+			return nil, fmt.Errorf("カタログのページの解析に失敗しました (url=%s, task=%s): %w", pageURL, task.TaskName, err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, thread := range page {
+			if !seen[thread.ID] {
+				seen[thread.ID] = true
+				threads = append(threads, thread)
+			}
+		}
+	}
+	return threads, nil
+}
+
+// sleepContext は、d の間待機します。待機中に ctx がキャンセルされた場合は直ちに ctx.Err() を返します。
+// time.After と異なり、キャンセルされた時点でタイマーを解放します。
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func containsAny(s string, substrings []string) bool {
 	for _, sub := range substrings {
 		if strings.Contains(s, sub) {
@@ -184,6 +563,39 @@ func containsAny(s string, substrings []string) bool {
 	return false
 }
 
-func checkDiskSpace(_ string, _ float64) error {
+// checkDiskSpace は、保存先の空き容量がセーフティーストップの閾値以上あるかを確認します。
+// 不足している場合は errs.ErrDiskFull をラップしたエラーを、空き容量を取得できない場合はそれ以外のエラーを返します。
+// 閾値が0以下の場合は確認しません。保存先がまだ作成されていない場合は、存在する親ディレクトリで確認します。
+func checkDiskSpace(saveDir string, minFreeGB float64) error {
+	if minFreeGB <= 0 {
+		return nil
+	}
+	dir := existingAncestor(saveDir)
+	free, err := freeDiskSpace(dir)
+	if err != nil {
+		return fmt.Errorf("空き容量の取得に失敗しました (path=%s): %w", dir, err)
+	}
+	freeGB := float64(free) / (1 << 30)
+	if freeGB < minFreeGB {
+		return fmt.Errorf("%w: 空き容量 %.2f GB が閾値 %.2f GB を下回っています (path=%s)", errs.ErrDiskFull, freeGB, minFreeGB, dir)
+	}
 	return nil
 }
+
+// existingAncestor は、path 自身またはその親のうち、存在する最も近いディレクトリを返します。
+func existingAncestor(path string) string {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		dir = path
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+	"GoImageBoardArchiver/internal/network"
+)
+
+func TestSleepContext(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		d       time.Duration
+		wantErr error
+		maxWait time.Duration
+	}{
+		{name: "待機して終了", ctx: context.Background(), d: 10 * time.Millisecond, maxWait: time.Second},
+		{name: "待機時間なし", ctx: context.Background(), d: 0, maxWait: 100 * time.Millisecond},
+		{name: "キャンセル済みなら直ちに終了", ctx: canceled, d: time.Hour, wantErr: context.Canceled, maxWait: 100 * time.Millisecond},
+		{name: "待機時間なしでもキャンセルを返す", ctx: canceled, d: 0, wantErr: context.Canceled, maxWait: 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+			err := sleepContext(tt.ctx, tt.d)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("sleepContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxWait {
+				t.Errorf("sleepContext() の待機時間 = %v, want %v 以内", elapsed, tt.maxWait)
+			}
+		})
+	}
+}
+
+func TestFetchCatalogPages(t *testing.T) {
+	t.Parallel()
+
+	// 2ページ目は1ページ目と1件重複し、3ページ目で検索結果が終わる
+	pages := map[string]string{
+		"1": `[{"id": 3, "file_url": "https://cdn.example.com/3.jpg"}, {"id": 2, "file_url": "https://cdn.example.com/2.jpg"}]`,
+		"2": `[{"id": 2, "file_url": "https://cdn.example.com/2.jpg"}, {"id": 1, "file_url": "https://cdn.example.com/1.jpg"}]`,
+		"3": `[]`,
+	}
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		requested = append(requested, page)
+		w.Write([]byte(pages[page]))
+	}))
+	defer server.Close()
+
+	task := config.Task{
+		TaskName:       "booru",
+		SiteAdapter:    config.SiteAdapterBooru,
+		TargetBoardURL: server.URL + "/",
+		BooruSettings:  &config.BooruSettings{API: config.BooruAPIDanbooru, Tags: "test", MaxPages: 5},
+	}
+	client, err := network.NewClient(config.NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := siteAdapter.Prepare(client, task); err != nil {
+		t.Fatal(err)
+	}
+
+	threads, err := fetchCatalog(context.Background(), task, client, siteAdapter)
+	if err != nil {
+		t.Fatalf("fetchCatalog() error = %v", err)
+	}
+	var ids []string
+	for _, thread := range threads {
+		ids = append(ids, thread.ID)
+	}
+	if strings.Join(ids, ",") != "3,2,1" {
+		t.Errorf("thread IDs = %v, want [3 2 1]", ids)
+	}
+	if strings.Join(requested, ",") != "1,2,3" {
+		t.Errorf("requested pages = %v, want [1 2 3]", requested)
+	}
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	t.Parallel()
+
+	// 保存先がまだ作成されていない場合は、存在する親ディレクトリで確認する
+	notCreated := filepath.Join(t.TempDir(), "archives", "b")
+	tests := []struct {
+		name      string
+		minFreeGB float64
+		wantFull  bool
+	}{
+		{name: "閾値なし", minFreeGB: 0, wantFull: false},
+		{name: "十分な空き容量", minFreeGB: 1e-9, wantFull: false},
+		{name: "空き容量不足", minFreeGB: 1e12, wantFull: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkDiskSpace(notCreated, tt.minFreeGB)
+			if got := errors.Is(err, errs.ErrDiskFull); got != tt.wantFull {
+				t.Errorf("checkDiskSpace(%v) error = %v, want ErrDiskFull: %v", tt.minFreeGB, err, tt.wantFull)
+			}
+			if !tt.wantFull && err != nil {
+				t.Errorf("checkDiskSpace(%v) error = %v, want nil", tt.minFreeGB, err)
+			}
+		})
+	}
+}
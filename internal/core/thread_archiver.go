@@ -4,6 +4,7 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,10 +14,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/archivecrypt"
 	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
 	"GoImageBoardArchiver/internal/model"
 	"GoImageBoardArchiver/internal/network"
 )
@@ -31,6 +35,7 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 	}
 
 	logger.Printf("Processing thread: %s (%s)", thread.ID, thread.Title)
+	thread.Date = inTaskTimezone(task, thread.Date)
 
 	// STEP 1: スレッドHTMLの取得と二次フィルタリング（ディレクトリ作成前に実行）
 	threadURL, err := url.Parse(task.TargetBoardURL)
@@ -38,14 +43,24 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		result.Error = fmt.Errorf("ターゲットボードURLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
 		return result
 	}
-	threadURL = threadURL.JoinPath(thread.URL)
+	threadURL = joinThreadURL(threadURL, thread.URL)
 
-	threadHTMLString, err := client.Get(ctx, threadURL.String())
+	// 監視モードでは前回の ETag / Last-Modified を条件とし、更新のないスレッドの転送を省く
+	threadHTMLString, validators, notModified, err := client.GetConditional(ctx, threadURL.String(), previousThreadValidators(task, thread))
 	if err != nil {
 		result.Error = fmt.Errorf("スレッドHTMLの取得に失敗しました (thread_id=%s, url=%s): %w", thread.ID, threadURL.String(), err)
 		return result
 	}
+	if notModified {
+		logger.Printf("Skipped: thread %s has not been modified (HTTP 304)", thread.ID)
+		return result // Successはfalseのまま、Errorはnil（スキップは正常）
+	}
 	threadHTML := []byte(threadHTMLString)
+	// メンテナンス中の表示をスレッドとして処理すると、空のアーカイブの作成やレスの削除の誤検知につながる
+	if err := checkMaintenance(siteAdapter, threadURL.String(), threadHTML); err != nil {
+		result.Error = fmt.Errorf("スレッドHTMLを処理しません (thread_id=%s): %w", thread.ID, err)
+		return result
+	}
 
 	htmlContent, err := siteAdapter.ParseThreadHTML(threadHTML)
 	if err != nil {
@@ -53,9 +68,24 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		return result
 	}
 
-	if passes, reason := applyPostContentFilters(htmlContent, task.PostContentFilters); !passes {
+	// 構造化したレスは、二次フィルタと内容のハッシュ（threadContentHash）で使用する
+	posts := parseThreadPosts(siteAdapter, htmlContent, threadURL.String(), logger)
+	if passes, reason := applyPostContentFilters(htmlContent, posts, task.PostContentFilters); !passes {
 		logger.Printf("Skipped by secondary filter: %s. Reason: %s", thread.ID, reason)
-		return result // Successはfalseのまま、Errorはnil（スキップは正常）
+		result.Error = fmt.Errorf("%w: 二次フィルタ (thread_id=%s): %s", errs.ErrFiltered, thread.ID, reason)
+		return result // Successはfalseのまま（フィルタによるスキップは正常）
+	}
+
+	// タイトルが「無題」などの定型文の場合は、OP本文の先頭をタイトルとして使用する
+	if title := fallbackTitle(task, siteAdapter, thread.Title, htmlContent); title != thread.Title {
+		logger.Printf("INFO: スレッド %s のタイトル '%s' は定型文のため、本文の先頭 '%s' を使用します", thread.ID, thread.Title, title)
+		thread.Title = title
+	}
+	result.Title = thread.Title
+
+	// カタログには投稿日時がないため、保存先の {year}/{month}/{day} などにはOPの投稿日時を使用する
+	if date, ok := threadDate(siteAdapter, htmlContent); ok {
+		thread.Date = inTaskTimezone(task, date)
 	}
 
 	mediaFiles, err := siteAdapter.ExtractMediaFiles(htmlContent, threadURL.String())
@@ -64,14 +94,30 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		return result
 	}
 
+	// 以前は解析できていた板で、レスもメディアも抽出できない場合はサイト構造の変化を疑い、空のアーカイブを作らない
+	if err := sharedLayoutMonitor.check(layoutKey(task, "thread"), htmlContent, len(mediaFiles)+len(extractResNumbers(htmlContent))); err != nil {
+		result.Error = fmt.Errorf("スレッドHTMLの解析結果が空です (thread_id=%s): %w", thread.ID, err)
+		return result
+	}
+
+	// 既知の広告・スパム画像はダウンロードせず、再構成したHTMLからも取り除く
+	blocklist, err := taskMediaBlocklist(task)
+	if err != nil {
+		result.Error = fmt.Errorf("blocked_media_patterns の解析に失敗しました (task=%s): %w", task.TaskName, err)
+		return result
+	}
+	mediaFiles, blockedMedia := partitionBlockedMedia(mediaFiles, blocklist, nil)
+
 	// minimum_media_countチェック（ディレクトリ作成前に実行）
-	if len(mediaFiles) < task.MinimumMediaCount {
-		logger.Printf("Skipped: media count %d is less than minimum %d. (thread_id=%s)", len(mediaFiles), task.MinimumMediaCount, thread.ID)
-		return result // Successはfalseのまま、Errorはnil（スキップは正常）
+	// HTMLに埋め込まれたアイコンなどの画像（data URI）は投稿されたメディアではないため数えない
+	if postedMedia := countPostedMedia(mediaFiles); postedMedia < task.MinimumMediaCount {
+		logger.Printf("Skipped: media count %d is less than minimum %d. (thread_id=%s)", postedMedia, task.MinimumMediaCount, thread.ID)
+		result.Error = fmt.Errorf("%w: メディア数 %d が最小値 %d 未満 (thread_id=%s)", errs.ErrFiltered, postedMedia, task.MinimumMediaCount, thread.ID)
+		return result // Successはfalseのまま（フィルタによるスキップは正常）
 	}
 
 	// STEP 2: ディレクトリ構造の準備とスナップショット確認
-	threadSavePath, err := generateDirectoryPath(task.SaveRootDirectory, task.DirectoryFormat, thread)
+	threadSavePath, err := resolveThreadSavePath(task, thread, logger)
 	if err != nil {
 		result.Error = fmt.Errorf("保存パスの生成に失敗しました (thread_id=%s, format=%s): %w", thread.ID, task.DirectoryFormat, err)
 		return result
@@ -83,21 +129,63 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		logger.Printf("WARNING: スナップショットの読み込みに失敗しました: %v", err)
 	}
 
+	// 前回までに内容のハッシュでブロックしたメディアは、再びダウンロードしない
+	if snapshot != nil && len(snapshot.BlockedMedia) > 0 {
+		var knownBlocked []model.MediaInfo
+		mediaFiles, knownBlocked = partitionBlockedMedia(mediaFiles, blocklist, snapshot.BlockedMedia)
+		blockedMedia = append(blockedMedia, knownBlocked...)
+	}
+	if len(blockedMedia) > 0 {
+		logger.Printf("INFO: blocked_media_patterns に一致した %d 件のメディアを除外します (thread_id=%s)", len(blockedMedia), thread.ID)
+	}
+
 	// 更新が必要かチェック
-	if !NeedsUpdate(snapshot, len(mediaFiles)) {
-		logger.Printf("Skipped: thread %s has no updates (media_count=%d)", thread.ID, len(mediaFiles))
+	postCount := threadPostCount(siteAdapter, htmlContent)
+	result.PostCount = postCount
+	// 内容のハッシュは、アダプタが構造化したレスからのみ計算する（HTMLのままでは「そうだね」の数などで毎回変わるため）
+	hashPosts := posts
+	// 一部のレスしか構造化できなかった場合は、残りのレスの編集を判定できないため比較しない
+	if len(hashPosts) < postCount {
+		hashPosts = nil
+	}
+	contentHash := threadContentHash(hashPosts)
+	if !task.ForceFull && !NeedsUpdate(snapshot, len(mediaFiles), postCount, contentHash) {
+		// 更新がなくても、新しい表記のタイトルや検証子を観測した場合はスナップショットに記録する
+		// レス数・内容のハッシュを記録していない以前のスナップショットには、次回以降の比較のために現在の値を記録する
+		if snapshot != nil {
+			titleObserved := snapshot.ObserveTitle(thread.Title, time.Now())
+			postCountRecorded := snapshot.LastPostCount == 0 && postCount > 0
+			if postCountRecorded {
+				snapshot.LastPostCount = postCount
+			}
+			hashRecorded := snapshot.ContentHash == "" && contentHash != ""
+			if hashRecorded {
+				snapshot.ContentHash = contentHash
+			}
+			if validatorsChanged := snapshot.setCacheValidators(validators); titleObserved || validatorsChanged || postCountRecorded || hashRecorded {
+				if err := SaveThreadSnapshot(threadSavePath, snapshot); err != nil {
+					logger.Printf("WARNING: スナップショットの保存に失敗しました: %v", err)
+				} else if err := SaveThreadMetadata(threadSavePath, threadURL.String(), snapshot); err != nil {
+					logger.Printf("WARNING: thread.jsonの保存に失敗しました: %v", err)
+				}
+			}
+		}
+		logger.Printf("Skipped: thread %s has no updates (media_count=%d, post_count=%d)", thread.ID, len(mediaFiles), postCount)
 		return result // Successはfalseのまま、Errorはnil（スキップは正常）
 	}
 
-	logger.Printf("Thread %s needs update (previous_media=%d, current_media=%d)",
-		thread.ID,
-		func() int {
-			if snapshot != nil {
-				return snapshot.LastMediaCount
-			}
-			return 0
-		}(),
-		len(mediaFiles))
+	var previousMedia, previousPosts int
+	if snapshot != nil {
+		previousMedia, previousPosts = snapshot.LastMediaCount, snapshot.LastPostCount
+	}
+	// 初回のアーカイブではすべてのレスを新しいレスとし、レス数を記録していない以前のスナップショットの場合は増分が分からないため数えない
+	if previousPosts > 0 && postCount > previousPosts {
+		result.NewPosts = postCount - previousPosts
+	} else if snapshot == nil {
+		result.NewPosts = postCount
+	}
+	logger.Printf("Thread %s needs update (previous_media=%d, current_media=%d, previous_posts=%d, current_posts=%d)",
+		thread.ID, previousMedia, len(mediaFiles), previousPosts, postCount)
 
 	imgSavePath := filepath.Join(threadSavePath, "img")
 	thumbSavePath := filepath.Join(threadSavePath, "thumb")
@@ -123,24 +211,76 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		logger.Printf("WARNING: futaba.cssのコピーに失敗しました (src=%s, dest=%s): %v", cssSource, cssDest, err)
 	}
 
+	// 解析・再構成の改善を後から再取得なしで適用できるよう、取得したままのHTMLを保存する
+	if task.KeepRawHTML {
+		if err := saveRawHTML(threadSavePath, threadHTML); err != nil {
+			logger.Printf("WARNING: %sの保存に失敗しました: %v", RawHTMLFileName, err)
+		}
+	}
+
+	// booru の投稿のタグなど、アダプタが提供するメタデータをスレッドのディレクトリに保存する
+	if sidecar, ok := siteAdapter.(adapter.MetadataSidecar); ok {
+		if name, data, ok := sidecar.MetadataSidecar(htmlContent); ok {
+			if err := os.WriteFile(filepath.Join(threadSavePath, name), data, 0644); err != nil {
+				logger.Printf("WARNING: %sの保存に失敗しました: %v", name, err)
+			}
+		}
+	}
+
+	// 初回のアーカイブが途中で中断された場合でも、起動時の回復処理（findInterruptedThreads）でスレッドを特定できるよう、
+	// ダウンロードの前に thread.json を保存する（スナップショットは完了時にのみ保存し、中断したスレッドは次回も更新対象とする）
+	if snapshot == nil {
+		provisional := &ThreadSnapshot{ThreadID: thread.ID}
+		provisional.ObserveTitle(thread.Title, time.Now())
+		if date, ok := threadDate(siteAdapter, htmlContent); ok {
+			provisional.CreatedAt = &date
+		}
+		if err := SaveThreadMetadata(threadSavePath, threadURL.String(), provisional); err != nil {
+			logger.Printf("WARNING: thread.jsonの保存に失敗しました: %v", err)
+		}
+	}
+
 	// STEP 3: レジューム処理
-	resumeFilePath := filepath.Join(threadSavePath, ".resume.json")
-	filesToDownload, err := handleResumeLogic(task.EnableResumeSupport, resumeFilePath, mediaFiles, imgSavePath)
+	resumeFilePath := filepath.Join(threadSavePath, resumeFileName)
+	// --force-full 指定時は、破損している可能性のある既存ファイルやレジューム情報を信用せずすべて再取得する
+	resumeEnabled := task.EnableResumeSupport
+	if task.ForceFull {
+		logger.Printf("INFO: 完全再ダウンロードモード: レジューム情報と既存ファイルを無視します (thread_id=%s)", thread.ID)
+		if err := os.Remove(resumeFilePath); err != nil && !os.IsNotExist(err) {
+			logger.Printf("WARNING: レジュームファイルの削除に失敗しました: %v", err)
+		}
+		resumeEnabled = false
+	}
+	// ハッシュを含むファイル名はダウンロード後にしか決まらないため、前回記録したハッシュから保存済みのファイル名を求める
+	if snapshot != nil && usesContentHash(task.FilenameFormat) {
+		restoreMediaHashes(mediaFiles, snapshot.MediaHashes)
+	}
+	filesToDownload, err := handleResumeLogic(resumeEnabled, resumeFilePath, task.FilenameFormat, thread, mediaFiles, imgSavePath)
 	if err != nil {
 		result.Error = fmt.Errorf("レジューム処理に失敗しました (thread_id=%s, resume_file=%s): %w", thread.ID, resumeFilePath, err)
 		return result
 	}
 
 	// STEP 4: メディアファイルのダウンロード
+	var stats downloadStats
 	if len(filesToDownload) > 0 {
 		logger.Printf("Starting media download. Files to download: %d", len(filesToDownload))
-		downloadedFiles, totalBytes, err := downloadMediaFiles(ctx, client, task, thread, filesToDownload, imgSavePath, thumbSavePath, resumeFilePath, logger)
+		stats, err = downloadMediaFiles(ctx, client, task, thread, filesToDownload, imgSavePath, thumbSavePath, resumeFilePath, fallbackMediaSource(siteAdapter), blocklist, logger)
 		if err != nil {
 			result.Error = err
 			return result
 		}
-		result.FilesDownloaded = downloadedFiles
-		result.BytesWritten = totalBytes
+		result.FilesDownloaded = stats.Downloaded
+		result.BytesWritten = stats.Bytes
+	}
+	requeuedFiles := stats.Requeued
+
+	// 成功率が閾値を下回った場合は、アーカイブ済みとせず次回のサイクルで再試行する
+	result.SuccessRatio = stats.successRatio()
+	belowThreshold := task.MinSuccessRatio > 0 && result.SuccessRatio < task.MinSuccessRatio
+	if belowThreshold {
+		logger.Printf("WARNING: スレッド %s のダウンロード成功率 %.1f%% (%d/%d) が閾値 %.1f%% を下回りました。次回のサイクルで再試行します。",
+			thread.ID, result.SuccessRatio*100, stats.Attempted-stats.Failed, stats.Attempted, task.MinSuccessRatio*100)
 	}
 
 	// ---- LocalPath/LocalThumbPath を mediaFiles に同期 ----
@@ -152,77 +292,95 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		if updated, ok := urlToLocal[mediaFiles[i].URL]; ok {
 			mediaFiles[i].LocalPath = updated.LocalPath
 			mediaFiles[i].LocalThumbPath = updated.LocalThumbPath
+			mediaFiles[i].SHA256 = updated.SHA256
+			mediaFiles[i].Blocked = updated.Blocked
 		}
 		if mediaFiles[i].LocalPath == "" {
 			base := filepath.Base(mediaFiles[i].URL)
+			if adapter.IsDataURI(mediaFiles[i].URL) {
+				base = mediaFiles[i].OriginalFilename
+			} else if name, err := generateFileName(task.FilenameFormat, thread, mediaFiles[i]); err == nil && name != "" {
+				// レジューム処理でスキップした保存済みのファイルは、ダウンロード時と同じ名前で保存されている
+				base = name
+			}
 			mediaFiles[i].LocalPath = filepath.Join(imgSavePath, base)
 		}
 		if mediaFiles[i].ThumbnailURL != "" && mediaFiles[i].LocalThumbPath == "" {
 			thumbBase := filepath.Base(mediaFiles[i].ThumbnailURL)
 			mediaFiles[i].LocalThumbPath = filepath.Join(thumbSavePath, thumbBase)
 		}
+		applyMediaSelection(task, &mediaFiles[i])
+		applyAnimatedThumbnail(task, &mediaFiles[i], thumbSavePath, logger)
 	}
 
+	// ダウンロード後に内容のハッシュでブロックしたメディアを除外する
+	mediaFiles, hashBlocked := partitionBlockedMedia(mediaFiles, nil, nil)
+	blockedMedia = append(blockedMedia, hashBlocked...)
+
 	// STEP 5: HTMLの完全な再構成
 	logger.Println("Reconstructing HTML...")
-	reconstructedHTML, err := siteAdapter.ReconstructHTML(htmlContent, thread, mediaFiles)
-	if err != nil {
-		result.Error = fmt.Errorf("HTMLの再構成に失敗しました (thread_id=%s, media_count=%d): %w", thread.ID, len(mediaFiles), err)
-		return result
-	}
-	htmlSavePath := filepath.Join(threadSavePath, "index.htm")
-	archiveFullPath := filepath.Join(threadSavePath, "archive_full.html")
-
-	// 既存のHTMLがある場合は、削除されたレスを検知して完全版に保存
-	var fullArchiveHTML string
-	if snapshot != nil && snapshot.LastMediaCount > 0 {
-		// 既存の完全版HTMLを読み込み
-		if existingFullHTML, err := os.ReadFile(archiveFullPath); err == nil {
-			// 削除されたレスを検知
-			deletedPosts := detectAndExtractDeletedContent(string(existingFullHTML), htmlContent, thread.ID, logger)
-
-			// 完全版HTMLを更新（削除されたレスをマージ）
-			fullArchiveHTML, err = mergeDeletedPostsIntoHTML(reconstructedHTML, deletedPosts)
-			if err != nil {
-				logger.Printf("WARNING: 完全版HTMLのマージに失敗しました: %v", err)
-				fullArchiveHTML = reconstructedHTML // フォールバック
-			}
-		} else {
-			// 初回または完全版が存在しない場合
-			fullArchiveHTML = reconstructedHTML
-		}
-	} else {
-		// 初回アーカイブ
-		fullArchiveHTML = reconstructedHTML
-	}
-
-	// 最新版HTMLを保存（削除されたレスは含まない）
-	if err := os.WriteFile(htmlSavePath, []byte(reconstructedHTML), 0644); err != nil {
-		result.Error = fmt.Errorf("index.htmの保存に失敗しました (path=%s, size=%d bytes): %w", htmlSavePath, len(reconstructedHTML), err)
+	// レス番号のないアダプタでは削除されたレスを判別できず、すべてのレスを削除済みとして誤検知するため比較しない
+	detectDeleted := snapshot != nil && snapshot.LastMediaCount > 0 && siteAdapter.Capabilities().ResNumbers
+	if err := saveThreadHTML(task, siteAdapter, thread, htmlContent, append(mediaFiles[:len(mediaFiles):len(mediaFiles)], blockedMedia...), threadSavePath, detectDeleted, logger); err != nil {
+		result.Error = err
 		return result
 	}
 
-	// 完全版HTMLを保存（削除されたレスも含む）
-	if err := os.WriteFile(archiveFullPath, []byte(fullArchiveHTML), 0644); err != nil {
-		logger.Printf("WARNING: archive_full.htmlの保存に失敗しました: %v", err)
-	} else {
-		logger.Printf("INFO: 完全版アーカイブを archive_full.html に保存しました")
-	}
-
 	// STEP 6: スナップショットの更新
+	// 再取得待ちのファイルがある場合は、その分だけメディア数を少なく記録し、次回のサイクルで更新対象にする
 	newSnapshot := &ThreadSnapshot{
 		ThreadID:       thread.ID,
 		LastChecked:    time.Now(),
-		LastPostCount:  0, // TODO: 実際のレス数を取得
-		LastMediaCount: len(mediaFiles),
+		LastPostCount:  postCount,
+		LastMediaCount: len(mediaFiles) - requeuedFiles,
 		LastModified:   time.Now(),
 		IsComplete:     false,
+		ContentHash:    contentHash,
+	}
+	if date, ok := threadDate(siteAdapter, htmlContent); ok {
+		newSnapshot.CreatedAt = &date
+	}
+	if belowThreshold {
+		// 前回の記録を維持し、次回の NeedsUpdate で再度更新対象になるようにする
+		newSnapshot.LastMediaCount = 0
+		if snapshot != nil {
+			newSnapshot.LastMediaCount = snapshot.LastMediaCount
+		}
 	}
+	var previousFeatures []model.PostFeatures
+	if snapshot != nil {
+		newSnapshot.TitleHistory = snapshot.TitleHistory
+		previousFeatures = snapshot.PostFeatures
+	}
+	newSnapshot.PostFeatures = threadPostFeatures(siteAdapter, htmlContent, previousFeatures)
+	newSnapshot.BlockedMedia = hashBlockedMedia(blocklist, blockedMedia)
+	if usesContentHash(task.FilenameFormat) {
+		newSnapshot.MediaHashes = mediaHashes(mediaFiles)
+	}
+	// 再取得が必要なファイルが残っている場合は、次回のサイクルで 304 により取得が省かれないよう検証子を記録しない
+	if !belowThreshold && requeuedFiles == 0 {
+		newSnapshot.setCacheValidators(validators)
+	}
+	newSnapshot.ObserveTitle(thread.Title, newSnapshot.LastChecked)
 	if err := SaveThreadSnapshot(threadSavePath, newSnapshot); err != nil {
 		logger.Printf("WARNING: スナップショットの保存に失敗しました: %v", err)
 	}
+	if err := SaveThreadMetadata(threadSavePath, threadURL.String(), newSnapshot); err != nil {
+		logger.Printf("WARNING: thread.jsonの保存に失敗しました: %v", err)
+	}
+	if title := newSnapshot.DisplayTitle(); title != "" {
+		result.Title = title
+	}
 
 	// STEP 7: 完了処理
+	if belowThreshold {
+		// 履歴には追記せず、レジュームファイルも残して次回に再試行する
+		result.SavePath = threadSavePath
+		result.Error = fmt.Errorf("ダウンロード成功率 %.1f%% が閾値 %.1f%% を下回りました (thread_id=%s, failed=%d/%d)",
+			result.SuccessRatio*100, task.MinSuccessRatio*100, thread.ID, stats.Failed, stats.Attempted)
+		return result
+	}
+
 	historyPath := filepath.Join(threadSavePath, ".giba", "history.log")
 	if err := appendToHistory(historyPath, thread.ID); err != nil {
 		result.Error = fmt.Errorf("履歴への追記に失敗しました (history_file=%s, thread_id=%s): %w", historyPath, thread.ID, err)
@@ -236,27 +394,147 @@ func ArchiveSingleThread(ctx context.Context, client *network.Client, siteAdapte
 		}
 	}
 
-	if task.EnableResumeSupport {
+	// 再取得待ちのファイルがある場合は、次回サイクルのためにレジュームファイルを残す
+	if task.EnableResumeSupport && requeuedFiles == 0 {
 		os.Remove(resumeFilePath)
 	}
 
+	// 期間ごとの一覧ページに追加し、Web UIなしでもアーカイブを辿れるようにする
+	if err := updateRollupPages(task, thread, result.Title, threadSavePath, inTaskTimezone(task, time.Now())); err != nil {
+		logger.Printf("WARNING: 一覧ページの更新に失敗しました: %v", err)
+	}
+
 	if task.NotifyOnComplete {
 		logger.Println("Notification: Archive complete:", thread.Title)
 	}
 
-	logger.Printf("Successfully archived thread %s (media_count=%d, files_downloaded=%d, bytes_written=%d)", thread.ID, len(mediaFiles), result.FilesDownloaded, result.BytesWritten)
+	result.SavePath = threadSavePath
+	logger.Printf("Successfully archived thread %s (media_count=%d, post_count=%d, new_posts=%d, files_downloaded=%d, bytes_written=%d)",
+		thread.ID, len(mediaFiles), postCount, result.NewPosts, result.FilesDownloaded, result.BytesWritten)
 	result.Success = true
 	return result
 }
 
 // --- ヘルパー関数群 ---
 
+// saveThreadHTML は、スレッドHTMLをローカルのメディアへのリンクで再構成し、タスクの render_strategies の形式で保存します。
+// 未設定の場合は index.htm・分割ページ・archive_full.html を保存します。
+// mediaFiles にはブロック対象（Blocked）のメディアも含め、HTMLから取り除かせます。
+// detectDeleted が true の場合は、既存の archive_full.html と比較して削除されたレスを完全版に残します。
+func saveThreadHTML(task config.Task, siteAdapter adapter.SiteAdapter, thread model.ThreadInfo, htmlContent string,
+	mediaFiles []model.MediaInfo, threadSavePath string, detectDeleted bool, logger *log.Logger) error {
+	reconstructedHTML, err := siteAdapter.ReconstructHTML(htmlContent, thread, mediaFiles)
+	if err != nil {
+		return fmt.Errorf("HTMLの再構成に失敗しました (thread_id=%s, media_count=%d): %w", thread.ID, len(mediaFiles), err)
+	}
+	if task.PaginationMode == PaginationVirtual {
+		reconstructedHTML = applyVirtualScroll(reconstructedHTML)
+	}
+	// 数年後にも出所が分かるよう、設定に応じて元のURLやアーカイブ日時をHTMLと README.txt に記載する
+	if task.ArchiveHeader {
+		info := newArchiveInfo(task, thread, mediaFiles, inTaskTimezone(task, time.Now()))
+		reconstructedHTML = injectArchiveHeader(reconstructedHTML, info)
+		if err := saveArchiveReadme(threadSavePath, task, info); err != nil {
+			logger.Printf("WARNING: %v", err)
+		}
+	}
+	// 既存のHTMLがある場合は、削除されたレスを検知して完全版に保存
+	// 旧完全版HTMLは削除レスの抽出後すぐに不要になるため、このブロック内に閉じ込めて早期に解放する
+	// レスが残っていても画像だけが削除された場合は、保存済みのファイルを削除せず、完全版から参照し続ける
+	var deletedPosts, removedMedia string
+	if detectDeleted {
+		if existingFullHTML, err := archivecrypt.ReadFile(filepath.Join(threadSavePath, fullHTMLFileName)); err == nil {
+			// 削除されたレスを検知
+			deletedPosts = detectAndExtractDeletedContent(string(existingFullHTML), htmlContent, thread.ID, logger)
+			removedMedia = detectRemovedMedia(string(existingFullHTML), reconstructedHTML, deletedPosts, threadSavePath, thread.ID, logger)
+		}
+	}
+
+	// タスクで選択した形式ごとに保存する
+	in := &renderInput{
+		task:           task,
+		thread:         thread,
+		htmlContent:    htmlContent,
+		mediaFiles:     mediaFiles,
+		reconstructed:  reconstructedHTML,
+		deletedPosts:   deletedPosts,
+		removedMedia:   removedMedia,
+		threadSavePath: threadSavePath,
+		logger:         logger,
+	}
+	for _, name := range taskRenderStrategies(task) {
+		strategy, ok := renderStrategies[name]
+		if !ok {
+			return fmt.Errorf("不明なレンダリング戦略です: %q", name)
+		}
+		if err := strategy.render(in); err != nil {
+			return err
+		}
+	}
+	// 暗号化はすべての形式の保存後に行い、暗号化していないHTMLやメディアを残さない
+	if err := encryptThreadFiles(task, threadSavePath); err != nil {
+		return fmt.Errorf("スレッドのファイルの暗号化に失敗しました (path=%s): %w", threadSavePath, err)
+	}
+	return nil
+}
+
+// downloadStats は、スレッド1件分のメディアダウンロードの集計です。
+type downloadStats struct {
+	Downloaded int   // ダウンロードに成功したファイル数（サムネイルを含む）
+	Bytes      int64 // 書き込んだバイト数
+	Attempted  int   // ダウンロードを試みたフルサイズメディアの数
+	Failed     int   // ダウンロードに失敗したフルサイズメディアの数
+	Requeued   int   // 次回のサイクルで再取得するフルサイズメディアの数
+}
+
+// successRatio は、フルサイズメディアのダウンロード成功率を返します。試行がない場合は1です。
+func (s downloadStats) successRatio() float64 {
+	if s.Attempted == 0 {
+		return 1
+	}
+	return float64(s.Attempted-s.Failed) / float64(s.Attempted)
+}
+
+// add は、other の集計を s に加算します。
+func (s *downloadStats) add(other downloadStats) {
+	s.Downloaded += other.Downloaded
+	s.Bytes += other.Bytes
+	s.Attempted += other.Attempted
+	s.Failed += other.Failed
+	s.Requeued += other.Requeued
+}
+
+// threadConcurrency は、タスク内で同時に処理するスレッド数を返します。
+func threadConcurrency(task config.Task) int {
+	switch {
+	case task.MaxConcurrentThreads > 0:
+		return task.MaxConcurrentThreads
+	case task.MaxConcurrentDownloads > 0:
+		return task.MaxConcurrentDownloads
+	}
+	return 4
+}
+
+// fileConcurrency は、1スレッド内で同時にダウンロードするファイル数を返します。
+func fileConcurrency(task config.Task) int {
+	if task.MaxConcurrentFilesPerThread > 0 {
+		return task.MaxConcurrentFilesPerThread
+	}
+	return 1
+}
+
+// downloadMediaFiles は、メディアファイルとサムネイルをダウンロードし、その集計を返します。
+// 同時に max_concurrent_files_per_thread 件までのファイルを並行してダウンロードし、
+// 各ワーカーはファイルごとに request_interval_ms だけ待機します。
+// fallback が nil でない場合、掲示板から削除された（404 の）メディアを保管サイトから取得します。
+// blocklist は ArchiveSingleThread で検証・コンパイル済みのブロックリストで、ダウンロード後のハッシュの照合に使用します（nil の場合は照合しません）。
 func downloadMediaFiles(ctx context.Context, client *network.Client, task config.Task, thread model.ThreadInfo,
-	filesToDownload []model.MediaInfo, imgSavePath string, thumbSavePath string, resumeFilePath string, logger *log.Logger) (int, int64, error) {
+	filesToDownload []model.MediaInfo, imgSavePath string, thumbSavePath string, resumeFilePath string, fallback adapter.FallbackMediaSource,
+	blocklist *mediaBlocklist, logger *log.Logger) (downloadStats, error) {
 	// ベースURLを一度パースしておく
 	baseURL, err := url.Parse(task.TargetBoardURL)
 	if err != nil {
-		return 0, 0, fmt.Errorf("ベースURLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
+		return downloadStats{}, fmt.Errorf("ベースURLの解析に失敗しました (url=%s): %w", task.TargetBoardURL, err)
 	}
 
 	// レジューム処理の開始ログは一度だけ出力
@@ -266,168 +544,363 @@ func downloadMediaFiles(ctx context.Context, client *network.Client, task config
 		}
 	}
 
-	// 統計情報の初期化
-	downloadedFiles := 0
-	totalBytes := int64(0)
+	var (
+		stats downloadStats
+		mu    sync.Mutex // stats とレジュームファイルを保護する
+		wg    sync.WaitGroup
+	)
+	resume := newResumeTracker(task, resumeFilePath)
+	semaphore := make(chan struct{}, fileConcurrency(task))
 
 	for i := range filesToDownload {
-		media := &filesToDownload[i]
-
-		// フルサイズ画像は img/ に保存
-		saveFileName, err := generateFileName(task.FilenameFormat, thread, *media)
-		if err != nil || saveFileName == "" {
-			// fallback: 元のファイル名を使用
-			saveFileName = media.OriginalFilename
-			if saveFileName == "" {
-				// さらにfallback: URLからファイル名を抽出
-				saveFileName = filepath.Base(media.URL)
-				logger.Printf("WARNING: ファイル名の生成に失敗したため、URLから抽出したファイル名を使用します: %s", saveFileName)
-			}
+		// 空きを待つ間もシャットダウンに応じられるよう、ctx と同時に待機する
+		select {
+		case <-ctx.Done():
+		case semaphore <- struct{}{}:
 		}
-		saveFilePath := filepath.Join(imgSavePath, saveFileName)
-		media.LocalPath = saveFilePath
-
-		// サムネイルは thumb/ に保存
-		if media.ThumbnailURL != "" {
-			thumbName := filepath.Base(media.ThumbnailURL)
-			if thumbName == "" || thumbName == "." {
-				// fallback: 元のファイル名から推測
-				// ふたばのサムネイルは常にjpgなので拡張子を.jpgに固定
-				ext := filepath.Ext(saveFileName)
-				nameWithoutExt := strings.TrimSuffix(saveFileName, ext)
-				thumbName = nameWithoutExt + "s.jpg"
-				logger.Printf("WARNING: サムネイルファイル名の抽出に失敗したため、推測値を使用します: %s", thumbName)
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			media := &filesToDownload[i]
+			mediaStats, completed := downloadMediaEntry(ctx, client, task, thread, baseURL, media, i, len(filesToDownload), imgSavePath, thumbSavePath, fallback, blocklist, logger)
+
+			mu.Lock()
+			stats.add(mediaStats)
+			if completed && task.EnableResumeSupport {
+				if err := resume.complete(media.URL); err != nil {
+					logger.Printf("WARNING: レジュームファイルの更新に失敗しました: %v", err)
+				}
 			}
-			thumbSavePath := filepath.Join(thumbSavePath, thumbName)
-			media.LocalThumbPath = thumbSavePath
+			mu.Unlock()
+
+			// キャンセルされた場合は待機を打ち切って終了する（次のファイルはループ側で中止される）
+			if err := sleepContext(ctx, time.Duration(task.RequestIntervalMillis)*time.Millisecond); err != nil {
+				return
+			}
+		}(i)
+	}
+	wg.Wait()
+	if task.EnableResumeSupport {
+		if err := resume.flush(); err != nil {
+			logger.Printf("WARNING: レジュームファイルの更新に失敗しました: %v", err)
 		}
-		// 相対URLを絶対に
-		fullMediaURL := media.URL
-		if !strings.HasPrefix(fullMediaURL, "http://") && !strings.HasPrefix(fullMediaURL, "https://") {
-			resolvedURL := baseURL.ResolveReference(&url.URL{Path: fullMediaURL})
-			fullMediaURL = resolvedURL.String()
+	}
+	// 中断したダウンロードをスナップショットに完了として記録しないよう、キャンセルはエラーとして返す
+	if err := ctx.Err(); err != nil {
+		return stats, fmt.Errorf("メディアのダウンロードを中断しました (thread_id=%s): %w", thread.ID, err)
+	}
+	return stats, nil
+}
+
+// downloadMediaEntry は、メディア1件のフルサイズファイルとサムネイルをダウンロードします。
+// media の保存先パスを設定し、このメディア分の集計と、フルサイズの取得に成功したかを返します。
+func downloadMediaEntry(ctx context.Context, client *network.Client, task config.Task, thread model.ThreadInfo, baseURL *url.URL,
+	media *model.MediaInfo, index, total int, imgSavePath, thumbSavePath string, fallback adapter.FallbackMediaSource, blocklist *mediaBlocklist,
+	logger *log.Logger) (stats downloadStats, completed bool) {
+	if adapter.IsDataURI(media.URL) {
+		return saveInlineMedia(media, imgSavePath, logger)
+	}
+
+	// フルサイズ画像は img/ に保存
+	saveFileName, err := generateFileName(task.FilenameFormat, thread, *media)
+	if err != nil || saveFileName == "" {
+		// fallback: 元のファイル名を使用
+		saveFileName = media.OriginalFilename
+		if saveFileName == "" {
+			// さらにfallback: URLからファイル名を抽出
+			saveFileName = filepath.Base(media.URL)
+			logger.Printf("WARNING: ファイル名の生成に失敗したため、URLから抽出したファイル名を使用します: %s", saveFileName)
+		}
+	}
+	saveFilePath := filepath.Join(imgSavePath, saveFileName)
+	media.LocalPath = saveFilePath
+
+	// サムネイルは thumb/ に保存
+	if media.ThumbnailURL != "" {
+		thumbName := filepath.Base(media.ThumbnailURL)
+		if thumbName == "" || thumbName == "." {
+			// fallback: 元のファイル名から推測
+			// ふたばのサムネイルは常にjpgなので拡張子を.jpgに固定
+			ext := filepath.Ext(saveFileName)
+			nameWithoutExt := strings.TrimSuffix(saveFileName, ext)
+			thumbName = nameWithoutExt + "s.jpg"
+			logger.Printf("WARNING: サムネイルファイル名の抽出に失敗したため、推測値を使用します: %s", thumbName)
 		}
+		thumbPath := filepath.Join(thumbSavePath, thumbName)
+		media.LocalThumbPath = thumbPath
+	}
+	// 相対URLを絶対に
+	fullMediaURL := media.URL
+	if !strings.HasPrefix(fullMediaURL, "http://") && !strings.HasPrefix(fullMediaURL, "https://") {
+		resolvedURL := baseURL.ResolveReference(&url.URL{Path: fullMediaURL})
+		fullMediaURL = resolvedURL.String()
+	}
 
-		logger.Printf("Downloading (%d/%d): %s -> %s", i+1, len(filesToDownload), fullMediaURL, saveFileName)
-		err = downloadFile(ctx, client, fullMediaURL, saveFilePath, task.RetryCount, task.RetryWaitMillis)
+	if task.ThumbnailsOnly {
+		// サムネイルのみモードではフルサイズの取得を行わない
+		logger.Printf("Skipping full-size media (thumbnails_only): %s", fullMediaURL)
+	} else {
+		logger.Printf("Downloading (%d/%d): %s -> %s", index+1, total, fullMediaURL, saveFileName)
+		stats.Attempted++
+		err = downloadFile(ctx, client, fullMediaURL, saveFilePath, task, logger)
+		if err != nil && errors.Is(err, errs.ErrThreadGone) {
+			err = downloadFromFallback(ctx, client, fallback, thread, fullMediaURL, saveFilePath, task, err, logger)
+		}
 		if err != nil {
 			logger.Printf("WARNING: ファイルのダウンロードに失敗しました: %s - %v. スキップします。", fullMediaURL, err)
+			stats.Failed++
+			var exhausted *RetryExhaustedError
+			if errors.As(err, &exhausted) && exhausted.Requeue {
+				logger.Printf("INFO: 次回のサイクルで再取得します: %s", fullMediaURL)
+				stats.Requeued++
+			}
 			// 失敗してもサムネイルは試みる（フルサイズ欠落でも HTML は表示可能）
 		} else {
+			if blocked, err := blockByContentHash(blocklist, media, saveFilePath); err != nil {
+				logger.Printf("WARNING: ブロック対象かどうかの確認に失敗しました: %s - %v", fullMediaURL, err)
+			} else if blocked {
+				// ブロックしたメディアのサムネイルは取得しない
+				logger.Printf("INFO: blocked_media_patterns のハッシュに一致したため削除しました: %s", fullMediaURL)
+				return stats, true
+			}
+			if usesContentHash(task.FilenameFormat) {
+				// ハッシュを含むファイル名はダウンロード後に決まるため、ここでリネームする
+				if saveFilePath, err = renameByContentHash(task.FilenameFormat, thread, media, saveFilePath); err != nil {
+					logger.Printf("WARNING: ハッシュによる命名に失敗したため、元のファイル名のまま保存します: %v", err)
+				}
+				saveFileName = filepath.Base(saveFilePath)
+				media.LocalPath = saveFilePath
+			}
 			logger.Printf("SUCCESS: ダウンロード完了: %s", saveFileName)
 			// ダウンロード成功時に統計を更新
-			downloadedFiles++
+			stats.Downloaded++
 			if fileInfo, err := os.Stat(saveFilePath); err == nil {
-				totalBytes += fileInfo.Size()
-			}
-
-			if task.EnableResumeSupport {
-				if err := updateResumeFile(resumeFilePath, media.URL); err != nil {
-					logger.Printf("WARNING: レジュームファイルの更新に失敗しました: %v", err)
-				}
+				stats.Bytes += fileInfo.Size()
 			}
+			completed = true
 		}
+	}
 
-		// ---- サムネイルのダウンロード（存在する場合）----
-		if thumbURL := strings.TrimSpace(media.ThumbnailURL); thumbURL != "" {
-			thumbName := filepath.Base(thumbURL) // 例: 1763426018532s.jpg
-			thumbSaveName := thumbName
+	// シャットダウン中はサムネイルの取得を試みない
+	if ctx.Err() != nil {
+		return stats, completed
+	}
 
-			// フォーマットがある場合でも、サムネイルは元の s 付きファイル名で保存する方が整合的
-			thumbSavePath := filepath.Join(thumbSavePath, thumbSaveName)
-			media.LocalThumbPath = thumbSavePath
+	// ---- サムネイルのダウンロード（存在する場合）----
+	if thumbURL := strings.TrimSpace(media.ThumbnailURL); thumbURL != "" && shouldDownloadThumbnails(task) {
+		thumbName := filepath.Base(thumbURL) // 例: 1763426018532s.jpg
+		thumbSaveName := thumbName
 
-			fullThumbURL := thumbURL
-			if !strings.HasPrefix(fullThumbURL, "http://") && !strings.HasPrefix(fullThumbURL, "https://") {
-				resolvedURL := baseURL.ResolveReference(&url.URL{Path: fullThumbURL})
-				fullThumbURL = resolvedURL.String()
-			}
+		// フォーマットがある場合でも、サムネイルは元の s 付きファイル名で保存する方が整合的
+		thumbPath := filepath.Join(thumbSavePath, thumbSaveName)
+		media.LocalThumbPath = thumbPath
 
-			logger.Printf("Downloading thumb: %s -> %s", fullThumbURL, thumbSaveName)
-			if err := downloadFile(ctx, client, fullThumbURL, thumbSavePath, task.RetryCount, task.RetryWaitMillis); err != nil {
-				logger.Printf("WARNING: サムネイルのダウンロードに失敗しました: %s - %v", fullThumbURL, err)
-			} else {
-				logger.Printf("SUCCESS: サムネイルダウンロード完了: %s", thumbSaveName)
-				// サムネイルもカウント
-				downloadedFiles++
-				if fileInfo, err := os.Stat(thumbSavePath); err == nil {
-					totalBytes += fileInfo.Size()
-				}
+		fullThumbURL := thumbURL
+		if !strings.HasPrefix(fullThumbURL, "http://") && !strings.HasPrefix(fullThumbURL, "https://") {
+			resolvedURL := baseURL.ResolveReference(&url.URL{Path: fullThumbURL})
+			fullThumbURL = resolvedURL.String()
+		}
+
+		logger.Printf("Downloading thumb: %s -> %s", fullThumbURL, thumbSaveName)
+		err := downloadFile(ctx, client, fullThumbURL, thumbPath, task, logger)
+		if err != nil && errors.Is(err, errs.ErrThreadGone) {
+			err = downloadFromFallback(ctx, client, fallback, thread, fullThumbURL, thumbPath, task, err, logger)
+		}
+		if err != nil {
+			logger.Printf("WARNING: サムネイルのダウンロードに失敗しました: %s - %v", fullThumbURL, err)
+		} else {
+			logger.Printf("SUCCESS: サムネイルダウンロード完了: %s", thumbSaveName)
+			// サムネイルもカウント
+			stats.Downloaded++
+			if fileInfo, err := os.Stat(thumbPath); err == nil {
+				stats.Bytes += fileInfo.Size()
 			}
 		}
+	}
+	return stats, completed
+}
 
-		time.Sleep(time.Duration(task.RequestIntervalMillis) * time.Millisecond)
+// fallbackMediaSource は、アダプタが保管サイトからの取得に対応していればそれを返し、対応していなければ nil を返します。
+func fallbackMediaSource(siteAdapter adapter.SiteAdapter) adapter.FallbackMediaSource {
+	if source, ok := siteAdapter.(adapter.FallbackMediaSource); ok {
+		return source
 	}
-	return downloadedFiles, totalBytes, nil
+	return nil
+}
+
+// downloadFromFallback は、掲示板から取得できなかった（404 の）mediaURL を、アダプタが返す保管サイトのURLから順に取得します。
+// いずれかから取得できた場合は nil を、すべて失敗した場合（保管サイトがない場合を含む）は元のエラー originalErr を返します。
+func downloadFromFallback(ctx context.Context, client *network.Client, fallback adapter.FallbackMediaSource, thread model.ThreadInfo,
+	mediaURL, destPath string, task config.Task, originalErr error, logger *log.Logger) error {
+	if fallback == nil {
+		return originalErr
+	}
+	for _, fallbackURL := range fallback.FallbackMediaURLs(thread, mediaURL) {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := downloadFile(ctx, client, fallbackURL, destPath, task, logger); err != nil {
+			logger.Printf("INFO: 保管サイトからの取得に失敗しました: %s - %v", fallbackURL, err)
+			continue
+		}
+		logger.Printf("SUCCESS: 掲示板から削除されたファイルを保管サイトから取得しました: %s -> %s", fallbackURL, filepath.Base(destPath))
+		return nil
+	}
+	return originalErr
+}
+
+// saveInlineMedia は、HTMLに埋め込まれた画像（data URI）をデコードして img/ に保存します。
+// ネットワークへのアクセスは発生しないため、サムネイルのみモードでも保存します。
+func saveInlineMedia(media *model.MediaInfo, imgSavePath string, logger *log.Logger) (stats downloadStats, completed bool) {
+	savePath := filepath.Join(imgSavePath, SanitizeFilename(media.OriginalFilename))
+	media.LocalPath = savePath
+	stats.Attempted++
+
+	data, err := adapter.DecodeDataURI(media.URL)
+	if err == nil {
+		err = os.WriteFile(savePath, data, 0644)
+	}
+	if err != nil {
+		logger.Printf("WARNING: 埋め込み画像の保存に失敗しました (path=%s): %v", savePath, err)
+		stats.Failed++
+		return stats, false
+	}
+
+	logger.Printf("SUCCESS: 埋め込み画像を保存しました: %s (%d bytes)", filepath.Base(savePath), len(data))
+	stats.Downloaded++
+	stats.Bytes += int64(len(data))
+	return stats, true
+}
+
+// countPostedMedia は、HTMLに埋め込まれた画像を除いたメディア数を返します。
+func countPostedMedia(mediaFiles []model.MediaInfo) int {
+	n := 0
+	for _, m := range mediaFiles {
+		if !adapter.IsDataURI(m.URL) {
+			n++
+		}
+	}
+	return n
 }
 
 // downloadFile は、単一のファイルをダウンロードし、指定されたパスに保存します。
-// リトライロジックを含みます。
+// 他のスレッドで同じURLを取得済み（または取得中）の場合は、そのファイルを共有してリクエストを省略します。
+// 壊れたファイルを取り直す完全再ダウンロード（force_full）では共有せず、必ず取得します。
+func downloadFile(ctx context.Context, client *network.Client, url string, destPath string, task config.Task, logger *log.Logger) error {
+	if task.ForceFull {
+		return downloadFileWithRetry(ctx, client, url, destPath, task, logger)
+	}
+	shared, err := sharedDownloadCache.fetch(ctx, downloadCacheKey(task, url), destPath, func() error {
+		return downloadFileWithRetry(ctx, client, url, destPath, task, logger)
+	})
+	if shared {
+		logger.Printf("INFO: 取得済みのファイルを共有しました (url=%s, path=%s)", url, destPath)
+	}
+	return err
+}
+
+// downloadFileWithRetry は、単一のファイルをダウンロードし、指定されたパスに保存します。
+// リトライはエラー種別（タイムアウト、5xx、書き込み失敗など）ごとのポリシーに従います。
 // 404などの恒久的なエラーの場合はリトライせず即座に失敗します。
-func downloadFile(ctx context.Context, client *network.Client, url string, destPath string, retryCount int, retryWaitMillis int) error {
-	for i := 0; i <= retryCount; i++ {
+// 種別ごとのリトライ上限に達した場合は *RetryExhaustedError を返します。
+func downloadFileWithRetry(ctx context.Context, client *network.Client, url string, destPath string, task config.Task, logger *log.Logger) error {
+	attempts := make(map[string]int)
+	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err() // コンテキストがキャンセルされたら即座に終了
 		default:
 		}
 
-		fileContent, err := client.Get(ctx, url)
-		if err != nil {
-			// HTTPErrorかどうかをチェック
-			if httpErr, ok := err.(*network.HTTPError); ok {
-				// リトライ不可能なエラー（404など）の場合は即座に失敗
-				if !httpErr.IsRetryable() {
-					log.Printf("ダウンロード失敗（リトライ不可、HTTP %d）: url=%s, error=%v", httpErr.StatusCode, url, err)
-					return fmt.Errorf("リトライ不可能なHTTPエラー (status=%d, url=%s): %w", httpErr.StatusCode, url, err)
-				}
-				// リトライ可能なエラー（5xxなど）の場合
-				log.Printf("ダウンロード失敗（リトライ可能、HTTP %d、試行 %d/%d）: url=%s, error=%v", httpErr.StatusCode, i+1, retryCount+1, url, err)
-			} else {
-				// ネットワークエラーなど、HTTPError以外のエラー
-				log.Printf("ダウンロード失敗（ネットワークエラー、試行 %d/%d）: url=%s, error=%v", i+1, retryCount+1, url, err)
-			}
-
-			// 最後のリトライでなければ待機
-			if i < retryCount {
-				time.Sleep(time.Duration(retryWaitMillis) * time.Millisecond)
-			}
-			continue
+		err := fetchToFile(ctx, client, url, destPath, task.FsyncPolicy == config.FsyncAll, logger)
+		if err == nil {
+			return nil // ダウンロード成功
 		}
 
-		// ファイル書き込み前に、既存の不完全なファイルを削除
-		if _, err := os.Stat(destPath); err == nil {
-			log.Printf("INFO: 既存ファイルを削除してリトライします: %s", destPath)
-			os.Remove(destPath)
+		// リトライ不可能なエラー（404など）の場合は即座に失敗
+		var httpErr *network.HTTPError
+		if errors.As(err, &httpErr) && !httpErr.IsRetryable() && !errors.Is(err, errs.ErrRateLimited) {
+			logger.Printf("ダウンロード失敗（リトライ不可、HTTP %d）: url=%s, error=%v", httpErr.StatusCode, url, err)
+			return fmt.Errorf("リトライ不可能なHTTPエラー (status=%d, url=%s): %w", httpErr.StatusCode, url, err)
 		}
 
-		if err := os.WriteFile(destPath, []byte(fileContent), 0644); err != nil {
-			log.Printf("ファイル書き込み失敗（試行 %d/%d）: path=%s, size=%d bytes, error=%v", i+1, retryCount+1, destPath, len(fileContent), err)
-			// 書き込み失敗時は不完全なファイルを削除
-			os.Remove(destPath)
-			// 最後のリトライでなければ待機
-			if i < retryCount {
-				time.Sleep(time.Duration(retryWaitMillis) * time.Millisecond)
+		class := classifyRetryError(err)
+		policy := resolveRetryPolicy(task, class)
+		attempts[class]++
+		logger.Printf("ダウンロード失敗（%s、試行 %d/%d）: url=%s, error=%v", class, attempts[class], policy.RetryCount+1, url, err)
+
+		if attempts[class] > policy.RetryCount {
+			// リトライ上限に達した場合、不完全なファイルが残っていれば削除
+			if _, statErr := os.Stat(destPath); statErr == nil {
+				logger.Printf("WARNING: リトライ上限に達したため、不完全なファイルを削除します: %s", destPath)
+				os.Remove(destPath)
 			}
-			continue
+			return &RetryExhaustedError{Class: class, Attempts: attempts[class], Requeue: policy.RequeueNextCycle, Err: err}
 		}
 
-		// ダウンロード成功 - ファイルサイズを確認
-		if fileInfo, err := os.Stat(destPath); err == nil {
-			log.Printf("INFO: ファイル保存成功 (path=%s, size=%d bytes)", destPath, fileInfo.Size())
+		if err := sleepContext(ctx, retryWait(policy, attempts[class])); err != nil {
+			return err
 		}
-		return nil // ダウンロード成功
 	}
+}
 
-	// リトライ上限に達した場合、不完全なファイルが残っていれば削除
+// fetchToFile は、URLの内容を1回だけ取得して destPath に書き込みます。sync が true の場合はディスクへの同期を待ちます。
+// 書き込みに失敗した場合は errs.ErrWriteFailed をラップしたエラーを返します。
+func fetchToFile(ctx context.Context, client *network.Client, url string, destPath string, sync bool, logger *log.Logger) error {
+	fileContent, err := client.Get(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	// ファイル書き込み前に、既存の不完全なファイルを削除
 	if _, err := os.Stat(destPath); err == nil {
-		log.Printf("WARNING: リトライ上限に達したため、不完全なファイルを削除します: %s", destPath)
+		logger.Printf("INFO: 既存ファイルを削除してリトライします: %s", destPath)
 		os.Remove(destPath)
 	}
-	return fmt.Errorf("ダウンロードがリトライ上限に達しました (url=%s, retry_count=%d): 最後のエラーを確認してください", url, retryCount)
+
+	if err := writeFileSync(destPath, []byte(fileContent), 0644, sync); err != nil {
+		// 書き込み失敗時は不完全なファイルを削除
+		os.Remove(destPath)
+		return fmt.Errorf("%w (path=%s, size=%d bytes): %w", errs.ErrWriteFailed, destPath, len(fileContent), err)
+	}
+
+	// ダウンロード成功 - ファイルサイズを確認
+	if fileInfo, err := os.Stat(destPath); err == nil {
+		logger.Printf("INFO: ファイル保存成功 (path=%s, size=%d bytes)", destPath, fileInfo.Size())
+	}
+	return nil
+}
+
+// shouldDownloadThumbnails は、タスク設定に基づいてサムネイルを取得するかどうかを判定します。
+// thumbnails_only が有効な場合は download_thumbnails の値に関わらずサムネイルを取得します。
+func shouldDownloadThumbnails(task config.Task) bool {
+	if task.ThumbnailsOnly {
+		return true
+	}
+	return task.DownloadThumbnails == nil || *task.DownloadThumbnails
 }
 
-func generateDirectoryPath(rootDir, format string, thread model.ThreadInfo) (string, error) {
+// applyMediaSelection は、取得しなかったメディアへのリンクが実在するローカルファイルを指すように
+// LocalPath/LocalThumbPath を付け替えます。
+// サムネイルのみの場合はフルサイズへのリンクをサムネイルに、サムネイルを取得しない場合は
+// サムネイル画像をフルサイズファイルに向けます。
+func applyMediaSelection(task config.Task, media *model.MediaInfo) {
+	switch {
+	case task.ThumbnailsOnly:
+		if media.LocalThumbPath != "" {
+			media.LocalPath = media.LocalThumbPath
+		}
+	case !shouldDownloadThumbnails(task):
+		media.LocalThumbPath = media.LocalPath
+	}
+}
+
+func generateDirectoryPath(rootDir, format string, thread model.ThreadInfo, maxTitleLength int) (string, error) {
 	// フォーマットが空の場合はデフォルトのフォーマットを使用
 	if format == "" {
 		format = "{thread_id}"
@@ -459,7 +932,7 @@ func generateDirectoryPath(rootDir, format string, thread model.ThreadInfo) (str
 		"{month}", month,
 		"{day}", day,
 		"{thread_id}", threadID,
-		"{thread_title_safe}", SanitizeFilename(threadTitle),
+		"{thread_title_safe}", safeTitle(threadTitle, maxTitleLength),
 	)
 
 	result := r.Replace(format)
@@ -472,14 +945,41 @@ func generateDirectoryPath(rootDir, format string, thread model.ThreadInfo) (str
 	return filepath.Join(rootDir, result), nil
 }
 
-func applyPostContentFilters(htmlContent string, filters *config.PostContentFilters) (bool, string) {
+// parseThreadPosts は、アダプタが対応している場合にスレッドのレスを構造化して返します。
+// 対応していない場合や解析に失敗した場合は nil を返し、呼び出し元はHTML全体を対象に処理します。
+func parseThreadPosts(siteAdapter adapter.SiteAdapter, htmlContent, threadURL string, logger *log.Logger) []model.Post {
+	parser, ok := siteAdapter.(adapter.PostParser)
+	if !ok {
+		return nil
+	}
+	posts, err := parser.ParsePosts(htmlContent, threadURL)
+	if err != nil {
+		logger.Printf("WARNING: レスの解析に失敗したため、二次フィルタはHTML全体を対象にし、内容のハッシュは比較しません: %v", err)
+		return nil
+	}
+	return posts
+}
+
+// applyPostContentFilters は、二次フィルタ（post_content_filters）を適用し、スレッドを保存するかどうかとその理由を返します。
+// posts がある場合は、テキストをレスの題名・本文と、投稿者をレスの名前・トリップ・ID表示と照合します。
+// posts がない場合は、タグを取り除いたHTML全体を対象にします。
+func applyPostContentFilters(htmlContent string, posts []model.Post, filters *config.PostContentFilters) (bool, string) {
 	if filters == nil {
 		return true, ""
 	}
 
-	// 簡易的なHTMLタグ除去
-	re := regexp.MustCompile(`<[^>]*>`)
-	text := re.ReplaceAllString(htmlContent, "")
+	var text string
+	if posts != nil {
+		var sb strings.Builder
+		for _, post := range posts {
+			sb.WriteString(post.Subject + "\n" + post.Body + "\n")
+		}
+		text = sb.String()
+	} else {
+		// 簡易的なHTMLタグ除去
+		re := regexp.MustCompile(`<[^>]*>`)
+		text = re.ReplaceAllString(htmlContent, "")
+	}
 
 	if len(filters.IncludeAnyText) > 0 {
 		found := false
@@ -505,7 +1005,7 @@ func applyPostContentFilters(htmlContent string, filters *config.PostContentFilt
 	if len(filters.IncludeAuthorIDs) > 0 {
 		found := false
 		for _, id := range filters.IncludeAuthorIDs {
-			if strings.Contains(htmlContent, id) {
+			if hasAuthor(htmlContent, posts, id) {
 				found = true
 				break
 			}
@@ -518,10 +1018,26 @@ func applyPostContentFilters(htmlContent string, filters *config.PostContentFilt
 	return true, ""
 }
 
+// hasAuthor は、スレッドに投稿者 id のレスがあるかを判定します。
+// posts がある場合は名前・トリップ（先頭の ◆ は省略可）・ID表示（先頭の ID: は省略可）の完全一致で、ない場合はHTML内の文字列で判定します。
+func hasAuthor(htmlContent string, posts []model.Post, id string) bool {
+	if posts == nil {
+		return strings.Contains(htmlContent, id)
+	}
+	trip, posterID := strings.TrimPrefix(id, "◆"), strings.TrimPrefix(id, "ID:")
+	for _, post := range posts {
+		if post.Author == id || (post.Trip != "" && post.Trip == trip) || (post.PosterID != "" && post.PosterID == posterID) {
+			return true
+		}
+	}
+	return false
+}
+
 // handleResumeLogic は、レジューム処理のロジックを管理します。
 // .resume.jsonを読み込み、ディスク上のファイル存在もチェックして、
 // 本当にダウンロードが必要なファイルのみのリストを返します。
-func handleResumeLogic(enabled bool, resumePath string, allMediaFiles []model.MediaInfo, mediaSavePath string) ([]model.MediaInfo, error) {
+// 保存済みかどうかは、ダウンロード時と同じくタスクのファイル名フォーマット（format）とスレッドの情報から求めた名前で判定します。
+func handleResumeLogic(enabled bool, resumePath string, format string, thread model.ThreadInfo, allMediaFiles []model.MediaInfo, mediaSavePath string) ([]model.MediaInfo, error) {
 	if !enabled {
 		return allMediaFiles, nil
 	}
@@ -544,7 +1060,7 @@ func handleResumeLogic(enabled bool, resumePath string, allMediaFiles []model.Me
 
 	// ディスク上のファイル存在チェック
 	for _, media := range initialFilesToCheck {
-		saveFileName, err := generateFileName("", model.ThreadInfo{}, media) // threadInfoはファイル名生成に不要なためダミー
+		saveFileName, err := generateFileName(format, thread, media)
 		if err != nil {
 			log.Printf("WARNING: レジューム処理中のファイル名生成失敗: %s - %v. このファイルをダウンロード対象とします。", media.URL, err)
 			finalFilesToDownload = append(finalFilesToDownload, media)
@@ -579,6 +1095,8 @@ func handleResumeLogic(enabled bool, resumePath string, allMediaFiles []model.Me
 }
 
 func generateFileName(format string, thread model.ThreadInfo, media model.MediaInfo) (string, error) {
+	format = resolveFilenameFormat(format)
+
 	// フォーマットが空の場合は元のファイル名をそのまま使用
 	if format == "" {
 		if media.OriginalFilename == "" {
@@ -614,7 +1132,14 @@ func generateFileName(format string, thread model.ThreadInfo, media model.MediaI
 		ext = "bin" // 拡張子が不明な場合のfallback
 	}
 
+	// ハッシュが未計算（ダウンロード前・失敗時）の場合は、衝突しないよう元のファイル名で代用する
+	sha256Prefix := SanitizeFilename(originalFilenameWithoutExt)
+	if len(media.SHA256) >= 8 {
+		sha256Prefix = media.SHA256[:8]
+	}
+
 	r := strings.NewReplacer(
+		"{sha256_8}", sha256Prefix,
 		"{year}", year,
 		"{month}", month,
 		"{day}", day,
@@ -655,31 +1180,6 @@ func copyFile(src, dst string) error {
 	return out.Sync()
 }
 
-func updateResumeFile(resumePath, downloadedURL string) error {
-	data, err := os.ReadFile(resumePath)
-	if err != nil {
-		return err
-	}
-
-	var pendingFiles []model.MediaInfo
-	if err := json.Unmarshal(data, &pendingFiles); err != nil {
-		return err
-	}
-
-	var newPendingFiles []model.MediaInfo
-	for _, file := range pendingFiles {
-		if file.URL != downloadedURL {
-			newPendingFiles = append(newPendingFiles, file)
-		}
-	}
-
-	newData, err := json.MarshalIndent(newPendingFiles, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(resumePath, newData, 0644)
-}
-
 func appendToHistory(path, threadID string) error {
 	// ディレクトリが存在しない場合は作成
 	dir := filepath.Dir(path)
@@ -703,7 +1203,10 @@ func appendToMetadataIndex(_ string, _ config.Task, thread model.ThreadInfo, _ [
 	return nil
 }
 
+// SanitizeFilename は、ファイル名として使用できない文字を全角文字に置き換えます。
+// あわせてNFC正規化を行い、制御文字やゼロ幅文字などの不可視文字を取り除きます。
 func SanitizeFilename(name string) string {
+	name = stripInvisible(name)
 	r := strings.NewReplacer(
 		"/", "／",
 		"\\", "＼",
@@ -0,0 +1,342 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+	"GoImageBoardArchiver/internal/network"
+)
+
+func TestDownloadStats_SuccessRatio(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		stats downloadStats
+		want  float64
+	}{
+		{name: "試行なし", stats: downloadStats{}, want: 1},
+		{name: "全成功", stats: downloadStats{Attempted: 4}, want: 1},
+		{name: "1割成功", stats: downloadStats{Attempted: 10, Failed: 9}, want: 0.1},
+		{name: "全失敗", stats: downloadStats{Attempted: 3, Failed: 3}, want: 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.stats.successRatio(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("successRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadAndFileConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		task        config.Task
+		wantThreads int
+		wantFiles   int
+	}{
+		{name: "未設定", task: config.Task{}, wantThreads: 4, wantFiles: 1},
+		{name: "旧設定のみ", task: config.Task{MaxConcurrentDownloads: 2}, wantThreads: 2, wantFiles: 1},
+		{name: "新設定を優先", task: config.Task{MaxConcurrentDownloads: 2, MaxConcurrentThreads: 6, MaxConcurrentFilesPerThread: 3}, wantThreads: 6, wantFiles: 3},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := threadConcurrency(tt.task); got != tt.wantThreads {
+				t.Errorf("threadConcurrency() = %d, want %d", got, tt.wantThreads)
+			}
+			if got := fileConcurrency(tt.task); got != tt.wantFiles {
+				t.Errorf("fileConcurrency() = %d, want %d", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestDownloadMediaFiles_FileConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := network.NewClient(config.NetworkSettings{
+		PerDomainIntervalMillis: map[string]int{serverURL.Hostname(): 1},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	const limit = 3
+	task := config.Task{TargetBoardURL: server.URL + "/b/", MaxConcurrentFilesPerThread: limit}
+	files := make([]model.MediaInfo, 8)
+	for i := range files {
+		name := fmt.Sprintf("%d.jpg", i)
+		files[i] = model.MediaInfo{URL: server.URL + "/src/" + name, OriginalFilename: name}
+	}
+
+	dir := t.TempDir()
+	logger := log.New(io.Discard, "", 0)
+	stats, err := downloadMediaFiles(context.Background(), client, task, model.ThreadInfo{ID: "1"}, files, dir, dir, filepath.Join(dir, ".resume.json"), nil, nil, logger)
+	if err != nil {
+		t.Fatalf("downloadMediaFiles() error = %v", err)
+	}
+
+	if stats.Downloaded != len(files) || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want %d downloaded without failures", stats, len(files))
+	}
+	if got := atomic.LoadInt32(&maxInFlight); got > limit || got < 2 {
+		t.Errorf("同時ダウンロード数の最大値 = %d, want 2..%d", got, limit)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.LocalPath); err != nil {
+			t.Errorf("ファイル %s が保存されていません: %v", f.LocalPath, err)
+		}
+	}
+}
+
+func TestDownloadMediaFiles_BlocksByContentHash(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(path.Base(r.URL.Path)))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := network.NewClient(config.NetworkSettings{
+		PerDomainIntervalMillis: map[string]int{serverURL.Hostname(): 1},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// ArchiveSingleThread でコンパイルしたブロックリストが、ダウンロード後のハッシュの照合に使用される
+	sum := sha256.Sum256([]byte("spam.jpg"))
+	task := config.Task{TargetBoardURL: server.URL + "/b/"}
+	blocklist, err := newMediaBlocklist([]string{"sha256:" + hex.EncodeToString(sum[:4])})
+	if err != nil {
+		t.Fatalf("newMediaBlocklist() error = %v", err)
+	}
+	files := []model.MediaInfo{
+		{URL: server.URL + "/src/spam.jpg", OriginalFilename: "spam.jpg"},
+		{URL: server.URL + "/src/ok.jpg", OriginalFilename: "ok.jpg"},
+	}
+
+	dir := t.TempDir()
+	logger := log.New(io.Discard, "", 0)
+	if _, err := downloadMediaFiles(context.Background(), client, task, model.ThreadInfo{ID: "1"}, files, dir, dir, filepath.Join(dir, ".resume.json"), nil, blocklist, logger); err != nil {
+		t.Fatalf("downloadMediaFiles() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "spam.jpg")); !os.IsNotExist(err) {
+		t.Errorf("ブロック対象のファイルが残っています: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ok.jpg")); err != nil {
+		t.Errorf("ブロック対象でないファイルが保存されていません: %v", err)
+	}
+}
+
+func TestDownloadWaits_RespectCancellation(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/busy/") {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	client, err := network.NewClient(config.NetworkSettings{
+		PerDomainIntervalMillis: map[string]int{serverURL.Hostname(): 1},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	logger := log.New(io.Discard, "", 0)
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, dir string) error
+	}{
+		{
+			// 5xx のリトライ待機中にキャンセルされた場合
+			name: "リトライの待機",
+			run: func(ctx context.Context, dir string) error {
+				task := config.Task{RetryCount: 5, RetryWaitMillis: int(time.Hour / time.Millisecond)}
+				return downloadFileWithRetry(ctx, client, server.URL+"/busy/1.jpg", filepath.Join(dir, "1.jpg"), task, log.New(io.Discard, "", 0))
+			},
+		},
+		{
+			// ファイル間のリクエスト間隔の待機中にキャンセルされた場合
+			name: "リクエスト間隔の待機",
+			run: func(ctx context.Context, dir string) error {
+				task := config.Task{TargetBoardURL: server.URL + "/b/", RequestIntervalMillis: int(time.Hour / time.Millisecond)}
+				files := []model.MediaInfo{
+					{URL: server.URL + "/src/1.jpg", OriginalFilename: "1.jpg"},
+					{URL: server.URL + "/src/2.jpg", OriginalFilename: "2.jpg"},
+				}
+				_, err := downloadMediaFiles(ctx, client, task, model.ThreadInfo{ID: "1"}, files, dir, dir, filepath.Join(dir, ".resume.json"), nil, nil, logger)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, cancel)
+
+			start := time.Now()
+			err := tt.run(ctx, t.TempDir())
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want context.Canceled", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("キャンセル後の終了までの時間 = %v, want 2秒以内", elapsed)
+			}
+		})
+	}
+}
+
+// testFallbackSource は、メディアのファイル名を保管サイトのURL（base/<ファイル名>）に対応させます。
+type testFallbackSource struct{ base string }
+
+func (s testFallbackSource) FallbackMediaURLs(_ model.ThreadInfo, mediaURL string) []string {
+	return []string{s.base + "/missing/" + path.Base(mediaURL), s.base + "/" + path.Base(mediaURL)}
+}
+
+func TestDownloadMediaFiles_Fallback(t *testing.T) {
+	t.Parallel()
+
+	board := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(board.Close)
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.jpg", "/1s.jpg":
+			w.Write([]byte("mirror " + r.URL.Path))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(mirror.Close)
+
+	boardURL, _ := url.Parse(board.URL)
+	client, err := network.NewClient(config.NetworkSettings{
+		PerDomainIntervalMillis: map[string]int{boardURL.Hostname(): 1},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	task := config.Task{TargetBoardURL: board.URL + "/b/"}
+	newFiles := func() []model.MediaInfo {
+		return []model.MediaInfo{
+			{URL: board.URL + "/b/src/1.jpg", ThumbnailURL: board.URL + "/b/thumb/1s.jpg", OriginalFilename: "1.jpg"},
+			{URL: board.URL + "/b/src/2.jpg", OriginalFilename: "2.jpg"},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		fallback       adapter.FallbackMediaSource
+		wantDownloaded int
+		wantFailed     int
+	}{
+		{name: "保管サイトから取得", fallback: testFallbackSource{base: mirror.URL}, wantDownloaded: 2, wantFailed: 1},
+		{name: "保管サイトなし", fallback: nil, wantDownloaded: 0, wantFailed: 2},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			files := newFiles()
+			logger := log.New(io.Discard, "", 0)
+			stats, err := downloadMediaFiles(context.Background(), client, task, model.ThreadInfo{ID: "1"}, files, dir, dir, filepath.Join(dir, ".resume.json"), tt.fallback, nil, logger)
+			if err != nil {
+				t.Fatalf("downloadMediaFiles() error = %v", err)
+			}
+			if stats.Downloaded != tt.wantDownloaded || stats.Failed != tt.wantFailed {
+				t.Errorf("stats = %+v, want downloaded=%d failed=%d", stats, tt.wantDownloaded, tt.wantFailed)
+			}
+			if tt.fallback != nil {
+				if data, err := os.ReadFile(files[0].LocalPath); err != nil || string(data) != "mirror /1.jpg" {
+					t.Errorf("保存したファイル = %q, %v, want %q", data, err, "mirror /1.jpg")
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPostContentFilters_Posts(t *testing.T) {
+	t.Parallel()
+
+	htmlContent := `<title>キーワード</title><div class="thre">Name <span class="cnm">としあき◆Trip01</span> ID:aBcD1234 No.1<blockquote>本文</blockquote></div>`
+	posts := []model.Post{{ResNumber: 1, Author: "としあき", Trip: "Trip01", PosterID: "aBcD1234", Body: "本文"}}
+
+	tests := []struct {
+		name    string
+		posts   []model.Post
+		filters config.PostContentFilters
+		want    bool
+	}{
+		// レスの題名・本文のみを対象にするため、<title> のテキストには一致しない
+		{name: "本文のテキスト", posts: posts, filters: config.PostContentFilters{IncludeAnyText: []string{"キーワード"}}, want: false},
+		{name: "HTML全体のテキスト", filters: config.PostContentFilters{IncludeAnyText: []string{"キーワード"}}, want: true},
+		{name: "トリップ", posts: posts, filters: config.PostContentFilters{IncludeAuthorIDs: []string{"◆Trip01"}}, want: true},
+		{name: "ID表示", posts: posts, filters: config.PostContentFilters{IncludeAuthorIDs: []string{"ID:aBcD1234"}}, want: true},
+		// レスの情報との完全一致のため、ID の一部には一致しない
+		{name: "IDの一部", posts: posts, filters: config.PostContentFilters{IncludeAuthorIDs: []string{"aBcD"}}, want: false},
+		{name: "HTML内の文字列", filters: config.PostContentFilters{IncludeAuthorIDs: []string{"aBcD"}}, want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got, reason := applyPostContentFilters(htmlContent, tt.posts, &tt.filters); got != tt.want {
+				t.Errorf("applyPostContentFilters() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+)
+
+// threadDate は、スレッドHTMLからスレッドの作成日時（OPの投稿日時）を取得します。
+// アダプタが日時の抽出に対応していない場合や、日時が見つからない場合は false を返します。
+func threadDate(siteAdapter adapter.SiteAdapter, htmlContent string) (time.Time, bool) {
+	extractor, ok := siteAdapter.(adapter.ThreadDateExtractor)
+	if !ok {
+		return time.Time{}, false
+	}
+	return extractor.ExtractThreadDate(htmlContent)
+}
+
+// inTaskTimezone は、日時をタスクの timezone（未設定の場合はマシンのローカル時刻）に変換します。
+// {year}/{month}/{day} は変換後の日時で計算されるため、UTC で動作するサーバーでも利用者の日付で保存されます。
+func inTaskTimezone(task config.Task, t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(config.Location(task.Timezone))
+}
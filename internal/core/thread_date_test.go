@@ -0,0 +1,52 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/model"
+)
+
+func TestThreadDate_DirectoryFormat(t *testing.T) {
+	t.Parallel()
+
+	// 2025/11/18 00:30 (日本時間) は UTC では前日の 15:30
+	const threadHTML = `<span>25/11/18(火)00:30:00</span> No.123<blockquote>本文</blockquote>`
+	futaba := adapter.NewFutabaAdapter()
+
+	tests := []struct {
+		name     string
+		timezone string
+		want     string
+	}{
+		{name: "日本時間", timezone: "Asia/Tokyo", want: "2025/11/18"},
+		{name: "UTC", timezone: "UTC", want: "2025/11/17"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			date, ok := threadDate(futaba, threadHTML)
+			if !ok {
+				t.Fatal("threadDate() が日時を返しませんでした")
+			}
+			task := config.Task{Timezone: tt.timezone}
+			thread := model.ThreadInfo{ID: "123", Date: inTaskTimezone(task, date)}
+			got, err := generateDirectoryPath("root", "{year}/{month}/{day}", thread, 0)
+			if err != nil {
+				t.Fatalf("generateDirectoryPath() error = %v", err)
+			}
+			if want := filepath.Join("root", filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("generateDirectoryPath() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	if got := inTaskTimezone(config.Task{Timezone: "UTC"}, time.Time{}); !got.IsZero() {
+		t.Errorf("inTaskTimezone(zero) = %v, want zero", got)
+	}
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+)
+
+// 差分の状態を表す定数です。
+const (
+	PostAdded   = "added"
+	PostDeleted = "deleted"
+)
+
+// DefaultDiffLeft と DefaultDiffRight は、差分表示で既定で比較するファイルです。
+// 完全版（削除レスを含む）と最新版を比較することで、スレッドから削除されたレスを確認できます。
+const (
+	DefaultDiffLeft  = "archive_full.html"
+	DefaultDiffRight = "index.htm"
+)
+
+// maxPostTextLength は、差分表示に含めるレス本文の最大文字数です。
+const maxPostTextLength = 1000
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// PostDiff は、2つのHTML間で追加または削除された1件のレスです。
+type PostDiff struct {
+	ResNumber string `json:"res_number"`
+	Status    string `json:"status"`         // PostAdded または PostDeleted
+	Text      string `json:"text,omitempty"` // タグを除いたレス本文（見つからない場合は空）
+}
+
+// ThreadDiff は、アーカイブ済みスレッドの2つのHTMLをレス単位で比較した結果です。
+type ThreadDiff struct {
+	ThreadDir string     `json:"thread_dir"`
+	Left      string     `json:"left"`
+	Right     string     `json:"right"`
+	Added     int        `json:"added"`
+	Deleted   int        `json:"deleted"`
+	Unchanged int        `json:"unchanged"`
+	Posts     []PostDiff `json:"posts"`
+}
+
+// diffResNumbers は、oldHTML と newHTML のレス番号を比較し、追加・削除されたレス番号をレス番号順に返します。
+func diffResNumbers(oldHTML, newHTML string) (added, deleted []string, unchanged int) {
+	oldResNumbers := extractResNumbers(oldHTML)
+	newResNumbers := extractResNumbers(newHTML)
+
+	for resNum := range newResNumbers {
+		if oldResNumbers[resNum] {
+			unchanged++
+		} else {
+			added = append(added, resNum)
+		}
+	}
+	for resNum := range oldResNumbers {
+		if !newResNumbers[resNum] {
+			deleted = append(deleted, resNum)
+		}
+	}
+	sortResNumbers(added)
+	sortResNumbers(deleted)
+	return added, deleted, unchanged
+}
+
+// sortResNumbers は、数字のみからなるレス番号を数値順に並べ替えます。
+func sortResNumbers(resNumbers []string) {
+	sort.Slice(resNumbers, func(i, j int) bool { return lessResNumber(resNumbers[i], resNumbers[j]) })
+}
+
+// lessResNumber は、数字のみからなるレス番号 a が b より小さいかを判定します（桁数が異なっても数値として比較します）。
+func lessResNumber(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// DiffThreadHTML は、2つのHTMLをレス単位で比較し、追加・削除されたレスをレス番号順に返します。
+func DiffThreadHTML(oldHTML, newHTML string) (posts []PostDiff, unchanged int) {
+	added, deleted, unchanged := diffResNumbers(oldHTML, newHTML)
+
+	posts = make([]PostDiff, 0, len(added)+len(deleted))
+	for _, resNum := range added {
+		posts = append(posts, PostDiff{ResNumber: resNum, Status: PostAdded, Text: postText(newHTML, resNum)})
+	}
+	for _, resNum := range deleted {
+		posts = append(posts, PostDiff{ResNumber: resNum, Status: PostDeleted, Text: postText(oldHTML, resNum)})
+	}
+	sort.SliceStable(posts, func(i, j int) bool { return lessResNumber(posts[i].ResNumber, posts[j].ResNumber) })
+	return posts, unchanged
+}
+
+// DiffArchivedThread は、スレッドの保存先ディレクトリにある2つのHTMLファイルをレス単位で比較します。
+// left・right はディレクトリ直下の .htm / .html ファイル名で、空の場合は DefaultDiffLeft・DefaultDiffRight を使用します。
+func DiffArchivedThread(threadDir, left, right string) (ThreadDiff, error) {
+	if left == "" {
+		left = DefaultDiffLeft
+	}
+	if right == "" {
+		right = DefaultDiffRight
+	}
+
+	oldHTML, err := readDiffableFile(threadDir, left)
+	if err != nil {
+		return ThreadDiff{}, err
+	}
+	newHTML, err := readDiffableFile(threadDir, right)
+	if err != nil {
+		return ThreadDiff{}, err
+	}
+
+	posts, unchanged := DiffThreadHTML(oldHTML, newHTML)
+	diff := ThreadDiff{ThreadDir: threadDir, Left: left, Right: right, Unchanged: unchanged, Posts: posts}
+	for _, p := range posts {
+		if p.Status == PostAdded {
+			diff.Added++
+		} else {
+			diff.Deleted++
+		}
+	}
+	return diff, nil
+}
+
+// readDiffableFile は、スレッドディレクトリ直下のHTMLファイルを読み込みます。
+// 任意のファイルを読めないよう、ディレクトリ区切りを含む名前やHTML以外のファイルは拒否します。
+func readDiffableFile(threadDir, name string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || (ext != ".htm" && ext != ".html") {
+		return "", fmt.Errorf("比較できるのはスレッドディレクトリ直下のHTMLファイルのみです: %q", name)
+	}
+	data, err := archivecrypt.ReadFile(filepath.Join(threadDir, name))
+	if err != nil {
+		return "", fmt.Errorf("比較対象のファイルの読み込みに失敗しました (path=%s): %w", filepath.Join(threadDir, name), err)
+	}
+	return string(data), nil
+}
+
+// postText は、HTMLから指定されたレスを探し、タグを除いた本文を返します。
+func postText(htmlContent, resNum string) string {
+	blocks := findPostHTML(htmlContent, resNum)
+	if len(blocks) == 0 {
+		return ""
+	}
+	// 最短一致のため、ブロックの先頭に前のレスが含まれることがある。レス番号の直前の開始タグから切り出す
+	block := trimToPost(blocks[0], resNum)
+	for _, b := range blocks[1:] {
+		if b = trimToPost(b, resNum); len(b) < len(block) {
+			block = b
+		}
+	}
+	text := htmlTagPattern.ReplaceAllString(block, " ")
+	text = strings.TrimSpace(whitespacePattern.ReplaceAllString(html.UnescapeString(text), " "))
+	if runes := []rune(text); len(runes) > maxPostTextLength {
+		text = string(runes[:maxPostTextLength]) + "…"
+	}
+	return text
+}
+
+// trimToPost は、レスのHTMLブロックから、レス番号の直前にある同じ種類の開始タグ以降を切り出します。
+func trimToPost(block, resNum string) string {
+	loc := regexp.MustCompile(`No\.` + resNum + `\b`).FindStringIndex(block)
+	end := strings.IndexAny(block, " >")
+	if loc == nil || end < 1 {
+		return block
+	}
+	if start := strings.LastIndex(block[:loc[0]], block[:end]); start > 0 {
+		return block[start:]
+	}
+	return block
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiffThreadHTML(t *testing.T) {
+	t.Parallel()
+
+	post := func(no, body string) string {
+		return `<table><tr><td>No.` + no + `<blockquote>` + body + `</blockquote></td></tr></table>`
+	}
+	oldHTML := post("100", "スレ本文") + post("99", "&gt;&gt;消えたレス") + post("101", "残るレス")
+	newHTML := post("100", "スレ本文") + post("101", "残るレス") + post("1000", "新しい  レス")
+
+	posts, unchanged := DiffThreadHTML(oldHTML, newHTML)
+	want := []PostDiff{
+		{ResNumber: "99", Status: PostDeleted, Text: "No.99 >>消えたレス"},
+		{ResNumber: "1000", Status: PostAdded, Text: "No.1000 新しい レス"},
+	}
+	if !reflect.DeepEqual(posts, want) {
+		t.Errorf("DiffThreadHTML() = %+v, want %+v", posts, want)
+	}
+	if unchanged != 2 {
+		t.Errorf("unchanged = %d, want 2", unchanged)
+	}
+}
+
+func TestDiffArchivedThread(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, DefaultDiffLeft), []byte(`<div class="reply">No.1 a</div><div class="reply">No.2 b</div>`), 0644)
+	os.WriteFile(filepath.Join(dir, DefaultDiffRight), []byte(`<div class="reply">No.1 a</div>`), 0644)
+
+	tests := []struct {
+		name        string
+		left, right string
+		wantDeleted int
+		wantErr     bool
+	}{
+		{name: "既定のファイル", wantDeleted: 1},
+		{name: "同じファイル", left: DefaultDiffRight, right: DefaultDiffRight},
+		{name: "ディレクトリ外は拒否", left: "../" + DefaultDiffLeft, wantErr: true},
+		{name: "HTML以外は拒否", left: ".snapshot.json", wantErr: true},
+		{name: "存在しないファイル", right: "missing.html", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			diff, err := DiffArchivedThread(dir, tt.left, tt.right)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiffArchivedThread() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (diff.Deleted != tt.wantDeleted || diff.Added != 0 || diff.Unchanged < 1) {
+				t.Errorf("DiffArchivedThread() = %+v, want deleted=%d", diff, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"GoImageBoardArchiver/internal/model"
+)
+
+// threadContentHash は、スレッドのレスの内容を正規化したSHA-256（16進数）を返します。
+// レスの番号・名前・トリップ・ID表示・題名・本文・添付ファイルのURLから計算するため、
+// 「そうだね」の数や広告・日時などレス以外の表示が変わってもハッシュは変わりません。
+// posts がない場合（アダプタがレスの構造化に対応していない場合）は空文字を返し、内容のハッシュでは比較しません。
+// メディア数・レス数が変わらないレスの編集や削除を検知するために、スナップショットに記録します。
+func threadContentHash(posts []model.Post) string {
+	if len(posts) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, post := range posts {
+		fields := []string{strconv.Itoa(post.ResNumber), post.Author, post.Trip, post.PosterID, post.Subject, post.Body}
+		for _, media := range post.Media {
+			fields = append(fields, media.URL)
+		}
+		// 区切り文字を含む値で、別のフィールドの組み合わせと同じ入力にならないようにする
+		for _, field := range fields {
+			h.Write([]byte(strconv.Itoa(len(field)) + ":" + field + "\x00"))
+		}
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/adapter"
+)
+
+// threadHashTestHTML は、ふたばのスレッドのHTMLです。{sod}・{script}・{body} を置き換えて使用します。
+const threadHashTestHTML = `<html><head>{script}</head><body>
+<div class="thre">
+<a href="/b/src/1700000000000.png" target="_blank"><img src="/b/thumb/1700000000000s.jpg"></a>
+<span class="cnw">25/11/18(火)12:00:00</span> <span class="cno">No.1000</span> <a class="sod" id="sd1000">{sod}</a>
+<blockquote>スレ本文</blockquote>
+<table border=0><tr><td class=rts>…</td><td class=rtd>
+<span class="cnw">25/11/18(火)12:01:00</span> <span class="cno">No.1001</span>
+<blockquote>{body}</blockquote>
+</td></tr></table>
+</div>
+</body></html>`
+
+func TestThreadContentHash(t *testing.T) {
+	t.Parallel()
+
+	render := func(sod, script, body string) string {
+		return strings.NewReplacer("{sod}", sod, "{script}", script, "{body}", body).Replace(threadHashTestHTML)
+	}
+	base := render("+", "", "返信")
+
+	tests := []struct {
+		name        string
+		html        string
+		wantChanged bool
+	}{
+		{name: "同じ内容", html: base},
+		{name: "スクリプトと日時のみの違い", html: strings.ReplaceAll(render("+", "<script>var t=1700000000;</script>", "返信"), "12:01:00", "12:01:30")},
+		{name: "そうだねの数の違い", html: render("そうだねx3", "", "返信")},
+		{name: "本文の編集", html: render("+", "", "編集された返信"), wantChanged: true},
+	}
+
+	threadURL := "https://may.2chan.net/b/res/1000.htm"
+	logger := log.New(io.Discard, "", 0)
+	futaba := &adapter.FutabaAdapter{}
+	postHash := func(htmlContent string) string {
+		posts := parseThreadPosts(futaba, htmlContent, threadURL, logger)
+		if posts == nil {
+			t.Fatal("parseThreadPosts() = nil")
+		}
+		return threadContentHash(posts)
+	}
+
+	for _, tt := range tests {
+		if changed := postHash(tt.html) != postHash(base); changed != tt.wantChanged {
+			t.Errorf("%s: ハッシュの変化 = %v, want %v", tt.name, changed, tt.wantChanged)
+		}
+	}
+
+	// レスを構造化できないアダプタでは、内容のハッシュでは比較しない
+	if got := threadContentHash(nil); got != "" {
+		t.Errorf("threadContentHash(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/model"
+)
+
+// threadMetadataFile は、スレッドディレクトリに保存するメタデータファイル名です。
+const threadMetadataFile = "thread.json"
+
+// ThreadMetadata は、外部ツールやビューアから参照するためのスレッド情報です（thread.json）。
+// .snapshot.json が内部状態であるのに対し、こちらは利用者向けの情報のみを含みます。
+type ThreadMetadata struct {
+	ThreadID     string             `json:"thread_id"`
+	Title        string             `json:"title"` // 表示用タイトル（観測したうち最も長いもの）
+	URL          string             `json:"url"`
+	TitleHistory []TitleObservation `json:"title_history"`
+	CreatedAt    *time.Time         `json:"created_at,omitempty"` // スレッドの作成日時（OPの投稿日時）
+	UpdatedAt    time.Time          `json:"updated_at"`
+	// Posts は、ID表示・おみくじ・削除などの掲示板固有の表示があるレスの情報です（アダプタが対応している場合のみ）。
+	Posts []model.PostFeatures `json:"posts,omitempty"`
+}
+
+// SaveThreadMetadata は、スナップショットの内容から thread.json を書き出します。
+func SaveThreadMetadata(threadSavePath, threadURL string, snapshot *ThreadSnapshot) error {
+	meta := ThreadMetadata{
+		ThreadID:     snapshot.ThreadID,
+		Title:        snapshot.DisplayTitle(),
+		URL:          threadURL,
+		TitleHistory: snapshot.TitleHistory,
+		CreatedAt:    snapshot.CreatedAt,
+		UpdatedAt:    time.Now(),
+		Posts:        snapshot.PostFeatures,
+	}
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("スレッドメタデータのシリアライズに失敗しました: %w", err)
+	}
+
+	path := filepath.Join(threadSavePath, threadMetadataFile)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("スレッドメタデータの書き込みに失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
+// LoadThreadMetadata は、スレッドディレクトリの thread.json を読み込みます。ファイルが存在しない場合は nil を返します。
+func LoadThreadMetadata(threadSavePath string) (*ThreadMetadata, error) {
+	path := filepath.Join(threadSavePath, threadMetadataFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("スレッドメタデータの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+
+	var meta ThreadMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("スレッドメタデータの解析に失敗しました (path=%s): %w", path, err)
+	}
+	return &meta, nil
+}
+
+// threadPostFeatures は、スレッドHTMLから抽出したレスごとの表示の情報を、前回の記録 previous に重ねて返します。
+// 今回のHTMLにないレス（スレッドから消えたレス）は前回の記録を残します。アダプタが対応していない場合は previous をそのまま返します。
+func threadPostFeatures(siteAdapter adapter.SiteAdapter, htmlContent string, previous []model.PostFeatures) []model.PostFeatures {
+	extractor, ok := siteAdapter.(adapter.PostFeatureExtractor)
+	if !ok {
+		return previous
+	}
+	byRes := make(map[int]model.PostFeatures, len(previous))
+	for _, f := range previous {
+		byRes[f.ResNumber] = f
+	}
+	for _, f := range extractor.ExtractPostFeatures(htmlContent) {
+		byRes[f.ResNumber] = f
+	}
+	if len(byRes) == 0 {
+		return nil
+	}
+	features := make([]model.PostFeatures, 0, len(byRes))
+	for _, f := range byRes {
+		features = append(features, f)
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i].ResNumber < features[j].ResNumber })
+	return features
+}
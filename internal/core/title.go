@@ -0,0 +1,186 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxTitleLength は、max_title_length 未設定時の {thread_title_safe} の最大長（書記素クラスタ数）です。
+// 多くのファイルシステムの上限である255バイトに、UTF-8の日本語（1文字3バイト）でも収まる長さにしています。
+const DefaultMaxTitleLength = 60
+
+// DefaultTitleFallbackLength は、title_fallback_length 未設定時に代替タイトルとして使用するOP本文の文字数です。
+const DefaultTitleFallbackLength = 30
+
+// boilerplateTitles は、スレッドを識別する役に立たない定型のタイトルです（大文字小文字は区別しません）。
+var boilerplateTitles = []string{"無題", "無念", "名無し", "untitled", "no title"}
+
+// autoGeneratedTitlePattern は、アダプタがタイトルを取得できなかった場合に付ける仮のタイトルです。
+var autoGeneratedTitlePattern = regexp.MustCompile(`^Thread \d+$`)
+
+const zeroWidthJoiner = '\u200d'
+
+// TruncateGraphemes は、文字列を書記素クラスタ単位で最大 maxGraphemes 個に切り詰めます。
+// 結合文字・異体字セレクタ・絵文字の修飾子やZWJ連結を途中で分断しないため、
+// rune 単位の切り詰めのように表示が崩れることがありません。切り詰めた場合は末尾に "…" を付けます。
+func TruncateGraphemes(s string, maxGraphemes int) string {
+	if maxGraphemes <= 0 {
+		return s
+	}
+
+	count := 0
+	for i := 0; i < len(s); {
+		if count == maxGraphemes {
+			return s[:i] + "…"
+		}
+		i += nextGraphemeLen(s[i:])
+		count++
+	}
+	return s
+}
+
+// nextGraphemeLen は、s の先頭の書記素クラスタのバイト長を返します。
+// Unicodeの書記素クラスタ境界規則（UAX #29）のうち、タイトルで問題になりやすい
+// 結合文字・異体字セレクタ・絵文字修飾子・ZWJシーケンス・国旗（地域指示記号のペア）のみを扱う簡易実装です。
+func nextGraphemeLen(s string) int {
+	var prev rune = -1
+	regionalIndicators := 0
+	for i, r := range s {
+		if i == 0 {
+			prev = r
+			if isRegionalIndicator(r) {
+				regionalIndicators = 1
+			}
+			continue
+		}
+
+		switch {
+		case isGraphemeExtender(r):
+			// 直前の文字に結合する
+		case prev == zeroWidthJoiner:
+			// ZWJ の次の文字は同じクラスタに含める
+		case isRegionalIndicator(r) && regionalIndicators == 1:
+			regionalIndicators = 2
+		default:
+			return i
+		}
+		prev = r
+	}
+	return len(s)
+}
+
+// isGraphemeExtender は、直前の文字と同じ書記素クラスタに属する文字かを判定します。
+func isGraphemeExtender(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F: // 異体字セレクタ
+		return true
+	case r >= 0xE0100 && r <= 0xE01EF: // 異体字セレクタ補助
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // 絵文字の肌の色修飾子
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // タグ文字（サブディビジョン旗）
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isStrippedInvisible は、ファイル名から取り除く不可視文字かを判定します。
+// 制御文字、ゼロ幅スペース、双方向制御文字、BOMなどが該当します。ZWJ は絵文字の連結に必要なため、ここでは扱いません。
+func isStrippedInvisible(r rune) bool {
+	switch {
+	case unicode.IsControl(r):
+		return true
+	case r == '\u200b', r == '\u200c', r == '\u2060', r == '\ufeff':
+		return true
+	case r == '\u200e', r == '\u200f', r == '\u061c':
+		return true
+	case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069':
+		return true
+	}
+	return false
+}
+
+// stripInvisible は、NFC正規化した文字列から不可視文字を取り除きます。
+// ZWJ は前後が絵文字などの記号の場合のみ残し、それ以外（文字の間に紛れ込んだもの）は取り除きます。
+func stripInvisible(name string) string {
+	runes := []rune(norm.NFC.String(name))
+	var sb strings.Builder
+	sb.Grow(len(name))
+	for i, r := range runes {
+		if isStrippedInvisible(r) {
+			continue
+		}
+		if r == zeroWidthJoiner {
+			if i == 0 || i == len(runes)-1 || !isEmojiLike(runes[i-1]) || !isEmojiLike(runes[i+1]) {
+				continue
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// isEmojiLike は、ZWJ シーケンスを構成しうる記号（絵文字とその修飾子）かを判定します。
+func isEmojiLike(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0xFE00 && r <= 0xFE0F) || (r >= 0x1F3FB && r <= 0x1F3FF)
+}
+
+// safeTitle は、スレッドタイトルを {thread_title_safe} 用にサニタイズし、最大長で切り詰めます。
+// Windowsで使用できない末尾の空白・ピリオドも取り除きます。
+func safeTitle(title string, maxGraphemes int) string {
+	if maxGraphemes <= 0 {
+		maxGraphemes = DefaultMaxTitleLength
+	}
+	s := TruncateGraphemes(strings.TrimSpace(SanitizeFilename(title)), maxGraphemes)
+	return strings.TrimRight(s, " .")
+}
+
+// isBoilerplateTitle は、タイトルが空白のみ・定型文・仮のタイトルのいずれかであるかを判定します。
+func isBoilerplateTitle(title string) bool {
+	trimmed := strings.TrimSpace(stripInvisible(title))
+	if trimmed == "" || autoGeneratedTitlePattern.MatchString(trimmed) {
+		return true
+	}
+	for _, b := range boilerplateTitles {
+		if strings.EqualFold(trimmed, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackTitle は、タイトルが定型文の場合にOP本文の先頭を代わりのタイトルとして返します。
+// アダプタがOP本文の抽出に対応していない場合や、本文が空の場合は元のタイトルをそのまま返します。
+func fallbackTitle(task config.Task, siteAdapter adapter.SiteAdapter, title, htmlContent string) string {
+	if task.TitleFallbackLength < 0 || !isBoilerplateTitle(title) {
+		return title
+	}
+	extractor, ok := siteAdapter.(adapter.OPTextExtractor)
+	if !ok {
+		return title
+	}
+	opText := strings.TrimSpace(extractor.ExtractOPText(htmlContent))
+	if opText == "" {
+		return title
+	}
+
+	length := task.TitleFallbackLength
+	if length == 0 {
+		length = DefaultTitleFallbackLength
+	}
+	return strings.TrimSuffix(TruncateGraphemes(opText, length), "…")
+}
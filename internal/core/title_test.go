@@ -0,0 +1,109 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/adapter"
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestTruncateGraphemes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		max   int
+		want  string
+	}{
+		{name: "短い文字列はそのまま", input: "スレッド", max: 10, want: "スレッド"},
+		{name: "日本語を切り詰める", input: "あいうえお", max: 3, want: "あいう…"},
+		{name: "結合文字を分断しない", input: "e\u0301e\u0301e\u0301", max: 2, want: "e\u0301e\u0301…"},
+		{name: "肌の色修飾子を分断しない", input: "👍🏽👍🏽", max: 1, want: "👍🏽…"},
+		{name: "ZWJシーケンスを分断しない", input: "👨\u200d👩\u200d👧x", max: 1, want: "👨\u200d👩\u200d👧…"},
+		{name: "国旗を分断しない", input: "🇯🇵🇺🇸", max: 1, want: "🇯🇵…"},
+		{name: "0以下は無制限", input: "あいうえお", max: 0, want: "あいうえお"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := TruncateGraphemes(tt.input, tt.max); got != tt.want {
+				t.Errorf("TruncateGraphemes(%q, %d) = %q, want %q", tt.input, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFilename_Unicode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "NFC正規化", input: "か\u3099", want: "が"},
+		{name: "ゼロ幅スペースを除去", input: "ab\u200bc", want: "abc"},
+		{name: "制御文字を除去", input: "a\tb\x00c", want: "abc"},
+		{name: "双方向制御文字を除去", input: "\u202eabc", want: "abc"},
+		{name: "文字間のZWJを除去", input: "a\u200db", want: "ab"},
+		{name: "絵文字のZWJは残す", input: "👨\u200d👩", want: "👨\u200d👩"},
+		{name: "禁止文字の置換", input: "a/b:c", want: "a／b：c"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := SanitizeFilename(tt.input); got != tt.want {
+				t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeTitle(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("長", 200)
+	if got := safeTitle(long, 0); len([]rune(got)) != DefaultMaxTitleLength+1 {
+		t.Errorf("safeTitle() 既定長 = %d runes, want %d", len([]rune(got)), DefaultMaxTitleLength+1)
+	}
+	if got := safeTitle(" title. ", 10); got != "title" {
+		t.Errorf("safeTitle() = %q, want %q", got, "title")
+	}
+}
+
+func TestFallbackTitle(t *testing.T) {
+	t.Parallel()
+
+	const threadHTML = `<html><body><blockquote>今日の<br>晩ごはん&amp;おやつ スレ</blockquote><blockquote>レス</blockquote></body></html>`
+	futaba := adapter.NewFutabaAdapter()
+
+	tests := []struct {
+		name  string
+		title string
+		task  config.Task
+		want  string
+	}{
+		{name: "通常のタイトルはそのまま", title: "晩ごはんスレ", want: "晩ごはんスレ"},
+		{name: "空白のみは本文で置き換え", title: "　 ", want: "今日の 晩ごはん&おやつ スレ"},
+		{name: "無題は本文で置き換え", title: "無題", want: "今日の 晩ごはん&おやつ スレ"},
+		{name: "仮タイトルは本文で置き換え", title: "Thread 12345", want: "今日の 晩ごはん&おやつ スレ"},
+		{name: "文字数を制限", title: "無題", task: config.Task{TitleFallbackLength: 3}, want: "今日の"},
+		{name: "負の値で無効", title: "無題", task: config.Task{TitleFallbackLength: -1}, want: "無題"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := fallbackTitle(tt.task, futaba, tt.title, threadHTML); got != tt.want {
+				t.Errorf("fallbackTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// UsageStats は、usage_stats_file に記録する累計の利用統計です。外部には送信せず、ローカルのファイルにのみ保存します。
+type UsageStats struct {
+	Since           time.Time              `json:"since"`            // 記録を開始した日時
+	UpdatedAt       time.Time              `json:"updated_at"`       // 最後に記録した日時
+	ThreadsArchived int64                  `json:"threads_archived"` // アーカイブしたスレッド数（更新による再保存を含む）
+	PostsArchived   int64                  `json:"posts_archived"`   // アーカイブした新しいレス数
+	FilesDownloaded int64                  `json:"files_downloaded"` // ダウンロードしたファイル数
+	BytesWritten    int64                  `json:"bytes_written"`    // ダウンロードしたファイルの合計サイズ（バイト）
+	UptimeSeconds   int64                  `json:"uptime_seconds"`   // エンジンが動作していた時間の累計（秒）
+	Boards          map[string]*BoardUsage `json:"boards"`           // 板（ホスト名/パス）ごとの累計
+}
+
+// BoardUsage は、板ごとの累計の利用統計です。
+type BoardUsage struct {
+	ThreadsArchived int64 `json:"threads_archived"`
+	PostsArchived   int64 `json:"posts_archived"`
+	FilesDownloaded int64 `json:"files_downloaded"`
+	BytesWritten    int64 `json:"bytes_written"`
+}
+
+// usageStatsRecorder は、アーカイブの完了ごとに累計の利用統計を更新してファイルに書き込みます。
+type usageStatsRecorder struct {
+	mu        sync.Mutex
+	path      string
+	stats     UsageStats
+	baseUp    int64     // 今回の起動より前の稼働時間の累計（秒）
+	startedAt time.Time // 今回の起動で記録を開始した日時
+	now       func() time.Time
+}
+
+// sharedUsageStats は、プロセス内で共有される利用統計です。
+var sharedUsageStats = &usageStatsRecorder{now: time.Now}
+
+// ConfigureUsageStatsFile は、累計の利用統計を記録するファイルを設定します（空文字で無効）。
+// ファイルが既にある場合は、その値から累計を続けます。読み込めない場合は記録を無効にし、既存のファイルを上書きしません。
+func ConfigureUsageStatsFile(path string) error {
+	return sharedUsageStats.configure(path)
+}
+
+// CurrentUsageStats は、現在の累計の利用統計を返します。記録が無効な場合は false を返します。
+func CurrentUsageStats() (UsageStats, bool) {
+	return sharedUsageStats.current()
+}
+
+func (r *usageStatsRecorder) configure(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.path = ""
+	if path == "" {
+		return nil
+	}
+	now := r.now()
+	stats := UsageStats{Since: now}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &stats); err != nil {
+			return fmt.Errorf("利用統計ファイルの解析に失敗しました (path=%s): %w", path, err)
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("利用統計ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	if stats.Boards == nil {
+		stats.Boards = make(map[string]*BoardUsage)
+	}
+	r.path = path
+	r.stats = stats
+	r.baseUp = stats.UptimeSeconds
+	r.startedAt = now
+	return nil
+}
+
+func (r *usageStatsRecorder) current() (UsageStats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path == "" {
+		return UsageStats{}, false
+	}
+	r.updateUptimeLocked()
+	stats := r.stats
+	stats.Boards = make(map[string]*BoardUsage, len(r.stats.Boards))
+	for key, b := range r.stats.Boards {
+		copied := *b
+		stats.Boards[key] = &copied
+	}
+	return stats, true
+}
+
+// record は、board（config.BoardKey の値）の板で thread をアーカイブしたことを記録して書き込みます。
+func (r *usageStatsRecorder) record(board string, thread ArchivedThread) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path == "" {
+		return
+	}
+	b := r.stats.Boards[board]
+	if b == nil {
+		b = &BoardUsage{}
+		r.stats.Boards[board] = b
+	}
+	b.ThreadsArchived++
+	b.PostsArchived += int64(thread.NewPosts)
+	b.FilesDownloaded += int64(thread.FilesDownloaded)
+	b.BytesWritten += thread.BytesWritten
+	r.stats.ThreadsArchived++
+	r.stats.PostsArchived += int64(thread.NewPosts)
+	r.stats.FilesDownloaded += int64(thread.FilesDownloaded)
+	r.stats.BytesWritten += thread.BytesWritten
+	r.flushLocked()
+}
+
+// flush は、稼働時間を更新して書き込みます。エンジンの停止時に呼び出します。
+func (r *usageStatsRecorder) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.path != "" {
+		r.flushLocked()
+	}
+}
+
+func (r *usageStatsRecorder) updateUptimeLocked() {
+	now := r.now()
+	r.stats.UptimeSeconds = r.baseUp + int64(now.Sub(r.startedAt)/time.Second)
+	r.stats.UpdatedAt = now
+}
+
+func (r *usageStatsRecorder) flushLocked() {
+	r.updateUptimeLocked()
+	if err := writeUsageStatsFile(r.path, r.stats); err != nil {
+		log.Printf("WARNING: 利用統計ファイルの更新に失敗しました: %v", err)
+	}
+}
+
+// writeUsageStatsFile は、利用統計をJSONで書き込みます。
+// 書き込み途中で終了しても累計が失われないよう、一時ファイルに書いてからリネームします。
+func writeUsageStatsFile(path string, stats UsageStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("利用統計のエンコードに失敗しました: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("利用統計ファイルのディレクトリ作成に失敗しました (path=%s): %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("利用統計ファイルの書き込みに失敗しました (path=%s): %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("利用統計ファイルの更新に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
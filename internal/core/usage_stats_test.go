@@ -0,0 +1,98 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageStatsRecorder(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "state", "usage_stats.json")
+	r := &usageStatsRecorder{now: func() time.Time { return now }}
+
+	// 未設定の場合は何も記録しない
+	r.record("may.2chan.net/b", ArchivedThread{NewPosts: 1})
+	if _, ok := r.current(); ok {
+		t.Fatal("usage_stats_file が未設定の場合は無効であるべきです")
+	}
+
+	if err := r.configure(path); err != nil {
+		t.Fatalf("configure() error = %v", err)
+	}
+	now = now.Add(time.Hour)
+	r.record("may.2chan.net/b", ArchivedThread{NewPosts: 10, FilesDownloaded: 3, BytesWritten: 1000})
+	r.record("may.2chan.net/b", ArchivedThread{NewPosts: 2, FilesDownloaded: 1, BytesWritten: 200})
+	r.record("img.2chan.net/b", ArchivedThread{NewPosts: 5})
+
+	// 再起動後は、ファイルの累計から続ける
+	restarted := &usageStatsRecorder{now: func() time.Time { return now }}
+	if err := restarted.configure(path); err != nil {
+		t.Fatalf("configure() error = %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	restarted.record("img.2chan.net/b", ArchivedThread{NewPosts: 1, FilesDownloaded: 1, BytesWritten: 50})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("利用統計ファイルが作成されていません: %v", err)
+	}
+	var stats UsageStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		t.Fatalf("利用統計ファイルの解析に失敗しました: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want int64
+	}{
+		{name: "スレッド数", got: stats.ThreadsArchived, want: 4},
+		{name: "レス数", got: stats.PostsArchived, want: 18},
+		{name: "ファイル数", got: stats.FilesDownloaded, want: 5},
+		{name: "容量", got: stats.BytesWritten, want: 1250},
+		{name: "稼働時間", got: stats.UptimeSeconds, want: int64((90 * time.Minute).Seconds())},
+		{name: "板ごとのスレッド数", got: stats.Boards["may.2chan.net/b"].ThreadsArchived, want: 2},
+		{name: "板ごとの容量", got: stats.Boards["may.2chan.net/b"].BytesWritten, want: 1200},
+		{name: "別の板のレス数", got: stats.Boards["img.2chan.net/b"].PostsArchived, want: 6},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+	if want := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC); !stats.Since.Equal(want) {
+		t.Errorf("Since = %v, want %v", stats.Since, want)
+	}
+
+	// 返した統計を変更しても、記録中の値は変わらない
+	current, ok := restarted.current()
+	if !ok {
+		t.Fatal("current() = false, want true")
+	}
+	current.Boards["img.2chan.net/b"].ThreadsArchived = 100
+	if again, _ := restarted.current(); again.Boards["img.2chan.net/b"].ThreadsArchived != 2 {
+		t.Errorf("記録中の板の統計が変更されました: %+v", again.Boards["img.2chan.net/b"])
+	}
+}
+
+func TestUsageStatsRecorder_BrokenFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "usage_stats.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &usageStatsRecorder{now: time.Now}
+	if err := r.configure(path); err == nil {
+		t.Fatal("configure() error = nil, want error")
+	}
+	// 壊れたファイルを上書きして累計を失わないよう、記録を無効にする
+	r.record("may.2chan.net/b", ArchivedThread{NewPosts: 1})
+	if data, _ := os.ReadFile(path); string(data) != "{broken" {
+		t.Errorf("壊れた利用統計ファイルが上書きされました: %s", data)
+	}
+}
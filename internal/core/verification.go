@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"GoImageBoardArchiver/internal/adapter"
@@ -14,26 +15,92 @@ import (
 	"GoImageBoardArchiver/internal/network"
 )
 
+// 検証結果の保存先（カレントディレクトリ基準）
+const (
+	verificationHistoryPath = "verification_history.json"
+	// VerificationReportPath は、直近の検証で見つかった問題の一覧を保存するファイルです。
+	// Web UIの検証結果ページはこのファイルを参照します。
+	VerificationReportPath = "verification_report.json"
+)
+
 // VerificationResult は検証結果を表します。
 type VerificationResult struct {
 	TotalChecked   int
 	TotalMissing   int
 	TotalRepaired  int
 	TotalFailed    int
+	TotalSkipped   int // VerificationFilter の期間外のためスキップしたスレッド数
 	MissingDetails []string
+	Issues         []VerificationIssue
 }
 
-// RunVerification は指定されたタスク（または全タスク）に対して検証と修復を実行します。
-func RunVerification(ctx context.Context, cfg *config.Config, targetTaskName string, repair bool, force bool) error {
+// VerificationIssue は、検証で見つかった単一スレッドの問題を表します。
+// UIからスレッドフォルダを開いたり、対象を絞った修復を実行するために必要な情報を保持します。
+type VerificationIssue struct {
+	TaskName  string   `json:"task_name"`
+	ThreadID  string   `json:"thread_id"`
+	ThreadDir string   `json:"thread_dir"`
+	Problems  []string `json:"problems"`
+	Repaired  bool     `json:"repaired"`
+}
+
+// VerificationReport は、検証結果をファイルに保存するための構造体です。
+type VerificationReport struct {
+	GeneratedAt time.Time           `json:"generated_at"`
+	Issues      []VerificationIssue `json:"issues"`
+}
+
+// VerificationFilter は、検証の対象を絞り込む条件です。
+// 期間はスレッドの最終更新日時（thread.json の updated_at。ない場合はスナップショットの確認日時、ディレクトリの更新日時）で判定します。
+type VerificationFilter struct {
+	TaskName string    // 対象のタスク名（空の場合はすべてのタスク）
+	Since    time.Time // この日時以降に更新されたスレッドのみ対象にします（ゼロ値の場合は制限なし）
+	Until    time.Time // この日時より前に更新されたスレッドのみ対象にします（ゼロ値の場合は制限なし）
+}
+
+// hasPeriod は、期間による絞り込みが指定されているかを返します。
+func (f VerificationFilter) hasPeriod() bool {
+	return !f.Since.IsZero() || !f.Until.IsZero()
+}
+
+// includes は、updatedAt に更新されたスレッドが検証の対象かを判定します。
+func (f VerificationFilter) includes(updatedAt time.Time) bool {
+	if !f.Since.IsZero() && updatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !updatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// threadUpdatedAt は、スレッドの最終更新日時を返します。
+// thread.json の updated_at、スナップショットの last_checked、ディレクトリの更新日時の順に使用し、いずれも取得できない場合は false を返します。
+func threadUpdatedAt(threadDir string) (time.Time, bool) {
+	if meta, err := LoadThreadMetadata(threadDir); err == nil && meta != nil && !meta.UpdatedAt.IsZero() {
+		return meta.UpdatedAt, true
+	}
+	if snapshot, err := LoadThreadSnapshot(threadDir); err == nil && snapshot != nil && !snapshot.LastChecked.IsZero() {
+		return snapshot.LastChecked, true
+	}
+	if info, err := os.Stat(threadDir); err == nil {
+		return info.ModTime(), true
+	}
+	return time.Time{}, false
+}
+
+// RunVerification は、filter に一致するタスクとスレッドに対して検証と修復を実行します。
+func RunVerification(ctx context.Context, cfg *config.Config, filter VerificationFilter, repair bool, force bool) error {
 	log.Println("検証モードを開始します...")
 	if repair {
 		log.Println("修復モード: 有効 (欠損ファイルを再ダウンロードします)")
 	} else {
 		log.Println("修復モード: 無効 (検証のみ行います)")
 	}
+	if filter.hasPeriod() {
+		log.Printf("対象期間: %s 〜 %s (スレッドの最終更新日時)", formatFilterTime(filter.Since), formatFilterTime(filter.Until))
+	}
 
-	// 検証履歴のパスを固定
-	verificationHistoryPath := "verification_history.json"
 	verificationHistory, err := loadVerificationHistory(verificationHistoryPath)
 	if err != nil {
 		log.Printf("WARNING: 検証履歴の読み込みに失敗しました: %v", err)
@@ -43,12 +110,21 @@ func RunVerification(ctx context.Context, cfg *config.Config, targetTaskName str
 	totalResult := VerificationResult{}
 
 	for _, task := range cfg.Tasks {
-		if targetTaskName != "" && task.TaskName != targetTaskName {
+		if filter.TaskName != "" && task.TaskName != filter.TaskName {
 			continue
 		}
 
 		log.Printf("タスク '%s' の検証を開始します...", task.TaskName)
-		result, err := verifyTask(ctx, task, cfg.Network, repair, force, verificationHistory)
+		if repair {
+			// タイトル変更などで分割されたアーカイブを先に統合する
+			merged, err := ReconcileSplitArchives(task.SaveRootDirectory, log.Default())
+			if err != nil {
+				log.Printf("WARNING: タスク '%s' の分割アーカイブの統合に失敗しました: %v", task.TaskName, err)
+			} else if merged > 0 {
+				log.Printf("タスク '%s': 分割されていた %d 件のスレッドを統合しました", task.TaskName, merged)
+			}
+		}
+		result, err := verifyTask(ctx, task, cfg.Network, filter, repair, force, verificationHistory)
 		if err != nil {
 			log.Printf("ERROR: タスク '%s' の検証中にエラーが発生しました: %v", task.TaskName, err)
 		}
@@ -57,7 +133,9 @@ func RunVerification(ctx context.Context, cfg *config.Config, targetTaskName str
 		totalResult.TotalMissing += result.TotalMissing
 		totalResult.TotalRepaired += result.TotalRepaired
 		totalResult.TotalFailed += result.TotalFailed
+		totalResult.TotalSkipped += result.TotalSkipped
 		totalResult.MissingDetails = append(totalResult.MissingDetails, result.MissingDetails...)
+		totalResult.Issues = append(totalResult.Issues, result.Issues...)
 	}
 
 	// 検証履歴の保存
@@ -65,9 +143,18 @@ func RunVerification(ctx context.Context, cfg *config.Config, targetTaskName str
 		log.Printf("ERROR: 検証履歴の保存に失敗しました: %v", err)
 	}
 
+	// UIから参照できるよう検証レポートを保存
+	report := &VerificationReport{GeneratedAt: time.Now(), Issues: totalResult.Issues}
+	if err := SaveVerificationReport(VerificationReportPath, report); err != nil {
+		log.Printf("ERROR: 検証レポートの保存に失敗しました: %v", err)
+	}
+
 	log.Println("========================================")
 	log.Println("検証完了")
 	log.Printf("チェック済みスレッド数: %d", totalResult.TotalChecked)
+	if filter.hasPeriod() {
+		log.Printf("対象期間外: %d", totalResult.TotalSkipped)
+	}
 	log.Printf("欠損あり: %d", totalResult.TotalMissing)
 	if repair {
 		log.Printf("修復成功: %d", totalResult.TotalRepaired)
@@ -78,13 +165,22 @@ func RunVerification(ctx context.Context, cfg *config.Config, targetTaskName str
 		for _, detail := range totalResult.MissingDetails {
 			log.Println(detail)
 		}
+		log.Println("問題のあるスレッドは、システムトレイの「検証結果を開く」からフォルダを開いたり個別に修復できます。")
 	}
 	log.Println("========================================")
 
 	return nil
 }
 
-func verifyTask(ctx context.Context, task config.Task, netSettings config.NetworkSettings, repair bool, force bool, history map[string]time.Time) (VerificationResult, error) {
+// formatFilterTime は、対象期間の端をログ用の文字列にします。ゼロ値は制限なしです。
+func formatFilterTime(t time.Time) string {
+	if t.IsZero() {
+		return "(制限なし)"
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+func verifyTask(ctx context.Context, task config.Task, netSettings config.NetworkSettings, filter VerificationFilter, repair bool, force bool, history map[string]time.Time) (VerificationResult, error) {
 	result := VerificationResult{}
 
 	if task.SaveRootDirectory == "" {
@@ -97,25 +193,16 @@ func verifyTask(ctx context.Context, task config.Task, netSettings config.Networ
 	}
 
 	// クライアントとアダプタの準備 (修復用)
-	var client *network.Client
-	var siteAdapter adapter.SiteAdapter
+	var repairer *threadRepairer
 	if repair {
-		var err error
-		client, err = network.NewClient(netSettings)
-		if err != nil {
-			return result, fmt.Errorf("クライアントの初期化に失敗しました: %w", err)
-		}
-		siteAdapter, err = adapter.GetAdapter(task.SiteAdapter)
-		if err != nil {
-			return result, fmt.Errorf("アダプタの取得に失敗しました: %w", err)
-		}
-		if err = siteAdapter.Prepare(client, task); err != nil {
-			return result, fmt.Errorf("アダプタの準備に失敗しました: %w", err)
+		if repairer, err = prepareRepair(task, netSettings); err != nil {
+			return result, err
 		}
 	}
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		// 期間ごとの一覧ページ（_archive）・板の情報（_board_meta）・内部状態（.giba）はスレッドのディレクトリではない
+		if !entry.IsDir() || entry.Name() == rollupDirName || entry.Name() == boardMetaDirName || entry.Name() == stateDirName {
 			continue
 		}
 
@@ -126,89 +213,278 @@ func verifyTask(ctx context.Context, task config.Task, netSettings config.Networ
 		}
 
 		threadDir := filepath.Join(task.SaveRootDirectory, entry.Name())
-		// スレッドIDはディレクトリ名から取得することを試みる
-		// より堅牢な方法はスナップショットファイルから読み込むこと
-		threadID := entry.Name()
-		if snapshot, err := LoadThreadSnapshot(threadDir); err == nil {
-			threadID = snapshot.ThreadID
+		// 期間が指定されている場合、対象期間外のスレッドは index.htm やメディアを確認せずにスキップする
+		if filter.hasPeriod() {
+			if updatedAt, ok := threadUpdatedAt(threadDir); ok && !filter.includes(updatedAt) {
+				result.TotalSkipped++
+				continue
+			}
 		}
+		verifyThreadDir(ctx, task, threadDir, entry.Name(), repairer, force, history, &result)
+	}
 
-		result.TotalChecked++
+	return result, nil
+}
 
-		// forceフラグがない場合、最近検証済みのスレッドはスキップ
-		if !force {
-			if lastVerified, ok := history[threadID]; ok {
-				if time.Since(lastVerified) < 24*time.Hour {
-					continue
-				}
-			}
-		}
+// threadRepairer は、検証で見つかった欠損ファイルを掲示板から取得し直すためのクライアントとサイトアダプタです。
+type threadRepairer struct {
+	task    config.Task
+	client  *network.Client
+	adapter adapter.SiteAdapter
+}
+
+// prepareRepair は、修復に使用するネットワーククライアントとサイトアダプタを準備します。
+func prepareRepair(task config.Task, netSettings config.NetworkSettings) (*threadRepairer, error) {
+	client, err := network.NewClient(netSettings)
+	if err != nil {
+		return nil, fmt.Errorf("クライアントの初期化に失敗しました: %w", err)
+	}
+	siteAdapter, err := adapter.GetAdapter(task.SiteAdapter)
+	if err != nil {
+		return nil, fmt.Errorf("アダプタの取得に失敗しました: %w", err)
+	}
+	if err := siteAdapter.Prepare(client, task); err != nil {
+		return nil, fmt.Errorf("アダプタの準備に失敗しました: %w", err)
+	}
+	// 既存のディレクトリに保存し直し、ディスク上に揃っているファイルは取得しない
+	if task.NamingConflictPolicy == "" || task.NamingConflictPolicy == NamingPolicyDuplicate {
+		task.NamingConflictPolicy = NamingPolicyByID
+	}
+	task.EnableResumeSupport = true
+	return &threadRepairer{task: task, client: client, adapter: siteAdapter}, nil
+}
 
-		// index.htmの確認
-		indexFiles := []string{"index.htm", "index.html"}
-		var indexFound bool
-		for _, name := range indexFiles {
-			path := filepath.Join(threadDir, name)
-			if content, err := os.ReadFile(path); err == nil && len(content) > 0 {
-				indexFound = true
-				break
+// rearchive は、再アーカイブと同じ手順でスレッドを取得し直し、ディスク上にないファイルをダウンロードします。
+// スレッドが既に落ちている場合などはエラーを返します。
+func (r *threadRepairer) rearchive(ctx context.Context, threadDir, threadID string) error {
+	if err := MarkForRearchive(threadDir, threadID); err != nil {
+		return fmt.Errorf("スレッド %s の再アーカイブ準備に失敗しました: %w", threadID, err)
+	}
+	result := ArchiveSingleThread(ctx, r.client, r.adapter, r.task, rearchiveThreadInfo(threadID, threadDir), log.Default())
+	if result.Error != nil {
+		return result.Error
+	}
+	if !result.Success {
+		return fmt.Errorf("スレッド %s を取得できませんでした", threadID)
+	}
+	return nil
+}
+
+// verifyThreadDir は、単一のスレッドディレクトリを検証（および repairer が nil でない場合は修復）し、結果を result に加算します。
+// 問題が見つかった場合は、UIから操作できるよう VerificationIssue として記録します。
+func verifyThreadDir(ctx context.Context, task config.Task, threadDir, dirName string, repairer *threadRepairer, force bool,
+	history map[string]time.Time, result *VerificationResult) {
+	// スレッドIDはディレクトリ名から取得することを試みる
+	// より堅牢な方法はスナップショットファイルから読み込むこと
+	threadID := dirName
+	if snapshot, err := LoadThreadSnapshot(threadDir); err == nil && snapshot != nil {
+		threadID = snapshot.ThreadID
+	}
+
+	result.TotalChecked++
+
+	// forceフラグがない場合、最近検証済みのスレッドはスキップ
+	if !force {
+		if lastVerified, ok := history[threadID]; ok {
+			if time.Since(lastVerified) < 24*time.Hour {
+				return
 			}
 		}
+	}
 
-		if !indexFound {
-			log.Printf("WARNING: スレッド %s (%s) のindex.htmが見つかりません", threadID, threadDir)
-			result.TotalMissing++
-			result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] index.htm消失", threadID))
-			// index.htmがないと修復は困難
-			if repair {
+	issue := VerificationIssue{TaskName: task.TaskName, ThreadID: threadID, ThreadDir: threadDir}
+
+	if !hasThreadIndex(threadDir) {
+		log.Printf("WARNING: スレッド %s (%s) のindex.htmが見つかりません", threadID, threadDir)
+		result.TotalMissing++
+		result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] index.htm消失", threadID))
+		issue.Problems = append(issue.Problems, "index.htm消失")
+		if repairer != nil {
+			// スレッドがまだ残っていれば、再アーカイブで index.htm を作り直す
+			if err := repairer.rearchive(ctx, threadDir, threadID); err != nil {
+				log.Printf("WARNING: スレッド %s の再取得に失敗しました: %v", threadID, err)
+			}
+			if hasThreadIndex(threadDir) {
+				result.TotalRepaired++
+				issue.Repaired = true
+			} else {
 				result.TotalFailed++
 			}
-			continue
 		}
+		result.Issues = append(result.Issues, issue)
+		return
+	}
+
+	// 簡易実装: ディレクトリ内のファイルサイズが0のものを検出
+	imgDir := filepath.Join(threadDir, "img")
+	files, err := os.ReadDir(imgDir)
+	if err != nil {
+		return // imgディレクトリがなければスキップ
+	}
 
-		// 簡易実装: ディレクトリ内のファイルサイズが0のものを検出
-		imgDir := filepath.Join(threadDir, "img")
-		files, err := os.ReadDir(imgDir)
+	var broken []string
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
 		if err != nil {
-			continue // imgディレクトリがなければスキップ
+			continue
+		}
+		if info.Size() == 0 {
+			broken = append(broken, file.Name())
+			log.Printf("WARNING: スレッド %s のファイル %s がサイズ0です", threadID, filepath.Join(imgDir, file.Name()))
 		}
+	}
 
-		missingCount := 0
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			info, err := file.Info()
-			if err != nil {
-				continue
-			}
-			if info.Size() == 0 {
-				missingCount++
-				filePath := filepath.Join(imgDir, file.Name())
-				log.Printf("WARNING: スレッド %s のファイル %s がサイズ0です", threadID, filePath)
-
-				if repair {
-					// 修復ロジックは複雑なため、今回は破損ファイルの削除のみ
-					os.Remove(filePath)
-					result.TotalFailed++ // 再ダウンロード機能がないためFailed扱い
-					result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] 破損ファイル削除: %s", threadID, file.Name()))
-				} else {
-					result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] 破損ファイル: %s", threadID, file.Name()))
-				}
-			}
+	if len(broken) == 0 {
+		// 問題なければ検証履歴を更新
+		history[threadID] = time.Now()
+		return
+	}
+	result.TotalMissing++
+
+	if repairer == nil {
+		for _, name := range broken {
+			result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] 破損ファイル: %s", threadID, name))
+			issue.Problems = append(issue.Problems, fmt.Sprintf("破損ファイル: %s", name))
 		}
+		result.Issues = append(result.Issues, issue)
+		return
+	}
 
-		if missingCount > 0 {
-			result.TotalMissing++
-		} else {
-			// 問題なければ検証履歴を更新
-			history[threadID] = time.Now()
+	// 破損ファイルを削除してから再アーカイブし、ディスク上にないファイルとして取得し直す
+	for _, name := range broken {
+		if err := os.Remove(filepath.Join(imgDir, name)); err != nil {
+			log.Printf("WARNING: 破損ファイルの削除に失敗しました (path=%s): %v", filepath.Join(imgDir, name), err)
 		}
 	}
+	if err := repairer.rearchive(ctx, threadDir, threadID); err != nil {
+		log.Printf("WARNING: スレッド %s の再取得に失敗しました: %v", threadID, err)
+	}
+	issue.Repaired = true
+	for _, name := range broken {
+		if info, err := os.Stat(filepath.Join(imgDir, name)); err == nil && info.Size() > 0 {
+			result.TotalRepaired++
+			result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] 破損ファイル再取得: %s", threadID, name))
+			issue.Problems = append(issue.Problems, fmt.Sprintf("破損ファイル再取得: %s", name))
+			continue
+		}
+		result.TotalFailed++
+		issue.Repaired = false
+		result.MissingDetails = append(result.MissingDetails, fmt.Sprintf("[%s] 破損ファイル再取得失敗: %s", threadID, name))
+		issue.Problems = append(issue.Problems, fmt.Sprintf("破損ファイル再取得失敗: %s", name))
+	}
+	result.Issues = append(result.Issues, issue)
+}
+
+// hasThreadIndex は、スレッドディレクトリに空でない index.htm（または index.html）があるかを判定します。
+func hasThreadIndex(threadDir string) bool {
+	for _, name := range []string{"index.htm", "index.html"} {
+		if info, err := os.Stat(filepath.Join(threadDir, name)); err == nil && info.Size() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RepairThread は、指定されたタスクの単一スレッドディレクトリに対して対象を絞った修復を実行します。
+// Web UIの検証結果ページから呼び出され、実行後は保存済みの検証レポートから該当スレッドの問題を更新します。
+func RepairThread(ctx context.Context, cfg *config.Config, taskName string, threadDir string) (VerificationResult, error) {
+	result := VerificationResult{}
+
+	var task *config.Task
+	for i := range cfg.Tasks {
+		if cfg.Tasks[i].TaskName == taskName {
+			task = &cfg.Tasks[i]
+			break
+		}
+	}
+	if task == nil {
+		return result, fmt.Errorf("タスク '%s' が見つかりません", taskName)
+	}
+
+	// タスクの保存先配下以外のディレクトリは操作しない
+	if !isThreadDirOf(task.SaveRootDirectory, threadDir) {
+		return result, fmt.Errorf("スレッドディレクトリ '%s' はタスク '%s' の保存先配下にありません", threadDir, taskName)
+	}
+
+	select {
+	case <-ctx.Done():
+		return result, ctx.Err()
+	default:
+	}
+
+	repairer, err := prepareRepair(*task, cfg.Network)
+	if err != nil {
+		return result, err
+	}
+
+	history, err := loadVerificationHistory(verificationHistoryPath)
+	if err != nil {
+		log.Printf("WARNING: 検証履歴の読み込みに失敗しました: %v", err)
+		history = make(map[string]time.Time)
+	}
+	verifyThreadDir(ctx, *task, threadDir, filepath.Base(threadDir), repairer, true, history, &result)
+	if err := saveVerificationHistory(verificationHistoryPath, history); err != nil {
+		log.Printf("ERROR: 検証履歴の保存に失敗しました: %v", err)
+	}
+
+	// 保存済みレポートの該当エントリを置き換える
+	report, err := LoadVerificationReport(VerificationReportPath)
+	if err != nil {
+		return result, fmt.Errorf("検証レポートの読み込みに失敗しました: %w", err)
+	}
+	issues := make([]VerificationIssue, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		if issue.ThreadDir != threadDir {
+			issues = append(issues, issue)
+		}
+	}
+	report.Issues = append(issues, result.Issues...)
+	if err := SaveVerificationReport(VerificationReportPath, report); err != nil {
+		return result, fmt.Errorf("検証レポートの保存に失敗しました: %w", err)
+	}
 
 	return result, nil
 }
 
+// isThreadDirOf は、threadDir が保存先 root 配下のディレクトリ（root 自身を除く）かを判定します。
+// "..foo" のような名前のディレクトリは配下として扱います。
+func isThreadDirOf(root, threadDir string) bool {
+	rel, err := filepath.Rel(root, threadDir)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// LoadVerificationReport は、保存済みの検証レポートを読み込みます。
+// ファイルが存在しない場合は空のレポートを返します。
+func LoadVerificationReport(path string) (*VerificationReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &VerificationReport{}, nil
+		}
+		return nil, fmt.Errorf("検証レポートの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	var report VerificationReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("検証レポートのパースに失敗しました (path=%s): %w", path, err)
+	}
+	return &report, nil
+}
+
+// SaveVerificationReport は、検証レポートをJSONファイルとして保存します。
+func SaveVerificationReport(path string, report *VerificationReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("検証レポートのシリアライズに失敗しました: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("検証レポートの書き込みに失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
+
 func loadVerificationHistory(path string) (map[string]time.Time, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
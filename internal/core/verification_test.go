@@ -0,0 +1,97 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerificationFilter_Includes(t *testing.T) {
+	t.Parallel()
+
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb1 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		filter    VerificationFilter
+		updatedAt time.Time
+		want      bool
+	}{
+		{name: "制限なし", filter: VerificationFilter{}, updatedAt: jan1.AddDate(-1, 0, 0), want: true},
+		{name: "since以降", filter: VerificationFilter{Since: jan1}, updatedAt: jan1, want: true},
+		{name: "since より前", filter: VerificationFilter{Since: jan1}, updatedAt: jan1.Add(-time.Second), want: false},
+		{name: "until より前", filter: VerificationFilter{Until: feb1}, updatedAt: feb1.Add(-time.Second), want: true},
+		{name: "until ちょうど", filter: VerificationFilter{Until: feb1}, updatedAt: feb1, want: false},
+		{name: "期間内", filter: VerificationFilter{Since: jan1, Until: feb1}, updatedAt: jan1.AddDate(0, 0, 15), want: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.filter.includes(tt.updatedAt); got != tt.want {
+				t.Errorf("includes(%v) = %v, want %v", tt.updatedAt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadUpdatedAt(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	withMeta := filepath.Join(dir, "100")
+	if err := os.MkdirAll(withMeta, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(withMeta, threadMetadataFile), []byte(`{"thread_id": "100", "updated_at": "2024-01-15T12:00:00Z"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := threadUpdatedAt(withMeta)
+	if want := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("threadUpdatedAt() = %v, %v, want %v", got, ok, want)
+	}
+
+	// thread.json がない場合はディレクトリの更新日時
+	noMeta := filepath.Join(dir, "200")
+	if err := os.MkdirAll(noMeta, 0755); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(noMeta, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := threadUpdatedAt(noMeta); !ok || !got.Equal(modTime) {
+		t.Errorf("threadUpdatedAt() = %v, %v, want %v", got, ok, modTime)
+	}
+
+	if _, ok := threadUpdatedAt(filepath.Join(dir, "missing")); ok {
+		t.Error("存在しないディレクトリで日時が返されました")
+	}
+}
+
+func TestIsThreadDirOf(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join(string(filepath.Separator), "archive", "b")
+	tests := []struct {
+		name      string
+		threadDir string
+		want      bool
+	}{
+		{name: "スレッドディレクトリ", threadDir: filepath.Join(root, "1001"), want: true},
+		{name: "..で始まる名前", threadDir: filepath.Join(root, "..foo"), want: true},
+		{name: "保存先そのもの", threadDir: root, want: false},
+		{name: "親ディレクトリ", threadDir: filepath.Dir(root), want: false},
+		{name: "保存先の外", threadDir: filepath.Join(filepath.Dir(root), "other", "1001"), want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isThreadDirOf(root, tt.threadDir); got != tt.want {
+				t.Errorf("isThreadDirOf(%q, %q) = %v, want %v", root, tt.threadDir, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"math/rand"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// jitteredInterval は、interval を watch_jitter_percent の範囲（±N%）でランダムに揺らした待機時間を返します。
+// 同じ間隔の複数のタスクのカタログ取得が、サイクルを重ねても同じ時刻に集中しないようにします。
+func jitteredInterval(interval time.Duration, percent int) time.Duration {
+	return applyJitter(interval, percent, rand.Float64())
+}
+
+// applyJitter は、r（0以上1未満）に応じて interval を ±percent% の範囲で揺らします。r が0.5のとき interval そのものです。
+func applyJitter(interval time.Duration, percent int, r float64) time.Duration {
+	if percent <= 0 || interval <= 0 {
+		return interval
+	}
+	spread := float64(interval) * float64(percent) / 100
+	return interval + time.Duration(spread*(2*r-1))
+}
+
+// staggerTaskStarts は、監視モードで開始する tasks のうち watch_jitter_percent が設定されたタスクの StartOffset を設定します。
+// 対象のタスクの開始を、それぞれの監視間隔の watch_jitter_percent% の範囲に等間隔でずらし、最初のカタログ取得が同時に行われないようにします。
+// tasks は変更せず、StartOffset を設定したコピーを返します。
+func staggerTaskStarts(tasks []config.Task) []config.Task {
+	staggered := append([]config.Task(nil), tasks...)
+	var targets []int
+	for i, task := range staggered {
+		if task.Enabled != nil && *task.Enabled && task.WatchJitterPercent > 0 {
+			targets = append(targets, i)
+		}
+	}
+	for k, i := range targets {
+		task := &staggered[i]
+		window := newPollScheduler(*task).cycleInterval(watchInterval(*task)) * time.Duration(task.WatchJitterPercent) / 100
+		task.StartOffset = window * time.Duration(k) / time.Duration(len(targets))
+	}
+	return staggered
+}
@@ -0,0 +1,58 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestApplyJitter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		percent int
+		r       float64
+		want    time.Duration
+	}{
+		{name: "ジッターなし", percent: 0, r: 0.9, want: 10 * time.Minute},
+		{name: "下限", percent: 10, r: 0, want: 9 * time.Minute},
+		{name: "中央", percent: 10, r: 0.5, want: 10 * time.Minute},
+		{name: "上限に近い値", percent: 20, r: 0.75, want: 11 * time.Minute},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := applyJitter(10*time.Minute, tt.percent, tt.r); got != tt.want {
+				t.Errorf("applyJitter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaggerTaskStarts(t *testing.T) {
+	t.Parallel()
+
+	enabled, disabled := true, false
+	tasks := []config.Task{
+		{TaskName: "a", Enabled: &enabled, WatchIntervalMillis: 600000, WatchJitterPercent: 30},
+		{TaskName: "b", Enabled: &enabled, WatchIntervalMillis: 600000},
+		{TaskName: "c", Enabled: &disabled, WatchIntervalMillis: 600000, WatchJitterPercent: 30},
+		{TaskName: "d", Enabled: &enabled, WatchIntervalMillis: 600000, WatchJitterPercent: 30},
+		{TaskName: "e", Enabled: &enabled, WatchIntervalMillis: 600000, WatchJitterPercent: 30},
+	}
+	got := staggerTaskStarts(tasks)
+
+	// ジッターを設定した有効なタスク（a, d, e）の開始を 600秒の30% = 180秒の範囲に等間隔でずらす
+	want := []time.Duration{0, 0, 0, time.Minute, 2 * time.Minute}
+	for i, task := range got {
+		if task.StartOffset != want[i] {
+			t.Errorf("task %s StartOffset = %v, want %v", task.TaskName, task.StartOffset, want[i])
+		}
+	}
+	if tasks[4].StartOffset != 0 {
+		t.Error("staggerTaskStarts() が元のスライスを変更しました")
+	}
+}
@@ -0,0 +1,33 @@
+// Package errs は、GIBA全体（アダプタ、ネットワーク、コア）で共有する分類済みのエラーを定義します。
+// 各層はこれらのセンチネルエラーを %w でラップして返すため、呼び出し側（エンジン、UI、リトライ処理）は
+// errors.Is / errors.As を使ってエラーの種類ごとに処理を分岐できます。
+package errs
+
+import (
+	"errors"
+)
+
+var (
+	// ErrThreadGone は、スレッドが既に削除された（落ちた）ことを表します。HTTP 404/410 が該当します。
+	ErrThreadGone = errors.New("スレッドは既に存在しません")
+	// ErrFiltered は、スレッドがフィルタ条件により処理対象外となったことを表します。
+	ErrFiltered = errors.New("フィルタ条件により除外されました")
+	// ErrDiskFull は、ディスクの空き容量がセーフティーストップの閾値を下回ったことを表します。
+	ErrDiskFull = errors.New("ディスクの空き容量が不足しています")
+	// ErrRateLimited は、サーバーのレート制限（HTTP 429）に達したことを表します。
+	ErrRateLimited = errors.New("レート制限に達しました")
+	// ErrTimeout は、ネットワークリクエストがタイムアウトしたことを表します。
+	ErrTimeout = errors.New("リクエストがタイムアウトしました")
+	// ErrServer は、サーバー側のエラー（HTTP 5xx）を表します。
+	ErrServer = errors.New("サーバーエラーが発生しました")
+	// ErrWriteFailed は、ダウンロードしたデータのディスクへの書き込みに失敗したことを表します。
+	ErrWriteFailed = errors.New("ファイルの書き込みに失敗しました")
+	// ErrLayoutChanged は、以前は解析できていたページから何も抽出できなくなったことを表します。
+	// 掲示板ソフトの更新などでサイトの構造が変わり、アダプタの修正が必要な可能性があります。
+	ErrLayoutChanged = errors.New("サイトの構造が変更された可能性があります")
+	// ErrAuthFailed は、パスワード付き・会員制の掲示板へのログイン（認証）に失敗したことを表します。
+	ErrAuthFailed = errors.New("掲示板の認証に失敗しました")
+	// ErrMaintenance は、掲示板がメンテナンス中・障害中の表示を（HTTP 200 で）返したことを表します。
+	// 表示をスレッドやカタログとして処理せず、タスクを一時停止するために使用します。
+	ErrMaintenance = errors.New("掲示板がメンテナンス中です")
+)
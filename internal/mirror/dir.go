@@ -0,0 +1,72 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// dirStore は、ローカルのディレクトリ（ネットワークドライブ・外付けディスクなど）のミラーです。
+type dirStore struct {
+	root string
+}
+
+func newDirStore(root string) (*dirStore, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("ミラーのディレクトリ '%s' を開けません: %w", root, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("ミラー '%s' はディレクトリではありません", root)
+	}
+	return &dirStore{root: root}, nil
+}
+
+func (s *dirStore) List(ctx context.Context, dir string) (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	base := filepath.Join(s.root, filepath.FromSlash(dir))
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == base && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		entries[filepath.ToSlash(rel)] = Entry{Size: info.Size()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ミラーのディレクトリ '%s' の一覧を取得できません: %w", base, err)
+	}
+	return entries, nil
+}
+
+func (s *dirStore) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.root, filepath.FromSlash(name)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+func (s *dirStore) String() string {
+	return s.root
+}
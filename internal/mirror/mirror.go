@@ -0,0 +1,95 @@
+// Package mirror は、アーカイブの複製（別のディレクトリ・S3 互換のバケット・WebDAV）のファイルを読み取り専用で参照します。
+// ミラーとの照合（--verify-mirror）で、ローカルのアーカイブと複製の差分を調べるために使用します。
+// 複製への書き込みや削除は行いません。
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"GoImageBoardArchiver/internal/secrets"
+)
+
+// ErrNotExist は、ミラーに指定されたファイルがないことを表します。
+var ErrNotExist = errors.New("ミラーにファイルがありません")
+
+// Entry は、ミラーのファイルの情報です。
+type Entry struct {
+	Size int64
+	// MD5 は、一覧から内容のハッシュが得られる場合の MD5（16進数）です（S3 の単一パートでアップロードしたオブジェクトの ETag）。
+	// 空の場合は、内容を読み込んで照合します。
+	MD5 string
+}
+
+// Store は、ミラーのファイルを参照します。パスはミラーのルートからのスラッシュ区切りの相対パスです。
+type Store interface {
+	// List は、dir 以下（サブディレクトリを含む）のファイルを、ミラーのルートからの相対パスをキーとして返します。
+	// dir がない場合は空の一覧を返します。
+	List(ctx context.Context, dir string) (map[string]Entry, error)
+	// Open は、ファイルの内容を返します。ファイルがない場合は ErrNotExist を返します。
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	// String は、ログに表示するミラーの場所です（認証情報は含みません）。
+	String() string
+}
+
+// ミラーの認証情報のシークレット名（環境変数 GIBA_SECRET_<名前> またはシークレットファイルで指定）
+const (
+	SecretS3AccessKeyID     = "mirror_s3_access_key_id"
+	SecretS3SecretAccessKey = "mirror_s3_secret_access_key"
+	SecretS3SessionToken    = "mirror_s3_session_token"
+	SecretWebDAVUsername    = "mirror_webdav_username"
+	SecretWebDAVPassword    = "mirror_webdav_password"
+)
+
+// lookupSecret は、シークレットを取得します。テストで差し替えられるよう変数にしています。
+var lookupSecret = secrets.Lookup
+
+// Open は、target のミラーを返します。target は次のいずれかです。
+//   - s3://<バケット>/<接頭辞>?region=<リージョン>&endpoint=<エンドポイント>（S3 互換のストレージ。endpoint は省略時 AWS）
+//   - http:// または https:// の URL（WebDAV）
+//   - それ以外はローカルのディレクトリ（ネットワークドライブ・外付けディスクなど）
+func Open(target string) (Store, error) {
+	switch {
+	case strings.HasPrefix(target, "s3://"):
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("ミラーの URL '%s' を解析できません: %w", target, err)
+		}
+		return newS3Store(u, http.DefaultClient)
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("ミラーの URL '%s' を解析できません: %w", target, err)
+		}
+		return newWebDAVStore(u, http.DefaultClient)
+	case target == "":
+		return nil, fmt.Errorf("ミラーの場所が指定されていません")
+	default:
+		return newDirStore(target)
+	}
+}
+
+// optionalSecret は、シークレットを取得します。設定されていない場合は空文字列を返します。
+func optionalSecret(name string) (string, error) {
+	value, err := lookupSecret(name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return "", nil
+	}
+	return value, err
+}
+
+// joinPath は、スラッシュ区切りの相対パスを連結します。空の要素は無視します。
+func joinPath(elems ...string) string {
+	var parts []string
+	for _, e := range elems {
+		if e = strings.Trim(e, "/"); e != "" {
+			parts = append(parts, e)
+		}
+	}
+	return strings.Join(parts, "/")
+}
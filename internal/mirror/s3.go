@@ -0,0 +1,259 @@
+package mirror
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadSHA256 は、ボディのないリクエストの x-amz-content-sha256 です。
+const emptyPayloadSHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// s3Store は、S3 互換のストレージ（AWS S3・MinIO・Cloudflare R2 など）のバケットのミラーです。
+// リクエストは署名バージョン4で署名し、バケットはパス形式（<エンドポイント>/<バケット>/<キー>）で指定します。
+type s3Store struct {
+	client       *http.Client
+	endpoint     *url.URL
+	bucket       string
+	prefix       string
+	region       string
+	accessKeyID  string
+	secretKey    string
+	sessionToken string
+	now          func() time.Time
+}
+
+func newS3Store(u *url.URL, client *http.Client) (*s3Store, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("ミラーの URL '%s' にバケット名がありません", u.Redacted())
+	}
+	q := u.Query()
+	region := q.Get("region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := q.Get("endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	ep, err := url.Parse(endpoint)
+	if err != nil || ep.Host == "" {
+		return nil, fmt.Errorf("ミラーのエンドポイント '%s' が不正です", endpoint)
+	}
+
+	s := &s3Store{
+		client:   client,
+		endpoint: ep,
+		bucket:   u.Host,
+		prefix:   strings.Trim(u.Path, "/"),
+		region:   region,
+		now:      time.Now,
+	}
+	if s.accessKeyID, err = lookupSecret(SecretS3AccessKeyID); err != nil {
+		return nil, fmt.Errorf("S3 のアクセスキー（シークレット %s）を取得できません: %w", SecretS3AccessKeyID, err)
+	}
+	if s.secretKey, err = lookupSecret(SecretS3SecretAccessKey); err != nil {
+		return nil, fmt.Errorf("S3 のシークレットキー（シークレット %s）を取得できません: %w", SecretS3SecretAccessKey, err)
+	}
+	if s.sessionToken, err = optionalSecret(SecretS3SessionToken); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// listBucketResult は、ListObjectsV2 のレスポンスのうち使用する項目です。
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+		ETag string `xml:"ETag"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *s3Store) List(ctx context.Context, dir string) (map[string]Entry, error) {
+	prefix := joinPath(s.prefix, dir)
+	if prefix != "" {
+		prefix += "/"
+	}
+	entries := make(map[string]Entry)
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, "/"+s.bucket, q)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("S3 のオブジェクト一覧を解析できません: %w", err)
+		}
+		for _, obj := range result.Contents {
+			if strings.HasSuffix(obj.Key, "/") {
+				continue // ディレクトリの代わりに作成された空のオブジェクト
+			}
+			name := strings.TrimPrefix(strings.TrimPrefix(obj.Key, s.prefix), "/")
+			entry := Entry{Size: obj.Size}
+			// マルチパートでアップロードしたオブジェクトの ETag（"<ハッシュ>-<パート数>"）は内容の MD5 ではない
+			if etag := strings.Trim(obj.ETag, `"`); len(etag) == 32 && !strings.Contains(etag, "-") {
+				entry.MD5 = strings.ToLower(etag)
+			}
+			entries[name] = entry
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return entries, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *s3Store) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, "/"+joinPath(s.bucket, s.prefix, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *s3Store) String() string {
+	return "s3://" + joinPath(s.bucket, s.prefix)
+}
+
+// do は、署名した GET リクエストを送信します。ステータスが 200 以外の場合はボディを閉じてエラーを返します。
+func (s *s3Store) do(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	// 署名と送信で同じエンコードになるよう、エンコード済みのパスを明示する
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	s.sign(req, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 へのリクエストに失敗しました: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && !strings.Contains(string(body), "NoSuchBucket") {
+		return nil, ErrNotExist
+	}
+	return nil, fmt.Errorf("S3 がエラーを返しました (status=%d, path=%s): %s", resp.StatusCode, path, strings.TrimSpace(string(body)))
+}
+
+// sign は、リクエストに署名バージョン4（AWS4-HMAC-SHA256）の Authorization ヘッダーを付与します。
+// ボディのない GET リクエストのみを対象とし、Host・Range と x-amz- で始まるヘッダーを署名に含めます。
+func (s *s3Store) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadSHA256)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadSHA256,
+	}, "\n")
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery は、署名に使用する形式（キーの順に並べ、RFC 3986 の非予約文字以外をエンコード）のクエリ文字列を返します。
+// 送信するクエリも同じ文字列にして、署名との不一致を防ぎます。
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode は、RFC 3986 の非予約文字（英数字と -_.~）以外をパーセントエンコードします。
+// encodeSlash が false の場合、パスの区切りの / はそのまま残します。
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			sb.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
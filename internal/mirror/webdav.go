@@ -0,0 +1,189 @@
+package mirror
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// propfindBody は、ファイルの一覧の取得で要求するプロパティです。
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/></d:prop></d:propfind>`
+
+// webdavStore は、WebDAV サーバー（Nextcloud・NAS の WebDAV 機能など）のミラーです。
+// 一覧は Depth: 1 の PROPFIND でディレクトリごとに取得します（Depth: infinity は無効にしているサーバーが多いため）。
+type webdavStore struct {
+	client   *http.Client
+	root     *url.URL
+	username string
+	password string
+}
+
+func newWebDAVStore(u *url.URL, client *http.Client) (*webdavStore, error) {
+	root := *u
+	root.User = nil
+	root.RawQuery = ""
+	root.Fragment = ""
+	if !strings.HasSuffix(root.Path, "/") {
+		root.Path += "/"
+	}
+	s := &webdavStore{client: client, root: &root}
+
+	// URL に含めたユーザー名はそのまま使用し、パスワードはシークレットから取得する（URL にパスワードを書かないため）
+	var err error
+	if s.username, err = optionalSecret(SecretWebDAVUsername); err != nil {
+		return nil, err
+	}
+	if u.User != nil && u.User.Username() != "" {
+		s.username = u.User.Username()
+	}
+	if s.password, err = optionalSecret(SecretWebDAVPassword); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// multistatus は、PROPFIND のレスポンスのうち使用する項目です。
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength string `xml:"getcontentlength"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (s *webdavStore) List(ctx context.Context, dir string) (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	pending := []string{joinPath(dir)}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		dirs, err := s.listDir(ctx, current, entries)
+		if errors.Is(err, ErrNotExist) && current == joinPath(dir) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, dirs...)
+	}
+	return entries, nil
+}
+
+// listDir は、dir の直下のファイルを entries に追加し、サブディレクトリの相対パスを返します。
+func (s *webdavStore) listDir(ctx context.Context, dir string, entries map[string]Entry) ([]string, error) {
+	target := s.resolve(dir)
+	if !strings.HasSuffix(target.Path, "/") {
+		target.Path += "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", target.String(), strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("WebDAV の一覧の取得に失敗しました (status=%d, path=%s)", resp.StatusCode, target.Path)
+	}
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("WebDAV の一覧を解析できません (path=%s): %w", target.Path, err)
+	}
+
+	var dirs []string
+	for _, r := range ms.Responses {
+		name, ok := s.relativeName(r.Href)
+		if !ok || name == dir {
+			continue // 一覧の対象のディレクトリ自体
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			if ps.Prop.ResourceType.Collection != nil {
+				dirs = append(dirs, name)
+			} else {
+				size, _ := strconv.ParseInt(strings.TrimSpace(ps.Prop.ContentLength), 10, 64)
+				entries[name] = Entry{Size: size}
+			}
+			break
+		}
+	}
+	return dirs, nil
+}
+
+// relativeName は、PROPFIND のレスポンスの href（絶対URLまたは絶対パス）を、ミラーのルートからの相対パスにします。
+func (s *webdavStore) relativeName(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	p := u.Path
+	if !strings.HasPrefix(p, s.root.Path) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(p, s.root.Path), "/"), true
+}
+
+func (s *webdavStore) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	target := s.resolve(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("WebDAV のファイルの取得に失敗しました (status=%d, path=%s)", resp.StatusCode, target.Path)
+	}
+	return resp.Body, nil
+}
+
+func (s *webdavStore) String() string {
+	return s.root.String()
+}
+
+// resolve は、ミラーのルートからの相対パスの URL を返します。
+func (s *webdavStore) resolve(name string) *url.URL {
+	u := *s.root
+	u.Path = s.root.Path + joinPath(name)
+	u.RawPath = ""
+	return &u
+}
+
+// do は、認証情報を付与してリクエストを送信します。404 の場合はボディを閉じて ErrNotExist を返します。
+func (s *webdavStore) do(req *http.Request) (*http.Response, error) {
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("WebDAV へのリクエストに失敗しました: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotExist
+	}
+	return resp, nil
+}
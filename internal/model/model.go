@@ -21,4 +21,29 @@ type MediaInfo struct {
 	ResNumber        int
 	LocalPath        string
 	LocalThumbPath   string
+	IsAnimated       bool   // フルサイズがアニメーション画像（GIF/APNG/WebP）かどうか
+	SHA256           string // フルサイズのSHA-256（16進数）。ファイル名に {sha256_8} を使用する場合のみ設定
+	Blocked          bool   // blocked_media_patterns に一致したため保存しないメディアかどうか
+}
+
+// Post は、スレッド内の1件のレス（OPを含む）を構造化した情報です。
+type Post struct {
+	ResNumber int
+	Author    string // 名前（トリップを除く）
+	Trip      string // トリップ（◆ を除く）
+	PosterID  string // ID表示（ID:xxxxxxxx の xxxxxxxx）
+	Subject   string
+	Body      string    // 本文のプレーンテキスト（改行は \n）
+	Date      time.Time // 投稿日時。取得できない場合はゼロ値
+	Media     []MediaInfo
+}
+
+// PostFeatures は、レスに付随する掲示板固有の表示（ID・おみくじ・削除・お絵かき）を保持します。
+// スレッドのメタデータ（thread.json）に記録されます。
+type PostFeatures struct {
+	ResNumber int    `json:"res"`
+	PosterID  string `json:"poster_id,omitempty"` // ID表示（ID:xxxxxxxx の xxxxxxxx）
+	Fortune   string `json:"fortune,omitempty"`   // おみくじの結果（大吉 など）
+	Deleted   bool   `json:"deleted,omitempty"`   // 削除されたレスかどうか
+	Oekaki    string `json:"oekaki,omitempty"`    // お絵かきのアニメーション（再生用ファイル）のファイル名
 }
@@ -5,8 +5,10 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -15,6 +17,8 @@ import (
 	"time"
 
 	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+	"GoImageBoardArchiver/internal/version"
 
 	"golang.org/x/time/rate"
 )
@@ -30,6 +34,21 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("HTTP %d: %s (URL: %s)", e.StatusCode, e.Message, e.URL)
 }
 
+// Is は、errors.Is による分類済みエラーとの比較をサポートします。
+// 404/410 は errs.ErrThreadGone、429 は errs.ErrRateLimited、5xx は errs.ErrServer として扱われます。
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case errs.ErrThreadGone:
+		return e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone
+	case errs.ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case errs.ErrServer:
+		return e.StatusCode >= 500 && e.StatusCode < 600
+	default:
+		return false
+	}
+}
+
 // IsRetryable は、このエラーがリトライ可能かどうかを判定します。
 // 4xxエラー（クライアントエラー）はリトライ不可、5xxエラー（サーバーエラー）はリトライ可能とします。
 func (e *HTTPError) IsRetryable() bool {
@@ -48,10 +67,15 @@ type Client struct {
 	httpClient         *http.Client
 	jar                *cookiejar.Jar
 	userAgent          string
+	userAgentMutex     sync.Mutex // userAgentへのアクセスを保護するMutex（SetUserAgent は通信中にも呼び出される）
 	defaultHeaders     map[string]string
 	rateLimiters       map[string]*rate.Limiter // ホスト名ごとのレートリミッター
 	rateLimitersMutex  sync.Mutex               // rateLimitersへのアクセスを保護するMutex
 	perDomainIntervals map[string]int           // ドメインごとの設定間隔
+	preloaded          map[string]string        // Preload で登録した、通信せずに返すレスポンスのボディ（URLごと）
+	preloadedMutex     sync.Mutex               // preloadedへのアクセスを保護するMutex
+	hostQueries        map[string]url.Values    // SetQueryParams で登録した、GETリクエストに追加するクエリパラメータ（ホスト名ごと）
+	hostQueriesMutex   sync.Mutex               // hostQueriesへのアクセスを保護するMutex
 }
 
 // NewClient は NetworkSettings に基づいて HTTP クライアントを初期化し、
@@ -71,6 +95,14 @@ func NewClient(settings config.NetworkSettings) (*Client, error) {
 	httpClient := &http.Client{
 		Jar:     jar,
 		Timeout: timeout, // タイムアウトを設定
+		// 圧縮されたレスポンスを展開し（gzip・deflate・br）、--trace-http 指定時のみ通信を記録する
+		Transport: &decodingTransport{base: &tracingTransport{base: http.DefaultTransport, tracer: sharedTracer}},
+	}
+
+	// User-Agentが未設定の場合は、GIBAとそのバージョンを明示する
+	userAgent := settings.UserAgent
+	if userAgent == "" {
+		userAgent = version.UserAgent()
 	}
 
 	// ドメインごとのレートリミッターを構築
@@ -87,7 +119,7 @@ func NewClient(settings config.NetworkSettings) (*Client, error) {
 	return &Client{
 		httpClient:         httpClient,
 		jar:                jar,
-		userAgent:          settings.UserAgent,
+		userAgent:          userAgent,
 		defaultHeaders:     settings.DefaultHeaders,
 		rateLimiters:       rateLimiters,
 		perDomainIntervals: settings.PerDomainIntervalMillis,
@@ -109,29 +141,172 @@ func (c *Client) SetCookie(domainURL string, cookie *http.Cookie) error {
 	return nil
 }
 
+// Cookies は、指定されたURLへのリクエストで送信されるCookieを返します。
+func (c *Client) Cookies(domainURL string) ([]*http.Cookie, error) {
+	parsedURL, err := url.Parse(domainURL)
+	if err != nil {
+		return nil, fmt.Errorf("Cookie取得のためのURL解析に失敗しました: %w", err)
+	}
+	return c.jar.Cookies(parsedURL), nil
+}
+
+// PostForm は、values をフォームの値として指定されたURLにPOSTし、レスポンスボディを文字列として返します。
+// ログインフォームの送信に使用します。レスポンスで発行されたCookieは以降のリクエストで送信されます。
+func (c *Client) PostForm(ctx context.Context, reqURL string, values url.Values) (string, error) {
+	parsedURL, err := url.Parse(reqURL)
+	if err != nil {
+		return "", fmt.Errorf("リクエストURLの解析に失敗しました (%s): %w", reqURL, err)
+	}
+	if err := c.getLimiterForHost(parsedURL.Hostname()).Wait(ctx); err != nil {
+		return "", limiterWaitError(ctx, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("POSTリクエストの作成に失敗しました (%s): %w", reqURL, err)
+	}
+	for key, value := range c.defaultHeaders {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("User-Agent", c.currentUserAgent())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if isTimeout(err) {
+			return "", fmt.Errorf("%w: POSTリクエストがタイムアウトしました (%s): %w", errs.ErrTimeout, reqURL, err)
+		}
+		return "", fmt.Errorf("POSTリクエストの送信に失敗しました (%s): %w", reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &HTTPError{StatusCode: resp.StatusCode, URL: reqURL, Message: http.StatusText(resp.StatusCode)}
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("レスポンスボディの読み込みに失敗しました: %w", err)
+	}
+	return string(data), nil
+}
+
+// SetUserAgent は、以降のリクエストの User-Agent を変更します。
+// ブラウザの Cookie を使用する場合に、Cookie を発行したブラウザと同じ User-Agent で通信するために使用します。
+func (c *Client) SetUserAgent(userAgent string) {
+	c.userAgentMutex.Lock()
+	defer c.userAgentMutex.Unlock()
+	c.userAgent = userAgent
+}
+
+// currentUserAgent は、リクエストに設定する User-Agent を返します。
+func (c *Client) currentUserAgent() string {
+	c.userAgentMutex.Lock()
+	defer c.userAgentMutex.Unlock()
+	return c.userAgent
+}
+
+// Preload は、reqURL への次の GET リクエストで、通信せずに body を返すようにします。
+// ブラウザの拡張機能から受け取ったページなど、既に手元にあるレスポンスを使用するために使用します。登録したボディは1回だけ使用します。
+func (c *Client) Preload(reqURL, body string) {
+	c.preloadedMutex.Lock()
+	defer c.preloadedMutex.Unlock()
+	if c.preloaded == nil {
+		c.preloaded = make(map[string]string)
+	}
+	c.preloaded[reqURL] = body
+}
+
+// takePreloaded は、Preload で reqURL に登録したボディを取り出します。
+func (c *Client) takePreloaded(reqURL string) (string, bool) {
+	c.preloadedMutex.Lock()
+	defer c.preloadedMutex.Unlock()
+	body, ok := c.preloaded[reqURL]
+	delete(c.preloaded, reqURL)
+	return body, ok
+}
+
+// SetQueryParams は、domainURL のホストへの以降の GET リクエストに、values のクエリパラメータを追加します。
+// Cookie ではなくクエリのAPIキーで認証するAPIに使用します。リクエストのURLに同じ名前のパラメータがある場合はそちらを優先します。
+// 追加したパラメータはエラーメッセージに含めません。
+func (c *Client) SetQueryParams(domainURL string, values url.Values) error {
+	parsedURL, err := url.Parse(domainURL)
+	if err != nil {
+		return fmt.Errorf("クエリパラメータ設定のためのURL解析に失敗しました: %w", err)
+	}
+	c.hostQueriesMutex.Lock()
+	defer c.hostQueriesMutex.Unlock()
+	if c.hostQueries == nil {
+		c.hostQueries = make(map[string]url.Values)
+	}
+	c.hostQueries[parsedURL.Hostname()] = values
+	return nil
+}
+
+// withHostQuery は、SetQueryParams で u のホストに登録したクエリパラメータを追加したURLを返します。
+func (c *Client) withHostQuery(u *url.URL) string {
+	c.hostQueriesMutex.Lock()
+	values := c.hostQueries[u.Hostname()]
+	c.hostQueriesMutex.Unlock()
+	if len(values) == 0 {
+		return u.String()
+	}
+	query := u.Query()
+	for key, v := range values {
+		if !query.Has(key) {
+			query[key] = v
+		}
+	}
+	withQuery := *u
+	withQuery.RawQuery = query.Encode()
+	return withQuery.String()
+}
+
 // Get は、設定済みのCookieを使って指定されたURLにGETリクエストを送信し、
 // レスポンスボディを文字列として返します。
 func (c *Client) Get(ctx context.Context, reqURL string) (string, error) {
+	body, _, _, err := c.GetConditional(ctx, reqURL, CacheValidators{})
+	return body, err
+}
+
+// CacheValidators は、レスポンスの ETag / Last-Modified ヘッダーの値です。
+// 次回のリクエストで If-None-Match / If-Modified-Since として送信し、内容が変わっていない場合の転送を省きます。
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// IsZero は、検証子がどちらも設定されていないかを判定します。
+func (v CacheValidators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// GetConditional は、validators を条件とするGETリクエスト（条件付きGET）を送信し、
+// レスポンスボディとレスポンスの検証子を返します。validators が空の場合は通常のGETと同じです。
+// サーバーが 304 Not Modified を返した場合は notModified が true になり、ボディは空になります。
+// 304 のレスポンスに検証子が含まれない場合は、送信した validators をそのまま返します。
+// Preload でボディを登録したURLは、通信せずにそのボディを返します。
+func (c *Client) GetConditional(ctx context.Context, reqURL string, validators CacheValidators) (body string, latest CacheValidators, notModified bool, err error) {
+	if preloaded, ok := c.takePreloaded(reqURL); ok {
+		return preloaded, CacheValidators{}, false, nil
+	}
 	parsedURL, err := url.Parse(reqURL)
 	if err != nil {
-		return "", fmt.Errorf("リクエストURLの解析に失敗しました (%s): %w", reqURL, err)
+		return "", CacheValidators{}, false, fmt.Errorf("リクエストURLの解析に失敗しました (%s): %w", reqURL, err)
 	}
 
 	// ドメインごとのレートリミッターを取得し、待機
 	host := parsedURL.Hostname()
 	limiter := c.getLimiterForHost(host)
 
-	// 排他制御を追加
-	c.rateLimitersMutex.Lock()
-	defer c.rateLimitersMutex.Unlock()
-
+	// rate.Limiter はゴルーチンセーフなため、待機中やリクエスト中にロックを保持しない。
+	// ロックを保持するとスレッド・ファイル単位の並列ダウンロードが直列化されてしまう。
 	if err := limiter.Wait(ctx); err != nil {
-		return "", fmt.Errorf("レートリミッター待機中にエラーが発生しました: %w", err)
+		return "", CacheValidators{}, false, limiterWaitError(ctx, err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.withHostQuery(parsedURL), nil)
 	if err != nil {
-		return "", fmt.Errorf("GETリクエストの作成に失敗しました (%s): %w", reqURL, err)
+		return "", CacheValidators{}, false, fmt.Errorf("GETリクエストの作成に失敗しました (%s): %w", reqURL, err)
 	}
 
 	// デフォルトヘッダーを全て設定
@@ -139,29 +314,67 @@ func (c *Client) Get(ctx context.Context, reqURL string) (string, error) {
 		req.Header.Set(key, value)
 	}
 	// User-Agentも設定
-	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("User-Agent", c.currentUserAgent())
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("GETリクエストの送信に失敗しました (%s): %w", reqURL, err)
+		// 追加したクエリパラメータ（APIキーなど）をエラーメッセージに含めない
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = reqURL
+		}
+		if isTimeout(err) {
+			return "", CacheValidators{}, false, fmt.Errorf("%w: GETリクエストがタイムアウトしました (%s): %w", errs.ErrTimeout, reqURL, err)
+		}
+		return "", CacheValidators{}, false, fmt.Errorf("GETリクエストの送信に失敗しました (%s): %w", reqURL, err)
 	}
 	defer resp.Body.Close()
 
+	latest = CacheValidators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if resp.StatusCode == http.StatusNotModified && !validators.IsZero() {
+		if latest.IsZero() {
+			latest = validators
+		}
+		return "", latest, true, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		// HTTPErrorとして返す（ステータスコードを含む）
-		return "", &HTTPError{
+		return "", CacheValidators{}, false, &HTTPError{
 			StatusCode: resp.StatusCode,
 			URL:        reqURL,
 			Message:    http.StatusText(resp.StatusCode),
 		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("レスポンスボディの読み込みに失敗しました: %w", err)
+		if isTimeout(err) {
+			return "", CacheValidators{}, false, fmt.Errorf("%w: レスポンスボディの読み込み中にタイムアウトしました: %w", errs.ErrTimeout, err)
+		}
+		return "", CacheValidators{}, false, fmt.Errorf("レスポンスボディの読み込みに失敗しました: %w", err)
 	}
 
-	return string(body), nil
+	return string(data), latest, false, nil
+}
+
+// limiterWaitError は、レートリミッターの待機で発生したエラーを返します。
+// errs.ErrRateLimited はサーバーの 429 応答にのみ使用し、キャンセル・タイムアウトは ctx のエラーをそのまま返します。
+// 待機の完了が ctx の期限を過ぎる場合（期限の前にエラーになる）は context.DeadlineExceeded として扱います。
+func limiterWaitError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return fmt.Errorf("%w: レートリミッターの待機が期限内に終わりません: %v", context.DeadlineExceeded, err)
+	}
+	return fmt.Errorf("レートリミッター待機中にエラーが発生しました: %w", err)
 }
 
 // getLimiterForHost は、指定されたホスト名に対応するレートリミッターを返します。
@@ -187,3 +400,12 @@ func (c *Client) getLimiterForHost(host string) *rate.Limiter {
 	c.rateLimiters[host] = newLimiter
 	return newLimiter
 }
+
+// isTimeout は、エラーがタイムアウトに起因するものかどうかを判定します。
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -1,11 +1,19 @@
 package network
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/wai55555/GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/errs"
+	"GoImageBoardArchiver/internal/version"
 )
 
 func TestClient_CookieIntegration(t *testing.T) {
@@ -35,7 +43,7 @@ func TestClient_CookieIntegration(t *testing.T) {
 	defer server.Close()
 
 	// 2. Arrange (準備) - テスト対象クライアントの作成
-	client, err := NewClient()
+	client, err := NewClient(config.NetworkSettings{})
 	if err != nil {
 		t.Fatalf("NewClientの作成に失敗しました: %v", err)
 	}
@@ -62,7 +70,7 @@ func TestClient_CookieIntegration(t *testing.T) {
 
 	// 3. Act (実行)
 	// ダミーサーバーにGETリクエストを送信
-	body, err := client.Get(server.URL)
+	body, err := client.Get(context.Background(), server.URL)
 
 	// 4. Assert (検証)
 	if err != nil {
@@ -73,3 +81,224 @@ func TestClient_CookieIntegration(t *testing.T) {
 		t.Errorf("レスポンスボディが期待値と異なります。期待値: 'Success', 実際値: '%s'", body)
 	}
 }
+
+func TestHTTPError_Is(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		statusCode int
+		target     error
+		want       bool
+	}{
+		{"404はスレッド消失", http.StatusNotFound, errs.ErrThreadGone, true},
+		{"410はスレッド消失", http.StatusGone, errs.ErrThreadGone, true},
+		{"429はレート制限", http.StatusTooManyRequests, errs.ErrRateLimited, true},
+		{"500はスレッド消失ではない", http.StatusInternalServerError, errs.ErrThreadGone, false},
+		{"404はレート制限ではない", http.StatusNotFound, errs.ErrRateLimited, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := fmt.Errorf("ラップ: %w", &HTTPError{StatusCode: tt.statusCode, URL: "http://example.com"})
+			if got := errors.Is(err, tt.target); got != tt.want {
+				t.Errorf("errors.Is() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_LimiterWaitErrors(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	// 最初のリクエストでバーストを使い切り、以降は1分待たなければ送信できない状態にする
+	client, err := NewClient(config.NetworkSettings{PerDomainIntervalMillis: map[string]int{"127.0.0.1": 60000}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, err := client.Get(context.Background(), server.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	withDeadline, cancelDeadline := context.WithTimeout(context.Background(), time.Second)
+	defer cancelDeadline()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{name: "キャンセル", ctx: canceled, want: context.Canceled},
+		{name: "期限内に送信できない", ctx: withDeadline, want: context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Get(tt.ctx, server.URL)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Get() error = %v, want %v", err, tt.want)
+			}
+			// レート制限はサーバーの 429 応答のみを表す
+			if errors.Is(err, errs.ErrRateLimited) {
+				t.Errorf("Get() error = %v はレート制限として扱われるべきではありません", err)
+			}
+		})
+	}
+}
+
+func TestClient_UserAgent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		configured string
+		want       string
+	}{
+		{name: "未設定ならGIBAのバージョンを名乗る", configured: "", want: version.UserAgent()},
+		{name: "設定値を優先", configured: "MyAgent/1.0", want: "MyAgent/1.0"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.UserAgent()
+			}))
+			defer server.Close()
+
+			client, err := NewClient(config.NetworkSettings{UserAgent: tt.configured})
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			if _, err := client.Get(context.Background(), server.URL); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_GetConditional(t *testing.T) {
+	t.Parallel()
+
+	const etag, lastModified = `"v1"`, "Mon, 02 Jan 2006 15:04:05 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.NetworkSettings{PerDomainIntervalMillis: map[string]int{"127.0.0.1": 1}})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		validators      CacheValidators
+		wantBody        string
+		wantNotModified bool
+	}{
+		{name: "検証子なしは通常のGET", validators: CacheValidators{}, wantBody: "body"},
+		{name: "ETagが一致すれば304", validators: CacheValidators{ETag: etag}, wantNotModified: true},
+		{name: "Last-Modifiedが一致すれば304", validators: CacheValidators{LastModified: lastModified}, wantNotModified: true},
+		{name: "ETagが異なれば本文を取得", validators: CacheValidators{ETag: `"v0"`}, wantBody: "body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, latest, notModified, err := client.GetConditional(context.Background(), server.URL, tt.validators)
+			if err != nil {
+				t.Fatalf("GetConditional() error = %v", err)
+			}
+			if body != tt.wantBody || notModified != tt.wantNotModified {
+				t.Errorf("GetConditional() = %q, notModified=%v, want %q, %v", body, notModified, tt.wantBody, tt.wantNotModified)
+			}
+			if want := (CacheValidators{ETag: etag, LastModified: lastModified}); latest != want {
+				t.Errorf("検証子 = %+v, want %+v", latest, want)
+			}
+		})
+	}
+}
+
+func TestClient_Preload(t *testing.T) {
+	t.Parallel()
+
+	var requests int
+	var gotUserAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		gotUserAgent = r.UserAgent()
+		w.Write([]byte("from server"))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.NetworkSettings{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.SetUserAgent("Browser/1.0")
+	client.Preload(server.URL, "from browser")
+
+	// 1回目は登録したボディを返し、2回目は通信する
+	for i, want := range []string{"from browser", "from server"} {
+		body, err := client.Get(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Get() #%d error = %v", i+1, err)
+		}
+		if body != want {
+			t.Errorf("Get() #%d = %q, want %q", i+1, body, want)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+	if gotUserAgent != "Browser/1.0" {
+		t.Errorf("User-Agent = %q, want %q", gotUserAgent, "Browser/1.0")
+	}
+}
+
+func TestClient_SetQueryParams(t *testing.T) {
+	t.Parallel()
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.NetworkSettings{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := client.SetQueryParams(server.URL, url.Values{"api_key": {"secret"}, "limit": {"10"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// リクエストのURLのパラメータを優先し、エラーメッセージには追加したパラメータを含めない
+	_, err = client.Get(context.Background(), server.URL+"/posts.json?limit=20")
+	if gotQuery != "api_key=secret&limit=20" {
+		t.Errorf("query = %q, want %q", gotQuery, "api_key=secret&limit=20")
+	}
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Get() error = %v, want 404 without api_key", err)
+	}
+}
@@ -0,0 +1,154 @@
+package network
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding は、リクエストで受け入れを通知する圧縮形式です。
+const acceptEncoding = "gzip, deflate, br"
+
+// decodingTransport は、レスポンスの Content-Encoding（gzip・deflate・br）を透過的に展開する RoundTripper です。
+// http.Transport による自動展開は gzip のみで、Accept-Encoding を自分で設定した場合（default_headers など）や
+// 独自の Transport を経由する場合は働かないため、ここで展開してから呼び出し元に返します。
+type decodingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		// RoundTripper はリクエストを変更してはならないため、複製してヘッダーを追加する
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	decodeResponseBody(resp)
+	return resp, nil
+}
+
+// decodeResponseBody は、resp のボディを Content-Encoding に従って展開するリーダーに置き換えます。
+// 展開後の長さは不明なため、Content-Length は削除します。展開のエラーはボディの読み込み時に返されます。
+func decodeResponseBody(resp *http.Response) {
+	encodings := contentEncodings(resp.Header.Get("Content-Encoding"))
+	if len(encodings) == 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	if resp.StatusCode == http.StatusNotModified || resp.StatusCode == http.StatusNoContent ||
+		(resp.Request != nil && resp.Request.Method == http.MethodHead) {
+		return
+	}
+
+	resp.Body = &decodedBody{raw: resp.Body, encodings: encodings}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// contentEncodings は、Content-Encoding の値を適用された順の小文字のリストで返します（identity は除きます）。
+func contentEncodings(header string) []string {
+	var encodings []string
+	for _, e := range strings.Split(header, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+			encodings = append(encodings, e)
+		}
+	}
+	return encodings
+}
+
+// decodedBody は、圧縮されたレスポンスボディを展開しながら読み込みます。
+// gzip などはリーダーの作成時にヘッダーを読み込むため、空のボディでもエラーにならないよう最初の Read で作成します。
+type decodedBody struct {
+	raw       io.ReadCloser
+	encodings []string
+	reader    io.Reader
+	closers   []io.Closer
+	err       error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		b.reader, b.err = b.newReader()
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+// newReader は、Content-Encoding に列挙された順とは逆の順に展開するリーダーを作成します。
+func (b *decodedBody) newReader() (io.Reader, error) {
+	var r io.Reader = b.raw
+	for i := len(b.encodings) - 1; i >= 0; i-- {
+		switch b.encodings[i] {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r)
+			if err == io.EOF {
+				return strings.NewReader(""), nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("gzipの展開に失敗しました: %w", err)
+			}
+			b.closers = append(b.closers, zr)
+			r = zr
+		case "deflate":
+			dr, err := newDeflateReader(r)
+			if err != nil {
+				return nil, err
+			}
+			b.closers = append(b.closers, dr)
+			r = dr
+		case "br":
+			r = brotli.NewReader(r)
+		default:
+			return nil, fmt.Errorf("未対応の Content-Encoding です: %s", b.encodings[i])
+		}
+	}
+	return r, nil
+}
+
+// newDeflateReader は、deflate で圧縮されたボディを展開するリーダーを返します。
+// HTTP の deflate は zlib 形式と定められていますが、生の deflate を返すサーバーもあるため先頭の2バイトで判別します。
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == io.EOF && len(header) == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("deflateの展開に失敗しました: %w", err)
+	}
+	if isZlibHeader(header[0], header[1]) {
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("deflate(zlib)の展開に失敗しました: %w", err)
+		}
+		return zr, nil
+	}
+	return flate.NewReader(br), nil
+}
+
+// isZlibHeader は、2バイトが zlib のヘッダー（圧縮方式 deflate、チェック値が31の倍数）かを判定します。
+func isZlibHeader(cmf, flg byte) bool {
+	return cmf&0x0f == 8 && (uint16(cmf)<<8|uint16(flg))%31 == 0
+}
+
+func (b *decodedBody) Close() error {
+	var errList []error
+	for _, c := range b.closers {
+		errList = append(errList, c.Close())
+	}
+	return errors.Join(append(errList, b.raw.Close())...)
+}
@@ -0,0 +1,141 @@
+package network
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressForTest は、data を encoding で圧縮します。
+func compressForTest(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	case "br":
+		w = brotli.NewWriter(&buf)
+	default:
+		t.Fatalf("不明な圧縮形式: %s", encoding)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestClient_DecodesContentEncoding(t *testing.T) {
+	t.Parallel()
+
+	const text = "<html>スレッド本文 No.123 テキスト</html>"
+	payload := strings.Repeat(text, 50)
+
+	tests := []struct {
+		name           string
+		encodingHeader string
+		body           func(t *testing.T) []byte
+		defaultHeaders map[string]string
+		wantErr        bool
+	}{
+		{name: "非圧縮", body: func(t *testing.T) []byte { return []byte(payload) }},
+		{name: "gzip", encodingHeader: "gzip", body: func(t *testing.T) []byte { return compressForTest(t, "gzip", []byte(payload)) }},
+		{name: "deflate(zlib)", encodingHeader: "deflate", body: func(t *testing.T) []byte { return compressForTest(t, "deflate", []byte(payload)) }},
+		{name: "deflate(生)", encodingHeader: "deflate", body: func(t *testing.T) []byte { return compressForTest(t, "raw-deflate", []byte(payload)) }},
+		{name: "brotli", encodingHeader: "br", body: func(t *testing.T) []byte { return compressForTest(t, "br", []byte(payload)) }},
+		{name: "多重圧縮", encodingHeader: "gzip, br", body: func(t *testing.T) []byte {
+			return compressForTest(t, "br", compressForTest(t, "gzip", []byte(payload)))
+		}},
+		{name: "default_headersでAccept-Encodingを指定", encodingHeader: "gzip", defaultHeaders: map[string]string{"Accept-Encoding": "gzip"},
+			body: func(t *testing.T) []byte { return compressForTest(t, "gzip", []byte(payload)) }},
+		{name: "未対応の形式", encodingHeader: "zstd", body: func(t *testing.T) []byte { return []byte("???") }, wantErr: true},
+		{name: "壊れたgzip", encodingHeader: "gzip", body: func(t *testing.T) []byte { return []byte("not gzip") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			body := tt.body(t)
+			acceptEncodings := make(chan string, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				acceptEncodings <- r.Header.Get("Accept-Encoding")
+				if tt.encodingHeader != "" {
+					w.Header().Set("Content-Encoding", tt.encodingHeader)
+				}
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(config.NetworkSettings{
+				DefaultHeaders:          tt.defaultHeaders,
+				PerDomainIntervalMillis: map[string]int{"127.0.0.1": 1},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := client.Get(context.Background(), server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != payload {
+				t.Errorf("Get() = %q..., want %q...", got[:min(len(got), 40)], payload[:40])
+			}
+			wantAccept := acceptEncoding
+			if tt.defaultHeaders != nil {
+				wantAccept = tt.defaultHeaders["Accept-Encoding"]
+			}
+			if gotAcceptEncoding := <-acceptEncodings; gotAcceptEncoding != wantAccept {
+				t.Errorf("Accept-Encoding = %q, want %q", gotAcceptEncoding, wantAccept)
+			}
+		})
+	}
+}
+
+func TestDecodeResponseBody_EmptyBody(t *testing.T) {
+	t.Parallel()
+
+	for _, encoding := range []string{"gzip", "deflate", "br"} {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Encoding": {encoding}, "Content-Length": {"0"}},
+			Body:       io.NopCloser(strings.NewReader("")),
+		}
+		decodeResponseBody(resp)
+		data, err := io.ReadAll(resp.Body)
+		if err != nil || len(data) != 0 {
+			t.Errorf("%s: ReadAll() = (%q, %v), want empty", encoding, data, err)
+		}
+		if resp.Header.Get("Content-Encoding") != "" || resp.ContentLength != -1 {
+			t.Errorf("%s: Content-Encoding / Content-Length が削除されていません", encoding)
+		}
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("%s: Close() error = %v", encoding, err)
+		}
+	}
+}
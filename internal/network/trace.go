@@ -0,0 +1,221 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceSettings は、HTTP通信のトレース（--trace-http）の設定です。
+type TraceSettings struct {
+	// Domains は、トレースするホスト名です。"2chan.net" はサブドメイン（may.2chan.net など）にも一致し、"*" はすべてのホストに一致します。
+	// 空の場合、トレースは無効です。
+	Domains []string
+	// BodyDir は、レスポンスボディを保存するディレクトリです。空の場合はメタデータのみをログに出力します。
+	BodyDir string
+}
+
+// tracer は、トレースの設定と、保存するファイル名の連番を保持します。
+type tracer struct {
+	mu       sync.RWMutex
+	settings TraceSettings
+	seq      atomic.Int64
+}
+
+// sharedTracer は、プロセス内のすべてのクライアントで共有されるトレースの設定です。
+// 設定前に作成したクライアントにも、以降のリクエストから適用されます。
+var sharedTracer = &tracer{}
+
+// ConfigureTracing は、HTTP通信のトレースを設定します。Domains が空の場合はトレースを無効にします。
+// BodyDir を指定した場合はディレクトリを作成します。
+func ConfigureTracing(settings TraceSettings) error {
+	return sharedTracer.configure(settings)
+}
+
+func (t *tracer) configure(settings TraceSettings) error {
+	if settings.BodyDir != "" {
+		if err := os.MkdirAll(settings.BodyDir, 0755); err != nil {
+			return fmt.Errorf("トレースの保存先ディレクトリの作成に失敗しました (path=%s): %w", settings.BodyDir, err)
+		}
+	}
+	normalized := make([]string, 0, len(settings.Domains))
+	for _, d := range settings.Domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			normalized = append(normalized, strings.TrimPrefix(d, "."))
+		}
+	}
+	settings.Domains = normalized
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.settings = settings
+	return nil
+}
+
+// ParseTraceDomains は、カンマ区切りのホスト名の一覧を分割します。
+func ParseTraceDomains(s string) []string {
+	var domains []string
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// lookup は、host をトレースするかどうかと、ボディの保存先を返します。
+func (t *tracer) lookup(host string) (bool, string) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	host = strings.ToLower(host)
+	for _, d := range t.settings.Domains {
+		if d == "*" || host == d || strings.HasSuffix(host, "."+d) {
+			return true, t.settings.BodyDir
+		}
+	}
+	return false, ""
+}
+
+// tracingTransport は、トレース対象のホストへのリクエストとレスポンスのメタデータをログに出力し、
+// 設定に応じてレスポンスボディをファイルに保存する http.RoundTripper です。
+// リダイレクトの各段階も個別に記録されます。
+type tracingTransport struct {
+	base   http.RoundTripper
+	tracer *tracer
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	enabled, bodyDir := t.tracer.lookup(req.URL.Hostname())
+	if !enabled {
+		return t.base.RoundTrip(req)
+	}
+
+	id := t.tracer.seq.Add(1)
+	log.Printf("TRACE[%d]: --> %s %s\n%s", id, req.Method, traceURL(req.URL), formatTraceHeaders(req.Header))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		log.Printf("TRACE[%d]: <-- エラー %s (%v): %v", id, traceURL(req.URL), elapsed, err)
+		return nil, err
+	}
+	log.Printf("TRACE[%d]: <-- %s %s (%v, %s %s, Content-Length=%d)\n%s",
+		id, resp.Status, traceURL(req.URL), elapsed, resp.Proto, resp.Header.Get("Content-Type"), resp.ContentLength, formatTraceHeaders(resp.Header))
+
+	if bodyDir != "" && resp.Body != nil {
+		name := fmt.Sprintf("%s_%06d_%s_%d.body", start.Format("20060102T150405"), id, sanitizeTraceName(req.URL.Hostname()), resp.StatusCode)
+		path := filepath.Join(bodyDir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			log.Printf("WARNING: TRACE[%d]: レスポンスボディの保存先を作成できませんでした: %v", id, err)
+			return resp, nil
+		}
+		log.Printf("TRACE[%d]: レスポンスボディを %s に保存します", id, path)
+		resp.Body = &tracedBody{ReadCloser: resp.Body, tee: io.TeeReader(resp.Body, f), file: f}
+	}
+	return resp, nil
+}
+
+// tracedBody は、読み込んだレスポンスボディをファイルにも書き込みます。
+// 読み込まれなかった部分は保存されません（呼び出し側が読んだ内容がそのまま残ります）。
+type tracedBody struct {
+	io.ReadCloser
+	tee  io.Reader
+	file *os.File
+	once sync.Once
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	return b.tee.Read(p)
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		if cerr := b.file.Close(); cerr != nil {
+			log.Printf("WARNING: トレースファイルのクローズに失敗しました (path=%s): %v", b.file.Name(), cerr)
+		}
+	})
+	return err
+}
+
+// traceMaskedHeaders は、値を伏せてログに出力するヘッダーです（Cookie の名前のみを残します）。
+var traceMaskedHeaders = map[string]bool{
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"Authorization": true,
+}
+
+// formatTraceHeaders は、ヘッダーを名前の順に1行ずつ整形します。Cookie などの値は伏せます。
+func formatTraceHeaders(h http.Header) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		for _, v := range h[name] {
+			if traceMaskedHeaders[name] {
+				v = maskHeaderValue(v)
+			}
+			fmt.Fprintf(&sb, "    %s: %s\n", name, v)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// traceMaskedQueryParams は、値を伏せてログに出力するクエリパラメータです（APIキーなど）。
+var traceMaskedQueryParams = []string{"api_key", "pass_hash"}
+
+// traceURL は、URLのクエリパラメータのうちAPIキーなどの値を伏せた文字列を返します。
+func traceURL(u *url.URL) string {
+	query := u.Query()
+	masked := false
+	for _, name := range traceMaskedQueryParams {
+		if query.Has(name) {
+			query.Set(name, "***")
+			masked = true
+		}
+	}
+	if !masked {
+		return u.String()
+	}
+	withMask := *u
+	withMask.RawQuery = query.Encode()
+	return withMask.String()
+}
+
+// maskHeaderValue は、Cookie の名前のみを残し、値を伏せます（"a=1; b=2" → "a=***; b=***"）。
+func maskHeaderValue(v string) string {
+	parts := strings.Split(v, ";")
+	for i, part := range parts {
+		if name, _, ok := strings.Cut(part, "="); ok {
+			parts[i] = name + "=***"
+		} else if i == 0 {
+			parts[i] = "***"
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// sanitizeTraceName は、ホスト名をファイル名に使用できる文字のみにします。
+func sanitizeTraceName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
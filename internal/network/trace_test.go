@@ -0,0 +1,86 @@
+package network
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTracer_Lookup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		domains []string
+		host    string
+		want    bool
+	}{
+		{[]string{"2chan.net"}, "may.2chan.net", true},
+		{[]string{"2chan.net"}, "2chan.net", true},
+		{[]string{"2chan.net"}, "not2chan.net", false},
+		{[]string{" May.2chan.net "}, "MAY.2chan.net", true},
+		{[]string{"may.2chan.net"}, "img.2chan.net", false},
+		{[]string{"*"}, "example.com", true},
+		{nil, "may.2chan.net", false},
+	}
+	for _, tt := range tests {
+		tr := &tracer{}
+		if err := tr.configure(TraceSettings{Domains: tt.domains}); err != nil {
+			t.Fatalf("configure() error = %v", err)
+		}
+		if got, _ := tr.lookup(tt.host); got != tt.want {
+			t.Errorf("lookup(%q) with %v = %v, want %v", tt.host, tt.domains, got, tt.want)
+		}
+	}
+}
+
+func TestTracingTransport_SavesBody(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>thread</html>"))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "trace")
+	tr := &tracer{}
+	if err := tr.configure(TraceSettings{Domains: []string{"127.0.0.1"}, BodyDir: dir}); err != nil {
+		t.Fatalf("configure() error = %v", err)
+	}
+	client := &http.Client{Transport: &tracingTransport{base: http.DefaultTransport, tracer: tr}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "<html>thread</html>" {
+		t.Fatalf("body = %q, %v", body, err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*_127.0.0.1_200.body"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("保存されたボディ = %v, %v, want 1件", files, err)
+	}
+	saved, err := os.ReadFile(files[0])
+	if err != nil || string(saved) != string(body) {
+		t.Errorf("保存されたボディの内容 = %q, %v", saved, err)
+	}
+}
+
+func TestMaskHeaderValue(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"cxyl=9x100x20x0x0; posttime=123": "cxyl=***; posttime=***",
+		"Bearer secret":                   "***",
+	}
+	for in, want := range tests {
+		if got := maskHeaderValue(in); got != want {
+			t.Errorf("maskHeaderValue(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
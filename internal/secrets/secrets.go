@@ -0,0 +1,94 @@
+// Package secrets は、掲示板のパスワードやセッションCookieなど、config.json に直接書きたくない値を提供します。
+// 値は名前で参照し、環境変数 GIBA_SECRET_<名前> を優先して、次にシークレットファイル
+// （名前と値の組を並べたJSONオブジェクト）から取得します。設定ファイルでは "${secret:名前}" の形式で参照します。
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultFileName は、secrets_file が未設定の場合のシークレットファイルの名前です（設定ファイルと同じディレクトリ）。
+const DefaultFileName = "secrets.json"
+
+// envPrefix は、シークレットを環境変数で渡す場合の接頭辞です。
+const envPrefix = "GIBA_SECRET_"
+
+// ErrNotFound は、指定された名前のシークレットが環境変数にもシークレットファイルにもないことを表します。
+var ErrNotFound = errors.New("シークレットが見つかりません")
+
+var (
+	// referencePattern は、設定値の中のシークレットの参照 "${secret:名前}" です。
+	referencePattern = regexp.MustCompile(`\$\{secret:([^}]+)\}`)
+	// envNameInvalidChars は、環境変数名に使用できない文字です。
+	envNameInvalidChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+	mu       sync.RWMutex
+	filePath = DefaultFileName
+)
+
+// Configure は、シークレットファイルのパスを設定します（空文字の場合は DefaultFileName）。
+func Configure(path string) {
+	if path == "" {
+		path = DefaultFileName
+	}
+	mu.Lock()
+	filePath = path
+	mu.Unlock()
+}
+
+// Lookup は、name のシークレットを返します。
+// ファイルはパスワードの変更がすぐに反映されるよう、呼び出しのたびに読み込みます。
+func Lookup(name string) (string, error) {
+	if value, ok := os.LookupEnv(EnvName(name)); ok {
+		return value, nil
+	}
+
+	mu.RLock()
+	path := filePath
+	mu.RUnlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w (name=%s, 環境変数 %s またはシークレットファイル %s に設定してください)", ErrNotFound, name, EnvName(name), path)
+		}
+		return "", fmt.Errorf("シークレットファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	var values map[string]string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return "", fmt.Errorf("シークレットファイルの解析に失敗しました (path=%s): %w", path, err)
+	}
+	value, ok := values[name]
+	if !ok {
+		return "", fmt.Errorf("%w (name=%s, 環境変数 %s またはシークレットファイル %s に設定してください)", ErrNotFound, name, EnvName(name), path)
+	}
+	return value, nil
+}
+
+// Expand は、s に含まれる "${secret:名前}" をすべてシークレットの値に置き換えます。
+func Expand(s string) (string, error) {
+	var firstErr error
+	expanded := referencePattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := referencePattern.FindStringSubmatch(ref)[1]
+		value, err := Lookup(name)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return value
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return expanded, nil
+}
+
+// EnvName は、name のシークレットを渡す環境変数の名前を返します（例: "board-pass" → "GIBA_SECRET_BOARD_PASS"）。
+func EnvName(name string) string {
+	return envPrefix + envNameInvalidChars.ReplaceAllString(strings.ToUpper(name), "_")
+}
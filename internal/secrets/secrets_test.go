@@ -0,0 +1,57 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// このテストは環境変数とシークレットファイルのパス（グローバル）を変更するため、並列実行しない。
+func TestExpand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	if err := os.WriteFile(path, []byte(`{"board-pass": "from-file", "user": "alice"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	Configure(path)
+	t.Cleanup(func() { Configure("") })
+	t.Setenv("GIBA_SECRET_BOARD_PASS", "from-env")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "参照なし", input: "plain", want: "plain"},
+		{name: "環境変数を優先", input: "${secret:board-pass}", want: "from-env"},
+		{name: "ファイルから取得", input: "id=${secret:user}&pw=${secret:board-pass}", want: "id=alice&pw=from-env"},
+		{name: "存在しない名前", input: "${secret:missing}", wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Expand(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expand(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"board-pass": "GIBA_SECRET_BOARD_PASS",
+		"may.b":      "GIBA_SECRET_MAY_B",
+		"TOKEN_1":    "GIBA_SECRET_TOKEN_1",
+	}
+	for name, want := range tests {
+		if got := EnvName(name); got != want {
+			t.Errorf("EnvName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package service は、GIBAをOSのサービス（Windowsサービス・systemdユニット）として登録・操作する機能を提供します。
+// サービスとして実行されるGIBAは、ヘッドレスの監視モード（-cli -watch）で動作します。
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// DefaultName は、登録するサービスの既定の名前です。
+const DefaultName = "giba"
+
+// ErrUnsupported は、現在のOSでサービスの登録がサポートされていないことを示します。
+var ErrUnsupported = errors.New("このOSではサービスの登録に対応していません")
+
+// Config は、サービスとして登録する内容です。
+type Config struct {
+	Name        string // サービス名（systemdのユニット名、Windowsのサービス名）
+	DisplayName string
+	Description string
+	ExecPath    string // GIBAの実行ファイルの絶対パス
+	WorkDir     string // 作業ディレクトリ（アーカイブの相対パスの基準）
+	ConfigPath  string // 設定ファイルの絶対パス
+}
+
+// NewConfig は、実行ファイル・作業ディレクトリ・設定ファイルのパスからサービス設定を作成します。
+// 相対パスは絶対パスに変換します（サービスは作業ディレクトリが異なる状態で起動されるため）。
+func NewConfig(execPath, workDir, configPath string) (Config, error) {
+	var err error
+	if execPath, err = filepath.Abs(execPath); err != nil {
+		return Config{}, fmt.Errorf("実行ファイルのパスを解決できません: %w", err)
+	}
+	if workDir, err = filepath.Abs(workDir); err != nil {
+		return Config{}, fmt.Errorf("作業ディレクトリを解決できません: %w", err)
+	}
+	if !filepath.IsAbs(configPath) {
+		configPath = filepath.Join(workDir, configPath)
+	}
+	return Config{
+		Name:        DefaultName,
+		DisplayName: "GIBA (Go Image Board Archiver)",
+		Description: "画像掲示板のスレッドを監視し、自動的にアーカイブします。",
+		ExecPath:    execPath,
+		WorkDir:     workDir,
+		ConfigPath:  configPath,
+	}, nil
+}
+
+// Args は、サービスとして起動する際のGIBAのコマンドライン引数を返します。
+func (c Config) Args() []string {
+	return []string{"-cli", "-watch", "-workdir", c.WorkDir, "-config", c.ConfigPath}
+}
+
+// Control は、サービスの登録・削除・開始・停止を行います。action は install, uninstall, start, stop のいずれかです。
+func Control(cfg Config, action string) error {
+	switch action {
+	case "install":
+		return install(cfg)
+	case "uninstall":
+		return uninstall(cfg)
+	case "start":
+		return start(cfg)
+	case "stop":
+		return stop(cfg)
+	}
+	return fmt.Errorf("不明な操作です: %q (install, uninstall, start, stop のいずれかを指定してください)", action)
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package service
+
+import "context"
+
+func install(Config) error   { return ErrUnsupported }
+func uninstall(Config) error { return ErrUnsupported }
+func start(Config) error     { return ErrUnsupported }
+func stop(Config) error      { return ErrUnsupported }
+
+// RunAsService は、Windowsサービスとして起動された場合にのみ意味を持ちます。
+func RunAsService(name string, run func(ctx context.Context)) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,162 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func openService(name string) (*mgr.Mgr, *mgr.Service, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, nil, fmt.Errorf("サービスマネージャーへの接続に失敗しました（管理者権限が必要です）: %w", err)
+	}
+	s, err := m.OpenService(name)
+	if err != nil {
+		m.Disconnect()
+		return nil, nil, fmt.Errorf("サービス %s を開けません: %w", name, err)
+	}
+	return m, s, nil
+}
+
+func install(cfg Config) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("サービスマネージャーへの接続に失敗しました（管理者権限が必要です）: %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(cfg.Name); err == nil {
+		s.Close()
+		return fmt.Errorf("サービス %s は既に登録されています", cfg.Name)
+	}
+	s, err := m.CreateService(cfg.Name, cfg.ExecPath, mgr.Config{
+		DisplayName: cfg.DisplayName,
+		Description: cfg.Description,
+		StartType:   mgr.StartAutomatic,
+	}, cfg.Args()...)
+	if err != nil {
+		return fmt.Errorf("サービス %s の登録に失敗しました: %w", cfg.Name, err)
+	}
+	defer s.Close()
+
+	// 異常終了時は30秒後に再起動する
+	recovery := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 30 * time.Second}}
+	if err := s.SetRecoveryActions(recovery, 24*60*60); err != nil {
+		return fmt.Errorf("サービス %s の回復設定に失敗しました: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+func uninstall(cfg Config) error {
+	m, s, err := openService(cfg.Name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	// 実行中の場合は先に停止する（停止済みの場合のエラーは無視する）
+	s.Control(svc.Stop)
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("サービス %s の削除に失敗しました: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+func start(cfg Config) error {
+	m, s, err := openService(cfg.Name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("サービス %s の開始に失敗しました: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+func stop(cfg Config) error {
+	m, s, err := openService(cfg.Name)
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("サービス %s の停止に失敗しました: %w", cfg.Name, err)
+	}
+	// 実行中のダウンロードの後始末を待つ
+	deadline := time.Now().Add(60 * time.Second)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("サービス %s の停止がタイムアウトしました", cfg.Name)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("サービス %s の状態取得に失敗しました: %w", cfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// serviceHandler は、サービスマネージャーからの停止要求をコンテキストのキャンセルに変換します。
+type serviceHandler struct {
+	run func(ctx context.Context)
+}
+
+func (h serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.run(ctx)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-done:
+			status <- svc.Status{State: svc.StopPending}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: 60000}
+				cancel()
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
+
+// RunAsService は、Windowsサービスとして起動された場合に、サービスマネージャーと連携して run を実行します。
+// run には、サービスの停止要求でキャンセルされるコンテキストが渡されます。
+// サービスとして起動されていない場合は何もせず false を返します。
+func RunAsService(name string, run func(ctx context.Context)) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return false, fmt.Errorf("サービスとして起動されたかの判定に失敗しました: %w", err)
+	}
+	if !isService {
+		return false, nil
+	}
+	if err := svc.Run(name, serviceHandler{run: run}); err != nil {
+		return true, fmt.Errorf("サービス %s の実行に失敗しました: %w", name, err)
+	}
+	return true, nil
+}
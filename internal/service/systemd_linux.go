@@ -0,0 +1,101 @@
+//go:build linux
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// systemdUnitDir は、ユニットファイルを配置するディレクトリです（テストで差し替え可能）。
+var systemdUnitDir = "/etc/systemd/system"
+
+// systemctl は、systemctl コマンドを実行します（テストで差し替え可能）。
+var systemctl = func(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl %s に失敗しました: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func unitPath(cfg Config) string {
+	return filepath.Join(systemdUnitDir, cfg.Name+".service")
+}
+
+// systemdUnit は、GIBAを監視モードで実行するsystemdユニットの内容を生成します。
+// 停止時は SIGTERM を送り、実行中のダウンロードの後始末のために TimeoutStopSec まで待機します。
+func systemdUnit(cfg Config) string {
+	args := make([]string, 0, len(cfg.Args())+1)
+	args = append(args, systemdQuote(cfg.ExecPath))
+	for _, a := range cfg.Args() {
+		args = append(args, systemdQuote(a))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Unit]\n")
+	fmt.Fprintf(&sb, "Description=%s\n", cfg.DisplayName)
+	fmt.Fprintf(&sb, "Wants=network-online.target\n")
+	fmt.Fprintf(&sb, "After=network-online.target\n\n")
+	fmt.Fprintf(&sb, "[Service]\n")
+	fmt.Fprintf(&sb, "Type=simple\n")
+	fmt.Fprintf(&sb, "WorkingDirectory=%s\n", systemdQuote(cfg.WorkDir))
+	fmt.Fprintf(&sb, "ExecStart=%s\n", strings.Join(args, " "))
+	fmt.Fprintf(&sb, "KillSignal=SIGTERM\n")
+	fmt.Fprintf(&sb, "TimeoutStopSec=60\n")
+	fmt.Fprintf(&sb, "Restart=on-failure\n")
+	fmt.Fprintf(&sb, "RestartSec=30\n\n")
+	fmt.Fprintf(&sb, "[Install]\n")
+	fmt.Fprintf(&sb, "WantedBy=multi-user.target\n")
+	return sb.String()
+}
+
+// systemdQuote は、空白などを含む値をsystemdの引用符で囲みます。
+func systemdQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func install(cfg Config) error {
+	path := unitPath(cfg)
+	if err := os.WriteFile(path, []byte(systemdUnit(cfg)), 0644); err != nil {
+		return fmt.Errorf("ユニットファイルの書き込みに失敗しました (path=%s): %w", path, err)
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", cfg.Name+".service")
+}
+
+func uninstall(cfg Config) error {
+	// 停止・無効化は未登録の場合に失敗するため、エラーは削除の妨げにしない
+	if err := systemctl("disable", "--now", cfg.Name+".service"); err != nil {
+		log.Printf("WARNING: %v", err)
+	}
+	path := unitPath(cfg)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ユニットファイルの削除に失敗しました (path=%s): %w", path, err)
+	}
+	return systemctl("daemon-reload")
+}
+
+func start(cfg Config) error {
+	return systemctl("start", cfg.Name+".service")
+}
+
+func stop(cfg Config) error {
+	return systemctl("stop", cfg.Name+".service")
+}
+
+// RunAsService は、Windowsサービスとして起動された場合にのみ意味を持ちます。
+// systemd では SIGTERM で停止されるため、通常のシグナル処理で十分です。
+func RunAsService(name string, run func(ctx context.Context)) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,87 @@
+//go:build linux
+
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Name:        "giba",
+		DisplayName: "GIBA",
+		ExecPath:    "/opt/giba/giba",
+		WorkDir:     "/srv/my archive",
+		ConfigPath:  "/srv/my archive/config.json",
+	}
+	unit := systemdUnit(cfg)
+
+	for _, want := range []string{
+		"WorkingDirectory=\"/srv/my archive\"\n",
+		"ExecStart=/opt/giba/giba -cli -watch -workdir \"/srv/my archive\" -config \"/srv/my archive/config.json\"\n",
+		"KillSignal=SIGTERM\n",
+		"WantedBy=multi-user.target\n",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("ユニットに %q が含まれていません:\n%s", want, unit)
+		}
+	}
+}
+
+// TestControl_Systemd はパッケージ変数を差し替えるため、並列実行しません。
+func TestControl_Systemd(t *testing.T) {
+	dir := t.TempDir()
+	origDir, origCtl := systemdUnitDir, systemctl
+	t.Cleanup(func() { systemdUnitDir, systemctl = origDir, origCtl })
+
+	var calls []string
+	systemdUnitDir = dir
+	systemctl = func(args ...string) error {
+		calls = append(calls, strings.Join(args, " "))
+		return nil
+	}
+
+	cfg, err := NewConfig("/opt/giba/giba", "/srv/giba", "config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ConfigPath != "/srv/giba/config.json" {
+		t.Errorf("ConfigPath = %q, want 作業ディレクトリ基準の絶対パス", cfg.ConfigPath)
+	}
+
+	if err := Control(cfg, "install"); err != nil {
+		t.Fatalf("install error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "giba.service")); err != nil {
+		t.Fatalf("ユニットファイルが作成されていません: %v", err)
+	}
+	if err := Control(cfg, "start"); err != nil {
+		t.Fatalf("start error = %v", err)
+	}
+	if err := Control(cfg, "uninstall"); err != nil {
+		t.Fatalf("uninstall error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "giba.service")); !os.IsNotExist(err) {
+		t.Errorf("ユニットファイルが削除されていません")
+	}
+	if err := Control(cfg, "restart"); err == nil {
+		t.Error("不明な操作がエラーになりません")
+	}
+
+	want := []string{
+		"daemon-reload",
+		"enable giba.service",
+		"start giba.service",
+		"disable --now giba.service",
+		"daemon-reload",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("systemctl の呼び出し = %v, want %v", calls, want)
+	}
+}
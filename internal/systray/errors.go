@@ -0,0 +1,86 @@
+package systray
+
+import (
+	"fmt"
+	"log"
+
+	"GoImageBoardArchiver/internal/core"
+	"GoImageBoardArchiver/internal/webui"
+
+	"fyne.io/systray"
+)
+
+// maxRecentErrors は「最近のエラー」に表示する最大件数です（すべてのエラーは Web UI で確認できます）。
+const maxRecentErrors = 10
+
+var (
+	mRecentErrors    *systray.MenuItem
+	recentErrorSlots []*systray.MenuItem
+)
+
+// buildRecentErrorsMenu は「最近のエラー」サブメニューを構築し、クリックハンドラを起動します。
+// 「最近のアーカイブ」と同様に、固定数のスロットを事前に作成して表示/非表示を切り替えます。
+func buildRecentErrorsMenu() {
+	mRecentErrors = systray.AddMenuItem("最近のエラー", "ログにのみ記録されていたタスクのエラーの一覧")
+	mRecentErrors.Disable()
+
+	recentErrorSlots = make([]*systray.MenuItem, maxRecentErrors)
+	for i := range recentErrorSlots {
+		item := mRecentErrors.AddSubMenuItem("-", "")
+		item.Hide()
+		recentErrorSlots[i] = item
+	}
+	showAll := mRecentErrors.AddSubMenuItem("すべて表示...", "Web UIでエラーの一覧を開きます")
+
+	clicked := make(chan struct{})
+	for _, item := range append(recentErrorSlots, showAll) {
+		go func(item *systray.MenuItem) {
+			for {
+				select {
+				case <-item.ClickedCh:
+					select {
+					case clicked <- struct{}{}:
+					case <-appCtx.Done():
+						return
+					}
+				case <-appCtx.Done():
+					return
+				}
+			}
+		}(item)
+	}
+	go func() {
+		for {
+			select {
+			case <-clicked:
+				log.Println("UI: 最近のエラーを開くイベント受信。")
+				webui.OpenPage("/#errors")
+			case <-appCtx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// refreshRecentErrors は、記録されている最近のエラーでメニュー表示を更新します。
+func refreshRecentErrors() {
+	records := core.RecentErrors()
+	for i, item := range recentErrorSlots {
+		if i >= len(records) {
+			item.Hide()
+			continue
+		}
+		rec := records[i]
+		label := rec.TaskName
+		if rec.ThreadID != "" {
+			label += " #" + rec.ThreadID
+		}
+		item.SetTitle(fmt.Sprintf("%s [%s] %s", rec.Time.Format("15:04"), rec.Class.Label(), core.TruncateGraphemes(label, 30)))
+		item.SetTooltip(rec.Message)
+		item.Show()
+	}
+	if len(records) > 0 {
+		mRecentErrors.SetTitle(fmt.Sprintf("最近のエラー (%d)", len(records)))
+		mRecentErrors.Enable()
+	}
+}
@@ -0,0 +1,149 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"runtime"
+	"sync"
+)
+
+// Badge は、ベースアイコンの右下に重ねて描画する状態バッジを表すビットフラグです。
+// 複数のバッジを OR で組み合わせることができます。
+type Badge uint8
+
+// BadgeNone はバッジなし（ベースアイコンそのまま）を表します。
+const BadgeNone Badge = 0
+
+const (
+	BadgeError       Badge = 1 << iota // 赤い点（エラー発生中）
+	BadgeDownloading                   // 下向き矢印（ダウンロード中）
+	BadgePaused                        // 一時停止バー（一時停止中）
+)
+
+var (
+	badgeErrorColor   = color.RGBA{R: 0xe5, G: 0x1c, B: 0x23, A: 0xff}
+	badgeArrowColor   = color.RGBA{R: 0x1e, G: 0x88, B: 0xe5, A: 0xff}
+	badgePauseColor   = color.RGBA{R: 0x42, G: 0x42, B: 0x42, A: 0xff}
+	badgeOutlineColor = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	composedIconCache = make(map[string][]byte)
+	composedIconMutex sync.Mutex
+)
+
+// ComposeIcon は、状態ごとのベースアイコンにバッジを合成したアイコンデータを返します。
+// 静的なビットマップだけでは表現できない複合状態（監視中かつエラーなど）を表示するために、
+// 実行時に画像を合成します。結果はOSに応じてPNGまたはICO形式で返され、キャッシュされます。
+func ComposeIcon(state string, badges Badge) ([]byte, error) {
+	if badges == BadgeNone {
+		return GetIconData(state), nil
+	}
+
+	key := fmt.Sprintf("%s/%d/%s", state, badges, runtime.GOOS)
+	composedIconMutex.Lock()
+	defer composedIconMutex.Unlock()
+	if data, ok := composedIconCache[key]; ok {
+		return data, nil
+	}
+
+	base, err := png.Decode(bytes.NewReader(getIconDataPNG(state)))
+	if err != nil {
+		return nil, fmt.Errorf("ベースアイコンのデコードに失敗しました (state=%s): %w", state, err)
+	}
+
+	canvas := image.NewRGBA(base.Bounds())
+	draw.Draw(canvas, canvas.Bounds(), base, base.Bounds().Min, draw.Src)
+	drawBadges(canvas, badges)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("合成アイコンのエンコードに失敗しました (state=%s): %w", state, err)
+	}
+
+	data := buf.Bytes()
+	if runtime.GOOS == "windows" {
+		data = wrapPNGInICO(data, canvas.Bounds().Dx(), canvas.Bounds().Dy())
+	}
+	composedIconCache[key] = data
+	return data, nil
+}
+
+// drawBadges は、指定されたバッジをキャンバスの右下（エラーは右上）に描画します。
+func drawBadges(canvas *image.RGBA, badges Badge) {
+	size := canvas.Bounds().Dx()
+	unit := size / 16 // 16px基準のスケール
+	if unit < 1 {
+		unit = 1
+	}
+
+	if badges&BadgeError != 0 {
+		// 右上に白縁付きの赤い点
+		cx, cy, r := size-4*unit, 4*unit, 4*unit
+		fillCircle(canvas, cx, cy, r, badgeOutlineColor)
+		fillCircle(canvas, cx, cy, r-unit, badgeErrorColor)
+	}
+
+	switch {
+	case badges&BadgePaused != 0:
+		// 右下に2本の縦バー
+		x0, y0 := size-8*unit, size-8*unit
+		fillRect(canvas, image.Rect(x0-unit, y0-unit, size, size), badgeOutlineColor)
+		fillRect(canvas, image.Rect(x0, y0, x0+3*unit, size-unit), badgePauseColor)
+		fillRect(canvas, image.Rect(x0+4*unit, y0, x0+7*unit, size-unit), badgePauseColor)
+	case badges&BadgeDownloading != 0:
+		// 右下に下向き矢印（軸＋三角形）
+		x0, y0 := size-8*unit, size-8*unit
+		fillRect(canvas, image.Rect(x0-unit, y0-unit, size, size), badgeOutlineColor)
+		mid := x0 + 3*unit + unit/2
+		fillRect(canvas, image.Rect(mid-unit, y0, mid+unit, y0+4*unit), badgeArrowColor)
+		for row := 0; row < 3*unit; row++ {
+			half := 3*unit - row
+			fillRect(canvas, image.Rect(mid-half, y0+4*unit+row, mid+half, y0+4*unit+row+1), badgeArrowColor)
+		}
+	}
+}
+
+func fillRect(canvas *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(canvas, r.Intersect(canvas.Bounds()), &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+func fillCircle(canvas *image.RGBA, cx, cy, r int, c color.Color) {
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			dx, dy := x-cx, y-cy
+			if dx*dx+dy*dy <= r*r && image.Pt(x, y).In(canvas.Bounds()) {
+				canvas.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// wrapPNGInICO は、PNGデータを単一エントリのICOコンテナに格納します。
+// Windows Vista以降はPNG圧縮されたICOエントリをサポートしています。
+func wrapPNGInICO(pngData []byte, width, height int) []byte {
+	// ICONDIRENTRY の幅・高さは 256px 以上を 0 で表す
+	w, h := byte(width), byte(height)
+	if width >= 256 {
+		w = 0
+	}
+	if height >= 256 {
+		h = 0
+	}
+
+	const headerSize = 6 + 16 // ICONDIR + ICONDIRENTRY 1件
+	data := make([]byte, 0, headerSize+len(pngData))
+	// ICONDIR: reserved, type(1=icon), count
+	data = binary.LittleEndian.AppendUint16(data, 0)
+	data = binary.LittleEndian.AppendUint16(data, 1)
+	data = binary.LittleEndian.AppendUint16(data, 1)
+	// ICONDIRENTRY: width, height, colors, reserved, planes, bpp, size, offset
+	data = append(data, w, h, 0, 0)
+	data = binary.LittleEndian.AppendUint16(data, 1)
+	data = binary.LittleEndian.AppendUint16(data, 32)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(pngData)))
+	data = binary.LittleEndian.AppendUint32(data, headerSize)
+	return append(data, pngData...)
+}
@@ -0,0 +1,35 @@
+package icon
+
+import (
+	"testing"
+)
+
+func TestComposeIcon(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		state  string
+		badges Badge
+	}{
+		{"バッジなし", "Idle", BadgeNone},
+		{"エラー", "Watching", BadgeError},
+		{"ダウンロード中", "Running", BadgeDownloading},
+		{"一時停止", "Paused", BadgePaused},
+		{"複合", "Watching", BadgeError | BadgeDownloading},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := ComposeIcon(tt.state, tt.badges)
+			if err != nil {
+				t.Fatalf("ComposeIconが予期せぬエラーを返しました: %v", err)
+			}
+			if err := ValidateIconData(data); err != nil {
+				t.Errorf("合成アイコンが不正です: %v", err)
+			}
+		})
+	}
+}
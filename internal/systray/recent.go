@@ -0,0 +1,136 @@
+package systray
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"GoImageBoardArchiver/internal/core"
+
+	"fyne.io/systray"
+)
+
+// maxRecentArchives は「最近のアーカイブ」に表示する最大件数です。
+const maxRecentArchives = 10
+
+// recentArchiveSlot は「最近のアーカイブ」サブメニューの1行分のメニュー項目です。
+// systrayはメニュー項目の削除ができないため、固定数のスロットを事前に作成し、表示/非表示を切り替えます。
+type recentArchiveSlot struct {
+	item     *systray.MenuItem
+	openItem *systray.MenuItem
+	copyItem *systray.MenuItem
+}
+
+var (
+	mRecentArchives *systray.MenuItem
+	recentSlots     []recentArchiveSlot
+
+	recentArchives      []core.ArchivedThread // 新しい順
+	recentArchivesMutex sync.Mutex
+)
+
+// buildRecentArchivesMenu は「最近のアーカイブ」サブメニューを構築し、クリックハンドラを起動します。
+func buildRecentArchivesMenu() {
+	mRecentArchives = systray.AddMenuItem("最近のアーカイブ", "直近に完了したスレッドの一覧")
+	mRecentArchives.Disable()
+
+	recentSlots = make([]recentArchiveSlot, maxRecentArchives)
+	for i := range recentSlots {
+		item := mRecentArchives.AddSubMenuItem("-", "")
+		slot := recentArchiveSlot{
+			item:     item,
+			openItem: item.AddSubMenuItem("index.htm を開く", "アーカイブしたHTMLをブラウザで開きます"),
+			copyItem: item.AddSubMenuItem("パスをコピー", "保存先フォルダのパスをクリップボードにコピーします"),
+		}
+		item.Hide()
+		recentSlots[i] = slot
+
+		go func(index int, slot recentArchiveSlot) {
+			for {
+				select {
+				case <-slot.openItem.ClickedCh:
+					if archived, ok := recentArchiveAt(index); ok {
+						openCommand(filepath.Join(archived.SavePath, "index.htm"))
+					}
+				case <-slot.copyItem.ClickedCh:
+					if archived, ok := recentArchiveAt(index); ok {
+						if err := copyToClipboard(archived.SavePath); err != nil {
+							log.Printf("WARNING: パスのコピーに失敗しました: %v", err)
+						}
+					}
+				case <-appCtx.Done():
+					return
+				}
+			}
+		}(i, slot)
+	}
+}
+
+// addRecentArchive は完了したスレッドを一覧の先頭に追加し、メニュー表示を更新します。
+// 同じスレッドが再アーカイブされた場合は、古いエントリを置き換えます。
+func addRecentArchive(archived core.ArchivedThread) {
+	recentArchivesMutex.Lock()
+	defer recentArchivesMutex.Unlock()
+
+	updated := []core.ArchivedThread{archived}
+	for _, a := range recentArchives {
+		if a.SavePath == archived.SavePath {
+			continue
+		}
+		updated = append(updated, a)
+	}
+	if len(updated) > maxRecentArchives {
+		updated = updated[:maxRecentArchives]
+	}
+	recentArchives = updated
+
+	for i, slot := range recentSlots {
+		if i >= len(recentArchives) {
+			slot.item.Hide()
+			continue
+		}
+		a := recentArchives[i]
+		slot.item.SetTitle(fmt.Sprintf("%s %s", a.CompletedAt.Format("15:04"), core.TruncateGraphemes(a.Title, 30)))
+		slot.item.SetTooltip(a.SavePath)
+		slot.item.Show()
+	}
+	if len(recentArchives) > 0 {
+		mRecentArchives.Enable()
+	}
+}
+
+// recentArchiveAt は、指定位置のアーカイブ情報を返します。
+func recentArchiveAt(index int) (core.ArchivedThread, bool) {
+	recentArchivesMutex.Lock()
+	defer recentArchivesMutex.Unlock()
+	if index < 0 || index >= len(recentArchives) {
+		return core.ArchivedThread{}, false
+	}
+	return recentArchives[index], true
+}
+
+// copyToClipboard は、OS標準のコマンドを使用してテキストをクリップボードにコピーします。
+func copyToClipboard(text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("clip")
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	default:
+		if _, err := exec.LookPath("wl-copy"); err == nil {
+			cmd = exec.Command("wl-copy")
+		} else {
+			cmd = exec.Command("xclip", "-selection", "clipboard")
+		}
+	}
+	cmd.Stdin = strings.NewReader(text)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("クリップボードへのコピーに失敗しました: %w", err)
+	}
+	return nil
+}
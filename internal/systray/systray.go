@@ -7,7 +7,6 @@ import (
 	"log"
 	"os/exec"
 	"runtime"
-	"strconv"
 	"sync"
 	"time"
 
@@ -29,6 +28,7 @@ const (
 	ClickOpenRootDir
 	ClickOpenConfig
 	ClickOpenLogs
+	ClickOpenVerification
 	ClickExit
 )
 
@@ -48,7 +48,7 @@ var (
 	// --- チャネル ---
 	uiEventChannel      chan UIEvent
 	coreCommandChannel  chan string
-	statusUpdateChannel chan AppStatus
+	statusUpdateChannel <-chan AppStatus
 
 	// --- メニュー項目 ---
 	mStatusState   *systray.MenuItem
@@ -63,18 +63,22 @@ var (
 	mOpenRootDir   *systray.MenuItem
 	mOpenConfig    *systray.MenuItem
 	mOpenLogs      *systray.MenuItem
+	mOpenVerify    *systray.MenuItem
 	mExit          *systray.MenuItem
 
 	// --- ライフサイクル管理 ---
+	engine    *core.Engine
+	appCfg    *config.Config
 	appCtx    context.Context
 	appCancel context.CancelFunc
 	coreWg    sync.WaitGroup
 )
 
 // RunSystrayApp は、システムトレイアプリケーションを開始します。
-func RunSystrayApp(globalCtx context.Context, showConsoleFunc, hideConsoleFunc func(), toggleLoggerFunc func(bool, string) error) {
+func RunSystrayApp(globalCtx context.Context, cfg *config.Config, showConsoleFunc, hideConsoleFunc func(), toggleLoggerFunc func(bool, string) error) {
 	appCtx, appCancel = context.WithCancel(globalCtx)
 	defer appCancel()
+	appCfg = cfg
 
 	// コールバック関数を保持
 	showConsole = showConsoleFunc
@@ -128,17 +132,24 @@ func onReady() {
 	systray.AddSeparator()
 
 	mOpenRootDir = systray.AddMenuItem("保存先フォルダを開く", "アーカイブが保存されているメインフォルダを開きます")
+	buildRecentArchivesMenu()
+	buildRecentErrorsMenu()
 	mLogsAndConfig := systray.AddMenuItem("ログと設定", "")
 	mOpenConfig = mLogsAndConfig.AddSubMenuItem("設定画面を開く", "Web UIで設定を編集します")
 	mOpenLogs = mLogsAndConfig.AddSubMenuItem("最新ログを開く", "ログファイルを開きます")
+	mOpenVerify = mLogsAndConfig.AddSubMenuItem("検証結果を開く", "検証で見つかった問題をWeb UIで確認・修復します")
 	systray.AddSeparator()
 
+	buildUpdateMenu()
 	mExit = systray.AddMenuItem("GIBAを終了", "アプリケーションを安全に終了します")
 
 	// 3. チャネルの初期化
 	uiEventChannel = make(chan UIEvent)
-	coreCommandChannel = make(chan string)
-	statusUpdateChannel = make(chan AppStatus, 10)
+	// UI更新ループは状態の唯一の受信側なので、コマンドの送信で止まらないようバッファを持たせ、送信はブロックしない
+	coreCommandChannel = make(chan string, coreCommandBuffer)
+	engine = core.NewEngine(appCfg)
+	statusCh, unsubscribe := engine.Subscribe()
+	statusUpdateChannel = statusCh
 
 	// 4. UIイベントハンドラの起動
 	go func() {
@@ -182,6 +193,8 @@ func onReady() {
 				uiEventChannel <- ClickOpenConfig
 			case <-mOpenLogs.ClickedCh:
 				uiEventChannel <- ClickOpenLogs
+			case <-mOpenVerify.ClickedCh:
+				uiEventChannel <- ClickOpenVerification
 			case <-mExit.ClickedCh:
 				uiEventChannel <- ClickExit
 			}
@@ -193,7 +206,7 @@ func onReady() {
 
 	// 6. コアエンジンの起動
 	coreWg.Add(1)
-	go startCoreEngine(appCtx, coreCommandChannel, statusUpdateChannel, &coreWg)
+	go runCoreEngine(appCtx, appCfg, coreCommandChannel, unsubscribe, &coreWg)
 
 	log.Println("UIの構築とバックグラウンドエンジンの起動が完了しました。")
 }
@@ -216,19 +229,13 @@ func startUIUpdateLoop() {
 	var isWatching bool
 	var animationFrame int
 
-	// 各タスクの状態を追跡するためのマップ
-	taskStates := make(map[string]core.AppState)
+	// タスクごとの状態から導出した全体の状態（エンジンが配信するたびに更新）
+	var summary core.StatusSummary
 
 	for {
 		// --- 全体の実行状態を判定 ---
-		var isAnyTaskRunning bool
-		var runningTaskCount int
-		for _, state := range taskStates {
-			if state == core.StateRunning || state == core.StatePreparing {
-				isAnyTaskRunning = true
-				runningTaskCount++
-			}
-		}
+		runningTaskCount := summary.RunningTasks
+		isAnyTaskRunning := runningTaskCount > 0
 
 		select {
 		case <-ticker.C:
@@ -270,13 +277,13 @@ func startUIUpdateLoop() {
 				return
 			case ClickToggleWatch:
 				log.Println("UI: 監視モード切り替えイベント受信。")
-				coreCommandChannel <- "toggle_watch"
+				sendCoreCommand("toggle_watch")
 			case ClickRunOnce:
 				log.Println("UI: 手動実行イベント受信。")
-				coreCommandChannel <- "run_once"
+				sendCoreCommand("run_once")
 			case ClickPauseResume:
 				log.Println("UI: 一時停止/再開イベント受信。")
-				coreCommandChannel <- "toggle_pause"
+				sendCoreCommand("toggle_pause")
 			case ClickOpenConfig:
 				log.Println("UI: 設定Web UIを開くイベント受信。")
 				webui.StartWebServer()
@@ -285,47 +292,70 @@ func startUIUpdateLoop() {
 				openCommand(".")
 			case ClickOpenLogs:
 				log.Println("UI: ログファイルを開くイベント受信。")
-				today := time.Now().Format("2006-01-02")
+				today := time.Now().In(config.Location(appCfg.Timezone)).Format("2006-01-02")
 				logFileName := fmt.Sprintf("giba_%s.log", today)
 				openCommand(logFileName)
+			case ClickOpenVerification:
+				log.Println("UI: 検証結果ページを開くイベント受信。")
+				webui.OpenPage("/verification")
 			}
 		case status := <-statusUpdateChannel:
-			// --- 状態の更新 ---
-			if status.TaskName != "" {
-				taskStates[status.TaskName] = status.State
+			if status.Archived != nil {
+				addRecentArchive(*status.Archived)
+			}
+			if status.Error != nil {
+				refreshRecentErrors()
+			}
+			if status.Snapshot == nil {
+				continue
 			}
-			isWatching = status.IsWatching
 
-			// NEXT_RUN情報の解析 (Detailフィールドに含まれると仮定: "NEXT_RUN:1234567890")
-			if len(status.Detail) > 9 && status.Detail[:9] == "NEXT_RUN:" {
-				tsStr := status.Detail[9:]
-				if ts, err := strconv.ParseInt(tsStr, 10, 64); err == nil {
-					nextRunTime = time.Unix(ts, 0)
-				}
+			// --- 状態の更新 ---
+			// 最後に状態を送ったタスクではなく、すべてのタスクの状態から導出した全体の状態を表示する
+			summary = status.Snapshot.Summary
+			isWatching = summary.IsWatching
+			isAnyTaskRunning = summary.RunningTasks > 0
+			nextRunTime = time.Time{}
+			if summary.NextRun != nil {
+				nextRunTime = *summary.NextRun
 			}
 
 			// --- UIの更新 ---
-			// isAnyTaskRunning はループの先頭で再計算される
 			var iconState string
 			if isAnyTaskRunning {
 				iconState = "実行中"
-			} else if isWatching {
+			} else if isWatching && summary.State != core.StatePaused {
 				iconState = "監視中"
 			} else {
-				iconState = status.State.String()
+				iconState = summary.State.String()
 			}
 
-			iconData := icon.GetIconData(iconState)
+			// 複合状態はベースアイコンにバッジを重ねて表現する
+			badges := icon.BadgeNone
+			if summary.ErrorTasks > 0 {
+				badges |= icon.BadgeError
+			}
+			if isAnyTaskRunning {
+				badges |= icon.BadgeDownloading
+			}
+			if summary.IsPaused {
+				badges |= icon.BadgePaused
+			}
+			iconData, err := icon.ComposeIcon(iconState, badges)
+			if err != nil {
+				log.Printf("WARNING: バッジ付きアイコンの生成に失敗しました: %v", err)
+				iconData = icon.GetIconData(iconState)
+			}
 			if err := icon.ValidateIconData(iconData); err == nil {
 				systray.SetIcon(iconData)
 			}
 
-			systray.SetTooltip(fmt.Sprintf("GIBA: %s", iconState))
+			systray.SetTooltip(formatTooltip(iconState, *status.Snapshot))
 			mStatusState.SetTitle(fmt.Sprintf("状態: %s", iconState))
-			mStatusDetail.SetTitle(fmt.Sprintf("詳細: %s", status.Detail))
-			mStatusSession.SetTitle(fmt.Sprintf("セッション: %s", status.SessionInfo))
+			mStatusDetail.SetTitle(fmt.Sprintf("詳細: %s", summary.Detail))
+			mStatusSession.SetTitle(fmt.Sprintf("セッション: %s", summary.SessionInfo))
 
-			if status.IsWatching {
+			if summary.IsWatching {
 				mToggleWatch.Check()
 			} else {
 				mToggleWatch.Uncheck()
@@ -337,7 +367,7 @@ func startUIUpdateLoop() {
 				mRunOnce.Enable()
 			}
 
-			if status.IsPaused {
+			if summary.IsPaused {
 				mPauseResume.SetTitle("活動を再開する")
 			} else {
 				mPauseResume.SetTitle("すべての活動を一時停止")
@@ -350,6 +380,20 @@ func startUIUpdateLoop() {
 	}
 }
 
+// coreCommandBuffer は、コアエンジンが処理を待っているコマンドの上限です。
+const coreCommandBuffer = 8
+
+// sendCoreCommand は、コアエンジンにコマンドを送ります。
+// エンジンの状態の配信はUI更新ループが受け取るため、ここで送信を待つとお互いを待ち合って止まります。
+// バッファが埋まっているときは待たずにコマンドを捨てます。
+func sendCoreCommand(cmd string) {
+	select {
+	case coreCommandChannel <- cmd:
+	default:
+		log.Printf("WARNING: コアエンジンが処理中のため、コマンド '%s' を破棄しました。", cmd)
+	}
+}
+
 // openCommandはOSのデフォルトアプリケーションでファイルやフォルダを開きます。
 func openCommand(path string) {
 	var cmd *exec.Cmd
@@ -366,18 +410,14 @@ func openCommand(path string) {
 	}
 }
 
-// startCoreEngineは、コアエンジンを起動するためのスタブ関数です。
-func startCoreEngine(ctx context.Context, commandCh <-chan string, statusCh chan<- AppStatus, wg *sync.WaitGroup) {
+// runCoreEngine は、コアエンジンを開始し、UIからのコマンドをエンジンの操作に変換します。
+// ctx がキャンセルされると、購読を解除してからエンジンを停止し、すべてのタスクの終了を待ちます。
+func runCoreEngine(ctx context.Context, cfg *config.Config, commandCh <-chan string, unsubscribe func(), wg *sync.WaitGroup) {
 	defer wg.Done()
-	log.Println("コアエンジン(スタブ)が起動しました。")
 
-	cfg, err := config.LoadAndResolve("config.json")
-	if err != nil {
-		log.Printf("FATAL: 設定ファイルの読み込みに失敗しました: %v", err)
-		statusCh <- AppStatus{State: core.StateError, Detail: fmt.Sprintf("設定エラー: %v", err), HasError: true, ConfigLoaded: false}
-		return
+	if cfg.CheckForUpdates {
+		go startUpdateCheck(ctx)
 	}
-	log.Printf("設定ファイル(v%s)を正常に読み込みました。", cfg.ConfigVersion)
 
 	// 初期ログ設定の反映
 	if cfg.EnableLogFile {
@@ -391,162 +431,44 @@ func startCoreEngine(ctx context.Context, commandCh <-chan string, statusCh chan
 		}
 	}
 
-	isWatching := false
-	isPaused := false
-
-	// セッション統計の初期化
-	sessionStats := &core.SessionStats{
-		StartTime:         time.Now(),
-		ThreadsArchived:   0,
-		FilesDownloaded:   0,
-		TotalBytesWritten: 0,
-	}
-
-	statusCh <- AppStatus{
-		State:        core.StateIdle,
-		Detail:       "待機中",
-		SessionInfo:  sessionStats.FormatSessionInfo(),
-		IsWatching:   isWatching,
-		IsPaused:     isPaused,
-		HasError:     false,
-		ConfigLoaded: true,
+	if err := engine.Start(ctx); err != nil {
+		log.Printf("ERROR: コアエンジンの開始に失敗しました: %v", err)
+		return
 	}
-
-	tasks := cfg.Tasks
-	if len(tasks) == 0 {
+	log.Println("コアエンジンを開始しました。")
+	if len(cfg.Tasks) == 0 {
 		log.Println("設定にタスクが見つかりませんでした。")
-		statusCh <- AppStatus{
-			State:        core.StateIdle,
-			Detail:       "タスクなし",
-			SessionInfo:  sessionStats.FormatSessionInfo(),
-			IsWatching:   isWatching,
-			IsPaused:     isPaused,
-			HasError:     false,
-			ConfigLoaded: true,
-		}
 	}
 
-	// 監視モード用のタスク管理
-	var watchTaskCancel context.CancelFunc
-	var watchTaskWg sync.WaitGroup
-
-	// 統計情報を定期的に更新するタイマー
-	statsTicker := time.NewTicker(10 * time.Second)
-	defer statsTicker.Stop()
-
 	for {
 		select {
-		case <-statsTicker.C:
-			// 統計情報を定期的に更新（10秒ごと）
-			// 現在の状態を保持したまま、SessionInfoだけ更新
-			statusCh <- AppStatus{
-				State:       core.StateIdle,
-				Detail:      "統計更新",
-				SessionInfo: sessionStats.FormatSessionInfo(),
-				IsWatching:  isWatching,
-				IsPaused:    isPaused,
-			}
 		case cmd := <-commandCh:
-			log.Printf("コアエンジン(スタブ): コマンド '%s' を受信しました。", cmd)
-			switch cmd {
-			case "toggle_watch":
-				isWatching = !isWatching
-				if isWatching {
-					// 監視モードを開始
-					log.Println("監視モードを開始します...")
-					statusCh <- AppStatus{State: core.StateWatching, Detail: "監視モード有効", SessionInfo: sessionStats.FormatSessionInfo(), IsWatching: isWatching, IsPaused: isPaused, HasError: false, ConfigLoaded: true}
-
-					// 既存の監視タスクがあればキャンセル
-					if watchTaskCancel != nil {
-						watchTaskCancel()
-						watchTaskWg.Wait()
-					}
-
-					// 新しい監視タスクを起動
-					watchCtx, cancel := context.WithCancel(ctx)
-					watchTaskCancel = cancel
-
-					for _, task := range tasks {
-						if task.Enabled == nil || !*task.Enabled {
-							continue
-						}
-						watchTaskWg.Add(1)
-						go func(t config.Task) {
-							defer watchTaskWg.Done()
-							core.ExecuteTask(watchCtx, t, cfg.Network, cfg.SafetyStopMinDiskGB, true, statusCh)
-						}(task)
-					}
-				} else {
-					// 監視モードを停止
-					log.Println("監視モードを停止します...")
-					if watchTaskCancel != nil {
-						watchTaskCancel()
-						watchTaskWg.Wait()
-						watchTaskCancel = nil
-					}
-					statusCh <- AppStatus{State: core.StateIdle, Detail: "監視モード無効", SessionInfo: sessionStats.FormatSessionInfo(), IsWatching: isWatching, IsPaused: isPaused, HasError: false, ConfigLoaded: true}
-				}
-			case "run_once":
-				// isRunning フラグはUI側で管理するため、ここでは直接操作しない
-				go func() {
-					// 監視モード中の場合、一時的に監視タスクをキャンセル
-					var wasWatching bool
-					var tempCancel context.CancelFunc
-					if isWatching && watchTaskCancel != nil {
-						wasWatching = true
-						tempCancel = watchTaskCancel
-						watchTaskCancel = nil
-						tempCancel()
-						watchTaskWg.Wait()
-						log.Println("監視タスクを一時停止して手動実行を開始します")
-					}
-
-					statusCh <- AppStatus{State: core.StateRunning, Detail: "手動実行中...", SessionInfo: sessionStats.FormatSessionInfo(), IsWatching: isWatching, IsPaused: isPaused, HasError: false, ConfigLoaded: true}
-
-					var runOnceWg sync.WaitGroup
-					for _, task := range tasks {
-						if task.Enabled == nil || !*task.Enabled {
-							continue
-						}
-						runOnceWg.Add(1)
-						go func(t config.Task) {
-							defer runOnceWg.Done()
-							core.ExecuteTask(ctx, t, cfg.Network, cfg.SafetyStopMinDiskGB, false, statusCh)
-						}(task)
-					}
-					runOnceWg.Wait()
-
-					statusCh <- AppStatus{State: core.StateIdle, Detail: "手動実行完了", SessionInfo: sessionStats.FormatSessionInfo(), IsWatching: isWatching, IsPaused: isPaused, HasError: false, ConfigLoaded: true}
-
-					// 監視モードが有効だった場合、再開
-					if wasWatching {
-						log.Println("監視タスクを再開します")
-						watchCtx, cancel := context.WithCancel(ctx)
-						watchTaskCancel = cancel
-
-						for _, task := range tasks {
-							if task.Enabled == nil || !*task.Enabled {
-								continue
-							}
-							watchTaskWg.Add(1)
-							go func(t config.Task) {
-								defer watchTaskWg.Done()
-								core.ExecuteTask(watchCtx, t, cfg.Network, cfg.SafetyStopMinDiskGB, true, statusCh)
-							}(task)
-						}
-					}
-				}()
-			case "toggle_pause":
-				isPaused = !isPaused
-				if isPaused {
-					statusCh <- AppStatus{State: core.StatePaused, Detail: "全活動を一時停止しました", SessionInfo: sessionStats.FormatSessionInfo(), IsWatching: isWatching, IsPaused: isPaused, HasError: false, ConfigLoaded: true}
-				} else {
-					statusCh <- AppStatus{State: core.StateIdle, Detail: "活動を再開しました", SessionInfo: sessionStats.FormatSessionInfo(), IsWatching: isWatching, IsPaused: isPaused, HasError: false, ConfigLoaded: true}
-				}
-			}
+			log.Printf("コアエンジン: コマンド '%s' を受信しました。", cmd)
+			// エンジンの操作は状態を配信し、その受信はUI更新ループが行うため、
+			// このループでは操作の終了を待たずに次のコマンドを受け付ける
+			go runCoreCommand(cmd)
 		case <-ctx.Done():
-			log.Println("コアエンジン(スタブ)が終了シグナルを受信し、シャットダウンします。")
+			log.Println("コアエンジンが終了シグナルを受信し、シャットダウンします。")
+			// UI更新ループは既に終了している可能性があるため、配信を待たないよう先に購読を解除する
+			unsubscribe()
+			engine.Stop()
 			return
 		}
 	}
 }
+
+// runCoreCommand は、UIからのコマンドをエンジンの操作として実行します。
+func runCoreCommand(cmd string) {
+	var err error
+	switch cmd {
+	case "toggle_watch":
+		_, err = engine.ToggleWatch()
+	case "run_once":
+		err = engine.RunOnce()
+	case "toggle_pause":
+		_, err = engine.TogglePause()
+	}
+	if err != nil {
+		log.Printf("WARNING: コマンド '%s' の実行に失敗しました: %v", cmd, err)
+	}
+}
@@ -0,0 +1,42 @@
+package systray
+
+import (
+	"fmt"
+	"strings"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// maxTooltipRunes は、ツールチップに表示する最大の文字数です（Windows の通知領域は128文字まで）。
+const maxTooltipRunes = 127
+
+// formatTooltip は、全体の状態の下にタスクごとの状態を1行ずつ並べたツールチップを返します。
+// 文字数の上限を超える場合は、残りのタスクを件数のみで表示します。
+func formatTooltip(title string, snap core.StatusSnapshot) string {
+	lines := []string{fmt.Sprintf("GIBA: %s", title)}
+	used := len([]rune(lines[0]))
+
+	tasks := snap.SortedTasks()
+	for i, t := range tasks {
+		line := fmt.Sprintf("%s: %s", t.TaskName, t.State)
+		if t.NextRun != nil {
+			line += fmt.Sprintf(" (次回 %s)", t.NextRun.Format("15:04"))
+		}
+		if t.HasError && t.State != core.StateError {
+			line += " ⚠"
+		}
+
+		rest := ""
+		if remaining := len(tasks) - i - 1; remaining > 0 {
+			rest = fmt.Sprintf("\n…他 %d 件", remaining)
+		}
+		// 改行1文字分を含めて、残りの件数の表示が収まる場合のみ追加する
+		if used+1+len([]rune(line))+len([]rune(rest)) > maxTooltipRunes {
+			lines = append(lines, fmt.Sprintf("…他 %d 件", len(tasks)-i))
+			break
+		}
+		lines = append(lines, line)
+		used += 1 + len([]rune(line))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package systray
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"GoImageBoardArchiver/internal/update"
+
+	"fyne.io/systray"
+)
+
+var (
+	mUpdate *systray.MenuItem
+
+	updateReleaseURL   string
+	updateReleaseMutex sync.Mutex
+)
+
+// buildUpdateMenu は、新しいバージョンが見つかった場合に表示するメニュー項目を構築します。
+// 初期状態では非表示で、クリックするとリリースページをブラウザで開きます。
+func buildUpdateMenu() {
+	mUpdate = systray.AddMenuItem("新しいバージョンが利用可能です", "クリックしてリリースページを開きます")
+	mUpdate.Hide()
+
+	go func() {
+		for range mUpdate.ClickedCh {
+			updateReleaseMutex.Lock()
+			url := updateReleaseURL
+			updateReleaseMutex.Unlock()
+			if url != "" {
+				log.Println("UI: リリースページを開くイベント受信。")
+				openCommand(url)
+			}
+		}
+	}()
+}
+
+// startUpdateCheck は、定期的な更新確認を開始し、新しいバージョンが見つかったらメニューに表示します。
+func startUpdateCheck(ctx context.Context) {
+	update.StartPeriodicCheck(ctx, update.DefaultCheckInterval, func(status update.Status) {
+		updateReleaseMutex.Lock()
+		updateReleaseURL = status.ReleaseURL
+		updateReleaseMutex.Unlock()
+
+		mUpdate.SetTitle(fmt.Sprintf("新しいバージョン %s が利用可能です", status.Latest))
+		mUpdate.SetTooltip(fmt.Sprintf("現在のバージョン: %s（クリックしてリリースページを開きます。`giba self-update` で更新できます）", status.Current))
+		mUpdate.Show()
+	})
+}
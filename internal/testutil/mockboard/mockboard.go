@@ -0,0 +1,421 @@
+// Package mockboard は、エンドツーエンドのテスト用に、ふたば☆ちゃんねる形式の板（カタログ・スレッド・メディア）を
+// httptest で配信する偽のサーバーを提供します。
+//
+// スレッドのページは ETag / Last-Modified 付きで配信し、条件付きリクエストには 304 Not Modified を返します。
+// スレッドやレスの追加・削除、応答の遅延、任意のパスの 404 をテストの途中で切り替えられるため、
+// 実際のサイトにアクセスせずに、アーカイブ・更新・削除検知の一連のサイクルを再現できます。
+package mockboard
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// BoardPath は、偽の板のパスです。板のURLは Server.BoardURL で取得できます。
+const BoardPath = "/b/"
+
+// Media は、レスに添付されたメディアファイルです。
+type Media struct {
+	// Name は、ふたば形式のファイル名です（13桁以上の数字 + 拡張子。例: "1700000000001.jpg"）。
+	Name string
+	// Data は、ファイルの内容です。空の場合はファイル名から生成した内容を返します。
+	Data []byte
+}
+
+// Post は、スレッド内のレス1件です。スレッドの最初のレスがOPになります。
+type Post struct {
+	No    int64
+	Text  string
+	Media *Media // 添付ファイルがない場合は nil
+}
+
+// thread は、配信中のスレッドです。
+type thread struct {
+	id       string
+	title    string
+	posts    []Post
+	version  int       // 内容が変わるたびに増える。ETag に使用する
+	modified time.Time // 最後に内容が変わった時刻。Last-Modified に使用する
+}
+
+// touch は、スレッドの内容が変わったことを記録します。
+func (th *thread) touch() {
+	th.version++
+	th.modified = time.Now()
+}
+
+// etag は、現在の内容を表す ETag です。
+func (th *thread) etag() string {
+	return fmt.Sprintf(`"%s-%d"`, th.id, th.version)
+}
+
+// Server は、偽の板を配信する HTTP サーバーです。メソッドは並行に呼び出せます。
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	threads  map[string]*thread
+	order    []string // カタログに表示する順（追加順）
+	latency  time.Duration
+	notFound map[string]bool
+	requests map[string]int
+	// notModified は、パスごとの 304 Not Modified の応答回数です。
+	notModified map[string]int
+	// notice は、板のトップページ（futaba.htm）の注意書きです。
+	notice string
+}
+
+// New は、スレッドのない偽の板を起動します。テストの終了時に Close を呼び出してください。
+func New() *Server {
+	s := &Server{
+		threads:     make(map[string]*thread),
+		notFound:    make(map[string]bool),
+		requests:    make(map[string]int),
+		notModified: make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BoardURL は、タスクの target_board_url に指定する板のURLを返します。
+func (s *Server) BoardURL() string {
+	return s.URL + BoardPath
+}
+
+// AddThread は、スレッドを追加してカタログに表示します。同じIDのスレッドがある場合は置き換えます。
+func (s *Server) AddThread(id, title string, posts ...Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.threads[id]; !ok {
+		s.order = append(s.order, id)
+	}
+	th := &thread{id: id, title: title, posts: append([]Post(nil), posts...)}
+	if old, ok := s.threads[id]; ok {
+		th.version = old.version
+	}
+	th.touch()
+	s.threads[id] = th
+}
+
+// AddPost は、スレッドの末尾にレスを追加します。スレッドが存在しない場合は何もしません。
+func (s *Server) AddPost(threadID string, post Post) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if th, ok := s.threads[threadID]; ok {
+		th.posts = append(th.posts, post)
+		th.touch()
+	}
+}
+
+// DeletePost は、スレッドからレスを削除します（削除されたレスの検知を再現します）。
+func (s *Server) DeletePost(threadID string, no int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	th, ok := s.threads[threadID]
+	if !ok {
+		return
+	}
+	posts := th.posts[:0]
+	for _, p := range th.posts {
+		if p.No != no {
+			posts = append(posts, p)
+		}
+	}
+	th.posts = posts
+	th.touch()
+}
+
+// EditPost は、レスの本文を書き換えます（レス数・メディア数の変わらない編集を再現します）。
+func (s *Server) EditPost(threadID string, no int64, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	th, ok := s.threads[threadID]
+	if !ok {
+		return
+	}
+	for i := range th.posts {
+		if th.posts[i].No == no {
+			th.posts[i].Text = text
+		}
+	}
+	th.touch()
+}
+
+// RemoveMedia は、レスを残したまま添付ファイルのみを削除します（画像だけが削除された状態を再現します）。
+// 削除したメディアのファイルは 404 を返します。
+func (s *Server) RemoveMedia(threadID string, no int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	th, ok := s.threads[threadID]
+	if !ok {
+		return
+	}
+	for i := range th.posts {
+		if th.posts[i].No == no {
+			th.posts[i].Media = nil
+		}
+	}
+	th.touch()
+}
+
+// DeleteThread は、スレッドを削除します（スレッドが落ちた状態を再現します）。
+// カタログから消え、スレッドのページとメディアは 404 を返します。
+func (s *Server) DeleteThread(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.threads, id)
+	for i, tid := range s.order {
+		if tid == id {
+			s.order = append(s.order[:i:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// SetLatency は、すべての応答を返す前に待つ時間を設定します。
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetNotFound は、path（例: "/b/src/1700000000001.jpg"）への要求に 404 を返すかどうかを設定します。
+// スレッドがカタログに残っている間にページだけが消えた状態や、メディアの欠落を再現するために使用します。
+func (s *Server) SetNotFound(path string, notFound bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if notFound {
+		s.notFound[path] = true
+	} else {
+		delete(s.notFound, path)
+	}
+}
+
+// Requests は、path への要求の回数を返します。
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// NotModifiedResponses は、path への要求に 304 Not Modified を返した回数を返します。
+func (s *Server) NotModifiedResponses(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.notModified[path]
+}
+
+// ThreadPath は、スレッドのページのパスを返します。
+func ThreadPath(id string) string {
+	return BoardPath + "res/" + id + ".htm"
+}
+
+// MediaPath は、メディアファイルのパスを返します。
+func MediaPath(name string) string {
+	return BoardPath + "src/" + name
+}
+
+// ThumbPath は、メディアファイルのサムネイルのパスを返します（ふたばのサムネイルは常に "<名前>s.jpg"）。
+func ThumbPath(name string) string {
+	return BoardPath + "thumb/" + strings.TrimSuffix(name, extension(name)) + "s.jpg"
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	latency := s.latency
+	notFound := s.notFound[r.URL.Path]
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if notFound {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := r.URL.Path
+	switch {
+	case path == BoardPath+"futaba.php" && r.URL.Query().Get("mode") == "cat":
+		s.writeHTML(w, s.catalogHTML())
+	case path == BoardPath+"futaba.htm":
+		s.writeHTML(w, s.boardHTML())
+	case strings.HasPrefix(path, BoardPath+"res/") && strings.HasSuffix(path, ".htm"):
+		s.serveThread(w, r, strings.TrimSuffix(strings.TrimPrefix(path, BoardPath+"res/"), ".htm"))
+	case strings.HasPrefix(path, BoardPath+"src/"):
+		s.serveMedia(w, r, false)
+	case strings.HasPrefix(path, BoardPath+"thumb/"):
+		s.serveMedia(w, r, true)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveThread は、スレッドのページを ETag と Last-Modified 付きで返します。
+// If-None-Match（指定がなければ If-Modified-Since）が現在の内容と一致する場合は 304 Not Modified を返します。
+func (s *Server) serveThread(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	th, ok := s.threads[id]
+	var etag string
+	var modified time.Time
+	if ok {
+		etag, modified = th.etag(), th.modified.UTC().Truncate(time.Second)
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	if isNotModified(r, etag, modified) {
+		s.mu.Lock()
+		s.notModified[r.URL.Path]++
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, ok := s.threadHTML(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeHTML(w, body)
+}
+
+// isNotModified は、条件付きリクエストの条件から、クライアントの保持する内容が最新かを判定します。
+func isNotModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return inm == etag
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		return err == nil && !modified.After(since)
+	}
+	return false
+}
+
+// writeHTML は、ふたばと同じく Shift_JIS でHTMLを返します。
+func (s *Server) writeHTML(w http.ResponseWriter, body string) {
+	encoded, err := japanese.ShiftJIS.NewEncoder().String(body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Shift_JIS への変換に失敗しました: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+	fmt.Fprint(w, encoded)
+}
+
+// serveMedia は、配信中のスレッドに添付されたメディア（thumb が true の場合はそのサムネイル）を返します。
+func (s *Server) serveMedia(w http.ResponseWriter, r *http.Request, thumb bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, th := range s.threads {
+		for _, p := range th.posts {
+			if p.Media == nil {
+				continue
+			}
+			servedPath := MediaPath(p.Media.Name)
+			if thumb {
+				servedPath = ThumbPath(p.Media.Name)
+			}
+			if servedPath != r.URL.Path {
+				continue
+			}
+			data := p.Media.Data
+			if len(data) == 0 {
+				data = []byte("media:" + p.Media.Name)
+			}
+			if thumb {
+				data = append([]byte("thumb:"), data...)
+			}
+			w.Write(data)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// SetNotice は、板のトップページ（futaba.htm）の注意書きを設定します。
+func (s *Server) SetNotice(notice string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notice = notice
+}
+
+// boardHTML は、投稿フォームと注意書き（td.chui）だけの板のトップページを生成します。
+func (s *Server) boardHTML() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=Shift_JIS\"><title>板</title></head><body>\n"+
+		"<form action=\"futaba.php\" method=\"POST\"><table><tr><td class=\"chui\"><ul><li>%s</li></ul></td></tr></table></form>\n</body></html>\n", html.EscapeString(s.notice))
+}
+
+// catalogHTML は、mode=cat のカタログページを生成します。
+func (s *Server) catalogHTML() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>カタログ</title></head><body>\n<table border=1 align=center id='cattable'><tr>\n")
+	for _, id := range s.order {
+		th := s.threads[id]
+		fmt.Fprintf(&sb, "<td><a href='res/%s.htm' target='_blank'>", th.id)
+		if len(th.posts) > 0 && th.posts[0].Media != nil {
+			fmt.Fprintf(&sb, "<img src='/b/cat/%s' border=0>", strings.TrimPrefix(ThumbPath(th.posts[0].Media.Name), BoardPath+"thumb/"))
+		}
+		fmt.Fprintf(&sb, "</a><br><small>%s</small><br><font size=2>%d</font></td>\n", html.EscapeString(th.title), len(th.posts))
+	}
+	sb.WriteString("</tr></table>\n</body></html>\n")
+	return sb.String()
+}
+
+// threadHTML は、スレッドのページを生成します。ふたばと同じく、OP は div.thre の直下、以降のレスは table として出力します。
+func (s *Server) threadHTML(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	th, ok := s.threads[id]
+	if !ok {
+		return "", false
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=Shift_JIS\"><title>%s</title></head><body>\n", html.EscapeString(th.title))
+	sb.WriteString("<div class=\"thre\">\n")
+	for i, p := range th.posts {
+		if i > 0 {
+			fmt.Fprintf(&sb, "<table border=0><tr><td class=rts>…</td><td class=rtd id=\"r%d\">", p.No)
+		}
+		if p.Media != nil {
+			fmt.Fprintf(&sb, "<a href=\"%s\" target=\"_blank\"><img src=\"%s\" border=0></a>", MediaPath(p.Media.Name), ThumbPath(p.Media.Name))
+		}
+		fmt.Fprintf(&sb, "<span class=\"cno\">No.%d</span><blockquote>%s</blockquote>", p.No, html.EscapeString(p.Text))
+		if i > 0 {
+			sb.WriteString("</td></tr></table>")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</div>\n</body></html>\n")
+	return sb.String(), true
+}
+
+func extension(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i:]
+	}
+	return ""
+}
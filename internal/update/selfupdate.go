@@ -0,0 +1,205 @@
+package update
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PublicKey は、リリースの checksums.txt の署名を検証するためのed25519公開鍵（Base64）です。
+// リリースビルドでは -ldflags "-X GoImageBoardArchiver/internal/update.PublicKey=..." で埋め込まれます。
+// 空の場合は署名を検証できないため、自己更新を行いません。
+var PublicKey = ""
+
+const (
+	checksumsAssetName = "checksums.txt"
+	signatureAssetName = "checksums.txt.sig"
+	maxMetadataSize    = 1 << 20 // checksums.txt と署名の最大サイズ
+)
+
+// ErrNoPublicKey は、署名検証用の公開鍵が埋め込まれていないことを示します。
+var ErrNoPublicKey = errors.New("署名検証用の公開鍵が埋め込まれていないため、自己更新できません")
+
+// BinaryAssetName は、現在のOS・アーキテクチャ向けの実行ファイルのアセット名を返します（例: giba_windows_amd64.exe）。
+func BinaryAssetName() string {
+	name := fmt.Sprintf("giba_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// Apply は、リリースから現在の環境向けの実行ファイルをダウンロードし、exePath の実行ファイルと置き換えます。
+// checksums.txt の署名を公開鍵で検証し、ダウンロードしたファイルのSHA-256が checksums.txt と一致した場合のみ置き換えます。
+// 置き換え前の実行ファイルは exePath + ".old" として残します。
+func Apply(ctx context.Context, release *Release, exePath string) error {
+	if PublicKey == "" {
+		return ErrNoPublicKey
+	}
+	return apply(ctx, newHTTPClient(), release, exePath, BinaryAssetName(), PublicKey)
+}
+
+func apply(ctx context.Context, client *http.Client, release *Release, exePath, assetName, publicKey string) error {
+	binary, ok := findAsset(release, assetName)
+	if !ok {
+		return fmt.Errorf("リリース %s に %s 向けの実行ファイル (%s) がありません", release.TagName, runtime.GOOS+"/"+runtime.GOARCH, assetName)
+	}
+	checksumsAsset, ok := findAsset(release, checksumsAssetName)
+	if !ok {
+		return fmt.Errorf("リリース %s に %s がありません", release.TagName, checksumsAssetName)
+	}
+	signatureAsset, ok := findAsset(release, signatureAssetName)
+	if !ok {
+		return fmt.Errorf("リリース %s に %s がありません", release.TagName, signatureAssetName)
+	}
+
+	checksums, err := downloadBytes(ctx, client, checksumsAsset.BrowserDownloadURL)
+	if err != nil {
+		return err
+	}
+	signature, err := downloadBytes(ctx, client, signatureAsset.BrowserDownloadURL)
+	if err != nil {
+		return err
+	}
+	if err := verifySignature(checksums, signature, publicKey); err != nil {
+		return err
+	}
+	expected, err := lookupChecksum(checksums, assetName)
+	if err != nil {
+		return err
+	}
+
+	newPath := exePath + ".new"
+	if err := downloadVerified(ctx, client, binary.BrowserDownloadURL, newPath, expected); err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	return replaceExecutable(exePath, newPath)
+}
+
+func findAsset(release *Release, name string) (Asset, bool) {
+	for _, a := range release.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// downloadBytes は、小さなメタデータファイル（checksums.txt・署名）をダウンロードします。
+func downloadBytes(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	body, err := openDownload(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, maxMetadataSize))
+	if err != nil {
+		return nil, fmt.Errorf("ダウンロードに失敗しました (url=%s): %w", url, err)
+	}
+	return data, nil
+}
+
+func openDownload(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ダウンロードのリクエスト作成に失敗しました (url=%s): %w", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ダウンロードに失敗しました (url=%s): %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("ダウンロードに失敗しました (url=%s): HTTP %d", url, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// verifySignature は、checksums.txt のed25519署名を検証します。署名はBase64テキストでも生のバイト列でも受け付けます。
+func verifySignature(message, signature []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("署名検証用の公開鍵が不正です")
+	}
+
+	sig := signature
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature))); err == nil {
+		sig = decoded
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), message, sig) {
+		return fmt.Errorf("%s の署名が一致しません。改ざんされている可能性があるため更新を中止します", checksumsAssetName)
+	}
+	return nil
+}
+
+// lookupChecksum は、sha256sum 形式の checksums.txt から指定されたファイルのハッシュを取り出します。
+func lookupChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s に %s のハッシュがありません", checksumsAssetName, name)
+}
+
+// downloadVerified は、ファイルをダウンロードしながらSHA-256を計算し、期待値と一致する場合のみ destPath に残します。
+func downloadVerified(ctx context.Context, client *http.Client, url, destPath, expectedSHA256 string) error {
+	body, err := openDownload(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return fmt.Errorf("一時ファイルの作成に失敗しました (path=%s): %w", destPath, err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), body); err != nil {
+		f.Close()
+		return fmt.Errorf("実行ファイルのダウンロードに失敗しました (url=%s): %w", url, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("一時ファイルの書き込みに失敗しました (path=%s): %w", destPath, err)
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); got != expectedSHA256 {
+		return fmt.Errorf("ダウンロードした実行ファイルのハッシュが一致しません (expected=%s, actual=%s)", expectedSHA256, got)
+	}
+	return nil
+}
+
+// replaceExecutable は、実行中のファイルを newPath のファイルに置き換えます。
+// Windowsでは実行中のファイルを上書きできないため、先に .old へリネームしてから新しいファイルを配置します。
+func replaceExecutable(exePath, newPath string) error {
+	oldPath := exePath + ".old"
+	os.Remove(oldPath) // 前回の更新で残ったファイル
+
+	if err := os.Rename(exePath, oldPath); err != nil {
+		return fmt.Errorf("現在の実行ファイルの退避に失敗しました (%s -> %s): %w", exePath, filepath.Base(oldPath), err)
+	}
+	if err := os.Rename(newPath, exePath); err != nil {
+		// 置き換えに失敗した場合は元に戻す
+		if restoreErr := os.Rename(oldPath, exePath); restoreErr != nil {
+			return fmt.Errorf("実行ファイルの置き換えと復元に失敗しました (%v): %w", restoreErr, err)
+		}
+		return fmt.Errorf("実行ファイルの置き換えに失敗しました: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package update は、GitHubのリリース情報を用いた更新確認と、実行ファイルの自己更新を提供します。
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/version"
+)
+
+// DefaultCheckInterval は、定期的な更新確認の間隔です。
+const DefaultCheckInterval = 24 * time.Hour
+
+// latestReleaseURL は、最新リリースを取得するGitHub APIのURLです（テストで差し替え可能）。
+var latestReleaseURL = "https://api.github.com/repos/wai55555/GoImageBoardArchiver/releases/latest"
+
+// Release は、GitHubのリリース情報のうちGIBAが使用する項目です。
+type Release struct {
+	TagName string  `json:"tag_name"`
+	HTMLURL string  `json:"html_url"`
+	Body    string  `json:"body"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset は、リリースに添付されたファイルです。
+type Asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Status は、直近の更新確認の結果です。Web UIやトレイでの通知に使用されます。
+type Status struct {
+	Current         string    `json:"current"`
+	Latest          string    `json:"latest,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	ReleaseURL      string    `json:"release_url,omitempty"`
+	CheckedAt       time.Time `json:"checked_at,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+var (
+	statusMu   sync.Mutex
+	lastStatus = Status{Current: version.Version}
+)
+
+// LastStatus は、直近の更新確認の結果を返します。未確認の場合は CheckedAt がゼロ値です。
+func LastStatus() Status {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return lastStatus
+}
+
+func setLastStatus(s Status) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	lastStatus = s
+}
+
+// newHTTPClient は、更新確認・ダウンロード用のHTTPクライアントを返します。
+// 掲示板へのアクセスとは無関係なため、network.Client のレート制限やCookieは使用しません。
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 5 * time.Minute}
+}
+
+// FetchLatestRelease は、GitHubから最新のリリース情報を取得します。
+func FetchLatestRelease(ctx context.Context, client *http.Client) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("リリース情報のリクエスト作成に失敗しました: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("リリース情報の取得に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("リリース情報の取得に失敗しました: HTTP %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("リリース情報の解析に失敗しました: %w", err)
+	}
+	return &release, nil
+}
+
+// Check は、最新のリリースを取得して実行中のバージョンと比較し、結果を LastStatus に記録します。
+// 開発ビルドでは比較を行わず、UpdateAvailable は常に false になります。
+func Check(ctx context.Context) (Status, *Release, error) {
+	status := Status{Current: version.Version, CheckedAt: time.Now()}
+
+	release, err := FetchLatestRelease(ctx, newHTTPClient())
+	if err != nil {
+		status.Error = err.Error()
+		setLastStatus(status)
+		return status, nil, err
+	}
+
+	status.Latest = release.TagName
+	status.ReleaseURL = release.HTMLURL
+	status.UpdateAvailable = version.IsRelease() && IsNewer(release.TagName, version.Version)
+	setLastStatus(status)
+	return status, release, nil
+}
+
+// StartPeriodicCheck は、起動直後と interval ごとに更新を確認し、新しいバージョンが見つかった場合に notify を呼び出します。
+// 同じバージョンについては一度だけ通知します。ctx がキャンセルされると終了します。
+func StartPeriodicCheck(ctx context.Context, interval time.Duration, notify func(Status)) {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+
+	var notified string
+	for {
+		status, _, err := Check(ctx)
+		if err != nil {
+			log.Printf("WARNING: 更新の確認に失敗しました: %v", err)
+		} else if status.UpdateAvailable && status.Latest != notified {
+			log.Printf("INFO: 新しいバージョン %s が利用可能です (現在: %s): %s", status.Latest, status.Current, status.ReleaseURL)
+			notified = status.Latest
+			if notify != nil {
+				notify(status)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// IsNewer は、latest が current より新しいバージョンかを判定します。
+// "v1.2.3" 形式（先頭の v は省略可）を数値として比較し、"-rc1" などのプレリリースは同じ番号の正式版より古いものとして扱います。
+// どちらかが解析できない場合は false を返します。
+func IsNewer(latest, current string) bool {
+	l, lPre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, cPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < len(l) || i < len(c); i++ {
+		var lv, cv int
+		if i < len(l) {
+			lv = l[i]
+		}
+		if i < len(c) {
+			cv = c[i]
+		}
+		if lv != cv {
+			return lv > cv
+		}
+	}
+	// 番号が同じ場合は、正式版がプレリリースより新しい
+	return cPre != "" && (lPre == "" || lPre > cPre)
+}
+
+// parseVersion は、バージョン文字列を数値の列とプレリリース部分に分解します。
+func parseVersion(v string) ([]int, string, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	v, _, _ = strings.Cut(v, "+") // ビルドメタデータは比較に使用しない
+	v, pre, _ := strings.Cut(v, "-")
+	if v == "" {
+		return nil, "", false
+	}
+
+	parts := strings.Split(v, ".")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		nums[i] = n
+	}
+	return nums, pre, true
+}
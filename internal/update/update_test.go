@@ -0,0 +1,142 @@
+package update
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsNewer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"1.2.0", "v1.2.0", false},
+		{"v1.2", "v1.2.0", false},
+		{"v1.2.1", "v1.2", true},
+		{"v1.1.0", "v1.2.0", false},
+		{"v1.2.0", "v1.2.0-rc1", true},
+		{"v1.2.0-rc2", "v1.2.0-rc1", true},
+		{"v1.2.0-rc1", "v1.2.0", false},
+		{"v1.2.0+build5", "v1.2.0", false},
+		{"v1.2.0", "dev", false},
+		{"latest", "v1.0.0", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.latest+"_vs_"+tt.current, func(t *testing.T) {
+			t.Parallel()
+			if got := IsNewer(tt.latest, tt.current); got != tt.want {
+				t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+			}
+		})
+	}
+}
+
+// releaseServer は、実行ファイル・checksums.txt・署名を配信するテスト用サーバーを構築します。
+func releaseServer(t *testing.T, binary []byte, checksums string, signature []byte) (*httptest.Server, *Release) {
+	t.Helper()
+	files := map[string][]byte{
+		"/giba_test":         binary,
+		"/checksums.txt":     []byte(checksums),
+		"/checksums.txt.sig": signature,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	release := &Release{TagName: "v9.9.9"}
+	for path := range files {
+		release.Assets = append(release.Assets, Asset{Name: strings.TrimPrefix(path, "/"), BrowserDownloadURL: server.URL + path})
+	}
+	return server, release
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+	binary := []byte("new binary")
+	sum := sha256.Sum256(binary)
+	checksums := fmt.Sprintf("%s  giba_test\n%s  other_file\n", hex.EncodeToString(sum[:]), strings.Repeat("0", 64))
+	validSig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(checksums))))
+
+	tests := []struct {
+		name      string
+		checksums string
+		signature []byte
+		wantErr   string
+	}{
+		{name: "正常", checksums: checksums, signature: validSig},
+		{name: "署名不一致", checksums: checksums, signature: ed25519.Sign(priv, []byte("other")), wantErr: "署名が一致しません"},
+		{
+			name:      "ハッシュ不一致",
+			checksums: strings.Repeat("a", 64) + "  giba_test\n",
+			signature: ed25519.Sign(priv, []byte(strings.Repeat("a", 64)+"  giba_test\n")),
+			wantErr:   "ハッシュが一致しません",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server, release := releaseServer(t, binary, tt.checksums, tt.signature)
+
+			exePath := filepath.Join(t.TempDir(), "giba")
+			if err := os.WriteFile(exePath, []byte("old binary"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := apply(context.Background(), server.Client(), release, exePath, "giba_test", publicKey)
+			got, _ := os.ReadFile(exePath)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("apply() error = %v, want containing %q", err, tt.wantErr)
+				}
+				if string(got) != "old binary" {
+					t.Errorf("検証失敗時に実行ファイルが置き換えられました: %q", got)
+				}
+				if _, err := os.Stat(exePath + ".new"); !os.IsNotExist(err) {
+					t.Errorf("一時ファイルが残っています")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if string(got) != string(binary) {
+				t.Errorf("実行ファイル = %q, want %q", got, binary)
+			}
+			if old, _ := os.ReadFile(exePath + ".old"); string(old) != "old binary" {
+				t.Errorf("退避した実行ファイル = %q, want %q", old, "old binary")
+			}
+		})
+	}
+}
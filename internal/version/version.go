@@ -0,0 +1,95 @@
+// Package version は、実行中のGIBAのバージョン情報を提供します。
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// ビルド時に -ldflags で埋め込まれる値です。例:
+//
+//	go build -ldflags "-X GoImageBoardArchiver/internal/version.Version=v1.2.3 \
+//	  -X GoImageBoardArchiver/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X GoImageBoardArchiver/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/giba
+var (
+	// Version は、実行中のバイナリのバージョンです（例: "v1.2.3"）。
+	// 開発ビルドでは "dev" のままとなり、更新確認の対象外となります。
+	Version = "dev"
+	// Commit は、ビルド元のコミットハッシュです。未設定の場合はGoが埋め込むVCS情報を使用します。
+	Commit = ""
+	// BuildDate は、ビルド日時（RFC 3339）です。未設定の場合はコミット日時を使用します。
+	BuildDate = ""
+)
+
+// projectURL は、掲示板の運営者がUser-Agentから参照できるプロジェクトのURLです。
+const projectURL = "https://github.com/wai55555/GoImageBoardArchiver"
+
+// Info は、バージョンとビルドに関する情報です。
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// IsRelease は、実行中のバイナリがリリースビルド（バージョンが埋め込まれている）かを返します。
+func IsRelease() bool {
+	return Version != "" && Version != "dev"
+}
+
+// Get は、実行中のバイナリのバージョン情報を返します。
+// -ldflags で埋め込まれていない項目は、go build が記録したVCS情報で補完します。
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		var modified bool
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+					if len(info.Commit) > 12 {
+						info.Commit = info.Commit[:12]
+					}
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if modified && Commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	return info
+}
+
+// String は、バージョン情報を1行の文字列で返します（例: "GIBA v1.2.3 (commit abc123, built 2024-01-01T00:00:00Z, go1.21.0 windows/amd64)"）。
+func (i Info) String() string {
+	s := "GIBA " + i.Version + " ("
+	if i.Commit != "" {
+		s += "commit " + i.Commit + ", "
+	}
+	if i.BuildDate != "" {
+		s += "built " + i.BuildDate + ", "
+	}
+	return s + i.GoVersion + " " + i.Platform + ")"
+}
+
+// UserAgent は、user_agent が未設定の場合に使用する既定のUser-Agentです。
+// 掲示板の運営者がアクセス元のツールとバージョンを識別できるよう、プロジェクトのURLを含めます。
+func UserAgent() string {
+	return fmt.Sprintf("GIBA/%s (+%s)", Version, projectURL)
+}
@@ -0,0 +1,46 @@
+package version
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfo_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		info Info
+		want string
+	}{
+		{
+			name: "全項目",
+			info: Info{Version: "v1.2.3", Commit: "abc123", BuildDate: "2024-01-01T00:00:00Z", GoVersion: "go1.21.0", Platform: "windows/amd64"},
+			want: "GIBA v1.2.3 (commit abc123, built 2024-01-01T00:00:00Z, go1.21.0 windows/amd64)",
+		},
+		{
+			name: "開発ビルド",
+			info: Info{Version: "dev", GoVersion: "go1.21.0", Platform: "linux/amd64"},
+			want: "GIBA dev (go1.21.0 linux/amd64)",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.info.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	t.Parallel()
+
+	ua := UserAgent()
+	if !strings.HasPrefix(ua, "GIBA/"+Version+" ") || !strings.Contains(ua, projectURL) {
+		t.Errorf("UserAgent() = %q, want GIBA/<version> and project URL", ua)
+	}
+}
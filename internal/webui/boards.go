@@ -0,0 +1,27 @@
+package webui
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// handleBoardPresets は /api/board-presets へのリクエストを処理し、同梱の既知の掲示板の一覧を返します。
+func handleBoardPresets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	presets, err := config.BoardPresets()
+	if err != nil {
+		log.Printf("ERROR: %v", err)
+		http.Error(w, `{"error": "既知の掲示板の一覧を読み込めませんでした。"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(presets); err != nil {
+		log.Printf("ERROR: 既知の掲示板の一覧のエンコードに失敗しました: %v", err)
+	}
+}
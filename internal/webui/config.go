@@ -0,0 +1,118 @@
+package webui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// configStore は、Web UIのハンドラが参照する設定ファイルの読み込み結果を保持します。
+// 保存先のファイルの配信などリクエストごとに設定を参照するため、ファイルが更新されるまでは読み込み直しません。
+type configStore struct {
+	mu      sync.Mutex
+	path    string
+	cached  *config.Config
+	modTime time.Time
+}
+
+// maxConfigBodyBytes は、Web UIから保存する設定のJSONの最大サイズです。
+const maxConfigBodyBytes = 4 << 20
+
+// sharedConfig は、Web UIのハンドラで共有される設定ファイルの読み込み結果です。
+var sharedConfig = &configStore{path: "config.json"}
+
+// SetConfigPath は、Web UIが読み書きする設定ファイルのパス（-config で指定されたもの）を設定します。
+func SetConfigPath(path string) {
+	sharedConfig.setPath(path)
+}
+
+// loadConfig は、Web UIが読み書きする設定ファイルの内容を返します。
+func loadConfig() (*config.Config, error) {
+	return sharedConfig.load()
+}
+
+// loadRawConfig は、Web UIで編集するための設定ファイルの内容を、テンプレートや相対パスを解決せずにそのまま返します。
+func loadRawConfig() ([]byte, error) {
+	return sharedConfig.raw()
+}
+
+// saveRawConfig は、Web UIで編集した設定を設定ファイルに保存します。呼び出し元で config.ParseAndResolve による検証を済ませてください。
+func saveRawConfig(data []byte) error {
+	return sharedConfig.save(data)
+}
+
+func (s *configStore) setPath(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	s.cached = nil
+}
+
+// load は、設定ファイルの内容を返します。前回の読み込み以降にファイルが更新されていれば読み込み直します。
+// 呼び出し元がタスクを絞り込むなどしても共有の結果が変わらないよう、タスクの一覧はコピーして返します。
+func (s *configStore) load() (*config.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルの情報を取得できませんでした (path=%s): %w", s.path, err)
+	}
+	if s.cached == nil || !info.ModTime().Equal(s.modTime) {
+		cfg, err := config.LoadAndResolve(s.path)
+		if err != nil {
+			return nil, err
+		}
+		s.cached, s.modTime = cfg, info.ModTime()
+	}
+	copied := *s.cached
+	copied.Tasks = append([]config.Task(nil), s.cached.Tasks...)
+	return &copied, nil
+}
+
+func (s *configStore) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cached = nil
+}
+
+// raw は、設定ファイルの内容を返します。相対パスやテンプレートは記述されたまま（未解決）です。
+func (s *configStore) raw() ([]byte, error) {
+	s.mu.Lock()
+	path := s.path
+	s.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルの読み込みに失敗しました (path=%s): %w", path, err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("設定ファイルが正しいJSONではありません (path=%s)", path)
+	}
+	return data, nil
+}
+
+// save は、設定のJSON data を整形して設定ファイルに書き込みます。
+// 読み込み時のようにテンプレートや相対パスを解決した内容ではなく、data をそのまま保存します。
+func (s *configStore) save(data []byte) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		return fmt.Errorf("設定のJSONの整形に失敗しました: %w", err)
+	}
+	indented.WriteByte('\n')
+
+	s.mu.Lock()
+	path := s.path
+	s.mu.Unlock()
+	if err := os.WriteFile(path, indented.Bytes(), 0644); err != nil {
+		return fmt.Errorf("設定ファイルの書き込みに失敗しました (path=%s): %w", path, err)
+	}
+	// 更新日時の精度が粗いファイルシステムでも変更を反映するため、読み込み結果を破棄する
+	s.invalidate()
+	return nil
+}
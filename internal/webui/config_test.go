@@ -0,0 +1,128 @@
+package webui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigStore_Load(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "custom.json")
+	write := func(taskName string, modTime time.Time) {
+		t.Helper()
+		data := `{"config_version": "1.0", "tasks": [{"task_name": "` + taskName + `", "save_root_directory": "archives"}]}`
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loadTaskName := func(s *configStore) string {
+		t.Helper()
+		cfg, err := s.load()
+		if err != nil {
+			t.Fatalf("load() error = %v", err)
+		}
+		if len(cfg.Tasks) != 1 {
+			t.Fatalf("tasks = %d, want 1", len(cfg.Tasks))
+		}
+		return cfg.Tasks[0].TaskName
+	}
+
+	modTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	write("first", modTime)
+	s := &configStore{}
+	s.setPath(path)
+
+	// 既定の config.json ではなく、設定したパスを読み込む（相対パスは設定ファイルのディレクトリが基準）
+	cfg, err := s.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if want := filepath.Join(dir, "archives"); cfg.Tasks[0].SaveRootDirectory != want {
+		t.Errorf("SaveRootDirectory = %q, want %q", cfg.Tasks[0].SaveRootDirectory, want)
+	}
+	// 呼び出し元がタスクの一覧を変更しても、共有の結果は変わらない
+	cfg.Tasks = cfg.Tasks[:0]
+	if got := loadTaskName(s); got != "first" {
+		t.Errorf("task_name = %q, want first", got)
+	}
+
+	tests := []struct {
+		name       string
+		taskName   string
+		modTime    time.Time
+		invalidate bool
+		want       string
+	}{
+		{"更新日時が同じなら読み込み直さない", "second", modTime, false, "first"},
+		{"保存後は読み込み直す", "second", modTime, true, "second"},
+		{"ファイルが更新されたら読み込み直す", "third", modTime.Add(time.Minute), false, "third"},
+	}
+	for _, tt := range tests {
+		write(tt.taskName, tt.modTime)
+		if tt.invalidate {
+			s.invalidate()
+		}
+		if got := loadTaskName(s); got != tt.want {
+			t.Errorf("%s: task_name = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfigStore_RawRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"config_version": "1.0", "task_templates": {"base": {"save_root_directory": "template_archives"}}, "tasks": [{"task_name": "may", "use_template": "base", "log_file_path": "logs/may.log"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &configStore{}
+	s.setPath(path)
+	if _, err := s.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	// Web UIには、テンプレートや相対パスを解決していない設定ファイルの内容を返す
+	raw, err := s.raw()
+	if err != nil {
+		t.Fatalf("raw() error = %v", err)
+	}
+	if string(raw) != data {
+		t.Errorf("raw() = %s, want %s", raw, data)
+	}
+
+	// そのまま保存しても、相対パスは絶対パスに書き換わらず、テンプレートも展開されない
+	if err := s.save(raw); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"use_template": "base"`, `"log_file_path": "logs/may.log"`} {
+		if !strings.Contains(string(saved), want) {
+			t.Errorf("保存した設定に %s が含まれていません:\n%s", want, saved)
+		}
+	}
+	if strings.Contains(string(saved), dir) {
+		t.Errorf("保存した設定に絶対パスが含まれています:\n%s", saved)
+	}
+
+	// 保存後の読み込みでは、設定ファイルのディレクトリを基準に解決する
+	cfg, err := s.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if want := filepath.Join(dir, "template_archives"); cfg.Tasks[0].SaveRootDirectory != want {
+		t.Errorf("SaveRootDirectory = %q, want %q", cfg.Tasks[0].SaveRootDirectory, want)
+	}
+}
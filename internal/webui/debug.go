@@ -0,0 +1,27 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"sync/atomic"
+)
+
+// debugEnabled が true の場合、Web UIサーバーに /debug/pprof/ エンドポイントを公開します。
+// プロファイル情報にはメモリ内容の一部が含まれるため、既定では無効です。
+var debugEnabled atomic.Bool
+
+// SetDebugMode は、次回起動するWeb UIサーバーで pprof エンドポイントを有効にするかどうかを設定します。
+// すでに起動しているサーバーには影響しません。
+func SetDebugMode(enabled bool) {
+	debugEnabled.Store(enabled)
+}
+
+// registerDebugHandlers は、net/http/pprof のハンドラを mux に登録します。
+// DefaultServeMux を使用しないため、pprof パッケージのinit登録には依存せず明示的に登録します。
+func registerDebugHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
@@ -0,0 +1,53 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// handleThreadDiff は /api/diff へのリクエストを処理し、アーカイブ済みスレッドの2つのHTMLをレス単位で比較した結果を返します。
+// target にはスレッドIDまたはスレッドURL、left・right には比較するファイル名（省略時は archive_full.html と index.htm）を指定します。
+func handleThreadDiff(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	targetParam := query.Get("target")
+	if targetParam == "" {
+		http.Error(w, `{"error": "スレッドIDまたはスレッドURLを指定してください"}`, http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("ERROR: 設定ファイルの読み込みに失敗しました: %v", err)
+		http.Error(w, `{"error": "設定ファイルの読み込みに失敗しました。"}`, http.StatusInternalServerError)
+		return
+	}
+
+	target, err := core.ResolveRearchiveTarget(cfg, targetParam)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if target.ThreadDir == "" {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, fmt.Sprintf("スレッド %s はまだアーカイブされていません", target.Thread.ID)), http.StatusNotFound)
+		return
+	}
+
+	diff, err := core.DiffArchivedThread(target.ThreadDir, query.Get("left"), query.Get("right"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(diff); err != nil {
+		log.Printf("ERROR: 差分のエンコードに失敗しました: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+document.addEventListener('DOMContentLoaded', () => {
+    const dom = {
+        version: document.getElementById('about-version'),
+        summary: document.getElementById('usage-summary'),
+        table: document.getElementById('usage-table'),
+        body: document.getElementById('usage-body'),
+        statusMessage: document.getElementById('status-message'),
+    };
+
+    // =================================================================
+    // 初期化
+    // =================================================================
+    async function loadVersion() {
+        try {
+            const response = await fetch('/api/version');
+            if (!response.ok) throw new Error('バージョン情報の取得に失敗しました');
+            const info = await response.json();
+            const commit = info.commit ? ` (${info.commit})` : '';
+            dom.version.textContent = `GIBA ${info.version}${commit}`;
+        } catch (error) {
+            dom.version.textContent = '';
+            showStatus(`エラー: ${error.message}`, 'error');
+        }
+    }
+
+    async function loadUsageStats() {
+        try {
+            const response = await fetch('/api/usage-stats');
+            const usage = await response.json();
+            if (!response.ok) throw new Error(usage.error || '利用統計の取得に失敗しました');
+            renderUsageStats(usage);
+        } catch (error) {
+            showStatus(`エラー: ${error.message}`, 'error');
+        }
+    }
+
+    // =================================================================
+    // レンダリング
+    // =================================================================
+    function renderUsageStats(usage) {
+        if (!usage.enabled) {
+            // 利用統計は設定した場合のみ記録する（外部には送信しない）
+            dom.summary.textContent = '利用統計は記録されていません。設定ファイルに "usage_stats_file" を指定すると、累計をローカルのファイルに記録します。';
+            dom.table.style.display = 'none';
+            return;
+        }
+        const stats = usage.stats;
+        dom.summary.textContent = `${new Date(stats.since).toLocaleDateString()} から: ` +
+            `スレッド ${stats.threads_archived}件 / レス ${stats.posts_archived}件 / ファイル ${stats.files_downloaded}件 / ` +
+            `${formatBytes(stats.bytes_written)} / 稼働時間 ${formatUptime(stats.uptime_seconds)}`;
+
+        const boards = Object.entries(stats.boards || {})
+            .sort((a, b) => b[1].threads_archived - a[1].threads_archived);
+        dom.body.innerHTML = '';
+        boards.forEach(([board, b]) => {
+            const row = document.createElement('tr');
+            row.innerHTML = `
+                <td>${escapeHtml(board)}</td>
+                <td>${b.threads_archived}</td>
+                <td>${b.posts_archived}</td>
+                <td>${b.files_downloaded}</td>
+                <td>${formatBytes(b.bytes_written)}</td>
+            `;
+            dom.body.appendChild(row);
+        });
+        dom.table.style.display = boards.length > 0 ? '' : 'none';
+    }
+
+    // =================================================================
+    // ユーティリティ
+    // =================================================================
+    function formatBytes(bytes) {
+        const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+        let value = bytes || 0;
+        let unit = 0;
+        while (value >= 1024 && unit < units.length - 1) {
+            value /= 1024;
+            unit++;
+        }
+        return `${value.toFixed(unit === 0 ? 0 : 1)}${units[unit]}`;
+    }
+
+    function formatUptime(seconds) {
+        const hours = Math.floor((seconds || 0) / 3600);
+        const minutes = Math.floor(((seconds || 0) % 3600) / 60);
+        return hours >= 24 ? `${Math.floor(hours / 24)}日${hours % 24}時間` : `${hours}時間${minutes}分`;
+    }
+
+    function showStatus(message, type) {
+        dom.statusMessage.textContent = message;
+        dom.statusMessage.className = `status-message ${type}`;
+        dom.statusMessage.style.display = 'block';
+        if (type !== 'info') {
+            setTimeout(() => { dom.statusMessage.style.display = 'none'; }, 5000);
+        }
+    }
+
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.appendChild(document.createTextNode(text || ''));
+        return div.innerHTML;
+    }
+
+    loadVersion();
+    loadUsageStats();
+});
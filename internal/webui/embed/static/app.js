@@ -2,6 +2,7 @@ document.addEventListener('DOMContentLoaded', () => {
     // グローバルな状態管理
     let state = {
         config: null,
+        boardPresets: [],
     };
 
     // DOM要素のキャッシュ
@@ -9,8 +10,29 @@ document.addEventListener('DOMContentLoaded', () => {
         globalSettingsContainer: document.getElementById('global-settings'),
         tasksContainer: document.getElementById('tasks-container'),
         addTaskBtn: document.getElementById('add-task-btn'),
+        boardPresetPicker: document.getElementById('board-preset-picker'),
+        boardPresetSelect: document.getElementById('board-preset-select'),
+        addBoardPresetBtn: document.getElementById('add-board-preset-btn'),
         saveBtn: document.getElementById('save-btn'),
         statusMessage: document.getElementById('status-message'),
+        updateBanner: document.getElementById('update-banner'),
+        versionInfo: document.getElementById('version-info'),
+        taskStatus: document.getElementById('task-status'),
+        recentErrors: document.getElementById('errors'),
+    };
+
+    // 実行状況を再取得する間隔（ミリ秒）
+    const STATUS_POLL_INTERVAL = 5000;
+
+    // /api/status の状態の識別子と表示名
+    const STATE_LABELS = {
+        initializing: '初期化中',
+        idle: 'アイドル',
+        watching: '監視中',
+        preparing: '準備中',
+        running: '実行中',
+        paused: '一時停止中',
+        error: 'エラー',
     };
 
     // =================================================================
@@ -33,6 +55,116 @@ document.addEventListener('DOMContentLoaded', () => {
         } catch (error) {
             showStatus(`初期設定の読み込み中にエラーが発生しました: ${error.message}`, 'error');
         }
+        loadBoardPresets();
+        loadUpdateStatus();
+        loadVersion();
+        loadTaskStatus();
+        loadRecentErrors();
+        setInterval(() => {
+            loadTaskStatus();
+            loadRecentErrors();
+        }, STATUS_POLL_INTERVAL);
+    }
+
+    // ログにのみ記録されていたタスクのエラーを新しい順に表示する
+    async function loadRecentErrors() {
+        try {
+            const response = await fetch('/api/errors');
+            if (!response.ok) return;
+            const records = await response.json();
+            if (!records || records.length === 0) {
+                dom.recentErrors.style.display = 'none';
+                return;
+            }
+            const rows = records.map(rec => `<tr>
+                    <td>${escapeHtml(new Date(rec.time).toLocaleString())}</td>
+                    <td>${escapeHtml(rec.task_name)}</td>
+                    <td>${escapeHtml(rec.thread_id || '-')}</td>
+                    <td>${escapeHtml(rec.class_label)}</td>
+                    <td class="error-message">${escapeHtml(rec.message)}</td>
+                </tr>`).join('');
+            dom.recentErrors.innerHTML = `<h2>最近のエラー (${records.length})</h2>
+                <table><thead><tr><th>時刻</th><th>タスク</th><th>スレッド</th><th>分類</th><th>内容</th></tr></thead><tbody>${rows}</tbody></table>`;
+            dom.recentErrors.style.display = 'block';
+            if (location.hash === '#errors' && !dom.recentErrors.dataset.scrolled) {
+                dom.recentErrors.dataset.scrolled = '1';
+                dom.recentErrors.scrollIntoView();
+            }
+        } catch (error) {
+            // エラー一覧は補助的な情報のため、取得に失敗しても設定画面の利用には影響させない
+        }
+    }
+
+    // タスクごとの実行状況を表示する（最後に状態を送ったタスクだけでなく、全タスクを一覧にする）
+    async function loadTaskStatus() {
+        try {
+            const response = await fetch('/api/status');
+            if (!response.ok) return;
+            const status = await response.json();
+            const tasks = Object.values(status.tasks || {}).sort((a, b) => a.task_name.localeCompare(b.task_name));
+            if (tasks.length === 0) {
+                dom.taskStatus.style.display = 'none';
+                return;
+            }
+            const summary = status.summary;
+            const rows = tasks.map(t => {
+                const next = t.next_run ? new Date(t.next_run).toLocaleTimeString() : '-';
+                return `<tr class="state-${escapeHtml(t.state)}${t.has_error ? ' has-error' : ''}">
+                    <td>${escapeHtml(t.task_name)}</td>
+                    <td>${escapeHtml(STATE_LABELS[t.state] || t.state)}</td>
+                    <td>${escapeHtml(t.detail)}</td>
+                    <td>${escapeHtml(next)}</td>
+                </tr>`;
+            }).join('');
+            dom.taskStatus.innerHTML = `<div class="task-status-summary"><strong>${escapeHtml(STATE_LABELS[summary.state] || summary.state)}</strong>
+                ${escapeHtml(summary.detail || '')} <span class="task-status-session">${escapeHtml(summary.session_info || '')}</span></div>
+                <table><thead><tr><th>タスク</th><th>状態</th><th>詳細</th><th>次回</th></tr></thead><tbody>${rows}</tbody></table>`;
+            dom.taskStatus.style.display = 'block';
+        } catch (error) {
+            // 実行状況は補助的な情報のため、取得に失敗しても設定画面の利用には影響させない
+        }
+    }
+
+    // 同梱の既知の掲示板を「既知の板から追加」の選択肢にする
+    async function loadBoardPresets() {
+        try {
+            const response = await fetch('/api/board-presets');
+            if (!response.ok) return;
+            state.boardPresets = await response.json() || [];
+            if (state.boardPresets.length === 0) return;
+            dom.boardPresetSelect.innerHTML = state.boardPresets.map(p =>
+                `<option value="${escapeHtml(p.id)}" title="${escapeHtml(p.description)}">${escapeHtml(p.name)}</option>`).join('');
+            dom.boardPresetPicker.style.display = 'inline';
+        } catch (error) {
+            // 既知の掲示板の一覧は補助的な機能のため、取得に失敗しても設定画面の利用には影響させない
+        }
+    }
+
+    async function loadVersion() {
+        try {
+            const response = await fetch('/api/version');
+            if (!response.ok) return;
+            const info = await response.json();
+            const commit = info.commit ? ` (${info.commit})` : '';
+            dom.versionInfo.textContent = `GIBA ${info.version}${commit}`;
+        } catch (error) {
+            // バージョン表示は補助的な情報のため、失敗しても無視する
+        }
+    }
+
+    // 新しいバージョンがあればバナーで通知する（確認自体はバックグラウンドで行われる）
+    async function loadUpdateStatus() {
+        try {
+            const response = await fetch('/api/update');
+            if (!response.ok) return;
+            const status = await response.json();
+            if (!status.update_available) return;
+            dom.updateBanner.innerHTML = `新しいバージョン <strong>${escapeHtml(status.latest)}</strong> が利用可能です（現在: ${escapeHtml(status.current)}）。
+                <a href="${escapeHtml(status.release_url)}" target="_blank" rel="noopener">リリースページ</a> から入手するか、<code>giba self-update</code> を実行してください。`;
+            dom.updateBanner.style.display = 'block';
+        } catch (error) {
+            // 更新情報の取得失敗は設定画面の利用に影響しないため無視する
+        }
     }
 
     // =================================================================
@@ -41,6 +173,7 @@ document.addEventListener('DOMContentLoaded', () => {
     function attachEventListeners() {
         dom.saveBtn.addEventListener('click', handleSave);
         dom.addTaskBtn.addEventListener('click', handleAddTask);
+        dom.addBoardPresetBtn.addEventListener('click', handleAddBoardPreset);
         
         // イベント委譲を使用して動的に生成される要素のイベントを処理
         document.body.addEventListener('click', (e) => {
@@ -85,6 +218,7 @@ document.addEventListener('DOMContentLoaded', () => {
         const notification = createAccordion('global-notification', 'ログと通知');
         notification.appendChild(createFormGroup('enable_log_file', 'ログファイルを有効にする', config.enable_log_file, 'checkbox', 'ログをファイルに書き出します。'));
         notification.appendChild(createFormGroup('log_file_path', 'ログファイルパス', config.log_file_path, 'text', 'ログファイルのパス。空の場合は `giba_[日付].log` が使用されます。'));
+        notification.appendChild(createFormGroup('check_for_updates', '更新を確認する', config.check_for_updates, 'checkbox', '起動時と1日ごとにGitHubで新しいリリースを確認し、トレイとこの画面で通知します。'));
         notification.appendChild(createFormGroup('notification_webhook_url', '通知用Webhook URL', config.notification_webhook_url, 'url', 'タスク完了・エラー時に通知を送るWebhook URL。'));
         container.appendChild(notification);
     }
@@ -131,6 +265,10 @@ document.addEventListener('DOMContentLoaded', () => {
         
         const advanced = createAccordion('task-advanced', '高度な設定');
         advanced.appendChild(createFormGroup(`directory_format_${index}`, 'ディレクトリ形式', task.directory_format, 'text', '保存ディレクトリ名のフォーマット。使用可能な変数: {thread_id}, {thread_title_safe}, {year}, {month}, {day}'));
+        advanced.appendChild(createFormGroup(`filename_format_${index}`, 'ファイル名形式', task.filename_format, 'text', 'メディアファイル名のフォーマット。使用可能な変数: {original_filename}, {ext}, {thread_id}, {res_number}, {year}, {month}, {day}, {sha256_8}。"hash" を指定すると内容のハッシュで命名します（{sha256_8}.{ext}）'));
+        advanced.appendChild(createFormGroup(`pagination_mode_${index}`, 'ページ分割', task.pagination_mode, 'select', 'レス数の多いスレッドの表示方法。"pages": index_p1.htm などに分割, "virtual": 画面外のレスの描画を省略, 空欄で無効', ['', 'pages', 'virtual']));
+        advanced.appendChild(createFormGroup(`posts_per_page_${index}`, '1ページのレス数', task.posts_per_page ?? '', 'number', 'ページ分割が "pages" の場合の1ページあたりのレス数（空欄で500）'));
+        advanced.appendChild(createFormGroup(`keep_raw_html_${index}`, '元のHTMLを保存', task.keep_raw_html, 'checkbox', '取得したままのスレッドHTMLを raw.html.gz として保存します。スレッドが落ちた後でも再構成をやり直せます。'));
         // TODO: 他の高度な設定項目を追加
         accordion.appendChild(advanced);
 
@@ -234,6 +372,20 @@ document.addEventListener('DOMContentLoaded', () => {
         renderTasks();
     }
 
+    // 選択した既知の掲示板のおすすめ設定でタスクを追加する（保存先は各自で設定する）
+    function handleAddBoardPreset() {
+        const preset = state.boardPresets.find(p => p.id === dom.boardPresetSelect.value);
+        if (!preset || !state.config) return;
+        const task = JSON.parse(JSON.stringify(preset.task)); // Deep copy
+        task.enabled = true;
+        task.task_name = preset.name;
+        if (state.config.tasks.some(t => t.task_name === task.task_name)) {
+            task.task_name = `${preset.name} ${state.config.tasks.length + 1}`;
+        }
+        state.config.tasks.push(task);
+        renderTasks();
+    }
+
     function handleRemoveTask(e) {
         const taskBox = e.target.closest('.task-box');
         const index = parseInt(taskBox.dataset.index, 10);
@@ -269,6 +421,7 @@ document.addEventListener('DOMContentLoaded', () => {
         newConfig.enable_log_file = document.getElementById('enable_log_file').checked;
         newConfig.log_file_path = document.getElementById('log_file_path').value;
         newConfig.notification_webhook_url = document.getElementById('notification_webhook_url').value;
+        newConfig.check_for_updates = document.getElementById('check_for_updates').checked;
         
         // タスク設定
         newConfig.tasks = [];
@@ -280,11 +433,24 @@ document.addEventListener('DOMContentLoaded', () => {
             task.enabled = taskBox.querySelector('.task-enabled-switch').checked;
             task.task_name = document.getElementById(`task_name_${i}`).value;
             task.site_adapter = document.getElementById(`site_adapter_${i}`).value;
-            task.target_board_url = document.getElementById(`target_board_url_${i}`).value;
-            task.save_root_directory = document.getElementById(`save_root_directory_${i}`).value;
-            task.watch_interval_ms = parseFloat(document.getElementById(`watch_interval_ms_${i}`).value) * 60000;
-            task.search_keyword = document.getElementById(`search_keyword_${i}`).value;
-            task.directory_format = document.getElementById(`directory_format_${i}`).value;
+            // 設定ファイルにない項目（テンプレートの値を使う項目）は、空のまま保存してテンプレートの値を上書きしない
+            setTaskField(task, 'target_board_url', document.getElementById(`target_board_url_${i}`).value);
+            setTaskField(task, 'save_root_directory', document.getElementById(`save_root_directory_${i}`).value);
+            const watchIntervalMinutes = parseFloat(document.getElementById(`watch_interval_ms_${i}`).value);
+            if (!isNaN(watchIntervalMinutes)) {
+                task.watch_interval_ms = watchIntervalMinutes * 60000;
+            }
+            setTaskField(task, 'search_keyword', document.getElementById(`search_keyword_${i}`).value);
+            setTaskField(task, 'directory_format', document.getElementById(`directory_format_${i}`).value);
+            setTaskField(task, 'filename_format', document.getElementById(`filename_format_${i}`).value);
+            task.keep_raw_html = document.getElementById(`keep_raw_html_${i}`).checked;
+            task.pagination_mode = document.getElementById(`pagination_mode_${i}`).value;
+            const postsPerPage = parseInt(document.getElementById(`posts_per_page_${i}`).value, 10);
+            if (isNaN(postsPerPage)) {
+                delete task.posts_per_page;
+            } else {
+                task.posts_per_page = postsPerPage;
+            }
             
             newConfig.tasks.push(task);
         });
@@ -292,6 +458,12 @@ document.addEventListener('DOMContentLoaded', () => {
         return newConfig;
     }
 
+    // setTaskField は、入力値をタスクに設定する。空の入力は、もともと設定ファイルにあった項目の場合のみ設定する
+    function setTaskField(task, key, value) {
+        if (value === '' && !(key in task)) return;
+        task[key] = value;
+    }
+
     function showStatus(message, type) {
         dom.statusMessage.textContent = message;
         dom.statusMessage.className = `status-message ${type}`;
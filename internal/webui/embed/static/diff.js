@@ -0,0 +1,100 @@
+document.addEventListener('DOMContentLoaded', () => {
+    const dom = {
+        form: document.getElementById('diff-form'),
+        target: document.getElementById('diff-target'),
+        left: document.getElementById('diff-left'),
+        right: document.getElementById('diff-right'),
+        summary: document.getElementById('diff-summary'),
+        posts: document.getElementById('diff-posts'),
+        statusMessage: document.getElementById('status-message'),
+        threadInfo: document.getElementById('thread-info'),
+        threadTitle: document.getElementById('thread-title'),
+        threadActions: document.getElementById('thread-actions'),
+    };
+
+    // =================================================================
+    // 差分の取得
+    // =================================================================
+    async function loadDiff() {
+        const params = new URLSearchParams({
+            target: dom.target.value.trim(),
+            left: dom.left.value.trim(),
+            right: dom.right.value.trim(),
+        });
+        try {
+            const response = await fetch(`/api/diff?${params}`);
+            const diff = await response.json();
+            if (!response.ok) throw new Error(diff.error || '差分の取得に失敗しました');
+            renderDiff(diff);
+            renderThreadInfo(params.get('target'));
+            // 再読み込みや共有ができるよう、比較条件をURLに残す
+            history.replaceState(null, '', `/diff?${params}`);
+        } catch (error) {
+            dom.summary.textContent = '';
+            dom.posts.innerHTML = '';
+            dom.threadInfo.style.display = 'none';
+            showStatus(`エラー: ${error.message}`, 'error');
+        }
+    }
+
+    // =================================================================
+    // レンダリング
+    // =================================================================
+    function renderDiff(diff) {
+        dom.summary.textContent = `${diff.left} → ${diff.right}: 追加 ${diff.added}件 / 削除 ${diff.deleted}件 / 変更なし ${diff.unchanged}件`;
+        dom.posts.innerHTML = '';
+        (diff.posts || []).forEach((post) => {
+            const item = document.createElement('div');
+            item.className = `diff-post ${post.status}`;
+            item.innerHTML = `
+                <div class="diff-post-header">${post.status === 'added' ? '＋ 追加' : '－ 削除'} No.${escapeHtml(post.res_number)}</div>
+                <div class="diff-post-text">${escapeHtml(post.text || '（本文を抽出できませんでした）')}</div>
+            `;
+            dom.posts.appendChild(item);
+        });
+    }
+
+    // 比較したスレッドの元のURLと、スレッド単位の操作を表示する
+    async function renderThreadInfo(target) {
+        dom.threadActions.innerHTML = '';
+        GIBAThreadActions.render(dom.threadActions, target, { showStatus });
+        try {
+            const info = await GIBAThreadActions.fetchInfo(target);
+            dom.threadTitle.innerHTML = `${escapeHtml(info.title || `No.${info.thread_id}`)} (${escapeHtml(info.task_name)}) <a href="${escapeHtml(info.url)}" target="_blank" rel="noopener noreferrer">${escapeHtml(info.url)}</a>`;
+            dom.threadActions.querySelector('.open-full').disabled = !info.has_archive_full;
+            dom.threadActions.querySelector('.view-full').disabled = !info.has_archive_full;
+        } catch (error) {
+            dom.threadTitle.textContent = '';
+        }
+        dom.threadInfo.style.display = 'block';
+    }
+
+    dom.form.addEventListener('submit', (event) => {
+        event.preventDefault();
+        if (dom.target.value.trim()) loadDiff();
+    });
+
+    function showStatus(message, type) {
+        dom.statusMessage.textContent = message;
+        dom.statusMessage.className = `status-message ${type}`;
+        dom.statusMessage.style.display = 'block';
+        if (type !== 'info') {
+            setTimeout(() => { dom.statusMessage.style.display = 'none'; }, 5000);
+        }
+    }
+
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.appendChild(document.createTextNode(text || ''));
+        return div.innerHTML;
+    }
+
+    // /diff?target=... で開かれた場合はすぐに比較する
+    const initial = new URLSearchParams(location.search);
+    if (initial.get('target')) {
+        dom.target.value = initial.get('target');
+        if (initial.get('left')) dom.left.value = initial.get('left');
+        if (initial.get('right')) dom.right.value = initial.get('right');
+        loadDiff();
+    }
+});
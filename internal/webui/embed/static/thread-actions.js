@@ -0,0 +1,132 @@
+// スレッド単位の操作（元のURL・保存先のコピー、ブラウザでの表示、フォルダ・完全版を開く、再アーカイブ）を提供します。
+// 差分ページと検証結果ページで共通して使用します。
+window.GIBAThreadActions = (() => {
+    const infoCache = new Map();
+
+    const ACTIONS = {
+        'copy-url': { label: 'URLをコピー', run: async (target) => copyText((await fetchInfo(target)).url, '元のURLをコピーしました') },
+        'copy-path': {
+            label: 'パスをコピー',
+            run: async (target) => {
+                const info = await fetchInfo(target);
+                if (!info.thread_dir) throw new Error('このスレッドはまだアーカイブされていません');
+                return copyText(info.thread_dir, '保存先のパスをコピーしました');
+            },
+        },
+        'view': { label: 'ブラウザで表示', run: (target) => viewTarget(target, 'view_url') },
+        'view-full': { label: '完全版を表示', run: (target) => viewTarget(target, 'archive_full_url') },
+        'open-folder': { label: 'フォルダを開く', run: (target) => openTarget(target, 'folder') },
+        'open-full': { label: '完全版を開く', run: (target) => openTarget(target, 'archive_full') },
+        'rearchive': { label: '今すぐ再アーカイブ', run: (target, options) => rearchive(target, options) },
+    };
+
+    // =================================================================
+    // API
+    // =================================================================
+    async function fetchInfo(target) {
+        if (!infoCache.has(target)) {
+            infoCache.set(target, (async () => {
+                const response = await fetch(`/api/thread?${new URLSearchParams({ target })}`);
+                const info = await response.json();
+                if (!response.ok) throw new Error(info.error || 'スレッド情報の取得に失敗しました');
+                return info;
+            })());
+            // 失敗した結果は保持せず、次の操作で再取得する
+            infoCache.get(target).catch(() => infoCache.delete(target));
+        }
+        return infoCache.get(target);
+    }
+
+    // viewTarget は、保存先のHTMLをWeb UI経由（/archive/）で新しいタブに表示します。
+    // file:// のリンクはブラウザに遮断されるため、サーバーから配信したURLを開きます。
+    async function viewTarget(target, key) {
+        // ポップアップブロックを避けるため、クリック直後にタブを開いてから移動する
+        const tab = window.open('', '_blank');
+        try {
+            const info = await fetchInfo(target);
+            if (!info[key]) throw new Error(key === 'view_url' ? 'このスレッドはまだアーカイブされていません' : 'archive_full.html がありません');
+            if (tab) {
+                tab.opener = null;
+                tab.location.href = info[key];
+            } else {
+                window.open(info[key], '_blank', 'noopener');
+            }
+            return '新しいタブで表示しました';
+        } catch (error) {
+            if (tab) tab.close();
+            throw error;
+        }
+    }
+
+    async function openTarget(target, what) {
+        const response = await fetch('/api/thread/open', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ target, what }),
+        });
+        const result = await response.json();
+        if (!response.ok) throw new Error(result.error || '開けませんでした');
+        return result.message;
+    }
+
+    async function rearchive(target, options) {
+        const response = await fetch('/api/rearchive', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                target,
+                task_name: options.taskName || '',
+                force_full: options.forceFull ? options.forceFull() : false,
+            }),
+        });
+        const result = await response.json();
+        if (!response.ok) throw new Error(result.error || '再アーカイブの開始に失敗しました');
+        return result.message;
+    }
+
+    async function copyText(text, message) {
+        if (navigator.clipboard && window.isSecureContext) {
+            await navigator.clipboard.writeText(text);
+            return message;
+        }
+        // Clipboard API を使えない環境向けの代替手段
+        const textarea = document.createElement('textarea');
+        textarea.value = text;
+        textarea.style.position = 'fixed';
+        textarea.style.opacity = '0';
+        document.body.appendChild(textarea);
+        textarea.select();
+        const ok = document.execCommand('copy');
+        textarea.remove();
+        if (!ok) throw new Error('クリップボードにコピーできませんでした');
+        return message;
+    }
+
+    // =================================================================
+    // レンダリング
+    // =================================================================
+    // render は、container に操作ボタンを追加します。
+    // options: { actions: 表示する操作の配列, taskName, forceFull: () => bool, showStatus: (message, type) => void }
+    function render(container, target, options = {}) {
+        const actions = options.actions || Object.keys(ACTIONS);
+        actions.forEach((name) => {
+            const action = ACTIONS[name];
+            if (!action) return;
+            const button = document.createElement('button');
+            button.type = 'button';
+            button.className = `thread-action ${name}`;
+            button.textContent = action.label;
+            button.addEventListener('click', async () => {
+                try {
+                    const message = await action.run(target, options);
+                    if (options.showStatus) options.showStatus(message || '完了しました', 'success');
+                } catch (error) {
+                    if (options.showStatus) options.showStatus(`エラー: ${error.message}`, 'error');
+                }
+            });
+            container.appendChild(button);
+        });
+    }
+
+    return { render, fetchInfo };
+})();
@@ -0,0 +1,129 @@
+document.addEventListener('DOMContentLoaded', () => {
+    const dom = {
+        summary: document.getElementById('report-summary'),
+        body: document.getElementById('issues-body'),
+        statusMessage: document.getElementById('status-message'),
+        rearchiveForm: document.getElementById('rearchive-form'),
+        rearchiveTarget: document.getElementById('rearchive-target'),
+        rearchiveForceFull: document.getElementById('rearchive-force-full'),
+    };
+
+    // =================================================================
+    // 初期化
+    // =================================================================
+    async function loadReport() {
+        try {
+            const response = await fetch('/api/verification');
+            const report = await response.json();
+            if (!response.ok) throw new Error(report.error || '検証レポートの取得に失敗しました');
+            renderReport(report);
+        } catch (error) {
+            showStatus(`検証レポートの読み込み中にエラーが発生しました: ${error.message}`, 'error');
+        }
+    }
+
+    // =================================================================
+    // レンダリング
+    // =================================================================
+    function renderReport(report) {
+        const issues = report.issues || [];
+        const generated = report.generated_at && !report.generated_at.startsWith('0001')
+            ? new Date(report.generated_at).toLocaleString()
+            : '未実行';
+        dom.summary.textContent = `最終検証: ${generated} / 問題のあるスレッド: ${issues.length}件`;
+
+        dom.body.innerHTML = '';
+        issues.forEach((issue) => {
+            const row = document.createElement('tr');
+            row.innerHTML = `
+                <td>${escapeHtml(issue.task_name)}</td>
+                <td title="${escapeHtml(issue.thread_dir)}">${escapeHtml(issue.thread_id)}</td>
+                <td>${(issue.problems || []).map(escapeHtml).join('<br>')}${issue.repaired ? ' <em>(修復済み)</em>' : ''}</td>
+                <td>
+                    <button type="button" class="open-folder-btn">フォルダを開く</button>
+                    <button type="button" class="repair-btn">修復</button>
+                    <button type="button" class="rearchive-btn">再アーカイブ</button>
+                    <button type="button" class="diff-btn">差分</button>
+                    <span class="thread-actions"></span>
+                </td>
+            `;
+            GIBAThreadActions.render(row.querySelector('.thread-actions'), issue.thread_id, {
+                actions: ['copy-url', 'copy-path', 'view', 'open-full'],
+                showStatus,
+            });
+            row.querySelector('.open-folder-btn').addEventListener('click', () => postAction('/api/verification/open', issue));
+            row.querySelector('.repair-btn').addEventListener('click', async () => {
+                if (await postAction('/api/verification/repair', issue)) loadReport();
+            });
+            row.querySelector('.rearchive-btn').addEventListener('click', () => {
+                rearchive(issue.thread_id, issue.task_name);
+            });
+            row.querySelector('.diff-btn').addEventListener('click', () => {
+                window.location.href = `/diff?${new URLSearchParams({ target: issue.thread_id })}`;
+            });
+            dom.body.appendChild(row);
+        });
+    }
+
+    // =================================================================
+    // 操作
+    // =================================================================
+    async function postAction(url, issue) {
+        try {
+            const response = await fetch(url, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ task_name: issue.task_name, thread_dir: issue.thread_dir }),
+            });
+            const result = await response.json();
+            if (!response.ok) throw new Error(result.error || '操作に失敗しました');
+            showStatus(result.message || '完了しました', 'success');
+            return true;
+        } catch (error) {
+            showStatus(`エラー: ${error.message}`, 'error');
+            return false;
+        }
+    }
+
+    async function rearchive(target, taskName) {
+        try {
+            const response = await fetch('/api/rearchive', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    target: target,
+                    task_name: taskName || '',
+                    force_full: dom.rearchiveForceFull.checked,
+                }),
+            });
+            const result = await response.json();
+            if (!response.ok) throw new Error(result.error || '再アーカイブの開始に失敗しました');
+            showStatus(result.message, 'success');
+        } catch (error) {
+            showStatus(`エラー: ${error.message}`, 'error');
+        }
+    }
+
+    dom.rearchiveForm.addEventListener('submit', (event) => {
+        event.preventDefault();
+        const target = dom.rearchiveTarget.value.trim();
+        if (target) rearchive(target);
+    });
+
+    function showStatus(message, type) {
+        dom.statusMessage.textContent = message;
+        dom.statusMessage.className = `status-message ${type}`;
+        dom.statusMessage.style.display = 'block';
+        if (type !== 'info') {
+            setTimeout(() => { dom.statusMessage.style.display = 'none'; }, 5000);
+        }
+    }
+
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.appendChild(document.createTextNode(text || ''));
+        return div.innerHTML;
+    }
+
+    loadReport();
+});
@@ -0,0 +1,29 @@
+package webui
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// HandleHealthz は /healthz へのリクエストを処理します。
+// 全ての実行中タスクが期限内にハートビートを記録していれば 200、停止しているタスクがあれば 503 を返します。
+// コンテナのオーケストレーターや監視スクリプトから、応答しなくなったプロセスを検出するために使用します。
+func HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := core.CurrentHealth()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("ERROR: ヘルスチェック結果のエンコードに失敗しました: %v", err)
+	}
+}
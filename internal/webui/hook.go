@@ -0,0 +1,216 @@
+package webui
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/core"
+	"GoImageBoardArchiver/internal/network"
+	"GoImageBoardArchiver/internal/secrets"
+)
+
+// archiveHookPath は、外部からスレッドのアーカイブを依頼するエンドポイントのパスです。
+const archiveHookPath = "/api/hooks/archive"
+
+// archiveHookMaxBodyBytes は、/api/hooks/archive へのリクエストのボディの上限です（ブラウザの拡張機能が送るページのHTMLを含む）。
+const archiveHookMaxBodyBytes = 32 << 20
+
+// archiveHookRequest は、/api/hooks/archive へのリクエストです。
+// html・cookies・user_agent は、ログイン中のブラウザのセッションでアーカイブするために、ブラウザの拡張機能が送る閲覧中のページの情報です。
+type archiveHookRequest struct {
+	URL       string `json:"url"`                  // アーカイブするスレッドのURL
+	HTML      string `json:"html,omitempty"`       // ブラウザで表示したスレッドのHTML（document.documentElement.outerHTML）
+	Cookies   string `json:"cookies,omitempty"`    // スレッドのページのCookie（Cookie ヘッダーと同じ "name=value; name2=value2" の形式）
+	UserAgent string `json:"user_agent,omitempty"` // ブラウザの User-Agent（navigator.userAgent）
+}
+
+// browserPage は、リクエストにブラウザのページの情報が含まれる場合に、アーカイブで使用する core.BrowserPage を返します。含まれない場合は nil です。
+func (req archiveHookRequest) browserPage() *core.BrowserPage {
+	if req.HTML == "" && req.Cookies == "" && req.UserAgent == "" {
+		return nil
+	}
+	header := http.Header{}
+	if req.Cookies != "" {
+		header.Set("Cookie", req.Cookies)
+	}
+	return &core.BrowserPage{
+		HTML:      req.HTML,
+		Cookies:   (&http.Request{Header: header}).Cookies(),
+		UserAgent: strings.TrimSpace(req.UserAgent),
+	}
+}
+
+// archiveHookServer は、ブラウザのユーザースクリプトや拡張機能から、閲覧中のスレッドのアーカイブの依頼を受け付けるハンドラです。
+// 依頼されたスレッドは監視モードと同じ差分の判定でアーカイブするため、同じスレッドを何度依頼しても更新分のみ取得します。
+type archiveHookServer struct {
+	ctx   context.Context
+	cfg   *config.Config
+	token string // ${secret:...} は展開済み
+	// client は、すべての依頼で使い回すクライアントです。
+	// 依頼ごとに作成するとドメインごとのリクエスト間隔が依頼の間で守られないため、サーバーの起動時に1つだけ作成します。
+	client *network.Client
+
+	mu       sync.Mutex
+	inFlight map[string]bool // アーカイブ中のスレッド（タスク名とスレッドID）
+}
+
+// StartArchiveHookServer は、cfg.ArchiveHook の設定でアーカイブの依頼を受け付けるサーバーを起動し、ctx の終了時に停止します。
+func StartArchiveHookServer(ctx context.Context, cfg *config.Config) error {
+	hook := cfg.ArchiveHook
+	token, err := secrets.Expand(hook.Token)
+	if err != nil {
+		return fmt.Errorf("archive_hook のトークンを取得できません: %w", err)
+	}
+	if token == "" {
+		return fmt.Errorf("archive_hook のトークンが空です")
+	}
+	client, err := network.NewClient(cfg.Network)
+	if err != nil {
+		return fmt.Errorf("ネットワーククライアントの初期化に失敗しました: %w", err)
+	}
+	handler := &archiveHookServer{ctx: ctx, cfg: cfg, token: token, client: client, inFlight: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.Handle(archiveHookPath, handler)
+	server := &http.Server{
+		Addr:              hook.ListenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       60 * time.Second, // 拡張機能が送る大きなページのHTMLを受け取れるようにする
+		WriteTimeout:      10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("アーカイブの依頼を http://%s%s で受け付けます。", hook.ListenAddr, archiveHookPath)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("ERROR: アーカイブの依頼を受け付けるサーバーが異常終了しました: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("WARNING: アーカイブの依頼を受け付けるサーバーの停止に失敗しました: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *archiveHookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 掲示板のページ上で動くスクリプトから呼び出せるよう、オリジンを問わずに許可する（依頼にはトークンが必要）
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-GIBA-Token")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, `{"error": "トークンが正しくありません"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req archiveHookRequest
+	r.Body = http.MaxBytesReader(w, r.Body, archiveHookMaxBodyBytes)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "無効なリクエストです"}`, http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseMultipartForm(archiveHookMaxBodyBytes); err != nil && err != http.ErrNotMultipart {
+			http.Error(w, `{"error": "無効なリクエストです"}`, http.StatusBadRequest)
+			return
+		}
+		req.URL = r.FormValue("url")
+		req.HTML = r.FormValue("html")
+		req.Cookies = r.FormValue("cookies")
+		req.UserAgent = r.FormValue("user_agent")
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	if !strings.HasPrefix(req.URL, "http://") && !strings.HasPrefix(req.URL, "https://") {
+		http.Error(w, `{"error": "スレッドのURLを指定してください"}`, http.StatusBadRequest)
+		return
+	}
+
+	target, err := core.ResolveRearchiveTarget(s.cfg, req.URL)
+	if err != nil {
+		writeHookError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key := target.Task.TaskName + "\x00" + target.Thread.ID
+	s.mu.Lock()
+	running := s.inFlight[key]
+	s.inFlight[key] = true
+	s.mu.Unlock()
+
+	page := req.browserPage()
+	if !running {
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.inFlight, key)
+				s.mu.Unlock()
+			}()
+			result, err := core.ArchiveThread(s.ctx, s.cfg, s.client, req.URL, page, log.Default())
+			switch {
+			case err != nil:
+				log.Printf("ERROR: 依頼されたスレッド %s のアーカイブに失敗しました: %v", target.Thread.ID, err)
+			case result.Success:
+				log.Printf("依頼されたスレッド %s のアーカイブが完了しました: %s", target.Thread.ID, result.SavePath)
+			default:
+				log.Printf("依頼されたスレッド %s には更新がありません", target.Thread.ID)
+			}
+		}()
+	}
+
+	message := fmt.Sprintf("スレッド %s のアーカイブを開始しました (タスク: %s)", target.Thread.ID, target.Task.TaskName)
+	if page != nil && page.HTML != "" {
+		message = fmt.Sprintf("ブラウザのページからスレッド %s のアーカイブを開始しました (タスク: %s)", target.Thread.ID, target.Task.TaskName)
+	}
+	if running {
+		message = fmt.Sprintf("スレッド %s は既にアーカイブ中です (タスク: %s)", target.Thread.ID, target.Task.TaskName)
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]string{
+		"message":   message,
+		"thread_id": target.Thread.ID,
+		"task_name": target.Task.TaskName,
+	}); err != nil {
+		log.Printf("ERROR: アーカイブの依頼への応答のエンコードに失敗しました: %v", err)
+	}
+}
+
+// writeHookError は、message を {"error": message} の JSON として status で返します。
+func writeHookError(w http.ResponseWriter, message string, status int) {
+	body, err := json.Marshal(map[string]string{"error": message})
+	if err != nil {
+		log.Printf("ERROR: エラー応答のエンコードに失敗しました: %v", err)
+		body = []byte(`{"error": "アーカイブの依頼を処理できませんでした"}`)
+	}
+	http.Error(w, string(body), status)
+}
+
+// authorized は、Authorization: Bearer または X-GIBA-Token ヘッダーのトークンが設定と一致するかを判定します。
+func (s *archiveHookServer) authorized(r *http.Request) bool {
+	token := r.Header.Get("X-GIBA-Token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = strings.TrimSpace(bearer)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
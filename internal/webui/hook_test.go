@@ -0,0 +1,68 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+func TestArchiveHookServer_Requests(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{Tasks: []config.Task{
+		{TaskName: "may", SiteAdapter: "futaba", TargetBoardURL: "https://may.example.com/b/", SaveRootDirectory: t.TempDir()},
+	}}
+	// トークンの判定と入力の検証のみを確認するため、アーカイブを開始するリクエストは送らない
+	s := &archiveHookServer{ctx: context.Background(), cfg: cfg, token: "hook-token", inFlight: make(map[string]bool)}
+
+	tests := []struct {
+		name       string
+		method     string
+		headers    map[string]string
+		body       string
+		wantStatus int
+	}{
+		{"プリフライト", http.MethodOptions, nil, "", http.StatusNoContent},
+		{"GETは不可", http.MethodGet, map[string]string{"X-GIBA-Token": "hook-token"}, "", http.StatusMethodNotAllowed},
+		{"トークンなし", http.MethodPost, nil, `{"url": "https://may.example.com/b/res/100.htm"}`, http.StatusUnauthorized},
+		{"トークンの誤り", http.MethodPost, map[string]string{"X-GIBA-Token": "wrong"}, `{"url": "https://may.example.com/b/res/100.htm"}`, http.StatusUnauthorized},
+		{"Bearerトークンの誤り", http.MethodPost, map[string]string{"Authorization": "Bearer wrong"}, `{"url": "https://may.example.com/b/res/100.htm"}`, http.StatusUnauthorized},
+		{"URLではない", http.MethodPost, map[string]string{"X-GIBA-Token": "hook-token"}, `{"url": "100"}`, http.StatusBadRequest},
+		{"壊れたJSON", http.MethodPost, map[string]string{"Authorization": "Bearer hook-token"}, `{"url":`, http.StatusBadRequest},
+		{"タスクに一致しない板", http.MethodPost, map[string]string{"Authorization": "Bearer hook-token"}, `{"url": "https://other.example.com/b/res/100.htm"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tt.method, archiveHookPath, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body=%q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code >= http.StatusBadRequest {
+				var body struct {
+					Error string `json:"error"`
+				}
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+					t.Errorf("エラーの応答がJSONではありません: %q (%v)", rec.Body.String(), err)
+				}
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+				t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package webui
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"GoImageBoardArchiver/internal/archivecrypt"
+	"GoImageBoardArchiver/internal/core"
+
+	"golang.org/x/time/rate"
+)
+
+// archiveURLPrefix は、Web UIで保存先のファイル（スレッドのHTML・サムネイル・メディア）を配信するパスの接頭辞です。
+// ブラウザは http:// のページから file:// のリンクを開けないため、保存先のファイルはこのパスを経由して表示します。
+const archiveURLPrefix = "/archive/"
+
+// archiveStreamTimeout は、動画などの大きなファイルを配信する際の書き込みの期限です。
+// Web UIサーバーの WriteTimeout（API向けの短い期限）のままではシーク再生中に切断されるため、延長します。
+const archiveStreamTimeout = 30 * time.Minute
+
+// archiveLimiter は、保存先のファイルの配信を制限します。
+// サムネイルの多いスレッドを開いた際に大量のリクエストが同時に届いても、ディスクへのアクセスが集中しないようにします。
+var archiveLimiter = rate.NewLimiter(rate.Limit(50), 100)
+
+// handleArchiveFile は /archive/<番号>/<パス> へのリクエストを処理し、<番号> 番目の保存先のルートにあるファイルを配信します。
+func handleArchiveFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "許可されていないメソッドです", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := archiveLimiter.Wait(r.Context()); err != nil {
+		// クライアントが待機中に切断した場合
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("ERROR: 設定ファイルの読み込みに失敗しました: %v", err)
+		http.Error(w, "設定ファイルの読み込みに失敗しました", http.StatusInternalServerError)
+		return
+	}
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(archiveStreamTimeout)); err != nil {
+		log.Printf("WARNING: 配信の期限を延長できませんでした: %v", err)
+	}
+	serveArchivePath(w, r, core.ArchiveRoots(cfg), strings.TrimPrefix(r.URL.Path, archiveURLPrefix))
+}
+
+// archiveURL は、保存先のファイル filePath を /archive/ のURLに変換します。
+// filePath がどの保存先のルートにも含まれない場合は空文字を返します。
+func archiveURL(roots []string, filePath string) string {
+	for i, root := range roots {
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return (&url.URL{Path: archiveURLPrefix + strconv.Itoa(i) + "/" + filepath.ToSlash(rel)}).String()
+	}
+	return ""
+}
+
+// archiveContentSecurityPolicy は、保存先のファイルの応答に付ける Content-Security-Policy です。
+// allow-scripts を含まない sandbox により、投稿に残ったイベントハンドラ属性などのスクリプトは実行されず、
+// 文書は固有のオリジンとして扱われるため、Web UIのAPIを呼び出すこともできません。
+const archiveContentSecurityPolicy = "sandbox"
+
+// serveArchivePath は、"<番号>/<パス>" の形式の rest を roots[<番号>] からの相対パスとして、保存先のファイルを配信します。
+// 範囲リクエスト（動画のシーク）と条件付きリクエスト（ETag・更新日時）に対応します。
+// 内部状態のファイル（.snapshot.json や .giba/ など、"." で始まるもの）とディレクトリの一覧は配信しません。
+// 暗号化したタスクのファイル（encryption）は、シークレットストアの鍵で復号しながら配信します。
+// 保存したHTMLは投稿者が書いた内容を含み、Web UIのAPIと同じオリジンで配信されるため、すべての応答に
+// archiveContentSecurityPolicy を付けてスクリプトを実行させません。
+func serveArchivePath(w http.ResponseWriter, r *http.Request, roots []string, rest string) {
+	w.Header().Set("Content-Security-Policy", archiveContentSecurityPolicy)
+	indexStr, rel, _ := strings.Cut(rest, "/")
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 || index >= len(roots) {
+		http.NotFound(w, r)
+		return
+	}
+	rel = path.Clean("/" + rel)
+	for _, segment := range strings.Split(rel, "/") {
+		if strings.HasPrefix(segment, ".") {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	fullPath := filepath.Join(roots[index], filepath.FromSlash(rel))
+	info, err := os.Stat(fullPath)
+	if err == nil && info.IsDir() {
+		// ディレクトリの場合は、スレッドの index.htm（一覧ページの場合は index.html）のみを返す
+		err = os.ErrNotExist
+		for _, name := range []string{"index.htm", "index.html"} {
+			if fi, statErr := os.Stat(filepath.Join(fullPath, name)); statErr == nil && !fi.IsDir() {
+				fullPath, info, err = filepath.Join(fullPath, name), fi, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := archivecrypt.Open(fullPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("ERROR: 保存先のファイルを開けませんでした: %v", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", archiveETag(info))
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// archiveETag は、ファイルの更新日時とサイズからETagを作成します。
+// アーカイブ済みのファイルは再アーカイブ・修復の際にのみ置き換えられ、その際は更新日時かサイズが変わります。
+func archiveETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
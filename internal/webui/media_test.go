@@ -0,0 +1,65 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestServeArchivePath(t *testing.T) {
+	t.Parallel()
+
+	base := t.TempDir()
+	root := filepath.Join(base, "archives")
+	writeArchiveFile(t, filepath.Join(root, "100", "index.htm"), "<html>thread</html>")
+	writeArchiveFile(t, filepath.Join(root, "100", "img", "1.jpg"), "0123456789")
+	writeArchiveFile(t, filepath.Join(root, "100", ".resume.json"), "{}")
+	writeArchiveFile(t, filepath.Join(root, ".giba", "locations.json"), "{}")
+	// 保存先のルートの外にあるファイル
+	secret := filepath.Join(base, "secret.txt")
+	writeArchiveFile(t, secret, "secret")
+
+	tests := []struct {
+		name       string
+		rest       string
+		rangeHdr   string
+		wantStatus int
+		wantBody   string
+	}{
+		{"メディア", "0/100/img/1.jpg", "", http.StatusOK, "0123456789"},
+		{"ディレクトリはスレッドのHTML", "0/100/", "", http.StatusOK, "<html>thread</html>"},
+		{"範囲リクエスト", "0/100/img/1.jpg", "bytes=2-4", http.StatusPartialContent, "234"},
+		{"親ディレクトリへの参照", "0/../secret.txt", "", http.StatusNotFound, ""},
+		{"途中の親ディレクトリへの参照", "0/100/../../secret.txt", "", http.StatusNotFound, ""},
+		{"絶対パス", "0/" + filepath.ToSlash(secret), "", http.StatusNotFound, ""},
+		{"レジューム情報", "0/100/.resume.json", "", http.StatusNotFound, ""},
+		{"内部状態のディレクトリ", "0/.giba/locations.json", "", http.StatusNotFound, ""},
+		{"ディレクトリの一覧", "0/", "", http.StatusNotFound, ""},
+		{"範囲外の番号", "1/100/index.htm", "", http.StatusNotFound, ""},
+		{"番号ではない", "x/100/index.htm", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.rangeHdr != "" {
+				req.Header.Set("Range", tt.rangeHdr)
+			}
+			rec := httptest.NewRecorder()
+			serveArchivePath(rec, req, []string{root}, tt.rest)
+			if got := rec.Header().Get("Content-Security-Policy"); got != "sandbox" {
+				t.Errorf("Content-Security-Policy = %q, want %q", got, "sandbox")
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body=%q)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
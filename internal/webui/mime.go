@@ -0,0 +1,26 @@
+package webui
+
+import "mime"
+
+// archiveMIMETypes は、アーカイブ済みメディアを配信する際の拡張子ごとのMIMEタイプです。
+// Windowsではレジストリの設定によって音声や文書の種類が誤って判定されることがあるため、明示的に登録します。
+var archiveMIMETypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".webm": "video/webm",
+	".mp4":  "video/mp4",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+	".zip":  "application/zip",
+}
+
+func init() {
+	for ext, typ := range archiveMIMETypes {
+		if err := mime.AddExtensionType(ext, typ); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package webui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// rearchiveRequest は、/api/rearchive へのリクエストです。
+type rearchiveRequest struct {
+	Target    string `json:"target"`               // スレッドIDまたはスレッドURL
+	TaskName  string `json:"task_name,omitempty"`  // 指定した場合はこのタスクのみを対象とする
+	ForceFull bool   `json:"force_full,omitempty"` // 既存ファイルを無視してすべて再ダウンロードする
+}
+
+// handleRearchive は /api/rearchive へのリクエストを処理し、指定されたスレッドの再アーカイブを開始します。
+// 再アーカイブには時間がかかるため、対象を確認した時点で応答を返し、処理はバックグラウンドで行います。
+func handleRearchive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rearchiveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		http.Error(w, `{"error": "無効なリクエストです"}`, http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("ERROR: 設定ファイルの読み込みに失敗しました: %v", err)
+		http.Error(w, `{"error": "設定ファイルの読み込みに失敗しました。"}`, http.StatusInternalServerError)
+		return
+	}
+	if req.TaskName != "" {
+		tasks := cfg.Tasks[:0]
+		for _, task := range cfg.Tasks {
+			if task.TaskName == req.TaskName {
+				tasks = append(tasks, task)
+			}
+		}
+		cfg.Tasks = tasks
+	}
+
+	target, err := core.ResolveRearchiveTarget(cfg, req.Target)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if _, err := core.RearchiveThread(context.Background(), cfg, req.Target, req.ForceFull, log.Default()); err != nil {
+			log.Printf("ERROR: スレッド %s の再アーカイブに失敗しました: %v", target.Thread.ID, err)
+			return
+		}
+		log.Printf("スレッド %s の再アーカイブが完了しました", target.Thread.ID)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": fmt.Sprintf("スレッド %s の再アーカイブを開始しました (タスク: %s)", target.Thread.ID, target.Task.TaskName),
+	})
+}
@@ -0,0 +1,179 @@
+package webui
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/core"
+	"GoImageBoardArchiver/internal/secrets"
+)
+
+// shareCacheTTL は、共有モードで生成した一覧ページを再利用する期間です。
+// アーカイブ全体の走査を閲覧のたびに行わないよう、この期間が過ぎてから次のリクエストで作り直します。
+const shareCacheTTL = time.Minute
+
+// shareFilesPrefix は、保存先のファイル（スレッドのHTMLやメディア）を配信するパスの接頭辞です。
+// 保存先のルートごとに /files/<番号>/ に割り当てます。
+const shareFilesPrefix = "files/"
+
+// shareServer は、共有モードでアーカイブの一覧・検索・スレッドの閲覧のみを提供するハンドラです。
+// 設定画面や操作用のAPI（再アーカイブ・修復・フォルダを開くなど）は登録しないため、閲覧者はGIBAの設定を変更できません。
+type shareServer struct {
+	cfg       *config.Config
+	roots     []string
+	users     map[string]string // ユーザー名とパスワード（${secret:...} は展開済み）
+	anonymous bool
+
+	mu         sync.Mutex
+	pages      map[string][]byte
+	renderedAt time.Time
+}
+
+// newShareServer は、設定から共有モードのハンドラを作成します。パスワードの ${secret:...} はここで展開します。
+func newShareServer(cfg *config.Config) (*shareServer, error) {
+	sharing := cfg.Sharing
+	s := &shareServer{cfg: cfg, roots: core.ArchiveRoots(cfg), users: make(map[string]string), anonymous: sharing.AllowAnonymous}
+	for user, password := range sharing.Users {
+		expanded, err := secrets.Expand(password)
+		if err != nil {
+			return nil, fmt.Errorf("ユーザー '%s' のパスワードを取得できません: %w", user, err)
+		}
+		s.users[user] = expanded
+	}
+	return s, nil
+}
+
+// StartShareServer は、cfg.Sharing の設定で共有モードのサーバーを起動し、ctx の終了時に停止します。
+// 設定画面のWeb UIとは別のアドレスで待ち受け、独自の認証（Basic認証）を行います。
+func StartShareServer(ctx context.Context, cfg *config.Config) error {
+	handler, err := newShareServer(cfg)
+	if err != nil {
+		return err
+	}
+	sharing := cfg.Sharing
+	server := &http.Server{
+		Addr:              sharing.ListenAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	scheme := "http"
+	if sharing.TLSCertFile != "" {
+		scheme = "https"
+	}
+	go func() {
+		log.Printf("共有モード（閲覧専用）を %s://%s で提供します。", scheme, sharing.ListenAddr)
+		var err error
+		if sharing.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(sharing.TLSCertFile, sharing.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("ERROR: 共有モードのサーバーが異常終了しました: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("WARNING: 共有モードのサーバーの停止に失敗しました: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (s *shareServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="GIBA", charset="UTF-8"`)
+		http.Error(w, "認証が必要です", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "許可されていないメソッドです", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" {
+		name = "index.html"
+	}
+	if rest, ok := strings.CutPrefix(name, shareFilesPrefix); ok {
+		serveArchivePath(w, r, s.roots, rest)
+		return
+	}
+
+	pages, err := s.renderedPages(r.Context())
+	if err != nil {
+		log.Printf("ERROR: 共有モードのページの生成に失敗しました: %v", err)
+		http.Error(w, "ページを生成できませんでした", http.StatusInternalServerError)
+		return
+	}
+	page, ok := pages[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(page))
+}
+
+// authorized は、Basic認証の資格情報が users のいずれかと一致するかを判定します。
+func (s *shareServer) authorized(r *http.Request) bool {
+	if s.anonymous && len(s.users) == 0 {
+		return true
+	}
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return s.anonymous
+	}
+	want, exists := s.users[user]
+	// ユーザーの有無で応答時間が変わらないよう、存在しない場合も比較する
+	match := subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1
+	return exists && match
+}
+
+// renderedPages は、一覧・検索のページを返します。前回の生成から shareCacheTTL が過ぎている場合は作り直します。
+func (s *shareServer) renderedPages(ctx context.Context) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pages != nil && time.Since(s.renderedAt) < shareCacheTTL {
+		return s.pages, nil
+	}
+
+	pages, _, err := core.RenderSite(ctx, s.cfg, s.threadLink, log.Default())
+	if err != nil {
+		return nil, err
+	}
+	s.pages, s.renderedAt = pages, time.Now()
+	return pages, nil
+}
+
+// threadLink は、保存先のルート root にあるスレッドのディレクトリを /files/<番号>/ からのリンクに変換します。
+func (s *shareServer) threadLink(root, threadDir string) (string, error) {
+	for i, r := range s.roots {
+		if r != root {
+			continue
+		}
+		rel, err := filepath.Rel(root, threadDir)
+		if err != nil {
+			return "", err
+		}
+		return shareFilesPrefix + strconv.Itoa(i) + "/" + filepath.ToSlash(rel), nil
+	}
+	return "", fmt.Errorf("保存先 %s は共有の対象ではありません", root)
+}
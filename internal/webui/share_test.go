@@ -0,0 +1,79 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"GoImageBoardArchiver/internal/config"
+)
+
+// writeArchiveFile は、テスト用の保存先に data を書き込みます。
+func writeArchiveFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShareServer_BasicAuth(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeArchiveFile(t, filepath.Join(root, "100", "index.htm"), "<html>thread</html>")
+	newServer := func(t *testing.T, sharing *config.SharingSettings) *shareServer {
+		t.Helper()
+		cfg := &config.Config{
+			Sharing: sharing,
+			Tasks:   []config.Task{{TaskName: "may", SaveRootDirectory: root}},
+		}
+		s, err := newShareServer(cfg)
+		if err != nil {
+			t.Fatalf("newShareServer() error = %v", err)
+		}
+		return s
+	}
+	users := map[string]string{"alice": "correct-password"}
+
+	tests := []struct {
+		name       string
+		sharing    *config.SharingSettings
+		user, pass string
+		useAuth    bool
+		wantStatus int
+	}{
+		{"資格情報なし", &config.SharingSettings{Users: users}, "", "", false, http.StatusUnauthorized},
+		{"パスワードの誤り", &config.SharingSettings{Users: users}, "alice", "wrong", true, http.StatusUnauthorized},
+		{"存在しないユーザー", &config.SharingSettings{Users: users}, "bob", "correct-password", true, http.StatusUnauthorized},
+		{"正しい資格情報", &config.SharingSettings{Users: users}, "alice", "correct-password", true, http.StatusOK},
+		{"匿名の閲覧を許可", &config.SharingSettings{Users: users, AllowAnonymous: true}, "", "", false, http.StatusOK},
+		{"匿名でも資格情報の誤りは拒否", &config.SharingSettings{Users: users, AllowAnonymous: true}, "alice", "wrong", true, http.StatusUnauthorized},
+		{"ユーザーなしの匿名", &config.SharingSettings{AllowAnonymous: true}, "", "", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := newServer(t, tt.sharing)
+			req := httptest.NewRequest(http.MethodGet, "/files/0/100/index.htm", nil)
+			if tt.useAuth {
+				req.SetBasicAuth(tt.user, tt.pass)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 の応答に WWW-Authenticate ヘッダーがありません")
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package webui
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// handleStatus は /api/status へのリクエストを処理し、タスクごとの現在の状態と全体の状態を返します。
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(core.CurrentStatus()); err != nil {
+		log.Printf("ERROR: 状態のエンコードに失敗しました: %v", err)
+	}
+}
+
+// errorResponse は、/api/errors が返す最近のエラー1件です。
+type errorResponse struct {
+	core.ErrorRecord
+	ClassLabel string `json:"class_label"`
+}
+
+// handleErrors は /api/errors へのリクエストを処理し、タスクの最近のエラーを新しい順に返します。
+func handleErrors(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	records := core.RecentErrors()
+	resp := make([]errorResponse, len(records))
+	for i, rec := range records {
+		resp[i] = errorResponse{ErrorRecord: rec, ClassLabel: rec.Class.Label()}
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("ERROR: エラー一覧のエンコードに失敗しました: %v", err)
+	}
+}
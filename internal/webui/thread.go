@@ -0,0 +1,149 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"GoImageBoardArchiver/internal/config"
+	"GoImageBoardArchiver/internal/core"
+)
+
+// threadInfoResponse は、/api/thread が返すアーカイブ済みスレッドの情報です。
+// ビューアの各操作（URLやパスのコピー、フォルダを開くなど）に必要な値をまとめて返します。
+type threadInfoResponse struct {
+	TaskName        string `json:"task_name"`
+	ThreadID        string `json:"thread_id"`
+	Title           string `json:"title,omitempty"`
+	URL             string `json:"url"`                  // 元のスレッドのURL
+	ThreadDir       string `json:"thread_dir,omitempty"` // 保存先の絶対パス（未アーカイブの場合は空）
+	HasArchiveFull  bool   `json:"has_archive_full"`
+	ArchiveFullPath string `json:"archive_full_path,omitempty"`
+	ViewURL         string `json:"view_url,omitempty"`         // Web UIで index.htm を表示するURL
+	ArchiveFullURL  string `json:"archive_full_url,omitempty"` // Web UIで archive_full.html を表示するURL
+}
+
+// threadOpenRequest は、/api/thread/open へのリクエストです。
+type threadOpenRequest struct {
+	Target string `json:"target"` // スレッドIDまたはスレッドURL
+	What   string `json:"what"`   // "folder": 保存先のフォルダ, "archive_full": archive_full.html
+}
+
+// handleThreadInfo は /api/thread へのリクエストを処理し、target で指定されたスレッドの情報を返します。
+func handleThreadInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg, target, ok := resolveThreadTarget(w, r.URL.Query().Get("target"))
+	if !ok {
+		return
+	}
+
+	info := threadInfoResponse{
+		TaskName: target.Task.TaskName,
+		ThreadID: target.Thread.ID,
+		Title:    target.Thread.Title,
+		URL:      target.OriginalURL(),
+	}
+	if target.ThreadDir != "" {
+		info.ThreadDir = absPath(target.ThreadDir)
+		roots := core.ArchiveRoots(cfg)
+		info.ViewURL = archiveURL(roots, filepath.Join(target.ThreadDir, "index.htm"))
+		fullPath := filepath.Join(info.ThreadDir, "archive_full.html")
+		if _, err := os.Stat(fullPath); err == nil {
+			info.HasArchiveFull = true
+			info.ArchiveFullPath = fullPath
+			info.ArchiveFullURL = archiveURL(roots, filepath.Join(target.ThreadDir, "archive_full.html"))
+		}
+		if meta, err := core.LoadThreadMetadata(target.ThreadDir); err == nil && meta != nil && meta.Title != "" {
+			info.Title = meta.Title
+		}
+	}
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		log.Printf("ERROR: スレッド情報のエンコードに失敗しました: %v", err)
+	}
+}
+
+// handleThreadOpen は /api/thread/open へのリクエストを処理し、アーカイブ済みスレッドのフォルダまたは完全版HTMLを開きます。
+// 任意のパスを開けないよう、開く対象は設定されたタスクの保存先から特定したスレッドのディレクトリに限ります。
+func handleThreadOpen(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req threadOpenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		http.Error(w, `{"error": "無効なリクエストです"}`, http.StatusBadRequest)
+		return
+	}
+
+	_, target, ok := resolveThreadTarget(w, req.Target)
+	if !ok {
+		return
+	}
+	if target.ThreadDir == "" {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, fmt.Sprintf("スレッド %s はまだアーカイブされていません", target.Thread.ID)), http.StatusNotFound)
+		return
+	}
+
+	var path, message string
+	switch req.What {
+	case "folder":
+		path, message = target.ThreadDir, "フォルダを開きました"
+	case "archive_full":
+		path, message = filepath.Join(target.ThreadDir, "archive_full.html"), "archive_full.html を開きました"
+		if _, err := os.Stat(path); err != nil {
+			http.Error(w, `{"error": "archive_full.html がありません"}`, http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, `{"error": "開く対象が不正です"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := openPath(absPath(path)); err != nil {
+		log.Printf("ERROR: %v", err)
+		http.Error(w, `{"error": "開けませんでした"}`, http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// resolveThreadTarget は、スレッドIDまたはスレッドURLから対象のスレッドを特定します。
+// 読み込んだ設定も返します。失敗した場合はエラーレスポンスを書き込み、false を返します。
+func resolveThreadTarget(w http.ResponseWriter, targetParam string) (*config.Config, core.RearchiveTarget, bool) {
+	if targetParam == "" {
+		http.Error(w, `{"error": "スレッドIDまたはスレッドURLを指定してください"}`, http.StatusBadRequest)
+		return nil, core.RearchiveTarget{}, false
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("ERROR: 設定ファイルの読み込みに失敗しました: %v", err)
+		http.Error(w, `{"error": "設定ファイルの読み込みに失敗しました。"}`, http.StatusInternalServerError)
+		return nil, core.RearchiveTarget{}, false
+	}
+
+	target, err := core.ResolveRearchiveTarget(cfg, targetParam)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusBadRequest)
+		return nil, core.RearchiveTarget{}, false
+	}
+	return cfg, target, true
+}
+
+// absPath は、path を絶対パスにして返します。変換できない場合は path をそのまま返します。
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
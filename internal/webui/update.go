@@ -0,0 +1,35 @@
+package webui
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/update"
+	"GoImageBoardArchiver/internal/version"
+)
+
+// handleUpdateStatus は /api/update へのリクエストを処理し、直近の更新確認の結果を返します。
+// 更新確認は check_for_updates が有効な場合にバックグラウンドで行われ、ここでは新たな確認は行いません。
+func handleUpdateStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(update.LastStatus()); err != nil {
+		log.Printf("ERROR: 更新情報のエンコードに失敗しました: %v", err)
+	}
+}
+
+// handleVersion は /api/version へのリクエストを処理し、実行中のバージョンとビルド情報を返します。
+func handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(version.Get()); err != nil {
+		log.Printf("ERROR: バージョン情報のエンコードに失敗しました: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package webui
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// usageStatsResponse は、/api/usage-stats が返す累計の利用統計です。usage_stats_file が未設定の場合、Enabled は false です。
+type usageStatsResponse struct {
+	Enabled bool             `json:"enabled"`
+	Stats   *core.UsageStats `json:"stats,omitempty"`
+}
+
+// handleUsageStats は /api/usage-stats へのリクエストを処理し、usage_stats_file に記録している累計の利用統計を返します。
+func handleUsageStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	var resp usageStatsResponse
+	if stats, ok := core.CurrentUsageStats(); ok {
+		resp = usageStatsResponse{Enabled: true, Stats: &stats}
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("ERROR: 利用統計のエンコードに失敗しました: %v", err)
+	}
+}
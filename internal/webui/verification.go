@@ -0,0 +1,137 @@
+package webui
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"runtime"
+
+	"GoImageBoardArchiver/internal/core"
+)
+
+// verificationActionRequest は、検証結果ページからの操作リクエストです。
+type verificationActionRequest struct {
+	TaskName  string `json:"task_name"`
+	ThreadDir string `json:"thread_dir"`
+}
+
+// handleVerificationReport は /api/verification へのリクエストを処理し、直近の検証レポートを返します。
+func handleVerificationReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := core.LoadVerificationReport(core.VerificationReportPath)
+	if err != nil {
+		log.Printf("ERROR: 検証レポートの読み込みに失敗しました: %v", err)
+		http.Error(w, `{"error": "検証レポートの読み込みに失敗しました。"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("ERROR: 検証レポートのエンコードに失敗しました: %v", err)
+	}
+}
+
+// handleVerificationOpen は /api/verification/open へのリクエストを処理し、
+// 検証レポートに記載されたスレッドのフォルダをOSのファイルマネージャで開きます。
+func handleVerificationOpen(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	req, ok := decodeVerificationAction(w, r)
+	if !ok {
+		return
+	}
+
+	// 任意のパスを開けないよう、レポートに含まれるディレクトリのみ許可する
+	if !isReportedThreadDir(req.ThreadDir) {
+		http.Error(w, `{"error": "検証レポートに含まれていないフォルダです"}`, http.StatusBadRequest)
+		return
+	}
+	if err := openPath(req.ThreadDir); err != nil {
+		log.Printf("ERROR: フォルダを開けませんでした: %v", err)
+		http.Error(w, `{"error": "フォルダを開けませんでした"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Write([]byte(`{"message": "フォルダを開きました"}`))
+}
+
+// handleVerificationRepair は /api/verification/repair へのリクエストを処理し、
+// 指定されたスレッドのみを対象に修復を実行します。
+func handleVerificationRepair(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	req, ok := decodeVerificationAction(w, r)
+	if !ok {
+		return
+	}
+	if !isReportedThreadDir(req.ThreadDir) {
+		http.Error(w, `{"error": "検証レポートに含まれていないフォルダです"}`, http.StatusBadRequest)
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("ERROR: 設定ファイルの読み込みに失敗しました: %v", err)
+		http.Error(w, `{"error": "設定ファイルの読み込みに失敗しました。"}`, http.StatusInternalServerError)
+		return
+	}
+
+	result, err := core.RepairThread(r.Context(), cfg, req.TaskName, req.ThreadDir)
+	if err != nil {
+		log.Printf("ERROR: スレッドの修復に失敗しました: %v", err)
+		http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("ERROR: 修復結果のエンコードに失敗しました: %v", err)
+	}
+}
+
+// decodeVerificationAction は、POSTされた操作リクエストをデコードします。
+// 失敗した場合はエラーレスポンスを書き込み、false を返します。
+func decodeVerificationAction(w http.ResponseWriter, r *http.Request) (verificationActionRequest, bool) {
+	var req verificationActionRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error": "許可されていないメソッドです"}`, http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ThreadDir == "" {
+		http.Error(w, `{"error": "無効なリクエストです"}`, http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// isReportedThreadDir は、指定されたディレクトリが保存済み検証レポートに含まれているかを判定します。
+func isReportedThreadDir(threadDir string) bool {
+	report, err := core.LoadVerificationReport(core.VerificationReportPath)
+	if err != nil {
+		log.Printf("WARNING: 検証レポートの読み込みに失敗しました: %v", err)
+		return false
+	}
+	for _, issue := range report.Issues {
+		if issue.ThreadDir == threadDir {
+			return true
+		}
+	}
+	return false
+}
+
+// openPath は、フォルダをOSのファイルマネージャで、ファイルを関連付けられたアプリケーションで開きます。
+func openPath(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("explorer", path)
+	case "darwin":
+		cmd = exec.Command("open", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("パスを開くコマンドの実行に失敗しました (path=%s): %w", path, err)
+	}
+	return nil
+}
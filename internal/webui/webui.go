@@ -5,11 +5,11 @@ import (
 	"embed"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
 	"net"
 	"net/http"
-	"os"
 	"os/exec"
 	"runtime"
 	"sync"
@@ -36,12 +36,18 @@ var (
 // StartWebServer はWebサーバーを非同期で起動し、ブラウザを開きます。
 // すでにサーバーが起動している場合は、新しいブラウザタブで既存のサーバーのURLを開くだけです。
 func StartWebServer() {
+	OpenPage("/")
+}
+
+// OpenPage はWebサーバーを（未起動であれば）起動し、指定されたページをブラウザで開きます。
+// page は "/" や "/verification" のようなサーバールートからのパスです。
+func OpenPage(page string) {
 	serverMutex.Lock()
 	defer serverMutex.Unlock()
 
 	if currentServer != nil {
 		log.Println("Web UIサーバーはすでに起動しています。既存のサーバーを利用します。")
-		if err := openBrowser(fmt.Sprintf("http://127.0.0.1:%d", currentServer.port)); err != nil {
+		if err := openBrowser(fmt.Sprintf("http://127.0.0.1:%d%s", currentServer.port, page)); err != nil {
 			log.Printf("WARNING: ブラウザの起動に失敗しました: %v", err)
 		}
 		return
@@ -60,6 +66,23 @@ func StartWebServer() {
 	// APIエンドポイント
 	mux.HandleFunc("/api/config", handleConfig)
 	mux.HandleFunc("/api/shutdown", handleShutdown)
+	mux.HandleFunc("/api/verification", handleVerificationReport)
+	mux.HandleFunc("/api/verification/open", handleVerificationOpen)
+	mux.HandleFunc("/api/verification/repair", handleVerificationRepair)
+	mux.HandleFunc("/api/rearchive", handleRearchive)
+	mux.HandleFunc("/api/diff", handleThreadDiff)
+	mux.HandleFunc("/api/thread", handleThreadInfo)
+	mux.HandleFunc("/api/thread/open", handleThreadOpen)
+	mux.HandleFunc("/api/update", handleUpdateStatus)
+	mux.HandleFunc("/api/version", handleVersion)
+	mux.HandleFunc("/api/usage-stats", handleUsageStats)
+	mux.HandleFunc("/api/board-presets", handleBoardPresets)
+	mux.HandleFunc("/api/status", handleStatus)
+	mux.HandleFunc("/api/errors", handleErrors)
+	mux.HandleFunc("/healthz", HandleHealthz)
+
+	// 保存先のファイル（スレッドのHTML・サムネイル・メディア）
+	mux.HandleFunc(archiveURLPrefix, handleArchiveFile)
 
 	// 静的ファイル用のハンドラ (CSS, JS)
 	staticFS, err := fs.Sub(embeddedAssets, "embed/static")
@@ -76,20 +99,30 @@ func StartWebServer() {
 			http.NotFound(w, r)
 			return
 		}
-		indexHTML, err := embeddedAssets.ReadFile("embed/index.html")
-		if err != nil {
-			log.Printf("ERROR: index.htmlの読み込みに失敗しました: %v", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		w.Write(indexHTML)
+		serveEmbeddedPage(w, "embed/index.html")
+	})
+	mux.HandleFunc("/verification", func(w http.ResponseWriter, r *http.Request) {
+		serveEmbeddedPage(w, "embed/verification.html")
+	})
+	mux.HandleFunc("/diff", func(w http.ResponseWriter, r *http.Request) {
+		serveEmbeddedPage(w, "embed/diff.html")
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		serveEmbeddedPage(w, "embed/about.html")
 	})
 
+	writeTimeout := 10 * time.Second
+	if debugEnabled.Load() {
+		registerDebugHandlers(mux)
+		// CPUプロファイルの取得（既定30秒）がタイムアウトしないように延長する
+		writeTimeout = 2 * time.Minute
+		log.Printf("DEBUG: pprofエンドポイントを有効化しました: http://127.0.0.1:%d/debug/pprof/", port)
+	}
+
 	server := &http.Server{
 		Handler:      mux,
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  10 * time.Minute, // 10分間アイドルなら自動終了
 	}
 
@@ -118,47 +151,52 @@ func StartWebServer() {
 	}()
 
 	// ブラウザでURLを開きます。
-	if err := openBrowser(fmt.Sprintf("http://127.0.0.1:%d", port)); err != nil {
-		log.Printf("WARNING: ブラウザの起動に失敗しました: %v。手動でURLを開いてください: http://127.0.0.1:%d", err, port)
+	if err := openBrowser(fmt.Sprintf("http://127.0.0.1:%d%s", port, page)); err != nil {
+		log.Printf("WARNING: ブラウザの起動に失敗しました: %v。手動でURLを開いてください: http://127.0.0.1:%d%s", err, port, page)
 	}
 }
 
+// serveEmbeddedPage は、埋め込まれたHTMLページをレスポンスとして返します。
+func serveEmbeddedPage(w http.ResponseWriter, name string) {
+	pageHTML, err := embeddedAssets.ReadFile(name)
+	if err != nil {
+		log.Printf("ERROR: %sの読み込みに失敗しました: %v", name, err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(pageHTML)
+}
+
 // handleConfig は /api/config へのリクエストを処理します。
 func handleConfig(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	switch r.Method {
 	case http.MethodGet:
-		// 設定ファイルを読み込んでJSONで返します。
-		cfg, err := config.LoadAndResolve("config.json")
+		// 設定ファイルを記述されたまま返します。テンプレートや相対パスを解決した内容を返すと、
+		// 保存したときに相対パスが絶対パスに書き換わり、テンプレートが各タスクに展開されてしまうためです。
+		data, err := loadRawConfig()
 		if err != nil {
 			log.Printf("ERROR: 設定ファイルの読み込みに失敗しました: %v", err)
 			http.Error(w, `{"error": "設定ファイルの読み込みに失敗しました。ファイルが破損しているか、アクセスできません。"}`, http.StatusInternalServerError)
 			return
 		}
-		if err := json.NewEncoder(w).Encode(cfg); err != nil {
-			log.Printf("ERROR: 設定JSONのエンコードに失敗しました: %v", err)
-			http.Error(w, `{"error": "設定データの準備中にエラーが発生しました。"}`, http.StatusInternalServerError)
-			return
-		}
+		w.Write(data)
 	case http.MethodPost:
-		// POSTされたJSONを解析して設定ファイルに保存します。
-		var newCfg config.Config
-		if err := json.NewDecoder(r.Body).Decode(&newCfg); err != nil {
-			log.Printf("ERROR: 受信したJSONのデコードに失敗しました: %v", err)
-			http.Error(w, `{"error": "無効なJSON形式です。入力データを確認してください。"}`, http.StatusBadRequest)
+		// POSTされたJSONを検証し、記述されたまま設定ファイルに保存します。
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigBodyBytes))
+		if err != nil {
+			log.Printf("ERROR: 受信した設定の読み込みに失敗しました: %v", err)
+			http.Error(w, `{"error": "設定データを受信できませんでした。"}`, http.StatusBadRequest)
 			return
 		}
-
-		// TODO: ここで詳細なバリデーションロジックを実装
-
-		// 新しい設定をファイルに書き込みます。
-		fileData, err := json.MarshalIndent(newCfg, "", "  ")
-		if err != nil {
-			log.Printf("ERROR: 新しい設定のJSONシリアライズに失敗しました: %v", err)
-			http.Error(w, `{"error": "設定データの保存準備中にエラーが発生しました。"}`, http.StatusInternalServerError)
+		if _, err := config.ParseAndResolve(data); err != nil {
+			log.Printf("ERROR: 受信した設定が不正です: %v", err)
+			message, _ := json.Marshal(map[string]string{"error": fmt.Sprintf("無効な設定です。入力データを確認してください: %v", err)})
+			http.Error(w, string(message), http.StatusBadRequest)
 			return
 		}
-		if err := os.WriteFile("config.json", fileData, 0644); err != nil {
+		if err := saveRawConfig(data); err != nil {
 			log.Printf("ERROR: 設定ファイルの書き込みに失敗しました: %v", err)
 			http.Error(w, `{"error": "設定ファイルの書き込みに失敗しました。ファイル権限を確認してください。"}`, http.StatusInternalServerError)
 			return